@@ -0,0 +1,232 @@
+package copilot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+)
+
+// Defaults of an [AdaptiveConcurrency].
+const (
+	DefaultInitialConcurrency = 16
+	DefaultMaxConcurrency     = 64
+)
+
+// DefaultOverloadCodes are the JSON-RPC error codes an [AdaptiveConcurrency]
+// treats as overload signals unless OverloadCodes is set.
+var DefaultOverloadCodes = []int{429, 503}
+
+// AdaptiveConcurrency limits the requests a [Client] has in flight to the
+// server, adjusting the limit to the server's load: each overload signal, an
+// error with one of OverloadCodes or a response slower than LatencyThreshold,
+// cuts the limit by DecreaseFactor, and each successful response raises it
+// so that it grows by one per limit's worth of responses. Requests over the
+// limit wait in a queue and are sent in the order they were made.
+//
+// Requests already in flight when the limit is cut do not cut it again, so a
+// burst of failures from one overload is counted once.
+//
+// Example:
+//
+//	client := copilot.NewClient(&copilot.ClientOptions{
+//	    AdaptiveConcurrency: &copilot.AdaptiveConcurrency{
+//	        MaxLimit:         32,
+//	        LatencyThreshold: 10 * time.Second,
+//	    },
+//	})
+type AdaptiveConcurrency struct {
+	// InitialLimit is the limit to start with. Defaults to
+	// [DefaultInitialConcurrency], capped to MaxLimit.
+	InitialLimit int
+	// MinLimit is the lowest the limit goes. Defaults to 1.
+	MinLimit int
+	// MaxLimit is the highest the limit goes. Defaults to
+	// [DefaultMaxConcurrency].
+	MaxLimit int
+	// DecreaseFactor is what the limit is multiplied by on overload, between 0
+	// and 1. Defaults to 0.5.
+	DecreaseFactor float64
+	// LatencyThreshold, if positive, treats responses that take longer as
+	// overload signals.
+	LatencyThreshold time.Duration
+	// OverloadCodes are the JSON-RPC error codes that signal overload. Defaults
+	// to [DefaultOverloadCodes].
+	OverloadCodes []int
+}
+
+// ConcurrencyStats reports the state of a client's [AdaptiveConcurrency].
+type ConcurrencyStats struct {
+	// Limit is the current limit on requests in flight, or zero if the client
+	// has no adaptive concurrency control.
+	Limit int
+	// InFlight is the number of requests sent and waiting for a response.
+	InFlight int
+	// Queued is the number of requests waiting to be sent.
+	Queued int
+	// Overloads is the number of overload signals received.
+	Overloads uint64
+	// Decreases is the number of times the limit was cut.
+	Decreases uint64
+}
+
+// validate reports the first invalid setting.
+func (a *AdaptiveConcurrency) validate() error {
+	if a.MinLimit < 0 || a.MaxLimit < 0 || a.InitialLimit < 0 {
+		return fmt.Errorf("invalid AdaptiveConcurrency: limits must not be negative")
+	}
+	if a.MinLimit > 0 && a.MaxLimit > 0 && a.MinLimit > a.MaxLimit {
+		return fmt.Errorf("invalid AdaptiveConcurrency: MinLimit %d exceeds MaxLimit %d", a.MinLimit, a.MaxLimit)
+	}
+	if a.DecreaseFactor < 0 || a.DecreaseFactor >= 1 {
+		return fmt.Errorf("invalid AdaptiveConcurrency: DecreaseFactor %v must be in [0, 1)", a.DecreaseFactor)
+	}
+	return nil
+}
+
+// adaptiveLimiter enforces an [AdaptiveConcurrency] on outbound requests.
+type adaptiveLimiter struct {
+	minLimit, maxLimit float64
+	decreaseFactor     float64
+	latencyThreshold   time.Duration
+	overloadCodes      map[int]bool
+
+	mu           sync.Mutex
+	limit        float64
+	inFlight     int
+	waiters      []chan struct{}
+	lastDecrease time.Time
+	overloads    uint64
+	decreases    uint64
+}
+
+// newAdaptiveLimiter returns a limiter for config, or nil if config is nil.
+func newAdaptiveLimiter(config *AdaptiveConcurrency) *adaptiveLimiter {
+	if config == nil {
+		return nil
+	}
+	l := &adaptiveLimiter{
+		minLimit:         float64(config.MinLimit),
+		maxLimit:         float64(config.MaxLimit),
+		decreaseFactor:   config.DecreaseFactor,
+		latencyThreshold: config.LatencyThreshold,
+		overloadCodes:    make(map[int]bool),
+	}
+	if l.minLimit == 0 {
+		l.minLimit = 1
+	}
+	if l.maxLimit == 0 {
+		l.maxLimit = math.Max(DefaultMaxConcurrency, l.minLimit)
+	}
+	if l.decreaseFactor == 0 {
+		l.decreaseFactor = 0.5
+	}
+	codes := config.OverloadCodes
+	if codes == nil {
+		codes = DefaultOverloadCodes
+	}
+	for _, code := range codes {
+		l.overloadCodes[code] = true
+	}
+	initial := float64(config.InitialLimit)
+	if initial == 0 {
+		initial = DefaultInitialConcurrency
+	}
+	l.limit = math.Min(math.Max(initial, l.minLimit), l.maxLimit)
+	return l
+}
+
+// acquire waits until a request may be sent, or until ctx is done or stop is
+// closed. It returns the time the request was admitted.
+func (l *adaptiveLimiter) acquire(ctx context.Context, stop <-chan struct{}) (time.Time, error) {
+	l.mu.Lock()
+	if len(l.waiters) == 0 && l.inFlight < l.capacity() {
+		l.inFlight++
+		l.mu.Unlock()
+		return time.Now(), nil
+	}
+	ready := make(chan struct{})
+	l.waiters = append(l.waiters, ready)
+	l.mu.Unlock()
+
+	var err error
+	select {
+	case <-ready:
+		return time.Now(), nil
+	case <-ctx.Done():
+		err = ctx.Err()
+	case <-stop:
+		err = fmt.Errorf("client stopped")
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for i, waiter := range l.waiters {
+		if waiter == ready {
+			l.waiters = append(l.waiters[:i], l.waiters[i+1:]...)
+			return time.Time{}, err
+		}
+	}
+	// Admitted as the wait ended: give the slot back.
+	l.inFlight--
+	l.admitLocked()
+	return time.Time{}, err
+}
+
+// release records the outcome of a request admitted at start and admits
+// waiting requests the limit allows.
+func (l *adaptiveLimiter) release(start time.Time, err error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.inFlight--
+
+	if l.isOverload(time.Since(start), err) {
+		l.overloads++
+		if !start.Before(l.lastDecrease) {
+			l.limit = math.Max(l.minLimit, l.limit*l.decreaseFactor)
+			l.lastDecrease = time.Now()
+			l.decreases++
+		}
+	} else if err == nil {
+		l.limit = math.Min(l.maxLimit, l.limit+1/l.limit)
+	}
+	l.admitLocked()
+}
+
+func (l *adaptiveLimiter) isOverload(latency time.Duration, err error) bool {
+	var rpcErr *JSONRPCError
+	if errors.As(err, &rpcErr) && l.overloadCodes[rpcErr.Code] {
+		return true
+	}
+	return l.latencyThreshold > 0 && latency > l.latencyThreshold
+}
+
+// capacity returns the whole number of requests the limit allows.
+func (l *adaptiveLimiter) capacity() int {
+	return int(l.limit)
+}
+
+// admitLocked admits waiting requests, in order, while the limit allows.
+func (l *adaptiveLimiter) admitLocked() {
+	for len(l.waiters) > 0 && l.inFlight < l.capacity() {
+		close(l.waiters[0])
+		l.waiters = l.waiters[1:]
+		l.inFlight++
+	}
+}
+
+func (l *adaptiveLimiter) stats() ConcurrencyStats {
+	if l == nil {
+		return ConcurrencyStats{}
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return ConcurrencyStats{
+		Limit:     l.capacity(),
+		InFlight:  l.inFlight,
+		Queued:    len(l.waiters),
+		Overloads: l.overloads,
+		Decreases: l.decreases,
+	}
+}
@@ -0,0 +1,194 @@
+package copilot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestAdaptiveLimiter(t *testing.T) {
+	overload := &JSONRPCError{Code: 429, Message: "too many requests"}
+
+	t.Run("cuts the limit on overload and recovers additively", func(t *testing.T) {
+		l := newAdaptiveLimiter(&AdaptiveConcurrency{InitialLimit: 8})
+		start, _ := l.acquire(context.Background(), nil)
+		l.release(start, fmt.Errorf("failed to send: %w", overload))
+		if stats := l.stats(); stats.Limit != 4 || stats.Overloads != 1 || stats.Decreases != 1 {
+			t.Fatalf("Unexpected stats %+v", stats)
+		}
+		for i := 0; i < 4; i++ {
+			start, _ := l.acquire(context.Background(), nil)
+			l.release(start, nil)
+		}
+		if stats := l.stats(); stats.Limit != 4 {
+			t.Errorf("Expected the limit to grow by less than one in one limit's worth of responses, got %+v", stats)
+		}
+		start, _ = l.acquire(context.Background(), nil)
+		l.release(start, nil)
+		if stats := l.stats(); stats.Limit != 5 {
+			t.Errorf("Expected the limit to grow to 5, got %+v", stats)
+		}
+	})
+
+	t.Run("counts one cut for requests already in flight", func(t *testing.T) {
+		l := newAdaptiveLimiter(&AdaptiveConcurrency{InitialLimit: 8})
+		var starts []time.Time
+		for i := 0; i < 3; i++ {
+			start, _ := l.acquire(context.Background(), nil)
+			starts = append(starts, start)
+		}
+		for _, start := range starts {
+			l.release(start, overload)
+		}
+		if stats := l.stats(); stats.Limit != 4 || stats.Overloads != 3 || stats.Decreases != 1 {
+			t.Errorf("Unexpected stats %+v", stats)
+		}
+	})
+
+	t.Run("stays within bounds", func(t *testing.T) {
+		l := newAdaptiveLimiter(&AdaptiveConcurrency{InitialLimit: 3, MinLimit: 2, MaxLimit: 4})
+		for i := 0; i < 3; i++ {
+			start, _ := l.acquire(context.Background(), nil)
+			l.release(start, overload)
+		}
+		if stats := l.stats(); stats.Limit != 2 {
+			t.Errorf("Expected the limit to stop at MinLimit, got %+v", stats)
+		}
+		for i := 0; i < 50; i++ {
+			start, _ := l.acquire(context.Background(), nil)
+			l.release(start, nil)
+		}
+		if stats := l.stats(); stats.Limit != 4 {
+			t.Errorf("Expected the limit to stop at MaxLimit, got %+v", stats)
+		}
+	})
+
+	t.Run("treats slow responses as overload", func(t *testing.T) {
+		l := newAdaptiveLimiter(&AdaptiveConcurrency{InitialLimit: 4, LatencyThreshold: time.Millisecond})
+		start, _ := l.acquire(context.Background(), nil)
+		l.release(start.Add(-time.Second), nil)
+		if stats := l.stats(); stats.Limit != 2 || stats.Overloads != 1 {
+			t.Errorf("Unexpected stats %+v", stats)
+		}
+	})
+
+	t.Run("ignores other errors", func(t *testing.T) {
+		l := newAdaptiveLimiter(&AdaptiveConcurrency{InitialLimit: 4})
+		start, _ := l.acquire(context.Background(), nil)
+		l.release(start, &JSONRPCError{Code: -32602, Message: "invalid params"})
+		if stats := l.stats(); stats.Limit != 4 || stats.Overloads != 0 {
+			t.Errorf("Unexpected stats %+v", stats)
+		}
+	})
+
+	t.Run("queues requests over the limit in order", func(t *testing.T) {
+		l := newAdaptiveLimiter(&AdaptiveConcurrency{InitialLimit: 1, MaxLimit: 1})
+		first, _ := l.acquire(context.Background(), nil)
+		order := make(chan int, 2)
+		for i := 1; i <= 2; i++ {
+			go func(i int) {
+				start, _ := l.acquire(context.Background(), nil)
+				order <- i
+				l.release(start, nil)
+			}(i)
+			waitForQueuedRequests(t, l, i)
+		}
+		l.release(first, nil)
+		if a, b := <-order, <-order; a != 1 || b != 2 {
+			t.Errorf("Expected requests in order, got %d then %d", a, b)
+		}
+	})
+
+	t.Run("stops waiting when the client stops", func(t *testing.T) {
+		l := newAdaptiveLimiter(&AdaptiveConcurrency{InitialLimit: 1, MaxLimit: 1})
+		start, _ := l.acquire(context.Background(), nil)
+		stop := make(chan struct{})
+		done := make(chan error, 1)
+		go func() {
+			_, err := l.acquire(context.Background(), stop)
+			done <- err
+		}()
+		waitForQueuedRequests(t, l, 1)
+		close(stop)
+		if err := <-done; err == nil {
+			t.Error("Expected an error")
+		}
+		l.release(start, nil)
+		if stats := l.stats(); stats.InFlight != 0 || stats.Queued != 0 {
+			t.Errorf("Unexpected stats %+v", stats)
+		}
+	})
+
+	t.Run("stops waiting when the context is done", func(t *testing.T) {
+		l := newAdaptiveLimiter(&AdaptiveConcurrency{InitialLimit: 1, MaxLimit: 1})
+		start, _ := l.acquire(context.Background(), nil)
+		ctx, cancel := context.WithCancel(context.Background())
+		done := make(chan error, 1)
+		go func() {
+			_, err := l.acquire(ctx, nil)
+			done <- err
+		}()
+		waitForQueuedRequests(t, l, 1)
+		cancel()
+		if err := <-done; !errors.Is(err, context.Canceled) {
+			t.Errorf("Expected context.Canceled, got %v", err)
+		}
+		l.release(start, nil)
+		if stats := l.stats(); stats.InFlight != 0 || stats.Queued != 0 {
+			t.Errorf("Unexpected stats %+v", stats)
+		}
+	})
+}
+
+func waitForQueuedRequests(t *testing.T, l *adaptiveLimiter, n int) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for l.stats().Queued < n {
+		if time.Now().After(deadline) {
+			t.Fatalf("Timed out waiting for %d queued requests", n)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestAdaptiveConcurrencyRequests(t *testing.T) {
+	client, _ := newFakeServer(t, func(method string, params map[string]interface{}) (map[string]interface{}, *JSONRPCError) {
+		if method == "busy" {
+			return nil, &JSONRPCError{Code: 503, Message: "overloaded"}
+		}
+		return map[string]interface{}{}, nil
+	})
+	client.limiter = newAdaptiveLimiter(&AdaptiveConcurrency{InitialLimit: 8})
+
+	if _, err := client.Request("busy", nil); err == nil {
+		t.Fatal("Expected an error")
+	}
+	if _, err := client.Request("ping", nil); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if stats := client.limiter.stats(); stats.Limit != 4 || stats.Overloads != 1 || stats.InFlight != 0 {
+		t.Errorf("Unexpected stats %+v", stats)
+	}
+
+	c := &Client{concurrency: client.limiter, isExternalServer: true}
+	if stats, _ := c.Stats(); stats.Concurrency.Limit != 4 {
+		t.Errorf("Expected Stats to report the limit, got %+v", stats)
+	}
+}
+
+func TestAdaptiveConcurrencyValidation(t *testing.T) {
+	for _, config := range []AdaptiveConcurrency{
+		{MinLimit: -1},
+		{MinLimit: 8, MaxLimit: 4},
+		{DecreaseFactor: 1},
+	} {
+		if err := config.validate(); err == nil {
+			t.Errorf("Expected %+v to be invalid", config)
+		}
+	}
+	if err := (&AdaptiveConcurrency{}).validate(); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+}
@@ -0,0 +1,184 @@
+package copilot
+
+import (
+	"fmt"
+)
+
+// ArgumentTransformer rewrites the arguments of a tool call before they are
+// decoded and passed to the handler. It lets a tool's parameters evolve without
+// breaking prompts and instructions written against older argument names or
+// shapes.
+//
+// args is a private copy of the call's arguments, so a transformer may modify
+// it in place and return it. Returning an error fails the tool call.
+type ArgumentTransformer func(args map[string]interface{}) (map[string]interface{}, error)
+
+// WithArgumentTransformers runs transformers, in order, on the arguments of
+// every call before they are decoded into the handler's parameter type.
+// The handler's [ToolInvocation] carries the transformed arguments.
+//
+// Example:
+//
+//	// "path" was renamed to "file", and "encoding" was added with a default.
+//	tool := copilot.DefineTool("read_file", "Read a file", readFile,
+//	    copilot.WithArgumentTransformers(
+//	        copilot.RenameArgument("path", "file"),
+//	        copilot.DefaultArgument("encoding", "utf-8"),
+//	    ))
+func WithArgumentTransformers(transformers ...ArgumentTransformer) ToolOption {
+	return func(o *toolOptions) {
+		o.argumentTransformers = append(o.argumentTransformers, transformers...)
+	}
+}
+
+// TransformArguments returns a copy of tool whose handler runs transformers on
+// the arguments of every call first. It is the equivalent of
+// [WithArgumentTransformers] for tools not created with [DefineTool].
+func TransformArguments(tool Tool, transformers ...ArgumentTransformer) Tool {
+	handler := tool.Handler
+	tool.Handler = func(inv ToolInvocation) (ToolResult, error) {
+		inv, err := applyArgumentTransformers(inv, transformers)
+		if err != nil {
+			return ToolResult{}, err
+		}
+		return handler(inv)
+	}
+	return tool
+}
+
+// RenameArgument returns a transformer that moves the top-level argument from
+// to the name to. If both are present, to wins and from is dropped.
+func RenameArgument(from, to string) ArgumentTransformer {
+	return func(args map[string]interface{}) (map[string]interface{}, error) {
+		value, ok := args[from]
+		if !ok {
+			return args, nil
+		}
+		delete(args, from)
+		if _, exists := args[to]; !exists {
+			args[to] = value
+		}
+		return args, nil
+	}
+}
+
+// DefaultArgument returns a transformer that sets the top-level argument name to
+// value when the call does not provide it or provides null.
+func DefaultArgument(name string, value interface{}) ArgumentTransformer {
+	return func(args map[string]interface{}) (map[string]interface{}, error) {
+		if existing, ok := args[name]; !ok || existing == nil {
+			args[name] = cloneJSONValue(value)
+		}
+		return args, nil
+	}
+}
+
+// applyArgumentTransformers returns inv with its arguments rewritten by
+// transformers. The original arguments are not modified.
+func applyArgumentTransformers(inv ToolInvocation, transformers []ArgumentTransformer) (ToolInvocation, error) {
+	if len(transformers) == 0 {
+		return inv, nil
+	}
+
+	var args map[string]interface{}
+	switch raw := inv.Arguments.(type) {
+	case nil:
+		args = make(map[string]interface{})
+	case map[string]interface{}:
+		args = cloneJSONValue(raw).(map[string]interface{})
+	default:
+		return inv, fmt.Errorf("failed to transform arguments: expected an object, got %T", inv.Arguments)
+	}
+
+	for _, transform := range transformers {
+		var err error
+		args, err = transform(args)
+		if err != nil {
+			return inv, fmt.Errorf("failed to transform arguments: %w", err)
+		}
+		if args == nil {
+			args = make(map[string]interface{})
+		}
+	}
+
+	inv.Arguments = args
+	return inv, nil
+}
+
+// applySchemaDefaults returns a copy of arguments in which properties that
+// are omitted or null are set to the "default" of their schema, in schema and
+// in the schemas of nested objects and array items.
+func applySchemaDefaults(schema map[string]interface{}, arguments interface{}) interface{} {
+	if arguments == nil {
+		arguments = map[string]interface{}{}
+	}
+	return fillDefaults(schema, cloneJSONValue(arguments))
+}
+
+// fillDefaults sets the defaults of schema in value, modifying it in place.
+func fillDefaults(schema map[string]interface{}, value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		properties, _ := schema["properties"].(map[string]interface{})
+		for name, raw := range properties {
+			property, _ := raw.(map[string]interface{})
+			if existing, ok := v[name]; !ok || existing == nil {
+				if def, ok := property["default"]; ok {
+					v[name] = cloneJSONValue(def)
+				}
+				continue
+			}
+			v[name] = fillDefaults(property, v[name])
+		}
+	case []interface{}:
+		if items, ok := schema["items"].(map[string]interface{}); ok {
+			for i, item := range v {
+				v[i] = fillDefaults(items, item)
+			}
+		}
+	}
+	return value
+}
+
+// schemaHasDefaults reports whether schema or any schema nested in it has a
+// "default".
+func schemaHasDefaults(schema interface{}) bool {
+	switch s := schema.(type) {
+	case map[string]interface{}:
+		if _, ok := s["default"]; ok {
+			return true
+		}
+		for _, nested := range s {
+			if schemaHasDefaults(nested) {
+				return true
+			}
+		}
+	case []interface{}:
+		for _, nested := range s {
+			if schemaHasDefaults(nested) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// cloneJSONValue deep-copies the maps and slices of a decoded JSON value.
+func cloneJSONValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		c := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			c[key] = cloneJSONValue(item)
+		}
+		return c
+	case []interface{}:
+		c := make([]interface{}, len(v))
+		for i, item := range v {
+			c[i] = cloneJSONValue(item)
+		}
+		return c
+	default:
+		return v
+	}
+}
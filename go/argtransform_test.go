@@ -0,0 +1,103 @@
+package copilot
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestArgumentTransformers(t *testing.T) {
+	type readParams struct {
+		File     string `json:"file"`
+		Encoding string `json:"encoding"`
+	}
+
+	t.Run("rename and default run before decoding", func(t *testing.T) {
+		var got readParams
+		var gotArgs interface{}
+		tool := DefineTool("read_file", "Read a file",
+			func(params readParams, inv ToolInvocation) (string, error) {
+				got = params
+				gotArgs = inv.Arguments
+				return "ok", nil
+			},
+			WithArgumentTransformers(RenameArgument("path", "file"), DefaultArgument("encoding", "utf-8")))
+
+		original := map[string]interface{}{"path": "main.go"}
+		if _, err := tool.Handler(ToolInvocation{Arguments: original}); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if got.File != "main.go" || got.Encoding != "utf-8" {
+			t.Errorf("Unexpected params %+v", got)
+		}
+		if args := gotArgs.(map[string]interface{}); args["file"] != "main.go" || args["path"] != nil {
+			t.Errorf("Expected the invocation to carry transformed arguments, got %v", args)
+		}
+		if original["path"] != "main.go" || original["file"] != nil {
+			t.Errorf("Expected original arguments to be unchanged, got %v", original)
+		}
+	})
+
+	t.Run("new names win over old ones", func(t *testing.T) {
+		args, _ := RenameArgument("path", "file")(map[string]interface{}{"path": "old", "file": "new"})
+		if args["file"] != "new" || len(args) != 1 {
+			t.Errorf("Unexpected args %v", args)
+		}
+		args, _ = DefaultArgument("encoding", "utf-8")(map[string]interface{}{"encoding": "latin1"})
+		if args["encoding"] != "latin1" {
+			t.Errorf("Expected explicit value to be kept, got %v", args)
+		}
+	})
+
+	t.Run("custom transformers migrate shapes", func(t *testing.T) {
+		// Old shape: {"range": "10-20"}; new shape: {"start": 10, "end": 20}.
+		migrate := func(args map[string]interface{}) (map[string]interface{}, error) {
+			r, ok := args["range"].(string)
+			if !ok {
+				return args, nil
+			}
+			start, end, found := strings.Cut(r, "-")
+			if !found {
+				return nil, errors.New("range must look like 10-20")
+			}
+			delete(args, "range")
+			args["start"], args["end"] = start, end
+			return args, nil
+		}
+		tool := DefineTool("lines", "Read lines",
+			func(params struct {
+				Start string `json:"start"`
+				End   string `json:"end"`
+			}, inv ToolInvocation) (string, error) {
+				return params.Start + ":" + params.End, nil
+			},
+			WithArgumentTransformers(migrate), WithErrorRenderer(RenderToolError))
+
+		result, err := tool.Handler(ToolInvocation{Arguments: map[string]interface{}{"range": "10-20"}})
+		if err != nil || result.TextResultForLLM != "10:20" {
+			t.Errorf("Unexpected result %+v, %v", result, err)
+		}
+
+		result, err = tool.Handler(ToolInvocation{Arguments: map[string]interface{}{"range": "10"}})
+		if err != nil || result.ResultType != "failure" || !strings.Contains(result.TextResultForLLM, "range must look like 10-20") {
+			t.Errorf("Expected the transformer error to be rendered, got %+v, %v", result, err)
+		}
+	})
+
+	t.Run("wraps tools not created with DefineTool", func(t *testing.T) {
+		tool := TransformArguments(Tool{
+			Name: "legacy",
+			Handler: func(inv ToolInvocation) (ToolResult, error) {
+				return ToolResult{TextResultForLLM: inv.Arguments.(map[string]interface{})["query"].(string)}, nil
+			},
+		}, RenameArgument("q", "query"))
+
+		result, err := tool.Handler(ToolInvocation{Arguments: map[string]interface{}{"q": "needle"}})
+		if err != nil || result.TextResultForLLM != "needle" {
+			t.Errorf("Unexpected result %+v, %v", result, err)
+		}
+		if _, err := tool.Handler(ToolInvocation{Arguments: "not an object"}); err == nil {
+			t.Error("Expected an error for non-object arguments")
+		}
+	})
+}
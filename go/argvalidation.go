@@ -0,0 +1,411 @@
+package copilot
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ArgumentError is a problem with one field of a tool call's arguments.
+type ArgumentError struct {
+	// Field is the path of the field, such as "unit" or "filters[0].label", or
+	// empty for the arguments as a whole.
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+func (e ArgumentError) String() string {
+	if e.Field == "" {
+		return e.Message
+	}
+	return e.Field + ": " + e.Message
+}
+
+// InvalidArgumentsError reports tool call arguments that do not match the
+// tool's parameters schema.
+type InvalidArgumentsError struct {
+	ToolName string
+	Errors   []ArgumentError
+}
+
+func (e *InvalidArgumentsError) Error() string {
+	messages := make([]string, len(e.Errors))
+	for i, fieldErr := range e.Errors {
+		messages[i] = fieldErr.String()
+	}
+	return fmt.Sprintf("invalid arguments for tool %s: %s", e.ToolName, strings.Join(messages, "; "))
+}
+
+// WithoutArgumentValidation turns off validating arguments against the tool's
+// generated schema, so that they are decoded into the handler's parameters
+// as is.
+func WithoutArgumentValidation() ToolOption {
+	return func(o *toolOptions) {
+		o.skipValidation = true
+	}
+}
+
+// ValidateArguments checks tool call arguments, such as
+// [ToolInvocation.Arguments], against a parameters schema and
+// returns a problem for each mismatching field, ordered by field. It supports
+// the keywords [DefineTool] generates: type, properties, required,
+// additionalProperties, items, enum, const, oneOf, anyOf, minimum, maximum,
+// minLength, maxLength, minItems, maxItems, and pattern. Other keywords are
+// ignored.
+//
+// Tools created with [DefineTool] validate their arguments before the handler
+// runs; call this from hand-built tools.
+func ValidateArguments(schema map[string]interface{}, arguments interface{}) []ArgumentError {
+	if schema == nil {
+		return nil
+	}
+	// Round-trip the arguments so that values built in Go, such as ints, have
+	// the types decoded JSON has.
+	var instance interface{} = map[string]interface{}{}
+	if arguments != nil {
+		data, err := json.Marshal(arguments)
+		if err != nil {
+			return []ArgumentError{{Message: fmt.Sprintf("cannot be encoded as JSON: %v", err)}}
+		}
+		if err := json.Unmarshal(data, &instance); err != nil {
+			return []ArgumentError{{Message: fmt.Sprintf("cannot be decoded as JSON: %v", err)}}
+		}
+	}
+	var errs []ArgumentError
+	validateValue(schema, instance, "", &errs)
+	sort.SliceStable(errs, func(i, j int) bool { return errs[i].Field < errs[j].Field })
+	return errs
+}
+
+func validateValue(schema map[string]interface{}, value interface{}, path string, errs *[]ArgumentError) {
+	add := func(format string, args ...interface{}) {
+		*errs = append(*errs, ArgumentError{Field: path, Message: fmt.Sprintf(format, args...)})
+	}
+
+	if types := schemaTypes(schema); len(types) > 0 && !matchesAnyType(types, value) {
+		add("must be %s, got %s", joinTypes(types), jsonTypeName(value))
+		return
+	}
+	if enum, ok := schema["enum"].([]interface{}); ok && !containsJSONValue(enum, value) {
+		add("must be one of %s", formatEnum(enum))
+		return
+	}
+	if constant, ok := schema["const"]; ok && !containsJSONValue([]interface{}{constant}, value) {
+		add("must be %s", formatEnum([]interface{}{constant}))
+		return
+	}
+	for _, keyword := range []string{"oneOf", "anyOf"} {
+		if variants, ok := schema[keyword].([]interface{}); ok {
+			validateVariants(variants, value, path, errs)
+		}
+	}
+
+	switch val := value.(type) {
+	case map[string]interface{}:
+		properties, _ := schema["properties"].(map[string]interface{})
+		if required, ok := schema["required"].([]interface{}); ok {
+			for _, name := range required {
+				name, _ := name.(string)
+				if _, present := val[name]; !present {
+					*errs = append(*errs, ArgumentError{Field: joinFieldPath(path, name), Message: "is required"})
+				}
+			}
+		}
+		propertyNames, _ := schema["propertyNames"].(map[string]interface{})
+		for name, fieldValue := range val {
+			fieldPath := joinFieldPath(path, name)
+			if propertyNames != nil {
+				var nameErrs []ArgumentError
+				validateValue(propertyNames, name, fieldPath, &nameErrs)
+				if len(nameErrs) > 0 {
+					*errs = append(*errs, ArgumentError{Field: fieldPath, Message: "is not a valid key: " + nameErrs[0].Message})
+					continue
+				}
+			}
+			if propertySchema, ok := properties[name].(map[string]interface{}); ok {
+				validateValue(propertySchema, fieldValue, fieldPath, errs)
+				continue
+			}
+			switch additional := schema["additionalProperties"].(type) {
+			case bool:
+				if !additional {
+					*errs = append(*errs, ArgumentError{Field: fieldPath, Message: "is not a known field"})
+				}
+			case map[string]interface{}:
+				validateValue(additional, fieldValue, fieldPath, errs)
+			}
+		}
+	case []interface{}:
+		if n, ok := schemaNumber(schema, "minItems"); ok && float64(len(val)) < n {
+			add("must have at least %s items", formatNumber(n))
+		}
+		if n, ok := schemaNumber(schema, "maxItems"); ok && float64(len(val)) > n {
+			add("must have at most %s items", formatNumber(n))
+		}
+		if items, ok := schema["items"].(map[string]interface{}); ok {
+			for i, item := range val {
+				validateValue(items, item, fmt.Sprintf("%s[%d]", path, i), errs)
+			}
+		}
+	case float64:
+		if n, ok := schemaNumber(schema, "minimum"); ok && val < n {
+			add("must be at least %s", formatNumber(n))
+		}
+		if n, ok := schemaNumber(schema, "maximum"); ok && val > n {
+			add("must be at most %s", formatNumber(n))
+		}
+	case string:
+		length := len([]rune(val))
+		if n, ok := schemaNumber(schema, "minLength"); ok && float64(length) < n {
+			add("must be at least %s characters long", formatNumber(n))
+		}
+		if n, ok := schemaNumber(schema, "maxLength"); ok && float64(length) > n {
+			add("must be at most %s characters long", formatNumber(n))
+		}
+		if pattern, ok := schema["pattern"].(string); ok {
+			if re, err := regexp.Compile(pattern); err == nil && !re.MatchString(val) {
+				add("must match the pattern %s", pattern)
+			}
+		}
+		switch schema["format"] {
+		case dateTimeFormat:
+			if _, err := time.Parse(time.RFC3339, val); err != nil {
+				add("must be an RFC 3339 date-time, such as 2006-01-02T15:04:05Z")
+			}
+		case durationFormat:
+			if _, err := parseDurationArgument(val); err != nil {
+				add(`must be a duration, such as "90s", "1h30m", or "PT1H30M"`)
+			}
+		}
+	}
+}
+
+// validateVariants checks a value against the variants of a oneOf or anyOf
+// schema. A value matching several variants of a oneOf is accepted, as
+// hand-written variants often overlap. If it matches none, the problems
+// reported are those of the variant its const properties, such as a union's
+// discriminator, select.
+func validateVariants(variants []interface{}, value interface{}, path string, errs *[]ArgumentError) {
+	matched := 0
+	var selected [][]ArgumentError
+	for _, v := range variants {
+		variant, _ := v.(map[string]interface{})
+		var variantErrs []ArgumentError
+		validateValue(variant, value, path, &variantErrs)
+		if len(variantErrs) == 0 {
+			matched++
+		} else if selectsVariant(variant, value) {
+			selected = append(selected, variantErrs)
+		}
+	}
+
+	switch {
+	case matched > 0:
+	case len(selected) == 1:
+		*errs = append(*errs, selected[0]...)
+	default:
+		if name, values, ok := variantDiscriminator(variants); ok {
+			*errs = append(*errs, ArgumentError{Field: joinFieldPath(path, name), Message: "must be one of " + formatEnum(values)})
+			return
+		}
+		*errs = append(*errs, ArgumentError{Field: path, Message: fmt.Sprintf("must match one of %d variants", len(variants))})
+	}
+}
+
+// selectsVariant reports whether value has the values of all of a variant's
+// const properties, and the variant has at least one.
+func selectsVariant(variant map[string]interface{}, value interface{}) bool {
+	object, ok := value.(map[string]interface{})
+	if !ok {
+		return false
+	}
+	properties, _ := variant["properties"].(map[string]interface{})
+	found := false
+	for name, property := range properties {
+		property, _ := property.(map[string]interface{})
+		constant, ok := property["const"]
+		if !ok {
+			continue
+		}
+		if !containsJSONValue([]interface{}{constant}, object[name]) {
+			return false
+		}
+		found = true
+	}
+	return found
+}
+
+// variantDiscriminator returns the const property every variant has, such as
+// a union's discriminator, and its values.
+func variantDiscriminator(variants []interface{}) (string, []interface{}, bool) {
+	var name string
+	var values []interface{}
+	for _, v := range variants {
+		variant, _ := v.(map[string]interface{})
+		properties, _ := variant["properties"].(map[string]interface{})
+		found := false
+		for propertyName, property := range properties {
+			property, _ := property.(map[string]interface{})
+			if constant, ok := property["const"]; ok && (name == "" || propertyName == name) {
+				name = propertyName
+				values = append(values, constant)
+				found = true
+				break
+			}
+		}
+		if !found {
+			return "", nil, false
+		}
+	}
+	return name, values, name != ""
+}
+
+func joinFieldPath(path, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
+
+// matchesAnyType reports whether a decoded JSON value has one of the JSON
+// schema types.
+func matchesAnyType(types []string, value interface{}) bool {
+	for _, t := range types {
+		switch t {
+		case "null":
+			if value == nil {
+				return true
+			}
+		case "boolean":
+			if _, ok := value.(bool); ok {
+				return true
+			}
+		case "string":
+			if _, ok := value.(string); ok {
+				return true
+			}
+		case "number":
+			if _, ok := value.(float64); ok {
+				return true
+			}
+		case "integer":
+			if n, ok := value.(float64); ok && n == math.Trunc(n) {
+				return true
+			}
+		case "array":
+			if _, ok := value.([]interface{}); ok {
+				return true
+			}
+		case "object":
+			if _, ok := value.(map[string]interface{}); ok {
+				return true
+			}
+		default:
+			return true
+		}
+	}
+	return false
+}
+
+func jsonTypeName(value interface{}) string {
+	switch val := value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case string:
+		return "string"
+	case float64:
+		if val == math.Trunc(val) {
+			return "integer"
+		}
+		return "number"
+	case json.Number:
+		return "number"
+	case []interface{}:
+		return "array"
+	case map[string]interface{}:
+		return "object"
+	}
+	return fmt.Sprintf("%T", value)
+}
+
+// joinTypes describes JSON schema types for messages, such as "a string or
+// null".
+func joinTypes(types []string) string {
+	described := make([]string, 0, len(types))
+	nullable := false
+	for _, t := range types {
+		switch {
+		case t == "null":
+			nullable = true
+		case strings.ContainsAny(t[:1], "aeiou"):
+			described = append(described, "an "+t)
+		default:
+			described = append(described, "a "+t)
+		}
+	}
+	if nullable {
+		described = append(described, "null")
+	}
+	return strings.Join(described, " or ")
+}
+
+func containsJSONValue(values []interface{}, value interface{}) bool {
+	encoded, _ := json.Marshal(value)
+	for _, candidate := range values {
+		if c, _ := json.Marshal(candidate); string(c) == string(encoded) {
+			return true
+		}
+	}
+	return false
+}
+
+func formatEnum(values []interface{}) string {
+	formatted := make([]string, len(values))
+	for i, v := range values {
+		data, _ := json.Marshal(v)
+		formatted[i] = string(data)
+	}
+	return strings.Join(formatted, ", ")
+}
+
+func schemaNumber(schema map[string]interface{}, key string) (float64, bool) {
+	n, ok := schema[key].(float64)
+	return n, ok
+}
+
+func formatNumber(n float64) string {
+	return strconv.FormatFloat(n, 'f', -1, 64)
+}
+
+// buildInvalidArgumentsResult creates a failure ToolResult listing the
+// problems with the arguments, so the model can correct them and retry. The
+// problems are also in ToolTelemetry under "fieldErrors".
+func buildInvalidArgumentsResult(err *InvalidArgumentsError) ToolResult {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Invalid arguments for tool '%s':\n", err.ToolName)
+	for _, fieldErr := range err.Errors {
+		fmt.Fprintf(&b, "- %s\n", fieldErr)
+	}
+	b.WriteString("Correct the arguments and call the tool again.")
+
+	fieldErrors := make([]interface{}, len(err.Errors))
+	for i, fieldErr := range err.Errors {
+		fieldErrors[i] = map[string]interface{}{"field": fieldErr.Field, "message": fieldErr.Message}
+	}
+	return ToolResult{
+		TextResultForLLM: b.String(),
+		ResultType:       "failure",
+		Error:            err.Error(),
+		ToolTelemetry: map[string]interface{}{
+			"errorType":   "invalid_arguments",
+			"fieldErrors": fieldErrors,
+		},
+	}
+}
@@ -0,0 +1,114 @@
+package copilot
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestValidateArguments(t *testing.T) {
+	type Filter struct {
+		Label string `json:"label"`
+	}
+	type Params struct {
+		City    string   `json:"city"`
+		Unit    string   `json:"unit" jsonschema:"optional,enum=celsius|fahrenheit"`
+		Days    int      `json:"days" jsonschema:"minimum=1,maximum=14"`
+		Code    *string  `json:"code" jsonschema:"pattern=^[A-Z]{2}$"`
+		Filters []Filter `json:"filters,omitempty"`
+	}
+	schema := generateSchemaForType(reflect.TypeOf(Params{}))
+
+	t.Run("accepts valid arguments", func(t *testing.T) {
+		args := map[string]interface{}{"city": "Oslo", "unit": "celsius", "days": 3, "code": nil, "filters": []interface{}{map[string]interface{}{"label": "rain"}}}
+		if errs := ValidateArguments(schema, args); len(errs) != 0 {
+			t.Errorf("Expected no errors, got %v", errs)
+		}
+	})
+
+	t.Run("reports every invalid field", func(t *testing.T) {
+		args := map[string]interface{}{
+			"unit":    "kelvin",
+			"days":    2.5,
+			"code":    "usa",
+			"extra":   true,
+			"filters": []interface{}{map[string]interface{}{"label": 7}},
+		}
+		want := []ArgumentError{
+			{Field: "city", Message: "is required"},
+			{Field: "code", Message: "must match the pattern ^[A-Z]{2}$"},
+			{Field: "days", Message: "must be an integer, got number"},
+			{Field: "extra", Message: "is not a known field"},
+			{Field: "filters[0].label", Message: "must be a string, got integer"},
+			{Field: "unit", Message: `must be one of "celsius", "fahrenheit"`},
+		}
+		if errs := ValidateArguments(schema, args); !reflect.DeepEqual(errs, want) {
+			t.Errorf("Unexpected errors:\n got %v\nwant %v", errs, want)
+		}
+	})
+
+	t.Run("checks bounds", func(t *testing.T) {
+		errs := ValidateArguments(schema, map[string]interface{}{"city": "Oslo", "days": 30})
+		if len(errs) != 1 || errs[0].String() != "days: must be at most 14" {
+			t.Errorf("Unexpected errors %v", errs)
+		}
+	})
+
+	t.Run("treats missing arguments as an empty object", func(t *testing.T) {
+		errs := ValidateArguments(schema, nil)
+		if len(errs) != 2 || errs[0].Field != "city" || errs[1].Field != "days" {
+			t.Errorf("Unexpected errors %v", errs)
+		}
+		if errs := ValidateArguments(schema, "oops"); len(errs) != 1 || errs[0].Message != "must be an object, got string" {
+			t.Errorf("Unexpected errors %v", errs)
+		}
+	})
+}
+
+func TestDefineTool_ArgumentValidation(t *testing.T) {
+	type Params struct {
+		Unit string `json:"unit" jsonschema:"enum=celsius|fahrenheit"`
+	}
+	called := false
+	handler := func(params Params, inv ToolInvocation) (string, error) {
+		called = true
+		return "ok", nil
+	}
+
+	t.Run("returns an invalid arguments result", func(t *testing.T) {
+		tool := DefineTool("convert", "Convert", handler)
+		result, err := tool.Handler(ToolInvocation{ToolName: "convert", Arguments: map[string]interface{}{"unit": "kelvin"}})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if called {
+			t.Error("Expected the handler not to be called")
+		}
+		if result.ResultType != "failure" || result.ToolTelemetry["errorType"] != "invalid_arguments" {
+			t.Errorf("Unexpected result %+v", result)
+		}
+		if !strings.Contains(result.TextResultForLLM, `- unit: must be one of "celsius", "fahrenheit"`) {
+			t.Errorf("Unexpected TextResultForLLM %q", result.TextResultForLLM)
+		}
+		fieldErrors, _ := result.ToolTelemetry["fieldErrors"].([]interface{})
+		if len(fieldErrors) != 1 || fieldErrors[0].(map[string]interface{})["field"] != "unit" {
+			t.Errorf("Unexpected field errors %v", result.ToolTelemetry["fieldErrors"])
+		}
+	})
+
+	t.Run("validates after argument transformers", func(t *testing.T) {
+		tool := DefineTool("convert", "Convert", handler, WithArgumentTransformers(DefaultArgument("unit", "celsius")))
+		result, err := tool.Handler(ToolInvocation{ToolName: "convert", Arguments: map[string]interface{}{}})
+		if err != nil || result.TextResultForLLM != "ok" {
+			t.Errorf("Unexpected result %+v, %v", result, err)
+		}
+	})
+
+	t.Run("can be turned off", func(t *testing.T) {
+		called = false
+		tool := DefineTool("convert", "Convert", handler, WithoutArgumentValidation())
+		if _, err := tool.Handler(ToolInvocation{Arguments: map[string]interface{}{"unit": "kelvin"}}); err != nil || !called {
+			t.Errorf("Expected the handler to be called, got %v", err)
+		}
+	})
+}
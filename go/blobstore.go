@@ -0,0 +1,170 @@
+package copilot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+)
+
+// DefaultBlobPartSize is the part size used by [NewBlobPartWriter] when none is
+// given. It satisfies the part size constraints of the supported object stores.
+const DefaultBlobPartSize = 8 << 20
+
+// BlobStore writes objects to blob storage such as Amazon S3, Google Cloud
+// Storage, or Azure Blob Storage. Implementations are provided by the
+// blobstore/s3, blobstore/gcs, and blobstore/azblob subpackages.
+type BlobStore interface {
+	// NewWriter starts writing the object at key. The object is created, or
+	// replaced, when the writer is closed.
+	NewWriter(ctx context.Context, key string) (BlobWriter, error)
+}
+
+// BlobWriter streams an object to a [BlobStore]. Data is uploaded in parts as it
+// is written, so an object of any size can be written without buffering it in
+// full or staging it on local disk.
+type BlobWriter interface {
+	io.Writer
+	// Close uploads any buffered data and completes the object.
+	Close() error
+	// Abort discards the upload. The object is not created.
+	Abort() error
+}
+
+// BlobPartUploader uploads the parts of a single object. It is the extension
+// point used by [NewBlobPartWriter] to implement a [BlobWriter] for an object
+// store's multipart or resumable upload API.
+type BlobPartUploader interface {
+	// UploadPart uploads part number (starting at 1). Parts are uploaded in
+	// order, one at a time. Every part except the last is exactly the writer's
+	// part size; the last part may be smaller or empty, and once it is uploaded
+	// the object must be complete.
+	UploadPart(ctx context.Context, number int, data []byte, last bool) error
+	// Abort discards the parts uploaded so far.
+	Abort(ctx context.Context) error
+}
+
+// ErrBlobWriterClosed is returned when writing to a [BlobWriter] that has been
+// closed or aborted.
+var ErrBlobWriterClosed = errors.New("blob writer is closed")
+
+type blobPart struct {
+	number int
+	data   []byte
+	last   bool
+}
+
+type blobPartWriter struct {
+	ctx      context.Context
+	uploader BlobPartUploader
+	partSize int
+
+	mu     sync.Mutex
+	buf    []byte
+	next   int
+	closed bool
+	parts  chan blobPart
+	done   chan struct{}
+
+	errMux sync.Mutex
+	err    error
+}
+
+// NewBlobPartWriter returns a [BlobWriter] that splits the data written to it
+// into parts of partSize bytes and uploads them with uploader. Parts are
+// uploaded in a background goroutine; Write only blocks when a part is waiting
+// behind one that is still uploading. A partSize of zero or less uses
+// [DefaultBlobPartSize].
+//
+// If a part fails to upload, later writes and Close return the error, and Close
+// aborts the upload.
+func NewBlobPartWriter(ctx context.Context, uploader BlobPartUploader, partSize int) BlobWriter {
+	if partSize <= 0 {
+		partSize = DefaultBlobPartSize
+	}
+	w := &blobPartWriter{
+		ctx:      ctx,
+		uploader: uploader,
+		partSize: partSize,
+		next:     1,
+		parts:    make(chan blobPart, 1),
+		done:     make(chan struct{}),
+	}
+	go w.upload()
+	return w
+}
+
+func (w *blobPartWriter) upload() {
+	defer close(w.done)
+	for part := range w.parts {
+		if w.failed() != nil {
+			continue
+		}
+		if err := w.uploader.UploadPart(w.ctx, part.number, part.data, part.last); err != nil {
+			w.errMux.Lock()
+			w.err = fmt.Errorf("failed to upload part %d: %w", part.number, err)
+			w.errMux.Unlock()
+		}
+	}
+}
+
+func (w *blobPartWriter) failed() error {
+	w.errMux.Lock()
+	defer w.errMux.Unlock()
+	return w.err
+}
+
+func (w *blobPartWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.closed {
+		return 0, ErrBlobWriterClosed
+	}
+	if err := w.failed(); err != nil {
+		return 0, err
+	}
+
+	w.buf = append(w.buf, p...)
+	for len(w.buf) >= w.partSize {
+		part := w.buf[:w.partSize:w.partSize]
+		w.buf = append([]byte(nil), w.buf[w.partSize:]...)
+		w.parts <- blobPart{number: w.next, data: part}
+		w.next++
+	}
+	return len(p), nil
+}
+
+func (w *blobPartWriter) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.closed {
+		return ErrBlobWriterClosed
+	}
+	w.closed = true
+
+	w.parts <- blobPart{number: w.next, data: w.buf, last: true}
+	w.buf = nil
+	close(w.parts)
+	<-w.done
+
+	if err := w.failed(); err != nil {
+		w.uploader.Abort(w.ctx)
+		return err
+	}
+	return nil
+}
+
+func (w *blobPartWriter) Abort() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.closed {
+		return ErrBlobWriterClosed
+	}
+	w.closed = true
+
+	w.buf = nil
+	close(w.parts)
+	<-w.done
+	return w.uploader.Abort(w.ctx)
+}
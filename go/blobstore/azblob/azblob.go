@@ -0,0 +1,198 @@
+// Package azblob implements a [copilot.BlobStore] for Azure Blob Storage, by
+// staging blocks of a block blob and committing them as a block list. It only
+// depends on the standard library.
+//
+// Example:
+//
+//	store, err := azblob.New(azblob.Config{
+//	    AccountURL: "https://myaccount.blob.core.windows.net",
+//	    Container:  "transcripts",
+//	    SASToken:   os.Getenv("AZURE_STORAGE_SAS_TOKEN"),
+//	})
+package azblob
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	copilot "github.com/github/copilot-sdk/go"
+)
+
+// apiVersion is the Blob service REST API version requests are made with.
+const apiVersion = "2021-08-06"
+
+// maxBlocks is the maximum number of blocks in a block blob.
+const maxBlocks = 50000
+
+// Config configures a [Store]. Exactly one of SASToken and TokenSource must be
+// set.
+type Config struct {
+	// AccountURL is the Blob service endpoint of the storage account, such as
+	// https://myaccount.blob.core.windows.net. Required.
+	AccountURL string
+	// Container is the container blobs are written to. Required.
+	Container string
+	// SASToken is a shared access signature granting write access to the
+	// container, with or without the leading "?".
+	SASToken string
+	// TokenSource returns a Microsoft Entra ID access token for the
+	// https://storage.azure.com/ resource.
+	TokenSource func(ctx context.Context) (string, error)
+	// BlockSize is the size of every staged block but the last. Defaults to
+	// [copilot.DefaultBlobPartSize].
+	BlockSize int
+	// HTTPClient is the client used for requests. Defaults to [http.DefaultClient].
+	HTTPClient *http.Client
+}
+
+// Store is a [copilot.BlobStore] backed by an Azure Blob Storage container.
+type Store struct {
+	config Config
+	client *http.Client
+}
+
+// New creates a store for the configured container.
+func New(config Config) (*Store, error) {
+	if config.AccountURL == "" {
+		return nil, errors.New("azblob: AccountURL is required")
+	}
+	if config.Container == "" {
+		return nil, errors.New("azblob: Container is required")
+	}
+	if (config.SASToken == "") == (config.TokenSource == nil) {
+		return nil, errors.New("azblob: exactly one of SASToken and TokenSource is required")
+	}
+	if config.BlockSize == 0 {
+		config.BlockSize = copilot.DefaultBlobPartSize
+	}
+	if config.BlockSize < 0 {
+		return nil, errors.New("azblob: BlockSize must be positive")
+	}
+	config.AccountURL = strings.TrimSuffix(config.AccountURL, "/")
+	config.SASToken = strings.TrimPrefix(config.SASToken, "?")
+	client := config.HTTPClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+	return &Store{config: config, client: client}, nil
+}
+
+// blobURL returns the URL of the blob key with the given query parameters.
+func (s *Store) blobURL(key string, query url.Values) string {
+	segments := strings.Split(key, "/")
+	for i, segment := range segments {
+		segments[i] = url.PathEscape(segment)
+	}
+	u := s.config.AccountURL + "/" + url.PathEscape(s.config.Container) + "/" + strings.Join(segments, "/")
+	rawQuery := query.Encode()
+	if s.config.SASToken != "" {
+		rawQuery += "&" + s.config.SASToken
+	}
+	return u + "?" + rawQuery
+}
+
+func (s *Store) do(ctx context.Context, req *http.Request) error {
+	req.Header.Set("x-ms-version", apiVersion)
+	req.Header.Set("x-ms-date", time.Now().UTC().Format(http.TimeFormat))
+	if s.config.TokenSource != nil {
+		token, err := s.config.TokenSource(ctx)
+		if err != nil {
+			return fmt.Errorf("azblob: failed to get access token: %w", err)
+		}
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	resp, err := s.client.Do(req.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusCreated {
+		return responseError(resp.StatusCode, body)
+	}
+	return nil
+}
+
+func responseError(status int, body []byte) error {
+	var e struct {
+		Code    string `xml:"Code"`
+		Message string `xml:"Message"`
+	}
+	if xml.Unmarshal(body, &e) == nil && e.Code != "" {
+		return fmt.Errorf("azblob: %s: %s (HTTP %d)", e.Code, strings.TrimSpace(e.Message), status)
+	}
+	return fmt.Errorf("azblob: unexpected HTTP status %d", status)
+}
+
+// NewWriter implements [copilot.BlobStore]. Blocks are staged as they are
+// written, and the blob is created or replaced when the writer is closed.
+func (s *Store) NewWriter(ctx context.Context, key string) (copilot.BlobWriter, error) {
+	return copilot.NewBlobPartWriter(ctx, &uploader{store: s, key: key}, s.config.BlockSize), nil
+}
+
+// uploader stages the blocks of one blob.
+type uploader struct {
+	store  *Store
+	key    string
+	blocks []string
+}
+
+// blockID returns the ID of block number. All block IDs of a blob must have the
+// same length.
+func blockID(number int) string {
+	return base64.StdEncoding.EncodeToString([]byte(fmt.Sprintf("block-%06d", number)))
+}
+
+func (u *uploader) UploadPart(ctx context.Context, number int, data []byte, last bool) error {
+	if number > maxBlocks {
+		return fmt.Errorf("azblob: blob exceeds %d blocks; increase BlockSize", maxBlocks)
+	}
+	if len(data) > 0 {
+		id := blockID(number)
+		req, err := http.NewRequest(http.MethodPut,
+			u.store.blobURL(u.key, url.Values{"comp": {"block"}, "blockid": {id}}), bytes.NewReader(data))
+		if err != nil {
+			return err
+		}
+		if err := u.store.do(ctx, req); err != nil {
+			return err
+		}
+		u.blocks = append(u.blocks, id)
+	}
+	if !last {
+		return nil
+	}
+
+	var body bytes.Buffer
+	body.WriteString(xml.Header + "<BlockList>")
+	for _, id := range u.blocks {
+		fmt.Fprintf(&body, "<Latest>%s</Latest>", id)
+	}
+	body.WriteString("</BlockList>")
+
+	req, err := http.NewRequest(http.MethodPut, u.store.blobURL(u.key, url.Values{"comp": {"blocklist"}}), &body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/xml")
+	if err := u.store.do(ctx, req); err != nil {
+		return fmt.Errorf("failed to commit block list: %w", err)
+	}
+	return nil
+}
+
+// Abort discards nothing: staged blocks that are never committed are deleted by
+// the service after a week.
+func (u *uploader) Abort(ctx context.Context) error {
+	return nil
+}
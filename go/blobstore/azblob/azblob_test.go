@@ -0,0 +1,83 @@
+package azblob
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+type fakeService struct {
+	blocks    map[string]string
+	committed string
+	fail      bool
+}
+
+func (f *fakeService) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get("x-ms-version") == "" || r.URL.Query().Get("sig") != "secret" {
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+	if f.fail {
+		w.WriteHeader(http.StatusInternalServerError)
+		io.WriteString(w, `<?xml version="1.0" encoding="utf-8"?><Error><Code>InternalError</Code><Message>boom</Message></Error>`)
+		return
+	}
+	body, _ := io.ReadAll(r.Body)
+	switch r.URL.Query().Get("comp") {
+	case "block":
+		f.blocks[r.URL.Query().Get("blockid")] = string(body)
+	case "blocklist":
+		var blob strings.Builder
+		for _, entry := range strings.Split(string(body), "<Latest>")[1:] {
+			blob.WriteString(f.blocks[strings.SplitN(entry, "<", 2)[0]])
+		}
+		f.committed = blob.String()
+	}
+	w.WriteHeader(http.StatusCreated)
+}
+
+func TestStore(t *testing.T) {
+	fake := &fakeService{blocks: make(map[string]string)}
+	server := httptest.NewServer(fake)
+	defer server.Close()
+
+	store, err := New(Config{AccountURL: server.URL, Container: "c", SASToken: "?sv=2021&sig=secret", BlockSize: 4})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	t.Run("stages blocks and commits the block list", func(t *testing.T) {
+		w, _ := store.NewWriter(context.Background(), "sessions/a b.jsonl")
+		io.WriteString(w, "hello ")
+		io.WriteString(w, "world")
+		if err := w.Close(); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if fake.committed != "hello world" {
+			t.Errorf("Expected committed blob 'hello world', got %q", fake.committed)
+		}
+		if len(fake.blocks) != 3 {
+			t.Errorf("Expected 3 staged blocks, got %d", len(fake.blocks))
+		}
+	})
+
+	t.Run("returns service errors", func(t *testing.T) {
+		fake.fail = true
+		defer func() { fake.fail = false }()
+		w, _ := store.NewWriter(context.Background(), "b.jsonl")
+		io.WriteString(w, "hi")
+		err := w.Close()
+		if err == nil || !strings.Contains(err.Error(), "InternalError: boom") {
+			t.Errorf("Expected a service error, got %v", err)
+		}
+	})
+
+	t.Run("validates the configuration", func(t *testing.T) {
+		if _, err := New(Config{AccountURL: server.URL, Container: "c"}); err == nil {
+			t.Error("Expected an error without credentials")
+		}
+	})
+}
@@ -0,0 +1,188 @@
+// Package gcs implements a [copilot.BlobStore] for Google Cloud Storage, using
+// resumable uploads. It only depends on the standard library.
+//
+// Example:
+//
+//	// ts is an oauth2.TokenSource, for example from google.DefaultTokenSource.
+//	store, err := gcs.New(gcs.Config{
+//	    Bucket: "my-transcripts",
+//	    TokenSource: func(ctx context.Context) (string, error) {
+//	        token, err := ts.Token()
+//	        if err != nil {
+//	            return "", err
+//	        }
+//	        return token.AccessToken, nil
+//	    },
+//	})
+package gcs
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	copilot "github.com/github/copilot-sdk/go"
+)
+
+// ChunkAlignment is the granularity of resumable upload chunks: every chunk but
+// the last must be a multiple of it.
+const ChunkAlignment = 256 << 10
+
+const defaultEndpoint = "https://storage.googleapis.com"
+
+// statusResumeIncomplete is returned for every accepted chunk but the last.
+const statusResumeIncomplete = 308
+
+// statusClientClosed is returned when a resumable upload is canceled.
+const statusClientClosed = 499
+
+// Config configures a [Store].
+type Config struct {
+	// Bucket is the bucket objects are written to. Required.
+	Bucket string
+	// TokenSource returns an OAuth 2.0 access token with a storage scope.
+	// Required.
+	TokenSource func(ctx context.Context) (string, error)
+	// Endpoint overrides the service endpoint, for example for an emulator.
+	// Defaults to https://storage.googleapis.com.
+	Endpoint string
+	// ChunkSize is the resumable upload chunk size. Defaults to
+	// [copilot.DefaultBlobPartSize]; it must be a multiple of [ChunkAlignment].
+	ChunkSize int
+	// HTTPClient is the client used for requests. Defaults to [http.DefaultClient].
+	HTTPClient *http.Client
+}
+
+// Store is a [copilot.BlobStore] backed by a Cloud Storage bucket.
+type Store struct {
+	config Config
+	client *http.Client
+}
+
+// New creates a store for the configured bucket.
+func New(config Config) (*Store, error) {
+	if config.Bucket == "" {
+		return nil, errors.New("gcs: Bucket is required")
+	}
+	if config.TokenSource == nil {
+		return nil, errors.New("gcs: TokenSource is required")
+	}
+	if config.Endpoint == "" {
+		config.Endpoint = defaultEndpoint
+	}
+	if config.ChunkSize == 0 {
+		config.ChunkSize = copilot.DefaultBlobPartSize
+	}
+	if config.ChunkSize <= 0 || config.ChunkSize%ChunkAlignment != 0 {
+		return nil, fmt.Errorf("gcs: ChunkSize must be a positive multiple of %d bytes", ChunkAlignment)
+	}
+	client := config.HTTPClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+	return &Store{config: config, client: client}, nil
+}
+
+// do sends an authorized request. It returns the response if its status is one
+// of expected, and an error otherwise.
+func (s *Store) do(ctx context.Context, req *http.Request, expected ...int) (*http.Response, error) {
+	token, err := s.config.TokenSource(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("gcs: failed to get access token: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	resp, err := s.client.Do(req.WithContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	for _, status := range expected {
+		if resp.StatusCode == status {
+			return resp, nil
+		}
+	}
+	return nil, responseError(resp.StatusCode, body)
+}
+
+func responseError(status int, body []byte) error {
+	var e struct {
+		Error struct {
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	if json.Unmarshal(body, &e) == nil && e.Error.Message != "" {
+		return fmt.Errorf("gcs: %s (HTTP %d)", e.Error.Message, status)
+	}
+	return fmt.Errorf("gcs: unexpected HTTP status %d", status)
+}
+
+// NewWriter implements [copilot.BlobStore] by starting a resumable upload.
+func (s *Store) NewWriter(ctx context.Context, key string) (copilot.BlobWriter, error) {
+	u := fmt.Sprintf("%s/upload/storage/v1/b/%s/o?uploadType=resumable&name=%s",
+		strings.TrimSuffix(s.config.Endpoint, "/"), url.PathEscape(s.config.Bucket), url.QueryEscape(key))
+	req, err := http.NewRequest(http.MethodPost, u, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := s.do(ctx, req, http.StatusOK, http.StatusCreated)
+	if err != nil {
+		return nil, fmt.Errorf("failed to start resumable upload: %w", err)
+	}
+	sessionURI := resp.Header.Get("Location")
+	if sessionURI == "" {
+		return nil, errors.New("failed to start resumable upload: missing Location header")
+	}
+	return copilot.NewBlobPartWriter(ctx, &uploader{store: s, sessionURI: sessionURI}, s.config.ChunkSize), nil
+}
+
+// uploader uploads the chunks of one resumable upload.
+type uploader struct {
+	store      *Store
+	sessionURI string
+	offset     int64
+}
+
+func (u *uploader) UploadPart(ctx context.Context, number int, data []byte, last bool) error {
+	var contentRange string
+	switch {
+	case last && len(data) == 0:
+		contentRange = fmt.Sprintf("bytes */%d", u.offset)
+	case last:
+		contentRange = fmt.Sprintf("bytes %d-%d/%d", u.offset, u.offset+int64(len(data))-1, u.offset+int64(len(data)))
+	default:
+		contentRange = fmt.Sprintf("bytes %d-%d/*", u.offset, u.offset+int64(len(data))-1)
+	}
+
+	req, err := http.NewRequest(http.MethodPut, u.sessionURI, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Range", contentRange)
+
+	expected := []int{statusResumeIncomplete}
+	if last {
+		expected = []int{http.StatusOK, http.StatusCreated}
+	}
+	if _, err := u.store.do(ctx, req, expected...); err != nil {
+		return err
+	}
+	u.offset += int64(len(data))
+	return nil
+}
+
+func (u *uploader) Abort(ctx context.Context) error {
+	req, err := http.NewRequest(http.MethodDelete, u.sessionURI, nil)
+	if err != nil {
+		return err
+	}
+	_, err = u.store.do(ctx, req, statusClientClosed, http.StatusOK, http.StatusNoContent)
+	return err
+}
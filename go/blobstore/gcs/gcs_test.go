@@ -0,0 +1,96 @@
+package gcs
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestStore(t *testing.T) {
+	var ranges []string
+	var received strings.Builder
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		switch r.Method {
+		case http.MethodPost:
+			if r.URL.Path != "/upload/storage/v1/b/bucket/o" || r.URL.Query().Get("name") != "sessions/a.jsonl" {
+				t.Errorf("Unexpected initiate request %s", r.URL)
+			}
+			w.Header().Set("Location", server.URL+"/session/1")
+		case http.MethodPut:
+			body, _ := io.ReadAll(r.Body)
+			received.Write(body)
+			contentRange := r.Header.Get("Content-Range")
+			ranges = append(ranges, contentRange)
+			if strings.HasSuffix(contentRange, "/*") {
+				w.WriteHeader(statusResumeIncomplete)
+			}
+		case http.MethodDelete:
+			w.WriteHeader(statusClientClosed)
+		}
+	}))
+	defer server.Close()
+
+	store, err := New(Config{
+		Bucket:      "bucket",
+		Endpoint:    server.URL,
+		ChunkSize:   ChunkAlignment,
+		TokenSource: func(context.Context) (string, error) { return "token", nil },
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	t.Run("uploads aligned chunks and finalizes the object", func(t *testing.T) {
+		ranges = nil
+		received.Reset()
+		w, err := store.NewWriter(context.Background(), "sessions/a.jsonl")
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		data := strings.Repeat("x", ChunkAlignment+10)
+		io.WriteString(w, data)
+		if err := w.Close(); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		want := []string{"bytes 0-262143/*", "bytes 262144-262153/262154"}
+		if len(ranges) != 2 || ranges[0] != want[0] || ranges[1] != want[1] {
+			t.Errorf("Unexpected ranges %v", ranges)
+		}
+		if received.String() != data {
+			t.Error("Expected the uploaded data to match")
+		}
+	})
+
+	t.Run("finalizes with an empty chunk on an aligned size", func(t *testing.T) {
+		ranges = nil
+		w, _ := store.NewWriter(context.Background(), "sessions/a.jsonl")
+		io.WriteString(w, strings.Repeat("x", ChunkAlignment))
+		if err := w.Close(); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if len(ranges) != 2 || ranges[1] != "bytes */262144" {
+			t.Errorf("Unexpected ranges %v", ranges)
+		}
+	})
+
+	t.Run("aborts the upload", func(t *testing.T) {
+		w, _ := store.NewWriter(context.Background(), "sessions/a.jsonl")
+		if err := w.Abort(); err != nil {
+			t.Errorf("Unexpected error: %v", err)
+		}
+	})
+
+	t.Run("validates the configuration", func(t *testing.T) {
+		if _, err := New(Config{Bucket: "b", TokenSource: store.config.TokenSource, ChunkSize: 1000}); err == nil {
+			t.Error("Expected an error for an unaligned chunk size")
+		}
+	})
+}
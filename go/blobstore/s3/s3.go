@@ -0,0 +1,233 @@
+// Package s3 implements a [copilot.BlobStore] for Amazon S3 and S3-compatible
+// object stores, using multipart uploads. It only depends on the standard
+// library.
+//
+// Example:
+//
+//	store, err := s3.New(s3.Config{
+//	    Bucket:          "my-transcripts",
+//	    Region:          "us-east-1",
+//	    AccessKeyID:     os.Getenv("AWS_ACCESS_KEY_ID"),
+//	    SecretAccessKey: os.Getenv("AWS_SECRET_ACCESS_KEY"),
+//	    SessionToken:    os.Getenv("AWS_SESSION_TOKEN"),
+//	})
+//	if err != nil {
+//	    log.Fatal(err)
+//	}
+//	sink, err := copilot.NewBlobTranscriptSink(ctx, session, store, session.SessionID+".jsonl")
+package s3
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	copilot "github.com/github/copilot-sdk/go"
+)
+
+// MinPartSize is the smallest part size S3 accepts for every part but the last.
+const MinPartSize = 5 << 20
+
+// maxParts is the maximum number of parts of a multipart upload.
+const maxParts = 10000
+
+// Config configures a [Store].
+type Config struct {
+	// Bucket is the bucket objects are written to. Required.
+	Bucket string
+	// Region is the bucket's region, for example "us-east-1". Required.
+	Region string
+	// AccessKeyID, SecretAccessKey, and the optional SessionToken are the
+	// credentials requests are signed with. Required.
+	AccessKeyID     string
+	SecretAccessKey string
+	SessionToken    string
+	// Endpoint overrides the service endpoint, for S3-compatible stores such as
+	// MinIO, for example "https://minio.internal:9000". Requests to a custom
+	// endpoint use path-style URLs.
+	Endpoint string
+	// PartSize is the multipart upload part size. Defaults to
+	// [copilot.DefaultBlobPartSize]; it must be at least [MinPartSize].
+	PartSize int
+	// HTTPClient is the client used for requests. Defaults to [http.DefaultClient].
+	HTTPClient *http.Client
+}
+
+// Store is a [copilot.BlobStore] backed by an S3 bucket.
+type Store struct {
+	config Config
+	creds  credentials
+	client *http.Client
+	now    func() time.Time
+}
+
+// New creates a store for the configured bucket.
+func New(config Config) (*Store, error) {
+	if config.Bucket == "" || config.Region == "" {
+		return nil, errors.New("s3: Bucket and Region are required")
+	}
+	if config.AccessKeyID == "" || config.SecretAccessKey == "" {
+		return nil, errors.New("s3: AccessKeyID and SecretAccessKey are required")
+	}
+	if config.PartSize == 0 {
+		config.PartSize = copilot.DefaultBlobPartSize
+	}
+	if config.PartSize < MinPartSize {
+		return nil, fmt.Errorf("s3: PartSize must be at least %d bytes", MinPartSize)
+	}
+	client := config.HTTPClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+	return &Store{
+		config: config,
+		creds: credentials{
+			accessKeyID:     config.AccessKeyID,
+			secretAccessKey: config.SecretAccessKey,
+			sessionToken:    config.SessionToken,
+		},
+		client: client,
+		now:    time.Now,
+	}, nil
+}
+
+// objectURL returns the URL of key, with the given query.
+func (s *Store) objectURL(key string, query url.Values) string {
+	var base string
+	if s.config.Endpoint != "" {
+		base = strings.TrimSuffix(s.config.Endpoint, "/") + "/" + s.config.Bucket
+	} else {
+		base = fmt.Sprintf("https://%s.s3.%s.amazonaws.com", s.config.Bucket, s.config.Region)
+	}
+	u := base + "/" + escapeKey(key)
+	if len(query) > 0 {
+		// Signature Version 4 requires %20 rather than + for spaces.
+		u += "?" + strings.ReplaceAll(query.Encode(), "+", "%20")
+	}
+	return u
+}
+
+// escapeKey escapes each segment of an object key, keeping the slashes.
+func escapeKey(key string) string {
+	segments := strings.Split(key, "/")
+	for i, segment := range segments {
+		segments[i] = uriEncode(segment)
+	}
+	return strings.Join(segments, "/")
+}
+
+// do sends a signed request and returns the response body of a 2xx response.
+func (s *Store) do(ctx context.Context, method, rawURL string, body []byte) (*http.Response, []byte, error) {
+	req, err := http.NewRequestWithContext(ctx, method, rawURL, bytes.NewReader(body))
+	if err != nil {
+		return nil, nil, err
+	}
+	req.ContentLength = int64(len(body))
+	signRequest(req, s.creds, s.config.Region, hashHex(body), s.now())
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer resp.Body.Close()
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, nil, err
+	}
+	if resp.StatusCode/100 != 2 {
+		return nil, nil, responseError(resp.StatusCode, respBody)
+	}
+	// CompleteMultipartUpload can report an error in a 200 response.
+	if bytes.Contains(respBody, []byte("<Error>")) {
+		return nil, nil, responseError(resp.StatusCode, respBody)
+	}
+	return resp, respBody, nil
+}
+
+func responseError(status int, body []byte) error {
+	var e struct {
+		Code    string `xml:"Code"`
+		Message string `xml:"Message"`
+	}
+	if xml.Unmarshal(body, &e) == nil && e.Code != "" {
+		return fmt.Errorf("s3: %s: %s (HTTP %d)", e.Code, e.Message, status)
+	}
+	return fmt.Errorf("s3: unexpected HTTP status %d", status)
+}
+
+// NewWriter implements [copilot.BlobStore] by starting a multipart upload.
+func (s *Store) NewWriter(ctx context.Context, key string) (copilot.BlobWriter, error) {
+	_, body, err := s.do(ctx, http.MethodPost, s.objectURL(key, url.Values{"uploads": {""}}), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to start multipart upload: %w", err)
+	}
+	var result struct {
+		UploadID string `xml:"UploadId"`
+	}
+	if err := xml.Unmarshal(body, &result); err != nil || result.UploadID == "" {
+		return nil, errors.New("failed to start multipart upload: missing UploadId")
+	}
+	u := &uploader{store: s, key: key, uploadID: result.UploadID}
+	return copilot.NewBlobPartWriter(ctx, u, s.config.PartSize), nil
+}
+
+// uploader uploads the parts of one multipart upload.
+type uploader struct {
+	store    *Store
+	key      string
+	uploadID string
+	mu       sync.Mutex
+	parts    []completedPart
+}
+
+type completedPart struct {
+	PartNumber int    `xml:"PartNumber"`
+	ETag       string `xml:"ETag"`
+}
+
+func (u *uploader) UploadPart(ctx context.Context, number int, data []byte, last bool) error {
+	if number > maxParts {
+		return fmt.Errorf("s3: object exceeds %d parts; increase PartSize", maxParts)
+	}
+	// An empty last part is only needed when the object has no other parts.
+	if !last || len(data) > 0 || number == 1 {
+		query := url.Values{"partNumber": {strconv.Itoa(number)}, "uploadId": {u.uploadID}}
+		resp, _, err := u.store.do(ctx, http.MethodPut, u.store.objectURL(u.key, query), data)
+		if err != nil {
+			return err
+		}
+		u.mu.Lock()
+		u.parts = append(u.parts, completedPart{PartNumber: number, ETag: resp.Header.Get("ETag")})
+		u.mu.Unlock()
+	}
+	if !last {
+		return nil
+	}
+
+	u.mu.Lock()
+	complete := struct {
+		XMLName xml.Name        `xml:"CompleteMultipartUpload"`
+		Parts   []completedPart `xml:"Part"`
+	}{Parts: u.parts}
+	u.mu.Unlock()
+	body, err := xml.Marshal(complete)
+	if err != nil {
+		return err
+	}
+	_, _, err = u.store.do(ctx, http.MethodPost, u.store.objectURL(u.key, url.Values{"uploadId": {u.uploadID}}), body)
+	return err
+}
+
+func (u *uploader) Abort(ctx context.Context) error {
+	_, _, err := u.store.do(ctx, http.MethodDelete, u.store.objectURL(u.key, url.Values{"uploadId": {u.uploadID}}), nil)
+	return err
+}
@@ -0,0 +1,124 @@
+package s3
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// fakeS3 records multipart upload requests.
+type fakeS3 struct {
+	mu       sync.Mutex
+	requests []string
+	parts    map[string]string
+	complete string
+}
+
+func (f *fakeS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if !strings.HasPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256 Credential=AKID/") {
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+	query := r.URL.Query()
+	f.requests = append(f.requests, r.Method+" "+r.URL.Path)
+	switch {
+	case r.Method == http.MethodPost && query.Has("uploads"):
+		io.WriteString(w, `<InitiateMultipartUploadResult><UploadId>up-1</UploadId></InitiateMultipartUploadResult>`)
+	case r.Method == http.MethodPut:
+		part := query.Get("partNumber")
+		f.parts[part] = string(body)
+		w.Header().Set("ETag", `"etag-`+part+`"`)
+	case r.Method == http.MethodPost:
+		f.complete = string(body)
+		io.WriteString(w, `<CompleteMultipartUploadResult></CompleteMultipartUploadResult>`)
+	case r.Method == http.MethodDelete:
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func newTestStore(t *testing.T, handler http.Handler) *Store {
+	t.Helper()
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+	store, err := New(Config{Bucket: "bucket", Region: "us-east-1", AccessKeyID: "AKID", SecretAccessKey: "secret", Endpoint: server.URL})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	store.config.PartSize = 4
+	return store
+}
+
+func TestStore(t *testing.T) {
+	t.Run("uploads parts and completes the object", func(t *testing.T) {
+		fake := &fakeS3{parts: map[string]string{}}
+		store := newTestStore(t, fake)
+
+		w, err := store.NewWriter(context.Background(), "sessions/a b.jsonl")
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		io.WriteString(w, "hello ")
+		io.WriteString(w, "world")
+		if err := w.Close(); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		if fake.parts["1"] != "hell" || fake.parts["2"] != "o wo" || fake.parts["3"] != "rld" {
+			t.Errorf("Unexpected parts %v", fake.parts)
+		}
+		if !strings.Contains(fake.complete, "<Part><PartNumber>3</PartNumber><ETag>&#34;etag-3&#34;</ETag></Part>") {
+			t.Errorf("Unexpected completion body %s", fake.complete)
+		}
+		if fake.requests[0] != "POST /bucket/sessions/a b.jsonl" {
+			t.Errorf("Unexpected first request %q", fake.requests[0])
+		}
+	})
+
+	t.Run("skips an empty last part", func(t *testing.T) {
+		fake := &fakeS3{parts: map[string]string{}}
+		store := newTestStore(t, fake)
+		w, _ := store.NewWriter(context.Background(), "k")
+		io.WriteString(w, "abcd")
+		w.Close()
+		if len(fake.parts) != 1 || strings.Count(fake.complete, "<Part>") != 1 {
+			t.Errorf("Expected a single part, got %v and %s", fake.parts, fake.complete)
+		}
+	})
+
+	t.Run("reports S3 errors and aborts", func(t *testing.T) {
+		fake := &fakeS3{parts: map[string]string{}}
+		store := newTestStore(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Method == http.MethodPut {
+				w.WriteHeader(http.StatusBadRequest)
+				io.WriteString(w, `<Error><Code>EntityTooSmall</Code><Message>too small</Message></Error>`)
+				return
+			}
+			fake.ServeHTTP(w, r)
+		}))
+		w, _ := store.NewWriter(context.Background(), "k")
+		io.WriteString(w, "abc")
+		err := w.Close()
+		if err == nil || !strings.Contains(err.Error(), "EntityTooSmall") {
+			t.Errorf("Expected the S3 error, got %v", err)
+		}
+		if last := fake.requests[len(fake.requests)-1]; !strings.HasPrefix(last, "DELETE") {
+			t.Errorf("Expected the upload to be aborted, got %v", fake.requests)
+		}
+	})
+
+	t.Run("validates the configuration", func(t *testing.T) {
+		if _, err := New(Config{Bucket: "b", Region: "r", AccessKeyID: "a", SecretAccessKey: "s", PartSize: 1024}); err == nil {
+			t.Error("Expected an error for a part size below the minimum")
+		}
+		if _, err := New(Config{Bucket: "b", Region: "r"}); err == nil {
+			t.Error("Expected an error without credentials")
+		}
+	})
+}
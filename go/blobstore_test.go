@@ -0,0 +1,88 @@
+package copilot
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+)
+
+type fakePartUploader struct {
+	mu      sync.Mutex
+	parts   []string
+	last    []bool
+	aborted bool
+	failAt  int
+}
+
+func (u *fakePartUploader) UploadPart(ctx context.Context, number int, data []byte, last bool) error {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	if number != len(u.parts)+1 {
+		return errors.New("parts out of order")
+	}
+	if number == u.failAt {
+		return errors.New("boom")
+	}
+	u.parts = append(u.parts, string(data))
+	u.last = append(u.last, last)
+	return nil
+}
+
+func (u *fakePartUploader) Abort(ctx context.Context) error {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	u.aborted = true
+	return nil
+}
+
+func TestBlobPartWriter(t *testing.T) {
+	t.Run("splits data into parts of the part size", func(t *testing.T) {
+		uploader := &fakePartUploader{}
+		w := NewBlobPartWriter(context.Background(), uploader, 4)
+		io.WriteString(w, "ab")
+		io.WriteString(w, "cdefghij")
+		if err := w.Close(); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if strings.Join(uploader.parts, "|") != "abcd|efgh|ij" {
+			t.Errorf("Unexpected parts %q", uploader.parts)
+		}
+		if uploader.last[1] || !uploader.last[2] {
+			t.Errorf("Expected only the final part to be last, got %v", uploader.last)
+		}
+	})
+
+	t.Run("uploads an empty last part", func(t *testing.T) {
+		uploader := &fakePartUploader{}
+		w := NewBlobPartWriter(context.Background(), uploader, 4)
+		io.WriteString(w, "abcd")
+		w.Close()
+		if len(uploader.parts) != 2 || uploader.parts[1] != "" || !uploader.last[1] {
+			t.Errorf("Expected an empty last part, got %q", uploader.parts)
+		}
+	})
+
+	t.Run("aborts when a part fails", func(t *testing.T) {
+		uploader := &fakePartUploader{failAt: 1}
+		w := NewBlobPartWriter(context.Background(), uploader, 2)
+		io.WriteString(w, "abcd")
+		err := w.Close()
+		if err == nil || !strings.Contains(err.Error(), "part 1: boom") {
+			t.Errorf("Expected the part error, got %v", err)
+		}
+		if !uploader.aborted {
+			t.Error("Expected the upload to be aborted")
+		}
+	})
+
+	t.Run("rejects writes after close", func(t *testing.T) {
+		w := NewBlobPartWriter(context.Background(), &fakePartUploader{}, 2)
+		w.Close()
+		if _, err := io.WriteString(w, "a"); !errors.Is(err, ErrBlobWriterClosed) {
+			t.Errorf("Expected ErrBlobWriterClosed, got %v", err)
+		}
+	})
+}
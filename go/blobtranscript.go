@@ -0,0 +1,117 @@
+package copilot
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+)
+
+// BlobTranscriptSink streams the events of a session to an object in a
+// [BlobStore] as they are dispatched, as JSON Lines: one [SessionEvent] per
+// line. Use [ReadTranscriptEvents] to read it back.
+//
+// The object is completed when the sink is closed, or when the session is
+// destroyed, including by [Client.Stop] at shutdown.
+//
+// Example:
+//
+//	store, _ := s3.New(s3.Config{Bucket: "transcripts", Region: "us-east-1", ...})
+//	sink, err := copilot.NewBlobTranscriptSink(ctx, session, store, "sessions/"+session.SessionID+".jsonl")
+//	if err != nil {
+//	    log.Fatal(err)
+//	}
+//	defer sink.Close()
+type BlobTranscriptSink struct {
+	writer      BlobWriter
+	unsubscribe func()
+	mu          sync.Mutex
+	closed      bool
+	err         error
+}
+
+// NewBlobTranscriptSink starts streaming session's events to key in store.
+func NewBlobTranscriptSink(ctx context.Context, session *Session, store BlobStore, key string) (*BlobTranscriptSink, error) {
+	writer, err := store.NewWriter(ctx, key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to start transcript upload: %w", err)
+	}
+	sink := &BlobTranscriptSink{writer: writer}
+	sink.unsubscribe = session.On(sink.record)
+	session.onDestroy(func() { sink.Close() })
+	return sink, nil
+}
+
+func (s *BlobTranscriptSink) record(event SessionEvent) {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return
+	}
+	data = append(data, '\n')
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed || s.err != nil {
+		return
+	}
+	if _, err := s.writer.Write(data); err != nil {
+		s.err = err
+	}
+}
+
+// Close stops recording and completes the object. It returns the first error
+// encountered while uploading the transcript. Calling Close again returns nil.
+func (s *BlobTranscriptSink) Close() error {
+	s.unsubscribe()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return nil
+	}
+	s.closed = true
+	if s.err != nil {
+		s.writer.Abort()
+		return fmt.Errorf("failed to upload transcript: %w", s.err)
+	}
+	if err := s.writer.Close(); err != nil {
+		return fmt.Errorf("failed to upload transcript: %w", err)
+	}
+	return nil
+}
+
+// Abort stops recording and discards the upload.
+func (s *BlobTranscriptSink) Abort() error {
+	s.unsubscribe()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return nil
+	}
+	s.closed = true
+	return s.writer.Abort()
+}
+
+// ReadTranscriptEvents reads the events written by a [BlobTranscriptSink].
+func ReadTranscriptEvents(r io.Reader) ([]SessionEvent, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxFrameSize)
+	var events []SessionEvent
+	for line := 1; scanner.Scan(); line++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		event, err := UnmarshalSessionEvent(scanner.Bytes())
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode transcript line %d: %w", line, err)
+		}
+		events = append(events, event)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read transcript: %w", err)
+	}
+	return events, nil
+}
@@ -0,0 +1,90 @@
+package copilot
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// memoryBlobStore keeps the objects written to it in memory.
+type memoryBlobStore struct {
+	mu      sync.Mutex
+	objects map[string]*strings.Builder
+	closed  map[string]bool
+}
+
+func (s *memoryBlobStore) NewWriter(ctx context.Context, key string) (BlobWriter, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.objects[key] = &strings.Builder{}
+	return &memoryBlobWriter{store: s, key: key}, nil
+}
+
+func (s *memoryBlobStore) contents(key string) (string, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.objects[key].String(), s.closed[key]
+}
+
+type memoryBlobWriter struct {
+	store *memoryBlobStore
+	key   string
+}
+
+func (w *memoryBlobWriter) Write(p []byte) (int, error) {
+	w.store.mu.Lock()
+	defer w.store.mu.Unlock()
+	return w.store.objects[w.key].Write(p)
+}
+
+func (w *memoryBlobWriter) Close() error {
+	w.store.mu.Lock()
+	defer w.store.mu.Unlock()
+	w.store.closed[w.key] = true
+	return nil
+}
+
+func (w *memoryBlobWriter) Abort() error { return nil }
+
+func TestBlobTranscriptSink(t *testing.T) {
+	session, server := newFakeSession(t, func(server *fakeServer, method string, params map[string]interface{}) (map[string]interface{}, *JSONRPCError) {
+		return map[string]interface{}{}, nil
+	})
+	store := &memoryBlobStore{objects: make(map[string]*strings.Builder), closed: make(map[string]bool)}
+	if _, err := NewBlobTranscriptSink(context.Background(), session, store, "t.jsonl"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	server.emitEvent(UserMessage, "e1", map[string]interface{}{"content": "hi"})
+	server.emitEvent(AssistantMessage, "e2", map[string]interface{}{"content": "hello", "messageId": "m1"})
+
+	deadline := time.Now().Add(time.Second)
+	for {
+		data, _ := store.contents("t.jsonl")
+		if strings.Count(data, "\n") == 2 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Timed out waiting for events, got %q", data)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	if err := session.Destroy(); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	data, closed := store.contents("t.jsonl")
+	if !closed {
+		t.Error("Expected the transcript to be completed when the session is destroyed")
+	}
+
+	events, err := ReadTranscriptEvents(strings.NewReader(data))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(events) != 2 || events[0].ID != "e1" || events[1].Type != AssistantMessage || *events[1].Data.Content != "hello" {
+		t.Errorf("Unexpected events %+v", events)
+	}
+}
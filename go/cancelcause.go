@@ -0,0 +1,136 @@
+package copilot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// CancelReason is why a turn was aborted. See [CancelCause].
+type CancelReason string
+
+const (
+	// CancelUserInterrupt is the reason of turns aborted by [Session.Abort],
+	// such as when the user interrupts the assistant.
+	CancelUserInterrupt CancelReason = "user_interrupt"
+	// CancelTimeout is the reason of turns aborted because they ran too long.
+	CancelTimeout CancelReason = "timeout"
+	// CancelBudgetExceeded is the reason of turns aborted because they exceeded
+	// a budget, such as of tokens or cost.
+	CancelBudgetExceeded CancelReason = "budget_exceeded"
+	// CancelPolicyDenied is the reason of turns aborted because a policy denied
+	// an action.
+	CancelPolicyDenied CancelReason = "policy_denied"
+	// CancelServer is the reason of turns aborted by the server without a
+	// request from this client.
+	CancelServer CancelReason = "server"
+)
+
+// CancelCause is the cause of the cancellation of a tool handler's context when
+// its turn is aborted, as returned by [context.Cause]. Handlers can use the
+// reason to decide whether to roll back side effects, for example keeping the
+// work of a call interrupted by the user but undoing one denied by a policy.
+//
+// A CancelCause matches [ErrSessionAborted] with [errors.Is].
+type CancelCause struct {
+	Reason CancelReason
+	// Message optionally describes the cancellation, such as the policy that
+	// denied an action.
+	Message string
+}
+
+func (c *CancelCause) Error() string {
+	if c.Message == "" {
+		return ErrSessionAborted.Error()
+	}
+	return fmt.Sprintf("%s: %s", ErrSessionAborted, c.Message)
+}
+
+// Unwrap returns [ErrSessionAborted].
+func (c *CancelCause) Unwrap() error {
+	return ErrSessionAborted
+}
+
+// CancelCauseOf returns the cause of ctx's cancellation if its turn was
+// aborted.
+//
+// Example:
+//
+//	if cause, ok := copilot.CancelCauseOf(ctx); ok && cause.Reason == copilot.CancelPolicyDenied {
+//	    rollback()
+//	}
+func CancelCauseOf(ctx context.Context) (*CancelCause, bool) {
+	var cause *CancelCause
+	ok := errors.As(context.Cause(ctx), &cause)
+	return cause, ok
+}
+
+// AbortWithCause aborts the currently processing message like [Session.Abort],
+// canceling the context of tool calls in flight with cause and dispatching an
+// [SDKTurnAborted] event. A nil cause is a [CancelUserInterrupt].
+//
+// Example:
+//
+//	if spent > budget {
+//	    session.AbortWithCause(&copilot.CancelCause{Reason: copilot.CancelBudgetExceeded, Message: "cost budget spent"})
+//	}
+func (s *Session) AbortWithCause(cause *CancelCause) error {
+	if cause == nil {
+		cause = &CancelCause{Reason: CancelUserInterrupt}
+	}
+	s.abortMux.Lock()
+	s.pendingAbort = cause
+	s.abortMux.Unlock()
+	s.cancelTools(cause, false)
+	s.emitTurnAborted(cause)
+
+	params := map[string]interface{}{
+		"sessionId": s.SessionID,
+	}
+
+	_, err := s.client.Request("session.abort", params)
+	if err != nil {
+		s.abortMux.Lock()
+		s.pendingAbort = nil
+		s.abortMux.Unlock()
+		return fmt.Errorf("failed to abort session: %w", err)
+	}
+
+	return nil
+}
+
+// handleAbortEvent cancels the tool calls in flight when the server reports an
+// abort. An abort this client requested keeps the cause it was requested with.
+func (s *Session) handleAbortEvent() {
+	s.abortMux.Lock()
+	cause := s.pendingAbort
+	s.pendingAbort = nil
+	s.abortMux.Unlock()
+
+	if cause != nil {
+		s.cancelTools(cause, false)
+		return
+	}
+	cause = &CancelCause{Reason: CancelServer}
+	s.cancelTools(cause, false)
+	s.emitTurnAborted(cause)
+}
+
+func (s *Session) emitTurnAborted(cause *CancelCause) {
+	s.emit(SDKTurnAborted, map[string]interface{}{
+		"reason":  string(cause.Reason),
+		"message": cause.Message,
+	})
+}
+
+// TurnAbortCause returns the cause carried by an [SDKTurnAborted] event, and
+// false for other events.
+func TurnAbortCause(event SessionEvent) (*CancelCause, bool) {
+	if event.Type != SDKTurnAborted {
+		return nil, false
+	}
+	return &CancelCause{
+		Reason:  CancelReason(sdkEventString(event, "reason")),
+		Message: sdkEventString(event, "message"),
+	}, true
+}
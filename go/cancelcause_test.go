@@ -0,0 +1,137 @@
+package copilot
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+// recordAborts records the causes of a session's sdk.turn_aborted events. The
+// returned function waits for the session to become idle, after the server's
+// abort event has been handled, and returns them.
+func recordAborts(session *Session) func(t *testing.T) []*CancelCause {
+	var mu sync.Mutex
+	var causes []*CancelCause
+	idle := make(chan struct{}, 1)
+	session.On(func(event SessionEvent) {
+		if event.Type == SessionIdle {
+			idle <- struct{}{}
+		}
+		if cause, ok := TurnAbortCause(event); ok {
+			mu.Lock()
+			causes = append(causes, cause)
+			mu.Unlock()
+		}
+	})
+	return func(t *testing.T) []*CancelCause {
+		t.Helper()
+		select {
+		case <-idle:
+		case <-time.After(time.Second):
+			t.Fatal("Timed out waiting for the session to become idle")
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]*CancelCause(nil), causes...)
+	}
+}
+
+func TestCancelCause(t *testing.T) {
+	// startCancelAwareTool calls a tool that reports the cause of its
+	// cancellation.
+	startCancelAwareTool := func(t *testing.T, session *Session) <-chan *CancelCause {
+		t.Helper()
+		started := make(chan struct{})
+		causes := make(chan *CancelCause, 1)
+		session.registerTools([]Tool{DefineToolCtx("wait", "Wait until canceled",
+			func(ctx context.Context, params waitParams, inv ToolInvocation) (string, error) {
+				close(started)
+				<-ctx.Done()
+				cause, _ := CancelCauseOf(ctx)
+				causes <- cause
+				return "", context.Cause(ctx)
+			})})
+		client := &Client{sessions: map[string]*Session{"s1": session}}
+		go client.handleToolCallRequest(map[string]interface{}{
+			"sessionId":  "s1",
+			"toolCallId": "call-1",
+			"toolName":   "wait",
+			"arguments":  map[string]interface{}{"name": "x"},
+		})
+		<-started
+		return causes
+	}
+	waitFor := func(t *testing.T, causes <-chan *CancelCause) *CancelCause {
+		t.Helper()
+		select {
+		case cause := <-causes:
+			if cause == nil {
+				t.Fatal("Expected a *CancelCause")
+			}
+			return cause
+		case <-time.After(time.Second):
+			t.Fatal("Timed out waiting for the tool to be canceled")
+			return nil
+		}
+	}
+	okServer := func(server *fakeServer, method string, params map[string]interface{}) (map[string]interface{}, *JSONRPCError) {
+		return map[string]interface{}{}, nil
+	}
+
+	t.Run("AbortWithCause tells handlers and events why", func(t *testing.T) {
+		session, server := newFakeSession(t, okServer)
+		aborted := recordAborts(session)
+
+		causes := startCancelAwareTool(t, session)
+		if err := session.AbortWithCause(&CancelCause{Reason: CancelPolicyDenied, Message: "writes to prod are denied"}); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		cause := waitFor(t, causes)
+		if cause.Reason != CancelPolicyDenied || cause.Error() != "session aborted: writes to prod are denied" || !errors.Is(cause, ErrSessionAborted) {
+			t.Errorf("Unexpected cause %+v", cause)
+		}
+
+		// The server's report of the requested abort is not a second abort.
+		server.emitEvent(Abort, "e1", map[string]interface{}{"reason": "user"})
+		server.emitEvent(SessionIdle, "e2", map[string]interface{}{})
+		if causes := aborted(t); len(causes) != 1 || causes[0].Reason != CancelPolicyDenied || causes[0].Message != "writes to prod are denied" {
+			t.Errorf("Expected one sdk.turn_aborted event, got %+v", causes)
+		}
+	})
+
+	t.Run("Abort is a user interrupt", func(t *testing.T) {
+		session, _ := newFakeSession(t, okServer)
+		causes := startCancelAwareTool(t, session)
+		session.Abort()
+		if cause := waitFor(t, causes); cause.Reason != CancelUserInterrupt {
+			t.Errorf("Expected a user interrupt, got %+v", cause)
+		}
+	})
+
+	t.Run("aborts by the server", func(t *testing.T) {
+		session, server := newFakeSession(t, okServer)
+		aborted := recordAborts(session)
+		causes := startCancelAwareTool(t, session)
+		server.emitEvent(Abort, "e1", map[string]interface{}{"reason": "user"})
+		if cause := waitFor(t, causes); cause.Reason != CancelServer {
+			t.Errorf("Expected a server abort, got %+v", cause)
+		}
+		server.emitEvent(SessionIdle, "e2", map[string]interface{}{})
+		if causes := aborted(t); len(causes) != 1 || causes[0].Reason != CancelServer {
+			t.Errorf("Expected an sdk.turn_aborted event, got %+v", causes)
+		}
+	})
+
+	t.Run("other cancellations have no CancelCause", func(t *testing.T) {
+		ctx, cancel := context.WithCancelCause(context.Background())
+		cancel(ErrSessionDestroyed)
+		if _, ok := CancelCauseOf(ctx); ok {
+			t.Error("Expected no CancelCause")
+		}
+		if _, ok := CancelCauseOf(context.Background()); ok {
+			t.Error("Expected no CancelCause for a live context")
+		}
+	})
+}
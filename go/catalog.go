@@ -0,0 +1,529 @@
+package copilot
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// ToolCatalogVersion is the format version written by [NewToolCatalog].
+const ToolCatalogVersion = 1
+
+// ToolCatalog is a machine-readable snapshot of a tool set.
+//
+// Tools are ordered by name and their schemas are normalized, so the JSON form of
+// a catalog is stable across runs and suitable for checking into source control
+// or comparing between releases with [DiffToolCatalogs].
+type ToolCatalog struct {
+	Version int           `json:"version"`
+	Tools   []CatalogTool `json:"tools"`
+}
+
+// CatalogTool describes a single tool in a [ToolCatalog].
+type CatalogTool struct {
+	Name        string                 `json:"name"`
+	Description string                 `json:"description,omitempty"`
+	Parameters  map[string]interface{} `json:"parameters,omitempty"`
+}
+
+// NewToolCatalog builds a catalog from the given tools. Tools without a name are skipped.
+//
+// Example:
+//
+//	catalog, err := copilot.NewToolCatalog(tools)
+//	if err != nil {
+//	    log.Fatal(err)
+//	}
+//	data, _ := catalog.MarshalIndent()
+//	os.WriteFile("tools.catalog.json", data, 0644)
+func NewToolCatalog(tools []Tool) (*ToolCatalog, error) {
+	catalog := &ToolCatalog{
+		Version: ToolCatalogVersion,
+		Tools:   make([]CatalogTool, 0, len(tools)),
+	}
+	for _, tool := range tools {
+		if tool.Name == "" {
+			continue
+		}
+		params, err := normalizeSchema(tool.Parameters)
+		if err != nil {
+			return nil, fmt.Errorf("failed to normalize schema for tool %s: %w", tool.Name, err)
+		}
+		catalog.Tools = append(catalog.Tools, CatalogTool{
+			Name:        tool.Name,
+			Description: tool.Description,
+			Parameters:  params,
+		})
+	}
+	sort.Slice(catalog.Tools, func(i, j int) bool {
+		return catalog.Tools[i].Name < catalog.Tools[j].Name
+	})
+	return catalog, nil
+}
+
+// ParseToolCatalog parses a catalog previously produced by [ToolCatalog.MarshalIndent].
+func ParseToolCatalog(data []byte) (*ToolCatalog, error) {
+	var catalog ToolCatalog
+	if err := json.Unmarshal(data, &catalog); err != nil {
+		return nil, fmt.Errorf("failed to parse tool catalog: %w", err)
+	}
+	for i := range catalog.Tools {
+		params, err := normalizeSchema(catalog.Tools[i].Parameters)
+		if err != nil {
+			return nil, fmt.Errorf("failed to normalize schema for tool %s: %w", catalog.Tools[i].Name, err)
+		}
+		catalog.Tools[i].Parameters = params
+	}
+	sort.Slice(catalog.Tools, func(i, j int) bool {
+		return catalog.Tools[i].Name < catalog.Tools[j].Name
+	})
+	return &catalog, nil
+}
+
+// MarshalIndent renders the catalog as indented JSON with a trailing newline.
+func (c *ToolCatalog) MarshalIndent() ([]byte, error) {
+	data, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(data, '\n'), nil
+}
+
+// Tool returns the catalog entry with the given name.
+func (c *ToolCatalog) Tool(name string) (CatalogTool, bool) {
+	i := sort.Search(len(c.Tools), func(i int) bool { return c.Tools[i].Name >= name })
+	if i < len(c.Tools) && c.Tools[i].Name == name {
+		return c.Tools[i], true
+	}
+	return CatalogTool{}, false
+}
+
+// normalizeSchema converts a schema into its canonical JSON representation:
+// values are round-tripped through encoding/json, "required" lists are sorted,
+// unordered "type" lists are sorted, and rendering-only keys are removed.
+func normalizeSchema(schema map[string]interface{}) (map[string]interface{}, error) {
+	if schema == nil {
+		return nil, nil
+	}
+	data, err := json.Marshal(schema)
+	if err != nil {
+		return nil, err
+	}
+	var normalized map[string]interface{}
+	if err := json.Unmarshal(data, &normalized); err != nil {
+		return nil, err
+	}
+	normalizeSchemaValue(normalized)
+	return normalized, nil
+}
+
+func normalizeSchemaValue(v interface{}) {
+	switch val := v.(type) {
+	case map[string]interface{}:
+		delete(val, "propertyOrder")
+		for key, child := range val {
+			if (key == "required" || key == "type") && isStringList(child) {
+				sortStringList(child.([]interface{}))
+				continue
+			}
+			normalizeSchemaValue(child)
+		}
+	case []interface{}:
+		for _, child := range val {
+			normalizeSchemaValue(child)
+		}
+	}
+}
+
+func isStringList(v interface{}) bool {
+	list, ok := v.([]interface{})
+	if !ok {
+		return false
+	}
+	for _, item := range list {
+		if _, ok := item.(string); !ok {
+			return false
+		}
+	}
+	return true
+}
+
+func sortStringList(list []interface{}) {
+	sort.Slice(list, func(i, j int) bool { return list[i].(string) < list[j].(string) })
+}
+
+// SchemaChangeKind classifies a difference between two versions of a tool schema.
+type SchemaChangeKind string
+
+const (
+	SchemaPropertyAdded       SchemaChangeKind = "property_added"
+	SchemaPropertyRemoved     SchemaChangeKind = "property_removed"
+	SchemaPropertyRequired    SchemaChangeKind = "property_required"
+	SchemaPropertyOptional    SchemaChangeKind = "property_optional"
+	SchemaTypeChanged         SchemaChangeKind = "type_changed"
+	SchemaEnumChanged         SchemaChangeKind = "enum_changed"
+	SchemaConstraintChanged   SchemaChangeKind = "constraint_changed"
+	SchemaDescriptionModified SchemaChangeKind = "description_changed"
+)
+
+// SchemaChange is a single difference between two versions of a tool schema.
+type SchemaChange struct {
+	// Path locates the changed schema node as a JSON pointer, e.g. "/properties/unit".
+	Path string           `json:"path"`
+	Kind SchemaChangeKind `json:"kind"`
+	// Breaking is true when arguments valid under the old schema may be rejected
+	// or misinterpreted under the new one.
+	Breaking bool   `json:"breaking"`
+	Detail   string `json:"detail,omitempty"`
+}
+
+// ToolChange describes how a tool present in both catalogs changed.
+type ToolChange struct {
+	Name               string         `json:"name"`
+	DescriptionChanged bool           `json:"descriptionChanged,omitempty"`
+	SchemaChanges      []SchemaChange `json:"schemaChanges,omitempty"`
+}
+
+// Breaking reports whether any schema change is breaking.
+func (c ToolChange) Breaking() bool {
+	for _, sc := range c.SchemaChanges {
+		if sc.Breaking {
+			return true
+		}
+	}
+	return false
+}
+
+// CatalogDiff is the result of [DiffToolCatalogs].
+type CatalogDiff struct {
+	Added   []string     `json:"added,omitempty"`
+	Removed []string     `json:"removed,omitempty"`
+	Changed []ToolChange `json:"changed,omitempty"`
+}
+
+// Empty reports whether the two catalogs were equivalent.
+func (d CatalogDiff) Empty() bool {
+	return len(d.Added) == 0 && len(d.Removed) == 0 && len(d.Changed) == 0
+}
+
+// HasBreakingChanges reports whether the diff removes tools or contains breaking schema changes.
+func (d CatalogDiff) HasBreakingChanges() bool {
+	if len(d.Removed) > 0 {
+		return true
+	}
+	for _, c := range d.Changed {
+		if c.Breaking() {
+			return true
+		}
+	}
+	return false
+}
+
+// Markdown renders the diff as a changelog fragment.
+func (d CatalogDiff) Markdown() string {
+	if d.Empty() {
+		return "No tool changes.\n"
+	}
+	var b strings.Builder
+	if len(d.Added) > 0 {
+		b.WriteString("### Added tools\n\n")
+		for _, name := range d.Added {
+			fmt.Fprintf(&b, "- `%s`\n", name)
+		}
+		b.WriteString("\n")
+	}
+	if len(d.Removed) > 0 {
+		b.WriteString("### Removed tools (breaking)\n\n")
+		for _, name := range d.Removed {
+			fmt.Fprintf(&b, "- `%s`\n", name)
+		}
+		b.WriteString("\n")
+	}
+	if len(d.Changed) > 0 {
+		b.WriteString("### Changed tools\n\n")
+		for _, c := range d.Changed {
+			suffix := ""
+			if c.Breaking() {
+				suffix = " (breaking)"
+			}
+			fmt.Fprintf(&b, "- `%s`%s\n", c.Name, suffix)
+			if c.DescriptionChanged {
+				b.WriteString("  - description changed\n")
+			}
+			for _, sc := range c.SchemaChanges {
+				marker := ""
+				if sc.Breaking {
+					marker = " **breaking**"
+				}
+				fmt.Fprintf(&b, "  - `%s`: %s%s", sc.Path, sc.Kind, marker)
+				if sc.Detail != "" {
+					fmt.Fprintf(&b, " (%s)", sc.Detail)
+				}
+				b.WriteString("\n")
+			}
+		}
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+// DiffToolCatalogs compares two catalogs and reports added, removed, and changed tools.
+//
+// Example (in CI):
+//
+//	diff := copilot.DiffToolCatalogs(previous, current)
+//	fmt.Print(diff.Markdown())
+//	if diff.HasBreakingChanges() {
+//	    os.Exit(1)
+//	}
+func DiffToolCatalogs(oldCatalog, newCatalog *ToolCatalog) CatalogDiff {
+	var diff CatalogDiff
+
+	oldTools := make(map[string]CatalogTool)
+	if oldCatalog != nil {
+		for _, tool := range oldCatalog.Tools {
+			oldTools[tool.Name] = tool
+		}
+	}
+	newTools := make(map[string]CatalogTool)
+	if newCatalog != nil {
+		for _, tool := range newCatalog.Tools {
+			newTools[tool.Name] = tool
+		}
+	}
+
+	for name := range newTools {
+		if _, ok := oldTools[name]; !ok {
+			diff.Added = append(diff.Added, name)
+		}
+	}
+	for name, oldTool := range oldTools {
+		newTool, ok := newTools[name]
+		if !ok {
+			diff.Removed = append(diff.Removed, name)
+			continue
+		}
+		change := ToolChange{
+			Name:               name,
+			DescriptionChanged: oldTool.Description != newTool.Description,
+			SchemaChanges:      diffSchemas("", oldTool.Parameters, newTool.Parameters),
+		}
+		if change.DescriptionChanged || len(change.SchemaChanges) > 0 {
+			diff.Changed = append(diff.Changed, change)
+		}
+	}
+
+	sort.Strings(diff.Added)
+	sort.Strings(diff.Removed)
+	sort.Slice(diff.Changed, func(i, j int) bool { return diff.Changed[i].Name < diff.Changed[j].Name })
+	return diff
+}
+
+// diffSchemas compares two schema nodes located at path. Changes beneath added or
+// removed properties are not reported individually.
+func diffSchemas(path string, oldSchema, newSchema map[string]interface{}) []SchemaChange {
+	var changes []SchemaChange
+	if reflect.DeepEqual(oldSchema, newSchema) {
+		return nil
+	}
+
+	oldTypes := schemaTypes(oldSchema)
+	newTypes := schemaTypes(newSchema)
+	if !equalStringSets(oldTypes, newTypes) {
+		changes = append(changes, SchemaChange{
+			Path:     pathOrRoot(path),
+			Kind:     SchemaTypeChanged,
+			Breaking: !isSubset(oldTypes, newTypes),
+			Detail:   fmt.Sprintf("%s -> %s", strings.Join(oldTypes, "|"), strings.Join(newTypes, "|")),
+		})
+	}
+
+	if oldEnum, newEnum := oldSchema["enum"], newSchema["enum"]; !reflect.DeepEqual(oldEnum, newEnum) {
+		breaking := newEnum != nil && (oldEnum == nil || !enumSubset(oldEnum, newEnum))
+		changes = append(changes, SchemaChange{
+			Path:     pathOrRoot(path),
+			Kind:     SchemaEnumChanged,
+			Breaking: breaking,
+		})
+	}
+
+	for _, key := range []string{"minimum", "maximum", "exclusiveMinimum", "exclusiveMaximum", "minLength", "maxLength", "pattern", "format", "minItems", "maxItems"} {
+		if !reflect.DeepEqual(oldSchema[key], newSchema[key]) {
+			changes = append(changes, SchemaChange{
+				Path:     pathOrRoot(path),
+				Kind:     SchemaConstraintChanged,
+				Breaking: constraintTightened(key, oldSchema[key], newSchema[key]),
+				Detail:   key,
+			})
+		}
+	}
+
+	if oldSchema["description"] != newSchema["description"] && path != "" {
+		changes = append(changes, SchemaChange{
+			Path: path,
+			Kind: SchemaDescriptionModified,
+		})
+	}
+
+	oldProps, _ := oldSchema["properties"].(map[string]interface{})
+	newProps, _ := newSchema["properties"].(map[string]interface{})
+	oldRequired := stringSet(oldSchema["required"])
+	newRequired := stringSet(newSchema["required"])
+
+	names := make([]string, 0, len(oldProps)+len(newProps))
+	for name := range oldProps {
+		names = append(names, name)
+	}
+	for name := range newProps {
+		if _, ok := oldProps[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		propPath := path + "/properties/" + name
+		oldProp, inOld := oldProps[name].(map[string]interface{})
+		newProp, inNew := newProps[name].(map[string]interface{})
+		switch {
+		case inOld && !inNew:
+			changes = append(changes, SchemaChange{Path: propPath, Kind: SchemaPropertyRemoved, Breaking: true})
+		case !inOld && inNew:
+			changes = append(changes, SchemaChange{Path: propPath, Kind: SchemaPropertyAdded, Breaking: newRequired[name]})
+		default:
+			if !oldRequired[name] && newRequired[name] {
+				changes = append(changes, SchemaChange{Path: propPath, Kind: SchemaPropertyRequired, Breaking: true})
+			} else if oldRequired[name] && !newRequired[name] {
+				changes = append(changes, SchemaChange{Path: propPath, Kind: SchemaPropertyOptional})
+			}
+			changes = append(changes, diffSchemas(propPath, oldProp, newProp)...)
+		}
+	}
+
+	if oldItems, ok := oldSchema["items"].(map[string]interface{}); ok {
+		if newItems, ok := newSchema["items"].(map[string]interface{}); ok {
+			changes = append(changes, diffSchemas(path+"/items", oldItems, newItems)...)
+		}
+	}
+	if oldAdditional, ok := oldSchema["additionalProperties"].(map[string]interface{}); ok {
+		if newAdditional, ok := newSchema["additionalProperties"].(map[string]interface{}); ok {
+			changes = append(changes, diffSchemas(path+"/additionalProperties", oldAdditional, newAdditional)...)
+		}
+	}
+
+	return changes
+}
+
+func pathOrRoot(path string) string {
+	if path == "" {
+		return "/"
+	}
+	return path
+}
+
+// schemaTypes returns the sorted set of JSON types a schema node accepts.
+func schemaTypes(schema map[string]interface{}) []string {
+	switch t := schema["type"].(type) {
+	case string:
+		return []string{t}
+	case []interface{}:
+		types := make([]string, 0, len(t))
+		for _, item := range t {
+			if s, ok := item.(string); ok {
+				types = append(types, s)
+			}
+		}
+		sort.Strings(types)
+		return types
+	}
+	return nil
+}
+
+func stringSet(v interface{}) map[string]bool {
+	set := make(map[string]bool)
+	if list, ok := v.([]interface{}); ok {
+		for _, item := range list {
+			if s, ok := item.(string); ok {
+				set[s] = true
+			}
+		}
+	}
+	return set
+}
+
+func equalStringSets(a, b []string) bool {
+	return isSubset(a, b) && isSubset(b, a)
+}
+
+// isSubset reports whether every element of a is in b. An empty b accepts
+// everything, since a schema without "type" is unconstrained.
+func isSubset(a, b []string) bool {
+	if len(b) == 0 {
+		return true
+	}
+	if len(a) == 0 {
+		return false
+	}
+	set := make(map[string]bool, len(b))
+	for _, s := range b {
+		set[s] = true
+	}
+	for _, s := range a {
+		if !set[s] {
+			return false
+		}
+	}
+	return true
+}
+
+// lowerBounds and upperBounds are the numeric constraints that are tightened
+// by raising and by lowering them, respectively.
+var (
+	lowerBounds = map[string]bool{"minimum": true, "exclusiveMinimum": true, "minLength": true, "minItems": true}
+	upperBounds = map[string]bool{"maximum": true, "exclusiveMaximum": true, "maxLength": true, "maxItems": true}
+)
+
+// constraintTightened reports whether changing the constraint key from
+// oldValue to newValue may reject values the old constraint allowed: adding
+// it, raising a lower bound, lowering an upper bound, or changing any other
+// constraint, such as a pattern. Removing a constraint is never breaking.
+func constraintTightened(key string, oldValue, newValue interface{}) bool {
+	if newValue == nil {
+		return false
+	}
+	if oldValue == nil {
+		return true
+	}
+	oldBound, oldIsNumber := oldValue.(float64)
+	newBound, newIsNumber := newValue.(float64)
+	if oldIsNumber && newIsNumber {
+		switch {
+		case lowerBounds[key]:
+			return newBound > oldBound
+		case upperBounds[key]:
+			return newBound < oldBound
+		}
+	}
+	return true
+}
+
+// enumSubset reports whether every old enum value is still allowed by the new enum.
+func enumSubset(oldEnum, newEnum interface{}) bool {
+	oldList, _ := oldEnum.([]interface{})
+	newList, _ := newEnum.([]interface{})
+	for _, o := range oldList {
+		found := false
+		for _, n := range newList {
+			if reflect.DeepEqual(o, n) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,197 @@
+package copilot
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestNewToolCatalog(t *testing.T) {
+	t.Run("orders tools by name and produces stable JSON", func(t *testing.T) {
+		type Params struct {
+			B string `json:"b"`
+			A string `json:"a"`
+		}
+		handler := func(params Params, inv ToolInvocation) (string, error) { return "", nil }
+		tools := []Tool{
+			DefineTool("zeta", "Last", handler),
+			DefineTool("alpha", "First", handler),
+			{Name: ""},
+		}
+
+		catalog, err := NewToolCatalog(tools)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if len(catalog.Tools) != 2 || catalog.Tools[0].Name != "alpha" || catalog.Tools[1].Name != "zeta" {
+			t.Fatalf("Expected [alpha zeta], got %+v", catalog.Tools)
+		}
+
+		first, err := catalog.MarshalIndent()
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		reversed, _ := NewToolCatalog([]Tool{tools[1], tools[0]})
+		second, _ := reversed.MarshalIndent()
+		if !bytes.Equal(first, second) {
+			t.Errorf("Expected identical output regardless of registration order\n%s\n%s", first, second)
+		}
+
+		parsed, err := ParseToolCatalog(first)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if diff := DiffToolCatalogs(catalog, parsed); !diff.Empty() {
+			t.Errorf("Expected round-tripped catalog to be equivalent, got %+v", diff)
+		}
+	})
+
+	t.Run("looks up tools by name", func(t *testing.T) {
+		catalog, _ := NewToolCatalog([]Tool{{Name: "b"}, {Name: "a"}})
+		if _, ok := catalog.Tool("b"); !ok {
+			t.Error("Expected to find tool b")
+		}
+		if _, ok := catalog.Tool("c"); ok {
+			t.Error("Expected not to find tool c")
+		}
+	})
+}
+
+func TestDiffToolCatalogs(t *testing.T) {
+	objectSchema := func(props map[string]interface{}, required ...string) map[string]interface{} {
+		req := make([]interface{}, 0, len(required))
+		for _, r := range required {
+			req = append(req, r)
+		}
+		return map[string]interface{}{"type": "object", "properties": props, "required": req}
+	}
+
+	t.Run("reports added and removed tools", func(t *testing.T) {
+		oldCatalog, _ := NewToolCatalog([]Tool{{Name: "keep"}, {Name: "gone"}})
+		newCatalog, _ := NewToolCatalog([]Tool{{Name: "keep"}, {Name: "fresh"}})
+
+		diff := DiffToolCatalogs(oldCatalog, newCatalog)
+		if len(diff.Added) != 1 || diff.Added[0] != "fresh" {
+			t.Errorf("Expected fresh to be added, got %v", diff.Added)
+		}
+		if len(diff.Removed) != 1 || diff.Removed[0] != "gone" {
+			t.Errorf("Expected gone to be removed, got %v", diff.Removed)
+		}
+		if !diff.HasBreakingChanges() {
+			t.Error("Expected removing a tool to be breaking")
+		}
+	})
+
+	t.Run("adding an optional property is not breaking", func(t *testing.T) {
+		oldCatalog, _ := NewToolCatalog([]Tool{{Name: "t", Parameters: objectSchema(map[string]interface{}{
+			"city": map[string]interface{}{"type": "string"},
+		}, "city")}})
+		newCatalog, _ := NewToolCatalog([]Tool{{Name: "t", Parameters: objectSchema(map[string]interface{}{
+			"city": map[string]interface{}{"type": "string"},
+			"unit": map[string]interface{}{"type": "string"},
+		}, "city")}})
+
+		diff := DiffToolCatalogs(oldCatalog, newCatalog)
+		if len(diff.Changed) != 1 || len(diff.Changed[0].SchemaChanges) != 1 {
+			t.Fatalf("Expected one schema change, got %+v", diff.Changed)
+		}
+		change := diff.Changed[0].SchemaChanges[0]
+		if change.Kind != SchemaPropertyAdded || change.Path != "/properties/unit" || change.Breaking {
+			t.Errorf("Expected non-breaking property_added at /properties/unit, got %+v", change)
+		}
+		if diff.HasBreakingChanges() {
+			t.Error("Expected no breaking changes")
+		}
+	})
+
+	t.Run("detects breaking schema changes", func(t *testing.T) {
+		oldCatalog, _ := NewToolCatalog([]Tool{{Name: "t", Parameters: objectSchema(map[string]interface{}{
+			"count": map[string]interface{}{"type": "integer"},
+			"mode":  map[string]interface{}{"type": "string", "enum": []interface{}{"a", "b"}},
+			"old":   map[string]interface{}{"type": "string"},
+			"opt":   map[string]interface{}{"type": "string"},
+		})}})
+		newCatalog, _ := NewToolCatalog([]Tool{{Name: "t", Parameters: objectSchema(map[string]interface{}{
+			"count": map[string]interface{}{"type": "string"},
+			"mode":  map[string]interface{}{"type": "string", "enum": []interface{}{"a"}},
+			"opt":   map[string]interface{}{"type": "string"},
+		}, "opt")}})
+
+		diff := DiffToolCatalogs(oldCatalog, newCatalog)
+		if len(diff.Changed) != 1 {
+			t.Fatalf("Expected one changed tool, got %+v", diff.Changed)
+		}
+
+		kinds := make(map[string]SchemaChangeKind)
+		for _, sc := range diff.Changed[0].SchemaChanges {
+			if !sc.Breaking {
+				t.Errorf("Expected %s at %s to be breaking", sc.Kind, sc.Path)
+			}
+			kinds[sc.Path] = sc.Kind
+		}
+		expected := map[string]SchemaChangeKind{
+			"/properties/count": SchemaTypeChanged,
+			"/properties/mode":  SchemaEnumChanged,
+			"/properties/old":   SchemaPropertyRemoved,
+			"/properties/opt":   SchemaPropertyRequired,
+		}
+		for path, kind := range expected {
+			if kinds[path] != kind {
+				t.Errorf("Expected %s at %s, got %q", kind, path, kinds[path])
+			}
+		}
+	})
+
+	t.Run("widening a type is not breaking", func(t *testing.T) {
+		oldCatalog, _ := NewToolCatalog([]Tool{{Name: "t", Parameters: map[string]interface{}{"type": "string"}}})
+		newCatalog, _ := NewToolCatalog([]Tool{{Name: "t", Parameters: map[string]interface{}{"type": []interface{}{"string", "null"}}}})
+
+		diff := DiffToolCatalogs(oldCatalog, newCatalog)
+		if diff.HasBreakingChanges() {
+			t.Errorf("Expected widening to be non-breaking, got %+v", diff.Changed)
+		}
+	})
+
+	t.Run("only tightening a constraint is breaking", func(t *testing.T) {
+		schema := func(props map[string]interface{}) []Tool {
+			return []Tool{{Name: "t", Parameters: objectSchema(props)}}
+		}
+		oldCatalog, _ := NewToolCatalog(schema(map[string]interface{}{
+			"count": map[string]interface{}{"type": "integer", "minimum": 1, "maximum": 10},
+			"name":  map[string]interface{}{"type": "string", "maxLength": 20},
+		}))
+
+		loosened, _ := NewToolCatalog(schema(map[string]interface{}{
+			"count": map[string]interface{}{"type": "integer", "minimum": 0, "maximum": 100},
+			"name":  map[string]interface{}{"type": "string"},
+		}))
+		diff := DiffToolCatalogs(oldCatalog, loosened)
+		if len(diff.Changed) != 1 || len(diff.Changed[0].SchemaChanges) != 3 {
+			t.Fatalf("Expected three constraint changes, got %+v", diff.Changed)
+		}
+		if diff.HasBreakingChanges() {
+			t.Errorf("Expected loosening to be non-breaking, got %+v", diff.Changed)
+		}
+
+		tightened, _ := NewToolCatalog(schema(map[string]interface{}{
+			"count": map[string]interface{}{"type": "integer", "minimum": 2, "maximum": 10},
+			"name":  map[string]interface{}{"type": "string", "maxLength": 10, "pattern": "^[a-z]+$"},
+		}))
+		breaking := make(map[string]bool)
+		for _, sc := range DiffToolCatalogs(oldCatalog, tightened).Changed[0].SchemaChanges {
+			breaking[sc.Detail] = sc.Breaking
+		}
+		if len(breaking) != 3 || !breaking["minimum"] || !breaking["maxLength"] || !breaking["pattern"] {
+			t.Errorf("Expected the raised minimum, lowered maxLength, and added pattern to be breaking, got %v", breaking)
+		}
+	})
+
+	t.Run("renders markdown changelog", func(t *testing.T) {
+		oldCatalog, _ := NewToolCatalog([]Tool{{Name: "gone"}})
+		newCatalog, _ := NewToolCatalog([]Tool{{Name: "fresh"}})
+
+		md := DiffToolCatalogs(oldCatalog, newCatalog).Markdown()
+		if !bytes.Contains([]byte(md), []byte("`fresh`")) || !bytes.Contains([]byte(md), []byte("Removed tools (breaking)")) {
+			t.Errorf("Unexpected markdown:\n%s", md)
+		}
+	})
+}
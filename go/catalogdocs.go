@@ -0,0 +1,273 @@
+package copilot
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// MarkdownDocsOptions configures [ToolCatalog.MarkdownDocs].
+type MarkdownDocsOptions struct {
+	// Title is the heading of the index page. Defaults to "Tools".
+	Title string
+	// Version, if set, is shown on every page, such as the release the
+	// catalog was built from.
+	Version string
+	// IndexName is the name of the index page. Defaults to "index.md".
+	IndexName string
+}
+
+// MarkdownDocs renders the catalog as Markdown documentation: an index page
+// listing every tool, and a page per tool describing its parameters, their
+// types and constraints, whether they are required, and example arguments.
+// It returns the pages by file name, ready to write to a directory or publish
+// to a developer portal. Tool pages are named after their tools, such as
+// "get_weather.md".
+//
+// Example (in a release pipeline):
+//
+//	catalog, _ := copilot.NewToolCatalog(tools)
+//	pages := catalog.MarkdownDocs(&copilot.MarkdownDocsOptions{Version: release})
+//	for name, page := range pages {
+//	    os.WriteFile(filepath.Join("docs/tools", name), page, 0644)
+//	}
+func (c *ToolCatalog) MarkdownDocs(opts *MarkdownDocsOptions) map[string][]byte {
+	var options MarkdownDocsOptions
+	if opts != nil {
+		options = *opts
+	}
+	if options.Title == "" {
+		options.Title = "Tools"
+	}
+	if options.IndexName == "" {
+		options.IndexName = "index.md"
+	}
+
+	pages := make(map[string][]byte, len(c.Tools)+1)
+	var index strings.Builder
+	fmt.Fprintf(&index, "# %s\n\n", options.Title)
+	writeDocsVersion(&index, options.Version)
+	if len(c.Tools) == 0 {
+		index.WriteString("No tools.\n")
+	} else {
+		index.WriteString("| Tool | Description |\n| --- | --- |\n")
+	}
+	for _, tool := range c.Tools {
+		page := toolDocsPageName(tool.Name)
+		fmt.Fprintf(&index, "| [`%s`](%s) | %s |\n", tool.Name, page, markdownCell(firstLine(tool.Description)))
+		pages[page] = []byte(tool.markdown(options, 1))
+	}
+	pages[options.IndexName] = []byte(index.String())
+	return pages
+}
+
+// Markdown renders the tool's documentation page.
+func (t CatalogTool) Markdown() string {
+	return t.markdown(MarkdownDocsOptions{}, 1)
+}
+
+// markdownDocument renders the catalog as one Markdown document, with a
+// section per tool linked from a table of the tools.
+func (c *ToolCatalog) markdownDocument() string {
+	var b strings.Builder
+	b.WriteString("# Tools\n\n")
+	if len(c.Tools) == 0 {
+		b.WriteString("No tools.\n")
+		return b.String()
+	}
+	b.WriteString("| Tool | Description |\n| --- | --- |\n")
+	for _, tool := range c.Tools {
+		fmt.Fprintf(&b, "| [`%s`](#%s) | %s |\n", tool.Name, strings.ToLower(tool.Name), markdownCell(firstLine(tool.Description)))
+	}
+	for _, tool := range c.Tools {
+		b.WriteString("\n")
+		b.WriteString(tool.markdown(MarkdownDocsOptions{}, 2))
+	}
+	return b.String()
+}
+
+// markdown renders the tool's documentation with its name as a heading of
+// level, and its sections one level below.
+func (t CatalogTool) markdown(options MarkdownDocsOptions, level int) string {
+	heading := strings.Repeat("#", level)
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s `%s`\n\n", heading, t.Name)
+	writeDocsVersion(&b, options.Version)
+	if description := strings.TrimSpace(t.Description); description != "" {
+		b.WriteString(description + "\n\n")
+	}
+
+	fmt.Fprintf(&b, "%s# Parameters\n\n", heading)
+	rows := parameterDocRows("", t.Parameters, true)
+	if len(rows) == 0 {
+		b.WriteString("This tool takes no parameters.\n")
+		return b.String()
+	}
+	b.WriteString("| Name | Type | Required | Description |\n| --- | --- | --- | --- |\n")
+	for _, row := range rows {
+		required := "no"
+		if row.required {
+			required = "yes"
+		}
+		fmt.Fprintf(&b, "| `%s` | %s | %s | %s |\n", row.name, markdownCell(row.typ), required, markdownCell(row.description))
+	}
+
+	if example, err := json.MarshalIndent(exampleForSchema(t.Parameters, 0), "", "  "); err == nil {
+		fmt.Fprintf(&b, "\n%s# Example\n\n```json\n", heading)
+		b.Write(example)
+		b.WriteString("\n```\n")
+	}
+	return b.String()
+}
+
+// parameterDocRow documents one parameter of a tool.
+type parameterDocRow struct {
+	name        string
+	typ         string
+	required    bool
+	description string
+}
+
+// parameterDocRows lists the properties of the object schema at prefix,
+// followed by their own properties, named with dotted paths. Array items are
+// named with a "[]" suffix. A property is required only if its parents are.
+func parameterDocRows(prefix string, schema map[string]interface{}, required bool) []parameterDocRow {
+	properties, _ := schema["properties"].(map[string]interface{})
+	names := make([]string, 0, len(properties))
+	for name := range properties {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	requiredNames := stringSet(schema["required"])
+
+	var rows []parameterDocRow
+	for _, name := range names {
+		property, _ := properties[name].(map[string]interface{})
+		path := prefix + name
+		isRequired := required && requiredNames[name]
+		rows = append(rows, parameterDocRow{
+			name:        path,
+			typ:         schemaDocType(property),
+			required:    isRequired,
+			description: schemaDocDescription(property),
+		})
+		rows = append(rows, parameterDocRows(path+".", property, isRequired)...)
+		if items, ok := property["items"].(map[string]interface{}); ok {
+			rows = append(rows, parameterDocRows(path+"[].", items, isRequired)...)
+		}
+	}
+	return rows
+}
+
+// schemaDocType describes the type a schema accepts, such as "string",
+// "array of integer", or "string | null".
+func schemaDocType(schema map[string]interface{}) string {
+	types := schemaTypes(schema)
+	if len(types) == 0 {
+		for _, key := range []string{"anyOf", "oneOf"} {
+			variants, _ := schema[key].([]interface{})
+			for _, variant := range variants {
+				if variant, ok := variant.(map[string]interface{}); ok {
+					types = append(types, schemaDocType(variant))
+				}
+			}
+		}
+	}
+	for i, typ := range types {
+		if typ == "array" {
+			if items, ok := schema["items"].(map[string]interface{}); ok {
+				if itemType := schemaDocType(items); itemType != "any" {
+					types[i] = "array of " + itemType
+				}
+			}
+		}
+	}
+	if len(types) == 0 {
+		return "any"
+	}
+	return strings.Join(types, " | ")
+}
+
+// schemaDocDescription returns a schema's description followed by its
+// constraints.
+func schemaDocDescription(schema map[string]interface{}) string {
+	var parts []string
+	if description, _ := schema["description"].(string); description != "" {
+		parts = append(parts, strings.TrimRight(strings.TrimSpace(description), ".")+".")
+	}
+	if enum, ok := schema["enum"].([]interface{}); ok && len(enum) > 0 {
+		values := make([]string, len(enum))
+		for i, value := range enum {
+			values[i] = docValue(value)
+		}
+		parts = append(parts, "One of "+strings.Join(values, ", ")+".")
+	}
+	for _, constraint := range []struct{ key, label string }{
+		{"default", "Default"},
+		{"minimum", "Minimum"},
+		{"maximum", "Maximum"},
+		{"exclusiveMinimum", "Greater than"},
+		{"exclusiveMaximum", "Less than"},
+		{"minLength", "Minimum length"},
+		{"maxLength", "Maximum length"},
+		{"minItems", "Minimum items"},
+		{"maxItems", "Maximum items"},
+		{"pattern", "Pattern"},
+		{"format", "Format"},
+	} {
+		if value, ok := schema[constraint.key]; ok {
+			parts = append(parts, fmt.Sprintf("%s: %s.", constraint.label, docValue(value)))
+		}
+	}
+	if examples, ok := schema["examples"].([]interface{}); ok && len(examples) > 0 {
+		values := make([]string, len(examples))
+		for i, value := range examples {
+			values[i] = docValue(value)
+		}
+		parts = append(parts, "Examples: "+strings.Join(values, ", ")+".")
+	}
+	return strings.Join(parts, " ")
+}
+
+// docValue renders a schema value as inline code.
+func docValue(value interface{}) string {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprintf("`%v`", value)
+	}
+	return "`" + string(data) + "`"
+}
+
+func writeDocsVersion(b *strings.Builder, version string) {
+	if version != "" {
+		fmt.Fprintf(b, "_Version %s_\n\n", version)
+	}
+}
+
+// toolDocsPageName returns the file name of a tool's page, replacing
+// characters that are unsafe in file names or links.
+func toolDocsPageName(name string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '_', r == '-', r == '.':
+			return r
+		default:
+			return '_'
+		}
+	}, name) + ".md"
+}
+
+// markdownCell escapes text for a Markdown table cell.
+func markdownCell(text string) string {
+	text = strings.ReplaceAll(strings.TrimSpace(text), "|", `\|`)
+	return strings.ReplaceAll(text, "\n", "<br>")
+}
+
+func firstLine(text string) string {
+	text = strings.TrimSpace(text)
+	if i := strings.IndexByte(text, '\n'); i >= 0 {
+		return text[:i]
+	}
+	return text
+}
@@ -0,0 +1,97 @@
+package copilot
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestToolCatalog_MarkdownDocs(t *testing.T) {
+	type Stop struct {
+		Name string `json:"name" jsonschema:"Name of the stop"`
+	}
+	type Params struct {
+		City  string   `json:"city" jsonschema:"City name"`
+		Unit  string   `json:"unit" jsonschema:"optional,enum=celsius|fahrenheit,Temperature unit"`
+		Days  int      `json:"days" jsonschema:"optional,minimum=1,maximum=14"`
+		Stops []Stop   `json:"stops" jsonschema:"optional"`
+		Tags  []string `json:"tags" jsonschema:"optional,Labels | filters"`
+	}
+	catalog, err := NewToolCatalog([]Tool{
+		DefineTool("get_weather", "Get the current weather.\nUses the forecast service.", func(params Params, inv ToolInvocation) (string, error) {
+			return "", nil
+		}),
+		{Name: "ping"},
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	pages := catalog.MarkdownDocs(&MarkdownDocsOptions{Title: "Weather tools", Version: "1.4.0"})
+	if len(pages) != 3 {
+		t.Fatalf("Expected an index and two tool pages, got %d pages", len(pages))
+	}
+
+	index := string(pages["index.md"])
+	for _, want := range []string{
+		"# Weather tools\n",
+		"_Version 1.4.0_",
+		"| [`get_weather`](get_weather.md) | Get the current weather. |\n",
+		"| [`ping`](ping.md) |  |\n",
+	} {
+		if !strings.Contains(index, want) {
+			t.Errorf("Expected index to contain %q, got:\n%s", want, index)
+		}
+	}
+
+	page := string(pages["get_weather.md"])
+	for _, want := range []string{
+		"# `get_weather`\n",
+		"Get the current weather.\nUses the forecast service.\n",
+		"| `city` | string | yes | City name. |\n",
+		"| `unit` | string | no | Temperature unit. One of `\"celsius\"`, `\"fahrenheit\"`. |\n",
+		"| `days` | integer | no | Minimum: `1`. Maximum: `14`. |\n",
+		"| `stops` | array of object \\| null | no |",
+		"| `stops[].name` | string | no | Name of the stop. |\n",
+		"| `tags` | array of string \\| null | no | Labels \\| filters. |\n",
+		"## Example\n\n```json\n{\n  \"city\": \"string\",",
+		"\"unit\": \"celsius\"",
+	} {
+		if !strings.Contains(page, want) {
+			t.Errorf("Expected page to contain %q, got:\n%s", want, page)
+		}
+	}
+
+	if ping := string(pages["ping.md"]); !strings.Contains(ping, "This tool takes no parameters.") || strings.Contains(ping, "## Example") {
+		t.Errorf("Unexpected page for a tool without parameters:\n%s", ping)
+	}
+}
+
+func TestCatalogTool_Markdown(t *testing.T) {
+	tool := CatalogTool{
+		Name: "github/search",
+		Parameters: map[string]interface{}{
+			"type":     "object",
+			"required": []interface{}{"query"},
+			"properties": map[string]interface{}{
+				"query": map[string]interface{}{"type": "string", "examples": []interface{}{"is:open"}},
+				"value": map[string]interface{}{"anyOf": []interface{}{
+					map[string]interface{}{"type": "string"},
+					map[string]interface{}{"type": "integer"},
+				}},
+			},
+		},
+	}
+	page := tool.Markdown()
+	for _, want := range []string{
+		"| `query` | string | yes | Examples: `\"is:open\"`. |\n",
+		"| `value` | string \\| integer | no |  |\n",
+		"\"query\": \"is:open\"",
+	} {
+		if !strings.Contains(page, want) {
+			t.Errorf("Expected page to contain %q, got:\n%s", want, page)
+		}
+	}
+	if name := toolDocsPageName(tool.Name); name != "github_search.md" {
+		t.Errorf("Expected a file-safe page name, got %q", name)
+	}
+}
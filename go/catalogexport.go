@@ -0,0 +1,215 @@
+package copilot
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// CatalogFormat is a tool definition format that a [ToolCatalog] can be
+// exported to.
+type CatalogFormat string
+
+const (
+	// CatalogFormatOpenAI is the OpenAI function-calling format: a JSON array of
+	// {"type": "function", "function": {name, description, parameters}}.
+	CatalogFormatOpenAI CatalogFormat = "openai"
+	// CatalogFormatMCP is the Model Context Protocol tools/list format: a JSON
+	// object {"tools": [{name, description, inputSchema}]}.
+	CatalogFormatMCP CatalogFormat = "mcp"
+	// CatalogFormatJSON is the catalog's own manifest, as written by
+	// [ToolCatalog.MarshalIndent].
+	CatalogFormatJSON CatalogFormat = "json"
+	// CatalogFormatMarkdown is a single Markdown document with a table of the
+	// tools followed by a section per tool, laid out like the pages of
+	// [ToolCatalog.MarkdownDocs].
+	CatalogFormatMarkdown CatalogFormat = "markdown"
+)
+
+// ExportToolCatalog renders tools in format, for documentation and for
+// reviewing what an agent can do: names, descriptions, and parameter schemas.
+// It is shorthand for [NewToolCatalog] followed by [ToolCatalog.Export].
+//
+// Example:
+//
+//	manifest, err := copilot.ExportToolCatalog(tools, copilot.CatalogFormatJSON)
+//	if err != nil {
+//	    log.Fatal(err)
+//	}
+//	os.WriteFile("tools.json", manifest, 0644)
+//
+//	review, _ := copilot.ExportToolCatalog(tools, copilot.CatalogFormatMarkdown)
+//	os.WriteFile("TOOLS.md", review, 0644)
+func ExportToolCatalog(tools []Tool, format CatalogFormat) ([]byte, error) {
+	catalog, err := NewToolCatalog(tools)
+	if err != nil {
+		return nil, err
+	}
+	return catalog.Export(format)
+}
+
+// TranslationKey identifies a description in a [ToolCatalog].
+type TranslationKey struct {
+	// Tool is the tool the description belongs to.
+	Tool string
+	// Path is the JSON pointer of the schema holding the description within the
+	// tool's parameters, such as "/properties/unit", or empty for the tool's own
+	// description.
+	Path string
+}
+
+// DescriptionTranslator translates a description into locale. Returning an
+// empty string keeps the source text, so missing translations fall back to the
+// descriptions registered in Go.
+type DescriptionTranslator func(locale string, key TranslationKey, text string) (string, error)
+
+// Localize returns a copy of the catalog with its tool and parameter
+// descriptions translated into locale by translate.
+//
+// Example:
+//
+//	for _, locale := range []string{"en", "de", "ja"} {
+//	    localized, err := catalog.Localize(locale, translations.Translate)
+//	    if err != nil {
+//	        log.Fatal(err)
+//	    }
+//	    data, _ := localized.Export(copilot.CatalogFormatMCP)
+//	    os.WriteFile("tools."+locale+".json", data, 0644)
+//	}
+func (c *ToolCatalog) Localize(locale string, translate DescriptionTranslator) (*ToolCatalog, error) {
+	localized := &ToolCatalog{Version: c.Version, Tools: make([]CatalogTool, len(c.Tools))}
+	for i, tool := range c.Tools {
+		description, err := translateDescription(translate, locale, TranslationKey{Tool: tool.Name}, tool.Description)
+		if err != nil {
+			return nil, err
+		}
+		params, err := normalizeSchema(tool.Parameters)
+		if err != nil {
+			return nil, fmt.Errorf("failed to copy schema for tool %s: %w", tool.Name, err)
+		}
+		if err := translateSchema(translate, locale, tool.Name, "", params); err != nil {
+			return nil, err
+		}
+		localized.Tools[i] = CatalogTool{Name: tool.Name, Description: description, Parameters: params}
+	}
+	return localized, nil
+}
+
+func translateDescription(translate DescriptionTranslator, locale string, key TranslationKey, text string) (string, error) {
+	if text == "" {
+		return "", nil
+	}
+	translated, err := translate(locale, key, text)
+	if err != nil {
+		return "", fmt.Errorf("failed to translate %s%s into %s: %w", key.Tool, key.Path, locale, err)
+	}
+	if translated == "" {
+		return text, nil
+	}
+	return translated, nil
+}
+
+// translateSchema translates the descriptions in schema, which is at the JSON
+// pointer path, in place.
+func translateSchema(translate DescriptionTranslator, locale, tool, path string, schema interface{}) error {
+	switch val := schema.(type) {
+	case map[string]interface{}:
+		keys := make([]string, 0, len(val))
+		for key := range val {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			if text, ok := val[key].(string); ok && key == "description" {
+				translated, err := translateDescription(translate, locale, TranslationKey{Tool: tool, Path: path}, text)
+				if err != nil {
+					return err
+				}
+				val[key] = translated
+				continue
+			}
+			if err := translateSchema(translate, locale, tool, path+"/"+escapeJSONPointer(key), val[key]); err != nil {
+				return err
+			}
+		}
+	case []interface{}:
+		for i, child := range val {
+			if err := translateSchema(translate, locale, tool, fmt.Sprintf("%s/%d", path, i), child); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func escapeJSONPointer(token string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(token, "~", "~0"), "/", "~1")
+}
+
+// Export renders the catalog's tools in format: indented JSON, or Markdown
+// for [CatalogFormatMarkdown].
+func (c *ToolCatalog) Export(format CatalogFormat) ([]byte, error) {
+	var document interface{}
+	switch format {
+	case CatalogFormatJSON:
+		return c.MarshalIndent()
+	case CatalogFormatMarkdown:
+		return []byte(c.markdownDocument()), nil
+	case CatalogFormatOpenAI:
+		tools := make([]map[string]interface{}, 0, len(c.Tools))
+		for _, tool := range c.Tools {
+			function := map[string]interface{}{"name": tool.Name, "parameters": parametersOrEmpty(tool.Parameters)}
+			if tool.Description != "" {
+				function["description"] = tool.Description
+			}
+			tools = append(tools, map[string]interface{}{"type": "function", "function": function})
+		}
+		document = tools
+	case CatalogFormatMCP:
+		tools := make([]map[string]interface{}, 0, len(c.Tools))
+		for _, tool := range c.Tools {
+			entry := map[string]interface{}{"name": tool.Name, "inputSchema": parametersOrEmpty(tool.Parameters)}
+			if tool.Description != "" {
+				entry["description"] = tool.Description
+			}
+			tools = append(tools, entry)
+		}
+		document = map[string]interface{}{"tools": tools}
+	default:
+		return nil, fmt.Errorf("unknown catalog format %q", format)
+	}
+
+	data, err := json.MarshalIndent(document, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(data, '\n'), nil
+}
+
+// ExportLocales exports the catalog in format once per locale, translating its
+// descriptions with translate. The result maps each locale to its document.
+func (c *ToolCatalog) ExportLocales(format CatalogFormat, locales []string, translate DescriptionTranslator) (map[string][]byte, error) {
+	documents := make(map[string][]byte, len(locales))
+	for _, locale := range locales {
+		localized, err := c.Localize(locale, translate)
+		if err != nil {
+			return nil, err
+		}
+		data, err := localized.Export(format)
+		if err != nil {
+			return nil, err
+		}
+		documents[locale] = data
+	}
+	return documents, nil
+}
+
+// parametersOrEmpty returns params, or an empty object schema for tools
+// without parameters, which both formats require a schema for.
+func parametersOrEmpty(params map[string]interface{}) map[string]interface{} {
+	if params == nil {
+		return map[string]interface{}{"type": "object", "properties": map[string]interface{}{}}
+	}
+	return params
+}
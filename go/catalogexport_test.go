@@ -0,0 +1,192 @@
+package copilot
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func newExportTestCatalog(t *testing.T) *ToolCatalog {
+	t.Helper()
+	type Params struct {
+		City string `json:"city" jsonschema:"City name"`
+		Unit string `json:"unit" jsonschema:"optional,enum=celsius|fahrenheit,Temperature unit"`
+	}
+	catalog, err := NewToolCatalog([]Tool{
+		DefineTool("get_weather", "Get the current weather", func(params Params, inv ToolInvocation) (string, error) {
+			return "", nil
+		}),
+		{Name: "ping"},
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	return catalog
+}
+
+func TestToolCatalog_Localize(t *testing.T) {
+	catalog := newExportTestCatalog(t)
+	german := map[TranslationKey]string{
+		{Tool: "get_weather"}:                           "Aktuelles Wetter abrufen",
+		{Tool: "get_weather", Path: "/properties/city"}: "Name der Stadt",
+	}
+	var keys []TranslationKey
+	translate := func(locale string, key TranslationKey, text string) (string, error) {
+		keys = append(keys, key)
+		if locale != "de" {
+			t.Errorf("Unexpected locale %q", locale)
+		}
+		return german[key], nil
+	}
+
+	localized, err := catalog.Localize("de", translate)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	weather, _ := localized.Tool("get_weather")
+	if weather.Description != "Aktuelles Wetter abrufen" {
+		t.Errorf("Unexpected description %q", weather.Description)
+	}
+	props := weather.Parameters["properties"].(map[string]interface{})
+	if got := props["city"].(map[string]interface{})["description"]; got != "Name der Stadt" {
+		t.Errorf("Unexpected city description %v", got)
+	}
+	if got := props["unit"].(map[string]interface{})["description"]; got != "Temperature unit" {
+		t.Errorf("Expected a missing translation to keep the source text, got %v", got)
+	}
+	if len(keys) != 3 {
+		t.Errorf("Expected 3 descriptions to translate, got %v", keys)
+	}
+
+	original, _ := catalog.Tool("get_weather")
+	if original.Description != "Get the current weather" {
+		t.Errorf("Expected the source catalog to be unchanged, got %q", original.Description)
+	}
+
+	failing := func(string, TranslationKey, string) (string, error) { return "", errors.New("no backend") }
+	if _, err := catalog.Localize("fr", failing); err == nil || !strings.Contains(err.Error(), "no backend") {
+		t.Errorf("Expected the translation error, got %v", err)
+	}
+}
+
+func TestToolCatalog_Export(t *testing.T) {
+	catalog := newExportTestCatalog(t)
+
+	t.Run("openai", func(t *testing.T) {
+		data, err := catalog.Export(CatalogFormatOpenAI)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		var tools []struct {
+			Type     string `json:"type"`
+			Function struct {
+				Name        string                 `json:"name"`
+				Description string                 `json:"description"`
+				Parameters  map[string]interface{} `json:"parameters"`
+			} `json:"function"`
+		}
+		if err := json.Unmarshal(data, &tools); err != nil {
+			t.Fatalf("Invalid JSON: %v\n%s", err, data)
+		}
+		if len(tools) != 2 || tools[0].Type != "function" || tools[0].Function.Name != "get_weather" || tools[0].Function.Description != "Get the current weather" {
+			t.Errorf("Unexpected tools %+v", tools)
+		}
+		if tools[1].Function.Parameters["type"] != "object" {
+			t.Errorf("Expected an empty object schema for ping, got %v", tools[1].Function.Parameters)
+		}
+	})
+
+	t.Run("mcp", func(t *testing.T) {
+		data, err := catalog.Export(CatalogFormatMCP)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		var document struct {
+			Tools []struct {
+				Name        string                 `json:"name"`
+				InputSchema map[string]interface{} `json:"inputSchema"`
+			} `json:"tools"`
+		}
+		if err := json.Unmarshal(data, &document); err != nil {
+			t.Fatalf("Invalid JSON: %v\n%s", err, data)
+		}
+		if len(document.Tools) != 2 || document.Tools[0].InputSchema["properties"] == nil {
+			t.Errorf("Unexpected document %+v", document)
+		}
+	})
+
+	t.Run("json", func(t *testing.T) {
+		data, err := catalog.Export(CatalogFormatJSON)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		parsed, err := ParseToolCatalog(data)
+		if err != nil {
+			t.Fatalf("Expected a catalog manifest: %v\n%s", err, data)
+		}
+		if len(parsed.Tools) != 2 || parsed.Tools[0].Parameters["properties"] == nil {
+			t.Errorf("Unexpected catalog %+v", parsed)
+		}
+	})
+
+	t.Run("markdown", func(t *testing.T) {
+		data, err := catalog.Export(CatalogFormatMarkdown)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		document := string(data)
+		for _, want := range []string{
+			"# Tools\n",
+			"| [`get_weather`](#get_weather) | Get the current weather |",
+			"\n## `get_weather`\n",
+			"\n### Parameters\n",
+			"| `city` | string | yes | City name. |",
+			"\n## `ping`\n\n### Parameters\n\nThis tool takes no parameters.\n",
+		} {
+			if !strings.Contains(document, want) {
+				t.Errorf("Expected the document to contain %q, got:\n%s", want, document)
+			}
+		}
+	})
+
+	t.Run("unknown format", func(t *testing.T) {
+		if _, err := catalog.Export("yaml"); err == nil {
+			t.Error("Expected an error")
+		}
+	})
+}
+
+func TestExportToolCatalog(t *testing.T) {
+	data, err := ExportToolCatalog([]Tool{{Name: "b"}, {Name: "a", Description: "First"}}, CatalogFormatJSON)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !strings.Contains(string(data), `"name": "a"`) || strings.Index(string(data), `"a"`) > strings.Index(string(data), `"b"`) {
+		t.Errorf("Expected tools ordered by name, got:\n%s", data)
+	}
+	if _, err := ExportToolCatalog(nil, "yaml"); err == nil {
+		t.Error("Expected an error for an unknown format")
+	}
+}
+
+func TestToolCatalog_ExportLocales(t *testing.T) {
+	catalog := newExportTestCatalog(t)
+	translate := func(locale string, key TranslationKey, text string) (string, error) {
+		if locale == "en" {
+			return "", nil
+		}
+		return "[" + locale + "] " + text, nil
+	}
+
+	documents, err := catalog.ExportLocales(CatalogFormatMCP, []string{"en", "ja"}, translate)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !strings.Contains(string(documents["en"]), `"Get the current weather"`) {
+		t.Errorf("Unexpected en document:\n%s", documents["en"])
+	}
+	if !strings.Contains(string(documents["ja"]), `"[ja] Get the current weather"`) || !strings.Contains(string(documents["ja"]), `"[ja] City name"`) {
+		t.Errorf("Unexpected ja document:\n%s", documents["ja"])
+	}
+}
@@ -0,0 +1,158 @@
+package copilot
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+// CitationType identifies what a [Citation] refers to.
+type CitationType string
+
+const (
+	// CitationFile is a reference to a file, optionally to a range of lines in it.
+	CitationFile CitationType = "file"
+	// CitationURL is a reference to a web resource.
+	CitationURL CitationType = "url"
+)
+
+// maxTrackedCitationEvents bounds how many events' citations a session retains.
+const maxTrackedCitationEvents = 256
+
+// Citation is a source reference the server attached to assistant content.
+//
+// Citations are parsed from the annotation metadata on message events, so UIs can
+// render source links without scanning the message text. Retrieve them for an
+// event with [Session.Citations], or for a whole turn from [Turn.Citations].
+type Citation struct {
+	// Type is the kind of source referenced.
+	Type CitationType `json:"type"`
+	// Title is a human-readable label for the source, if provided.
+	Title string `json:"title,omitempty"`
+	// URL is the address of a web source.
+	URL string `json:"url,omitempty"`
+	// Path is the path of a file source.
+	Path string `json:"path,omitempty"`
+	// StartLine and EndLine are the 1-based, inclusive line range cited within
+	// Path. Both are zero when the whole file is cited.
+	StartLine int `json:"startLine,omitempty"`
+	EndLine   int `json:"endLine,omitempty"`
+	// StartIndex and EndIndex are the character offsets of the cited span within
+	// the message content. Both are zero when the server did not provide a span.
+	StartIndex int `json:"startIndex,omitempty"`
+	EndIndex   int `json:"endIndex,omitempty"`
+}
+
+// rawCitation accepts the field spellings used for citation metadata on the wire.
+type rawCitation struct {
+	Type       string `json:"type"`
+	Title      string `json:"title"`
+	URL        string `json:"url"`
+	URI        string `json:"uri"`
+	Path       string `json:"path"`
+	File       string `json:"file"`
+	FilePath   string `json:"filePath"`
+	StartLine  int    `json:"startLine"`
+	EndLine    int    `json:"endLine"`
+	StartIndex int    `json:"startIndex"`
+	EndIndex   int    `json:"endIndex"`
+}
+
+// parseCitations extracts the citations attached to a raw session event, or nil
+// if there are none. Both "citations" and "annotations" arrays are recognized.
+func parseCitations(eventJSON []byte) []Citation {
+	var envelope struct {
+		Data struct {
+			Citations   []rawCitation `json:"citations"`
+			Annotations []rawCitation `json:"annotations"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(eventJSON, &envelope); err != nil {
+		return nil
+	}
+
+	raw := append(envelope.Data.Citations, envelope.Data.Annotations...)
+	var citations []Citation
+	for _, r := range raw {
+		citation, ok := r.citation()
+		if ok {
+			citations = append(citations, citation)
+		}
+	}
+	return citations
+}
+
+// citation converts a wire citation, reporting false if it references nothing.
+func (r rawCitation) citation() (Citation, bool) {
+	c := Citation{
+		Title:      r.Title,
+		URL:        firstNonEmpty(r.URL, r.URI),
+		Path:       firstNonEmpty(r.Path, r.FilePath, r.File),
+		StartLine:  r.StartLine,
+		EndLine:    r.EndLine,
+		StartIndex: r.StartIndex,
+		EndIndex:   r.EndIndex,
+	}
+	if c.EndLine == 0 {
+		c.EndLine = c.StartLine
+	}
+
+	kind := strings.ToLower(r.Type)
+	switch {
+	case strings.Contains(kind, "url") || strings.Contains(kind, "web"):
+		c.Type = CitationURL
+	case strings.Contains(kind, "file"):
+		c.Type = CitationFile
+	case c.Path != "":
+		c.Type = CitationFile
+	case c.URL != "":
+		c.Type = CitationURL
+	}
+
+	if strings.HasPrefix(c.URL, "file://") && c.Path == "" {
+		c.Type = CitationFile
+		c.Path = strings.TrimPrefix(c.URL, "file://")
+		c.URL = ""
+	}
+
+	return c, (c.Type == CitationURL && c.URL != "") || (c.Type == CitationFile && c.Path != "")
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+// Citations returns the citations the server attached to the given event, or nil
+// if it has none. Citations are retained for the most recent events only, so call
+// this from an event handler or shortly after the event is delivered.
+func (s *Session) Citations(event SessionEvent) []Citation {
+	s.citationsMux.Lock()
+	defer s.citationsMux.Unlock()
+	return s.citations[event.ID]
+}
+
+// recordCitations stores the citations for an event, evicting the oldest entries
+// once maxTrackedCitationEvents events are tracked.
+func (s *Session) recordCitations(eventID string, citations []Citation) {
+	if eventID == "" || len(citations) == 0 {
+		return
+	}
+	s.citationsMux.Lock()
+	defer s.citationsMux.Unlock()
+
+	if s.citations == nil {
+		s.citations = make(map[string][]Citation)
+	}
+	if _, exists := s.citations[eventID]; !exists {
+		s.citationOrder = append(s.citationOrder, eventID)
+	}
+	s.citations[eventID] = citations
+	for len(s.citationOrder) > maxTrackedCitationEvents {
+		delete(s.citations, s.citationOrder[0])
+		s.citationOrder = s.citationOrder[1:]
+	}
+}
@@ -0,0 +1,59 @@
+package copilot
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestParseCitations(t *testing.T) {
+	t.Run("parses file and url citations", func(t *testing.T) {
+		citations := parseCitations([]byte(`{"id":"e1","type":"assistant.message","data":{
+			"content":"See retry.go",
+			"citations":[
+				{"type":"file","path":"pkg/retry.go","startLine":10,"endLine":24,"startIndex":4,"endIndex":12},
+				{"url":"https://example.com/docs","title":"Docs"}
+			],
+			"annotations":[{"type":"file_citation","filePath":"main.go","startLine":3}]
+		}}`))
+
+		if len(citations) != 3 {
+			t.Fatalf("Expected 3 citations, got %+v", citations)
+		}
+		if c := citations[0]; c.Type != CitationFile || c.Path != "pkg/retry.go" || c.StartLine != 10 || c.EndLine != 24 || c.EndIndex != 12 {
+			t.Errorf("Unexpected file citation %+v", c)
+		}
+		if c := citations[1]; c.Type != CitationURL || c.URL != "https://example.com/docs" || c.Title != "Docs" {
+			t.Errorf("Unexpected url citation %+v", c)
+		}
+		if c := citations[2]; c.Type != CitationFile || c.Path != "main.go" || c.EndLine != 3 {
+			t.Errorf("Unexpected annotation citation %+v", c)
+		}
+	})
+
+	t.Run("ignores events without usable citations", func(t *testing.T) {
+		if c := parseCitations([]byte(`{"data":{"content":"hi"}}`)); c != nil {
+			t.Errorf("Expected nil, got %+v", c)
+		}
+		if c := parseCitations([]byte(`{"data":{"citations":[{"type":"url"}]}}`)); c != nil {
+			t.Errorf("Expected empty citation to be dropped, got %+v", c)
+		}
+		if c := parseCitations([]byte(`{"data":{"citations":"bogus"}}`)); c != nil {
+			t.Errorf("Expected malformed citations to be ignored, got %+v", c)
+		}
+	})
+}
+
+func TestSession_Citations(t *testing.T) {
+	t.Run("retains citations for recent events only", func(t *testing.T) {
+		session := &Session{}
+		for i := 0; i < maxTrackedCitationEvents+10; i++ {
+			session.recordCitations(fmt.Sprintf("e%d", i), []Citation{{Type: CitationURL, URL: "https://example.com"}})
+		}
+		if c := session.Citations(SessionEvent{ID: "e0"}); c != nil {
+			t.Errorf("Expected oldest citations to be evicted, got %+v", c)
+		}
+		if c := session.Citations(SessionEvent{ID: fmt.Sprintf("e%d", maxTrackedCitationEvents+9)}); len(c) != 1 {
+			t.Errorf("Expected newest citations to be retained, got %+v", c)
+		}
+	})
+}
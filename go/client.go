@@ -29,8 +29,10 @@ package copilot
 
 import (
 	"bufio"
+	"context"
 	"encoding/json"
 	"fmt"
+	"io"
 	"net"
 	"os"
 	"os/exec"
@@ -38,6 +40,7 @@ import (
 	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -63,10 +66,10 @@ import (
 type Client struct {
 	options          ClientOptions
 	process          *exec.Cmd
-	client           *JSONRPCClient
+	client           *JSONRPCClient // guarded by connectMux; see rpc
 	actualPort       int
 	actualHost       string
-	state            ConnectionState
+	state            ConnectionState // guarded by connectMux
 	sessions         map[string]*Session
 	sessionsMux      sync.Mutex
 	isExternalServer bool
@@ -75,7 +78,31 @@ type Client struct {
 	autoStart        bool        // resolved value from options
 	autoRestart      bool        // resolved value from options
 	modelsCache      []ModelInfo
-	modelsCacheMux   sync.Mutex
+	modelsCachedAt   time.Time
+	modelsFetch      *modelsCall // in-flight models.list request, if any
+	modelsCacheMux   sync.Mutex  // guards modelsCache, modelsCachedAt, and modelsFetch
+	appliedLimits    *appliedResourceLimits
+	lazyConnect      bool         // resolved value from options
+	connectMux       sync.Mutex   // guards client, state, and connecting
+	connecting       *connectCall // in-flight connection attempt, if any
+	concurrency      *adaptiveLimiter
+	journal          *frameJournal
+	flow             *flowWindow
+	protocolErrors   *protocolErrorLog
+	serverToolLimits atomic.Pointer[ToolLimits] // declared in the handshake
+}
+
+// modelsCall is a models.list request shared by concurrent callers.
+type modelsCall struct {
+	done   chan struct{}
+	models []ModelInfo
+	err    error
+}
+
+// connectCall is a connection attempt shared by concurrent callers.
+type connectCall struct {
+	done chan struct{}
+	err  error
 }
 
 // NewClient creates a new Copilot CLI client with the given options.
@@ -123,6 +150,11 @@ func NewClient(options *ClientOptions) *Client {
 			panic("GithubToken and UseLoggedInUser cannot be used with CLIUrl (external server manages its own auth)")
 		}
 
+		// Validate resource limits with external server
+		if options.CLIUrl != "" && options.ResourceLimits != nil {
+			panic("ResourceLimits cannot be used with CLIUrl (the SDK does not manage the external server process)")
+		}
+
 		// Parse CLIUrl if provided
 		if options.CLIUrl != "" {
 			host, port := parseCliUrl(options.CLIUrl)
@@ -159,12 +191,59 @@ func NewClient(options *ClientOptions) *Client {
 		if options.AutoRestart != nil {
 			client.autoRestart = *options.AutoRestart
 		}
+		if options.LazyConnect {
+			if options.AutoStart != nil && !*options.AutoStart {
+				panic("LazyConnect cannot be used with AutoStart disabled")
+			}
+			client.lazyConnect = true
+		}
 		if options.GithubToken != "" {
 			opts.GithubToken = options.GithubToken
 		}
 		if options.UseLoggedInUser != nil {
 			opts.UseLoggedInUser = options.UseLoggedInUser
 		}
+		if options.ResourceLimits != nil {
+			opts.ResourceLimits = options.ResourceLimits
+		}
+		if options.StdioChannel != nil {
+			if !client.useStdio {
+				panic("StdioChannel requires the stdio transport")
+			}
+			opts.StdioChannel = options.StdioChannel
+		}
+		opts.ToolMiddleware = options.ToolMiddleware
+		opts.ToolObserver = options.ToolObserver
+		opts.ResultSanitizer = options.ResultSanitizer
+		opts.DryRun = options.DryRun
+		opts.ModelFallback = options.ModelFallback
+		opts.Secrets = options.Secrets
+		opts.OnSecretAccess = options.OnSecretAccess
+		opts.ProtocolErrorHistory = options.ProtocolErrorHistory
+		if options.ToolLimits != nil {
+			if err := options.ToolLimits.validate(); err != nil {
+				panic(err.Error())
+			}
+			opts.ToolLimits = options.ToolLimits
+		}
+		if options.AdaptiveConcurrency != nil {
+			if err := options.AdaptiveConcurrency.validate(); err != nil {
+				panic(err.Error())
+			}
+			opts.AdaptiveConcurrency = options.AdaptiveConcurrency
+		}
+		if options.Journal != nil {
+			if err := options.Journal.validate(); err != nil {
+				panic(err.Error())
+			}
+			opts.Journal = options.Journal
+		}
+		if options.FlowControl != nil {
+			if err := options.FlowControl.validate(); err != nil {
+				panic(err.Error())
+			}
+			opts.FlowControl = options.FlowControl
+		}
 	}
 
 	// Default Env to current environment if not set
@@ -178,6 +257,9 @@ func NewClient(options *ClientOptions) *Client {
 	}
 
 	client.options = opts
+	client.concurrency = newAdaptiveLimiter(opts.AdaptiveConcurrency)
+	client.flow = newFlowWindow(opts.FlowControl)
+	client.protocolErrors = newProtocolErrorLog(opts.ProtocolErrorHistory)
 	return client
 }
 
@@ -226,6 +308,8 @@ func parseCliUrl(url string) (string, int) {
 // This method is called automatically when creating a session if AutoStart is true (default).
 //
 // Returns an error if the server fails to start or the connection fails.
+// Concurrent calls, including the connections made on first use with
+// LazyConnect or AutoStart, share a single attempt.
 //
 // Example:
 //
@@ -235,34 +319,81 @@ func parseCliUrl(url string) (string, int) {
 //	}
 //	// Now ready to create sessions
 func (c *Client) Start() error {
-	if c.state == StateConnected {
-		return nil
+	_, err := c.ensureConnected()
+	return err
+}
+
+// start starts the server and connects to it. The connection is only
+// published, along with StateConnected, once the protocol handshake succeeds;
+// if any step fails, everything started so far is torn down.
+func (c *Client) start() error {
+	c.setState(StateConnecting)
+	client, err := c.connect()
+	if err != nil {
+		c.abandonStart(client)
+		c.setState(StateError)
+		return err
 	}
+	c.connectMux.Lock()
+	c.client = client
+	c.state = StateConnected
+	c.connectMux.Unlock()
+	return nil
+}
 
-	c.state = StateConnecting
+// connect starts the CLI server (if not using an external server), connects
+// to it, and verifies the protocol version, returning the connection made, if
+// any, even on failure.
+func (c *Client) connect() (*JSONRPCClient, error) {
+	if err := c.openJournal(); err != nil {
+		return nil, err
+	}
 
+	var client *JSONRPCClient
+	var err error
 	// Only start CLI server process if not connecting to external server
 	if !c.isExternalServer {
-		if err := c.startCLIServer(); err != nil {
-			c.state = StateError
-			return err
+		if client, err = c.startCLIServer(); err != nil {
+			return nil, err
 		}
 	}
 
-	// Connect to the server
-	if err := c.connectToServer(); err != nil {
-		c.state = StateError
-		return err
+	// Connect to the server, unless already connected via stdio
+	if client == nil {
+		if client, err = c.connectViaTcp(); err != nil {
+			return nil, err
+		}
 	}
 
 	// Verify protocol version compatibility
-	if err := c.verifyProtocolVersion(); err != nil {
-		c.state = StateError
-		return err
+	if err := c.verifyProtocolVersion(client); err != nil {
+		return client, err
 	}
+	return client, nil
+}
 
-	c.state = StateConnected
-	return nil
+// abandonStart tears down what a failed start set up, including client if
+// it is not nil, so that the next attempt starts afresh.
+func (c *Client) abandonStart(client *JSONRPCClient) {
+	// Kill the process first, as with Stop, so that client's read loop exits.
+	if c.process != nil && !c.isExternalServer {
+		if c.process.Process != nil {
+			c.process.Process.Kill()
+		}
+		c.process = nil
+		c.releaseResourceLimits()
+	}
+	if closer, ok := c.conn.(interface{ Close() error }); ok {
+		closer.Close()
+	}
+	c.conn = nil
+	if client != nil {
+		client.Stop()
+	}
+	c.setRPC(nil)
+	if !c.isExternalServer {
+		c.actualPort = 0
+	}
 }
 
 // Stop stops the CLI server and closes all active sessions.
@@ -293,6 +424,7 @@ func (c *Client) Stop() []error {
 	c.sessionsMux.Unlock()
 
 	for _, session := range sessions {
+		session.cancelTools(ErrClientStopped, true)
 		if err := session.Destroy(); err != nil {
 			errors = append(errors, fmt.Errorf("failed to destroy session %s: %w", session.SessionID, err))
 		}
@@ -308,6 +440,7 @@ func (c *Client) Stop() []error {
 			errors = append(errors, fmt.Errorf("failed to kill CLI process: %w", err))
 		}
 		c.process = nil
+		c.releaseResourceLimits()
 	}
 
 	// Close external TCP connection if exists
@@ -321,9 +454,15 @@ func (c *Client) Stop() []error {
 	}
 
 	// Then close JSON-RPC client (readLoop can now exit)
-	if c.client != nil {
-		c.client.Stop()
-		c.client = nil
+	if client := c.setRPC(nil); client != nil {
+		client.Stop()
+	}
+
+	if c.journal != nil {
+		if err := c.journal.close(); err != nil {
+			errors = append(errors, err)
+		}
+		c.journal = nil
 	}
 
 	// Clear models cache
@@ -331,7 +470,7 @@ func (c *Client) Stop() []error {
 	c.modelsCache = nil
 	c.modelsCacheMux.Unlock()
 
-	c.state = StateDisconnected
+	c.setState(StateDisconnected)
 	if !c.isExternalServer {
 		c.actualPort = 0
 	}
@@ -364,6 +503,9 @@ func (c *Client) Stop() []error {
 func (c *Client) ForceStop() {
 	// Clear sessions immediately without trying to destroy them
 	c.sessionsMux.Lock()
+	for _, session := range c.sessions {
+		session.cancelTools(ErrClientStopped, true)
+	}
 	c.sessions = make(map[string]*Session)
 	c.sessionsMux.Unlock()
 
@@ -371,6 +513,7 @@ func (c *Client) ForceStop() {
 	if c.process != nil && !c.isExternalServer {
 		c.process.Process.Kill() // Ignore errors
 		c.process = nil
+		c.releaseResourceLimits()
 	}
 
 	// Close external TCP connection if exists
@@ -382,9 +525,13 @@ func (c *Client) ForceStop() {
 	}
 
 	// Close JSON-RPC client
-	if c.client != nil {
-		c.client.Stop()
-		c.client = nil
+	if client := c.setRPC(nil); client != nil {
+		client.Stop()
+	}
+
+	if c.journal != nil {
+		c.journal.close() // Ignore errors
+		c.journal = nil
 	}
 
 	// Clear models cache
@@ -392,7 +539,7 @@ func (c *Client) ForceStop() {
 	c.modelsCache = nil
 	c.modelsCacheMux.Unlock()
 
-	c.state = StateDisconnected
+	c.setState(StateDisconnected)
 	if !c.isExternalServer {
 		c.actualPort = 0
 	}
@@ -428,6 +575,26 @@ func buildProviderParams(p *ProviderConfig) map[string]interface{} {
 	return params
 }
 
+// buildToolDefinitions converts tools into the definitions advertised to the CLI.
+// Tools without a name are skipped.
+func buildToolDefinitions(tools []Tool) []map[string]interface{} {
+	toolDefs := make([]map[string]interface{}, 0, len(tools))
+	for _, tool := range tools {
+		if tool.Name == "" {
+			continue
+		}
+		definition := map[string]interface{}{
+			"name":        tool.Name,
+			"description": tool.Description,
+		}
+		if tool.Parameters != nil {
+			definition["parameters"] = tool.Parameters
+		}
+		toolDefs = append(toolDefs, definition)
+	}
+	return toolDefs
+}
+
 // CreateSession creates a new conversation session with the Copilot CLI.
 //
 // Sessions maintain conversation state, handle events, and manage tool execution.
@@ -455,18 +622,38 @@ func buildProviderParams(p *ProviderConfig) map[string]interface{} {
 //	    },
 //	})
 func (c *Client) CreateSession(config *SessionConfig) (*Session, error) {
-	if c.client == nil {
-		if c.autoStart {
-			if err := c.Start(); err != nil {
-				return nil, err
-			}
-		} else {
-			return nil, fmt.Errorf("client not connected. Call Start() first")
+	client, err := c.autoConnect()
+	if err != nil {
+		return nil, err
+	}
+
+	if config != nil && config.Memory != nil {
+		if err := config.Memory.validate(); err != nil {
+			return nil, err
 		}
 	}
 
+	var parentID string
+	var maxDepth int
+	if config != nil {
+		parentID, maxDepth = config.ParentSessionID, config.MaxDepth
+	}
+	nesting, err := c.resolveNesting(parentID, maxDepth)
+	if err != nil {
+		return nil, err
+	}
+
 	params := make(map[string]interface{})
+	var tools, baseTools []Tool
 	if config != nil {
+		skills := enabledSkills(config.Skills, config.DisabledSkills)
+		baseTools = withResourceTools(skillTools(config.Tools, skills), config.Resources)
+		tools = filterTools(withDeprecatedTools(config.ToolRegistry.withTools(baseTools)), config.ToolFilter)
+		if tools, err = c.limitTools(tools); err != nil {
+			return nil, err
+		}
+		systemMessageConfig := withSkillInstructions(config.SystemMessage, skills)
+
 		if config.Model != "" {
 			params["model"] = config.Model
 		}
@@ -476,40 +663,24 @@ func (c *Client) CreateSession(config *SessionConfig) (*Session, error) {
 		if config.ReasoningEffort != "" {
 			params["reasoningEffort"] = config.ReasoningEffort
 		}
-		if len(config.Tools) > 0 {
-			toolDefs := make([]map[string]interface{}, 0, len(config.Tools))
-			for _, tool := range config.Tools {
-				if tool.Name == "" {
-					continue
-				}
-				definition := map[string]interface{}{
-					"name":        tool.Name,
-					"description": tool.Description,
-				}
-				if tool.Parameters != nil {
-					definition["parameters"] = tool.Parameters
-				}
-				toolDefs = append(toolDefs, definition)
-			}
-			if len(toolDefs) > 0 {
-				params["tools"] = toolDefs
-			}
+		if toolDefs := buildToolDefinitions(tools); len(toolDefs) > 0 {
+			params["tools"] = toolDefs
 		}
 		// Add system message configuration if provided
-		if config.SystemMessage != nil {
+		if systemMessageConfig != nil {
 			systemMessage := make(map[string]interface{})
 
-			if config.SystemMessage.Mode != "" {
-				systemMessage["mode"] = config.SystemMessage.Mode
+			if systemMessageConfig.Mode != "" {
+				systemMessage["mode"] = systemMessageConfig.Mode
 			}
 
-			if config.SystemMessage.Mode == "replace" {
-				if config.SystemMessage.Content != "" {
-					systemMessage["content"] = config.SystemMessage.Content
+			if systemMessageConfig.Mode == "replace" {
+				if systemMessageConfig.Content != "" {
+					systemMessage["content"] = systemMessageConfig.Content
 				}
 			} else {
-				if config.SystemMessage.Content != "" {
-					systemMessage["content"] = config.SystemMessage.Content
+				if systemMessageConfig.Content != "" {
+					systemMessage["content"] = systemMessageConfig.Content
 				}
 			}
 
@@ -612,7 +783,7 @@ func (c *Client) CreateSession(config *SessionConfig) (*Session, error) {
 		}
 	}
 
-	result, err := c.client.Request("session.create", params)
+	result, err := client.Request("session.create", params)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create session: %w", err)
 	}
@@ -624,10 +795,31 @@ func (c *Client) CreateSession(config *SessionConfig) (*Session, error) {
 
 	workspacePath, _ := result["workspacePath"].(string)
 
-	session := NewSession(sessionID, c.client, workspacePath)
+	session := NewSession(sessionID, client, workspacePath)
+	c.registerNesting(session, nesting)
+	session.registerResultSanitizer(c.options.ResultSanitizer)
+	session.registerToolLimits(c.toolLimitPolicy())
 
 	if config != nil {
-		session.registerTools(config.Tools)
+		session.registerToolMiddleware(c.options.ToolMiddleware, config.ToolMiddleware)
+		session.registerResultLimit(config.ResultLimit)
+		session.registerCostBudget(config.CostBudget)
+		session.registerStreaming(config.Streaming)
+		session.registerToolPanicHandler(config.OnToolPanic)
+		session.registerApprovalHandler(config.OnToolApproval)
+		session.registerInvocationLimit(config.MaxConcurrentInvocations)
+		session.registerTools(tools)
+		session.registerSkills(config.Skills, config.DisabledSkills, config.Tools, config.Resources)
+		session.registerExperimentProvider(config.ExperimentProvider)
+		session.registerModeration(config.Moderation)
+		session.registerTurnQueue(config.TurnQueue)
+		session.registerMemory(config.Memory)
+		session.registerDryRun(c.dryRunConfig(config.DryRun), tools)
+		session.registerModelFallback(c.modelFallbackConfig(config.ModelFallback), config.Model)
+		session.registerToolBudget(config.ToolBudget, tools)
+		session.registerReplayBuffer(config.ReplayBufferSize)
+		session.registerToolFilter(config.ToolFilter)
+		session.registerToolRegistry(config.ToolRegistry, baseTools)
 		if config.OnPermissionRequest != nil {
 			session.registerPermissionHandler(config.OnPermissionRequest)
 		}
@@ -638,7 +830,9 @@ func (c *Client) CreateSession(config *SessionConfig) (*Session, error) {
 			session.registerHooks(config.Hooks)
 		}
 	} else {
+		session.registerToolMiddleware(c.options.ToolMiddleware, nil)
 		session.registerTools(nil)
+		session.registerModelFallback(c.options.ModelFallback, "")
 	}
 
 	c.sessionsMux.Lock()
@@ -670,42 +864,45 @@ func (c *Client) ResumeSession(sessionID string) (*Session, error) {
 //	    Tools: []copilot.Tool{myNewTool},
 //	})
 func (c *Client) ResumeSessionWithOptions(sessionID string, config *ResumeSessionConfig) (*Session, error) {
-	if c.client == nil {
-		if c.autoStart {
-			if err := c.Start(); err != nil {
-				return nil, err
-			}
-		} else {
-			return nil, fmt.Errorf("client not connected. Call Start() first")
+	client, err := c.autoConnect()
+	if err != nil {
+		return nil, err
+	}
+
+	if config != nil && config.Memory != nil {
+		if err := config.Memory.validate(); err != nil {
+			return nil, err
 		}
 	}
 
+	var parentID string
+	var maxDepth int
+	if config != nil {
+		parentID, maxDepth = config.ParentSessionID, config.MaxDepth
+	}
+	nesting, err := c.resolveNesting(parentID, maxDepth)
+	if err != nil {
+		return nil, err
+	}
+
 	params := map[string]interface{}{
 		"sessionId": sessionID,
 	}
 
+	var tools, baseTools []Tool
 	if config != nil {
+		baseTools = withResourceTools(skillTools(config.Tools, enabledSkills(config.Skills, config.DisabledSkills)), config.Resources)
+		tools = filterTools(withDeprecatedTools(config.ToolRegistry.withTools(baseTools)), config.ToolFilter)
+		var err error
+		if tools, err = c.limitTools(tools); err != nil {
+			return nil, err
+		}
+
 		if config.ReasoningEffort != "" {
 			params["reasoningEffort"] = config.ReasoningEffort
 		}
-		if len(config.Tools) > 0 {
-			toolDefs := make([]map[string]interface{}, 0, len(config.Tools))
-			for _, tool := range config.Tools {
-				if tool.Name == "" {
-					continue
-				}
-				definition := map[string]interface{}{
-					"name":        tool.Name,
-					"description": tool.Description,
-				}
-				if tool.Parameters != nil {
-					definition["parameters"] = tool.Parameters
-				}
-				toolDefs = append(toolDefs, definition)
-			}
-			if len(toolDefs) > 0 {
-				params["tools"] = toolDefs
-			}
+		if toolDefs := buildToolDefinitions(tools); len(toolDefs) > 0 {
+			params["tools"] = toolDefs
 		}
 		if config.Provider != nil {
 			params["provider"] = buildProviderParams(config.Provider)
@@ -780,7 +977,7 @@ func (c *Client) ResumeSessionWithOptions(sessionID string, config *ResumeSessio
 		}
 	}
 
-	result, err := c.client.Request("session.resume", params)
+	result, err := client.Request("session.resume", params)
 	if err != nil {
 		return nil, fmt.Errorf("failed to resume session: %w", err)
 	}
@@ -792,9 +989,30 @@ func (c *Client) ResumeSessionWithOptions(sessionID string, config *ResumeSessio
 
 	workspacePath, _ := result["workspacePath"].(string)
 
-	session := NewSession(resumedSessionID, c.client, workspacePath)
+	session := NewSession(resumedSessionID, client, workspacePath)
+	c.registerNesting(session, nesting)
+	session.registerResultSanitizer(c.options.ResultSanitizer)
+	session.registerToolLimits(c.toolLimitPolicy())
 	if config != nil {
-		session.registerTools(config.Tools)
+		session.registerToolMiddleware(c.options.ToolMiddleware, config.ToolMiddleware)
+		session.registerResultLimit(config.ResultLimit)
+		session.registerCostBudget(config.CostBudget)
+		session.registerStreaming(config.Streaming)
+		session.registerToolPanicHandler(config.OnToolPanic)
+		session.registerApprovalHandler(config.OnToolApproval)
+		session.registerInvocationLimit(config.MaxConcurrentInvocations)
+		session.registerTools(tools)
+		session.registerSkills(config.Skills, config.DisabledSkills, config.Tools, config.Resources)
+		session.registerExperimentProvider(config.ExperimentProvider)
+		session.registerModeration(config.Moderation)
+		session.registerTurnQueue(config.TurnQueue)
+		session.registerMemory(config.Memory)
+		session.registerDryRun(c.dryRunConfig(config.DryRun), tools)
+		session.registerModelFallback(c.modelFallbackConfig(config.ModelFallback), "")
+		session.registerToolBudget(config.ToolBudget, tools)
+		session.registerReplayBuffer(config.ReplayBufferSize)
+		session.registerToolFilter(config.ToolFilter)
+		session.registerToolRegistry(config.ToolRegistry, baseTools)
 		if config.OnPermissionRequest != nil {
 			session.registerPermissionHandler(config.OnPermissionRequest)
 		}
@@ -805,7 +1023,9 @@ func (c *Client) ResumeSessionWithOptions(sessionID string, config *ResumeSessio
 			session.registerHooks(config.Hooks)
 		}
 	} else {
+		session.registerToolMiddleware(c.options.ToolMiddleware, nil)
 		session.registerTools(nil)
+		session.registerModelFallback(c.options.ModelFallback, "")
 	}
 
 	c.sessionsMux.Lock()
@@ -830,17 +1050,12 @@ func (c *Client) ResumeSessionWithOptions(sessionID string, config *ResumeSessio
 //	    fmt.Printf("Session: %s\n", session.SessionID)
 //	}
 func (c *Client) ListSessions() ([]SessionMetadata, error) {
-	if c.client == nil {
-		if c.autoStart {
-			if err := c.Start(); err != nil {
-				return nil, err
-			}
-		} else {
-			return nil, fmt.Errorf("client not connected. Call Start() first")
-		}
+	client, err := c.autoConnect()
+	if err != nil {
+		return nil, err
 	}
 
-	result, err := c.client.Request("session.list", map[string]interface{}{})
+	result, err := client.Request("session.list", map[string]interface{}{})
 	if err != nil {
 		return nil, err
 	}
@@ -870,21 +1085,16 @@ func (c *Client) ListSessions() ([]SessionMetadata, error) {
 //	    log.Fatal(err)
 //	}
 func (c *Client) DeleteSession(sessionID string) error {
-	if c.client == nil {
-		if c.autoStart {
-			if err := c.Start(); err != nil {
-				return err
-			}
-		} else {
-			return fmt.Errorf("client not connected. Call Start() first")
-		}
+	client, err := c.autoConnect()
+	if err != nil {
+		return err
 	}
 
 	params := map[string]interface{}{
 		"sessionId": sessionID,
 	}
 
-	result, err := c.client.Request("session.delete", params)
+	result, err := client.Request("session.delete", params)
 	if err != nil {
 		return err
 	}
@@ -926,6 +1136,8 @@ func (c *Client) DeleteSession(sessionID string) error {
 //	    session, err := client.CreateSession(nil)
 //	}
 func (c *Client) GetState() ConnectionState {
+	c.connectMux.Lock()
+	defer c.connectMux.Unlock()
 	return c.state
 }
 
@@ -943,16 +1155,21 @@ func (c *Client) GetState() ConnectionState {
 //	    log.Printf("Server responded at %d", resp.Timestamp)
 //	}
 func (c *Client) Ping(message string) (*PingResponse, error) {
-	if c.client == nil {
-		return nil, fmt.Errorf("client not connected")
+	client, err := c.connectIfLazy()
+	if err != nil {
+		return nil, err
 	}
+	return ping(client, message)
+}
 
+// ping sends a ping request over client.
+func ping(client *JSONRPCClient, message string) (*PingResponse, error) {
 	params := map[string]interface{}{}
 	if message != "" {
 		params["message"] = message
 	}
 
-	result, err := c.client.Request("ping", params)
+	result, err := client.Request("ping", params)
 	if err != nil {
 		return nil, err
 	}
@@ -968,17 +1185,19 @@ func (c *Client) Ping(message string) (*PingResponse, error) {
 		v := int(pv)
 		response.ProtocolVersion = &v
 	}
+	response.ToolLimits = parseToolLimits(result["toolLimits"])
 
 	return response, nil
 }
 
 // GetStatus returns CLI status including version and protocol information
 func (c *Client) GetStatus() (*GetStatusResponse, error) {
-	if c.client == nil {
-		return nil, fmt.Errorf("client not connected")
+	client, err := c.connectIfLazy()
+	if err != nil {
+		return nil, err
 	}
 
-	result, err := c.client.Request("status.get", map[string]interface{}{})
+	result, err := client.Request("status.get", map[string]interface{}{})
 	if err != nil {
 		return nil, err
 	}
@@ -996,11 +1215,12 @@ func (c *Client) GetStatus() (*GetStatusResponse, error) {
 
 // GetAuthStatus returns current authentication status
 func (c *Client) GetAuthStatus() (*GetAuthStatusResponse, error) {
-	if c.client == nil {
-		return nil, fmt.Errorf("client not connected")
+	client, err := c.connectIfLazy()
+	if err != nil {
+		return nil, err
 	}
 
-	result, err := c.client.Request("auth.getStatus", map[string]interface{}{})
+	result, err := client.Request("auth.getStatus", map[string]interface{}{})
 	if err != nil {
 		return nil, err
 	}
@@ -1025,29 +1245,78 @@ func (c *Client) GetAuthStatus() (*GetAuthStatusResponse, error) {
 	return response, nil
 }
 
-// ListModels returns available models with their metadata.
+// ListModels returns available models with their metadata, such as their
+// context window and whether they support vision and reasoning effort, so
+// applications can adapt features per model.
 //
-// Results are cached after the first successful call to avoid rate limiting.
-// The cache is cleared when the client disconnects.
+// Results are cached after the first successful call to avoid rate limiting,
+// for [ClientOptions.ModelsCacheTTL] if set. The cache is cleared when the
+// client disconnects.
 func (c *Client) ListModels() ([]ModelInfo, error) {
-	if c.client == nil {
-		return nil, fmt.Errorf("client not connected")
+	return c.ListModelsContext(context.Background())
+}
+
+// ListModelsContext is like [Client.ListModels], but stops waiting for the
+// server to list the models when ctx is done. Concurrent calls share one
+// request, so a call made while another is fetching waits for it; a call
+// that stops waiting leaves the request to finish and fill the cache for the
+// others.
+func (c *Client) ListModelsContext(ctx context.Context) ([]ModelInfo, error) {
+	client, err := c.connectIfLazy()
+	if err != nil {
+		return nil, err
 	}
 
-	// Use mutex for locking to prevent race condition with concurrent calls
 	c.modelsCacheMux.Lock()
-	defer c.modelsCacheMux.Unlock()
-
-	// Check cache (already inside lock)
-	if c.modelsCache != nil {
+	if c.modelsCache != nil && (c.options.ModelsCacheTTL <= 0 || time.Since(c.modelsCachedAt) < c.options.ModelsCacheTTL) {
 		// Return a copy to prevent cache mutation
 		result := make([]ModelInfo, len(c.modelsCache))
 		copy(result, c.modelsCache)
+		c.modelsCacheMux.Unlock()
 		return result, nil
 	}
+	call := c.modelsFetch
+	if call == nil {
+		// The request is shared, so it is not bound to this caller's ctx.
+		call = &modelsCall{done: make(chan struct{})}
+		c.modelsFetch = call
+		go c.fetchModels(client, call)
+	}
+	c.modelsCacheMux.Unlock()
 
-	// Cache miss - fetch from backend while holding lock
-	result, err := c.client.Request("models.list", map[string]interface{}{})
+	select {
+	case <-call.done:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	if call.err != nil {
+		return nil, call.err
+	}
+	// Return a copy to prevent cache mutation
+	models := make([]ModelInfo, len(call.models))
+	copy(models, call.models)
+	return models, nil
+}
+
+// fetchModels completes call with the models client lists, and caches them
+// unless the client has disconnected from client meanwhile.
+func (c *Client) fetchModels(client *JSONRPCClient, call *modelsCall) {
+	defer close(call.done)
+	call.models, call.err = listModels(client)
+	current := c.rpc()
+
+	c.modelsCacheMux.Lock()
+	defer c.modelsCacheMux.Unlock()
+	c.modelsFetch = nil
+	if call.err == nil && current == client {
+		c.modelsCache = call.models
+		c.modelsCachedAt = time.Now()
+	}
+}
+
+// listModels requests the models client's server offers.
+func listModels(client *JSONRPCClient) ([]ModelInfo, error) {
+	result, err := client.Request("models.list", map[string]interface{}{})
 	if err != nil {
 		return nil, err
 	}
@@ -1062,20 +1331,36 @@ func (c *Client) ListModels() ([]ModelInfo, error) {
 	if err := json.Unmarshal(jsonBytes, &response); err != nil {
 		return nil, fmt.Errorf("failed to unmarshal models response: %w", err)
 	}
+	return response.Models, nil
+}
 
-	// Update cache before releasing lock
-	c.modelsCache = response.Models
-
-	// Return a copy to prevent cache mutation
-	models := make([]ModelInfo, len(response.Models))
-	copy(models, response.Models)
-	return models, nil
+// GetModel returns the model with the given ID from [Client.ListModels], or
+// an error if the server does not offer it.
+//
+// Example:
+//
+//	model, err := client.GetModel("gpt-5")
+//	if err == nil && model.Capabilities.Supports.Vision {
+//	    options.Attachments = append(options.Attachments, screenshot)
+//	}
+func (c *Client) GetModel(id string) (*ModelInfo, error) {
+	models, err := c.ListModels()
+	if err != nil {
+		return nil, err
+	}
+	for i := range models {
+		if models[i].ID == id {
+			return &models[i], nil
+		}
+	}
+	return nil, fmt.Errorf("model %s is not available", id)
 }
 
-// verifyProtocolVersion verifies that the server's protocol version matches the SDK's expected version
-func (c *Client) verifyProtocolVersion() error {
+// verifyProtocolVersion verifies that the protocol version of the server at
+// the other end of client matches the SDK's expected version
+func (c *Client) verifyProtocolVersion(client *JSONRPCClient) error {
 	expectedVersion := GetSdkProtocolVersion()
-	pingResult, err := c.Ping("")
+	pingResult, err := ping(client, "")
 	if err != nil {
 		return err
 	}
@@ -1087,6 +1372,7 @@ func (c *Client) verifyProtocolVersion() error {
 	if *pingResult.ProtocolVersion != expectedVersion {
 		return fmt.Errorf("SDK protocol version mismatch: SDK expects version %d, but server reports version %d. Please update your SDK or server to ensure compatibility", expectedVersion, *pingResult.ProtocolVersion)
 	}
+	c.serverToolLimits.Store(pingResult.ToolLimits)
 
 	return nil
 }
@@ -1094,8 +1380,10 @@ func (c *Client) verifyProtocolVersion() error {
 // startCLIServer starts the CLI server process.
 //
 // This spawns the CLI server as a subprocess using the configured transport
-// mode (stdio or TCP).
-func (c *Client) startCLIServer() error {
+// mode (stdio or TCP). In stdio mode, it returns the connection over the
+// process's stdio; in TCP mode, it returns nil once the process announces its
+// port.
+func (c *Client) startCLIServer() (*JSONRPCClient, error) {
 	args := []string{"--server", "--log-level", c.options.LogLevel}
 
 	// Choose transport mode
@@ -1145,17 +1433,17 @@ func (c *Client) startCLIServer() error {
 		// For stdio mode, we need stdin/stdout pipes
 		stdin, err := c.process.StdinPipe()
 		if err != nil {
-			return fmt.Errorf("failed to create stdin pipe: %w", err)
+			return nil, fmt.Errorf("failed to create stdin pipe: %w", err)
 		}
 
 		stdout, err := c.process.StdoutPipe()
 		if err != nil {
-			return fmt.Errorf("failed to create stdout pipe: %w", err)
+			return nil, fmt.Errorf("failed to create stdout pipe: %w", err)
 		}
 
 		stderr, err := c.process.StderrPipe()
 		if err != nil {
-			return fmt.Errorf("failed to create stderr pipe: %w", err)
+			return nil, fmt.Errorf("failed to create stderr pipe: %w", err)
 		}
 
 		// Read stderr in background
@@ -1167,25 +1455,40 @@ func (c *Client) startCLIServer() error {
 			}
 		}()
 
-		if err := c.process.Start(); err != nil {
-			return fmt.Errorf("failed to start CLI server: %w", err)
+		var reader io.ReadCloser = stdout
+		if channel := c.options.StdioChannel; channel != nil {
+			if err := channel.Configure(c.process); err != nil {
+				return nil, fmt.Errorf("failed to configure stdio channel: %w", err)
+			}
+		}
+
+		if err := c.startProcess(); err != nil {
+			abortStdioChannel(c.options.StdioChannel, c.process)
+			return nil, fmt.Errorf("failed to start CLI server: %w", err)
+		}
+
+		if channel := c.options.StdioChannel; channel != nil {
+			if stdin, reader, err = channel.Wrap(c.process, stdin, stdout); err != nil {
+				c.process.Process.Kill()
+				return nil, fmt.Errorf("failed to set up stdio channel: %w", err)
+			}
 		}
 
 		// Create JSON-RPC client immediately
-		c.client = NewJSONRPCClient(stdin, stdout)
-		c.setupNotificationHandler()
-		c.client.Start()
+		client := NewJSONRPCClient(stdin, reader)
+		c.setupNotificationHandler(client)
+		client.Start()
 
-		return nil
+		return client, nil
 	} else {
 		// For TCP mode, capture stdout to get port number
 		stdout, err := c.process.StdoutPipe()
 		if err != nil {
-			return fmt.Errorf("failed to create stdout pipe: %w", err)
+			return nil, fmt.Errorf("failed to create stdout pipe: %w", err)
 		}
 
-		if err := c.process.Start(); err != nil {
-			return fmt.Errorf("failed to start CLI server: %w", err)
+		if err := c.startProcess(); err != nil {
+			return nil, fmt.Errorf("failed to start CLI server: %w", err)
 		}
 
 		// Wait for port announcement
@@ -1196,17 +1499,17 @@ func (c *Client) startCLIServer() error {
 		for {
 			select {
 			case <-timeout:
-				return fmt.Errorf("timeout waiting for CLI server to start")
+				return nil, fmt.Errorf("timeout waiting for CLI server to start")
 			default:
 				if scanner.Scan() {
 					line := scanner.Text()
 					if matches := portRegex.FindStringSubmatch(line); len(matches) > 1 {
 						port, err := strconv.Atoi(matches[1])
 						if err != nil {
-							return fmt.Errorf("failed to parse port: %w", err)
+							return nil, fmt.Errorf("failed to parse port: %w", err)
 						}
 						c.actualPort = port
-						return nil
+						return nil, nil
 					}
 				}
 			}
@@ -1214,43 +1517,137 @@ func (c *Client) startCLIServer() error {
 	}
 }
 
-// connectToServer establishes a connection to the server.
-func (c *Client) connectToServer() error {
-	if c.useStdio {
-		// Already connected via stdio in startCLIServer
+// ensureConnected starts the client if it is not connected, and returns its
+// connection. Concurrent callers share a single connection attempt; a failed
+// attempt is retried by the next call.
+func (c *Client) ensureConnected() (*JSONRPCClient, error) {
+	c.connectMux.Lock()
+	if c.client != nil && c.state == StateConnected {
+		client := c.client
+		c.connectMux.Unlock()
+		return client, nil
+	}
+	if call := c.connecting; call != nil {
+		c.connectMux.Unlock()
+		<-call.done
+		if call.err != nil {
+			return nil, call.err
+		}
+		return c.connected()
+	}
+	call := &connectCall{done: make(chan struct{})}
+	c.connecting = call
+	c.connectMux.Unlock()
+
+	call.err = c.start()
+
+	c.connectMux.Lock()
+	c.connecting = nil
+	c.connectMux.Unlock()
+	close(call.done)
+	if call.err != nil {
+		return nil, call.err
+	}
+	return c.connected()
+}
+
+// connectIfLazy returns the client's connection, connecting on first use when
+// LazyConnect is enabled, and otherwise reports an error if the client is not
+// connected.
+func (c *Client) connectIfLazy() (*JSONRPCClient, error) {
+	if client := c.rpc(); client != nil {
+		return client, nil
+	}
+	if !c.lazyConnect {
+		return nil, fmt.Errorf("client not connected")
+	}
+	return c.ensureConnected()
+}
+
+// autoConnect returns the client's connection, starting the client first if
+// AutoStart is enabled.
+func (c *Client) autoConnect() (*JSONRPCClient, error) {
+	if client := c.rpc(); client != nil {
+		return client, nil
+	}
+	if !c.autoStart {
+		return nil, fmt.Errorf("client not connected. Call Start() first")
+	}
+	return c.ensureConnected()
+}
+
+// connected returns the client's connection, or an error if it was stopped
+// since connecting.
+func (c *Client) connected() (*JSONRPCClient, error) {
+	if client := c.rpc(); client != nil {
+		return client, nil
+	}
+	return nil, fmt.Errorf("client not connected")
+}
+
+// rpc returns the client's connection, or nil if it is not connected. The
+// connection is published under connectMux, so callers that may race with a
+// lazy or automatic connect read it here rather than from c.client.
+func (c *Client) rpc() *JSONRPCClient {
+	c.connectMux.Lock()
+	defer c.connectMux.Unlock()
+	if c.state != StateConnected {
 		return nil
 	}
+	return c.client
+}
+
+// setRPC publishes client as the client's connection and returns the previous
+// one.
+func (c *Client) setRPC(client *JSONRPCClient) *JSONRPCClient {
+	c.connectMux.Lock()
+	defer c.connectMux.Unlock()
+	previous := c.client
+	c.client = client
+	return previous
+}
 
-	// Connect via TCP
-	return c.connectViaTcp()
+func (c *Client) setState(state ConnectionState) {
+	c.connectMux.Lock()
+	c.state = state
+	c.connectMux.Unlock()
 }
 
 // connectViaTcp connects to the CLI server via TCP socket.
-func (c *Client) connectViaTcp() error {
+func (c *Client) connectViaTcp() (*JSONRPCClient, error) {
 	if c.actualPort == 0 {
-		return fmt.Errorf("server port not available")
+		return nil, fmt.Errorf("server port not available")
 	}
 
 	// Create TCP connection with 10 second timeout
 	address := net.JoinHostPort(c.actualHost, fmt.Sprintf("%d", c.actualPort))
 	conn, err := net.DialTimeout("tcp", address, 10*time.Second)
 	if err != nil {
-		return fmt.Errorf("failed to connect to CLI server at %s: %w", address, err)
+		return nil, fmt.Errorf("failed to connect to CLI server at %s: %w", address, err)
 	}
 
 	c.conn = conn
 
 	// Create JSON-RPC client with the connection
-	c.client = NewJSONRPCClient(conn, conn)
-	c.setupNotificationHandler()
-	c.client.Start()
+	client := NewJSONRPCClient(conn, conn)
+	c.setupNotificationHandler(client)
+	client.Start()
 
-	return nil
+	return client, nil
 }
 
-// setupNotificationHandler configures handlers for session events, tool calls, and permission requests.
-func (c *Client) setupNotificationHandler() {
-	c.client.SetNotificationHandler(func(method string, params map[string]interface{}) {
+// setupNotificationHandler configures client's handlers for session events,
+// tool calls, and permission requests.
+func (c *Client) setupNotificationHandler(client *JSONRPCClient) {
+	client.limiter = c.concurrency
+	client.journal = c.journal
+	client.protocolErrors = c.protocolErrors
+	if c.flow != nil {
+		// The read loop reads flow without locking, so it is only set before
+		// the client starts.
+		client.flow = c.flow
+	}
+	client.SetNotificationHandler(func(method string, params map[string]interface{}) {
 		if method == "session.event" {
 			// Extract sessionId and event
 			sessionID, ok := params["sessionId"].(string)
@@ -1275,15 +1672,18 @@ func (c *Client) setupNotificationHandler() {
 			c.sessionsMux.Unlock()
 
 			if ok {
-				session.dispatchEvent(event)
+				session.recordCitations(event.ID, parseCitations(eventJSON))
+				session.dispatchServerEvent(event, len(eventJSON))
 			}
+		} else if record, ok := parseLogRecord(method, params); ok {
+			c.handleLogRecord(record)
 		}
 	})
 
-	c.client.SetRequestHandler("tool.call", c.handleToolCallRequest)
-	c.client.SetRequestHandler("permission.request", c.handlePermissionRequest)
-	c.client.SetRequestHandler("userInput.request", c.handleUserInputRequest)
-	c.client.SetRequestHandler("hooks.invoke", c.handleHooksInvoke)
+	client.SetRequestHandler("tool.call", c.handleToolCallRequest)
+	client.SetRequestHandler("permission.request", c.handlePermissionRequest)
+	client.SetRequestHandler("userInput.request", c.handleUserInputRequest)
+	client.SetRequestHandler("hooks.invoke", c.handleHooksInvoke)
 }
 
 // handleToolCallRequest handles a tool call request from the CLI server.
@@ -1303,47 +1703,140 @@ func (c *Client) handleToolCallRequest(params map[string]interface{}) (map[strin
 		return nil, &JSONRPCError{Code: -32602, Message: fmt.Sprintf("unknown session %s", sessionID)}
 	}
 
+	if !session.experimentAllowsTool(toolName) {
+		return map[string]interface{}{"result": buildExperimentExcludedToolResult(toolName)}, nil
+	}
+
 	handler, ok := session.getToolHandler(toolName)
 	if !ok {
+		if skillName := session.disabledSkillForTool(toolName); skillName != "" {
+			return map[string]interface{}{"result": buildDisabledSkillToolResult(toolName, skillName)}, nil
+		}
 		return map[string]interface{}{"result": buildUnsupportedToolResult(toolName)}, nil
 	}
 
 	arguments := params["arguments"]
-	result := c.executeToolCall(sessionID, toolCallID, toolName, arguments, handler)
+	if result, ok := session.simulateToolCall(toolCallID, toolName, arguments); ok {
+		return map[string]interface{}{
+			"result":         result,
+			"idempotencyKey": toolIdempotencyKey(sessionID, toolCallID),
+		}, nil
+	}
 
-	return map[string]interface{}{"result": result}, nil
+	result := c.executeToolCall(session.toolContext(), sessionID, toolCallID, toolName, arguments, handler)
+	if result.pending != "" {
+		return nil, deferResponse(func(respond responder) {
+			session.pendingCalls.attach(result.pending, func(final ToolResult) {
+				respond(map[string]interface{}{
+					"result":         final,
+					"idempotencyKey": toolIdempotencyKey(sessionID, toolCallID),
+				}, nil)
+			})
+		})
+	}
+
+	return map[string]interface{}{
+		"result":         result,
+		"idempotencyKey": toolIdempotencyKey(sessionID, toolCallID),
+	}, nil
+}
+
+// sessionByID returns the client's session with the given ID, or nil.
+func (c *Client) sessionByID(sessionID string) *Session {
+	c.sessionsMux.Lock()
+	defer c.sessionsMux.Unlock()
+	return c.sessions[sessionID]
 }
 
 // executeToolCall executes a tool handler and returns the result.
 func (c *Client) executeToolCall(
+	ctx context.Context,
 	sessionID, toolCallID, toolName string,
 	arguments interface{},
 	handler ToolHandler,
 ) (result ToolResult) {
 	invocation := ToolInvocation{
-		SessionID:  sessionID,
-		ToolCallID: toolCallID,
-		ToolName:   toolName,
-		Arguments:  arguments,
+		SessionID:      sessionID,
+		ToolCallID:     toolCallID,
+		ToolName:       toolName,
+		Arguments:      arguments,
+		IdempotencyKey: toolIdempotencyKey(sessionID, toolCallID),
+		ctx:            ctx,
+		outbox:         &toolOutbox{},
+	}
+	if c.options.Secrets != nil {
+		invocation.secrets = &secretSource{provider: c.options.Secrets, onAccess: c.options.OnSecretAccess}
+	}
+	session := c.sessionByID(sessionID)
+	if session != nil {
+		invocation.kv = session.ToolKV()
+		invocation.pending = &session.pendingCalls
+	}
+
+	var err error
+	deferred := false
+	observeResult := c.observeToolInvocation(invocation)
+	defer func() {
+		if !deferred {
+			observeResult(result, err)
+		}
+	}()
+
+	// Failure results built here from handler errors and panics have not
+	// passed through the session's result sanitizer.
+	sanitizeFailure := func(result ToolResult) ToolResult {
+		if session == nil {
+			return result
+		}
+		return session.sanitizeToolResult(invocation, result)
 	}
 
 	defer func() {
 		if r := recover(); r != nil {
-			fmt.Printf("Tool handler panic (%s): %v\n", toolName, r)
-			result = buildFailedToolResult(fmt.Sprintf("tool panic: %v", r))
+			invocation.outbox.take()
+			result = sanitizeFailure(c.handleToolPanic(sessionID, newToolPanicError(invocation, r)))
 		}
 	}()
 
-	var err error
 	if handler != nil {
 		result, err = handler(invocation)
 	}
 
+	notifications := invocation.outbox.take()
+	if result.pending != "" && invocation.pending != nil {
+		if err == nil {
+			// The response is sent when the call is completed.
+			deferred = true
+			invocation.pending.prepare(result.pending, func(final ToolResult) ToolResult {
+				if session != nil {
+					final = session.sanitizeToolResult(invocation, final)
+				}
+				observeResult(final, nil)
+				return c.finishToolCall(invocation, final, notifications)
+			})
+			c.emitToolCallDeferred(invocation, result.pending)
+			return result
+		}
+		invocation.pending.drop(result.pending)
+	}
+	if panicErr, ok := asToolPanic(err); ok {
+		return sanitizeFailure(c.handleToolPanic(sessionID, panicErr))
+	}
 	if err != nil {
-		return buildFailedToolResult(err.Error())
+		return sanitizeFailure(buildHandlerErrorResult(err))
+	}
+	return c.finishToolCall(invocation, result, notifications)
+}
+
+// finishToolCall emits the notifications and display data of a tool call's
+// result and encodes it to be sent.
+func (c *Client) finishToolCall(invocation ToolInvocation, result ToolResult, notifications []ToolNotification) ToolResult {
+	if result.ResultType != "failure" {
+		c.flushToolNotifications(invocation, notifications)
 	}
+	c.emitToolResultDisplay(invocation, result)
 
-	return result
+	return encodeAttachments(result)
 }
 
 // handlePermissionRequest handles a permission request from the CLI server.
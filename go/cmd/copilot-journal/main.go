@@ -0,0 +1,74 @@
+// Command copilot-journal dumps a journal written by a client with a
+// [copilot.JournalConfig], to establish what the client last sent the server
+// before a crash.
+//
+// Usage:
+//
+//	copilot-journal [-tail n] [-method name] [-kind kind] [-frames] journal
+//
+// By default each frame is listed on one line with its sequence number, time,
+// kind, ID, and method. -frames prints the frames themselves, one JSON object
+// per line.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	copilot "github.com/github/copilot-sdk/go"
+)
+
+func main() {
+	tail := flag.Int("tail", 0, "show only the last `n` matching frames")
+	method := flag.String("method", "", "show only requests and notifications of this `method`")
+	kind := flag.String("kind", "", "show only frames of this `kind`: request, notification, response, or error")
+	frames := flag.Bool("frames", false, "print the frames as JSON instead of a summary")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "usage: copilot-journal [flags] journal\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() != 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	file, err := os.Open(flag.Arg(0))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	defer file.Close()
+	journal, err := copilot.ReadJournal(file)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	var entries []copilot.JournalEntry
+	for _, entry := range journal.Entries {
+		if (*method == "" || entry.Method() == *method) && (*kind == "" || entry.Kind() == *kind) {
+			entries = append(entries, entry)
+		}
+	}
+	if *tail > 0 && len(entries) > *tail {
+		entries = entries[len(entries)-*tail:]
+	}
+	dump(os.Stdout, entries, *frames)
+	if journal.Truncated {
+		fmt.Fprintln(os.Stderr, "journal ends with a partly written frame")
+	}
+}
+
+func dump(w io.Writer, entries []copilot.JournalEntry, frames bool) {
+	for _, entry := range entries {
+		if frames {
+			fmt.Fprintf(w, "%s\n", entry.Frame)
+			continue
+		}
+		fmt.Fprintf(w, "%6d  %s  %-12s  %-6s  %s\n", entry.Seq, entry.Time.Format(time.RFC3339Nano), entry.Kind(), entry.ID(), entry.Method())
+	}
+}
@@ -0,0 +1,160 @@
+// Command copilot-schemagen generates [copilot.RegisterFieldDescriptions]
+// calls from the doc comments of struct fields, so that tool parameter types
+// can be documented once, in Go, instead of again in jsonschema struct tags.
+//
+// Usage:
+//
+//	copilot-schemagen -type T[,T...] [-output file] [dir]
+//
+// It is meant to be run by go generate, from the package that declares the
+// types:
+//
+//	//go:generate go run github.com/github/copilot-sdk/go/cmd/copilot-schemagen -type GetWeatherParams,ForecastParams
+//
+// The doc comment of each exported field, or its line comment if it has no doc
+// comment, becomes the field's description, joined onto one line. Fields
+// without comments are left out; descriptions in jsonschema tags still take
+// precedence over the generated ones. The output defaults to
+// <type>_schemadoc.go, after the first type, in dir, which defaults to the
+// current directory.
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"go/ast"
+	"go/build"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+func main() {
+	typeNames := flag.String("type", "", "comma-separated list of struct `types` to describe; required")
+	output := flag.String("output", "", "output `file`; defaults to <type>_schemadoc.go")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "usage: copilot-schemagen -type T[,T...] [flags] [dir]\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if *typeNames == "" || flag.NArg() > 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+	dir := "."
+	if flag.NArg() == 1 {
+		dir = flag.Arg(0)
+	}
+	types := strings.Split(*typeNames, ",")
+	if *output == "" {
+		*output = strings.ToLower(types[0]) + "_schemadoc.go"
+	}
+	path := filepath.Join(dir, *output)
+
+	src, err := generate(dir, types, filepath.Base(path), strings.Join(os.Args[1:], " "))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "copilot-schemagen: %v\n", err)
+		os.Exit(1)
+	}
+	if err := os.WriteFile(path, src, 0o644); err != nil {
+		fmt.Fprintf(os.Stderr, "copilot-schemagen: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// fieldDoc is the description of one struct field.
+type fieldDoc struct {
+	name, description string
+}
+
+// generate returns the source of a file registering the field descriptions of
+// types, which are declared in the package in dir. The file output, if it
+// exists, is not read, so that stale output cannot break generation. args are
+// the command's arguments, recorded in the file's header.
+func generate(dir string, types []string, output, args string) ([]byte, error) {
+	pkg, err := build.ImportDir(dir, 0)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load package: %w", err)
+	}
+
+	wanted := make(map[string]bool, len(types))
+	for _, name := range types {
+		wanted[name] = true
+	}
+	found := make(map[string][]fieldDoc)
+	fset := token.NewFileSet()
+	for _, name := range pkg.GoFiles {
+		if name == output {
+			continue
+		}
+		file, err := parser.ParseFile(fset, filepath.Join(dir, name), nil, parser.ParseComments)
+		if err != nil {
+			return nil, err
+		}
+		for _, decl := range file.Decls {
+			gen, ok := decl.(*ast.GenDecl)
+			if !ok || gen.Tok != token.TYPE {
+				continue
+			}
+			for _, spec := range gen.Specs {
+				typeSpec := spec.(*ast.TypeSpec)
+				if !wanted[typeSpec.Name.Name] {
+					continue
+				}
+				structType, ok := typeSpec.Type.(*ast.StructType)
+				if !ok {
+					return nil, fmt.Errorf("%s is not a struct type", typeSpec.Name.Name)
+				}
+				if typeSpec.TypeParams != nil {
+					return nil, fmt.Errorf("%s has type parameters, which are not supported", typeSpec.Name.Name)
+				}
+				found[typeSpec.Name.Name] = fieldDocs(structType)
+			}
+		}
+	}
+
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "// Code generated by \"copilot-schemagen %s\"; DO NOT EDIT.\n\n", args)
+	fmt.Fprintf(&b, "package %s\n\n", pkg.Name)
+	fmt.Fprintf(&b, "import copilot %q\n\n", "github.com/github/copilot-sdk/go")
+	fmt.Fprintf(&b, "func init() {\n")
+	for _, name := range types {
+		docs, ok := found[name]
+		if !ok {
+			return nil, fmt.Errorf("type %s not found in %s", name, dir)
+		}
+		fmt.Fprintf(&b, "copilot.RegisterFieldDescriptions[%s](map[string]string{\n", name)
+		for _, doc := range docs {
+			fmt.Fprintf(&b, "%q: %q,\n", doc.name, doc.description)
+		}
+		fmt.Fprintf(&b, "})\n")
+	}
+	fmt.Fprintf(&b, "}\n")
+	return format.Source(b.Bytes())
+}
+
+// fieldDocs returns the descriptions of the exported, commented fields of a
+// struct, in declaration order.
+func fieldDocs(structType *ast.StructType) []fieldDoc {
+	var docs []fieldDoc
+	for _, field := range structType.Fields.List {
+		comment := field.Doc
+		if comment == nil {
+			comment = field.Comment
+		}
+		description := strings.Join(strings.Fields(comment.Text()), " ")
+		if description == "" {
+			continue
+		}
+		for _, name := range field.Names {
+			if name.IsExported() {
+				docs = append(docs, fieldDoc{name: name.Name, description: description})
+			}
+		}
+	}
+	return docs
+}
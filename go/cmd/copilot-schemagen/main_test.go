@@ -0,0 +1,57 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestGenerate(t *testing.T) {
+	dir := t.TempDir()
+	src := `package weather
+
+// GetWeatherParams are the parameters of get_weather.
+type GetWeatherParams struct {
+	// City is the name of the city,
+	// such as "Paris".
+	City string ` + "`json:\"city\"`" + `
+	Unit string ` + "`json:\"unit\"`" + ` // Unit is celsius or fahrenheit.
+	Days int
+	// internal is not part of the schema.
+	internal bool
+}
+`
+	if err := os.WriteFile(filepath.Join(dir, "weather.go"), []byte(src), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	// Stale output is ignored.
+	if err := os.WriteFile(filepath.Join(dir, "getweatherparams_schemadoc.go"), []byte("package weather\n\nbroken"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	out, err := generate(dir, []string{"GetWeatherParams"}, "getweatherparams_schemadoc.go", "-type GetWeatherParams")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	want := `// Code generated by "copilot-schemagen -type GetWeatherParams"; DO NOT EDIT.
+
+package weather
+
+import copilot "github.com/github/copilot-sdk/go"
+
+func init() {
+	copilot.RegisterFieldDescriptions[GetWeatherParams](map[string]string{
+		"City": "City is the name of the city, such as \"Paris\".",
+		"Unit": "Unit is celsius or fahrenheit.",
+	})
+}
+`
+	if string(out) != want {
+		t.Errorf("Unexpected output:\n%s", out)
+	}
+
+	if _, err := generate(dir, []string{"Missing"}, "getweatherparams_schemadoc.go", ""); err == nil || !strings.Contains(err.Error(), "not found") {
+		t.Errorf("Expected an error for a missing type, got %v", err)
+	}
+}
@@ -0,0 +1,330 @@
+package copilot
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"text/template"
+	"unicode"
+)
+
+// Command is a slash command handled by a [CommandRouter]. A command either
+// expands to a prompt for the model, with Template, or is handled directly,
+// without the model, with Run.
+type Command struct {
+	// Name is the command's name without the leading slash, such as "fix".
+	Name string
+	// Aliases are alternative names for the command.
+	Aliases []string
+	// Description is a one-line description shown in help.
+	Description string
+	// Usage describes the command's arguments in help, such as "<file> [line]".
+	Usage string
+	// Template is a text/template for the prompt sent to the model. It is
+	// executed with a [CommandInput].
+	Template string
+	// Run, if set, handles the command directly instead of sending a prompt. Its
+	// output is returned to the frontend.
+	Run func(input CommandInput) (string, error)
+}
+
+// CommandInput is a parsed slash command, passed to [Command.Run] and to
+// command templates.
+type CommandInput struct {
+	// Command is the name the command was invoked with, without the slash.
+	Command string
+	// Args is everything after the command name, with surrounding whitespace
+	// removed.
+	Args string
+	// Fields are Args split on whitespace.
+	Fields []string
+}
+
+// CommandResponse describes how a [CommandRouter] handled an input.
+type CommandResponse struct {
+	// Command is the name of the command that handled the input, or empty if the
+	// input was not a command and was sent to the model as is.
+	Command string
+	// MessageID is the ID of the message sent to the model, if any.
+	MessageID string
+	// Output is the output of a command handled directly.
+	Output string
+}
+
+// UnknownCommandError is returned for inputs that start with a slash but name
+// no registered command.
+type UnknownCommandError struct {
+	Command string
+	// Suggestions are registered commands with similar names.
+	Suggestions []string
+}
+
+func (e *UnknownCommandError) Error() string {
+	if len(e.Suggestions) == 0 {
+		return fmt.Sprintf("unknown command /%s", e.Command)
+	}
+	return fmt.Sprintf("unknown command /%s (did you mean /%s?)", e.Command, strings.Join(e.Suggestions, ", /"))
+}
+
+// CommandRouter routes user inputs that begin with a registered slash command,
+// such as "/fix main.go", to a prompt template or a direct handler before they
+// reach the model, so that every chat frontend gets the same commands. A
+// built-in /help command lists the registered commands.
+//
+// Inputs that do not start with a slash are sent as is. Start an input with two
+// slashes to send a literal leading slash, such as "//etc/hosts is missing".
+//
+// Example:
+//
+//	router, err := copilot.NewCommandRouter(
+//	    copilot.Command{
+//	        Name:        "explain",
+//	        Usage:       "<symbol>",
+//	        Description: "Explain how a symbol works",
+//	        Template:    "Explain how {{.Args}} works, with examples from this repository.",
+//	    },
+//	    copilot.ToolCommand("deploy", "Deploy to an environment", deployTool, func(input copilot.CommandInput) (map[string]interface{}, error) {
+//	        return map[string]interface{}{"environment": input.Args}, nil
+//	    }),
+//	)
+//	response, err := router.Send(session, copilot.MessageOptions{Prompt: userInput})
+type CommandRouter struct {
+	commands map[string]*registeredCommand
+	names    []string
+}
+
+type registeredCommand struct {
+	Command
+	template *template.Template
+}
+
+// NewCommandRouter creates a router for commands. It returns an error if a
+// command has no name, has both or neither of Template and Run, has an invalid
+// template, or its name or an alias is already taken.
+func NewCommandRouter(commands ...Command) (*CommandRouter, error) {
+	r := &CommandRouter{commands: make(map[string]*registeredCommand)}
+	help := Command{Name: "help", Usage: "[command]", Description: "Show available commands", Run: r.runHelp}
+	for _, command := range append([]Command{help}, commands...) {
+		if err := r.register(command); err != nil {
+			return nil, err
+		}
+	}
+	return r, nil
+}
+
+func (r *CommandRouter) register(command Command) error {
+	if command.Name == "" || strings.ContainsAny(command.Name, " \t\n/") {
+		return fmt.Errorf("invalid command name %q", command.Name)
+	}
+	if (command.Template == "") == (command.Run == nil) {
+		return fmt.Errorf("command /%s: exactly one of Template and Run is required", command.Name)
+	}
+
+	registered := &registeredCommand{Command: command}
+	if command.Template != "" {
+		tmpl, err := template.New(command.Name).Option("missingkey=error").Parse(command.Template)
+		if err != nil {
+			return fmt.Errorf("command /%s: invalid template: %w", command.Name, err)
+		}
+		registered.template = tmpl
+	}
+
+	for _, name := range append([]string{command.Name}, command.Aliases...) {
+		key := strings.ToLower(name)
+		if _, exists := r.commands[key]; exists {
+			return fmt.Errorf("command /%s is already registered", name)
+		}
+		r.commands[key] = registered
+	}
+	r.names = append(r.names, command.Name)
+	return nil
+}
+
+// ToolCommand returns a command that invokes tool directly, without the model.
+// args converts the command's input to the tool's arguments; the tool's text
+// result is the command's output.
+func ToolCommand(name, description string, tool Tool, args func(input CommandInput) (map[string]interface{}, error)) Command {
+	return Command{
+		Name:        name,
+		Description: description,
+		Run: func(input CommandInput) (string, error) {
+			arguments, err := args(input)
+			if err != nil {
+				return "", err
+			}
+			result, err := tool.Handler(ToolInvocation{
+				ToolCallID: "command-" + generateUUID(),
+				ToolName:   tool.Name,
+				Arguments:  arguments,
+			})
+			if err != nil {
+				return "", err
+			}
+			if result.ResultType == "failure" {
+				return "", fmt.Errorf("tool %s failed: %s", tool.Name, result.TextResultForLLM)
+			}
+			return result.TextResultForLLM, nil
+		},
+	}
+}
+
+// ParseCommand splits input into a command and its arguments. It returns false for
+// inputs that are not commands.
+func ParseCommand(input string) (CommandInput, bool) {
+	trimmed := strings.TrimSpace(input)
+	if !strings.HasPrefix(trimmed, "/") || strings.HasPrefix(trimmed, "//") || len(trimmed) == 1 {
+		return CommandInput{}, false
+	}
+	name, args := trimmed[1:], ""
+	if i := strings.IndexFunc(name, unicode.IsSpace); i >= 0 {
+		name, args = name[:i], strings.TrimSpace(name[i:])
+	}
+	return CommandInput{Command: name, Args: args, Fields: strings.Fields(args)}, true
+}
+
+// Route resolves input. For a command with a template, it returns the
+// expanded prompt; for a command with a Run handler, it runs it and returns its
+// output as the response. For an input that is not a command, it returns the
+// input, without an escaping slash, as the prompt.
+func (r *CommandRouter) Route(input string) (prompt string, response *CommandResponse, err error) {
+	parsed, ok := ParseCommand(input)
+	if !ok {
+		trimmed := strings.TrimLeft(input, " \t\n")
+		if strings.HasPrefix(trimmed, "//") {
+			return trimmed[1:], &CommandResponse{}, nil
+		}
+		return input, &CommandResponse{}, nil
+	}
+
+	command, ok := r.commands[strings.ToLower(parsed.Command)]
+	if !ok {
+		return "", nil, &UnknownCommandError{Command: parsed.Command, Suggestions: r.suggest(parsed.Command)}
+	}
+	response = &CommandResponse{Command: command.Name}
+
+	if command.Run != nil {
+		output, err := command.Run(parsed)
+		if err != nil {
+			return "", nil, fmt.Errorf("command /%s failed: %w", command.Name, err)
+		}
+		response.Output = output
+		return "", response, nil
+	}
+
+	var b strings.Builder
+	if err := command.template.Execute(&b, parsed); err != nil {
+		return "", nil, fmt.Errorf("command /%s: failed to expand template: %w", command.Name, err)
+	}
+	return b.String(), response, nil
+}
+
+// Send routes options.Prompt and, unless the command was handled directly,
+// sends the resulting prompt to session with the rest of options.
+func (r *CommandRouter) Send(session *Session, options MessageOptions) (*CommandResponse, error) {
+	prompt, response, err := r.Route(options.Prompt)
+	if err != nil {
+		return nil, err
+	}
+	if response.Command != "" && r.commands[strings.ToLower(response.Command)].Run != nil {
+		return response, nil
+	}
+	options.Prompt = prompt
+	messageID, err := session.Send(options)
+	if err != nil {
+		return nil, err
+	}
+	response.MessageID = messageID
+	return response, nil
+}
+
+// Help returns help text listing the registered commands, in registration
+// order.
+func (r *CommandRouter) Help() string {
+	var b strings.Builder
+	b.WriteString("Available commands:\n")
+	width := 0
+	for _, name := range r.names {
+		width = max(width, len(r.commands[name].synopsis()))
+	}
+	for _, name := range r.names {
+		command := r.commands[name]
+		fmt.Fprintf(&b, "  %-*s  %s\n", width, command.synopsis(), command.Description)
+	}
+	return b.String()
+}
+
+func (c *registeredCommand) synopsis() string {
+	if c.Usage == "" {
+		return "/" + c.Name
+	}
+	return "/" + c.Name + " " + c.Usage
+}
+
+func (r *CommandRouter) runHelp(input CommandInput) (string, error) {
+	if len(input.Fields) == 0 {
+		return r.Help(), nil
+	}
+	name := strings.TrimPrefix(input.Fields[0], "/")
+	command, ok := r.commands[strings.ToLower(name)]
+	if !ok {
+		return "", &UnknownCommandError{Command: name, Suggestions: r.suggest(name)}
+	}
+	text := command.synopsis() + "\n  " + command.Description
+	if len(command.Aliases) > 0 {
+		text += "\n  Aliases: /" + strings.Join(command.Aliases, ", /")
+	}
+	return text + "\n", nil
+}
+
+// suggest returns registered command names within a small edit distance of
+// name, closest first.
+func (r *CommandRouter) suggest(name string) []string {
+	name = strings.ToLower(name)
+	type candidate struct {
+		name     string
+		distance int
+	}
+	var candidates []candidate
+	for key, command := range r.commands {
+		if key != strings.ToLower(command.Name) {
+			continue
+		}
+		distance := editDistance(name, key)
+		if distance <= max(2, len(name)/3) || strings.HasPrefix(key, name) {
+			candidates = append(candidates, candidate{command.Name, distance})
+		}
+	}
+	sort.Slice(candidates, func(i, j int) bool {
+		if candidates[i].distance != candidates[j].distance {
+			return candidates[i].distance < candidates[j].distance
+		}
+		return candidates[i].name < candidates[j].name
+	})
+	suggestions := make([]string, len(candidates))
+	for i, c := range candidates {
+		suggestions[i] = c.name
+	}
+	return suggestions
+}
+
+// editDistance returns the Levenshtein distance between a and b.
+func editDistance(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		cur := make([]int, len(rb)+1)
+		cur[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev = cur
+	}
+	return prev[len(rb)]
+}
@@ -0,0 +1,184 @@
+package copilot
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func newTestRouter(t *testing.T, commands ...Command) *CommandRouter {
+	t.Helper()
+	router, err := NewCommandRouter(commands...)
+	if err != nil {
+		t.Fatalf("Failed to create router: %v", err)
+	}
+	return router
+}
+
+func TestCommandRouter_Route(t *testing.T) {
+	router := newTestRouter(t,
+		Command{Name: "fix", Aliases: []string{"f"}, Usage: "<file>", Description: "Fix a file", Template: "Fix the bugs in {{.Args}}."},
+		Command{Name: "explain", Description: "Explain code", Template: "Explain {{index .Fields 0}} in {{len .Fields}} words."},
+		Command{Name: "version", Description: "Print the version", Run: func(input CommandInput) (string, error) {
+			return "v1.2.3", nil
+		}},
+	)
+
+	t.Run("expands templates", func(t *testing.T) {
+		prompt, response, err := router.Route("/fix  main.go ")
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if prompt != "Fix the bugs in main.go." || response.Command != "fix" {
+			t.Errorf("Unexpected routing %q, %+v", prompt, response)
+		}
+
+		prompt, _, _ = router.Route("/explain retry.go\tbriefly")
+		if prompt != "Explain retry.go in 2 words." {
+			t.Errorf("Unexpected prompt %q", prompt)
+		}
+	})
+
+	t.Run("matches aliases case-insensitively", func(t *testing.T) {
+		prompt, response, err := router.Route("/F go.mod")
+		if err != nil || prompt != "Fix the bugs in go.mod." || response.Command != "fix" {
+			t.Errorf("Unexpected routing %q, %+v, %v", prompt, response, err)
+		}
+	})
+
+	t.Run("runs direct commands", func(t *testing.T) {
+		prompt, response, err := router.Route("/version")
+		if err != nil || prompt != "" || response.Output != "v1.2.3" {
+			t.Errorf("Unexpected routing %q, %+v, %v", prompt, response, err)
+		}
+	})
+
+	t.Run("passes through non-commands", func(t *testing.T) {
+		for input, want := range map[string]string{
+			"fix main.go":          "fix main.go",
+			"/":                    "/",
+			"//etc/hosts is empty": "/etc/hosts is empty",
+		} {
+			prompt, response, err := router.Route(input)
+			if err != nil || prompt != want || response.Command != "" {
+				t.Errorf("Route(%q) = %q, %+v, %v; want %q", input, prompt, response, err, want)
+			}
+		}
+	})
+
+	t.Run("reports unknown commands with suggestions", func(t *testing.T) {
+		_, _, err := router.Route("/fxi main.go")
+		var unknown *UnknownCommandError
+		if !errors.As(err, &unknown) {
+			t.Fatalf("Expected UnknownCommandError, got %v", err)
+		}
+		if unknown.Command != "fxi" || len(unknown.Suggestions) == 0 || unknown.Suggestions[0] != "fix" {
+			t.Errorf("Unexpected error %+v", unknown)
+		}
+		if !strings.Contains(err.Error(), "did you mean /fix") {
+			t.Errorf("Unexpected message %q", err)
+		}
+	})
+
+	t.Run("reports template errors", func(t *testing.T) {
+		_, _, err := router.Route("/explain")
+		if err == nil || !strings.Contains(err.Error(), "failed to expand template") {
+			t.Errorf("Expected a template error, got %v", err)
+		}
+	})
+}
+
+func TestNewCommandRouter_Validation(t *testing.T) {
+	cases := map[string][]Command{
+		"empty name":         {{Name: "", Template: "x"}},
+		"name with slash":    {{Name: "/fix", Template: "x"}},
+		"no action":          {{Name: "fix"}},
+		"both actions":       {{Name: "fix", Template: "x", Run: func(CommandInput) (string, error) { return "", nil }}},
+		"invalid template":   {{Name: "fix", Template: "{{.Args"}},
+		"duplicate":          {{Name: "fix", Template: "x"}, {Name: "FIX", Template: "y"}},
+		"duplicate alias":    {{Name: "fix", Template: "x"}, {Name: "explain", Aliases: []string{"fix"}, Template: "y"}},
+		"overrides built-in": {{Name: "help", Template: "x"}},
+	}
+	for name, commands := range cases {
+		if _, err := NewCommandRouter(commands...); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
+
+func TestCommandRouter_Help(t *testing.T) {
+	router := newTestRouter(t,
+		Command{Name: "fix", Usage: "<file>", Aliases: []string{"f"}, Description: "Fix a file", Template: "x"},
+		Command{Name: "deploy", Description: "Deploy the service", Template: "y"},
+	)
+
+	help := router.Help()
+	want := "Available commands:\n" +
+		"  /help [command]  Show available commands\n" +
+		"  /fix <file>      Fix a file\n" +
+		"  /deploy          Deploy the service\n"
+	if help != want {
+		t.Errorf("Unexpected help:\n%s", help)
+	}
+
+	_, response, err := router.Route("/help")
+	if err != nil || response.Output != want {
+		t.Errorf("Unexpected /help output %q, %v", response.Output, err)
+	}
+
+	_, response, err = router.Route("/help /fix")
+	if err != nil || response.Output != "/fix <file>\n  Fix a file\n  Aliases: /f\n" {
+		t.Errorf("Unexpected /help fix output %q, %v", response.Output, err)
+	}
+}
+
+func TestToolCommand(t *testing.T) {
+	type DeployParams struct {
+		Environment string `json:"environment"`
+	}
+	tool := DefineTool("deploy", "Deploy", func(params DeployParams, inv ToolInvocation) (string, error) {
+		if params.Environment == "" {
+			return "", errors.New("missing environment")
+		}
+		return "deployed to " + params.Environment, nil
+	})
+	router := newTestRouter(t, ToolCommand("deploy", "Deploy", tool, func(input CommandInput) (map[string]interface{}, error) {
+		return map[string]interface{}{"environment": input.Args}, nil
+	}))
+
+	_, response, err := router.Route("/deploy staging")
+	if err != nil || response.Output != "deployed to staging" {
+		t.Errorf("Unexpected routing %+v, %v", response, err)
+	}
+
+	if _, _, err := router.Route("/deploy"); err == nil || !strings.Contains(err.Error(), "missing environment") {
+		t.Errorf("Expected a tool failure, got %v", err)
+	}
+}
+
+func TestCommandRouter_Send(t *testing.T) {
+	var prompts []string
+	session, _ := newFakeSession(t, func(server *fakeServer, method string, params map[string]interface{}) (map[string]interface{}, *JSONRPCError) {
+		if method != "session.send" {
+			return nil, &JSONRPCError{Code: -32601, Message: "unexpected method " + method}
+		}
+		prompts = append(prompts, params["prompt"].(string))
+		return map[string]interface{}{"messageId": "msg-1"}, nil
+	})
+	router := newTestRouter(t, Command{Name: "fix", Template: "Fix {{.Args}}"})
+
+	response, err := router.Send(session, MessageOptions{Prompt: "/fix main.go"})
+	if err != nil || response.MessageID != "msg-1" || response.Command != "fix" {
+		t.Errorf("Unexpected response %+v, %v", response, err)
+	}
+	response, err = router.Send(session, MessageOptions{Prompt: "/help"})
+	if err != nil || response.MessageID != "" || response.Output == "" {
+		t.Errorf("Unexpected response %+v, %v", response, err)
+	}
+	if _, err := router.Send(session, MessageOptions{Prompt: "hello"}); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if len(prompts) != 2 || prompts[0] != "Fix main.go" || prompts[1] != "hello" {
+		t.Errorf("Unexpected prompts %q", prompts)
+	}
+}
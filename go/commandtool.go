@@ -0,0 +1,255 @@
+package copilot
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// ToolSubCommand is one operation of a tool created with [DefineCommandTool].
+// Create it with [SubCommand].
+type ToolSubCommand struct {
+	description string
+	schema      map[string]interface{}
+	err         error
+	run         func(inv ToolInvocation) (interface{}, error)
+}
+
+// SubCommand creates an operation of a command tool whose arguments are
+// decoded into T, like the handler of [DefineTool]. Its schema is generated
+// from T; an error generating it is reported by [TryDefineCommandTool].
+func SubCommand[T any, U any](description string, handler func(T, ToolInvocation) (U, error)) ToolSubCommand {
+	schema, err := tryGenerateSchemaForType(reflect.TypeOf((*T)(nil)).Elem())
+	return ToolSubCommand{
+		description: description,
+		schema:      schema,
+		err:         err,
+		run: func(inv ToolInvocation) (interface{}, error) {
+			var params T
+			data, err := json.Marshal(inv.Arguments)
+			if err != nil {
+				return nil, fmt.Errorf("failed to marshal arguments: %w", err)
+			}
+			if err := decodeArguments(data, &params); err != nil {
+				return nil, fmt.Errorf("failed to unmarshal arguments into %T: %w", params, err)
+			}
+			return handler(params, inv)
+		},
+	}
+}
+
+// commandArgument is the name of the parameter that selects the command of a
+// command tool.
+const commandArgument = "command"
+
+// DefineCommandTool creates one tool that dispatches to several operations,
+// for groups of small operations that would otherwise each take a place in
+// the tool list. The tool takes a "command" parameter, whose enum lists the
+// names of commands and whose description lists what each does, and the
+// arguments of every command as its other parameters. When called, the
+// arguments of the selected command are validated against its schema and
+// decoded for its handler; arguments of other commands are dropped.
+//
+// Commands that share an argument name must declare it with the same schema.
+// Results have ToolTelemetry "command". Options apply to the tool as a whole.
+//
+// Example:
+//
+//	branches := copilot.DefineCommandTool("branches", "Manage git branches",
+//	    map[string]copilot.ToolSubCommand{
+//	        "list":   copilot.SubCommand("List branches", listBranches),
+//	        "create": copilot.SubCommand("Create a branch from a base", createBranch),
+//	        "delete": copilot.SubCommand("Delete a merged branch", deleteBranch),
+//	    })
+//
+// DefineCommandTool panics if the schema cannot be generated; use
+// [TryDefineCommandTool] to handle the error instead.
+func DefineCommandTool(name, description string, commands map[string]ToolSubCommand, opts ...ToolOption) Tool {
+	tool, err := TryDefineCommandTool(name, description, commands, opts...)
+	if err != nil {
+		panic(err.Error())
+	}
+	return tool
+}
+
+// TryDefineCommandTool is like [DefineCommandTool], but returns an error
+// instead of panicking if there are no commands, the schema of a command
+// cannot be generated, or commands declare an argument differently.
+func TryDefineCommandTool(name, description string, commands map[string]ToolSubCommand, opts ...ToolOption) (Tool, error) {
+	schema, err := buildCommandToolSchema(commands)
+	if err != nil {
+		return Tool{}, fmt.Errorf("failed to define tool %s: %w", name, err)
+	}
+	handler := func(args map[string]interface{}, inv ToolInvocation) (interface{}, error) {
+		return dispatchCommand(commands, args, inv)
+	}
+	return TryDefineTool(name, description, handler, append([]ToolOption{WithParameters(schema)}, opts...)...)
+}
+
+// buildCommandToolSchema combines the schemas of commands into the parameters
+// of a command tool.
+func buildCommandToolSchema(commands map[string]ToolSubCommand) (map[string]interface{}, error) {
+	if len(commands) == 0 {
+		return nil, fmt.Errorf("no commands")
+	}
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	properties := make(map[string]interface{})
+	owners := make(map[string][]string)
+	shapes := make(map[string]string)
+	var summary strings.Builder
+	summary.WriteString("The operation to run:")
+	enum := make([]interface{}, len(names))
+	for i, name := range names {
+		command := commands[name]
+		if command.err != nil {
+			return nil, fmt.Errorf("failed to generate schema for command %s: %w", name, command.err)
+		}
+		enum[i] = name
+
+		commandProperties, _ := command.schema["properties"].(map[string]interface{})
+		required := make(map[string]bool)
+		for _, field := range schemaRequired(command.schema) {
+			required[field] = true
+		}
+		fields := make([]string, 0, len(commandProperties))
+		for field, property := range commandProperties {
+			if field == commandArgument {
+				return nil, fmt.Errorf("command %s has an argument named %q, which selects the command", name, commandArgument)
+			}
+			shape, err := schemaShape(property)
+			if err != nil {
+				return nil, fmt.Errorf("failed to encode argument %s of command %s: %w", field, name, err)
+			}
+			if existing, ok := shapes[field]; ok && existing != shape {
+				return nil, fmt.Errorf("commands %s and %s declare argument %s differently", owners[field][0], name, field)
+			}
+			if _, ok := properties[field]; !ok {
+				properties[field] = property
+			}
+			shapes[field] = shape
+			owners[field] = append(owners[field], name)
+			if required[field] {
+				field += " (required)"
+			}
+			fields = append(fields, field)
+		}
+		sort.Strings(fields)
+
+		fmt.Fprintf(&summary, "\n- %s: %s", name, command.description)
+		if len(fields) > 0 {
+			fmt.Fprintf(&summary, " Arguments: %s.", strings.Join(fields, ", "))
+		}
+	}
+
+	for field, property := range properties {
+		properties[field] = withCommandOwners(property, owners[field])
+	}
+	properties[commandArgument] = map[string]interface{}{
+		"type":        "string",
+		"enum":        enum,
+		"description": summary.String(),
+	}
+	return map[string]interface{}{
+		"type":       "object",
+		"properties": properties,
+		"required":   []string{commandArgument},
+	}, nil
+}
+
+// schemaRequired returns the required properties of an object schema.
+func schemaRequired(schema map[string]interface{}) []string {
+	switch required := schema["required"].(type) {
+	case []string:
+		return required
+	case []interface{}:
+		fields := make([]string, 0, len(required))
+		for _, field := range required {
+			if field, ok := field.(string); ok {
+				fields = append(fields, field)
+			}
+		}
+		return fields
+	}
+	return nil
+}
+
+// schemaShape encodes a property schema without its description, so that
+// commands documenting a shared argument differently can share it.
+func schemaShape(property interface{}) (string, error) {
+	if object, ok := property.(map[string]interface{}); ok {
+		shape := make(map[string]interface{}, len(object))
+		for key, value := range object {
+			if key != "description" {
+				shape[key] = value
+			}
+		}
+		property = shape
+	}
+	data, err := json.Marshal(property)
+	return string(data), err
+}
+
+// withCommandOwners returns a copy of property whose description names the
+// commands that take it.
+func withCommandOwners(property interface{}, owners []string) interface{} {
+	object, ok := property.(map[string]interface{})
+	if !ok {
+		return property
+	}
+	copied := make(map[string]interface{}, len(object)+1)
+	for key, value := range object {
+		copied[key] = value
+	}
+	usage := "Used by " + strings.Join(owners, ", ") + "."
+	if description, _ := object["description"].(string); description != "" {
+		usage = description + " " + usage
+	}
+	copied["description"] = usage
+	return copied
+}
+
+// dispatchCommand runs the command args select with its own arguments.
+func dispatchCommand(commands map[string]ToolSubCommand, args map[string]interface{}, inv ToolInvocation) (interface{}, error) {
+	name, _ := args[commandArgument].(string)
+	command, ok := commands[name]
+	if !ok {
+		names := make([]interface{}, 0, len(commands))
+		for name := range commands {
+			names = append(names, name)
+		}
+		sort.Slice(names, func(i, j int) bool { return names[i].(string) < names[j].(string) })
+		return buildInvalidArgumentsResult(&InvalidArgumentsError{ToolName: inv.ToolName, Errors: []ArgumentError{
+			{Field: commandArgument, Message: "must be one of " + formatEnum(names)},
+		}}), nil
+	}
+
+	commandArgs := make(map[string]interface{}, len(args))
+	properties, _ := command.schema["properties"].(map[string]interface{})
+	for field, value := range args {
+		if _, declared := properties[field]; declared || (properties == nil && field != commandArgument) {
+			commandArgs[field] = value
+		}
+	}
+	if errs := ValidateArguments(command.schema, commandArgs); len(errs) > 0 {
+		return buildInvalidArgumentsResult(&InvalidArgumentsError{ToolName: inv.ToolName, Errors: errs}), nil
+	}
+	inv.Arguments = commandArgs
+
+	out, err := command.run(inv)
+	if err != nil {
+		return nil, err
+	}
+	result, err := normalizeResult(out)
+	if err != nil {
+		return nil, err
+	}
+	result.ToolTelemetry = withTelemetry(result.ToolTelemetry, "command", name)
+	return result, nil
+}
@@ -0,0 +1,201 @@
+package copilot
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestDefineCommandTool(t *testing.T) {
+	type ListParams struct {
+		Remote bool `json:"remote,omitempty" jsonschema:"include remote branches"`
+	}
+	type CreateParams struct {
+		Name string `json:"name" jsonschema:"branch name"`
+		Base string `json:"base,omitempty"`
+	}
+	type DeleteParams struct {
+		Name string `json:"name" jsonschema:"the branch to delete"`
+	}
+
+	var created CreateParams
+	var deleted []ToolInvocation
+	commands := map[string]ToolSubCommand{
+		"list": SubCommand("List branches.", func(params ListParams, inv ToolInvocation) ([]string, error) {
+			if params.Remote {
+				return []string{"main", "origin/main"}, nil
+			}
+			return []string{"main"}, nil
+		}),
+		"create": SubCommand("Create a branch.", func(params CreateParams, inv ToolInvocation) (string, error) {
+			created = params
+			return "created " + params.Name, nil
+		}),
+		"delete": SubCommand("Delete a branch.", func(params DeleteParams, inv ToolInvocation) (string, error) {
+			deleted = append(deleted, inv)
+			if params.Name == "main" {
+				return "", &ToolError{Code: "protected", Message: "main cannot be deleted"}
+			}
+			return "deleted " + params.Name, nil
+		}),
+	}
+	tool := DefineCommandTool("branches", "Manage branches", commands)
+
+	call := func(t *testing.T, args map[string]interface{}) ToolResult {
+		t.Helper()
+		result, err := tool.Handler(ToolInvocation{ToolName: "branches", ToolCallID: "call-1", Arguments: args})
+		if err != nil {
+			t.Fatalf("Handler returned error: %v", err)
+		}
+		return result
+	}
+
+	t.Run("combines the schemas of commands", func(t *testing.T) {
+		props := tool.Parameters["properties"].(map[string]interface{})
+		command := props["command"].(map[string]interface{})
+		enum := command["enum"].([]interface{})
+		if len(enum) != 3 || enum[0] != "create" || enum[1] != "delete" || enum[2] != "list" {
+			t.Errorf("Expected sorted command enum, got %v", enum)
+		}
+		summary := command["description"].(string)
+		for _, want := range []string{"- create: Create a branch.", "base", "name (required)", "- list: List branches. Arguments: remote."} {
+			if !strings.Contains(summary, want) {
+				t.Errorf("Expected command description to contain %q, got %q", want, summary)
+			}
+		}
+		name := props["name"].(map[string]interface{})
+		if desc := name["description"]; desc != "branch name Used by create, delete." {
+			t.Errorf("Expected shared argument to name its commands, got %q", desc)
+		}
+		if _, ok := props["remote"]; !ok {
+			t.Error("Expected 'remote' property in schema")
+		}
+		required := tool.Parameters["required"].([]interface{})
+		if len(required) != 1 || required[0] != "command" {
+			t.Errorf("Expected only command to be required, got %v", required)
+		}
+	})
+
+	t.Run("dispatches to the selected command", func(t *testing.T) {
+		result := call(t, map[string]interface{}{"command": "create", "name": "feature", "base": "main", "remote": true})
+		if result.TextResultForLLM != "created feature" {
+			t.Errorf("Expected create result, got %q", result.TextResultForLLM)
+		}
+		if created.Name != "feature" || created.Base != "main" {
+			t.Errorf("Expected typed arguments, got %+v", created)
+		}
+		if result.ToolTelemetry["command"] != "create" {
+			t.Errorf("Expected command telemetry, got %v", result.ToolTelemetry)
+		}
+
+		result = call(t, map[string]interface{}{"command": "list", "remote": true})
+		if !strings.Contains(result.TextResultForLLM, "origin/main") {
+			t.Errorf("Expected list result, got %q", result.TextResultForLLM)
+		}
+	})
+
+	t.Run("passes only the command's arguments", func(t *testing.T) {
+		deleted = nil
+		call(t, map[string]interface{}{"command": "delete", "name": "old", "base": "main"})
+		if len(deleted) != 1 {
+			t.Fatalf("Expected delete to run once, ran %d times", len(deleted))
+		}
+		args := deleted[0].Arguments.(map[string]interface{})
+		if len(args) != 1 || args["name"] != "old" {
+			t.Errorf("Expected only the name argument, got %v", args)
+		}
+	})
+
+	t.Run("validates the arguments of the command", func(t *testing.T) {
+		result := call(t, map[string]interface{}{"command": "delete"})
+		if result.ResultType != "failure" || !strings.Contains(result.TextResultForLLM, "name") {
+			t.Errorf("Expected invalid arguments failure for name, got %+v", result)
+		}
+		if result.ToolTelemetry["errorType"] != "invalid_arguments" {
+			t.Errorf("Expected invalid_arguments telemetry, got %v", result.ToolTelemetry)
+		}
+	})
+
+	t.Run("rejects unknown commands", func(t *testing.T) {
+		result := call(t, map[string]interface{}{"command": "rename"})
+		if result.ResultType != "failure" || !strings.Contains(result.TextResultForLLM, "command") {
+			t.Errorf("Expected invalid arguments failure for command, got %+v", result)
+		}
+	})
+
+	t.Run("maps a ToolError of a command", func(t *testing.T) {
+		result := call(t, map[string]interface{}{"command": "delete", "name": "main"})
+		if toolErrorCode(result) != "protected" {
+			t.Errorf("Expected ToolError result, got %+v", result)
+		}
+	})
+}
+
+func TestTryDefineCommandTool(t *testing.T) {
+	noop := func(params struct {
+		Name string `json:"name"`
+	}, inv ToolInvocation) (string, error) {
+		return "", nil
+	}
+
+	t.Run("requires commands", func(t *testing.T) {
+		if _, err := TryDefineCommandTool("empty", "Nothing", nil); err == nil {
+			t.Error("Expected error for no commands")
+		}
+	})
+
+	t.Run("rejects conflicting arguments", func(t *testing.T) {
+		_, err := TryDefineCommandTool("tool", "Tool", map[string]ToolSubCommand{
+			"a": SubCommand("A", noop),
+			"b": SubCommand("B", func(params struct {
+				Name int `json:"name"`
+			}, inv ToolInvocation) (string, error) {
+				return "", nil
+			}),
+		})
+		if err == nil || !strings.Contains(err.Error(), "declare argument name differently") {
+			t.Errorf("Expected conflicting argument error, got %v", err)
+		}
+	})
+
+	t.Run("rejects an argument named command", func(t *testing.T) {
+		_, err := TryDefineCommandTool("tool", "Tool", map[string]ToolSubCommand{
+			"a": SubCommand("A", func(params struct {
+				Command string `json:"command"`
+			}, inv ToolInvocation) (string, error) {
+				return "", nil
+			}),
+		})
+		if err == nil {
+			t.Error("Expected error for an argument named command")
+		}
+	})
+
+	t.Run("reports schema generation errors", func(t *testing.T) {
+		_, err := TryDefineCommandTool("tool", "Tool", map[string]ToolSubCommand{
+			"a": SubCommand("A", noop),
+			"b": SubCommand("B", func(params chan int, inv ToolInvocation) (string, error) {
+				return "", nil
+			}),
+		})
+		if err == nil {
+			t.Error("Expected schema generation error")
+		}
+	})
+
+	t.Run("returns command errors", func(t *testing.T) {
+		failure := errors.New("boom")
+		tool, err := TryDefineCommandTool("tool", "Tool", map[string]ToolSubCommand{
+			"a": SubCommand("A", func(params struct{}, inv ToolInvocation) (string, error) {
+				return "", failure
+			}),
+		}, WithoutErrorRenderer())
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		_, err = tool.Handler(ToolInvocation{ToolName: "tool", Arguments: map[string]interface{}{"command": "a"}})
+		if !errors.Is(err, failure) {
+			t.Errorf("Expected command error, got %v", err)
+		}
+	})
+}
@@ -0,0 +1,163 @@
+package copilot
+
+import (
+	"bytes"
+	"encoding/binary"
+	"strings"
+)
+
+// sniffLength is how many leading bytes detectContentType looks at.
+const sniffLength = 512
+
+// contentSignature is a media type identified by bytes at the start of the
+// content; a '?' byte in prefix matches any byte.
+type contentSignature struct {
+	prefix   string
+	mimeType string
+}
+
+// htmlSignatures are the tags that identify HTML. They match case-insensitively
+// after leading whitespace, when followed by a space or '>'.
+var htmlSignatures = []string{
+	"<!DOCTYPE HTML", "<HTML", "<HEAD", "<SCRIPT", "<IFRAME", "<H1", "<DIV",
+	"<FONT", "<TABLE", "<A", "<STYLE", "<TITLE", "<B", "<BODY", "<BR", "<P",
+	"<!--",
+}
+
+// contentSignatures are the signatures of the WHATWG MIME Sniffing Standard
+// that net/http.DetectContentType uses after HTML and XML, in its order.
+var contentSignatures = []contentSignature{
+	{"%PDF-", "application/pdf"},
+	{"%!PS-Adobe-", "application/postscript"},
+	{"\xfe\xff??", "text/plain; charset=utf-16be"},
+	{"\xff\xfe??", "text/plain; charset=utf-16le"},
+	{"\xef\xbb\xbf?", "text/plain; charset=utf-8"},
+	{"\x00\x00\x01\x00", "image/x-icon"},
+	{"\x00\x00\x02\x00", "image/x-icon"},
+	{"BM", "image/bmp"},
+	{"GIF87a", "image/gif"},
+	{"GIF89a", "image/gif"},
+	{"RIFF????WEBPVP", "image/webp"},
+	{"\x89PNG\r\n\x1a\n", "image/png"},
+	{"\xff\xd8\xff", "image/jpeg"},
+	{"FORM????AIFF", "audio/aiff"},
+	{"ID3", "audio/mpeg"},
+	{"OggS\x00", "application/ogg"},
+	{"MThd\x00\x00\x00\x06", "audio/midi"},
+	{"RIFF????AVI ", "video/avi"},
+	{"RIFF????WAVE", "audio/wave"},
+}
+
+// trailingSignatures are the signatures net/http.DetectContentType checks
+// after MP4.
+var trailingSignatures = []contentSignature{
+	{"\x1a\x45\xdf\xa3", "video/webm"},
+	{strings.Repeat("?", 34) + "LP", "application/vnd.ms-fontobject"},
+	{"\x00\x01\x00\x00", "font/ttf"},
+	{"OTTO", "font/otf"},
+	{"ttcf", "font/collection"},
+	{"wOFF", "font/woff"},
+	{"wOF2", "font/woff2"},
+	{"\x1f\x8b\x08", "application/x-gzip"},
+	{"PK\x03\x04", "application/zip"},
+	{"Rar!\x1a\x07\x00", "application/x-rar-compressed"},
+	{"Rar!\x1a\x07\x01\x00", "application/x-rar-compressed"},
+	{"\x00asm", "application/wasm"},
+}
+
+// detectContentType returns the media type of data as
+// net/http.DetectContentType does, without linking net/http into every
+// binary that uses the SDK. Content it does not recognize is text/plain if
+// it has no binary control bytes, and application/octet-stream otherwise.
+func detectContentType(data []byte) string {
+	if len(data) > sniffLength {
+		data = data[:sniffLength]
+	}
+
+	text := bytes.TrimLeft(data, "\t\n\x0c\r ")
+	for _, tag := range htmlSignatures {
+		if isHTMLTag(text, tag) {
+			return "text/html; charset=utf-8"
+		}
+	}
+	if bytes.HasPrefix(text, []byte("<?xml")) {
+		return "text/xml; charset=utf-8"
+	}
+	for _, signature := range contentSignatures {
+		if matchesSignature(data, signature.prefix) {
+			return signature.mimeType
+		}
+	}
+	if isMP4(data) {
+		return "video/mp4"
+	}
+	for _, signature := range trailingSignatures {
+		if matchesSignature(data, signature.prefix) {
+			return signature.mimeType
+		}
+	}
+	if isText(text) {
+		return "text/plain; charset=utf-8"
+	}
+	return "application/octet-stream"
+}
+
+// isHTMLTag reports whether data starts with tag, ignoring the ASCII case of
+// its letters, followed by a space or '>'.
+func isHTMLTag(data []byte, tag string) bool {
+	if len(data) < len(tag)+1 {
+		return false
+	}
+	for i := 0; i < len(tag); i++ {
+		b := data[i]
+		if 'A' <= tag[i] && tag[i] <= 'Z' {
+			b &^= 0x20
+		}
+		if b != tag[i] {
+			return false
+		}
+	}
+	return data[len(tag)] == ' ' || data[len(tag)] == '>'
+}
+
+func matchesSignature(data []byte, prefix string) bool {
+	if len(data) < len(prefix) {
+		return false
+	}
+	for i := 0; i < len(prefix); i++ {
+		if prefix[i] != '?' && prefix[i] != data[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// isMP4 reports whether data starts with an ftyp box whose major or a
+// compatible brand is an MP4 brand.
+func isMP4(data []byte) bool {
+	if len(data) < 12 {
+		return false
+	}
+	boxSize := int(binary.BigEndian.Uint32(data))
+	if boxSize%4 != 0 || len(data) < boxSize || string(data[4:8]) != "ftyp" {
+		return false
+	}
+	for start := 8; start < boxSize; start += 4 {
+		// The bytes at 12 are the minor version, not a brand.
+		if start != 12 && string(data[start:start+3]) == "mp4" {
+			return true
+		}
+	}
+	return false
+}
+
+// isText reports whether data has no binary control bytes. Like
+// net/http.DetectContentType, it does not check that data is UTF-8.
+func isText(data []byte) bool {
+	for _, b := range data {
+		if b <= 0x08 || b == 0x0b || 0x0e <= b && b <= 0x1a || 0x1c <= b && b <= 0x1f {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,76 @@
+package copilot
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestDetectContentType(t *testing.T) {
+	t.Run("agrees with net/http", func(t *testing.T) {
+		samples := []string{
+			"",
+			"plain text",
+			"héllo wörld",
+			strings.Repeat("é", 300),
+			"\x89PNG\r\n\x1a\n0000",
+			"\xff\xd8\xff\xe0JFIF",
+			"GIF89a....",
+			"RIFF\x10\x00\x00\x00WEBPVP8 ",
+			"RIFF\x10\x00\x00\x00WAVEfmt ",
+			"%PDF-1.7",
+			"PK\x03\x04rest",
+			"\x1f\x8b\x08\x00",
+			"ID3\x03",
+			"\x00\x00\x00\x18ftypmp42\x00\x00\x00\x00mp42isom",
+			"\x00\x00\x00\x14ftypqt  \x00\x00\x00\x00qt  ",
+			"  <!DOCTYPE html><html></html>",
+			"<?xml version=\"1.0\"?><a/>",
+			"\x00\x01\x02binary",
+			"caf\xe9 latin-1",
+			"\xef\xbb\xbf\x00bom",
+			"\xfe\xff\x00h",
+			"\xff\xfe\x00h",
+			"<!DOCTYPE HTML>",
+			"<!doctypehtml>",
+			"\n<html lang=en>",
+			"<head>",
+			"<script>alert(1)</script>",
+			"<iframe src=x>",
+			"<h1>Title</h1>",
+			"<div class=a>",
+			"<font face=x>",
+			"<table>",
+			"<a href=x>",
+			"<abbr>",
+			"<style>p{}</style>",
+			"<title>x</title>",
+			"<b>bold</b>",
+			"<body>",
+			"<br>",
+			"<p>para",
+			"<pre>",
+			"<!-- comment -->",
+			"<!--comment-->",
+			"<HTML",
+			"FORM\x00\x00\x00\x00AIFF",
+			"MThd\x00\x00\x00\x06",
+			"RIFF\x10\x00\x00\x00AVI LIST",
+			"\x1a\x45\xdf\xa3",
+			"wOFF",
+			"\x00\x01\x00\x00",
+			"Rar!\x1a\x07\x00",
+			"\x00asm\x01\x00\x00\x00",
+			"text\x1bwith escape",
+			"text with\x0bvertical tab",
+		}
+		for _, sample := range samples {
+			// Every prefix, so signatures cut off at the end are covered too.
+			for n := 0; n <= len(sample); n++ {
+				if got, want := detectContentType([]byte(sample[:n])), http.DetectContentType([]byte(sample[:n])); got != want {
+					t.Errorf("detectContentType(%.16q) = %s, want %s", sample[:n], got, want)
+				}
+			}
+		}
+	})
+}
@@ -0,0 +1,266 @@
+package copilot
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ContentStore is a content-addressed cache of attachment contents on disk.
+// Each distinct content is stored once, named by its SHA-256 digest, and
+// attached to messages by reference: the attachments it returns point at the
+// stored copy. When the same large files are attached in many sessions, a
+// store shared by those sessions, or by every process using the same
+// directory, avoids holding and writing a copy per session.
+//
+// A ContentStore is safe for concurrent use. Stored contents are never
+// modified; remove unused ones with [ContentStore.Prune].
+//
+// Example:
+//
+//	store, err := copilot.NewContentStore(filepath.Join(os.TempDir(), "copilot-content"))
+//	if err != nil {
+//	    log.Fatal(err)
+//	}
+//	attachment, err := store.PutFile("internal/server/handler.go")
+//	if err != nil {
+//	    log.Fatal(err)
+//	}
+//	session.Send(copilot.MessageOptions{
+//	    Prompt:      "Review this handler",
+//	    Attachments: []copilot.Attachment{attachment},
+//	})
+type ContentStore struct {
+	dir   string
+	mu    sync.Mutex
+	files map[string]fileStamp
+	stats ContentStoreStats
+}
+
+// fileStamp identifies a version of a file by its size and modification time,
+// so that unchanged files are not hashed again.
+type fileStamp struct {
+	size    int64
+	modTime time.Time
+	digest  string
+}
+
+// ContentStoreStats counts the contents put in a [ContentStore].
+type ContentStoreStats struct {
+	// Stored is the number of contents written to the store.
+	Stored int
+	// Deduplicated is the number of contents that were already stored.
+	Deduplicated int
+	// BytesSaved is the total size of the deduplicated contents.
+	BytesSaved int64
+}
+
+// errContentChanged is returned when a file changes while it is being stored.
+var errContentChanged = errors.New("file changed while it was being stored")
+
+// NewContentStore creates a content store in dir, creating the directory if it
+// does not exist.
+func NewContentStore(dir string) (*ContentStore, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create content directory: %w", err)
+	}
+	return &ContentStore{dir: dir, files: make(map[string]fileStamp)}, nil
+}
+
+// Put stores data, unless the same content is already stored, and returns a
+// file attachment referencing it. name is the attachment's display name; its
+// extension is kept so that the content type can still be recognized.
+func (s *ContentStore) Put(data []byte, name string) (Attachment, error) {
+	sum := sha256.Sum256(data)
+	path := s.blobPath(hex.EncodeToString(sum[:]), name)
+	stored, err := s.touch(path, int64(len(data)))
+	if err != nil {
+		return Attachment{}, err
+	}
+	if !stored {
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			return Attachment{}, fmt.Errorf("failed to store content: %w", err)
+		}
+		if err := writeFileAtomic(path, data); err != nil {
+			return Attachment{}, fmt.Errorf("failed to store content: %w", err)
+		}
+		s.countStored()
+	}
+	return contentAttachment(path, name), nil
+}
+
+// PutFile stores a snapshot of the file at path, unless the same content is
+// already stored, and returns a file attachment referencing it, named after
+// the file. The file is only read again once its size or modification time
+// changes.
+func (s *ContentStore) PutFile(path string) (Attachment, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return Attachment{}, fmt.Errorf("failed to store %s: %w", path, err)
+	}
+	name := filepath.Base(abs)
+	info, err := os.Stat(abs)
+	if err != nil {
+		return Attachment{}, fmt.Errorf("failed to store %s: %w", path, err)
+	}
+	if info.IsDir() {
+		return Attachment{}, fmt.Errorf("failed to store %s: is a directory", path)
+	}
+
+	s.mu.Lock()
+	stamp, ok := s.files[abs]
+	s.mu.Unlock()
+	if !ok || stamp.size != info.Size() || !stamp.modTime.Equal(info.ModTime()) {
+		digest, err := hashFile(abs)
+		if err != nil {
+			return Attachment{}, fmt.Errorf("failed to store %s: %w", path, err)
+		}
+		stamp = fileStamp{size: info.Size(), modTime: info.ModTime(), digest: digest}
+	}
+
+	blob := s.blobPath(stamp.digest, name)
+	stored, err := s.touch(blob, stamp.size)
+	if err != nil {
+		return Attachment{}, err
+	}
+	if !stored {
+		if err := s.copyFile(abs, blob, stamp.digest); err != nil {
+			return Attachment{}, fmt.Errorf("failed to store %s: %w", path, err)
+		}
+		s.countStored()
+	}
+
+	s.mu.Lock()
+	s.files[abs] = stamp
+	s.mu.Unlock()
+	return contentAttachment(blob, name), nil
+}
+
+// Prune removes the contents that have not been put in the store for longer
+// than maxAge, and returns how many were removed. Attachments referencing them
+// must no longer be sent.
+func (s *ContentStore) Prune(maxAge time.Duration) (int, error) {
+	cutoff := time.Now().Add(-maxAge)
+	removed := 0
+	err := filepath.WalkDir(s.dir, func(path string, entry os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if entry.IsDir() || strings.HasPrefix(entry.Name(), ".") {
+			return nil
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return nil
+		}
+		if info.ModTime().Before(cutoff) {
+			if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+				return err
+			}
+			removed++
+		}
+		return nil
+	})
+	if err != nil {
+		return removed, fmt.Errorf("failed to prune content store: %w", err)
+	}
+	return removed, nil
+}
+
+// Stats returns the store's counters since it was created.
+func (s *ContentStore) Stats() ContentStoreStats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.stats
+}
+
+// blobPath returns where the content with digest is stored, spreading contents
+// over subdirectories by the first byte of their digest.
+func (s *ContentStore) blobPath(digest, name string) string {
+	ext := strings.ToLower(filepath.Ext(name))
+	if len(ext) > 16 || strings.ContainsAny(ext, `/\`) {
+		ext = ""
+	}
+	return filepath.Join(s.dir, digest[:2], digest+ext)
+}
+
+// touch reports whether the content at path is already stored. If it is, its
+// modification time is updated, so that [ContentStore.Prune] keeps it, and it
+// counts as deduplicated.
+func (s *ContentStore) touch(path string, size int64) (bool, error) {
+	now := time.Now()
+	err := os.Chtimes(path, now, now)
+	if errors.Is(err, os.ErrNotExist) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to store content: %w", err)
+	}
+	s.mu.Lock()
+	s.stats.Deduplicated++
+	s.stats.BytesSaved += size
+	s.mu.Unlock()
+	return true, nil
+}
+
+func (s *ContentStore) countStored() {
+	s.mu.Lock()
+	s.stats.Stored++
+	s.mu.Unlock()
+}
+
+// copyFile stores the file at src as dst, failing if its content no longer
+// has the given digest.
+func (s *ContentStore) copyFile(src, dst, digest string) error {
+	f, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if err := os.MkdirAll(filepath.Dir(dst), 0o755); err != nil {
+		return err
+	}
+	return writeReaderAtomic(dst, &digestReader{r: f, hash: sha256.New(), digest: digest})
+}
+
+func hashFile(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// digestReader hashes what is read through it, and fails at the end if the
+// content does not have the expected digest.
+type digestReader struct {
+	r      io.Reader
+	hash   hash.Hash
+	digest string
+}
+
+func (d *digestReader) Read(p []byte) (int, error) {
+	n, err := d.r.Read(p)
+	d.hash.Write(p[:n])
+	if err == io.EOF && hex.EncodeToString(d.hash.Sum(nil)) != d.digest {
+		return n, errContentChanged
+	}
+	return n, err
+}
+
+func contentAttachment(path, name string) Attachment {
+	return Attachment{Type: File, Path: &path, DisplayName: name}
+}
@@ -0,0 +1,90 @@
+package copilot
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestContentStore(t *testing.T) {
+	t.Run("stores each content once", func(t *testing.T) {
+		store, err := NewContentStore(t.TempDir())
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		a, err := store.Put([]byte("package main"), "main.go")
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		b, _ := store.Put([]byte("package main"), "copy.go")
+		if *a.Path != *b.Path {
+			t.Errorf("Expected identical contents to share a path, got %s and %s", *a.Path, *b.Path)
+		}
+		if a.Type != File || b.DisplayName != "copy.go" || filepath.Ext(*a.Path) != ".go" {
+			t.Errorf("Unexpected attachment %+v", b)
+		}
+		if data, _ := os.ReadFile(*a.Path); string(data) != "package main" {
+			t.Errorf("Unexpected stored content %q", data)
+		}
+
+		c, _ := store.Put([]byte("package other"), "main.go")
+		if *c.Path == *a.Path {
+			t.Error("Expected different contents to have different paths")
+		}
+		stats := store.Stats()
+		if stats.Stored != 2 || stats.Deduplicated != 1 || stats.BytesSaved != int64(len("package main")) {
+			t.Errorf("Unexpected stats %+v", stats)
+		}
+	})
+
+	t.Run("snapshots files and deduplicates across stores", func(t *testing.T) {
+		dir := t.TempDir()
+		src := filepath.Join(t.TempDir(), "README.md")
+		os.WriteFile(src, []byte("v1"), 0o644)
+
+		first, _ := NewContentStore(dir)
+		a, err := first.PutFile(src)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if a.DisplayName != "README.md" {
+			t.Errorf("Expected the file name as display name, got %q", a.DisplayName)
+		}
+
+		// Another process sharing the directory reuses the stored copy.
+		second, _ := NewContentStore(dir)
+		b, _ := second.PutFile(src)
+		if *a.Path != *b.Path || second.Stats().Deduplicated != 1 {
+			t.Errorf("Expected the second store to reuse %s, got %s", *a.Path, *b.Path)
+		}
+
+		os.WriteFile(src, []byte("version 2"), 0o644)
+		c, _ := first.PutFile(src)
+		if *c.Path == *a.Path {
+			t.Error("Expected a changed file to be stored again")
+		}
+		if data, _ := os.ReadFile(*a.Path); string(data) != "v1" {
+			t.Errorf("Expected the earlier snapshot to be kept, got %q", data)
+		}
+	})
+
+	t.Run("prunes unused contents", func(t *testing.T) {
+		store, _ := NewContentStore(t.TempDir())
+		old, _ := store.Put([]byte("old"), "old.txt")
+		recent, _ := store.Put([]byte("recent"), "recent.txt")
+		past := time.Now().Add(-2 * time.Hour)
+		os.Chtimes(*old.Path, past, past)
+
+		removed, err := store.Prune(time.Hour)
+		if err != nil || removed != 1 {
+			t.Fatalf("Expected one content to be pruned, got %d, %v", removed, err)
+		}
+		if _, err := os.Stat(*recent.Path); err != nil {
+			t.Errorf("Expected the recent content to be kept: %v", err)
+		}
+		if again, _ := store.Put([]byte("old"), "old.txt"); store.Stats().Stored != 3 {
+			t.Errorf("Expected pruned content to be stored again at %s", *again.Path)
+		}
+	})
+}
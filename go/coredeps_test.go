@@ -0,0 +1,39 @@
+package copilot
+
+import (
+	"os/exec"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// TestCoreDependencies keeps the core package small for binaries that only
+// need DefineTool and stdio JSON-RPC: optional subsystems with heavy
+// dependencies belong in subpackages or separate modules.
+func TestCoreDependencies(t *testing.T) {
+	if testing.Short() {
+		t.Skip("runs go list")
+	}
+	goTool, err := exec.LookPath("go")
+	if err != nil {
+		t.Skip("go is not installed")
+	}
+	out, err := exec.Command(goTool, "list", "-deps", "-f", "{{if not .Standard}}{{.ImportPath}}{{end}}", ".").Output()
+	if err != nil {
+		t.Fatalf("go list failed: %v", err)
+	}
+	deps := strings.Fields(string(out))
+	if want := []string{"github.com/google/jsonschema-go/jsonschema", "github.com/github/copilot-sdk/go"}; !slices.Equal(deps, want) {
+		t.Errorf("Expected the core package to depend only on %v, got %v", want[:1], deps)
+	}
+
+	out, err = exec.Command(goTool, "list", "-deps", ".").Output()
+	if err != nil {
+		t.Fatalf("go list failed: %v", err)
+	}
+	for _, heavy := range []string{"net/http", "crypto/tls", "database/sql"} {
+		if slices.Contains(strings.Fields(string(out)), heavy) {
+			t.Errorf("Expected the core package not to import %s", heavy)
+		}
+	}
+}
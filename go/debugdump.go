@@ -0,0 +1,286 @@
+package copilot
+
+import (
+	"net"
+	"reflect"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DebugSnapshot is the internal state of a [Client] at one point in time, as
+// returned by [Client.DebugDump]. It is meant to be encoded as JSON and
+// attached to bug and incident reports; its fields may change between
+// releases.
+type DebugSnapshot struct {
+	// Time is when the snapshot was taken.
+	Time time.Time
+	// ProtocolVersion is the SDK protocol version.
+	ProtocolVersion int
+	// State is the client's connection state.
+	State ConnectionState
+	// Goroutines counts the goroutines attributable to the SDK.
+	Goroutines DebugGoroutines
+	// PendingRequests are the requests sent to the server that are waiting
+	// for a response, oldest first.
+	PendingRequests []DebugRequest
+	// Concurrency is the state of the client's [AdaptiveConcurrency],
+	// including the requests queued to be sent.
+	Concurrency ConcurrencyStats
+	// FlowControl is the state of the client's [FlowControl], including the
+	// event bytes queued for subscribers.
+	FlowControl FlowControlStats
+	// Process is the status of the CLI process.
+	Process DebugProcess
+	// Sessions are the client's active sessions, by session ID.
+	Sessions []DebugSession
+	// ProtocolErrors are the client's recent protocol errors, oldest first.
+	// See [Client.RecentErrors].
+	ProtocolErrors []ProtocolError
+}
+
+// DebugGoroutines counts goroutines. A goroutine is attributed to the SDK when
+// a function of this package is on its stack or started it, which includes
+// tool handlers while they run.
+type DebugGoroutines struct {
+	// Total is the number of goroutines in the process.
+	Total int
+	// SDK is the number of goroutines attributed to the SDK.
+	SDK int
+	// ByFunction counts the SDK's goroutines by the outermost function of
+	// this package on their stacks, such as "(*JSONRPCClient).readLoop".
+	ByFunction map[string]int
+}
+
+// DebugRequest is a request sent to the server that is waiting for a response.
+type DebugRequest struct {
+	Method string
+	// Age is how long ago the request was sent.
+	Age time.Duration
+}
+
+// DebugProcess is the status of the CLI process of a [Client].
+type DebugProcess struct {
+	// External reports whether the client is connected to a server it did
+	// not spawn, through [ClientOptions.CLIUrl].
+	External bool
+	// Address is the host and port of a server connected over TCP.
+	Address string
+	// Running reports whether the client has a CLI process it spawned.
+	Running bool
+	// PID is the process ID of the CLI process, if running.
+	PID int
+	// RSSBytes, UserCPUTime, and SystemCPUTime are the resource usage of the
+	// CLI process, as reported by [Client.Stats].
+	RSSBytes      int64
+	UserCPUTime   time.Duration
+	SystemCPUTime time.Duration
+	// StatsError is why resource usage is not reported, if it is not.
+	StatsError string
+}
+
+// DebugSession is the state and buffer sizes of one session.
+type DebugSession struct {
+	SessionID string
+	// Handlers is the number of event handlers registered with [Session.On].
+	Handlers int
+	// Tools is the number of tools registered.
+	Tools int
+	// ToolCallsInFlight is the number of tool calls requested and not yet
+	// completed.
+	ToolCallsInFlight int
+	// PendingToolCalls is the number of tool calls deferred with
+	// [ToolInvocation.PendingResult] and not yet completed.
+	PendingToolCalls int
+	// TurnInProgress reports whether a turn of a session with a turn queue is
+	// in progress, and QueuedTurns how many turns wait to start.
+	TurnInProgress bool
+	QueuedTurns    int
+	// LastCursor is the cursor of the last event dispatched, and
+	// ReplayBufferEvents the number of events retained for
+	// [Subscription.ResumeFrom].
+	LastCursor         EventCursor
+	ReplayBufferEvents int
+	// StreamingMessages is the number of messages receiving deltas.
+	StreamingMessages int
+	// CitationMessages is the number of messages with citations retained.
+	CitationMessages int
+}
+
+// DebugDump captures the client's internal state in one snapshot: goroutines
+// attributable to the SDK, queued and pending requests, per-session buffers,
+// and the status of the CLI process. It does not contact the server, so it
+// can be taken while the client is stuck.
+//
+// Example:
+//
+//	data, _ := json.MarshalIndent(client.DebugDump(), "", "  ")
+//	os.WriteFile("copilot-debug.json", data, 0o644)
+func (c *Client) DebugDump() DebugSnapshot {
+	snapshot := DebugSnapshot{
+		Time:            time.Now(),
+		ProtocolVersion: SdkProtocolVersion,
+		State:           c.GetState(),
+		Goroutines:      countSDKGoroutines(),
+		Concurrency:     c.concurrency.stats(),
+		FlowControl:     c.flow.stats(),
+		Process:         c.debugProcess(),
+		ProtocolErrors:  c.RecentErrors(),
+	}
+	if client := c.rpc(); client != nil {
+		snapshot.PendingRequests = client.debugPendingRequests(snapshot.Time)
+	}
+
+	c.sessionsMux.Lock()
+	sessions := make([]*Session, 0, len(c.sessions))
+	for _, session := range c.sessions {
+		sessions = append(sessions, session)
+	}
+	c.sessionsMux.Unlock()
+	sort.Slice(sessions, func(i, j int) bool { return sessions[i].SessionID < sessions[j].SessionID })
+	for _, session := range sessions {
+		snapshot.Sessions = append(snapshot.Sessions, session.debugSession())
+	}
+	return snapshot
+}
+
+func (c *Client) debugProcess() DebugProcess {
+	process := DebugProcess{External: c.isExternalServer}
+	if !c.useStdio || c.isExternalServer {
+		process.Address = c.actualHost
+		if c.actualPort != 0 {
+			process.Address = net.JoinHostPort(c.actualHost, strconv.Itoa(c.actualPort))
+		}
+	}
+	stats, err := c.Stats()
+	if err != nil {
+		process.StatsError = err.Error()
+	}
+	if c.process != nil && c.process.Process != nil {
+		process.Running = true
+		process.PID = c.process.Process.Pid
+	}
+	process.RSSBytes = stats.RSSBytes
+	process.UserCPUTime = stats.UserCPUTime
+	process.SystemCPUTime = stats.SystemCPUTime
+	return process
+}
+
+// debugPendingRequests returns the requests waiting for a response as of now,
+// oldest first.
+func (c *JSONRPCClient) debugPendingRequests(now time.Time) []DebugRequest {
+	c.mu.Lock()
+	pending := make([]*pendingRequest, 0, len(c.pendingRequests))
+	for _, request := range c.pendingRequests {
+		pending = append(pending, request)
+	}
+	c.mu.Unlock()
+	sort.Slice(pending, func(i, j int) bool { return pending[i].sentAt.Before(pending[j].sentAt) })
+
+	requests := make([]DebugRequest, len(pending))
+	for i, request := range pending {
+		requests[i] = DebugRequest{Method: request.method, Age: now.Sub(request.sentAt)}
+	}
+	return requests
+}
+
+func (s *Session) debugSession() DebugSession {
+	info := DebugSession{SessionID: s.SessionID}
+
+	s.handlerMutex.RLock()
+	info.Handlers = len(s.handlers)
+	s.handlerMutex.RUnlock()
+
+	s.toolHandlersM.RLock()
+	info.Tools = len(s.toolHandlers)
+	s.toolHandlersM.RUnlock()
+
+	s.toolCallNamesMux.Lock()
+	info.ToolCallsInFlight = len(s.toolCallNames)
+	s.toolCallNamesMux.Unlock()
+
+	s.pendingCalls.mu.Lock()
+	info.PendingToolCalls = len(s.pendingCalls.calls)
+	s.pendingCalls.mu.Unlock()
+
+	s.turns.mu.Lock()
+	info.TurnInProgress = s.turns.busy
+	info.QueuedTurns = len(s.turns.waiting)
+	s.turns.mu.Unlock()
+
+	s.eventLogMux.Lock()
+	info.LastCursor = s.eventLog.last
+	info.ReplayBufferEvents = len(s.eventLog.events)
+	s.eventLogMux.Unlock()
+
+	s.streamFallback.mu.Lock()
+	info.StreamingMessages = len(s.streamFallback.streamed)
+	s.streamFallback.mu.Unlock()
+
+	s.citationsMux.Lock()
+	info.CitationMessages = len(s.citationOrder)
+	s.citationsMux.Unlock()
+	return info
+}
+
+// sdkPackagePrefix prefixes the names of this package's functions in stack
+// traces.
+var sdkPackagePrefix = reflect.TypeOf(Client{}).PkgPath() + "."
+
+// countSDKGoroutines counts the goroutines of the process, other than the
+// caller's, by the outermost function of this package on their stacks.
+func countSDKGoroutines() DebugGoroutines {
+	buf := make([]byte, 64<<10)
+	for {
+		n := runtime.Stack(buf, true)
+		if n < len(buf) {
+			buf = buf[:n]
+			break
+		}
+		buf = make([]byte, 2*len(buf))
+	}
+
+	counts := DebugGoroutines{ByFunction: make(map[string]int)}
+	for i, stack := range strings.Split(string(buf), "\n\n") {
+		if i == 0 {
+			// The first stack is the caller's.
+			continue
+		}
+		counts.Total++
+		if function := outermostSDKFunction(stack); function != "" {
+			counts.SDK++
+			counts.ByFunction[function]++
+		}
+	}
+	return counts
+}
+
+// outermostSDKFunction returns the outermost function of this package in a
+// goroutine's stack trace, falling back to the function that created the
+// goroutine, without the package path.
+func outermostSDKFunction(stack string) string {
+	var outermost string
+	for _, line := range strings.Split(stack, "\n") {
+		if strings.HasPrefix(line, "\t") || strings.HasPrefix(line, "goroutine ") {
+			continue
+		}
+		creator := strings.HasPrefix(line, "created by ")
+		line = strings.TrimPrefix(line, "created by ")
+		if !strings.HasPrefix(line, sdkPackagePrefix) {
+			continue
+		}
+		function := strings.TrimPrefix(line, sdkPackagePrefix)
+		if end := strings.Index(function, " in goroutine "); end >= 0 {
+			function = function[:end]
+		}
+		if end := strings.LastIndex(function, "("); end > 0 && strings.HasSuffix(function, ")") && !creator {
+			function = function[:end]
+		}
+		if !creator || outermost == "" {
+			outermost = function
+		}
+	}
+	return outermost
+}
@@ -0,0 +1,73 @@
+package copilot
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestClient_DebugDump(t *testing.T) {
+	release := make(chan struct{})
+	rpc, _ := newFakeServer(t, func(method string, params map[string]interface{}) (map[string]interface{}, *JSONRPCError) {
+		if method == "session.slow" {
+			<-release
+		}
+		return nil, nil
+	})
+	defer close(release)
+	session := NewSession("s1", rpc, "")
+	session.On(func(event SessionEvent) {})
+	session.emit(SDKToolRetried, map[string]interface{}{})
+	client := &Client{client: rpc, state: StateConnected, sessions: map[string]*Session{"s1": session}}
+
+	go rpc.Request("session.slow", nil)
+	deadline := time.Now().Add(2 * time.Second)
+	for rpc.PendingRequests() == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("Timed out waiting for the request to be sent")
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	dump := client.DebugDump()
+	if dump.State != StateConnected || dump.ProtocolVersion != SdkProtocolVersion {
+		t.Errorf("Unexpected client state %+v", dump)
+	}
+	if len(dump.PendingRequests) != 1 || dump.PendingRequests[0].Method != "session.slow" || dump.PendingRequests[0].Age <= 0 {
+		t.Errorf("Expected the pending request, got %+v", dump.PendingRequests)
+	}
+	if dump.Goroutines.ByFunction["(*JSONRPCClient).readLoop"] != 1 || dump.Goroutines.SDK > dump.Goroutines.Total {
+		t.Errorf("Expected the read loop to be counted, got %+v", dump.Goroutines)
+	}
+	if len(dump.Sessions) != 1 {
+		t.Fatalf("Expected one session, got %+v", dump.Sessions)
+	}
+	if s := dump.Sessions[0]; s.SessionID != "s1" || s.Handlers != 1 || s.LastCursor != 1 || s.ReplayBufferEvents != 1 {
+		t.Errorf("Unexpected session state %+v", s)
+	}
+	if dump.Process.Running || dump.Process.StatsError == "" {
+		t.Errorf("Expected no CLI process, got %+v", dump.Process)
+	}
+	if _, err := json.Marshal(dump); err != nil {
+		t.Errorf("Failed to encode the snapshot: %v", err)
+	}
+}
+
+func TestOutermostSDKFunction(t *testing.T) {
+	stacks := map[string]string{
+		"goroutine 7 [IO wait]:\n" +
+			"internal/poll.runtime_pollWait(0x7f, 0x72)\n\t/go/src/runtime/netpoll.go:345 +0x85\n" +
+			sdkPackagePrefix + "readFrame(0xc000010000)\n\t/src/jsonrpc.go:540 +0x3a\n" +
+			sdkPackagePrefix + "(*JSONRPCClient).readLoop(0xc000020000)\n\t/src/jsonrpc.go:302 +0x9c\n" +
+			"created by " + sdkPackagePrefix + "(*JSONRPCClient).Start in goroutine 1\n\t/src/jsonrpc.go:108 +0x6a": "(*JSONRPCClient).readLoop",
+		"goroutine 9 [select]:\n" +
+			"main.handler()\n\t/app/main.go:10 +0x1\n" +
+			"created by " + sdkPackagePrefix + "(*JSONRPCClient).handleRequest in goroutine 7\n\t/src/jsonrpc.go:470 +0x2": "(*JSONRPCClient).handleRequest",
+		"goroutine 3 [chan receive]:\nmain.main()\n\t/app/main.go:5 +0x1": "",
+	}
+	for stack, want := range stacks {
+		if got := outermostSDKFunction(stack); got != want {
+			t.Errorf("Expected %q, got %q", want, got)
+		}
+	}
+}
@@ -0,0 +1,253 @@
+package copilot
+
+// DebugToolStatus is the state of a tool call at a point in a recording.
+type DebugToolStatus string
+
+const (
+	// DebugToolRequested is a tool call the assistant requested that has not
+	// started executing.
+	DebugToolRequested DebugToolStatus = "requested"
+	// DebugToolRunning is a tool call that has started and not completed.
+	DebugToolRunning DebugToolStatus = "running"
+	// DebugToolSucceeded is a tool call that completed successfully.
+	DebugToolSucceeded DebugToolStatus = "succeeded"
+	// DebugToolFailed is a tool call that completed with an error.
+	DebugToolFailed DebugToolStatus = "failed"
+)
+
+// DebugToolCall is the state of one tool call at a point in a recording.
+type DebugToolCall struct {
+	ToolCallID string
+	ToolName   string
+	Arguments  interface{}
+	Status     DebugToolStatus
+	// Progress is the last progress message the tool reported.
+	Progress string
+	// PartialOutput is the last partial output the tool reported.
+	PartialOutput string
+	// Result is the content of the tool's result once it succeeded.
+	Result string
+	// Error is the error message once the tool failed.
+	Error string
+}
+
+// DebugState is the client state reconstructed from the first events of a
+// recording, as seen after the event at [DebugState.Position].
+type DebugState struct {
+	// Position is the number of events applied, from 0 before the first event to
+	// the number of recorded events after the last.
+	Position int
+	// Event is the last applied event, or nil at position 0.
+	Event *SessionEvent
+	// Turn is the number of user messages applied, so 1 during the first turn.
+	Turn int
+	// Busy reports whether a turn is in progress, from its user message until the
+	// session becomes idle.
+	Busy bool
+	// Content is the text of the current turn's last assistant message, or the
+	// streamed deltas received since it.
+	Content string
+	// ToolCalls are the tool calls of the recording so far, in the order they
+	// were requested or started.
+	ToolCalls []*DebugToolCall
+	// LastError is the message of the last session.error event, if any.
+	LastError string
+}
+
+// PendingToolCalls returns the tool calls that have been requested or started
+// but not completed.
+func (s *DebugState) PendingToolCalls() []*DebugToolCall {
+	var pending []*DebugToolCall
+	for _, call := range s.ToolCalls {
+		if call.Status == DebugToolRequested || call.Status == DebugToolRunning {
+			pending = append(pending, call)
+		}
+	}
+	return pending
+}
+
+// ToolCall returns the state of a tool call, or nil if it has not been seen.
+func (s *DebugState) ToolCall(toolCallID string) *DebugToolCall {
+	for _, call := range s.ToolCalls {
+		if call.ToolCallID == toolCallID {
+			return call
+		}
+	}
+	return nil
+}
+
+// clone returns a copy of s that does not share tool calls with it.
+func (s *DebugState) clone() *DebugState {
+	c := *s
+	c.ToolCalls = make([]*DebugToolCall, len(s.ToolCalls))
+	for i, call := range s.ToolCalls {
+		callCopy := *call
+		c.ToolCalls[i] = &callCopy
+	}
+	return &c
+}
+
+// apply advances the state past event.
+func (s *DebugState) apply(event SessionEvent) {
+	s.Position++
+	eventCopy := event
+	s.Event = &eventCopy
+
+	for _, req := range event.Data.ToolRequests {
+		if req.ToolCallID != "" && s.ToolCall(req.ToolCallID) == nil {
+			s.ToolCalls = append(s.ToolCalls, &DebugToolCall{
+				ToolCallID: req.ToolCallID,
+				ToolName:   req.Name,
+				Arguments:  req.Arguments,
+				Status:     DebugToolRequested,
+			})
+		}
+	}
+
+	switch event.Type {
+	case UserMessage:
+		s.Turn++
+		s.Busy = true
+		s.Content = ""
+	case SessionIdle:
+		s.Busy = false
+	case SessionError:
+		if event.Data.Message != nil {
+			s.LastError = *event.Data.Message
+		}
+	case AssistantMessage:
+		if event.Data.Content != nil {
+			s.Content = *event.Data.Content
+		}
+	case AssistantMessageDelta:
+		if event.Data.DeltaContent != nil {
+			s.Content += *event.Data.DeltaContent
+		}
+	case ToolExecutionStart, ToolExecutionProgress, ToolExecutionPartialResult, ToolExecutionComplete:
+		s.applyToolEvent(event)
+	}
+}
+
+func (s *DebugState) applyToolEvent(event SessionEvent) {
+	if event.Data.ToolCallID == nil {
+		return
+	}
+	call := s.ToolCall(*event.Data.ToolCallID)
+	if call == nil {
+		call = &DebugToolCall{ToolCallID: *event.Data.ToolCallID, Status: DebugToolRunning}
+		s.ToolCalls = append(s.ToolCalls, call)
+	}
+	if event.Data.ToolName != nil {
+		call.ToolName = *event.Data.ToolName
+	}
+
+	switch event.Type {
+	case ToolExecutionStart:
+		call.Status = DebugToolRunning
+		if event.Data.Arguments != nil {
+			call.Arguments = event.Data.Arguments
+		}
+	case ToolExecutionProgress:
+		if event.Data.ProgressMessage != nil {
+			call.Progress = *event.Data.ProgressMessage
+		}
+	case ToolExecutionPartialResult:
+		if event.Data.PartialOutput != nil {
+			call.PartialOutput = *event.Data.PartialOutput
+		}
+	case ToolExecutionComplete:
+		if err := toolCallErrorFromEvent(event, call.ToolName); err != nil {
+			call.Status = DebugToolFailed
+			call.Error = err.Message
+			return
+		}
+		call.Status = DebugToolSucceeded
+		if event.Data.Result != nil {
+			call.Result = event.Data.Result.Content
+		}
+	}
+}
+
+// TranscriptDebugger steps forward and backward through recorded session
+// events, reconstructing the client state at each point. It is meant for tests
+// and tools that diagnose protocol issues, such as a tool call that never
+// completes.
+//
+// A TranscriptDebugger is not safe for concurrent use.
+//
+// Example:
+//
+//	transcript, _ := store.Load(sessionID)
+//	debugger := copilot.NewTranscriptDebugger(transcript.Events)
+//	debugger.RunUntil(func(s *copilot.DebugState) bool { return len(s.PendingToolCalls()) > 0 })
+//	for _, call := range debugger.State().PendingToolCalls() {
+//	    fmt.Println(call.ToolName, call.Status)
+//	}
+type TranscriptDebugger struct {
+	events []SessionEvent
+	state  *DebugState
+}
+
+// NewTranscriptDebugger creates a debugger over events, positioned before the
+// first event.
+func NewTranscriptDebugger(events []SessionEvent) *TranscriptDebugger {
+	return &TranscriptDebugger{events: events, state: &DebugState{}}
+}
+
+// Len returns the number of recorded events.
+func (d *TranscriptDebugger) Len() int {
+	return len(d.events)
+}
+
+// Position returns the number of events applied.
+func (d *TranscriptDebugger) Position() int {
+	return d.state.Position
+}
+
+// State returns a copy of the state at the current position.
+func (d *TranscriptDebugger) State() *DebugState {
+	return d.state.clone()
+}
+
+// Step applies the next event. It returns false at the end of the recording.
+func (d *TranscriptDebugger) Step() bool {
+	if d.state.Position >= len(d.events) {
+		return false
+	}
+	d.state.apply(d.events[d.state.Position])
+	return true
+}
+
+// Back undoes the last applied event. It returns false at the start of the
+// recording.
+func (d *TranscriptDebugger) Back() bool {
+	if d.state.Position == 0 {
+		return false
+	}
+	d.Seek(d.state.Position - 1)
+	return true
+}
+
+// Seek moves to position, clamped to the recording. Moving backward replays the
+// events from the start.
+func (d *TranscriptDebugger) Seek(position int) {
+	position = max(0, min(position, len(d.events)))
+	if position < d.state.Position {
+		d.state = &DebugState{}
+	}
+	for d.state.Position < position {
+		d.state.apply(d.events[d.state.Position])
+	}
+}
+
+// RunUntil steps forward until cond reports true for the state after an event,
+// and reports whether it did. If it never does, the debugger stops at the end.
+// cond must not modify or retain the state it is passed.
+func (d *TranscriptDebugger) RunUntil(cond func(*DebugState) bool) bool {
+	for d.Step() {
+		if cond(d.state) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,84 @@
+package copilot
+
+import "testing"
+
+func TestTranscriptDebugger(t *testing.T) {
+	prompt, reply := "List the files", "Here they are"
+	progress := "listing"
+	failure := toolCompleteEvent("2", false, "")
+	failure.Data.Error = &ErrorUnion{String: strPtr("not found")}
+	events := []SessionEvent{
+		{Type: UserMessage, Data: Data{Content: &prompt}},
+		{Type: AssistantMessage, Data: Data{ToolRequests: []ToolRequest{
+			{ToolCallID: "1", Name: "ls"},
+			{ToolCallID: "2", Name: "cat"},
+		}}},
+		toolStartEvent("1", "ls", map[string]interface{}{"path": "."}),
+		{Type: ToolExecutionProgress, Data: Data{ToolCallID: strPtr("1"), ProgressMessage: &progress}},
+		toolCompleteEvent("1", true, "a.go"),
+		toolStartEvent("2", "cat", nil),
+		failure,
+		{Type: AssistantMessage, Data: Data{Content: &reply}},
+		{Type: SessionIdle},
+	}
+	debugger := NewTranscriptDebugger(events)
+
+	if state := debugger.State(); state.Position != 0 || state.Event != nil || state.Busy {
+		t.Errorf("Expected the initial state, got %+v", state)
+	}
+
+	if !debugger.RunUntil(func(s *DebugState) bool { return len(s.PendingToolCalls()) == 2 }) {
+		t.Fatal("Expected both tool calls to become pending")
+	}
+	state := debugger.State()
+	if state.Position != 2 || state.Turn != 1 || !state.Busy || state.ToolCall("1").Status != DebugToolRequested {
+		t.Errorf("Unexpected state after the tool requests: %+v", state)
+	}
+
+	debugger.Step()
+	debugger.Step()
+	if call := debugger.State().ToolCall("1"); call.Status != DebugToolRunning || call.Progress != "listing" || call.Arguments.(map[string]interface{})["path"] != "." {
+		t.Errorf("Unexpected running tool call %+v", call)
+	}
+
+	debugger.Seek(debugger.Len())
+	state = debugger.State()
+	if state.Busy || state.Content != reply || len(state.PendingToolCalls()) != 0 {
+		t.Errorf("Unexpected final state %+v", state)
+	}
+	if call := state.ToolCall("1"); call.Status != DebugToolSucceeded || call.Result != "a.go" {
+		t.Errorf("Unexpected succeeded tool call %+v", call)
+	}
+	if call := state.ToolCall("2"); call.Status != DebugToolFailed || call.Error != "not found" {
+		t.Errorf("Unexpected failed tool call %+v", call)
+	}
+	if debugger.Step() {
+		t.Error("Expected Step to stop at the end")
+	}
+
+	t.Run("steps backward", func(t *testing.T) {
+		debugger.Back()
+		debugger.Back()
+		state := debugger.State()
+		if state.Position != len(events)-2 || state.Content != "" || !state.Busy {
+			t.Errorf("Unexpected state after stepping back: %+v", state)
+		}
+		if state.Event.Type != ToolExecutionComplete {
+			t.Errorf("Expected the failed completion to be the current event, got %s", state.Event.Type)
+		}
+		debugger.Seek(-1)
+		if debugger.Position() != 0 || debugger.Back() {
+			t.Error("Expected Back to stop at the start")
+		}
+	})
+
+	t.Run("states are snapshots", func(t *testing.T) {
+		debugger.Seek(5)
+		snapshot := debugger.State()
+		debugger.Step()
+		debugger.Step()
+		if snapshot.ToolCall("2").Status != DebugToolRequested {
+			t.Errorf("Expected an earlier state to be unaffected by stepping, got %+v", snapshot.ToolCall("2"))
+		}
+	})
+}
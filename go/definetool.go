@@ -8,8 +8,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"reflect"
-
-	"github.com/google/jsonschema-go/jsonschema"
+	"time"
 )
 
 // DefineTool creates a Tool with automatic JSON schema generation from a typed handler function.
@@ -27,45 +26,241 @@ import (
 //	    func(params GetWeatherParams, inv copilot.ToolInvocation) (any, error) {
 //	        return fmt.Sprintf("Weather in %s: 22°%s", params.City, params.Unit), nil
 //	    })
-func DefineTool[T any, U any](name, description string, handler func(T, ToolInvocation) (U, error)) Tool {
-	var zero T
-	schema := generateSchemaForType(reflect.TypeOf(zero))
+//
+// Omitted arguments are set to the defaults of fields with a default tag, and
+// arguments are validated against the generated schema before they are decoded;
+// see [ValidateArguments]. Options such as [WithErrorRenderer],
+// [WithArgumentTransformers], [WithToolTimeout], [WithRetryPolicy],
+// [WithResultPostProcessors], [WithResultLimit], [WithParameters], and
+// [WithoutArgumentValidation] can be passed to customize the tool.
+//
+// DefineTool panics if no schema can be generated for T; use [TryDefineTool] to
+// handle the error instead.
+func DefineTool[T any, U any](name, description string, handler func(T, ToolInvocation) (U, error), opts ...ToolOption) Tool {
+	tool, err := TryDefineTool(name, description, handler, opts...)
+	if err != nil {
+		panic(err.Error())
+	}
+	return tool
+}
+
+// TryDefineTool is like [DefineTool], but returns an error instead of panicking
+// if no schema can be generated for T, for example because it has a channel
+// field or a recursive union, or if the schema passed to [WithParameters]
+// cannot be encoded.
+//
+// Example:
+//
+//	tool, err := copilot.TryDefineTool(endpoint.Name, endpoint.Summary, handler)
+//	if err != nil {
+//	    return fmt.Errorf("failed to define tool for %s: %w", endpoint.Path, err)
+//	}
+func TryDefineTool[T any, U any](name, description string, handler func(T, ToolInvocation) (U, error), opts ...ToolOption) (Tool, error) {
+	var options toolOptions
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	var schema map[string]interface{}
+	var err error
+	if options.parameters != nil {
+		schema, err = decodeParametersSchema(options.parameters)
+	} else {
+		schema, err = tryGenerateSchemaForType(reflect.TypeOf((*T)(nil)).Elem())
+	}
+	if err != nil {
+		return Tool{}, fmt.Errorf("failed to define tool %s: %w", name, err)
+	}
 
 	return Tool{
-		Name:        name,
-		Description: description,
-		Parameters:  schema,
-		Handler:     createTypedHandler(handler),
+		Name:                     name,
+		Description:              description,
+		Parameters:               schema,
+		Handler:                  createTypedHandler(handler, schema, options),
+		Timeout:                  options.timeout,
+		PostProcessors:           options.postProcessors,
+		ResultLimit:              options.resultLimit,
+		MaxConcurrentInvocations: options.maxConcurrent,
+		MutexGroups:              options.mutexGroups,
+		RetryPolicy:              options.retryPolicy,
+		RequiresApproval:         options.requiresApproval,
+		ApprovalPolicy:           options.approvalPolicy,
+		Cost:                     options.cost,
+		CostBudget:               options.costBudget,
+		resultSchema:             resultSchemaForType(reflect.TypeOf((*U)(nil)).Elem()),
+	}, nil
+}
+
+// ToolOption configures a tool created by [DefineTool].
+type ToolOption func(*toolOptions)
+
+type toolOptions struct {
+	errorRenderer        ErrorRenderer
+	rawErrors            bool
+	argumentTransformers []ArgumentTransformer
+	timeout              time.Duration
+	skipValidation       bool
+	postProcessors       []ResultPostProcessor
+	parameters           map[string]interface{}
+	resultLimit          *ResultLimit
+	maxConcurrent        int
+	mutexGroups          []string
+	retryPolicy          *RetryPolicy
+	requiresApproval     bool
+	approvalPolicy       func(inv ToolInvocation) bool
+	cost                 func(inv ToolInvocation) ToolCost
+	costBudget           *CostBudget
+	lenientArguments     bool
+}
+
+// WithErrorRenderer reports handler errors to the model as text produced by
+// renderer instead of [RenderToolError].
+//
+// An error returned from a typed handler makes the tool return a failure
+// ToolResult whose TextResultForLLM is renderer(err), letting the model
+// understand and correct the problem. The raw error is still recorded in
+// ToolResult.Error. See [WithoutErrorRenderer] to fail the tool call instead.
+//
+// Example:
+//
+//	tool := copilot.DefineTool("read_file", "Read a file", readFile,
+//	    copilot.WithErrorRenderer(func(err error) string {
+//	        return "Could not read the file: " + err.Error()
+//	    }))
+func WithErrorRenderer(renderer ErrorRenderer) ToolOption {
+	return func(o *toolOptions) {
+		o.errorRenderer = renderer
+		o.rawErrors = false
+	}
+}
+
+// WithoutErrorRenderer makes an error returned from a typed handler fail the
+// tool call, so the model only sees a generic failure message, rather than
+// being rendered with [RenderToolError].
+func WithoutErrorRenderer() ToolOption {
+	return func(o *toolOptions) {
+		o.errorRenderer = nil
+		o.rawErrors = true
+	}
+}
+
+// WithParameters advertises schema as the tool's parameters instead of the
+// schema generated from the handler's parameter type. Use it for parameters a Go
+// struct cannot describe, such as oneOf or anyOf unions and recursive types,
+// with a handler that takes json.RawMessage or map[string]interface{} and
+// decodes the arguments itself.
+//
+// Arguments are still validated against schema, but only with the keywords
+// [ValidateArguments] supports; others, such as oneOf, are left to the handler.
+//
+// Example:
+//
+//	shapeSchema := map[string]interface{}{
+//	    "type": "object",
+//	    "properties": map[string]interface{}{
+//	        "shape": map[string]interface{}{
+//	            "oneOf": []interface{}{circleSchema, rectangleSchema},
+//	        },
+//	    },
+//	    "required": []string{"shape"},
+//	}
+//	tool := copilot.DefineTool("area", "Compute the area of a shape",
+//	    func(args json.RawMessage, inv copilot.ToolInvocation) (float64, error) {
+//	        return computeArea(args)
+//	    },
+//	    copilot.WithParameters(shapeSchema))
+func WithParameters(schema map[string]interface{}) ToolOption {
+	return func(o *toolOptions) {
+		o.parameters = schema
 	}
 }
 
 // createTypedHandler wraps a typed handler function into the standard ToolHandler signature.
-func createTypedHandler[T any, U any](handler func(T, ToolInvocation) (U, error)) ToolHandler {
+// Omitted arguments are set to their defaults in schema, and arguments that do
+// not match schema produce an invalid-arguments failure result without calling
+// the handler.
+func createTypedHandler[T any, U any](handler func(T, ToolInvocation) (U, error), schema map[string]interface{}, options toolOptions) ToolHandler {
+	hasDefaults := schemaHasDefaults(schema)
+	renderer := options.errorRenderer
+	if renderer == nil && !options.rawErrors {
+		renderer = RenderToolError
+	}
 	return func(inv ToolInvocation) (ToolResult, error) {
-		var params T
-
-		// Convert arguments to typed struct via JSON round-trip
-		// Arguments is already map[string]interface{} from JSON-RPC parsing
-		jsonBytes, err := json.Marshal(inv.Arguments)
-		if err != nil {
-			return ToolResult{}, fmt.Errorf("failed to marshal arguments: %w", err)
+		if options.lenientArguments {
+			inv.Arguments = unfenceArguments(inv.Arguments)
 		}
-
-		if err := json.Unmarshal(jsonBytes, &params); err != nil {
-			return ToolResult{}, fmt.Errorf("failed to unmarshal arguments into %T: %w", params, err)
+		inv, err := applyArgumentTransformers(inv, options.argumentTransformers)
+		if err == nil && options.lenientArguments {
+			inv.Arguments = coerceArguments(schema, inv.Arguments)
+		}
+		if err == nil && hasDefaults {
+			inv.Arguments = applySchemaDefaults(schema, inv.Arguments)
+		}
+		if err == nil && !options.skipValidation {
+			if errs := ValidateArguments(schema, inv.Arguments); len(errs) > 0 {
+				return buildInvalidArgumentsResult(&InvalidArgumentsError{ToolName: inv.ToolName, Errors: errs}), nil
+			}
+		}
+		var result ToolResult
+		if err == nil {
+			result, err = invokeTypedHandler(handler, inv)
 		}
-
-		result, err := handler(params, inv)
 		if err != nil {
+			if _, panicked := asToolPanic(err); !panicked {
+				if toolErr, ok := asToolError(err); ok {
+					return buildToolErrorResult(err, toolErr), nil
+				}
+				if renderer != nil {
+					return buildRenderedErrorResult(err, renderer), nil
+				}
+			}
 			return ToolResult{}, err
 		}
+		return result, nil
+	}
+}
+
+// invokeTypedHandler decodes the invocation arguments into T, calls the handler,
+// and normalizes its result. A panic in the handler is returned as a
+// [*ToolPanicError].
+func invokeTypedHandler[T any, U any](handler func(T, ToolInvocation) (U, error), inv ToolInvocation) (_ ToolResult, err error) {
+	defer recoverToolPanic(inv, &err)
+	var params T
+
+	// Convert arguments to typed struct via JSON round-trip
+	// Arguments is already map[string]interface{} from JSON-RPC parsing
+	jsonBytes, err := json.Marshal(inv.Arguments)
+	if err != nil {
+		return ToolResult{}, fmt.Errorf("failed to marshal arguments: %w", err)
+	}
 
-		return normalizeResult(result)
+	if err := decodeArguments(jsonBytes, &params); err != nil {
+		return ToolResult{}, fmt.Errorf("failed to unmarshal arguments into %T: %w", params, err)
+	}
+
+	result, err := handler(params, inv)
+	if err != nil {
+		return ToolResult{}, err
+	}
+
+	return normalizeResult(result)
+}
+
+// buildRenderedErrorResult creates a failure ToolResult whose model-facing text is rendered from err.
+func buildRenderedErrorResult(err error, renderer ErrorRenderer) ToolResult {
+	return ToolResult{
+		TextResultForLLM: renderer(err),
+		ResultType:       "failure",
+		Error:            err.Error(),
+		ToolTelemetry:    map[string]interface{}{},
+		renderedErr:      err,
 	}
 }
 
 // normalizeResult converts any value to a ToolResult.
-// Strings pass through directly, ToolResult passes through, other types are JSON-serialized.
+// Strings pass through directly, ToolResult and *ToolResult pass through with
+// their DisplayData and MimeType, attachments become binary results, a
+// [ToolError] becomes a failure result, other types are JSON-serialized.
 func normalizeResult(result any) (ToolResult, error) {
 	if result == nil {
 		return ToolResult{
@@ -78,6 +273,35 @@ func normalizeResult(result any) (ToolResult, error) {
 	if tr, ok := result.(ToolResult); ok {
 		return tr, nil
 	}
+	if tr, ok := result.(*ToolResult); ok {
+		if tr == nil {
+			return ToolResult{ResultType: "success"}, nil
+		}
+		return *tr, nil
+	}
+
+	// A ToolError fails the call with its code and message
+	switch toolErr := result.(type) {
+	case ToolError:
+		return buildToolErrorResult(&toolErr, &toolErr), nil
+	case *ToolError:
+		if toolErr != nil {
+			return buildToolErrorResult(toolErr, toolErr), nil
+		}
+	}
+
+	// Attachments become binary results
+	switch attachments := result.(type) {
+	case ToolResultAttachment:
+		return ToolResult{ResultType: "success", Attachments: []ToolResultAttachment{attachments}}, nil
+	case *ToolResultAttachment:
+		if attachments == nil {
+			return ToolResult{ResultType: "success"}, nil
+		}
+		return ToolResult{ResultType: "success", Attachments: []ToolResultAttachment{*attachments}}, nil
+	case []ToolResultAttachment:
+		return ToolResult{ResultType: "success", Attachments: attachments}, nil
+	}
 
 	// Strings pass through directly
 	if str, ok := result.(string); ok {
@@ -99,34 +323,50 @@ func normalizeResult(result any) (ToolResult, error) {
 	}, nil
 }
 
+// decodeParametersSchema returns a copy of a hand-written schema with the types
+// decoded JSON has, such as []interface{} for a []string, so that arguments can
+// be validated against it.
+func decodeParametersSchema(schema map[string]interface{}) (map[string]interface{}, error) {
+	data, err := json.Marshal(schema)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode parameters schema: %w", err)
+	}
+	var decoded map[string]interface{}
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		return nil, fmt.Errorf("failed to decode parameters schema: %w", err)
+	}
+	return decoded, nil
+}
+
 // generateSchemaForType generates a JSON schema map from a Go type using reflection.
-// Panics if schema generation fails, as this indicates a programming error.
+// Panics if the type is not supported.
 func generateSchemaForType(t reflect.Type) map[string]interface{} {
+	schema, err := tryGenerateSchemaForType(t)
+	if err != nil {
+		panic(err.Error())
+	}
+	return schema
+}
+
+// tryGenerateSchemaForType is like generateSchemaForType, but returns an error
+// instead of panicking.
+func tryGenerateSchemaForType(t reflect.Type) (map[string]interface{}, error) {
 	if t == nil {
-		return nil
+		return nil, nil
 	}
 
 	// Handle pointer types
 	if t.Kind() == reflect.Ptr {
 		t = t.Elem()
 	}
-
-	// Use google/jsonschema-go to generate the schema
-	schema, err := jsonschema.ForType(t, nil)
-	if err != nil {
-		panic(fmt.Sprintf("failed to generate schema for type %v: %v", t, err))
+	// Interfaces other than unions accept any arguments.
+	if t.Kind() == reflect.Interface && lookupUnion(t) == nil {
+		return nil, nil
 	}
 
-	// Convert schema to map[string]interface{}
-	schemaBytes, err := json.Marshal(schema)
+	schema, err := schemaForType(t)
 	if err != nil {
-		panic(fmt.Sprintf("failed to marshal schema for type %v: %v", t, err))
+		return nil, fmt.Errorf("failed to generate schema for type %v: %w", t, err)
 	}
-
-	var schemaMap map[string]interface{}
-	if err := json.Unmarshal(schemaBytes, &schemaMap); err != nil {
-		panic(fmt.Sprintf("failed to unmarshal schema for type %v: %v", t, err))
-	}
-
-	return schemaMap
+	return schema, nil
 }
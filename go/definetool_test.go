@@ -1,8 +1,11 @@
 package copilot
 
 import (
+	"context"
+	"encoding/json"
 	"errors"
 	"reflect"
+	"strings"
 	"testing"
 )
 
@@ -123,14 +126,31 @@ func TestDefineTool(t *testing.T) {
 		}
 	})
 
-	t.Run("handler error is propagated", func(t *testing.T) {
+	t.Run("handler error is rendered for the model by default", func(t *testing.T) {
 		type Params struct{}
 
 		tool := DefineTool("failing", "A failing tool",
 			func(params Params, inv ToolInvocation) (any, error) {
-				return nil, errors.New("something went wrong")
+				return nil, errors.New("something went wrong\ngoroutine 1 [running]:")
 			})
 
+		result, err := tool.Handler(ToolInvocation{Arguments: map[string]interface{}{}})
+		if err != nil {
+			t.Fatalf("Expected a failure result, got %v", err)
+		}
+		if result.ResultType != "failure" || result.TextResultForLLM != "Error: something went wrong" {
+			t.Errorf("Expected the rendered error, got %+v", result)
+		}
+	})
+
+	t.Run("handler error is propagated without an error renderer", func(t *testing.T) {
+		type Params struct{}
+
+		tool := DefineTool("failing", "A failing tool",
+			func(params Params, inv ToolInvocation) (any, error) {
+				return nil, errors.New("something went wrong")
+			}, WithoutErrorRenderer())
+
 		inv := ToolInvocation{
 			Arguments: map[string]interface{}{},
 		}
@@ -143,6 +163,114 @@ func TestDefineTool(t *testing.T) {
 			t.Errorf("Expected error 'something went wrong', got %q", err.Error())
 		}
 	})
+
+	t.Run("raw parameters with a hand-written schema", func(t *testing.T) {
+		schema := map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"shape": map[string]interface{}{
+					"oneOf": []interface{}{
+						map[string]interface{}{"type": "object", "properties": map[string]interface{}{"radius": map[string]interface{}{"type": "number"}}},
+						map[string]interface{}{"type": "object", "properties": map[string]interface{}{"side": map[string]interface{}{"type": "number"}}},
+					},
+				},
+			},
+			"required": []string{"shape"},
+		}
+
+		var received json.RawMessage
+		tool := DefineTool("area", "Compute an area",
+			func(args json.RawMessage, inv ToolInvocation) (string, error) {
+				received = args
+				return "ok", nil
+			},
+			WithParameters(schema))
+
+		if _, ok := tool.Parameters["properties"].(map[string]interface{})["shape"].(map[string]interface{})["oneOf"]; !ok {
+			t.Errorf("Expected the hand-written schema to be advertised, got %v", tool.Parameters)
+		}
+
+		result, err := tool.Handler(ToolInvocation{Arguments: map[string]interface{}{"shape": map[string]interface{}{"radius": 2.0}}})
+		if err != nil || result.TextResultForLLM != "ok" {
+			t.Fatalf("Unexpected result %+v, %v", result, err)
+		}
+		if string(received) != `{"shape":{"radius":2}}` {
+			t.Errorf("Expected the raw arguments, got %s", received)
+		}
+
+		result, _ = tool.Handler(ToolInvocation{ToolName: "area", Arguments: map[string]interface{}{}})
+		if result.ResultType != "failure" || !strings.Contains(result.TextResultForLLM, "shape: is required") {
+			t.Errorf("Expected the hand-written schema to be validated, got %+v", result)
+		}
+	})
+
+	t.Run("map parameters with a hand-written schema", func(t *testing.T) {
+		var received map[string]interface{}
+		tool := DefineTool("tree", "Walk a tree",
+			func(args map[string]interface{}, inv ToolInvocation) (string, error) {
+				received = args
+				return "ok", nil
+			},
+			WithParameters(map[string]interface{}{
+				"$defs": map[string]interface{}{"node": map[string]interface{}{"type": "object"}},
+				"$ref":  "#/$defs/node",
+			}))
+
+		if tool.Parameters["$ref"] != "#/$defs/node" {
+			t.Errorf("Expected the hand-written schema to be advertised, got %v", tool.Parameters)
+		}
+		if _, err := tool.Handler(ToolInvocation{Arguments: map[string]interface{}{"children": []interface{}{}}}); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if _, ok := received["children"]; !ok {
+			t.Errorf("Expected the decoded arguments, got %v", received)
+		}
+	})
+	t.Run("fills in defaults for omitted arguments", func(t *testing.T) {
+		type Filter struct {
+			Field string `json:"field"`
+			Op    string `json:"op" default:"eq"`
+		}
+		type Params struct {
+			Dir     string   `json:"dir"`
+			Limit   int      `json:"limit" default:"50"`
+			Hidden  bool     `json:"hidden" default:"true"`
+			Exts    []string `json:"exts" default:"[\"go\",\"md\"]"`
+			Filters []Filter `json:"filters"`
+		}
+		var received Params
+		var receivedArgs interface{}
+		tool := DefineTool("list", "List files",
+			func(params Params, inv ToolInvocation) (string, error) {
+				received = params
+				receivedArgs = inv.Arguments
+				return "ok", nil
+			})
+
+		args := map[string]interface{}{
+			"dir":     "src",
+			"hidden":  nil,
+			"filters": []interface{}{map[string]interface{}{"field": "name"}},
+		}
+		result, err := tool.Handler(ToolInvocation{Arguments: args})
+		if err != nil || result.ResultType != "success" {
+			t.Fatalf("Unexpected result %+v, %v", result, err)
+		}
+		want := Params{Dir: "src", Limit: 50, Hidden: true, Exts: []string{"go", "md"}, Filters: []Filter{{Field: "name", Op: "eq"}}}
+		if !reflect.DeepEqual(received, want) {
+			t.Errorf("Expected %+v, got %+v", want, received)
+		}
+		if filled, _ := receivedArgs.(map[string]interface{}); filled["limit"] != float64(50) {
+			t.Errorf("Expected the invocation to carry the defaults, got %v", receivedArgs)
+		}
+		if _, ok := args["limit"]; ok || args["hidden"] != nil {
+			t.Errorf("Expected the caller's arguments to be left unchanged, got %v", args)
+		}
+
+		if _, err := tool.Handler(ToolInvocation{Arguments: map[string]interface{}{"dir": "src", "limit": 5, "filters": []interface{}{}}}); err != nil || received.Limit != 5 {
+			t.Errorf("Expected a given argument to win over the default, got %d (%v)", received.Limit, err)
+		}
+	})
 }
 
 func TestNormalizeResult(t *testing.T) {
@@ -251,6 +379,24 @@ func TestNormalizeResult(t *testing.T) {
 			t.Fatal("Expected error for unserializable value, got nil")
 		}
 	})
+
+	t.Run("attachments become binary results", func(t *testing.T) {
+		result, err := normalizeResult([]ToolResultAttachment{{Data: []byte("png"), MimeType: "image/png"}})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if len(result.Attachments) != 1 || result.ResultType != "success" {
+			t.Errorf("Expected one attachment, got %+v", result)
+		}
+
+		result, err = normalizeResult(ToolResultAttachment{Data: []byte("csv"), Name: "data.csv"})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if len(result.Attachments) != 1 || result.Attachments[0].Name != "data.csv" {
+			t.Errorf("Expected one attachment, got %+v", result)
+		}
+	})
 }
 
 func TestGenerateSchemaForType(t *testing.T) {
@@ -376,4 +522,226 @@ func TestGenerateSchemaForType(t *testing.T) {
 			t.Errorf("Expected tags type to be string or array, got %T: %v", tagType, tagType)
 		}
 	})
+	t.Run("marks optional fields", func(t *testing.T) {
+		type Filter struct {
+			Label string  `json:"label"`
+			Since *string `json:"since"`
+		}
+		type Params struct {
+			Query    string   `json:"query" jsonschema:"Search query"`
+			Limit    *int     `json:"limit" jsonschema:"Maximum number of results"`
+			Page     int      `json:"page,omitempty"`
+			Sort     string   `json:"sort" jsonschema:"optional,Sort order"`
+			Verbose  bool     `json:"verbose" jsonschema:"optional"`
+			Cursor   *string  `json:"cursor" jsonschema:"required,Cursor from the previous page, or null"`
+			Tags     []string `json:"tags,omitempty" jsonschema:"required"`
+			Filters  []Filter `json:"filters"`
+			internal string
+		}
+
+		schema := generateSchemaForType(reflect.TypeOf(Params{}))
+
+		required, _ := schema["required"].([]interface{})
+		want := []interface{}{"query", "cursor", "tags", "filters"}
+		if !reflect.DeepEqual(required, want) {
+			t.Errorf("Expected required %v, got %v", want, required)
+		}
+
+		props := schema["properties"].(map[string]interface{})
+		descriptions := map[string]interface{}{
+			"query":   "Search query",
+			"limit":   "Maximum number of results",
+			"sort":    "Sort order",
+			"verbose": nil,
+			"cursor":  "Cursor from the previous page, or null",
+			"tags":    nil,
+		}
+		for name, want := range descriptions {
+			if got := props[name].(map[string]interface{})["description"]; got != want {
+				t.Errorf("Expected %s description %v, got %v", name, want, got)
+			}
+		}
+
+		items := props["filters"].(map[string]interface{})["items"].(map[string]interface{})
+		if got, _ := items["required"].([]interface{}); !reflect.DeepEqual(got, []interface{}{"label"}) {
+			t.Errorf("Expected nested required [label], got %v", got)
+		}
+	})
+
+	t.Run("omits required when every field is optional", func(t *testing.T) {
+		type Params struct {
+			Name *string `json:"name"`
+		}
+
+		schema := generateSchemaForType(reflect.TypeOf(Params{}))
+
+		if _, ok := schema["required"]; ok {
+			t.Errorf("Expected no required array, got %v", schema["required"])
+		}
+	})
+	t.Run("applies enum and constraint directives", func(t *testing.T) {
+		type Base struct {
+			Region string `json:"region" jsonschema:"enum=us|eu,Deployment region"`
+		}
+		type Params struct {
+			Base
+			Unit     string   `json:"unit" jsonschema:"enum=celsius|fahrenheit,Temperature unit"`
+			Days     int      `json:"days" jsonschema:"minimum=1,maximum=14"`
+			Priority *int     `json:"priority" jsonschema:"enum=1|2|3"`
+			Date     string   `json:"date" jsonschema:"format=date,pattern=^\\d{4}-\\d{2}-\\d{2}$"`
+			Code     string   `json:"code" jsonschema:"optional,pattern=^[A-Z]{2\\,3}$,Country code, such as US"`
+			Labels   []string `json:"labels" jsonschema:"enum=bug|feature"`
+		}
+
+		schema := generateSchemaForType(reflect.TypeOf(Params{}))
+		props := schema["properties"].(map[string]interface{})
+		prop := func(name string) map[string]interface{} {
+			t.Helper()
+			p, ok := props[name].(map[string]interface{})
+			if !ok {
+				t.Fatalf("Expected %q property, got %v", name, props)
+			}
+			return p
+		}
+
+		unit := prop("unit")
+		if !reflect.DeepEqual(unit["enum"], []interface{}{"celsius", "fahrenheit"}) || unit["description"] != "Temperature unit" {
+			t.Errorf("Unexpected unit schema %v", unit)
+		}
+		if region := prop("region"); !reflect.DeepEqual(region["enum"], []interface{}{"us", "eu"}) || region["description"] != "Deployment region" {
+			t.Errorf("Unexpected embedded region schema %v", region)
+		}
+		if days := prop("days"); days["minimum"] != 1.0 || days["maximum"] != 14.0 {
+			t.Errorf("Unexpected days schema %v", days)
+		}
+		if priority := prop("priority"); !reflect.DeepEqual(priority["enum"], []interface{}{1.0, 2.0, 3.0, nil}) {
+			t.Errorf("Unexpected priority schema %v", priority)
+		}
+		if date := prop("date"); date["format"] != "date" || date["pattern"] != `^\d{4}-\d{2}-\d{2}$` {
+			t.Errorf("Unexpected date schema %v", date)
+		}
+		if code := prop("code"); code["pattern"] != "^[A-Z]{2,3}$" || code["description"] != "Country code, such as US" {
+			t.Errorf("Unexpected code schema %v", code)
+		}
+		items := prop("labels")["items"].(map[string]interface{})
+		if !reflect.DeepEqual(items["enum"], []interface{}{"bug", "feature"}) {
+			t.Errorf("Unexpected labels items %v", items)
+		}
+
+		required, _ := schema["required"].([]interface{})
+		if want := []interface{}{"region", "unit", "days", "date", "labels"}; !reflect.DeepEqual(required, want) {
+			t.Errorf("Expected required %v, got %v", want, required)
+		}
+	})
+
+	t.Run("sets defaults from default tags", func(t *testing.T) {
+		type Params struct {
+			Query string   `json:"query"`
+			Unit  string   `json:"unit" default:"celsius" jsonschema:"enum=celsius|fahrenheit"`
+			Days  int      `json:"days" default:"3"`
+			Ratio *float64 `json:"ratio" default:"0.5"`
+			Tags  []string `json:"tags" default:"[\"a\"]"`
+		}
+		schema, err := schemaForType(reflect.TypeOf(Params{}))
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		props := schema["properties"].(map[string]interface{})
+		defaults := map[string]interface{}{
+			"unit":  "celsius",
+			"days":  float64(3),
+			"ratio": 0.5,
+			"tags":  []interface{}{"a"},
+		}
+		for name, want := range defaults {
+			if got := props[name].(map[string]interface{})["default"]; !reflect.DeepEqual(got, want) {
+				t.Errorf("Expected %s to default to %v, got %v", name, want, got)
+			}
+		}
+		if required, _ := schema["required"].([]interface{}); !reflect.DeepEqual(required, []interface{}{"query"}) {
+			t.Errorf("Expected fields with defaults to be optional, got %v", required)
+		}
+	})
+
+	t.Run("rejects invalid directives", func(t *testing.T) {
+		types := map[string]reflect.Type{
+			"unknown directive": reflect.TypeOf(struct {
+				A string `json:"a" jsonschema:"maxlen=3"`
+			}{}),
+			"bad number": reflect.TypeOf(struct {
+				A int `json:"a" jsonschema:"minimum=one"`
+			}{}),
+			"bad enum value": reflect.TypeOf(struct {
+				A int `json:"a" jsonschema:"enum=1|two"`
+			}{}),
+			"bad pattern": reflect.TypeOf(struct {
+				A string `json:"a" jsonschema:"pattern=("`
+			}{}),
+			"minimum on string": reflect.TypeOf(struct {
+				A string `json:"a" jsonschema:"minimum=1"`
+			}{}),
+			"optional and required": reflect.TypeOf(struct {
+				A string `json:"a" jsonschema:"optional,required"`
+			}{}),
+			"bad default": reflect.TypeOf(struct {
+				A int `json:"a" default:"many"`
+			}{}),
+			"bad JSON default": reflect.TypeOf(struct {
+				A []string `json:"a" default:"go,md"`
+			}{}),
+		}
+		for name, typ := range types {
+			if _, err := schemaForType(typ); err == nil {
+				t.Errorf("%s: expected an error", name)
+			}
+		}
+	})
+}
+
+func TestTryDefineTool(t *testing.T) {
+	type BadParams struct {
+		Updates chan string `json:"updates"`
+	}
+	handler := func(params BadParams, inv ToolInvocation) (string, error) { return "", nil }
+
+	t.Run("returns schema errors", func(t *testing.T) {
+		_, err := TryDefineTool("watch", "Watch", handler)
+		if err == nil || !strings.Contains(err.Error(), "failed to define tool watch") {
+			t.Errorf("Expected an error naming the tool, got %v", err)
+		}
+		_, err = TryDefineToolCtx("watch", "Watch", func(ctx context.Context, params BadParams, inv ToolInvocation) (string, error) {
+			return "", nil
+		})
+		if err == nil {
+			t.Error("Expected an error from TryDefineToolCtx")
+		}
+	})
+
+	t.Run("returns errors for unencodable parameters", func(t *testing.T) {
+		_, err := TryDefineTool("raw", "Raw", func(args json.RawMessage, inv ToolInvocation) (string, error) { return "", nil },
+			WithParameters(map[string]interface{}{"default": make(chan int)}))
+		if err == nil || !strings.Contains(err.Error(), "failed to encode parameters schema") {
+			t.Errorf("Unexpected error %v", err)
+		}
+	})
+
+	t.Run("defines supported tools", func(t *testing.T) {
+		tool, err := TryDefineTool("greet", "Greet", func(params struct {
+			Name string `json:"name"`
+		}, inv ToolInvocation) (string, error) {
+			return "hi " + params.Name, nil
+		})
+		if err != nil || tool.Parameters["type"] != "object" || tool.Handler == nil {
+			t.Errorf("Unexpected tool %+v, error %v", tool, err)
+		}
+	})
+
+	t.Run("DefineTool panics", func(t *testing.T) {
+		defer func() {
+			if recover() == nil {
+				t.Error("Expected a panic")
+			}
+		}()
+		DefineTool("watch", "Watch", handler)
+	})
 }
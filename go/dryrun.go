@@ -0,0 +1,241 @@
+package copilot
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"time"
+)
+
+// DryRunConfig puts a session in dry-run mode: tool handlers are not executed.
+// Set it in [SessionConfig] for one session, or in [ClientOptions] for every
+// session of a client.
+// Instead, each tool call gets a simulated result and is recorded, so you can
+// preview what an agent would do before letting it perform high-risk
+// operations. Retrieve the recorded calls with [Session.SimulatedToolCalls].
+//
+// A simulated result is, in order of preference: the canned result from
+// Results, the result returned by Simulate, an example derived from the
+// result type of a tool created with [DefineTool], or a generic message.
+//
+// Example:
+//
+//	session, err := client.CreateSession(&copilot.SessionConfig{
+//	    Tools: []copilot.Tool{deployTool, readLogsTool},
+//	    DryRun: &copilot.DryRunConfig{
+//	        Tools: []string{"deploy"},
+//	        Results: map[string]copilot.ToolResult{
+//	            "deploy": {TextResultForLLM: "Deployed version 42", ResultType: "success"},
+//	        },
+//	    },
+//	})
+//	// ...
+//	for _, call := range session.SimulatedToolCalls() {
+//	    fmt.Printf("Would have run %s with %v\n", call.ToolName, call.Arguments)
+//	}
+type DryRunConfig struct {
+	// Tools limits the dry run to the named tools; other tools run normally.
+	// If empty, every tool is simulated.
+	Tools []string
+	// Results are canned results by tool name.
+	Results map[string]ToolResult
+	// Simulate, if set, produces the result of tool calls without a canned
+	// result. Returning an error fails the simulated call. The call's Result is
+	// not yet set.
+	Simulate func(call SimulatedToolCall) (ToolResult, error)
+}
+
+// SimulatedToolCall records a tool call that was simulated instead of run.
+type SimulatedToolCall struct {
+	ToolCallID string
+	ToolName   string
+	Arguments  interface{}
+	// Result is the simulated result returned to the model.
+	Result ToolResult
+	Time   time.Time
+}
+
+// dryRunState holds the dry-run configuration of a session and the calls
+// simulated so far.
+type dryRunState struct {
+	config        *DryRunConfig
+	tools         map[string]bool
+	resultSchemas map[string]map[string]interface{}
+	calls         []SimulatedToolCall
+}
+
+// registerDryRun enables dry-run mode for this session.
+func (s *Session) registerDryRun(config *DryRunConfig, tools []Tool) {
+	if config == nil {
+		return
+	}
+	state := dryRunState{config: config, resultSchemas: make(map[string]map[string]interface{})}
+	if len(config.Tools) > 0 {
+		state.tools = make(map[string]bool, len(config.Tools))
+		for _, name := range config.Tools {
+			state.tools[name] = true
+		}
+	}
+	for _, tool := range tools {
+		if tool.resultSchema != nil {
+			state.resultSchemas[tool.Name] = tool.resultSchema
+		}
+	}
+
+	s.dryRunMux.Lock()
+	defer s.dryRunMux.Unlock()
+	s.dryRun = state
+}
+
+// dryRunConfig returns the dry-run configuration of a session that sets
+// config, which defaults to the client's.
+func (c *Client) dryRunConfig(config *DryRunConfig) *DryRunConfig {
+	if config != nil {
+		return config
+	}
+	return c.options.DryRun
+}
+
+// SimulatedToolCalls returns the tool calls simulated in dry-run mode, oldest
+// first. See [DryRunConfig].
+func (s *Session) SimulatedToolCalls() []SimulatedToolCall {
+	s.dryRunMux.Lock()
+	defer s.dryRunMux.Unlock()
+	return append([]SimulatedToolCall(nil), s.dryRun.calls...)
+}
+
+// simulateToolCall returns a simulated result for the tool call if it falls
+// under the session's dry run, and records it.
+func (s *Session) simulateToolCall(toolCallID, toolName string, arguments interface{}) (ToolResult, bool) {
+	s.dryRunMux.Lock()
+	config := s.dryRun.config
+	simulated := config != nil && (s.dryRun.tools == nil || s.dryRun.tools[toolName])
+	schema := s.dryRun.resultSchemas[toolName]
+	s.dryRunMux.Unlock()
+	if !simulated {
+		return ToolResult{}, false
+	}
+
+	call := SimulatedToolCall{
+		ToolCallID: toolCallID,
+		ToolName:   toolName,
+		Arguments:  arguments,
+		Time:       time.Now(),
+	}
+	if result, ok := config.Results[toolName]; ok {
+		call.Result = result
+	} else if config.Simulate != nil {
+		result, err := config.Simulate(call)
+		if err != nil {
+			result = buildHandlerErrorResult(err)
+		}
+		call.Result = result
+	} else {
+		call.Result = buildExampleToolResult(toolName, schema)
+	}
+
+	s.dryRunMux.Lock()
+	s.dryRun.calls = append(s.dryRun.calls, call)
+	s.dryRunMux.Unlock()
+
+	s.emit(SDKToolSimulated, map[string]interface{}{
+		"toolCallId": toolCallID,
+		"toolName":   toolName,
+	})
+	return call.Result, true
+}
+
+// buildExampleToolResult creates a successful ToolResult holding an example
+// value of schema, or a generic message if there is no schema.
+func buildExampleToolResult(toolName string, schema map[string]interface{}) ToolResult {
+	text := fmt.Sprintf("Dry run: tool '%s' was not executed.", toolName)
+	if schema != nil {
+		if data, err := json.Marshal(exampleForSchema(schema, 0)); err == nil {
+			text = string(data)
+		}
+	}
+	return ToolResult{
+		TextResultForLLM: text,
+		ResultType:       "success",
+		ToolTelemetry:    map[string]interface{}{"dryRun": true},
+	}
+}
+
+// maxExampleDepth bounds the nesting of generated examples.
+const maxExampleDepth = 8
+
+// exampleForSchema returns a value that conforms to a JSON schema, preferring
+// the examples, default, const, and enum values the schema provides.
+func exampleForSchema(schema map[string]interface{}, depth int) interface{} {
+	if examples, ok := schema["examples"].([]interface{}); ok && len(examples) > 0 {
+		return examples[0]
+	}
+	for _, key := range []string{"default", "const"} {
+		if value, ok := schema[key]; ok {
+			return value
+		}
+	}
+	if enum, ok := schema["enum"].([]interface{}); ok && len(enum) > 0 {
+		return enum[0]
+	}
+	if depth >= maxExampleDepth {
+		return nil
+	}
+
+	switch schemaType(schema) {
+	case "object":
+		example := make(map[string]interface{})
+		properties, _ := schema["properties"].(map[string]interface{})
+		for name, property := range properties {
+			if property, ok := property.(map[string]interface{}); ok {
+				example[name] = exampleForSchema(property, depth+1)
+			}
+		}
+		return example
+	case "array":
+		if items, ok := schema["items"].(map[string]interface{}); ok {
+			return []interface{}{exampleForSchema(items, depth+1)}
+		}
+		return []interface{}{}
+	case "string":
+		return "string"
+	case "integer", "number":
+		return 0
+	case "boolean":
+		return false
+	default:
+		return nil
+	}
+}
+
+// schemaType returns the type of a schema, ignoring "null" in type lists.
+func schemaType(schema map[string]interface{}) string {
+	switch t := schema["type"].(type) {
+	case string:
+		return t
+	case []interface{}:
+		for _, item := range t {
+			if name, ok := item.(string); ok && name != "null" {
+				return name
+			}
+		}
+	}
+	return ""
+}
+
+// resultSchemaForType returns the JSON schema of a typed handler's result type,
+// used to derive dry-run examples. It returns nil for types whose results are
+// not JSON-serialized, and for types no schema can be generated for.
+func resultSchemaForType(t reflect.Type) map[string]interface{} {
+	for t != nil && t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t == nil || t.Kind() == reflect.Interface || t.Kind() == reflect.String || t == reflect.TypeOf(ToolResult{}) {
+		return nil
+	}
+	schema, err := schemaForType(t)
+	if err != nil {
+		return nil
+	}
+	return schema
+}
@@ -0,0 +1,145 @@
+package copilot
+
+import (
+	"errors"
+	"testing"
+)
+
+type deployParams struct {
+	Version string `json:"version"`
+}
+
+type deployResult struct {
+	URL      string   `json:"url"`
+	Replicas int      `json:"replicas"`
+	Regions  []string `json:"regions"`
+}
+
+func TestDryRun(t *testing.T) {
+	callTool := func(t *testing.T, client *Client, name string) ToolResult {
+		t.Helper()
+		response, rpcErr := client.handleToolCallRequest(map[string]interface{}{
+			"sessionId":  "s1",
+			"toolCallId": "call-" + name,
+			"toolName":   name,
+			"arguments":  map[string]interface{}{"version": "42"},
+		})
+		if rpcErr != nil {
+			t.Fatalf("Unexpected error: %v", rpcErr)
+		}
+		return response["result"].(ToolResult)
+	}
+
+	setup := func(config *DryRunConfig) (*Session, *Client, *[]string) {
+		var executed []string
+		tools := []Tool{
+			DefineTool("deploy", "Deploy", func(params deployParams, inv ToolInvocation) (deployResult, error) {
+				executed = append(executed, "deploy")
+				return deployResult{URL: "https://example.com"}, nil
+			}),
+			DefineTool("read_logs", "Read logs", func(params deployParams, inv ToolInvocation) (string, error) {
+				executed = append(executed, "read_logs")
+				return "logs", nil
+			}),
+		}
+		session := NewSession("s1", nil, "")
+		session.registerTools(tools)
+		session.registerDryRun(config, tools)
+		return session, &Client{sessions: map[string]*Session{"s1": session}}, &executed
+	}
+
+	t.Run("derives examples from the result type", func(t *testing.T) {
+		session, client, executed := setup(&DryRunConfig{})
+		var simulated []SessionEvent
+		session.On(func(event SessionEvent) {
+			if event.Type == SDKToolSimulated {
+				simulated = append(simulated, event)
+			}
+		})
+
+		result := callTool(t, client, "deploy")
+		if len(*executed) != 0 {
+			t.Errorf("Expected no handler to run, ran %v", *executed)
+		}
+		if result.TextResultForLLM != `{"regions":["string"],"replicas":0,"url":"string"}` {
+			t.Errorf("Unexpected example %s", result.TextResultForLLM)
+		}
+
+		result = callTool(t, client, "read_logs")
+		if result.TextResultForLLM != "Dry run: tool 'read_logs' was not executed." {
+			t.Errorf("Unexpected result %q", result.TextResultForLLM)
+		}
+
+		calls := session.SimulatedToolCalls()
+		if len(calls) != 2 || calls[0].ToolName != "deploy" || calls[0].Arguments.(map[string]interface{})["version"] != "42" {
+			t.Errorf("Unexpected calls %+v", calls)
+		}
+		if len(simulated) != 2 || sdkEventString(simulated[0], "toolCallId") != "call-deploy" {
+			t.Errorf("Unexpected events %+v", simulated)
+		}
+	})
+
+	t.Run("uses canned and simulated results", func(t *testing.T) {
+		_, client, _ := setup(&DryRunConfig{
+			Results: map[string]ToolResult{"deploy": {TextResultForLLM: "Deployed", ResultType: "success"}},
+			Simulate: func(call SimulatedToolCall) (ToolResult, error) {
+				return ToolResult{}, errors.New("no logs in dry run")
+			},
+		})
+		if result := callTool(t, client, "deploy"); result.TextResultForLLM != "Deployed" {
+			t.Errorf("Expected the canned result, got %q", result.TextResultForLLM)
+		}
+		if result := callTool(t, client, "read_logs"); result.ResultType != "failure" || result.Error != "no logs in dry run" {
+			t.Errorf("Expected a failed simulated result, got %+v", result)
+		}
+	})
+
+	t.Run("only simulates the listed tools", func(t *testing.T) {
+		session, client, executed := setup(&DryRunConfig{Tools: []string{"deploy"}})
+		callTool(t, client, "deploy")
+		if result := callTool(t, client, "read_logs"); result.TextResultForLLM != "logs" {
+			t.Errorf("Expected read_logs to run, got %q", result.TextResultForLLM)
+		}
+		if len(*executed) != 1 || (*executed)[0] != "read_logs" {
+			t.Errorf("Expected only read_logs to run, ran %v", *executed)
+		}
+		if len(session.SimulatedToolCalls()) != 1 {
+			t.Errorf("Expected one simulated call, got %d", len(session.SimulatedToolCalls()))
+		}
+	})
+
+	t.Run("defaults to the client's configuration", func(t *testing.T) {
+		client := NewClient(&ClientOptions{DryRun: &DryRunConfig{
+			Results: map[string]ToolResult{"deploy": {TextResultForLLM: "Deployed", ResultType: "success"}},
+		}})
+		own := &DryRunConfig{Tools: []string{"read_logs"}}
+		if client.dryRunConfig(own) != own {
+			t.Error("Expected a session's own configuration to take precedence")
+		}
+
+		session, sessions, executed := setup(client.dryRunConfig(nil))
+		client.sessions = sessions.sessions
+		if result := callTool(t, client, "deploy"); result.TextResultForLLM != "Deployed" {
+			t.Errorf("Expected the client's canned result, got %q", result.TextResultForLLM)
+		}
+		callTool(t, client, "read_logs")
+		if len(*executed) != 0 || len(session.SimulatedToolCalls()) != 2 {
+			t.Errorf("Expected every call to be simulated, ran %v", *executed)
+		}
+	})
+}
+
+func TestExampleForSchema(t *testing.T) {
+	schema := map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"status": map[string]interface{}{"type": "string", "enum": []interface{}{"ok", "failed"}},
+			"note":   map[string]interface{}{"type": []interface{}{"null", "string"}, "examples": []interface{}{"all good"}},
+			"ready":  map[string]interface{}{"type": "boolean", "default": true},
+		},
+	}
+	example := exampleForSchema(schema, 0).(map[string]interface{})
+	if example["status"] != "ok" || example["note"] != "all good" || example["ready"] != true {
+		t.Errorf("Unexpected example %v", example)
+	}
+}
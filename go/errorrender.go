@@ -0,0 +1,124 @@
+package copilot
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"strings"
+	"unicode/utf8"
+)
+
+// ErrorRenderer converts an error returned by a tool handler into the text
+// reported to the model. See [WithErrorRenderer] and [RenderToolError].
+type ErrorRenderer func(err error) string
+
+// maxRenderedErrorLength caps the length of the message produced by [RenderToolError].
+const maxRenderedErrorLength = 500
+
+// RenderToolError is the default [ErrorRenderer]. It produces a short, actionable
+// description of err intended for the model:
+//
+//   - Well-known errors anywhere in the wrap chain (argument decoding errors,
+//     context cancellation and deadlines, missing files, permission errors) are
+//     mapped to a fixed explanation.
+//   - Otherwise the first line of the error message is used, with stack traces
+//     and other multi-line noise removed, truncated to a reasonable length.
+//   - If any error in the chain has a Suggestion() string method, its
+//     suggestion is appended.
+func RenderToolError(err error) string {
+	if err == nil {
+		return ""
+	}
+
+	message, suggestion := describeKnownError(err)
+	if message == "" {
+		message = trimErrorNoise(err.Error())
+	}
+	if s := findSuggestion(err); s != "" {
+		suggestion = s
+	}
+
+	text := "Error: " + message
+	if suggestion != "" {
+		text += "\nSuggestion: " + suggestion
+	}
+	return text
+}
+
+// describeKnownError maps well-known errors to a message and suggestion.
+func describeKnownError(err error) (message, suggestion string) {
+	var typeErr *json.UnmarshalTypeError
+	var syntaxErr *json.SyntaxError
+	switch {
+	case errors.As(err, &typeErr):
+		field := typeErr.Field
+		if field == "" {
+			field = "arguments"
+		}
+		return fmt.Sprintf("argument %q must be of type %s, but got %s", field, typeErr.Type, typeErr.Value),
+			"Call the tool again with arguments that match its parameter schema."
+	case errors.As(err, &syntaxErr):
+		return "the tool arguments were not valid JSON",
+			"Call the tool again with a single well-formed JSON object."
+	case errors.Is(err, context.DeadlineExceeded):
+		return "the operation timed out",
+			"Try again with a smaller or more specific request."
+	case errors.Is(err, context.Canceled):
+		return "the operation was cancelled", ""
+	case errors.Is(err, fs.ErrNotExist):
+		return "the requested file or resource does not exist: " + trimErrorNoise(err.Error()),
+			"Check the path or identifier and try again."
+	case errors.Is(err, fs.ErrPermission):
+		return "permission denied: " + trimErrorNoise(err.Error()),
+			"Choose a resource the tool is allowed to access."
+	}
+	return "", ""
+}
+
+// findSuggestion walks the wrap chain (including joined errors) for an error
+// that offers a suggestion.
+func findSuggestion(err error) string {
+	if err == nil {
+		return ""
+	}
+	if s, ok := err.(interface{ Suggestion() string }); ok {
+		if suggestion := s.Suggestion(); suggestion != "" {
+			return suggestion
+		}
+	}
+	switch e := err.(type) {
+	case interface{ Unwrap() error }:
+		return findSuggestion(e.Unwrap())
+	case interface{ Unwrap() []error }:
+		for _, inner := range e.Unwrap() {
+			if s := findSuggestion(inner); s != "" {
+				return s
+			}
+		}
+	}
+	return ""
+}
+
+// trimErrorNoise keeps the first meaningful line of an error message, dropping
+// stack traces and goroutine dumps, and truncates overly long messages.
+func trimErrorNoise(message string) string {
+	for _, line := range strings.Split(message, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || strings.HasPrefix(line, "\t") || strings.HasPrefix(trimmed, "goroutine ") {
+			continue
+		}
+		message = trimmed
+		break
+	}
+	if len(message) > maxRenderedErrorLength {
+		end := maxRenderedErrorLength
+		// Don't split a multi-byte character.
+		for end > 0 && !utf8.RuneStart(message[end]) {
+			end--
+		}
+		message = message[:end] + "..."
+	}
+	return message
+}
@@ -0,0 +1,106 @@
+package copilot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+type suggestingError struct{}
+
+func (suggestingError) Error() string      { return "quota exceeded" }
+func (suggestingError) Suggestion() string { return "Wait a minute before retrying." }
+
+func TestRenderToolError(t *testing.T) {
+	t.Run("uses the first line and drops stack noise", func(t *testing.T) {
+		err := errors.New("database unavailable\ngoroutine 1 [running]:\n\tmain.go:12 +0x1d")
+		text := RenderToolError(err)
+		if text != "Error: database unavailable" {
+			t.Errorf("Unexpected text %q", text)
+		}
+	})
+
+	t.Run("truncates long messages", func(t *testing.T) {
+		text := RenderToolError(errors.New(strings.Repeat("x", 2000)))
+		if len(text) > maxRenderedErrorLength+len("Error: ...") {
+			t.Errorf("Expected truncated text, got %d bytes", len(text))
+		}
+	})
+
+	t.Run("truncates on a character boundary", func(t *testing.T) {
+		text := RenderToolError(errors.New("x" + strings.Repeat("é", maxRenderedErrorLength)))
+		if !utf8.ValidString(text) || !strings.HasSuffix(text, "é...") {
+			t.Errorf("Expected valid UTF-8 ending in a whole character, got %q", text[len(text)-8:])
+		}
+	})
+
+	t.Run("maps wrapped well-known errors", func(t *testing.T) {
+		err := fmt.Errorf("query failed: %w", context.DeadlineExceeded)
+		text := RenderToolError(err)
+		if !strings.Contains(text, "timed out") || !strings.Contains(text, "Suggestion:") {
+			t.Errorf("Unexpected text %q", text)
+		}
+
+		_, statErr := os.Stat("/definitely/not/here")
+		text = RenderToolError(fmt.Errorf("open config: %w", statErr))
+		if !strings.Contains(text, "does not exist") {
+			t.Errorf("Unexpected text %q", text)
+		}
+	})
+
+	t.Run("includes suggestions from the error chain", func(t *testing.T) {
+		err := fmt.Errorf("calling API: %w", suggestingError{})
+		text := RenderToolError(err)
+		if text != "Error: calling API: quota exceeded\nSuggestion: Wait a minute before retrying." {
+			t.Errorf("Unexpected text %q", text)
+		}
+	})
+}
+
+func TestDefineTool_WithErrorRenderer(t *testing.T) {
+	t.Run("handler errors become failure results with rendered text", func(t *testing.T) {
+		type Params struct{}
+
+		tool := DefineTool("failing", "A failing tool",
+			func(params Params, inv ToolInvocation) (any, error) {
+				return nil, errors.New("something went wrong")
+			},
+			WithErrorRenderer(func(err error) string { return "rendered: " + err.Error() }))
+
+		result, err := tool.Handler(ToolInvocation{Arguments: map[string]interface{}{}})
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if result.ResultType != "failure" {
+			t.Errorf("Expected failure result, got %q", result.ResultType)
+		}
+		if result.TextResultForLLM != "rendered: something went wrong" {
+			t.Errorf("Unexpected TextResultForLLM %q", result.TextResultForLLM)
+		}
+		if result.Error != "something went wrong" {
+			t.Errorf("Unexpected Error %q", result.Error)
+		}
+	})
+
+	t.Run("argument decoding errors are rendered", func(t *testing.T) {
+		type Params struct {
+			Count int `json:"count"`
+		}
+
+		tool := DefineTool("counter", "Counts",
+			func(params Params, inv ToolInvocation) (any, error) { return "ok", nil },
+			WithErrorRenderer(RenderToolError), WithoutArgumentValidation())
+
+		result, err := tool.Handler(ToolInvocation{Arguments: map[string]interface{}{"count": "many"}})
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if !strings.Contains(result.TextResultForLLM, `argument "count" must be of type int`) {
+			t.Errorf("Unexpected TextResultForLLM %q", result.TextResultForLLM)
+		}
+	})
+}
@@ -0,0 +1,236 @@
+package copilot
+
+import (
+	"fmt"
+	"sync"
+)
+
+// DefaultReplayBufferSize is how many recent events a session retains for
+// [Subscription.ResumeFrom] unless configured otherwise.
+const DefaultReplayBufferSize = 1000
+
+// EventCursor is the position of an event in a session's event stream. Every
+// event dispatched by a session gets the next cursor, starting at 1, so a
+// cursor names the last event a consumer has seen. The zero cursor is before
+// the first event.
+type EventCursor uint64
+
+// SequencedEvent is a session event with its position in the stream.
+type SequencedEvent struct {
+	Cursor EventCursor
+	Event  SessionEvent
+}
+
+// CursorExpiredError is returned when resuming from a cursor whose following
+// events are no longer all retained. The retained events, from Oldest on, are
+// still replayed; events between Cursor and Oldest were missed, so a consumer
+// should reload state, for example with [Session.GetMessages].
+type CursorExpiredError struct {
+	Cursor EventCursor
+	Oldest EventCursor
+}
+
+func (e *CursorExpiredError) Error() string {
+	return fmt.Sprintf("events after cursor %d are no longer retained; the oldest retained event is %d", e.Cursor, e.Oldest)
+}
+
+// eventLog numbers a session's events and retains the most recent ones.
+type eventLog struct {
+	last   EventCursor
+	events []SequencedEvent
+	// size is the number of events retained: 0 means DefaultReplayBufferSize
+	// and a negative size retains none.
+	size int
+}
+
+func (l *eventLog) append(event SessionEvent) SequencedEvent {
+	l.last++
+	sequenced := SequencedEvent{Cursor: l.last, Event: event}
+	size := l.size
+	if size == 0 {
+		size = DefaultReplayBufferSize
+	}
+	if size > 0 {
+		l.events = append(l.events, sequenced)
+		if len(l.events) > size {
+			l.events = l.events[len(l.events)-size:]
+		}
+	}
+	return sequenced
+}
+
+// since returns the retained events after cursor.
+func (l *eventLog) since(cursor EventCursor) ([]SequencedEvent, error) {
+	if cursor > l.last {
+		return nil, fmt.Errorf("cursor %d is ahead of the session's last event %d", cursor, l.last)
+	}
+	oldest := l.last + 1
+	if len(l.events) > 0 {
+		oldest = l.events[0].Cursor
+	}
+	var err error
+	if cursor+1 < oldest {
+		err = &CursorExpiredError{Cursor: cursor, Oldest: oldest}
+	}
+	start := 0
+	for start < len(l.events) && l.events[start].Cursor <= cursor {
+		start++
+	}
+	return append([]SequencedEvent(nil), l.events[start:]...), err
+}
+
+func (s *Session) registerReplayBuffer(size int) {
+	s.eventLogMux.Lock()
+	defer s.eventLogMux.Unlock()
+	s.eventLog.size = size
+}
+
+// Cursor returns the cursor of the last event dispatched by this session.
+func (s *Session) Cursor() EventCursor {
+	s.eventLogMux.Lock()
+	defer s.eventLogMux.Unlock()
+	return s.eventLog.last
+}
+
+// Subscription is a handler of sequenced session events that can catch up on
+// events it missed. Create one with [Session.Subscribe] or
+// [Session.ResumeFrom].
+type Subscription struct {
+	session     *Session
+	handler     func(SequencedEvent)
+	unsubscribe func()
+
+	mu     sync.Mutex
+	cursor EventCursor
+	// While replaying, live events are queued rather than delivered, so they
+	// follow the replayed ones without blocking dispatch. Queued events up to
+	// replayed were part of the replay and are dropped.
+	replaying bool
+	queued    []SequencedEvent
+	replayed  EventCursor
+}
+
+// Subscribe calls handler with each event this session dispatches from now on,
+// together with its cursor.
+func (s *Session) Subscribe(handler func(SequencedEvent)) *Subscription {
+	sub := &Subscription{session: s, handler: handler}
+	s.eventLogMux.Lock()
+	defer s.eventLogMux.Unlock()
+	sub.unsubscribe = s.subscribeSequenced(sub.deliver)
+	return sub
+}
+
+// ResumeFrom subscribes handler to this session starting after cursor: the
+// retained events after cursor are replayed, then live events follow with no
+// gap or duplicate between them. Use it when a consumer, such as a UI that lost
+// its connection, reconnects with the last cursor it saw.
+//
+// If some events after cursor are no longer retained, the subscription is
+// still returned, along with a [*CursorExpiredError].
+//
+// Example:
+//
+//	sub, err := session.ResumeFrom(lastCursor, func(e copilot.SequencedEvent) {
+//	    stream.Send(e.Cursor, e.Event)
+//	})
+//	var expired *copilot.CursorExpiredError
+//	if errors.As(err, &expired) {
+//	    stream.Reload()
+//	}
+//	defer sub.Unsubscribe()
+func (s *Session) ResumeFrom(cursor EventCursor, handler func(SequencedEvent)) (*Subscription, error) {
+	sub := &Subscription{session: s, handler: handler, cursor: cursor, replaying: true}
+
+	s.eventLogMux.Lock()
+	missed, err := s.eventLog.since(cursor)
+	if _, expired := err.(*CursorExpiredError); err != nil && !expired {
+		s.eventLogMux.Unlock()
+		return nil, err
+	}
+	// Events dispatched from here on are delivered live, after the replay.
+	sub.unsubscribe = s.subscribeSequenced(sub.deliver)
+	s.eventLogMux.Unlock()
+
+	sub.replay(missed)
+	return sub, err
+}
+
+// ResumeFrom replays the retained events after cursor to the subscription's
+// handler, then continues with live events. Events after cursor that the
+// handler already received are delivered again, so a consumer can resume from
+// the last cursor it acknowledged rather than the last one it was sent.
+//
+// If some events after cursor are no longer retained, the retained ones are
+// replayed and a [*CursorExpiredError] is returned.
+func (sub *Subscription) ResumeFrom(cursor EventCursor) error {
+	sub.mu.Lock()
+	sub.replaying = true
+	sub.mu.Unlock()
+
+	sub.session.eventLogMux.Lock()
+	missed, err := sub.session.eventLog.since(cursor)
+	sub.session.eventLogMux.Unlock()
+	sub.replay(missed)
+	return err
+}
+
+// Cursor returns the cursor of the last event delivered to the subscription's
+// handler.
+func (sub *Subscription) Cursor() EventCursor {
+	sub.mu.Lock()
+	defer sub.mu.Unlock()
+	return sub.cursor
+}
+
+// Unsubscribe stops delivering events to the subscription's handler.
+func (sub *Subscription) Unsubscribe() {
+	sub.unsubscribe()
+}
+
+// replay delivers missed events, then the live events queued meanwhile.
+func (sub *Subscription) replay(missed []SequencedEvent) {
+	for _, event := range missed {
+		sub.call(event)
+	}
+	sub.mu.Lock()
+	if len(missed) > 0 {
+		sub.replayed = max(sub.replayed, missed[len(missed)-1].Cursor)
+	}
+	for len(sub.queued) > 0 {
+		queued := sub.queued
+		sub.queued = nil
+		sub.mu.Unlock()
+		for _, event := range queued {
+			if event.Cursor > sub.replayed {
+				sub.call(event)
+			}
+		}
+		sub.mu.Lock()
+	}
+	sub.replaying = false
+	sub.mu.Unlock()
+}
+
+func (sub *Subscription) deliver(event SequencedEvent) {
+	sub.mu.Lock()
+	if sub.replaying {
+		sub.queued = append(sub.queued, event)
+		sub.mu.Unlock()
+		return
+	}
+	sub.mu.Unlock()
+	sub.call(event)
+}
+
+func (sub *Subscription) call(event SequencedEvent) {
+	sub.mu.Lock()
+	sub.cursor = max(sub.cursor, event.Cursor)
+	sub.mu.Unlock()
+
+	defer func() {
+		if r := recover(); r != nil {
+			fmt.Printf("Error in session event handler: %v\n", r)
+		}
+	}()
+	sub.handler(event)
+}
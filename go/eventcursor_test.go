@@ -0,0 +1,175 @@
+package copilot
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+)
+
+func dispatchNumbered(session *Session, from, to int) {
+	for i := from; i <= to; i++ {
+		session.dispatchEvent(SessionEvent{ID: fmt.Sprintf("e%d", i), Type: AssistantMessage})
+	}
+}
+
+func eventIDs(events []SequencedEvent) []string {
+	ids := make([]string, len(events))
+	for i, event := range events {
+		ids[i] = event.Event.ID
+	}
+	return ids
+}
+
+func TestSession_Subscribe(t *testing.T) {
+	session := NewSession("s1", nil, "")
+	dispatchNumbered(session, 1, 2)
+
+	var got []SequencedEvent
+	sub := session.Subscribe(func(event SequencedEvent) { got = append(got, event) })
+	dispatchNumbered(session, 3, 4)
+	sub.Unsubscribe()
+	dispatchNumbered(session, 5, 5)
+
+	if len(got) != 2 || got[0].Cursor != 3 || got[1].Cursor != 4 || got[1].Event.ID != "e4" {
+		t.Errorf("Unexpected events %+v", got)
+	}
+	if sub.Cursor() != 4 || session.Cursor() != 5 {
+		t.Errorf("Unexpected cursors: subscription %d, session %d", sub.Cursor(), session.Cursor())
+	}
+}
+
+func TestSession_ResumeFrom(t *testing.T) {
+	t.Run("replays missed events then continues live", func(t *testing.T) {
+		session := NewSession("s1", nil, "")
+		dispatchNumbered(session, 1, 5)
+
+		var got []SequencedEvent
+		sub, err := session.ResumeFrom(3, func(event SequencedEvent) { got = append(got, event) })
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		defer sub.Unsubscribe()
+		dispatchNumbered(session, 6, 6)
+
+		if ids := fmt.Sprint(eventIDs(got)); ids != "[e4 e5 e6]" {
+			t.Errorf("Unexpected events %s", ids)
+		}
+		if sub.Cursor() != 6 {
+			t.Errorf("Expected cursor 6, got %d", sub.Cursor())
+		}
+	})
+
+	t.Run("reports events that are no longer retained", func(t *testing.T) {
+		session := NewSession("s1", nil, "")
+		session.registerReplayBuffer(3)
+		dispatchNumbered(session, 1, 6)
+
+		var got []SequencedEvent
+		sub, err := session.ResumeFrom(1, func(event SequencedEvent) { got = append(got, event) })
+		var expired *CursorExpiredError
+		if !errors.As(err, &expired) || expired.Cursor != 1 || expired.Oldest != 4 {
+			t.Fatalf("Expected a CursorExpiredError, got %v", err)
+		}
+		if sub == nil {
+			t.Fatal("Expected a subscription despite the gap")
+		}
+		if ids := fmt.Sprint(eventIDs(got)); ids != "[e4 e5 e6]" {
+			t.Errorf("Expected the retained events, got %s", ids)
+		}
+
+		if _, err := session.ResumeFrom(3, func(SequencedEvent) {}); err != nil {
+			t.Errorf("Expected no gap when resuming right before the oldest event, got %v", err)
+		}
+	})
+
+	t.Run("rejects cursors ahead of the session", func(t *testing.T) {
+		session := NewSession("s1", nil, "")
+		dispatchNumbered(session, 1, 2)
+		if sub, err := session.ResumeFrom(10, func(SequencedEvent) {}); err == nil || sub != nil {
+			t.Errorf("Expected an error, got %v", err)
+		}
+	})
+
+	t.Run("works without a buffer", func(t *testing.T) {
+		session := NewSession("s1", nil, "")
+		session.registerReplayBuffer(-1)
+		dispatchNumbered(session, 1, 2)
+
+		if _, err := session.ResumeFrom(2, func(SequencedEvent) {}); err != nil {
+			t.Errorf("Expected no error when nothing was missed, got %v", err)
+		}
+		var expired *CursorExpiredError
+		if _, err := session.ResumeFrom(0, func(SequencedEvent) {}); !errors.As(err, &expired) {
+			t.Errorf("Expected a CursorExpiredError, got %v", err)
+		}
+	})
+
+	t.Run("delivers each event once across replay and live dispatch", func(t *testing.T) {
+		session := NewSession("s1", nil, "")
+		dispatchNumbered(session, 1, 200)
+
+		var mu sync.Mutex
+		seen := make(map[EventCursor]int)
+		var wg sync.WaitGroup
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			dispatchNumbered(session, 201, 400)
+		}()
+		sub, err := session.ResumeFrom(100, func(event SequencedEvent) {
+			mu.Lock()
+			seen[event.Cursor]++
+			mu.Unlock()
+		})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		wg.Wait()
+		sub.Unsubscribe()
+
+		mu.Lock()
+		defer mu.Unlock()
+		for cursor := EventCursor(101); cursor <= 400; cursor++ {
+			if seen[cursor] != 1 {
+				t.Fatalf("Event %d delivered %d times", cursor, seen[cursor])
+			}
+		}
+	})
+
+	t.Run("handlers can cause events while replaying", func(t *testing.T) {
+		session := NewSession("s1", nil, "")
+		dispatchNumbered(session, 1, 2)
+
+		var got []SequencedEvent
+		_, err := session.ResumeFrom(0, func(event SequencedEvent) {
+			got = append(got, event)
+			if event.Cursor == 1 {
+				session.emit(SDKTurnQueued, nil)
+			}
+		})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if len(got) != 3 || got[2].Event.Type != SDKTurnQueued {
+			t.Errorf("Unexpected events %+v", got)
+		}
+	})
+}
+
+func TestSubscription_ResumeFrom(t *testing.T) {
+	session := NewSession("s1", nil, "")
+	var got []SequencedEvent
+	sub := session.Subscribe(func(event SequencedEvent) { got = append(got, event) })
+	dispatchNumbered(session, 1, 3)
+
+	// The consumer only acknowledged e1, so e2 and e3 are delivered again.
+	if err := sub.ResumeFrom(1); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	dispatchNumbered(session, 4, 4)
+
+	if ids := fmt.Sprint(eventIDs(got)); ids != "[e1 e2 e3 e2 e3 e4]" {
+		t.Errorf("Unexpected events %s", ids)
+	}
+}
@@ -0,0 +1,110 @@
+package copilot
+
+import (
+	"path"
+	"strings"
+)
+
+// EventRole classifies who produced a session event.
+type EventRole string
+
+const (
+	EventRoleUser      EventRole = "user"
+	EventRoleAssistant EventRole = "assistant"
+	EventRoleTool      EventRole = "tool"
+	EventRoleSystem    EventRole = "system"
+	EventRoleSession   EventRole = "session"
+)
+
+// EventFilter selects the events delivered to a subscriber registered with
+// [Session.OnFiltered]. All non-empty criteria must match for an event to be
+// delivered; the zero value matches every event.
+type EventFilter struct {
+	// Types restricts delivery to the listed event types.
+	Types []SessionEventType
+	// Roles restricts delivery to events produced by the listed roles.
+	Roles []EventRole
+	// ToolNames restricts delivery to events about tools whose name matches one of
+	// the patterns, using [path.Match] syntax (e.g. "git_*"). Events that are not
+	// associated with a tool are dropped when this is set.
+	ToolNames []string
+	// ExcludeDeltas drops streaming delta events (assistant.message_delta and
+	// assistant.reasoning_delta).
+	ExcludeDeltas bool
+	// Match is an optional predicate evaluated after all other criteria.
+	Match func(event SessionEvent) bool
+}
+
+// RoleOf returns the role that produced the given event, derived from its type.
+func RoleOf(event SessionEvent) EventRole {
+	t := string(event.Type)
+	switch {
+	case strings.HasPrefix(t, "user."):
+		return EventRoleUser
+	case strings.HasPrefix(t, "assistant."):
+		return EventRoleAssistant
+	case strings.HasPrefix(t, "tool."):
+		return EventRoleTool
+	case strings.HasPrefix(t, "system.") || strings.HasPrefix(t, "hook."):
+		return EventRoleSystem
+	default:
+		return EventRoleSession
+	}
+}
+
+// matches reports whether the event satisfies the filter. toolName is the name of
+// the tool the event refers to, or empty if the event is not tool-related.
+func (f *EventFilter) matches(event SessionEvent, toolName string) bool {
+	if f.ExcludeDeltas && (event.Type == AssistantMessageDelta || event.Type == AssistantReasoningDelta) {
+		return false
+	}
+
+	if len(f.Types) > 0 {
+		found := false
+		for _, t := range f.Types {
+			if t == event.Type {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+
+	if len(f.Roles) > 0 {
+		role := RoleOf(event)
+		found := false
+		for _, r := range f.Roles {
+			if r == role {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+
+	if len(f.ToolNames) > 0 {
+		if toolName == "" {
+			return false
+		}
+		found := false
+		for _, pattern := range f.ToolNames {
+			if ok, err := path.Match(pattern, toolName); err == nil && ok {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+
+	if f.Match != nil && !f.Match(event) {
+		return false
+	}
+
+	return true
+}
@@ -0,0 +1,126 @@
+package copilot
+
+import (
+	"testing"
+)
+
+func strPtr(s string) *string {
+	return &s
+}
+
+func TestSession_OnFiltered(t *testing.T) {
+	t.Run("delivers only matching event types", func(t *testing.T) {
+		session := &Session{handlers: make([]sessionHandler, 0)}
+
+		var received []SessionEventType
+		session.OnFiltered(EventFilter{Types: []SessionEventType{AssistantMessage}}, func(event SessionEvent) {
+			received = append(received, event.Type)
+		})
+
+		session.dispatchEvent(SessionEvent{Type: UserMessage})
+		session.dispatchEvent(SessionEvent{Type: AssistantMessage})
+		session.dispatchEvent(SessionEvent{Type: SessionIdle})
+
+		if len(received) != 1 || received[0] != AssistantMessage {
+			t.Errorf("Expected only assistant.message, got %v", received)
+		}
+	})
+
+	t.Run("excludes deltas", func(t *testing.T) {
+		session := &Session{handlers: make([]sessionHandler, 0)}
+
+		var count int
+		session.OnFiltered(EventFilter{ExcludeDeltas: true}, func(event SessionEvent) { count++ })
+
+		session.dispatchEvent(SessionEvent{Type: AssistantMessageDelta})
+		session.dispatchEvent(SessionEvent{Type: AssistantReasoningDelta})
+		session.dispatchEvent(SessionEvent{Type: AssistantMessage})
+
+		if count != 1 {
+			t.Errorf("Expected 1 event, got %d", count)
+		}
+	})
+
+	t.Run("filters by role", func(t *testing.T) {
+		session := &Session{handlers: make([]sessionHandler, 0)}
+
+		var received []SessionEventType
+		session.OnFiltered(EventFilter{Roles: []EventRole{EventRoleAssistant}}, func(event SessionEvent) {
+			received = append(received, event.Type)
+		})
+
+		session.dispatchEvent(SessionEvent{Type: UserMessage})
+		session.dispatchEvent(SessionEvent{Type: AssistantTurnStart})
+		session.dispatchEvent(SessionEvent{Type: ToolExecutionStart})
+
+		if len(received) != 1 || received[0] != AssistantTurnStart {
+			t.Errorf("Expected only assistant.turn_start, got %v", received)
+		}
+	})
+
+	t.Run("matches tool name patterns across tool call events", func(t *testing.T) {
+		session := &Session{handlers: make([]sessionHandler, 0)}
+
+		var received []SessionEventType
+		session.OnFiltered(EventFilter{ToolNames: []string{"git_*"}}, func(event SessionEvent) {
+			received = append(received, event.Type)
+		})
+
+		session.dispatchEvent(SessionEvent{Type: ToolExecutionStart, Data: Data{ToolCallID: strPtr("1"), ToolName: strPtr("git_status")}})
+		session.dispatchEvent(SessionEvent{Type: ToolExecutionStart, Data: Data{ToolCallID: strPtr("2"), ToolName: strPtr("read_file")}})
+		// Completion events only carry the tool call ID
+		session.dispatchEvent(SessionEvent{Type: ToolExecutionComplete, Data: Data{ToolCallID: strPtr("2")}})
+		session.dispatchEvent(SessionEvent{Type: ToolExecutionComplete, Data: Data{ToolCallID: strPtr("1")}})
+		session.dispatchEvent(SessionEvent{Type: AssistantMessage})
+
+		if len(received) != 2 || received[0] != ToolExecutionStart || received[1] != ToolExecutionComplete {
+			t.Errorf("Expected start and complete for git_status, got %v", received)
+		}
+		if len(session.toolCallNames) != 0 {
+			t.Errorf("Expected tool call names to be released after completion, got %v", session.toolCallNames)
+		}
+	})
+
+	t.Run("releases tool call names when the turn ends", func(t *testing.T) {
+		for _, end := range []SessionEventType{SessionIdle, Abort} {
+			session := &Session{handlers: make([]sessionHandler, 0)}
+			session.dispatchEvent(SessionEvent{Type: AssistantMessage, Data: Data{ToolRequests: []ToolRequest{{ToolCallID: "1", Name: "denied"}}}})
+			session.dispatchEvent(SessionEvent{Type: ToolExecutionStart, Data: Data{ToolCallID: strPtr("2"), ToolName: strPtr("aborted")}})
+			session.dispatchEvent(SessionEvent{Type: end})
+			if len(session.toolCallNames) != 0 {
+				t.Errorf("Expected tool call names to be released on %s, got %v", end, session.toolCallNames)
+			}
+		}
+	})
+
+	t.Run("applies custom predicate", func(t *testing.T) {
+		session := &Session{handlers: make([]sessionHandler, 0)}
+
+		var count int
+		session.OnFiltered(EventFilter{Match: func(event SessionEvent) bool {
+			return event.Data.Content != nil && *event.Data.Content == "yes"
+		}}, func(event SessionEvent) { count++ })
+
+		session.dispatchEvent(SessionEvent{Type: AssistantMessage, Data: Data{Content: strPtr("no")}})
+		session.dispatchEvent(SessionEvent{Type: AssistantMessage, Data: Data{Content: strPtr("yes")}})
+
+		if count != 1 {
+			t.Errorf("Expected 1 event, got %d", count)
+		}
+	})
+
+	t.Run("unfiltered handlers still receive everything", func(t *testing.T) {
+		session := &Session{handlers: make([]sessionHandler, 0)}
+
+		var filtered, all int
+		session.OnFiltered(EventFilter{Types: []SessionEventType{SessionIdle}}, func(event SessionEvent) { filtered++ })
+		session.On(func(event SessionEvent) { all++ })
+
+		session.dispatchEvent(SessionEvent{Type: UserMessage})
+		session.dispatchEvent(SessionEvent{Type: SessionIdle})
+
+		if filtered != 1 || all != 2 {
+			t.Errorf("Expected filtered=1 all=2, got filtered=%d all=%d", filtered, all)
+		}
+	})
+}
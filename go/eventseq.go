@@ -0,0 +1,109 @@
+package copilot
+
+import (
+	"context"
+	"encoding/json"
+	"iter"
+	"sync"
+)
+
+// EventsSeq returns an iterator over the live events of this session.
+//
+// Iteration subscribes to the session when it starts and unsubscribes when the
+// loop exits, either because the loop body breaks or returns, or because ctx is
+// done. Events are buffered while the loop body runs, so a slow consumer does not
+// block event dispatch or other handlers. With [ClientOptions.FlowControl], the
+// buffered events count toward the client's watermarks. Events dispatched
+// before iteration starts are not seen.
+//
+// Example:
+//
+//	// Log events until the session reports an error or ctx is canceled.
+//	for event := range session.EventsSeq(ctx) {
+//	    log.Println(event.Type)
+//	    if event.Type == copilot.SessionError {
+//	        break
+//	    }
+//	}
+func (s *Session) EventsSeq(ctx context.Context) iter.Seq[SessionEvent] {
+	return s.eventsSeq(ctx, nil)
+}
+
+// EventsSeqFiltered is like [Session.EventsSeq] but only yields events that
+// match filter. See [EventFilter].
+func (s *Session) EventsSeqFiltered(ctx context.Context, filter EventFilter) iter.Seq[SessionEvent] {
+	return s.eventsSeq(ctx, &filter)
+}
+
+func (s *Session) eventsSeq(ctx context.Context, filter *EventFilter) iter.Seq[SessionEvent] {
+	return func(yield func(SessionEvent) bool) {
+		flow := s.flowWindow()
+		var mu sync.Mutex
+		var pending []queuedEvent
+		// closed is set once the loop exits. A dispatch that took its snapshot
+		// of the handlers before unsubscribe may still call the handler, whose
+		// event would never be released.
+		var closed bool
+		wake := make(chan struct{}, 1)
+
+		unsubscribe := s.addHandler(sessionHandler{sized: func(event SessionEvent, size int) {
+			if flow != nil && size == 0 {
+				data, _ := json.Marshal(event)
+				size = len(data)
+			}
+			mu.Lock()
+			if closed {
+				mu.Unlock()
+				return
+			}
+			flow.add(int64(size))
+			pending = append(pending, queuedEvent{event: event, size: size})
+			mu.Unlock()
+			select {
+			case wake <- struct{}{}:
+			default:
+			}
+		}, filter: filter})
+		var batch []queuedEvent
+		defer func() {
+			unsubscribe()
+			// Release the events that will not be consumed.
+			mu.Lock()
+			closed = true
+			batch = append(batch, pending...)
+			pending = nil
+			mu.Unlock()
+			for _, queued := range batch {
+				flow.release(int64(queued.size))
+			}
+		}()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-wake:
+			}
+
+			mu.Lock()
+			batch = pending
+			pending = nil
+			mu.Unlock()
+
+			for len(batch) > 0 {
+				queued := batch[0]
+				batch = batch[1:]
+				flow.release(int64(queued.size))
+				if ctx.Err() != nil || !yield(queued.event) {
+					return
+				}
+			}
+		}
+	}
+}
+
+// queuedEvent is an event buffered for a consumer, with its size as received.
+type queuedEvent struct {
+	event SessionEvent
+	size  int
+}
@@ -0,0 +1,142 @@
+package copilot
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestEventsSeq(t *testing.T) {
+	t.Run("yields events until the loop breaks and then unsubscribes", func(t *testing.T) {
+		session := NewSession("s1", nil, "")
+		started := make(chan struct{})
+		done := make(chan []SessionEventType)
+
+		go func() {
+			var seen []SessionEventType
+			for event := range session.EventsSeq(context.Background()) {
+				seen = append(seen, event.Type)
+				if event.Type == SessionIdle {
+					break
+				}
+			}
+			done <- seen
+		}()
+		go func() {
+			for {
+				session.handlerMutex.RLock()
+				n := len(session.handlers)
+				session.handlerMutex.RUnlock()
+				if n > 0 {
+					close(started)
+					return
+				}
+				time.Sleep(time.Millisecond)
+			}
+		}()
+		<-started
+
+		session.dispatchEvent(SessionEvent{Type: AssistantMessage})
+		session.dispatchEvent(SessionEvent{Type: SessionIdle})
+		session.dispatchEvent(SessionEvent{Type: AssistantMessage})
+
+		select {
+		case seen := <-done:
+			if len(seen) != 2 || seen[0] != AssistantMessage || seen[1] != SessionIdle {
+				t.Errorf("Unexpected events %v", seen)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("Timed out waiting for the loop to exit")
+		}
+
+		session.handlerMutex.RLock()
+		defer session.handlerMutex.RUnlock()
+		if len(session.handlers) != 0 {
+			t.Errorf("Expected the iterator to unsubscribe, got %d handlers", len(session.handlers))
+		}
+	})
+
+	t.Run("stops when the context is canceled", func(t *testing.T) {
+		session := NewSession("s1", nil, "")
+		ctx, cancel := context.WithCancel(context.Background())
+		done := make(chan struct{})
+		go func() {
+			for range session.EventsSeq(ctx) {
+			}
+			close(done)
+		}()
+		cancel()
+		select {
+		case <-done:
+		case <-time.After(5 * time.Second):
+			t.Fatal("Timed out waiting for the loop to exit")
+		}
+	})
+
+	t.Run("applies the filter", func(t *testing.T) {
+		session := NewSession("s1", nil, "")
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		next := make(chan SessionEventType, 1)
+		go func() {
+			for event := range session.EventsSeqFiltered(ctx, EventFilter{Types: []SessionEventType{SessionIdle}}) {
+				next <- event.Type
+				return
+			}
+		}()
+		for {
+			session.dispatchEvent(SessionEvent{Type: AssistantMessage})
+			session.dispatchEvent(SessionEvent{Type: SessionIdle})
+			select {
+			case eventType := <-next:
+				if eventType != SessionIdle {
+					t.Errorf("Expected only session.idle, got %s", eventType)
+				}
+				return
+			case <-time.After(time.Millisecond):
+			}
+		}
+	})
+
+	t.Run("releases every buffered event when the loop breaks during dispatch", func(t *testing.T) {
+		rpc := NewJSONRPCClient(nil, nil)
+		rpc.flow = newFlowWindow(&FlowControl{HighWatermark: 1 << 30})
+		session := NewSession("s1", rpc, "")
+
+		for i := 0; i < 10; i++ {
+			first := make(chan struct{})
+			exited := make(chan struct{})
+			go func() {
+				for range session.EventsSeq(context.Background()) {
+					close(first)
+					break
+				}
+				close(exited)
+			}()
+
+			var wg sync.WaitGroup
+			for j := 0; j < 4; j++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					for {
+						session.dispatchEvent(SessionEvent{Type: AssistantMessage})
+						select {
+						case <-exited:
+							return
+						default:
+						}
+					}
+				}()
+			}
+			<-first
+			wg.Wait()
+
+			if stats := rpc.flow.stats(); stats.QueuedBytes != 0 {
+				t.Fatalf("Expected no queued bytes once the loop exits, got %d", stats.QueuedBytes)
+			}
+		}
+	})
+}
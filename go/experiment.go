@@ -0,0 +1,135 @@
+package copilot
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ExperimentProvider assigns a turn to an experiment variant. It is consulted by
+// [Session.Send] before every message, so prompt, model, or tool changes can be
+// measured by comparing turns across variants.
+//
+// Return a nil assignment to send the turn unchanged. Returning an error fails the
+// send.
+//
+// Example:
+//
+//	provider := func(req copilot.ExperimentRequest) (*copilot.ExperimentAssignment, error) {
+//	    if hash(req.SessionID)%2 == 0 {
+//	        return &copilot.ExperimentAssignment{Experiment: "terse", Variant: "control"}, nil
+//	    }
+//	    return &copilot.ExperimentAssignment{
+//	        Experiment:   "terse",
+//	        Variant:      "treatment",
+//	        Instructions: "Answer in at most three sentences.",
+//	    }, nil
+//	}
+type ExperimentProvider func(request ExperimentRequest) (*ExperimentAssignment, error)
+
+// ExperimentRequest describes the turn an [ExperimentProvider] is assigning.
+type ExperimentRequest struct {
+	// SessionID is the session the turn belongs to.
+	SessionID string
+	// Turn is the 1-based number of the turn within this session object.
+	Turn int
+	// Prompt is the prompt being sent.
+	Prompt string
+}
+
+// ExperimentAssignment is the variant an [ExperimentProvider] assigned to a turn,
+// and how the turn should differ from the default.
+type ExperimentAssignment struct {
+	// Experiment names the experiment.
+	Experiment string
+	// Variant names the assigned variant, for example "control" or "treatment".
+	Variant string
+	// Model overrides the model for this turn. It is sent with the message and
+	// requires server support for per-message model selection.
+	Model string
+	// Instructions are appended to the prompt for this turn.
+	Instructions string
+	// Tools, when non-nil, restricts the session's tools to the named ones for
+	// this turn. Calls to other tools fail with a result telling the model the
+	// tool is unavailable.
+	Tools []string
+}
+
+// experimentState tracks the experiment provider and the active turn's assignment.
+type experimentState struct {
+	provider     ExperimentProvider
+	turns        int
+	allowedTools map[string]bool
+}
+
+// registerExperimentProvider sets the experiment provider for this session.
+func (s *Session) registerExperimentProvider(provider ExperimentProvider) {
+	s.experimentMux.Lock()
+	defer s.experimentMux.Unlock()
+	s.experiment.provider = provider
+}
+
+// assignExperiment consults the experiment provider for the next turn and
+// applies the assignment to params. The provider sees the caller's prompt, before
+// any SDK-added instructions. It returns nil if there is no provider or
+// the provider left the turn unchanged.
+func (s *Session) assignExperiment(prompt string, params map[string]interface{}) (*ExperimentAssignment, int, error) {
+	s.experimentMux.Lock()
+	defer s.experimentMux.Unlock()
+
+	if s.experiment.provider == nil {
+		return nil, 0, nil
+	}
+	s.experiment.turns++
+	turn := s.experiment.turns
+
+	assignment, err := s.experiment.provider(ExperimentRequest{SessionID: s.SessionID, Turn: turn, Prompt: prompt})
+	if err != nil {
+		return nil, turn, fmt.Errorf("experiment assignment failed: %w", err)
+	}
+
+	s.experiment.allowedTools = nil
+	if assignment == nil {
+		return nil, turn, nil
+	}
+	if assignment.Model != "" {
+		params["model"] = assignment.Model
+	}
+	if assignment.Instructions != "" {
+		current, _ := params["prompt"].(string)
+		params["prompt"] = appendPromptInstructions(current, assignment.Instructions)
+	}
+	if assignment.Tools != nil {
+		s.experiment.allowedTools = make(map[string]bool, len(assignment.Tools))
+		for _, name := range assignment.Tools {
+			s.experiment.allowedTools[name] = true
+		}
+	}
+	return assignment, turn, nil
+}
+
+// experimentAllowsTool reports whether the active turn's assignment permits the tool.
+func (s *Session) experimentAllowsTool(toolName string) bool {
+	s.experimentMux.Lock()
+	defer s.experimentMux.Unlock()
+	return s.experiment.allowedTools == nil || s.experiment.allowedTools[toolName]
+}
+
+// appendPromptInstructions appends instructions to a prompt, separated by a blank line.
+func appendPromptInstructions(prompt, instructions string) string {
+	instructions = strings.TrimSpace(instructions)
+	if prompt == "" {
+		return instructions
+	}
+	return prompt + "\n\n" + instructions
+}
+
+// buildExperimentExcludedToolResult creates a failure ToolResult for a tool the
+// turn's experiment variant does not include.
+func buildExperimentExcludedToolResult(toolName string) ToolResult {
+	return ToolResult{
+		TextResultForLLM: fmt.Sprintf("Tool '%s' is not available in this turn.", toolName),
+		ResultType:       "failure",
+		Error:            fmt.Sprintf("tool '%s' excluded by experiment", toolName),
+		ToolTelemetry:    map[string]interface{}{},
+	}
+}
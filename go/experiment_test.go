@@ -0,0 +1,92 @@
+package copilot
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestExperimentProvider(t *testing.T) {
+	t.Run("applies the assignment and stamps it on the turn", func(t *testing.T) {
+		var mu sync.Mutex
+		var sent map[string]interface{}
+		session, _ := newFakeSession(t, func(server *fakeServer, method string, params map[string]interface{}) (map[string]interface{}, *JSONRPCError) {
+			mu.Lock()
+			sent = params
+			mu.Unlock()
+			go server.emitEvent(SessionIdle, "e1", map[string]interface{}{})
+			return map[string]interface{}{"messageId": "msg-1"}, nil
+		})
+
+		var requests []ExperimentRequest
+		session.registerExperimentProvider(func(req ExperimentRequest) (*ExperimentAssignment, error) {
+			requests = append(requests, req)
+			return &ExperimentAssignment{
+				Experiment:   "terse",
+				Variant:      "treatment",
+				Model:        "gpt-5-mini",
+				Instructions: "Be terse.",
+				Tools:        []string{"allowed"},
+			}, nil
+		})
+
+		var assigned []SessionEvent
+		session.On(func(event SessionEvent) {
+			if event.Type == SDKExperimentAssigned {
+				assigned = append(assigned, event)
+			}
+		})
+
+		turn, err := session.SendAndCollect(MessageOptions{Prompt: "Explain"}, 5*time.Second)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		mu.Lock()
+		defer mu.Unlock()
+		if sent["prompt"] != "Explain\n\nBe terse." || sent["model"] != "gpt-5-mini" {
+			t.Errorf("Expected assignment to be applied to the request, got %v", sent)
+		}
+		if len(requests) != 1 || requests[0].Turn != 1 || requests[0].Prompt != "Explain" {
+			t.Errorf("Unexpected provider requests %+v", requests)
+		}
+		if turn.Experiment == nil || turn.Experiment.Experiment != "terse" || turn.Experiment.Variant != "treatment" {
+			t.Errorf("Expected turn to record the assignment, got %+v", turn.Experiment)
+		}
+		if len(assigned) != 1 || sdkEventString(assigned[0], "messageId") != "msg-1" {
+			t.Errorf("Expected one assignment event, got %+v", assigned)
+		}
+
+		if !session.experimentAllowsTool("allowed") || session.experimentAllowsTool("other") {
+			t.Error("Expected the turn's tools to be restricted to the assignment")
+		}
+	})
+
+	t.Run("excluded tools fail without running", func(t *testing.T) {
+		session := NewSession("s1", nil, "")
+		ran := false
+		session.registerTools([]Tool{{Name: "other", Handler: func(ToolInvocation) (ToolResult, error) {
+			ran = true
+			return ToolResult{}, nil
+		}}})
+		session.experiment.allowedTools = map[string]bool{"allowed": true}
+		client := &Client{sessions: map[string]*Session{"s1": session}}
+
+		response, _ := client.handleToolCallRequest(map[string]interface{}{
+			"sessionId": "s1", "toolCallId": "1", "toolName": "other", "arguments": map[string]interface{}{},
+		})
+		if result := response["result"].(ToolResult); result.ResultType != "failure" || ran {
+			t.Errorf("Expected excluded tool to fail without running, got %+v", result)
+		}
+	})
+
+	t.Run("nil assignments leave the turn unchanged", func(t *testing.T) {
+		session := &Session{SessionID: "s1"}
+		session.registerExperimentProvider(func(ExperimentRequest) (*ExperimentAssignment, error) { return nil, nil })
+		params := map[string]interface{}{"prompt": "hi"}
+		assignment, turn, err := session.assignExperiment("hi", params)
+		if err != nil || assignment != nil || turn != 1 || params["prompt"] != "hi" {
+			t.Errorf("Unexpected result %+v, %d, %v, %v", assignment, turn, err, params)
+		}
+	})
+}
@@ -0,0 +1,162 @@
+package copilot
+
+import "sync"
+
+// FileOperationKind is what a [FileOperation] did to a file.
+type FileOperationKind string
+
+const (
+	// FileRead is a file or directory read, such as viewing a file.
+	FileRead FileOperationKind = "read"
+	// FileCreate is a file created or overwritten with new content.
+	FileCreate FileOperationKind = "create"
+	// FileEdit is an in-place change to part of a file.
+	FileEdit FileOperationKind = "edit"
+)
+
+// FileOperation is a filesystem access performed by the agent's built-in tools
+// on the server, normalized from its tool execution events so that clients can
+// display and audit it although no local tool was invoked.
+type FileOperation struct {
+	ToolCallID string
+	// ToolName is the built-in tool that performed the operation, such as
+	// "view" or "edit".
+	ToolName string
+	Kind     FileOperationKind
+	Path     string
+	// BytesRead is the size of the content the tool returned for a read.
+	BytesRead int
+	// BytesWritten is the size of the content written: the whole file for a
+	// create, or the replacement text for an edit.
+	BytesWritten int
+	// BytesRemoved is the size of the text an edit replaced.
+	BytesRemoved int
+	// Success reports whether the tool completed successfully.
+	Success bool
+	// Error is the failure message of an unsuccessful operation, if any.
+	Error string
+}
+
+// fileTool describes the arguments of a built-in tool that accesses files.
+type fileTool struct {
+	kind FileOperationKind
+	// written and removed are the arguments holding the written and replaced
+	// text.
+	written, removed string
+}
+
+// fileTools are the server's built-in file tools, by name. All of them take
+// the file in a "path" argument.
+var fileTools = map[string]fileTool{
+	"view":        {kind: FileRead},
+	"create":      {kind: FileCreate, written: "file_text"},
+	"edit":        {kind: FileEdit, written: "new_str", removed: "old_str"},
+	"str_replace": {kind: FileEdit, written: "new_str", removed: "old_str"},
+	"insert":      {kind: FileEdit, written: "new_str"},
+}
+
+// fileOperationTracker correlates the start and completion events of built-in
+// file tool calls.
+type fileOperationTracker struct {
+	// isLocal reports whether a tool name is handled by the client, in which
+	// case its calls are not server-side file operations.
+	isLocal func(name string) bool
+
+	mu      sync.Mutex
+	pending map[string]FileOperation
+}
+
+// track returns the file operation completed by event, if any.
+func (t *fileOperationTracker) track(event SessionEvent) (FileOperation, bool) {
+	data := event.Data
+	if data.ToolCallID == nil {
+		return FileOperation{}, false
+	}
+	toolCallID := *data.ToolCallID
+
+	switch event.Type {
+	case ToolExecutionStart:
+		if data.ToolName == nil || data.MCPServerName != nil {
+			return FileOperation{}, false
+		}
+		tool, ok := fileTools[*data.ToolName]
+		if !ok || (t.isLocal != nil && t.isLocal(*data.ToolName)) {
+			return FileOperation{}, false
+		}
+		args, _ := data.Arguments.(map[string]interface{})
+		path, _ := args["path"].(string)
+		if path == "" {
+			return FileOperation{}, false
+		}
+		op := FileOperation{ToolCallID: toolCallID, ToolName: *data.ToolName, Kind: tool.kind, Path: path}
+		if text, ok := args[tool.written].(string); ok {
+			op.BytesWritten = len(text)
+		}
+		if text, ok := args[tool.removed].(string); ok {
+			op.BytesRemoved = len(text)
+		}
+		t.mu.Lock()
+		if t.pending == nil {
+			t.pending = make(map[string]FileOperation)
+		}
+		t.pending[toolCallID] = op
+		t.mu.Unlock()
+
+	case ToolExecutionComplete:
+		t.mu.Lock()
+		op, ok := t.pending[toolCallID]
+		delete(t.pending, toolCallID)
+		t.mu.Unlock()
+		if !ok {
+			return FileOperation{}, false
+		}
+		op.Success = data.Success != nil && *data.Success
+		if op.Kind == FileRead && data.Result != nil {
+			op.BytesRead = len(data.Result.Content)
+		}
+		if data.Error != nil {
+			if data.Error.String != nil {
+				op.Error = *data.Error.String
+			} else if data.Error.ErrorClass != nil {
+				op.Error = data.Error.ErrorClass.Message
+			}
+		}
+		return op, true
+	}
+	return FileOperation{}, false
+}
+
+// OnFileOperation calls handler with each file operation the agent's built-in
+// tools perform on the server, once the operation completes. Calls of tools
+// this session handles locally are not reported. It returns a function that
+// unsubscribes the handler.
+//
+// Example:
+//
+//	session.OnFileOperation(func(op copilot.FileOperation) {
+//	    audit.Printf("%s %s (+%d -%d bytes)", op.Kind, op.Path, op.BytesWritten, op.BytesRemoved)
+//	})
+func (s *Session) OnFileOperation(handler func(FileOperation)) func() {
+	tracker := &fileOperationTracker{isLocal: func(name string) bool {
+		_, ok := s.getToolHandler(name)
+		return ok
+	}}
+	return s.On(func(event SessionEvent) {
+		if op, ok := tracker.track(event); ok {
+			handler(op)
+		}
+	})
+}
+
+// FileOperations returns the completed file operations recorded in events, such
+// as a session's history from [Session.GetMessages], in completion order.
+func FileOperations(events []SessionEvent) []FileOperation {
+	var tracker fileOperationTracker
+	var ops []FileOperation
+	for _, event := range events {
+		if op, ok := tracker.track(event); ok {
+			ops = append(ops, op)
+		}
+	}
+	return ops
+}
@@ -0,0 +1,88 @@
+package copilot
+
+import (
+	"testing"
+	"time"
+)
+
+func toolStartEvent(toolCallID, toolName string, args map[string]interface{}) SessionEvent {
+	return SessionEvent{
+		Type:      ToolExecutionStart,
+		Timestamp: time.Now(),
+		Data:      Data{ToolCallID: strPtr(toolCallID), ToolName: strPtr(toolName), Arguments: args},
+	}
+}
+
+func toolCompleteEvent(toolCallID string, success bool, content string) SessionEvent {
+	return SessionEvent{
+		Type:      ToolExecutionComplete,
+		Timestamp: time.Now(),
+		Data:      Data{ToolCallID: strPtr(toolCallID), Success: &success, Result: &Result{Content: content}},
+	}
+}
+
+func TestFileOperations(t *testing.T) {
+	failure := toolCompleteEvent("4", false, "")
+	failure.Data.Error = &ErrorUnion{ErrorClass: &ErrorClass{Message: "permission denied"}}
+
+	events := []SessionEvent{
+		toolStartEvent("1", "view", map[string]interface{}{"path": "main.go"}),
+		toolStartEvent("2", "edit", map[string]interface{}{"path": "main.go", "old_str": "foo", "new_str": "foobar"}),
+		toolStartEvent("3", "bash", map[string]interface{}{"command": "ls"}),
+		toolCompleteEvent("2", true, "edited"),
+		toolCompleteEvent("1", true, "package main\n"),
+		toolCompleteEvent("3", true, "main.go"),
+		toolStartEvent("4", "create", map[string]interface{}{"path": "/etc/motd", "file_text": "hello"}),
+		failure,
+	}
+
+	ops := FileOperations(events)
+	expected := []FileOperation{
+		{ToolCallID: "2", ToolName: "edit", Kind: FileEdit, Path: "main.go", BytesWritten: 6, BytesRemoved: 3, Success: true},
+		{ToolCallID: "1", ToolName: "view", Kind: FileRead, Path: "main.go", BytesRead: 13, Success: true},
+		{ToolCallID: "4", ToolName: "create", Kind: FileCreate, Path: "/etc/motd", BytesWritten: 5, Error: "permission denied"},
+	}
+	if len(ops) != len(expected) {
+		t.Fatalf("Expected %d operations, got %+v", len(expected), ops)
+	}
+	for i := range expected {
+		if ops[i] != expected[i] {
+			t.Errorf("Operation %d: expected %+v, got %+v", i, expected[i], ops[i])
+		}
+	}
+}
+
+func TestSession_OnFileOperation(t *testing.T) {
+	session := NewSession("s1", nil, "")
+	session.registerTools([]Tool{namedTool("create", "local create")})
+
+	var ops []FileOperation
+	unsubscribe := session.OnFileOperation(func(op FileOperation) { ops = append(ops, op) })
+
+	session.dispatchEvent(toolStartEvent("1", "create", map[string]interface{}{"path": "a.txt", "file_text": "a"}))
+	session.dispatchEvent(toolCompleteEvent("1", true, ""))
+	if len(ops) != 0 {
+		t.Errorf("Expected calls of local tools to be skipped, got %+v", ops)
+	}
+
+	mcp := toolStartEvent("2", "view", map[string]interface{}{"path": "b.txt"})
+	mcp.Data.MCPServerName = strPtr("files")
+	session.dispatchEvent(mcp)
+	session.dispatchEvent(toolCompleteEvent("2", true, "b"))
+	if len(ops) != 0 {
+		t.Errorf("Expected MCP tool calls to be skipped, got %+v", ops)
+	}
+
+	session.dispatchEvent(toolStartEvent("3", "view", map[string]interface{}{"path": "c.txt"}))
+	session.dispatchEvent(toolCompleteEvent("3", true, "contents"))
+	if len(ops) != 1 || ops[0].Path != "c.txt" || ops[0].BytesRead != 8 {
+		t.Errorf("Expected the view of c.txt, got %+v", ops)
+	}
+
+	unsubscribe()
+	session.dispatchEvent(toolStartEvent("4", "view", map[string]interface{}{"path": "d.txt"}))
+	session.dispatchEvent(toolCompleteEvent("4", true, ""))
+	if len(ops) != 1 {
+		t.Errorf("Expected no operations after unsubscribing, got %+v", ops)
+	}
+}
@@ -0,0 +1,240 @@
+// Package fixtures builds protocol messages, as the Copilot CLI server sends
+// them, for tests of code built on the SDK. Messages are built from the SDK's
+// own protocol types, such as [copilot.SessionEvent] and
+// [copilot.JSONRPCRequest], so fixtures follow the protocol when its fields
+// change instead of drifting from it.
+//
+// A test can write fixtures to a fake server's connection, for example one
+// the client reaches through [copilot.ClientOptions.CLIUrl]:
+//
+//	conn.Write(fixtures.Frames(
+//	    fixtures.AssistantDelta("s1", "m1", "Hello"),
+//	    fixtures.AssistantDelta("s1", "m1", ", world"),
+//	    fixtures.AssistantMessage("s1", "m1", "Hello, world"),
+//	    fixtures.SessionIdle("s1"),
+//	))
+//
+// Event IDs default to "evt-1", "evt-2", and so on, in build order, and
+// timestamps to [Epoch]; pass [WithEventID] or [WithTimestamp] to set them.
+package fixtures
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	copilot "github.com/github/copilot-sdk/go"
+)
+
+// Epoch is the default timestamp of fixture events.
+var Epoch = time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// Message is a protocol message built by this package.
+type Message struct {
+	value interface{}
+}
+
+// JSON returns the message body.
+func (m Message) JSON() []byte {
+	data, err := json.Marshal(m.value)
+	if err != nil {
+		// Messages are built from protocol types, which always encode.
+		panic(fmt.Sprintf("fixtures: failed to encode message: %v", err))
+	}
+	return data
+}
+
+// Frame returns the message with its Content-Length header, as written to the
+// transport.
+func (m Message) Frame() []byte {
+	body := m.JSON()
+	return append([]byte(fmt.Sprintf("Content-Length: %d\r\n\r\n", len(body))), body...)
+}
+
+// String returns the message body.
+func (m Message) String() string {
+	return string(m.JSON())
+}
+
+// Frames returns the frames of messages, concatenated in order.
+func Frames(messages ...Message) []byte {
+	var frames []byte
+	for _, m := range messages {
+		frames = append(frames, m.Frame()...)
+	}
+	return frames
+}
+
+// EventOption customizes an event built by this package.
+type EventOption func(*copilot.SessionEvent)
+
+// WithEventID sets the ID of an event.
+func WithEventID(id string) EventOption {
+	return func(e *copilot.SessionEvent) { e.ID = id }
+}
+
+// WithParentID sets the ID of an event's parent.
+func WithParentID(id string) EventOption {
+	return func(e *copilot.SessionEvent) { e.ParentID = &id }
+}
+
+// WithTimestamp sets the timestamp of an event.
+func WithTimestamp(t time.Time) EventOption {
+	return func(e *copilot.SessionEvent) { e.Timestamp = t }
+}
+
+// Ephemeral marks an event as ephemeral, as the server does for deltas.
+func Ephemeral() EventOption {
+	return func(e *copilot.SessionEvent) {
+		ephemeral := true
+		e.Ephemeral = &ephemeral
+	}
+}
+
+var nextEventID atomic.Int64
+
+// Event returns a session.event notification carrying an event of type
+// eventType with data, for events that have no builder of their own.
+func Event(sessionID string, eventType copilot.SessionEventType, data copilot.Data, opts ...EventOption) Message {
+	event := copilot.SessionEvent{
+		Type:      eventType,
+		Data:      data,
+		ID:        "evt-" + strconv.FormatInt(nextEventID.Add(1), 10),
+		Timestamp: Epoch,
+	}
+	for _, opt := range opts {
+		opt(&event)
+	}
+	return Message{copilot.JSONRPCNotification{
+		JSONRPC: "2.0",
+		Method:  "session.event",
+		Params: map[string]interface{}{
+			"sessionId": sessionID,
+			"event":     event,
+		},
+	}}
+}
+
+// AssistantDelta returns an ephemeral assistant.message_delta event with a
+// chunk of the assistant message messageID.
+func AssistantDelta(sessionID, messageID, delta string, opts ...EventOption) Message {
+	opts = append([]EventOption{Ephemeral()}, opts...)
+	return Event(sessionID, copilot.AssistantMessageDelta, copilot.Data{
+		MessageID:    &messageID,
+		DeltaContent: &delta,
+	}, opts...)
+}
+
+// AssistantMessage returns an assistant.message event with the full content
+// of the assistant message messageID.
+func AssistantMessage(sessionID, messageID, content string, opts ...EventOption) Message {
+	return Event(sessionID, copilot.AssistantMessage, copilot.Data{
+		MessageID: &messageID,
+		Content:   &content,
+	}, opts...)
+}
+
+// ToolExecutionStart returns a tool.execution_start event for a tool call.
+func ToolExecutionStart(sessionID, toolCallID, toolName string, arguments interface{}, opts ...EventOption) Message {
+	return Event(sessionID, copilot.ToolExecutionStart, copilot.Data{
+		ToolCallID: &toolCallID,
+		ToolName:   &toolName,
+		Arguments:  arguments,
+	}, opts...)
+}
+
+// ToolExecutionComplete returns a tool.execution_complete event for a tool
+// call.
+func ToolExecutionComplete(sessionID, toolCallID string, success bool, opts ...EventOption) Message {
+	return Event(sessionID, copilot.ToolExecutionComplete, copilot.Data{
+		ToolCallID: &toolCallID,
+		Success:    &success,
+	}, opts...)
+}
+
+// SessionError returns a session.error event.
+func SessionError(sessionID, errorType, message string, opts ...EventOption) Message {
+	return Event(sessionID, copilot.SessionError, copilot.Data{
+		ErrorType: &errorType,
+		Message:   &message,
+	}, opts...)
+}
+
+// SessionIdle returns a session.idle event, which ends a turn.
+func SessionIdle(sessionID string, opts ...EventOption) Message {
+	return Event(sessionID, copilot.SessionIdle, copilot.Data{}, opts...)
+}
+
+// ToolCallRequest returns a tool.call request, with request ID id, asking the
+// client to run a tool.
+func ToolCallRequest(id, sessionID, toolCallID, toolName string, arguments interface{}) Message {
+	return request(id, "tool.call", map[string]interface{}{
+		"sessionId":  sessionID,
+		"toolCallId": toolCallID,
+		"toolName":   toolName,
+		"arguments":  arguments,
+	})
+}
+
+// PermissionRequest returns a permission.request request, with request ID id,
+// asking the client to approve an operation of kind, such as "shell" or
+// "write". Fields of the request other than kind are set from details.
+func PermissionRequest(id, sessionID, kind string, details map[string]interface{}) Message {
+	permission := map[string]interface{}{"kind": kind}
+	for key, value := range details {
+		permission[key] = value
+	}
+	return request(id, "permission.request", map[string]interface{}{
+		"sessionId":         sessionID,
+		"permissionRequest": permission,
+	})
+}
+
+// UserInputRequest returns a userInput.request request, with request ID id,
+// asking the user question. choices may be empty.
+func UserInputRequest(id, sessionID, question string, choices ...string) Message {
+	params := map[string]interface{}{
+		"sessionId": sessionID,
+		"question":  question,
+	}
+	if len(choices) > 0 {
+		params["choices"] = choices
+	}
+	return request(id, "userInput.request", params)
+}
+
+// Response returns a successful response to the client request id. id is the
+// raw JSON ID of the request, as in the request the client sent.
+func Response(id json.RawMessage, result map[string]interface{}) Message {
+	if result == nil {
+		result = map[string]interface{}{}
+	}
+	return Message{copilot.JSONRPCResponse{JSONRPC: "2.0", ID: id, Result: result}}
+}
+
+// ErrorResponse returns an error response to the client request id.
+func ErrorResponse(id json.RawMessage, code int, message string) Message {
+	return Message{copilot.JSONRPCResponse{
+		JSONRPC: "2.0",
+		ID:      id,
+		Error:   &copilot.JSONRPCError{Code: code, Message: message},
+	}}
+}
+
+// Notification returns a notification of method with params, for
+// notifications that have no builder of their own.
+func Notification(method string, params map[string]interface{}) Message {
+	return Message{copilot.JSONRPCNotification{JSONRPC: "2.0", Method: method, Params: params}}
+}
+
+// request returns a request of method with params and the string ID id.
+func request(id, method string, params map[string]interface{}) Message {
+	return Message{copilot.JSONRPCRequest{
+		JSONRPC: "2.0",
+		ID:      json.RawMessage(strconv.Quote(id)),
+		Method:  method,
+		Params:  params,
+	}}
+}
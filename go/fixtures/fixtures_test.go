@@ -0,0 +1,176 @@
+package fixtures
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	copilot "github.com/github/copilot-sdk/go"
+)
+
+// readFrame reads a Content-Length framed message body from r.
+func readFrame(r *bufio.Reader) ([]byte, error) {
+	header, err := textproto.NewReader(r).ReadMIMEHeader()
+	if err != nil {
+		return nil, err
+	}
+	length, err := strconv.Atoi(header.Get("Content-Length"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid Content-Length: %w", err)
+	}
+	body := make([]byte, length)
+	_, err = io.ReadFull(r, body)
+	return body, err
+}
+
+// startServer starts a fake CLI server that answers requests and runs script
+// when a message is sent. It returns the server's port and the responses the
+// client sent to the server's requests.
+func startServer(t *testing.T, script func(write func([]byte))) (port int, responses <-chan copilot.JSONRPCResponse) {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	t.Cleanup(func() { listener.Close() })
+	received := make(chan copilot.JSONRPCResponse, 16)
+
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		var mu sync.Mutex
+		write := func(data []byte) {
+			mu.Lock()
+			defer mu.Unlock()
+			conn.Write(data)
+		}
+		reader := bufio.NewReader(conn)
+		for {
+			body, err := readFrame(reader)
+			if err != nil {
+				return
+			}
+			var request copilot.JSONRPCRequest
+			if err := json.Unmarshal(body, &request); err == nil && request.Method == "" {
+				var response copilot.JSONRPCResponse
+				json.Unmarshal(body, &response)
+				received <- response
+				continue
+			}
+			switch request.Method {
+			case "session.create":
+				write(Response(request.ID, map[string]interface{}{"sessionId": "s1"}).Frame())
+			case "session.send":
+				write(Response(request.ID, map[string]interface{}{"messageId": "m1"}).Frame())
+				go script(write)
+			default:
+				write(Response(request.ID, map[string]interface{}{"protocolVersion": copilot.GetSdkProtocolVersion()}).Frame())
+			}
+		}
+	}()
+	return listener.Addr().(*net.TCPAddr).Port, received
+}
+
+func TestFixtures_DecodedByClient(t *testing.T) {
+	port, responses := startServer(t, func(write func([]byte)) {
+		write(Frames(
+			AssistantDelta("s1", "m1", "Hel"),
+			AssistantDelta("s1", "m1", "lo"),
+			ToolExecutionStart("s1", "call-1", "lookup", map[string]interface{}{"key": "a"}),
+			ToolCallRequest("req-1", "s1", "call-1", "lookup", map[string]interface{}{"key": "a"}),
+			ToolExecutionComplete("s1", "call-1", true),
+			AssistantMessage("s1", "m1", "Hello", WithEventID("final")),
+			SessionIdle("s1"),
+		))
+	})
+
+	client := copilot.NewClient(&copilot.ClientOptions{CLIUrl: strconv.Itoa(port)})
+	t.Cleanup(func() { client.ForceStop() })
+	type lookupParams struct {
+		Key string `json:"key"`
+	}
+	session, err := client.CreateSession(&copilot.SessionConfig{
+		Tools: []copilot.Tool{copilot.DefineTool("lookup", "Look up a key",
+			func(params lookupParams, inv copilot.ToolInvocation) (string, error) {
+				return "value of " + params.Key, nil
+			})},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create session: %v", err)
+	}
+
+	var mu sync.Mutex
+	var deltas []string
+	var types []copilot.SessionEventType
+	session.On(func(event copilot.SessionEvent) {
+		mu.Lock()
+		defer mu.Unlock()
+		types = append(types, event.Type)
+		if event.Type == copilot.AssistantMessageDelta && event.Data.DeltaContent != nil {
+			deltas = append(deltas, *event.Data.DeltaContent)
+		}
+	})
+
+	final, err := session.SendAndWait(copilot.MessageOptions{Prompt: "hi"}, 5*time.Second)
+	if err != nil {
+		t.Fatalf("Failed to send: %v", err)
+	}
+	if final == nil || final.ID != "final" || *final.Data.Content != "Hello" || !final.Timestamp.Equal(Epoch) {
+		t.Errorf("Unexpected final message %+v", final)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if strings.Join(deltas, "") != "Hello" {
+		t.Errorf("Expected the deltas to be decoded, got %q", deltas)
+	}
+	want := []copilot.SessionEventType{
+		copilot.AssistantMessageDelta, copilot.AssistantMessageDelta, copilot.ToolExecutionStart,
+		copilot.ToolExecutionComplete, copilot.AssistantMessage, copilot.SessionIdle,
+	}
+	if fmt.Sprint(types) != fmt.Sprint(want) {
+		t.Errorf("Expected events %v, got %v", want, types)
+	}
+
+	select {
+	case response := <-responses:
+		result, _ := response.Result["result"].(map[string]interface{})
+		if string(response.ID) != `"req-1"` || result["textResultForLlm"] != "value of a" {
+			t.Errorf("Unexpected tool call response %+v", response)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Expected a response to the tool call request")
+	}
+}
+
+func TestMessage_Frame(t *testing.T) {
+	m := ErrorResponse(json.RawMessage(`3`), -32601, "Method not found")
+	frame := string(m.Frame())
+	body := `{"jsonrpc":"2.0","id":3,"error":{"code":-32601,"message":"Method not found"}}`
+	if want := fmt.Sprintf("Content-Length: %d\r\n\r\n%s", len(body), body); frame != want {
+		t.Errorf("Expected %q, got %q", want, frame)
+	}
+
+	first, second := SessionIdle("s1"), SessionIdle("s1")
+	var a, b struct {
+		Params struct {
+			Event copilot.SessionEvent `json:"event"`
+		} `json:"params"`
+	}
+	json.Unmarshal(first.JSON(), &a)
+	json.Unmarshal(second.JSON(), &b)
+	if a.Params.Event.ID == b.Params.Event.ID {
+		t.Errorf("Expected distinct event IDs, got %q twice", a.Params.Event.ID)
+	}
+}
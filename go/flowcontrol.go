@@ -0,0 +1,209 @@
+package copilot
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// DefaultFlowControlHighWatermark is the number of queued event bytes at which
+// a [FlowControl] pauses reading unless HighWatermark is set.
+const DefaultFlowControlHighWatermark = 8 << 20
+
+// FlowControl bounds the memory held by events the server has sent but the
+// application has not consumed yet. Events are queued for subscribers that
+// consume them at their own pace, such as [Session.EventsSeq]; when the bytes
+// queued across all sessions of a [Client] reach HighWatermark, the client
+// stops reading from the server until they drain to LowWatermark. While
+// reading is paused, the server's writes block once the transport's buffers
+// fill, so it is slowed to the application's pace instead of growing the
+// queues without bound.
+//
+// Pausing also holds back responses and tool calls from the server, so a
+// consumer must not wait on a request to the server while it has events
+// queued past the high watermark.
+//
+// Example:
+//
+//	client := copilot.NewClient(&copilot.ClientOptions{
+//	    FlowControl: &copilot.FlowControl{
+//	        HighWatermark: 16 << 20,
+//	        OnPause: func(e copilot.FlowControlEvent) {
+//	            log.Printf("Consumers are behind by %d bytes; pausing reads", e.QueuedBytes)
+//	        },
+//	    },
+//	})
+type FlowControl struct {
+	// HighWatermark is the number of queued event bytes at which reading
+	// pauses. Defaults to [DefaultFlowControlHighWatermark].
+	HighWatermark int64
+	// LowWatermark is the number of queued event bytes at or below which
+	// reading resumes. Defaults to half of HighWatermark.
+	LowWatermark int64
+	// OnPause, if set, is called when reading pauses.
+	OnPause func(event FlowControlEvent)
+	// OnResume, if set, is called when reading resumes.
+	OnResume func(event FlowControlEvent)
+}
+
+// FlowControlEvent describes a pause or resume of reading by a [FlowControl].
+type FlowControlEvent struct {
+	// QueuedBytes is the number of event bytes queued when reading paused or
+	// resumed.
+	QueuedBytes int64
+	// PausedFor is how long reading was paused, for resumes.
+	PausedFor time.Duration
+}
+
+// FlowControlStats reports the state of a client's [FlowControl].
+type FlowControlStats struct {
+	// QueuedBytes is the number of event bytes queued for subscribers.
+	QueuedBytes int64
+	// Paused reports whether reading is paused.
+	Paused bool
+	// Pauses is the number of times reading paused.
+	Pauses uint64
+	// PausedTime is the total time reading was paused, including the current
+	// pause.
+	PausedTime time.Duration
+}
+
+// validate reports the first invalid setting.
+func (f *FlowControl) validate() error {
+	if f.HighWatermark < 0 || f.LowWatermark < 0 {
+		return fmt.Errorf("invalid FlowControl: watermarks must not be negative")
+	}
+	if f.HighWatermark > 0 && f.LowWatermark >= f.HighWatermark {
+		return fmt.Errorf("invalid FlowControl: LowWatermark %d must be below HighWatermark %d", f.LowWatermark, f.HighWatermark)
+	}
+	return nil
+}
+
+// flowWindow enforces a [FlowControl] on the read loop. Its methods may be
+// called on a nil window, which never pauses.
+type flowWindow struct {
+	high, low int64
+	onPause   func(FlowControlEvent)
+	onResume  func(FlowControlEvent)
+
+	mu       sync.Mutex
+	queued   int64
+	paused   bool
+	pausedAt time.Time
+	// resumed is closed when a pause ends.
+	resumed     chan struct{}
+	pauses      uint64
+	pausedTotal time.Duration
+}
+
+// newFlowWindow returns a window for config, or nil if config is nil.
+func newFlowWindow(config *FlowControl) *flowWindow {
+	if config == nil {
+		return nil
+	}
+	w := &flowWindow{
+		high:     config.HighWatermark,
+		low:      config.LowWatermark,
+		onPause:  config.OnPause,
+		onResume: config.OnResume,
+	}
+	if w.high == 0 {
+		w.high = DefaultFlowControlHighWatermark
+	}
+	if w.low == 0 || w.low >= w.high {
+		w.low = w.high / 2
+	}
+	return w
+}
+
+// add records n bytes queued for a subscriber, pausing reads at the high
+// watermark.
+func (w *flowWindow) add(n int64) {
+	if w == nil {
+		return
+	}
+	w.mu.Lock()
+	w.queued += n
+	if w.paused || w.queued < w.high {
+		w.mu.Unlock()
+		return
+	}
+	w.paused = true
+	w.pausedAt = time.Now()
+	w.resumed = make(chan struct{})
+	w.pauses++
+	event := FlowControlEvent{QueuedBytes: w.queued}
+	w.mu.Unlock()
+
+	if w.onPause != nil {
+		w.onPause(event)
+	}
+}
+
+// release records n queued bytes consumed, resuming reads at the low
+// watermark.
+func (w *flowWindow) release(n int64) {
+	if w == nil {
+		return
+	}
+	w.mu.Lock()
+	w.queued -= n
+	if !w.paused || w.queued > w.low {
+		w.mu.Unlock()
+		return
+	}
+	w.paused = false
+	pausedFor := time.Since(w.pausedAt)
+	w.pausedTotal += pausedFor
+	close(w.resumed)
+	event := FlowControlEvent{QueuedBytes: w.queued, PausedFor: pausedFor}
+	w.mu.Unlock()
+
+	if w.onResume != nil {
+		w.onResume(event)
+	}
+}
+
+// wait blocks while reads are paused, or until stop is closed.
+func (w *flowWindow) wait(stop <-chan struct{}) {
+	if w == nil {
+		return
+	}
+	w.mu.Lock()
+	paused, resumed := w.paused, w.resumed
+	w.mu.Unlock()
+	if !paused {
+		return
+	}
+	select {
+	case <-resumed:
+	case <-stop:
+	}
+}
+
+// stats returns the window's current state.
+func (w *flowWindow) stats() FlowControlStats {
+	if w == nil {
+		return FlowControlStats{}
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	stats := FlowControlStats{
+		QueuedBytes: w.queued,
+		Paused:      w.paused,
+		Pauses:      w.pauses,
+		PausedTime:  w.pausedTotal,
+	}
+	if w.paused {
+		stats.PausedTime += time.Since(w.pausedAt)
+	}
+	return stats
+}
+
+// flowWindow returns the flow control window of the session's client, or nil.
+func (s *Session) flowWindow() *flowWindow {
+	if s.client == nil {
+		return nil
+	}
+	return s.client.flow
+}
@@ -0,0 +1,151 @@
+package copilot
+
+import (
+	"context"
+	"io"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestFlowWindow(t *testing.T) {
+	var pauses, resumes []FlowControlEvent
+	w := newFlowWindow(&FlowControl{
+		HighWatermark: 100,
+		OnPause:       func(e FlowControlEvent) { pauses = append(pauses, e) },
+		OnResume:      func(e FlowControlEvent) { resumes = append(resumes, e) },
+	})
+	if w.low != 50 {
+		t.Errorf("Expected the low watermark to default to half the high watermark, got %d", w.low)
+	}
+
+	w.add(60)
+	w.add(60)
+	if stats := w.stats(); !stats.Paused || stats.QueuedBytes != 120 || stats.Pauses != 1 {
+		t.Fatalf("Expected reads to pause at the high watermark, got %+v", stats)
+	}
+	if len(pauses) != 1 || pauses[0].QueuedBytes != 120 {
+		t.Errorf("Unexpected pause events %+v", pauses)
+	}
+
+	waited := make(chan struct{})
+	go func() {
+		w.wait(nil)
+		close(waited)
+	}()
+	w.release(60)
+	select {
+	case <-waited:
+		t.Fatal("Expected reads to stay paused above the low watermark")
+	case <-time.After(20 * time.Millisecond):
+	}
+	w.release(20)
+	select {
+	case <-waited:
+	case <-time.After(time.Second):
+		t.Fatal("Expected reads to resume at the low watermark")
+	}
+	if len(resumes) != 1 || resumes[0].QueuedBytes != 40 || resumes[0].PausedFor <= 0 {
+		t.Errorf("Unexpected resume events %+v", resumes)
+	}
+
+	stop := make(chan struct{})
+	w.add(100)
+	close(stop)
+	w.wait(stop)
+
+	var nilWindow *flowWindow
+	nilWindow.add(1 << 30)
+	nilWindow.wait(nil)
+	if stats := nilWindow.stats(); stats.Paused {
+		t.Error("Expected a nil window never to pause")
+	}
+
+	if err := (&FlowControl{HighWatermark: 10, LowWatermark: 10}).validate(); err == nil {
+		t.Error("Expected an error for a low watermark at the high watermark")
+	}
+}
+
+func TestFlowControl_PausesReadsForSlowConsumers(t *testing.T) {
+	// The window must be set before the read loop starts, so the client is
+	// wired up by hand instead of with newFakeServer.
+	requestsReader, requestsWriter := io.Pipe()
+	go io.Copy(io.Discard, requestsReader)
+	responsesReader, responsesWriter := io.Pipe()
+	server := &fakeServer{writer: responsesWriter}
+	rpc := NewJSONRPCClient(requestsWriter, responsesReader)
+	session := NewSession("s1", rpc, "")
+	client := &Client{
+		client:   rpc,
+		state:    StateConnected,
+		sessions: map[string]*Session{"s1": session},
+		flow:     newFlowWindow(&FlowControl{HighWatermark: 1000}),
+	}
+	client.setupNotificationHandler(client.client)
+	rpc.Start()
+	t.Cleanup(func() {
+		rpc.Stop()
+		requestsWriter.Close()
+		responsesWriter.Close()
+	})
+
+	const total = 50
+	var dispatched atomic.Int32
+	session.On(func(SessionEvent) { dispatched.Add(1) })
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	gate := make(chan struct{})
+	consumed := make(chan int)
+	go func() {
+		n := 0
+		for range session.EventsSeq(ctx) {
+			<-gate
+			if n++; n == total {
+				break
+			}
+		}
+		consumed <- n
+	}()
+	subscribers := func() int {
+		session.handlerMutex.RLock()
+		defer session.handlerMutex.RUnlock()
+		return len(session.handlers)
+	}
+	for subscribers() < 2 {
+		time.Sleep(time.Millisecond)
+	}
+
+	content := strings.Repeat("x", 200)
+	go func() {
+		for i := 0; i < total; i++ {
+			server.emitEvent(AssistantMessageDelta, "e", map[string]interface{}{"deltaContent": content})
+		}
+	}()
+
+	deadline := time.Now().Add(2 * time.Second)
+	for !client.flow.stats().Paused && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	time.Sleep(20 * time.Millisecond)
+	if !client.flow.stats().Paused {
+		t.Fatal("Expected reads to pause while the consumer is behind")
+	}
+	if n := dispatched.Load(); n >= total {
+		t.Fatalf("Expected dispatch to stop while paused, got %d events", n)
+	}
+
+	close(gate)
+	select {
+	case n := <-consumed:
+		if n != total {
+			t.Fatalf("Expected %d events, got %d", total, n)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected reads to resume once the consumer caught up")
+	}
+	if stats := client.flow.stats(); stats.Pauses == 0 || stats.QueuedBytes != 0 {
+		t.Errorf("Expected every event to be consumed, got %+v", stats)
+	}
+}
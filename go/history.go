@@ -0,0 +1,123 @@
+package copilot
+
+import (
+	"encoding/json"
+	"fmt"
+	"iter"
+)
+
+// History is a snapshot of a session's conversation history, as returned by
+// [Session.History].
+type History struct {
+	// SessionID is the ID of the session the history belongs to.
+	SessionID string
+	// Events are the session events in chronological order.
+	Events []SessionEvent
+	// citations holds the citations parsed from each event, keyed by event ID.
+	citations map[string][]Citation
+}
+
+// History retrieves this session's conversation history.
+//
+// It returns the same events as [Session.GetMessages], together with accessors
+// that group them into turns.
+//
+// Example:
+//
+//	history, err := session.History()
+//	if err != nil {
+//	    log.Fatal(err)
+//	}
+//	for turn := range history.Turns() {
+//	    fmt.Println(turn.Content)
+//	}
+func (s *Session) History() (*History, error) {
+	params := map[string]interface{}{
+		"sessionId": s.SessionID,
+	}
+
+	result, err := s.client.Request("session.getMessages", params)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get messages: %w", err)
+	}
+
+	eventsRaw, ok := result["events"].([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("invalid response: missing events")
+	}
+
+	history := &History{
+		SessionID: s.SessionID,
+		Events:    make([]SessionEvent, 0, len(eventsRaw)),
+		citations: make(map[string][]Citation),
+	}
+	for _, eventRaw := range eventsRaw {
+		// Marshal back to JSON and unmarshal into typed struct
+		eventJSON, err := json.Marshal(eventRaw)
+		if err != nil {
+			continue
+		}
+
+		event, err := UnmarshalSessionEvent(eventJSON)
+		if err != nil {
+			continue
+		}
+
+		if citations := parseCitations(eventJSON); len(citations) > 0 {
+			history.citations[event.ID] = citations
+		}
+		history.Events = append(history.Events, event)
+	}
+
+	return history, nil
+}
+
+// All iterates over the events of the history in chronological order.
+func (h *History) All() iter.Seq[SessionEvent] {
+	return func(yield func(SessionEvent) bool) {
+		for _, event := range h.Events {
+			if !yield(event) {
+				return
+			}
+		}
+	}
+}
+
+// Turns iterates over the turns of the history. Each turn starts with a
+// user.message event and includes every event up to the next one; events before
+// the first user message are not part of any turn.
+//
+// For turns from history, [Turn.MessageID] is the ID of the user.message event
+// that started the turn.
+func (h *History) Turns() iter.Seq[*Turn] {
+	return func(yield func(*Turn) bool) {
+		var collector *turnCollector
+		var messageID string
+		flush := func() bool {
+			if collector == nil {
+				return true
+			}
+			turn := collector.result()
+			turn.MessageID = messageID
+			return yield(turn)
+		}
+
+		for _, event := range h.Events {
+			if event.Type == UserMessage {
+				if !flush() {
+					return
+				}
+				collector = &turnCollector{citations: h.citationsFor, names: make(toolCallNames)}
+				messageID = event.ID
+			}
+			if collector != nil {
+				collector.add(event)
+			}
+		}
+		flush()
+	}
+}
+
+func (h *History) citationsFor(event SessionEvent) []Citation {
+	return h.citations[event.ID]
+}
@@ -0,0 +1,71 @@
+package copilot
+
+import (
+	"testing"
+)
+
+func TestHistory(t *testing.T) {
+	session, _ := newFakeSession(t, func(server *fakeServer, method string, params map[string]interface{}) (map[string]interface{}, *JSONRPCError) {
+		event := func(id, eventType string, data map[string]interface{}) map[string]interface{} {
+			return map[string]interface{}{"id": id, "type": eventType, "timestamp": "2025-01-01T00:00:00Z", "data": data}
+		}
+		return map[string]interface{}{"events": []interface{}{
+			event("e0", "session.start", map[string]interface{}{}),
+			event("u1", "user.message", map[string]interface{}{"content": "Where is main?"}),
+			event("e2", "tool.execution_start", map[string]interface{}{"toolCallId": "c1", "toolName": "grep"}),
+			event("e3", "tool.execution_complete", map[string]interface{}{"toolCallId": "c1", "success": false, "error": "boom"}),
+			event("a1", "assistant.message", map[string]interface{}{
+				"content":   "In main.go",
+				"citations": []interface{}{map[string]interface{}{"type": "file", "path": "main.go", "startLine": 1}},
+			}),
+			event("u2", "user.message", map[string]interface{}{"content": "Thanks"}),
+			event("a2", "assistant.message", map[string]interface{}{"content": "You're welcome"}),
+		}}, nil
+	})
+
+	history, err := session.History()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(history.Events) != 7 {
+		t.Fatalf("Expected 7 events, got %d", len(history.Events))
+	}
+
+	t.Run("groups events into turns", func(t *testing.T) {
+		var turns []*Turn
+		for turn := range history.Turns() {
+			turns = append(turns, turn)
+		}
+		if len(turns) != 2 {
+			t.Fatalf("Expected 2 turns, got %d", len(turns))
+		}
+		first := turns[0]
+		if first.MessageID != "u1" || first.Content != "In main.go" || len(first.Events) != 4 {
+			t.Errorf("Unexpected first turn %+v", first)
+		}
+		if len(first.Citations) != 1 || first.Citations[0].Path != "main.go" {
+			t.Errorf("Expected citations from history, got %+v", first.Citations)
+		}
+		if first.ToolErrors == nil || first.ToolErrors.Errors[0].ToolName != "grep" {
+			t.Errorf("Expected the failed grep call, got %+v", first.ToolErrors)
+		}
+		if turns[1].Content != "You're welcome" || turns[1].ToolErrors != nil {
+			t.Errorf("Unexpected second turn %+v", turns[1])
+		}
+	})
+
+	t.Run("stops early when the loop breaks", func(t *testing.T) {
+		count := 0
+		for range history.Turns() {
+			count++
+			break
+		}
+		for range history.All() {
+			count++
+			break
+		}
+		if count != 2 {
+			t.Errorf("Expected one value from each iterator, got %d", count)
+		}
+	})
+}
@@ -0,0 +1,50 @@
+package copilot
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+)
+
+// NewIdempotencyKey returns a new random idempotency key suitable for
+// [MessageOptions.IdempotencyKey].
+//
+// Generate the key once per logical message and reuse it when retrying a send
+// after a reconnect, so the server can recognize the retry and avoid creating a
+// duplicate turn.
+func NewIdempotencyKey() string {
+	return generateUUID()
+}
+
+// SendError is returned by [Session.Send] and the methods built on it when
+// the send request fails, such as when the connection drops before the server
+// answers. The message may or may not have been delivered, so retry it with
+// IdempotencyKey as [MessageOptions.IdempotencyKey], which is the key the
+// failed request carried even when the caller did not set one.
+//
+// Example:
+//
+//	_, err := session.Send(options)
+//	var sendErr *copilot.SendError
+//	if errors.As(err, &sendErr) {
+//	    options.IdempotencyKey = sendErr.IdempotencyKey
+//	    _, err = session.Send(options)
+//	}
+type SendError struct {
+	IdempotencyKey string
+	Err            error
+}
+
+func (e *SendError) Error() string {
+	return "failed to send message: " + e.Err.Error()
+}
+
+func (e *SendError) Unwrap() error {
+	return e.Err
+}
+
+// toolIdempotencyKey derives a stable idempotency key for a tool call, so that
+// a tool call redelivered after a reconnect carries the same key as the original.
+func toolIdempotencyKey(sessionID, toolCallID string) string {
+	sum := sha256.Sum256([]byte(sessionID + "\x00" + toolCallID))
+	return hex.EncodeToString(sum[:16])
+}
@@ -0,0 +1,76 @@
+package copilot
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestIdempotencyKeys(t *testing.T) {
+	t.Run("new keys are unique", func(t *testing.T) {
+		if NewIdempotencyKey() == NewIdempotencyKey() {
+			t.Error("Expected distinct keys")
+		}
+	})
+
+	t.Run("tool call keys are stable per call", func(t *testing.T) {
+		if toolIdempotencyKey("s1", "call-1") != toolIdempotencyKey("s1", "call-1") {
+			t.Error("Expected the same key for the same tool call")
+		}
+		if toolIdempotencyKey("s1", "call-1") == toolIdempotencyKey("s2", "call-1") {
+			t.Error("Expected different keys for different sessions")
+		}
+	})
+
+	t.Run("tool handlers and responses carry the key", func(t *testing.T) {
+		session := NewSession("s1", nil, "")
+		var seen string
+		session.registerTools([]Tool{{
+			Name: "echo",
+			Handler: func(inv ToolInvocation) (ToolResult, error) {
+				seen = inv.IdempotencyKey
+				return ToolResult{TextResultForLLM: "ok", ResultType: "success"}, nil
+			},
+		}})
+		client := &Client{sessions: map[string]*Session{"s1": session}}
+
+		response, rpcErr := client.handleToolCallRequest(map[string]interface{}{
+			"sessionId":  "s1",
+			"toolCallId": "call-1",
+			"toolName":   "echo",
+			"arguments":  map[string]interface{}{},
+		})
+		if rpcErr != nil {
+			t.Fatalf("Unexpected error: %v", rpcErr)
+		}
+		want := toolIdempotencyKey("s1", "call-1")
+		if seen != want {
+			t.Errorf("Expected handler to see key %q, got %q", want, seen)
+		}
+		if response["idempotencyKey"] != want {
+			t.Errorf("Expected response to carry key %q, got %v", want, response["idempotencyKey"])
+		}
+	})
+
+	t.Run("failed sends report the key to retry with", func(t *testing.T) {
+		var sent []string
+		session, _ := newFakeSession(t, func(server *fakeServer, method string, params map[string]interface{}) (map[string]interface{}, *JSONRPCError) {
+			sent = append(sent, params["idempotencyKey"].(string))
+			return nil, &JSONRPCError{Code: -32000, Message: "connection reset"}
+		})
+
+		_, err := session.Send(MessageOptions{Prompt: "hi"})
+		var sendErr *SendError
+		if !errors.As(err, &sendErr) || sendErr.IdempotencyKey == "" || sendErr.IdempotencyKey != sent[0] {
+			t.Fatalf("Expected a SendError with the generated key %q, got %v", sent[0], err)
+		}
+		var rpcErr *JSONRPCError
+		if !errors.As(err, &rpcErr) {
+			t.Errorf("Expected the RPC error to be wrapped, got %v", err)
+		}
+
+		_, err = session.Send(MessageOptions{Prompt: "hi", IdempotencyKey: sendErr.IdempotencyKey})
+		if !errors.As(err, &sendErr) || sent[1] != sent[0] || sendErr.IdempotencyKey != sent[0] {
+			t.Errorf("Expected the retry to reuse the key, got %v and %v", sent, err)
+		}
+	})
+}
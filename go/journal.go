@@ -0,0 +1,298 @@
+package copilot
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+// JournalSync is when a [JournalConfig] journal is flushed to stable storage.
+type JournalSync int
+
+const (
+	// JournalSyncNone leaves flushing to the operating system. Every frame is
+	// written to the file before it is sent, so the journal survives a crash
+	// of the process, but not necessarily of the machine.
+	JournalSyncNone JournalSync = iota
+	// JournalSyncEveryFrame flushes each frame to disk before it is sent. It
+	// survives power loss, at the cost of an fsync per frame.
+	JournalSyncEveryFrame
+	// JournalSyncInterval flushes written frames every SyncInterval, so at most
+	// that much of the journal is lost with the machine.
+	JournalSyncInterval
+)
+
+// DefaultJournalSyncInterval is the SyncInterval of a [JournalSyncInterval]
+// journal unless set.
+const DefaultJournalSyncInterval = time.Second
+
+// JournalConfig enables write-ahead journaling of the frames a [Client] sends
+// to the server: each request, notification, and response is appended to the
+// journal before it is written to the transport, so after a crash the journal
+// shows exactly what the client last told the server. Read it back with
+// [ReadJournal] or the copilot-journal command.
+//
+// The journal holds frames verbatim, including prompts and tool results, so it
+// is created readable only by its owner.
+//
+// Example:
+//
+//	client := copilot.NewClient(&copilot.ClientOptions{
+//	    Journal: &copilot.JournalConfig{
+//	        Path: "/var/log/myapp/copilot.journal",
+//	        Sync: copilot.JournalSyncInterval,
+//	    },
+//	})
+type JournalConfig struct {
+	// Path is the file frames are appended to. It is created if needed.
+	Path string
+	// Sync is when frames are flushed to disk. Defaults to [JournalSyncNone].
+	Sync JournalSync
+	// SyncInterval is how often a [JournalSyncInterval] journal is flushed.
+	// Defaults to [DefaultJournalSyncInterval].
+	SyncInterval time.Duration
+}
+
+func (c *JournalConfig) validate() error {
+	if c.Path == "" {
+		return errors.New("journal path is required")
+	}
+	if c.Sync < JournalSyncNone || c.Sync > JournalSyncInterval {
+		return fmt.Errorf("invalid journal sync policy %d", c.Sync)
+	}
+	if c.SyncInterval < 0 {
+		return errors.New("journal sync interval must not be negative")
+	}
+	return nil
+}
+
+// JournalEntry is a frame recorded in a journal.
+type JournalEntry struct {
+	// Seq numbers the frames written since the journal was opened, from 1. It
+	// restarts when a client reopens the journal.
+	Seq uint64 `json:"seq"`
+	// Time is when the frame was journaled, just before it was sent.
+	Time time.Time `json:"time"`
+	// Frame is the JSON-RPC message as sent.
+	Frame json.RawMessage `json:"frame"`
+}
+
+// Kind returns the kind of the entry's frame: "request", "notification",
+// "response", or "error" for an error response.
+func (e JournalEntry) Kind() string {
+	var frame struct {
+		ID     json.RawMessage `json:"id"`
+		Method string          `json:"method"`
+		Error  json.RawMessage `json:"error"`
+	}
+	_ = json.Unmarshal(e.Frame, &frame)
+	switch {
+	case frame.Method != "" && len(frame.ID) > 0:
+		return "request"
+	case frame.Method != "":
+		return "notification"
+	case len(frame.Error) > 0 && string(frame.Error) != "null":
+		return "error"
+	default:
+		return "response"
+	}
+}
+
+// Method returns the method of the entry's request or notification, or "" for
+// a response.
+func (e JournalEntry) Method() string {
+	var frame struct {
+		Method string `json:"method"`
+	}
+	_ = json.Unmarshal(e.Frame, &frame)
+	return frame.Method
+}
+
+// ID returns the JSON-RPC ID of the entry's request or response, or "" for a
+// notification.
+func (e JournalEntry) ID() string {
+	var frame struct {
+		ID json.RawMessage `json:"id"`
+	}
+	_ = json.Unmarshal(e.Frame, &frame)
+	var id string
+	if json.Unmarshal(frame.ID, &id) == nil {
+		return id
+	}
+	return string(frame.ID)
+}
+
+// Journal is the contents of a journal file.
+type Journal struct {
+	Entries []JournalEntry
+	// Truncated reports that the journal ended with a partly written entry,
+	// as when the process died mid-write. The partial entry is not in Entries.
+	Truncated bool
+}
+
+// ReadJournal reads a journal written by a client with a [JournalConfig].
+// A partly written last entry is dropped and reported in Truncated; any other
+// malformed entry is an error.
+//
+// Example:
+//
+//	file, _ := os.Open("/var/log/myapp/copilot.journal")
+//	journal, err := copilot.ReadJournal(file)
+//	if err != nil {
+//	    log.Fatal(err)
+//	}
+//	last := journal.Entries[len(journal.Entries)-1]
+//	fmt.Println(last.Time, last.Kind(), last.Method())
+func ReadJournal(r io.Reader) (*Journal, error) {
+	journal := &Journal{}
+	reader := bufio.NewReader(r)
+	for line := 1; ; line++ {
+		data, err := reader.ReadBytes('\n')
+		if err != nil && err != io.EOF {
+			return nil, fmt.Errorf("failed to read journal: %w", err)
+		}
+		complete := err == nil
+		if data = bytes.TrimSpace(data); len(data) > 0 {
+			var entry JournalEntry
+			if decodeErr := json.Unmarshal(data, &entry); decodeErr != nil {
+				if !complete {
+					journal.Truncated = true
+					return journal, nil
+				}
+				return nil, fmt.Errorf("failed to decode journal line %d: %w", line, decodeErr)
+			}
+			journal.Entries = append(journal.Entries, entry)
+		}
+		if !complete {
+			return journal, nil
+		}
+	}
+}
+
+// frameJournal appends outgoing frames to a journal file.
+type frameJournal struct {
+	mu     sync.Mutex
+	file   *os.File
+	config JournalConfig
+	seq    uint64
+	dirty  bool
+	stop   chan struct{}
+	done   chan struct{}
+	once   sync.Once
+	now    func() time.Time
+}
+
+// openFrameJournal opens the journal config describes for appending.
+func openFrameJournal(config JournalConfig) (*frameJournal, error) {
+	file, err := os.OpenFile(config.Path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open journal: %w", err)
+	}
+	j := &frameJournal{file: file, config: config, now: time.Now}
+	if config.Sync == JournalSyncInterval {
+		interval := config.SyncInterval
+		if interval == 0 {
+			interval = DefaultJournalSyncInterval
+		}
+		j.stop = make(chan struct{})
+		j.done = make(chan struct{})
+		go j.syncEvery(interval)
+	}
+	return j, nil
+}
+
+// append records frame, returning once it is written or, under
+// [JournalSyncEveryFrame], on disk.
+func (j *frameJournal) append(frame []byte) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if j.file == nil {
+		return errors.New("journal is closed")
+	}
+	j.seq++
+	line, err := json.Marshal(JournalEntry{Seq: j.seq, Time: j.now().UTC(), Frame: frame})
+	if err != nil {
+		return fmt.Errorf("failed to encode journal entry: %w", err)
+	}
+	if _, err := j.file.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("failed to write journal: %w", err)
+	}
+	if j.config.Sync == JournalSyncEveryFrame {
+		if err := j.file.Sync(); err != nil {
+			return fmt.Errorf("failed to sync journal: %w", err)
+		}
+		return nil
+	}
+	j.dirty = true
+	return nil
+}
+
+func (j *frameJournal) syncEvery(interval time.Duration) {
+	defer close(j.done)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-j.stop:
+			return
+		case <-ticker.C:
+			j.mu.Lock()
+			if j.file != nil && j.dirty {
+				// A failed sync is retried on the next tick; the frames are
+				// already written.
+				if j.file.Sync() == nil {
+					j.dirty = false
+				}
+			}
+			j.mu.Unlock()
+		}
+	}
+}
+
+// close flushes the journal to disk and closes it.
+func (j *frameJournal) close() error {
+	var err error
+	j.once.Do(func() { err = j.closeFile() })
+	return err
+}
+
+func (j *frameJournal) closeFile() error {
+	if j.stop != nil {
+		close(j.stop)
+		<-j.done
+	}
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if j.file == nil {
+		return nil
+	}
+	syncErr := j.file.Sync()
+	closeErr := j.file.Close()
+	j.file = nil
+	if syncErr != nil {
+		return fmt.Errorf("failed to sync journal: %w", syncErr)
+	}
+	if closeErr != nil {
+		return fmt.Errorf("failed to close journal: %w", closeErr)
+	}
+	return nil
+}
+
+// openJournal opens the client's journal, if it has one and it is not open.
+func (c *Client) openJournal() error {
+	if c.options.Journal == nil || c.journal != nil {
+		return nil
+	}
+	journal, err := openFrameJournal(*c.options.Journal)
+	if err != nil {
+		return err
+	}
+	c.journal = journal
+	return nil
+}
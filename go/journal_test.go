@@ -0,0 +1,164 @@
+package copilot
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// failingWriter is a transport that fails every write.
+type failingWriter struct{}
+
+func (failingWriter) Write([]byte) (int, error) { return 0, errors.New("broken pipe") }
+func (failingWriter) Close() error              { return nil }
+
+func readJournalFile(t *testing.T, path string) *Journal {
+	t.Helper()
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	journal, err := ReadJournal(file)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	return journal
+}
+
+func TestJournal(t *testing.T) {
+	t.Run("records frames before they are sent", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "copilot.journal")
+		journal, err := openFrameJournal(JournalConfig{Path: path, Sync: JournalSyncEveryFrame})
+		if err != nil {
+			t.Fatal(err)
+		}
+		client, server := newFakeServer(t, func(method string, params map[string]interface{}) (map[string]interface{}, *JSONRPCError) {
+			return map[string]interface{}{"ok": true}, nil
+		})
+		client.journal = journal
+		if _, err := client.Request("session.create", map[string]interface{}{"model": "gpt-5"}); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if err := client.Notify("session.log", nil); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		server.send(JSONRPCRequest{JSONRPC: "2.0", ID: []byte(`"srv-1"`), Method: "tool.call", Params: map[string]interface{}{}})
+		deadline := time.Now().Add(time.Second)
+		for len(readJournalFile(t, path).Entries) < 3 && time.Now().Before(deadline) {
+			time.Sleep(5 * time.Millisecond)
+		}
+		if err := journal.close(); err != nil {
+			t.Fatal(err)
+		}
+
+		entries := readJournalFile(t, path).Entries
+		if len(entries) != 3 {
+			t.Fatalf("Expected 3 frames, got %d", len(entries))
+		}
+		if entries[0].Seq != 1 || entries[0].Kind() != "request" || entries[0].Method() != "session.create" || entries[0].ID() == "" {
+			t.Errorf("Unexpected first entry %+v", entries[0])
+		}
+		if !strings.Contains(string(entries[0].Frame), `"model":"gpt-5"`) || entries[0].Time.IsZero() {
+			t.Errorf("Expected the frame verbatim, got %s", entries[0].Frame)
+		}
+		if entries[1].Kind() != "notification" || entries[1].Method() != "session.log" || entries[1].ID() != "" {
+			t.Errorf("Unexpected second entry %+v", entries[1])
+		}
+		// The server's tool.call has no handler, so the client answers with an
+		// error response.
+		if entries[2].Kind() != "error" || entries[2].ID() != "srv-1" || entries[2].Method() != "" {
+			t.Errorf("Unexpected third entry %+v", entries[2])
+		}
+		if info, err := os.Stat(path); err != nil || info.Mode().Perm() != 0o600 {
+			t.Errorf("Expected a journal readable only by its owner, got %v, %v", info.Mode(), err)
+		}
+	})
+
+	t.Run("keeps frames whose write failed", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "copilot.journal")
+		journal, err := openFrameJournal(JournalConfig{Path: path, Sync: JournalSyncInterval, SyncInterval: time.Millisecond})
+		if err != nil {
+			t.Fatal(err)
+		}
+		client := NewJSONRPCClient(failingWriter{}, nil)
+		client.journal = journal
+		if err := client.Notify("session.abort", map[string]interface{}{"sessionId": "s1"}); err == nil {
+			t.Fatal("Expected the write to fail")
+		}
+		if err := journal.close(); err != nil {
+			t.Fatal(err)
+		}
+		if err := journal.close(); err != nil {
+			t.Errorf("Expected closing twice to succeed, got %v", err)
+		}
+		if entries := readJournalFile(t, path).Entries; len(entries) != 1 || entries[0].Method() != "session.abort" {
+			t.Errorf("Unexpected entries %+v", entries)
+		}
+		if err := client.Notify("session.abort", nil); err == nil || !strings.Contains(err.Error(), "journal is closed") {
+			t.Errorf("Expected sends to fail once the journal is closed, got %v", err)
+		}
+	})
+
+	t.Run("appends across reopens", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "copilot.journal")
+		for i := 0; i < 2; i++ {
+			journal, err := openFrameJournal(JournalConfig{Path: path})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := journal.append([]byte(`{"jsonrpc":"2.0","method":"ping"}`)); err != nil {
+				t.Fatal(err)
+			}
+			journal.close()
+		}
+		if entries := readJournalFile(t, path).Entries; len(entries) != 2 || entries[1].Seq != 1 {
+			t.Errorf("Unexpected entries %+v", entries)
+		}
+	})
+
+	t.Run("validates config", func(t *testing.T) {
+		for _, config := range []JournalConfig{{}, {Path: "j", Sync: 7}, {Path: "j", SyncInterval: -time.Second}} {
+			if err := config.validate(); err == nil {
+				t.Errorf("Expected %+v to be invalid", config)
+			}
+		}
+		defer func() {
+			if recover() == nil {
+				t.Error("Expected NewClient to panic on an invalid journal")
+			}
+		}()
+		NewClient(&ClientOptions{Journal: &JournalConfig{}})
+	})
+}
+
+func TestReadJournal(t *testing.T) {
+	entry := `{"seq":1,"time":"2026-01-02T03:04:05Z","frame":{"jsonrpc":"2.0","id":"1","method":"ping"}}`
+
+	t.Run("drops a partly written last entry", func(t *testing.T) {
+		journal, err := ReadJournal(strings.NewReader(entry + "\n" + `{"seq":2,"time":"2026-01-02T03:0`))
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if len(journal.Entries) != 1 || !journal.Truncated || journal.Entries[0].Method() != "ping" {
+			t.Errorf("Unexpected journal %+v", journal)
+		}
+	})
+
+	t.Run("reads a last entry without a newline", func(t *testing.T) {
+		journal, err := ReadJournal(strings.NewReader(entry))
+		if err != nil || len(journal.Entries) != 1 || journal.Truncated {
+			t.Errorf("Unexpected journal %+v, %v", journal, err)
+		}
+	})
+
+	t.Run("rejects a malformed entry before the end", func(t *testing.T) {
+		_, err := ReadJournal(strings.NewReader(entry + "\nnot json\n" + entry + "\n"))
+		if err == nil || !strings.Contains(err.Error(), "line 2") {
+			t.Errorf("Expected an error naming line 2, got %v", err)
+		}
+	})
+}
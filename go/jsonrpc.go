@@ -2,11 +2,16 @@ package copilot
 
 import (
 	"bufio"
+	"context"
 	"crypto/rand"
 	"encoding/json"
 	"fmt"
 	"io"
+	"strconv"
+	"strings"
 	"sync"
+	"sync/atomic"
+	"time"
 )
 
 // JSONRPCError represents a JSON-RPC error response
@@ -14,12 +19,27 @@ type JSONRPCError struct {
 	Code    int                    `json:"code"`
 	Message string                 `json:"message"`
 	Data    map[string]interface{} `json:"data,omitempty"`
+
+	// handoff, if set, marks the error returned by deferResponse.
+	handoff func(respond responder)
 }
 
 func (e *JSONRPCError) Error() string {
 	return fmt.Sprintf("JSON-RPC Error %d: %s", e.Code, e.Message)
 }
 
+// responder sends the response to an incoming request. Calls after the first
+// are ignored.
+type responder func(result map[string]interface{}, err *JSONRPCError)
+
+// deferResponse returns an error that, returned by a [RequestHandler], tells
+// the client not to respond when the handler returns. handoff is called with
+// the responder to use instead, so the response can be sent later without
+// holding the handler's goroutine.
+func deferResponse(handoff func(respond responder)) *JSONRPCError {
+	return &JSONRPCError{Code: -32603, Message: "response deferred", handoff: handoff}
+}
+
 // JSONRPCRequest represents a JSON-RPC 2.0 request
 type JSONRPCRequest struct {
 	JSONRPC string                 `json:"jsonrpc"`
@@ -54,12 +74,31 @@ type JSONRPCClient struct {
 	stdin               io.WriteCloser
 	stdout              io.ReadCloser
 	mu                  sync.Mutex
-	pendingRequests     map[string]chan *JSONRPCResponse
+	pendingRequests     map[string]*pendingRequest
 	notificationHandler NotificationHandler
 	requestHandlers     map[string]RequestHandler
-	running             bool
+	running             atomic.Bool
 	stopChan            chan struct{}
+	closed              chan struct{} // closed when the read loop exits
 	wg                  sync.WaitGroup
+	observers           []rpcObserver
+	nextObserverID      uint64
+	observersMux        sync.RWMutex
+	// limiter, if set, bounds the requests in flight.
+	limiter *adaptiveLimiter
+	// journal, if set, records each frame before it is written.
+	journal *frameJournal
+	// flow, if set, pauses the read loop while subscribers are behind.
+	flow *flowWindow
+	// protocolErrors, if set, records protocol anomalies.
+	protocolErrors *protocolErrorLog
+}
+
+// pendingRequest is a request sent and waiting for its response.
+type pendingRequest struct {
+	method   string
+	sentAt   time.Time
+	response chan *JSONRPCResponse
 }
 
 // NewJSONRPCClient creates a new JSON-RPC client
@@ -67,25 +106,25 @@ func NewJSONRPCClient(stdin io.WriteCloser, stdout io.ReadCloser) *JSONRPCClient
 	return &JSONRPCClient{
 		stdin:           stdin,
 		stdout:          stdout,
-		pendingRequests: make(map[string]chan *JSONRPCResponse),
+		pendingRequests: make(map[string]*pendingRequest),
 		requestHandlers: make(map[string]RequestHandler),
 		stopChan:        make(chan struct{}),
+		closed:          make(chan struct{}),
 	}
 }
 
 // Start begins listening for messages in a background goroutine
 func (c *JSONRPCClient) Start() {
-	c.running = true
+	c.running.Store(true)
 	c.wg.Add(1)
 	go c.readLoop()
 }
 
 // Stop stops the client and cleans up
 func (c *JSONRPCClient) Stop() {
-	if !c.running {
+	if !c.running.CompareAndSwap(true, false) {
 		return
 	}
-	c.running = false
 	close(c.stopChan)
 
 	// Close stdout to unblock the readLoop
@@ -96,6 +135,12 @@ func (c *JSONRPCClient) Stop() {
 	c.wg.Wait()
 }
 
+// Done returns a channel that is closed when the connection ends, because the
+// client was stopped or the server closed the connection.
+func (c *JSONRPCClient) Done() <-chan struct{} {
+	return c.closed
+}
+
 // SetNotificationHandler sets the handler for incoming notifications
 func (c *JSONRPCClient) SetNotificationHandler(handler NotificationHandler) {
 	c.mu.Lock()
@@ -116,12 +161,32 @@ func (c *JSONRPCClient) SetRequestHandler(method string, handler RequestHandler)
 
 // Request sends a JSON-RPC request and waits for the response
 func (c *JSONRPCClient) Request(method string, params map[string]interface{}) (map[string]interface{}, error) {
+	return c.RequestContext(context.Background(), method, params)
+}
+
+// RequestContext sends a JSON-RPC request and waits for the response, or
+// until ctx is done. A response that arrives after ctx is done is dropped.
+func (c *JSONRPCClient) RequestContext(ctx context.Context, method string, params map[string]interface{}) (result map[string]interface{}, err error) {
 	requestID := generateUUID()
+	start := time.Now()
+	defer func() {
+		c.observeCall(rpcCall{Method: method, Params: params, Start: start, End: time.Now(), Err: err})
+	}()
+
+	if c.limiter != nil {
+		admitted, acquireErr := c.limiter.acquire(ctx, c.stopChan)
+		if acquireErr != nil {
+			return nil, acquireErr
+		}
+		defer func() {
+			c.limiter.release(admitted, err)
+		}()
+	}
 
 	// Create response channel
 	responseChan := make(chan *JSONRPCResponse, 1)
 	c.mu.Lock()
-	c.pendingRequests[requestID] = responseChan
+	c.pendingRequests[requestID] = &pendingRequest{method: method, sentAt: time.Now(), response: responseChan}
 	c.mu.Unlock()
 
 	// Clean up on exit
@@ -147,14 +212,27 @@ func (c *JSONRPCClient) Request(method string, params map[string]interface{}) (m
 	select {
 	case response := <-responseChan:
 		if response.Error != nil {
+			if response.Error.Code == -32601 {
+				c.protocolErrors.record(ProtocolErrorMethodNotFound, method, response.Error.Message, nil)
+			}
 			return nil, response.Error
 		}
 		return response.Result, nil
 	case <-c.stopChan:
 		return nil, fmt.Errorf("client stopped")
+	case <-ctx.Done():
+		return nil, ctx.Err()
 	}
 }
 
+// PendingRequests returns the number of requests sent that are still waiting
+// for a response.
+func (c *JSONRPCClient) PendingRequests() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.pendingRequests)
+}
+
 // Notify sends a JSON-RPC notification (no response expected)
 func (c *JSONRPCClient) Notify(method string, params map[string]interface{}) error {
 	notification := JSONRPCNotification{
@@ -165,6 +243,56 @@ func (c *JSONRPCClient) Notify(method string, params map[string]interface{}) err
 	return c.sendMessage(notification)
 }
 
+// rpcCall describes a completed JSON-RPC call, as reported to observers.
+type rpcCall struct {
+	Method string
+	// Incoming is true for requests sent by the server and handled by the SDK.
+	Incoming bool
+	Params   map[string]interface{}
+	Start    time.Time
+	End      time.Time
+	Err      error
+}
+
+type rpcObserver struct {
+	id uint64
+	fn func(rpcCall)
+}
+
+// observe registers fn to be called after every request completes, in either
+// direction. The returned function unregisters it.
+func (c *JSONRPCClient) observe(fn func(rpcCall)) func() {
+	c.observersMux.Lock()
+	id := c.nextObserverID
+	c.nextObserverID++
+	c.observers = append(c.observers, rpcObserver{id: id, fn: fn})
+	c.observersMux.Unlock()
+
+	return func() {
+		c.observersMux.Lock()
+		defer c.observersMux.Unlock()
+		for i, o := range c.observers {
+			if o.id == id {
+				c.observers = append(c.observers[:i], c.observers[i+1:]...)
+				return
+			}
+		}
+	}
+}
+
+func (c *JSONRPCClient) observeCall(call rpcCall) {
+	c.observersMux.RLock()
+	observers := make([]func(rpcCall), len(c.observers))
+	for i, o := range c.observers {
+		observers[i] = o.fn
+	}
+	c.observersMux.RUnlock()
+
+	for _, fn := range observers {
+		fn(call)
+	}
+}
+
 // sendMessage writes a message to stdin
 func (c *JSONRPCClient) sendMessage(message interface{}) error {
 	data, err := json.Marshal(message)
@@ -175,6 +303,14 @@ func (c *JSONRPCClient) sendMessage(message interface{}) error {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
+	// Journal the frame first, so that it is on record even if the write
+	// never completes
+	if c.journal != nil {
+		if err := c.journal.append(data); err != nil {
+			return fmt.Errorf("failed to journal message: %w", err)
+		}
+	}
+
 	// Write Content-Length header + message
 	header := fmt.Sprintf("Content-Length: %d\r\n\r\n", len(data))
 	if _, err := c.stdin.Write([]byte(header)); err != nil {
@@ -190,42 +326,19 @@ func (c *JSONRPCClient) sendMessage(message interface{}) error {
 // readLoop reads messages from stdout in a background goroutine
 func (c *JSONRPCClient) readLoop() {
 	defer c.wg.Done()
+	defer close(c.closed)
 
 	reader := bufio.NewReader(c.stdout)
 
-	for c.running {
-		// Read Content-Length header
-		var contentLength int
-		for {
-			line, err := reader.ReadString('\n')
-			if err != nil {
-				// Only log unexpected errors (not EOF or closed pipe during shutdown)
-				if err != io.EOF && c.running {
-					fmt.Printf("Error reading header: %v\n", err)
-				}
-				return
-			}
-
-			// Check for blank line (end of headers)
-			if line == "\r\n" || line == "\n" {
-				break
-			}
-
-			// Parse Content-Length
-			var length int
-			if _, err := fmt.Sscanf(line, "Content-Length: %d", &length); err == nil {
-				contentLength = length
+	for c.running.Load() {
+		c.flow.wait(c.stopChan)
+		body, err := readFrame(reader)
+		if err != nil {
+			// Only log unexpected errors (not EOF or closed pipe during shutdown)
+			if err != io.EOF && c.running.Load() {
+				c.protocolErrors.record(ProtocolErrorFraming, "", err.Error(), nil)
+				fmt.Printf("Error reading message: %v\n", err)
 			}
-		}
-
-		if contentLength == 0 {
-			continue
-		}
-
-		// Read message body
-		body := make([]byte, contentLength)
-		if _, err := io.ReadFull(reader, body); err != nil {
-			fmt.Printf("Error reading body: %v\n", err)
 			return
 		}
 
@@ -249,6 +362,103 @@ func (c *JSONRPCClient) readLoop() {
 			c.handleNotification(&notification)
 			continue
 		}
+
+		message := "frame is not a request, response, or notification"
+		if err := json.Unmarshal(body, &json.RawMessage{}); err != nil {
+			message = "invalid JSON: " + err.Error()
+		}
+		c.protocolErrors.record(ProtocolErrorParse, "", message, body)
+	}
+}
+
+// maxHeaderLineLength bounds a single header line so a corrupt stream cannot
+// make the reader buffer unbounded amounts of data while looking for a newline.
+const maxHeaderLineLength = 8 * 1024
+
+// maxFrameSize bounds the Content-Length accepted for a single message.
+const maxFrameSize = 256 * 1024 * 1024
+
+// readFrame reads the next Content-Length framed message body from reader.
+//
+// Header names are matched case-insensitively and surrounding whitespace is
+// ignored, so "content-length:  42 " is accepted. Headers other than
+// Content-Length (such as Content-Type) are skipped. Header blocks without a
+// Content-Length, including stray blank lines between messages, are skipped.
+// Because reads go through a bufio.Reader, headers split across reads and
+// several messages arriving in a single read are handled transparently.
+//
+// Returns io.EOF if the stream ends cleanly between messages, and an error if
+// the stream ends mid-message or contains an invalid Content-Length.
+func readFrame(reader *bufio.Reader) ([]byte, error) {
+	for {
+		contentLength := -1
+		sawHeader := false
+		for {
+			line, err := readHeaderLine(reader)
+			if err != nil {
+				if err == io.EOF && sawHeader {
+					return nil, io.ErrUnexpectedEOF
+				}
+				return nil, err
+			}
+
+			// Blank line ends the header block
+			if strings.TrimSpace(line) == "" {
+				break
+			}
+			sawHeader = true
+
+			name, value, ok := strings.Cut(line, ":")
+			if !ok || !strings.EqualFold(strings.TrimSpace(name), "Content-Length") {
+				continue
+			}
+			length, err := strconv.Atoi(strings.TrimSpace(value))
+			if err != nil || length < 0 {
+				return nil, fmt.Errorf("invalid Content-Length header: %q", strings.TrimSpace(value))
+			}
+			if length > maxFrameSize {
+				return nil, fmt.Errorf("Content-Length %d exceeds maximum frame size %d", length, maxFrameSize)
+			}
+			contentLength = length
+		}
+
+		if contentLength <= 0 {
+			continue
+		}
+
+		body := make([]byte, contentLength)
+		if _, err := io.ReadFull(reader, body); err != nil {
+			if err == io.EOF {
+				err = io.ErrUnexpectedEOF
+			}
+			return nil, fmt.Errorf("failed to read message body: %w", err)
+		}
+		return body, nil
+	}
+}
+
+// readHeaderLine reads a single header line, including its terminating newline.
+func readHeaderLine(reader *bufio.Reader) (string, error) {
+	var line []byte
+	for {
+		chunk, err := reader.ReadSlice('\n')
+		line = append(line, chunk...)
+		if len(line) > maxHeaderLineLength {
+			return "", fmt.Errorf("header line exceeds %d bytes", maxHeaderLineLength)
+		}
+		switch err {
+		case nil:
+			return string(line), nil
+		case bufio.ErrBufferFull:
+			continue
+		case io.EOF:
+			if len(line) > 0 {
+				return "", io.ErrUnexpectedEOF
+			}
+			return "", io.EOF
+		default:
+			return "", err
+		}
 	}
 }
 
@@ -256,17 +466,21 @@ func (c *JSONRPCClient) readLoop() {
 func (c *JSONRPCClient) handleResponse(response *JSONRPCResponse) {
 	var id string
 	if err := json.Unmarshal(response.ID, &id); err != nil {
-		return // ignore responses with non-string IDs
+		// Ignore responses with non-string IDs.
+		c.protocolErrors.record(ProtocolErrorOrphanResponse, "", "response has a non-string ID "+string(response.ID), nil)
+		return
 	}
 	c.mu.Lock()
-	responseChan, ok := c.pendingRequests[id]
+	pending, ok := c.pendingRequests[id]
 	c.mu.Unlock()
 
-	if ok {
-		select {
-		case responseChan <- response:
-		default:
-		}
+	if !ok {
+		c.protocolErrors.record(ProtocolErrorOrphanResponse, "", "response to no pending request, with ID "+string(response.ID), nil)
+		return
+	}
+	select {
+	case pending.response <- response:
+	default:
 	}
 }
 
@@ -287,6 +501,7 @@ func (c *JSONRPCClient) handleRequest(request *JSONRPCRequest) {
 	c.mu.Unlock()
 
 	if handler == nil {
+		c.protocolErrors.record(ProtocolErrorUnhandledRequest, request.Method, "the server called a method the client does not handle", nil)
 		c.sendErrorResponse(request.ID, -32601, fmt.Sprintf("Method not found: %s", request.Method), nil)
 		return
 	}
@@ -298,15 +513,32 @@ func (c *JSONRPCClient) handleRequest(request *JSONRPCRequest) {
 			}
 		}()
 
+		start := time.Now()
+		var once sync.Once
+		respond := func(result map[string]interface{}, err *JSONRPCError) {
+			once.Do(func() {
+				call := rpcCall{Method: request.Method, Incoming: true, Params: request.Params, Start: start, End: time.Now()}
+				if err != nil {
+					call.Err = err
+				}
+				c.observeCall(call)
+				if err != nil {
+					c.sendErrorResponse(request.ID, err.Code, err.Message, err.Data)
+					return
+				}
+				if result == nil {
+					result = make(map[string]interface{})
+				}
+				c.sendResponse(request.ID, result)
+			})
+		}
+
 		result, err := handler(request.Params)
-		if err != nil {
-			c.sendErrorResponse(request.ID, err.Code, err.Message, err.Data)
+		if err != nil && err.handoff != nil {
+			err.handoff(respond)
 			return
 		}
-		if result == nil {
-			result = make(map[string]interface{})
-		}
-		c.sendResponse(request.ID, result)
+		respond(result, err)
 	}()
 }
 
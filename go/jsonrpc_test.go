@@ -0,0 +1,250 @@
+package copilot
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+	"testing/iotest"
+	"time"
+)
+
+func frame(body string) string {
+	return fmt.Sprintf("Content-Length: %d\r\n\r\n%s", len(body), body)
+}
+
+func readAllFrames(t *testing.T, r io.Reader) []string {
+	t.Helper()
+	reader := bufio.NewReader(r)
+	var bodies []string
+	for {
+		body, err := readFrame(reader)
+		if err == io.EOF {
+			return bodies
+		}
+		if err != nil {
+			t.Fatalf("Unexpected error after %d frames: %v", len(bodies), err)
+		}
+		bodies = append(bodies, string(body))
+	}
+}
+
+func TestReadFrame(t *testing.T) {
+	t.Run("reads pipelined messages from a single read", func(t *testing.T) {
+		input := frame(`{"a":1}`) + frame(`{"b":2}`) + frame(`{"c":3}`)
+		bodies := readAllFrames(t, strings.NewReader(input))
+		if len(bodies) != 3 || bodies[0] != `{"a":1}` || bodies[2] != `{"c":3}` {
+			t.Errorf("Unexpected bodies %v", bodies)
+		}
+	})
+
+	t.Run("reads headers and bodies split across reads", func(t *testing.T) {
+		input := frame(`{"jsonrpc":"2.0","method":"x"}`) + frame(`{}`)
+		bodies := readAllFrames(t, iotest.OneByteReader(strings.NewReader(input)))
+		if len(bodies) != 2 || bodies[0] != `{"jsonrpc":"2.0","method":"x"}` {
+			t.Errorf("Unexpected bodies %v", bodies)
+		}
+	})
+
+	t.Run("tolerates case, whitespace, extra headers, and bare newlines", func(t *testing.T) {
+		input := "content-length:   7  \r\nContent-Type: application/vscode-jsonrpc; charset=utf-8\r\n\r\n{\"a\":1}" +
+			"\r\n" + // stray blank line between messages
+			"Content-Length: 2\n\n{}"
+		bodies := readAllFrames(t, strings.NewReader(input))
+		if len(bodies) != 2 || bodies[0] != `{"a":1}` || bodies[1] != `{}` {
+			t.Errorf("Unexpected bodies %v", bodies)
+		}
+	})
+
+	t.Run("reports truncated messages", func(t *testing.T) {
+		reader := bufio.NewReader(strings.NewReader("Content-Length: 10\r\n\r\n{}"))
+		if _, err := readFrame(reader); err == nil || err == io.EOF {
+			t.Errorf("Expected unexpected EOF error, got %v", err)
+		}
+
+		reader = bufio.NewReader(strings.NewReader("Content-Length: 10\r\n"))
+		if _, err := readFrame(reader); err != io.ErrUnexpectedEOF {
+			t.Errorf("Expected io.ErrUnexpectedEOF for truncated headers, got %v", err)
+		}
+	})
+
+	t.Run("rejects invalid content lengths", func(t *testing.T) {
+		for _, header := range []string{"Content-Length: abc", "Content-Length: -5", fmt.Sprintf("Content-Length: %d", maxFrameSize+1)} {
+			reader := bufio.NewReader(strings.NewReader(header + "\r\n\r\n{}"))
+			if _, err := readFrame(reader); err == nil || err == io.EOF {
+				t.Errorf("Expected error for %q, got %v", header, err)
+			}
+		}
+	})
+
+	t.Run("rejects overlong header lines", func(t *testing.T) {
+		reader := bufio.NewReader(strings.NewReader("X-Junk: " + strings.Repeat("a", maxHeaderLineLength) + "\r\n\r\n"))
+		if _, err := readFrame(reader); err == nil {
+			t.Error("Expected error for overlong header line")
+		}
+	})
+}
+
+type nopWriteCloser struct{ io.Writer }
+
+func (nopWriteCloser) Close() error { return nil }
+
+func TestJSONRPCClient_PipelinedNotifications(t *testing.T) {
+	input := frame(`{"jsonrpc":"2.0","method":"one","params":{}}`) + frame(`{"jsonrpc":"2.0","method":"two","params":{}}`)
+
+	client := NewJSONRPCClient(nopWriteCloser{&bytes.Buffer{}}, io.NopCloser(strings.NewReader(input)))
+
+	var mu sync.Mutex
+	var methods []string
+	done := make(chan struct{})
+	client.SetNotificationHandler(func(method string, params map[string]interface{}) {
+		mu.Lock()
+		defer mu.Unlock()
+		methods = append(methods, method)
+		if len(methods) == 2 {
+			close(done)
+		}
+	})
+	client.Start()
+	defer client.Stop()
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Timed out waiting for notifications")
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if methods[0] != "one" || methods[1] != "two" {
+		t.Errorf("Expected notifications in order, got %v", methods)
+	}
+}
+
+func FuzzReadFrame(f *testing.F) {
+	f.Add([]byte(frame(`{"jsonrpc":"2.0","id":"1","result":{}}`)))
+	f.Add([]byte(frame(`{}`) + frame(`{"a":[1,2,3]}`)))
+	f.Add([]byte("content-length:  3 \n\n{}}"))
+	f.Add([]byte("Content-Length: 999999999999999999999\r\n\r\n"))
+	f.Add([]byte("\r\n\r\nContent-Type: x\r\n\r\n"))
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		// Arbitrary input must never panic or loop forever.
+		reader := bufio.NewReader(bytes.NewReader(data))
+		for i := 0; i < len(data)+1; i++ {
+			body, err := readFrame(reader)
+			if err != nil {
+				break
+			}
+			if len(body) == 0 {
+				t.Fatalf("readFrame returned an empty body without error")
+			}
+		}
+
+		// Any body, framed and split at arbitrary points, must round-trip.
+		framed := frame(string(data)) + frame(`{}`)
+		reader = bufio.NewReader(iotest.HalfReader(strings.NewReader(framed)))
+		if len(data) > 0 {
+			body, err := readFrame(reader)
+			if err != nil {
+				t.Fatalf("Failed to read framed body: %v", err)
+			}
+			if !bytes.Equal(body, data) {
+				t.Fatalf("Round trip mismatch: got %q, want %q", body, data)
+			}
+		}
+		body, err := readFrame(reader)
+		if err != nil || string(body) != `{}` {
+			t.Fatalf("Failed to read trailing frame: %q, %v", body, err)
+		}
+	})
+}
+
+// fakeServer plays the CLI side of a JSON-RPC connection in tests.
+type fakeServer struct {
+	writer io.Writer
+	mu     sync.Mutex
+}
+
+// newFakeServer starts a JSON-RPC client connected to a fake server that answers
+// each request with handle. The client is stopped when the test finishes.
+func newFakeServer(t *testing.T, handle func(method string, params map[string]interface{}) (map[string]interface{}, *JSONRPCError)) (*JSONRPCClient, *fakeServer) {
+	t.Helper()
+	requestsReader, requestsWriter := io.Pipe()
+	responsesReader, responsesWriter := io.Pipe()
+	server := &fakeServer{writer: responsesWriter}
+
+	go func() {
+		reader := bufio.NewReader(requestsReader)
+		for {
+			body, err := readFrame(reader)
+			if err != nil {
+				return
+			}
+			var request JSONRPCRequest
+			if err := json.Unmarshal(body, &request); err != nil || request.Method == "" {
+				continue
+			}
+			result, rpcErr := handle(request.Method, request.Params)
+			if result == nil && rpcErr == nil {
+				result = map[string]interface{}{}
+			}
+			server.send(JSONRPCResponse{JSONRPC: "2.0", ID: request.ID, Result: result, Error: rpcErr})
+		}
+	}()
+
+	client := NewJSONRPCClient(requestsWriter, responsesReader)
+	client.Start()
+	t.Cleanup(func() {
+		client.Stop()
+		requestsWriter.Close()
+		responsesWriter.Close()
+	})
+	return client, server
+}
+
+// notify sends a notification from the fake server to the client.
+func (s *fakeServer) notify(method string, params map[string]interface{}) {
+	s.send(JSONRPCNotification{JSONRPC: "2.0", Method: method, Params: params})
+}
+
+func (s *fakeServer) send(message interface{}) {
+	data, _ := json.Marshal(message)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	io.WriteString(s.writer, frame(string(data)))
+}
+
+// newFakeSession returns a session wired through a Client to a fake server, so
+// that events the server emits with emitEvent reach the session's handlers.
+func newFakeSession(t *testing.T, handle func(server *fakeServer, method string, params map[string]interface{}) (map[string]interface{}, *JSONRPCError)) (*Session, *fakeServer) {
+	t.Helper()
+	var server *fakeServer
+	ready := make(chan struct{})
+	rpc, server := newFakeServer(t, func(method string, params map[string]interface{}) (map[string]interface{}, *JSONRPCError) {
+		<-ready
+		return handle(server, method, params)
+	})
+	session := NewSession("s1", rpc, "")
+	client := &Client{client: rpc, state: StateConnected, sessions: map[string]*Session{"s1": session}}
+	client.setupNotificationHandler(client.client)
+	close(ready)
+	return session, server
+}
+
+// emitEvent sends a session.event notification for session s1.
+func (s *fakeServer) emitEvent(eventType SessionEventType, id string, data map[string]interface{}) {
+	s.notify("session.event", map[string]interface{}{
+		"sessionId": "s1",
+		"event": map[string]interface{}{
+			"id":        id,
+			"type":      string(eventType),
+			"timestamp": time.Now().Format(time.RFC3339Nano),
+			"data":      data,
+		},
+	})
+}
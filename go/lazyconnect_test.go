@@ -0,0 +1,173 @@
+package copilot
+
+import (
+	"bufio"
+	"encoding/json"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// startFakeTCPServer serves ping and status.get over TCP and counts connections.
+func startFakeTCPServer(t *testing.T) (port int, connections *atomic.Int32) {
+	return startFakeTCPServerVersion(t, GetSdkProtocolVersion(), nil)
+}
+
+// startFakeTCPServerVersion is startFakeTCPServer reporting protocolVersion,
+// and, if closed is not nil, incrementing it as each connection closes.
+func startFakeTCPServerVersion(t *testing.T, protocolVersion int, closed *atomic.Int32) (port int, connections *atomic.Int32) {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	t.Cleanup(func() { listener.Close() })
+	connections = &atomic.Int32{}
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			connections.Add(1)
+			go func() {
+				defer conn.Close()
+				if closed != nil {
+					defer closed.Add(1)
+				}
+				reader := bufio.NewReader(conn)
+				for {
+					body, err := readFrame(reader)
+					if err != nil {
+						return
+					}
+					var request JSONRPCRequest
+					json.Unmarshal(body, &request)
+					result := map[string]interface{}{"version": "test", "protocolVersion": protocolVersion}
+					data, _ := json.Marshal(JSONRPCResponse{JSONRPC: "2.0", ID: request.ID, Result: result})
+					conn.Write([]byte(frame(string(data))))
+				}
+			}()
+		}
+	}()
+	return listener.Addr().(*net.TCPAddr).Port, connections
+}
+
+func TestClient_LazyConnect(t *testing.T) {
+	t.Run("connects once on first use across concurrent callers", func(t *testing.T) {
+		port, connections := startFakeTCPServer(t)
+		client := NewClient(&ClientOptions{CLIUrl: strconv.Itoa(port), LazyConnect: true})
+		defer client.ForceStop()
+
+		if connections.Load() != 0 {
+			t.Fatal("Expected no connection before first use")
+		}
+
+		var wg sync.WaitGroup
+		errs := make(chan error, 10)
+		for i := 0; i < 10; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				if _, err := client.GetStatus(); err != nil {
+					errs <- err
+				}
+			}()
+		}
+		wg.Wait()
+		close(errs)
+		for err := range errs {
+			t.Errorf("Unexpected error: %v", err)
+		}
+
+		if n := connections.Load(); n != 1 {
+			t.Errorf("Expected exactly 1 connection, got %d", n)
+		}
+		if client.GetState() != StateConnected {
+			t.Errorf("Expected connected state, got %v", client.GetState())
+		}
+	})
+
+	t.Run("Start shares the connection made on first use", func(t *testing.T) {
+		port, connections := startFakeTCPServer(t)
+		client := NewClient(&ClientOptions{CLIUrl: strconv.Itoa(port), LazyConnect: true})
+		defer client.ForceStop()
+
+		var wg sync.WaitGroup
+		errs := make(chan error, 10)
+		for i := 0; i < 10; i++ {
+			wg.Add(1)
+			go func(start bool) {
+				defer wg.Done()
+				var err error
+				if start {
+					err = client.Start()
+				} else {
+					_, err = client.GetStatus()
+				}
+				if err != nil {
+					errs <- err
+				}
+			}(i%2 == 0)
+		}
+		wg.Wait()
+		close(errs)
+		for err := range errs {
+			t.Errorf("Unexpected error: %v", err)
+		}
+		if n := connections.Load(); n != 1 {
+			t.Errorf("Expected exactly 1 connection, got %d", n)
+		}
+	})
+
+	t.Run("a failed handshake leaves nothing connected", func(t *testing.T) {
+		var closed atomic.Int32
+		port, connections := startFakeTCPServerVersion(t, GetSdkProtocolVersion()+1, &closed)
+		client := NewClient(&ClientOptions{CLIUrl: strconv.Itoa(port), LazyConnect: true})
+		defer client.ForceStop()
+
+		for i := 0; i < 2; i++ {
+			if _, err := client.GetStatus(); err == nil || !strings.Contains(err.Error(), "protocol version mismatch") {
+				t.Fatalf("Expected a protocol version mismatch, got %v", err)
+			}
+			if client.GetState() != StateError || client.rpc() != nil || client.client != nil {
+				t.Errorf("Expected no connection to be published, got state %v", client.GetState())
+			}
+		}
+		if n := connections.Load(); n != 2 {
+			t.Errorf("Expected each call to retry the connection, got %d connections", n)
+		}
+		deadline := time.Now().Add(time.Second)
+		for closed.Load() != 2 && time.Now().Before(deadline) {
+			time.Sleep(time.Millisecond)
+		}
+		if n := closed.Load(); n != 2 {
+			t.Errorf("Expected the failed connections to be closed, got %d closed", n)
+		}
+	})
+
+	t.Run("without LazyConnect, status calls require Start", func(t *testing.T) {
+		port, connections := startFakeTCPServer(t)
+		client := NewClient(&ClientOptions{CLIUrl: strconv.Itoa(port)})
+		if _, err := client.GetStatus(); err == nil {
+			t.Error("Expected not connected error")
+		}
+		if connections.Load() != 0 {
+			t.Error("Expected no connection")
+		}
+	})
+
+	t.Run("panics when AutoStart is disabled", func(t *testing.T) {
+		defer func() {
+			if r := recover(); r == nil {
+				t.Error("Expected panic for LazyConnect with AutoStart disabled")
+			}
+		}()
+		NewClient(&ClientOptions{LazyConnect: true, AutoStart: Bool(false)})
+	})
+}
@@ -0,0 +1,146 @@
+package copilot
+
+import (
+	"encoding/json"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// WithLenientArguments coerces arguments that are slightly off from the
+// tool's schema into the expected types before they are validated and
+// decoded, instead of failing the call. Models sometimes quote numbers and
+// booleans, pass a single value where a list is expected, or wrap JSON in a
+// markdown code fence. With this option:
+//
+//   - a string where a number or integer is expected is parsed, so "42"
+//     becomes 42;
+//   - "true" and "false" where a boolean is expected become booleans;
+//   - a single value where an array is expected becomes a one-element array;
+//   - a string where an object or array is expected, including the arguments
+//     as a whole, is decoded as JSON after trimming a surrounding code fence.
+//
+// Values that cannot be coerced are left as they are, so they still fail
+// validation. The handler's [ToolInvocation] carries the coerced arguments.
+//
+// Example:
+//
+//	tool := copilot.DefineTool("add_labels", "Add labels to an issue", addLabels,
+//	    copilot.WithLenientArguments())
+func WithLenientArguments() ToolOption {
+	return func(o *toolOptions) {
+		o.lenientArguments = true
+	}
+}
+
+// unfenceArguments returns arguments sent as a string, such as a JSON object
+// in a code fence, decoded, so that argument transformers can run on them.
+func unfenceArguments(arguments interface{}) interface{} {
+	raw, ok := arguments.(string)
+	if !ok {
+		return arguments
+	}
+	var decoded map[string]interface{}
+	if err := json.Unmarshal([]byte(trimCodeFence(raw)), &decoded); err != nil {
+		return arguments
+	}
+	return decoded
+}
+
+// coerceArguments returns a copy of arguments with the values that do not
+// match schema coerced into its types where possible.
+func coerceArguments(schema map[string]interface{}, arguments interface{}) interface{} {
+	return coerceValue(schema, cloneJSONValue(unfenceArguments(arguments)))
+}
+
+// coerceValue coerces value into the types of schema, modifying maps and
+// slices in place.
+func coerceValue(schema map[string]interface{}, value interface{}) interface{} {
+	if types := schemaTypes(schema); len(types) > 0 && value != nil && !matchesAnyType(types, value) {
+		value = coerceToTypes(types, value)
+	}
+
+	switch v := value.(type) {
+	case map[string]interface{}:
+		properties, _ := schema["properties"].(map[string]interface{})
+		additional, _ := schema["additionalProperties"].(map[string]interface{})
+		for name, fieldValue := range v {
+			if property, ok := properties[name].(map[string]interface{}); ok {
+				v[name] = coerceValue(property, fieldValue)
+			} else if additional != nil {
+				v[name] = coerceValue(additional, fieldValue)
+			}
+		}
+	case []interface{}:
+		if items, ok := schema["items"].(map[string]interface{}); ok {
+			for i, item := range v {
+				v[i] = coerceValue(items, item)
+			}
+		}
+	}
+	return value
+}
+
+// coerceToTypes converts value, which matches none of types, into the first
+// of them it can be converted to. It returns value unchanged if there is none.
+func coerceToTypes(types []string, value interface{}) interface{} {
+	if s, ok := value.(string); ok {
+		for _, t := range types {
+			if coerced, ok := coerceString(t, s); ok {
+				return coerced
+			}
+		}
+	}
+	for _, t := range types {
+		if t == "array" {
+			return []interface{}{value}
+		}
+	}
+	return value
+}
+
+// coerceString converts s into a value of the JSON schema type t.
+func coerceString(t, s string) (interface{}, bool) {
+	trimmed := strings.TrimSpace(s)
+	switch t {
+	case "number", "integer":
+		n, err := strconv.ParseFloat(trimmed, 64)
+		if err != nil || math.IsInf(n, 0) || math.IsNaN(n) || (t == "integer" && n != math.Trunc(n)) {
+			return nil, false
+		}
+		return n, true
+	case "boolean":
+		switch strings.ToLower(trimmed) {
+		case "true":
+			return true, true
+		case "false":
+			return false, true
+		}
+	case "object", "array":
+		var decoded interface{}
+		if err := json.Unmarshal([]byte(trimCodeFence(trimmed)), &decoded); err != nil {
+			return nil, false
+		}
+		if matchesAnyType([]string{t}, decoded) {
+			return decoded, true
+		}
+	}
+	return nil, false
+}
+
+// trimCodeFence returns s without a surrounding markdown code fence, such as
+// "```json\n{...}\n```". Other strings are returned trimmed of whitespace.
+func trimCodeFence(s string) string {
+	s = strings.TrimSpace(s)
+	if !strings.HasPrefix(s, "```") {
+		return s
+	}
+	s = strings.TrimPrefix(s, "```")
+	if newline := strings.IndexByte(s, '\n'); newline >= 0 {
+		s = s[newline+1:]
+	} else {
+		// A fence on one line, such as "```json{...}```".
+		s = strings.TrimPrefix(s, "json")
+	}
+	return strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(s), "```"))
+}
@@ -0,0 +1,98 @@
+package copilot
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestLenientArguments(t *testing.T) {
+	type labelParams struct {
+		Number int               `json:"number"`
+		Labels []string          `json:"labels"`
+		Notify bool              `json:"notify"`
+		Weight float64           `json:"weight,omitempty"`
+		Meta   map[string]string `json:"meta,omitempty"`
+		Steps  []struct {
+			Limit int `json:"limit"`
+		} `json:"steps,omitempty"`
+	}
+
+	var got labelParams
+	handler := func(params labelParams, inv ToolInvocation) (string, error) {
+		got = params
+		return "ok", nil
+	}
+	lenient := DefineTool("add_labels", "Add labels", handler, WithLenientArguments())
+
+	t.Run("coerces common mistakes", func(t *testing.T) {
+		got = labelParams{}
+		original := map[string]interface{}{
+			"number": "42",
+			"labels": "bug",
+			"notify": "TRUE",
+			"weight": " 1.5 ",
+			"meta":   "```json\n{\"source\": \"triage\"}\n```",
+			"steps":  map[string]interface{}{"limit": "3"},
+		}
+		result, err := lenient.Handler(ToolInvocation{Arguments: original})
+		if err != nil || result.ResultType != "success" {
+			t.Fatalf("Expected success, got %+v, %v", result, err)
+		}
+		if got.Number != 42 || !reflect.DeepEqual(got.Labels, []string{"bug"}) || !got.Notify || got.Weight != 1.5 {
+			t.Errorf("Unexpected params %+v", got)
+		}
+		if got.Meta["source"] != "triage" || len(got.Steps) != 1 || got.Steps[0].Limit != 3 {
+			t.Errorf("Expected nested values to be coerced, got %+v", got)
+		}
+		if original["number"] != "42" {
+			t.Errorf("Expected original arguments to be unchanged, got %v", original)
+		}
+	})
+
+	t.Run("decodes fenced arguments", func(t *testing.T) {
+		got = labelParams{}
+		args := "```json\n{\"number\": 7, \"labels\": [\"p1\"], \"notify\": false}\n```"
+		if result, err := lenient.Handler(ToolInvocation{Arguments: args}); err != nil || result.ResultType != "success" {
+			t.Fatalf("Expected success, got %+v, %v", result, err)
+		}
+		if got.Number != 7 || got.Labels[0] != "p1" {
+			t.Errorf("Unexpected params %+v", got)
+		}
+	})
+
+	t.Run("leaves values it cannot coerce to validation", func(t *testing.T) {
+		result, err := lenient.Handler(ToolInvocation{Arguments: map[string]interface{}{
+			"number": "4.5",
+			"labels": []interface{}{"bug"},
+			"notify": "yes",
+		}})
+		if err != nil || result.ResultType != "failure" {
+			t.Fatalf("Expected an invalid-arguments failure, got %+v, %v", result, err)
+		}
+		if want := "- notify: must be a boolean, got string\n- number: must be an integer, got string"; !strings.Contains(result.TextResultForLLM, want) {
+			t.Errorf("Expected errors for notify and number, got %q", result.TextResultForLLM)
+		}
+	})
+
+	t.Run("is off by default", func(t *testing.T) {
+		strict := DefineTool("add_labels", "Add labels", handler)
+		result, _ := strict.Handler(ToolInvocation{Arguments: map[string]interface{}{"number": "42", "labels": "bug", "notify": true}})
+		if result.ResultType != "failure" {
+			t.Errorf("Expected strict arguments to fail, got %+v", result)
+		}
+	})
+}
+
+func TestTrimCodeFence(t *testing.T) {
+	for input, want := range map[string]string{
+		"```json\n{\"a\": 1}\n```": `{"a": 1}`,
+		"```\n[1]\n```":            "[1]",
+		"```json{\"a\": 1}```":     `{"a": 1}`,
+		"  {\"a\": 1} ":            `{"a": 1}`,
+	} {
+		if got := trimCodeFence(input); got != want {
+			t.Errorf("trimCodeFence(%q) = %q, want %q", input, got, want)
+		}
+	}
+}
@@ -0,0 +1,172 @@
+package copilot
+
+import (
+	"context"
+	"log/slog"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+)
+
+// LogNotificationMethods are the notifications the server sends log and
+// telemetry records with, which a [Client] turns into [LogRecord] values.
+var LogNotificationMethods = []string{"log", "session.log", "telemetry", "notifications/message"}
+
+// LogRecord is a log or telemetry record the server sent. See
+// [ClientOptions.Logger] and [ClientOptions.OnLogRecord].
+type LogRecord struct {
+	// Time is when the server logged the record, or when it was received if
+	// the server did not say.
+	Time  time.Time
+	Level slog.Level
+	// Method is the notification the record came in, such as "telemetry".
+	Method  string
+	Message string
+	// SessionID is the session the record is about, if any.
+	SessionID string
+	// Source is the server component that logged the record, if known.
+	Source string
+	// Attrs holds the record's other fields, such as a telemetry event's
+	// properties and measurements.
+	Attrs map[string]interface{}
+}
+
+// logRecordFields are the notification fields that map to [LogRecord]
+// fields rather than to its Attrs.
+var logRecordFields = map[string]bool{
+	"level": true, "severity": true, "message": true, "msg": true, "name": true,
+	"timestamp": true, "time": true, "sessionId": true,
+	"logger": true, "source": true, "category": true,
+	"data": true, "attributes": true, "properties": true, "measurements": true,
+}
+
+// parseLogRecord returns the record a log or telemetry notification carries,
+// reporting false for other notifications.
+func parseLogRecord(method string, params map[string]interface{}) (LogRecord, bool) {
+	if !slices.Contains(LogNotificationMethods, method) {
+		return LogRecord{}, false
+	}
+
+	record := LogRecord{Time: time.Now(), Level: slog.LevelInfo, Method: method, Attrs: make(map[string]interface{})}
+	if method == "telemetry" {
+		record.Level = slog.LevelDebug
+	}
+	if level, ok := params["level"]; ok {
+		record.Level = parseLogLevel(level)
+	} else if severity, ok := params["severity"]; ok {
+		record.Level = parseLogLevel(severity)
+	}
+	record.Message = firstString(params, "message", "msg", "name")
+	record.SessionID, _ = params["sessionId"].(string)
+	record.Source = firstString(params, "logger", "source", "category")
+	if stamp := firstString(params, "timestamp", "time"); stamp != "" {
+		if t, err := time.Parse(time.RFC3339Nano, stamp); err == nil {
+			record.Time = t
+		}
+	}
+
+	for _, key := range []string{"data", "attributes", "properties", "measurements"} {
+		switch value := params[key].(type) {
+		case nil:
+		case map[string]interface{}:
+			for k, v := range value {
+				record.Attrs[k] = v
+			}
+		case string:
+			// MCP-style log messages carry their text as data.
+			if record.Message == "" {
+				record.Message = value
+			} else {
+				record.Attrs[key] = value
+			}
+		default:
+			record.Attrs[key] = value
+		}
+	}
+	for key, value := range params {
+		if !logRecordFields[key] {
+			record.Attrs[key] = value
+		}
+	}
+	return record, true
+}
+
+// parseLogLevel maps a level name, such as "warning" or "fatal", or an
+// [slog.Level] number to a level. Unknown levels are [slog.LevelInfo].
+func parseLogLevel(level interface{}) slog.Level {
+	switch level := level.(type) {
+	case float64:
+		return slog.Level(level)
+	case string:
+		switch strings.ToLower(level) {
+		case "trace", "verbose":
+			return slog.LevelDebug - 4
+		case "debug":
+			return slog.LevelDebug
+		case "warn", "warning":
+			return slog.LevelWarn
+		case "error":
+			return slog.LevelError
+		case "critical", "fatal", "alert", "emergency":
+			return slog.LevelError + 4
+		}
+	}
+	return slog.LevelInfo
+}
+
+func firstString(params map[string]interface{}, keys ...string) string {
+	for _, key := range keys {
+		if value, ok := params[key].(string); ok && value != "" {
+			return value
+		}
+	}
+	return ""
+}
+
+// handleLogRecord passes a record from the server to the client's logger
+// and OnLogRecord callback.
+func (c *Client) handleLogRecord(record LogRecord) {
+	if c.options.Logger != nil {
+		record.log(c.options.Logger)
+	}
+	if c.options.OnLogRecord != nil {
+		c.options.OnLogRecord(record)
+	}
+}
+
+// log writes the record to logger, with the session and source as the
+// sessionId and source attributes, and the record's Attrs in a server group.
+func (r LogRecord) log(logger *slog.Logger) {
+	ctx := context.Background()
+	if !logger.Enabled(ctx, r.Level) {
+		return
+	}
+	message := r.Message
+	if message == "" {
+		message = r.Method
+	}
+	var attrs []slog.Attr
+	if r.SessionID != "" {
+		attrs = append(attrs, slog.String("sessionId", r.SessionID))
+	}
+	if r.Source != "" {
+		attrs = append(attrs, slog.String("source", r.Source))
+	}
+	if len(r.Attrs) > 0 {
+		keys := make([]string, 0, len(r.Attrs))
+		for key := range r.Attrs {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		group := make([]interface{}, 0, len(keys))
+		for _, key := range keys {
+			group = append(group, slog.Any(key, r.Attrs[key]))
+		}
+		attrs = append(attrs, slog.Group("server", group...))
+	}
+	record := slog.NewRecord(r.Time, r.Level, message, 0)
+	record.AddAttrs(attrs...)
+	// Like slog.Logger, drop records the handler fails to write.
+	_ = logger.Handler().Handle(ctx, record)
+}
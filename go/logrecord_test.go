@@ -0,0 +1,105 @@
+package copilot
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestParseLogRecord(t *testing.T) {
+	t.Run("maps log notifications", func(t *testing.T) {
+		record, ok := parseLogRecord("session.log", map[string]interface{}{
+			"level":     "warning",
+			"message":   "tool slow",
+			"sessionId": "s1",
+			"logger":    "tools",
+			"timestamp": "2024-05-01T12:00:00Z",
+			"data":      map[string]interface{}{"tool": "search"},
+			"elapsedMs": float64(1200),
+		})
+		if !ok || record.Level != slog.LevelWarn || record.Message != "tool slow" || record.SessionID != "s1" || record.Source != "tools" {
+			t.Fatalf("Unexpected record %+v", record)
+		}
+		if !record.Time.Equal(time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)) {
+			t.Errorf("Expected the server's time, got %v", record.Time)
+		}
+		if record.Attrs["tool"] != "search" || record.Attrs["elapsedMs"] != float64(1200) || len(record.Attrs) != 2 {
+			t.Errorf("Unexpected attributes %+v", record.Attrs)
+		}
+	})
+
+	t.Run("maps telemetry and MCP-style messages", func(t *testing.T) {
+		record, _ := parseLogRecord("telemetry", map[string]interface{}{
+			"name":         "model_call",
+			"properties":   map[string]interface{}{"model": "gpt-5"},
+			"measurements": map[string]interface{}{"latencyMs": float64(300)},
+		})
+		if record.Level != slog.LevelDebug || record.Message != "model_call" || record.Attrs["model"] != "gpt-5" || record.Attrs["latencyMs"] != float64(300) {
+			t.Errorf("Unexpected record %+v", record)
+		}
+
+		record, _ = parseLogRecord("notifications/message", map[string]interface{}{"level": "critical", "data": "disk full"})
+		if record.Level != slog.LevelError+4 || record.Message != "disk full" {
+			t.Errorf("Unexpected record %+v", record)
+		}
+	})
+
+	t.Run("ignores other notifications", func(t *testing.T) {
+		if _, ok := parseLogRecord("session.event", map[string]interface{}{}); ok {
+			t.Error("Expected session events not to be log records")
+		}
+	})
+}
+
+func TestClient_LogRecords(t *testing.T) {
+	var mu sync.Mutex
+	var buf bytes.Buffer
+	records := make(chan LogRecord, 1)
+	rpc, server := newFakeServer(t, func(method string, params map[string]interface{}) (map[string]interface{}, *JSONRPCError) {
+		return nil, nil
+	})
+	client := &Client{client: rpc, state: StateConnected, sessions: make(map[string]*Session), options: ClientOptions{
+		Logger: slog.New(slog.NewJSONHandler(lockedWriter{&mu, &buf}, &slog.HandlerOptions{Level: slog.LevelInfo})),
+		OnLogRecord: func(record LogRecord) {
+			records <- record
+		},
+	}}
+	client.setupNotificationHandler(client.client)
+
+	server.notify("log", map[string]interface{}{"level": "debug", "message": "verbose"})
+	<-records
+	server.notify("log", map[string]interface{}{"level": "error", "message": "auth failed", "sessionId": "s1", "data": map[string]interface{}{"status": float64(401)}})
+	if record := <-records; record.Message != "auth failed" {
+		t.Fatalf("Unexpected record %+v", record)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 1 {
+		t.Fatalf("Expected only the error to pass the logger's level, got %q", buf.String())
+	}
+	var logged map[string]interface{}
+	if err := json.Unmarshal([]byte(lines[0]), &logged); err != nil {
+		t.Fatal(err)
+	}
+	attrs, _ := logged["server"].(map[string]interface{})
+	if logged["level"] != "ERROR" || logged["msg"] != "auth failed" || logged["sessionId"] != "s1" || attrs["status"] != float64(401) {
+		t.Errorf("Unexpected log line %v", logged)
+	}
+}
+
+type lockedWriter struct {
+	mu  *sync.Mutex
+	buf *bytes.Buffer
+}
+
+func (w lockedWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.buf.Write(p)
+}
@@ -0,0 +1,324 @@
+package copilot
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+// defaultMaxRecalledMemories is the default for [MemoryConfig.MaxRecalled].
+const defaultMaxRecalledMemories = 10
+
+// MemoryConfig enables long-term memory for a session: facts about the user are
+// recalled into the prompt at the start of every turn, and new facts are
+// extracted after every turn.
+//
+// Example:
+//
+//	store, _ := copilot.NewFileMemoryStore("./memories")
+//	session, err := client.CreateSession(&copilot.SessionConfig{
+//	    Memory: &copilot.MemoryConfig{
+//	        Store:     store,
+//	        UserID:    user.ID,
+//	        Extractor: copilot.NewModelMemoryExtractor(client, &copilot.SessionConfig{Model: "gpt-5-mini"}),
+//	    },
+//	})
+type MemoryConfig struct {
+	// Store persists the memories. Required.
+	Store MemoryStore
+	// UserID scopes memories to a user. Only this user's memories are recalled,
+	// and extracted memories are stored for this user. Required.
+	UserID string
+	// Extractor, if set, is called after every turn to extract new memories from
+	// it. See [ExtractExplicitMemories] and [NewModelMemoryExtractor].
+	Extractor MemoryExtractor
+	// MaxRecalled is the maximum number of memories added to each prompt.
+	// Defaults to 10. Set DisableRecall to add none.
+	MaxRecalled int
+	// DisableRecall stops memories from being added to prompts, for sessions that
+	// should only extract them.
+	DisableRecall bool
+	// OnError, if set, is called with errors from recalling, extracting, or
+	// storing memories. These errors never fail the turn.
+	OnError func(err error)
+}
+
+func (c *MemoryConfig) validate() error {
+	if c.Store == nil {
+		return errors.New("memory: Store is required")
+	}
+	if c.UserID == "" {
+		return errors.New("memory: UserID is required")
+	}
+	return nil
+}
+
+func (c *MemoryConfig) reportError(err error) {
+	if c.OnError != nil {
+		c.OnError(err)
+	}
+}
+
+// MemoryExtractionRequest describes a completed turn to a [MemoryExtractor].
+type MemoryExtractionRequest struct {
+	UserID    string
+	SessionID string
+	// Prompt is the user's prompt, without memories or other SDK-added instructions.
+	Prompt string
+	// Response is the assistant's final message of the turn.
+	Response string
+	// Existing are the memories already stored for the user.
+	Existing []Memory
+}
+
+// MemoryExtractor returns the facts about the user worth remembering from a
+// turn. Facts already stored for the user, ignoring case, are skipped.
+type MemoryExtractor func(request MemoryExtractionRequest) ([]string, error)
+
+// explicitMemoryPatterns match statements in which the user asks to be
+// remembered or states a preference.
+var explicitMemoryPatterns = []struct {
+	pattern *regexp.Regexp
+	format  string
+}{
+	{regexp.MustCompile(`(?i)^(?:please\s+)?(?:always\s+)?remember(?:\s+that\s+|\s*:\s*)(.+)$`), "%s"},
+	{regexp.MustCompile(`(?i)^i\s+(?:always\s+|usually\s+)?prefer\s+(.+)$`), "The user prefers %s"},
+}
+
+// ExtractExplicitMemories is a [MemoryExtractor] that stores only what the user
+// explicitly asks to be remembered in a prompt ("remember that …") and stated
+// preferences ("I prefer …"). It needs no model call.
+func ExtractExplicitMemories(request MemoryExtractionRequest) ([]string, error) {
+	var facts []string
+	for _, sentence := range splitSentences(request.Prompt) {
+		for _, p := range explicitMemoryPatterns {
+			match := p.pattern.FindStringSubmatch(sentence)
+			if match == nil {
+				continue
+			}
+			args := make([]interface{}, len(match)-1)
+			for i, group := range match[1:] {
+				args[i] = strings.TrimSpace(group)
+			}
+			facts = append(facts, capitalize(fmt.Sprintf(p.format, args...)))
+			break
+		}
+	}
+	return facts, nil
+}
+
+func splitSentences(text string) []string {
+	sentences := strings.FieldsFunc(text, func(r rune) bool {
+		return r == '.' || r == '!' || r == '?' || r == '\n' || r == ';'
+	})
+	result := sentences[:0]
+	for _, sentence := range sentences {
+		if sentence = strings.TrimSpace(sentence); sentence != "" {
+			result = append(result, sentence)
+		}
+	}
+	return result
+}
+
+func capitalize(s string) string {
+	r, size := utf8.DecodeRuneInString(s)
+	if r == utf8.RuneError {
+		return s
+	}
+	return string(unicode.ToUpper(r)) + s[size:]
+}
+
+// extractedMemories is the response format of the model memory extractor.
+type extractedMemories struct {
+	Memories []string `json:"memories" jsonschema:"new long-term facts about the user, one short sentence each"`
+}
+
+// NewModelMemoryExtractor returns a [MemoryExtractor] that asks a model to
+// extract facts from each turn. Every extraction runs in a new session created
+// on client with config, which is destroyed afterwards; a small, fast model is
+// usually sufficient. config may be nil.
+func NewModelMemoryExtractor(client *Client, config *SessionConfig) MemoryExtractor {
+	return func(request MemoryExtractionRequest) ([]string, error) {
+		var sessionConfig SessionConfig
+		if config != nil {
+			sessionConfig = *config
+		}
+		sessionConfig.Memory = nil
+
+		session, err := client.CreateSession(&sessionConfig)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create memory extraction session: %w", err)
+		}
+		defer session.Destroy()
+
+		turn, err := session.SendAndCollect(MessageOptions{
+			Prompt:         buildMemoryExtractionPrompt(request),
+			ResponseFormat: JSONResponseFormatFor[extractedMemories](),
+		}, 0)
+		if err != nil {
+			return nil, fmt.Errorf("failed to extract memories: %w", err)
+		}
+		var result extractedMemories
+		if err := turn.DecodeJSON(&result); err != nil {
+			return nil, fmt.Errorf("failed to extract memories: %w", err)
+		}
+		return result.Memories, nil
+	}
+}
+
+func buildMemoryExtractionPrompt(request MemoryExtractionRequest) string {
+	var b strings.Builder
+	b.WriteString("Extract long-term facts about the user from the conversation turn below that " +
+		"would help in future, unrelated conversations: preferences, their role and expertise, " +
+		"and conventions they follow. Only include facts the user stated or clearly implied. " +
+		"Do not include facts about the current task, and do not repeat known facts. " +
+		"Return an empty list if there is nothing worth remembering.\n")
+	if len(request.Existing) > 0 {
+		b.WriteString("\nKnown facts:\n")
+		for _, memory := range request.Existing {
+			fmt.Fprintf(&b, "- %s\n", memory.Content)
+		}
+	}
+	fmt.Fprintf(&b, "\nUser:\n%s\n\nAssistant:\n%s\n", request.Prompt, request.Response)
+	return b.String()
+}
+
+// memoryState tracks the memory configuration and the turn in flight.
+type memoryState struct {
+	config   *MemoryConfig
+	pending  bool
+	prompt   string
+	response string
+}
+
+// registerMemory enables memory for this session.
+func (s *Session) registerMemory(config *MemoryConfig) {
+	if config == nil {
+		return
+	}
+	s.memoryMux.Lock()
+	s.memory.config = config
+	s.memoryMux.Unlock()
+	if config.Extractor != nil {
+		s.On(s.trackMemoryTurn)
+	}
+}
+
+// recallMemories adds the user's memories most relevant to prompt to params,
+// and starts tracking the turn for extraction.
+func (s *Session) recallMemories(prompt string, params map[string]interface{}) {
+	s.memoryMux.Lock()
+	config := s.memory.config
+	if config == nil {
+		s.memoryMux.Unlock()
+		return
+	}
+	s.memory.pending = true
+	s.memory.prompt = prompt
+	s.memory.response = ""
+	s.memoryMux.Unlock()
+
+	if config.DisableRecall {
+		return
+	}
+	limit := config.MaxRecalled
+	if limit <= 0 {
+		limit = defaultMaxRecalledMemories
+	}
+	memories, err := config.Store.Search(config.UserID, prompt, limit)
+	if err != nil {
+		config.reportError(fmt.Errorf("failed to recall memories: %w", err))
+		return
+	}
+	if len(memories) == 0 {
+		return
+	}
+
+	var b strings.Builder
+	b.WriteString("Facts remembered about the user from earlier conversations (use them when relevant):")
+	for _, memory := range memories {
+		fmt.Fprintf(&b, "\n- %s", memory.Content)
+	}
+	params["prompt"] = appendPromptInstructions(params["prompt"].(string), b.String())
+}
+
+// trackMemoryTurn records the assistant's response and extracts memories once
+// the session becomes idle.
+func (s *Session) trackMemoryTurn(event SessionEvent) {
+	s.memoryMux.Lock()
+	defer s.memoryMux.Unlock()
+	if !s.memory.pending {
+		return
+	}
+	switch event.Type {
+	case AssistantMessage:
+		// Messages from sub-agents are not the turn's response.
+		if event.Data.Content != nil && event.Data.ParentToolCallID == nil {
+			s.memory.response = *event.Data.Content
+		}
+	case SessionIdle:
+		request := MemoryExtractionRequest{
+			UserID:    s.memory.config.UserID,
+			SessionID: s.SessionID,
+			Prompt:    s.memory.prompt,
+			Response:  s.memory.response,
+		}
+		s.memory.pending = false
+		// Extraction may call a model, so it must not block event dispatch.
+		go s.extractMemories(s.memory.config, request)
+	}
+}
+
+func (s *Session) extractMemories(config *MemoryConfig, request MemoryExtractionRequest) {
+	existing, err := config.Store.List(config.UserID)
+	if err != nil {
+		config.reportError(fmt.Errorf("failed to extract memories: %w", err))
+		return
+	}
+	request.Existing = existing
+
+	facts, err := config.Extractor(request)
+	if err != nil {
+		config.reportError(err)
+		return
+	}
+
+	known := make(map[string]bool, len(existing))
+	for _, memory := range existing {
+		known[strings.ToLower(memory.Content)] = true
+	}
+
+	now := time.Now()
+	var stored []string
+	for _, fact := range facts {
+		fact = strings.TrimSpace(fact)
+		if fact == "" || known[strings.ToLower(fact)] {
+			continue
+		}
+		memory := Memory{
+			ID:        generateUUID(),
+			UserID:    config.UserID,
+			Content:   fact,
+			SessionID: s.SessionID,
+			CreatedAt: now,
+			UpdatedAt: now,
+		}
+		if err := config.Store.Save(memory); err != nil {
+			config.reportError(fmt.Errorf("failed to store memory: %w", err))
+			continue
+		}
+		known[strings.ToLower(fact)] = true
+		stored = append(stored, memory.ID)
+	}
+
+	if len(stored) > 0 {
+		s.emit(SDKMemoriesStored, map[string]interface{}{
+			"userId":    config.UserID,
+			"memoryIds": stored,
+		})
+	}
+}
@@ -0,0 +1,136 @@
+package copilot
+
+import (
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestExtractExplicitMemories(t *testing.T) {
+	facts, _ := ExtractExplicitMemories(MemoryExtractionRequest{
+		Prompt: "Fix the build. Remember that I deploy on Fridays! I prefer table-driven tests\nremember to run lint",
+	})
+	if len(facts) != 2 || facts[0] != "I deploy on Fridays" || facts[1] != "The user prefers table-driven tests" {
+		t.Errorf("Unexpected facts %q", facts)
+	}
+}
+
+func TestSessionMemory(t *testing.T) {
+	newMemorySession := func(t *testing.T, config *MemoryConfig) (*Session, func() string) {
+		var mu sync.Mutex
+		var prompt string
+		session, _ := newFakeSession(t, func(server *fakeServer, method string, params map[string]interface{}) (map[string]interface{}, *JSONRPCError) {
+			mu.Lock()
+			prompt = params["prompt"].(string)
+			mu.Unlock()
+			go func() {
+				server.emitEvent(AssistantMessage, "a1", map[string]interface{}{"content": "Done."})
+				server.emitEvent(SessionIdle, "e1", map[string]interface{}{})
+			}()
+			return map[string]interface{}{"messageId": "msg-1"}, nil
+		})
+		session.registerMemory(config)
+		return session, func() string {
+			mu.Lock()
+			defer mu.Unlock()
+			return prompt
+		}
+	}
+
+	t.Run("recalls the user's memories into the prompt", func(t *testing.T) {
+		store := NewInMemoryStore()
+		store.Save(Memory{ID: "m1", UserID: "alice", Content: "Prefers tabs"})
+		store.Save(Memory{ID: "m2", UserID: "bob", Content: "Prefers spaces"})
+		session, sent := newMemorySession(t, &MemoryConfig{Store: store, UserID: "alice"})
+
+		if _, err := session.SendAndCollect(MessageOptions{Prompt: "Format main.go"}, 5*time.Second); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		prompt := sent()
+		if !strings.HasPrefix(prompt, "Format main.go\n\n") || !strings.Contains(prompt, "- Prefers tabs") {
+			t.Errorf("Expected alice's memory in the prompt, got %q", prompt)
+		}
+		if strings.Contains(prompt, "spaces") {
+			t.Errorf("Expected other users' memories to be excluded, got %q", prompt)
+		}
+	})
+
+	t.Run("extracts and stores memories after the turn", func(t *testing.T) {
+		store := NewInMemoryStore()
+		store.Save(Memory{ID: "m1", UserID: "alice", Content: "The user prefers tabs"})
+
+		var requests []MemoryExtractionRequest
+		config := &MemoryConfig{
+			Store:         store,
+			UserID:        "alice",
+			DisableRecall: true,
+			Extractor: func(request MemoryExtractionRequest) ([]string, error) {
+				requests = append(requests, request)
+				return []string{"the user prefers TABS", "Works at Contoso", " "}, nil
+			},
+		}
+		session, sent := newMemorySession(t, config)
+		storedCh := make(chan SessionEvent, 1)
+		session.On(func(event SessionEvent) {
+			if event.Type == SDKMemoriesStored {
+				storedCh <- event
+			}
+		})
+
+		if _, err := session.SendAndCollect(MessageOptions{Prompt: "Hi"}, 5*time.Second); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if sent() != "Hi" {
+			t.Errorf("Expected no recall, got %q", sent())
+		}
+
+		select {
+		case event := <-storedCh:
+			if ids, _ := event.Data.Metadata.Variables["memoryIds"].([]string); len(ids) != 1 {
+				t.Errorf("Expected one stored memory, got %v", event.Data.Metadata.Variables)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("Timed out waiting for memories to be stored")
+		}
+
+		if len(requests) != 1 || requests[0].Prompt != "Hi" || requests[0].Response != "Done." || len(requests[0].Existing) != 1 {
+			t.Errorf("Unexpected extraction request %+v", requests)
+		}
+		memories, _ := store.List("alice")
+		if len(memories) != 2 || memories[1].Content != "Works at Contoso" || memories[1].SessionID != "s1" {
+			t.Errorf("Expected the new memory without duplicates, got %+v", memories)
+		}
+	})
+
+	t.Run("reports extraction errors", func(t *testing.T) {
+		errCh := make(chan error, 1)
+		session, _ := newMemorySession(t, &MemoryConfig{
+			Store:     NewInMemoryStore(),
+			UserID:    "alice",
+			Extractor: func(MemoryExtractionRequest) ([]string, error) { return nil, errors.New("model unavailable") },
+			OnError:   func(err error) { errCh <- err },
+		})
+		if _, err := session.SendAndCollect(MessageOptions{Prompt: "Hi"}, 5*time.Second); err != nil {
+			t.Fatalf("Expected the turn to succeed, got %v", err)
+		}
+		select {
+		case err := <-errCh:
+			if err.Error() != "model unavailable" {
+				t.Errorf("Unexpected error %v", err)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("Timed out waiting for the error")
+		}
+	})
+
+	t.Run("requires a store and user", func(t *testing.T) {
+		if err := (&MemoryConfig{UserID: "alice"}).validate(); err == nil {
+			t.Error("Expected an error without a store")
+		}
+		if err := (&MemoryConfig{Store: NewInMemoryStore()}).validate(); err == nil {
+			t.Error("Expected an error without a user")
+		}
+	})
+}
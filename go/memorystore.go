@@ -0,0 +1,273 @@
+package copilot
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+)
+
+// ErrMemoryNotFound is returned by [MemoryStore.Delete] when the user has no
+// memory with the given ID.
+var ErrMemoryNotFound = errors.New("memory not found")
+
+// Memory is a long-term fact about a user, such as a preference, that is
+// remembered across sessions.
+type Memory struct {
+	// ID uniquely identifies the memory within its user's memories.
+	ID string `json:"id"`
+	// UserID is the user the memory belongs to. Memories are only ever retrieved
+	// for the user that owns them.
+	UserID string `json:"userId"`
+	// Content is the fact, phrased so it can be shown to the model as is.
+	Content string `json:"content"`
+	// SessionID is the session the memory was extracted from, if any.
+	SessionID string `json:"sessionId,omitempty"`
+	// CreatedAt is when the memory was first stored.
+	CreatedAt time.Time `json:"createdAt"`
+	// UpdatedAt is when the memory was last stored.
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// MemoryStore persists memories, scoped by user. Implementations must be safe
+// for concurrent use.
+type MemoryStore interface {
+	// Save stores the memory, replacing any memory of the same user with the same ID.
+	Save(memory Memory) error
+	// Delete removes a memory, or returns [ErrMemoryNotFound].
+	Delete(userID, id string) error
+	// List returns all memories of a user, oldest first.
+	List(userID string) ([]Memory, error)
+	// Search returns up to limit memories of a user, most relevant to query
+	// first. A limit of zero or less means no limit.
+	Search(userID, query string, limit int) ([]Memory, error)
+}
+
+// InMemoryStore is a [MemoryStore] that keeps memories in memory.
+type InMemoryStore struct {
+	mu    sync.RWMutex
+	users map[string][]Memory
+}
+
+// NewInMemoryStore creates an empty in-memory memory store.
+func NewInMemoryStore() *InMemoryStore {
+	return &InMemoryStore{users: make(map[string][]Memory)}
+}
+
+// Save implements [MemoryStore].
+func (s *InMemoryStore) Save(memory Memory) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.users[memory.UserID] = upsertMemory(s.users[memory.UserID], memory)
+	return nil
+}
+
+// Delete implements [MemoryStore].
+func (s *InMemoryStore) Delete(userID, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	memories, ok := removeMemory(s.users[userID], id)
+	if !ok {
+		return ErrMemoryNotFound
+	}
+	s.users[userID] = memories
+	return nil
+}
+
+// List implements [MemoryStore].
+func (s *InMemoryStore) List(userID string) ([]Memory, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return append([]Memory(nil), s.users[userID]...), nil
+}
+
+// Search implements [MemoryStore] by ranking the user's memories with
+// [RankMemories].
+func (s *InMemoryStore) Search(userID, query string, limit int) ([]Memory, error) {
+	memories, _ := s.List(userID)
+	return RankMemories(memories, query, limit), nil
+}
+
+// FileMemoryStore is a [MemoryStore] that keeps one JSON file per user in a
+// directory.
+type FileMemoryStore struct {
+	dir string
+	mu  sync.Mutex
+}
+
+// NewFileMemoryStore creates a memory store in dir, creating the directory if
+// it does not exist.
+func NewFileMemoryStore(dir string) (*FileMemoryStore, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create memory directory: %w", err)
+	}
+	return &FileMemoryStore{dir: dir}, nil
+}
+
+func (s *FileMemoryStore) path(userID string) string {
+	return filepath.Join(s.dir, url.PathEscape(userID)+".json")
+}
+
+// Save implements [MemoryStore].
+func (s *FileMemoryStore) Save(memory Memory) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	memories, err := s.load(memory.UserID)
+	if err != nil {
+		return err
+	}
+	return s.write(memory.UserID, upsertMemory(memories, memory))
+}
+
+// Delete implements [MemoryStore].
+func (s *FileMemoryStore) Delete(userID, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	memories, err := s.load(userID)
+	if err != nil {
+		return err
+	}
+	memories, ok := removeMemory(memories, id)
+	if !ok {
+		return ErrMemoryNotFound
+	}
+	return s.write(userID, memories)
+}
+
+// List implements [MemoryStore].
+func (s *FileMemoryStore) List(userID string) ([]Memory, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.load(userID)
+}
+
+// Search implements [MemoryStore] by ranking the user's memories with
+// [RankMemories].
+func (s *FileMemoryStore) Search(userID, query string, limit int) ([]Memory, error) {
+	memories, err := s.List(userID)
+	if err != nil {
+		return nil, err
+	}
+	return RankMemories(memories, query, limit), nil
+}
+
+func (s *FileMemoryStore) load(userID string) ([]Memory, error) {
+	data, err := os.ReadFile(s.path(userID))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read memories: %w", err)
+	}
+	var memories []Memory
+	if err := json.Unmarshal(data, &memories); err != nil {
+		return nil, fmt.Errorf("failed to decode memories of user %s: %w", userID, err)
+	}
+	return memories, nil
+}
+
+func (s *FileMemoryStore) write(userID string, memories []Memory) error {
+	data, err := json.Marshal(memories)
+	if err != nil {
+		return fmt.Errorf("failed to encode memories: %w", err)
+	}
+	if err := writeFileAtomic(s.path(userID), data); err != nil {
+		return fmt.Errorf("failed to save memories: %w", err)
+	}
+	return nil
+}
+
+// upsertMemory replaces the memory with the same ID in memories, or appends it.
+func upsertMemory(memories []Memory, memory Memory) []Memory {
+	for i, existing := range memories {
+		if existing.ID == memory.ID {
+			memories[i] = memory
+			return memories
+		}
+	}
+	return append(memories, memory)
+}
+
+func removeMemory(memories []Memory, id string) ([]Memory, bool) {
+	for i, existing := range memories {
+		if existing.ID == id {
+			return append(memories[:i], memories[i+1:]...), true
+		}
+	}
+	return memories, false
+}
+
+// RankMemories orders memories by relevance to query and returns up to limit of
+// them. A limit of zero or less means no limit.
+//
+// Relevance is the number of distinct words of query that appear in a memory,
+// ignoring case and common words. Memories sharing no words with query are still
+// returned after the relevant ones, since general preferences ("prefers tabs")
+// rarely share words with the prompt they apply to. Ties are ordered most
+// recently updated first. It is the ranking used by the built-in stores and can
+// be reused by custom [MemoryStore] implementations.
+func RankMemories(memories []Memory, query string, limit int) []Memory {
+	terms := memoryTerms(query)
+	type scored struct {
+		memory Memory
+		score  int
+	}
+	candidates := make([]scored, 0, len(memories))
+	for _, memory := range memories {
+		score := 0
+		if len(terms) > 0 {
+			words := memoryTerms(memory.Content)
+			for term := range terms {
+				if words[term] {
+					score++
+				}
+			}
+		}
+		candidates = append(candidates, scored{memory, score})
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		if candidates[i].score != candidates[j].score {
+			return candidates[i].score > candidates[j].score
+		}
+		return candidates[i].memory.UpdatedAt.After(candidates[j].memory.UpdatedAt)
+	})
+
+	if limit > 0 && len(candidates) > limit {
+		candidates = candidates[:limit]
+	}
+	result := make([]Memory, len(candidates))
+	for i, c := range candidates {
+		result[i] = c.memory
+	}
+	return result
+}
+
+// memoryStopWords are words too common to make two texts related.
+var memoryStopWords = map[string]bool{
+	"a": true, "an": true, "and": true, "are": true, "as": true, "at": true, "be": true,
+	"but": true, "by": true, "do": true, "for": true, "from": true, "how": true, "i": true,
+	"in": true, "is": true, "it": true, "me": true, "my": true, "of": true, "on": true,
+	"or": true, "that": true, "the": true, "this": true, "to": true, "was": true,
+	"what": true, "with": true, "you": true,
+}
+
+// memoryTerms returns the distinct lower-cased words of s, without stop words.
+func memoryTerms(s string) map[string]bool {
+	terms := make(map[string]bool)
+	for _, word := range strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}) {
+		if !memoryStopWords[word] {
+			terms[word] = true
+		}
+	}
+	return terms
+}
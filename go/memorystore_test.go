@@ -0,0 +1,70 @@
+package copilot
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestMemoryStores(t *testing.T) {
+	fileStore, err := NewFileMemoryStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	stores := map[string]MemoryStore{
+		"memory": NewInMemoryStore(),
+		"file":   fileStore,
+	}
+
+	for name, store := range stores {
+		t.Run(name+" scopes memories by user", func(t *testing.T) {
+			base := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+			store.Save(Memory{ID: "m1", UserID: "alice", Content: "Prefers tabs", UpdatedAt: base})
+			store.Save(Memory{ID: "m2", UserID: "alice", Content: "Deploys with Terraform", UpdatedAt: base.Add(time.Hour)})
+			store.Save(Memory{ID: "m1", UserID: "bob/ext", Content: "Prefers spaces", UpdatedAt: base})
+
+			alice, err := store.List("alice")
+			if err != nil || len(alice) != 2 || alice[0].ID != "m1" {
+				t.Fatalf("Unexpected memories %+v, %v", alice, err)
+			}
+			bob, _ := store.List("bob/ext")
+			if len(bob) != 1 || bob[0].Content != "Prefers spaces" {
+				t.Errorf("Unexpected memories %+v", bob)
+			}
+
+			store.Save(Memory{ID: "m1", UserID: "alice", Content: "Prefers tabs, width 4", UpdatedAt: base})
+			found, _ := store.Search("alice", "how do I configure terraform?", 1)
+			if len(found) != 1 || found[0].ID != "m2" {
+				t.Errorf("Expected the Terraform memory, got %+v", found)
+			}
+
+			if err := store.Delete("alice", "m2"); err != nil {
+				t.Errorf("Unexpected error: %v", err)
+			}
+			if err := store.Delete("alice", "m2"); !errors.Is(err, ErrMemoryNotFound) {
+				t.Errorf("Expected ErrMemoryNotFound, got %v", err)
+			}
+			alice, _ = store.List("alice")
+			if len(alice) != 1 || alice[0].Content != "Prefers tabs, width 4" {
+				t.Errorf("Unexpected memories after update and delete %+v", alice)
+			}
+		})
+	}
+}
+
+func TestRankMemories(t *testing.T) {
+	base := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	memories := []Memory{
+		{ID: "old", Content: "Prefers short answers", UpdatedAt: base},
+		{ID: "go", Content: "Writes Go services", UpdatedAt: base.Add(time.Hour)},
+		{ID: "recent", Content: "Uses a Mac", UpdatedAt: base.Add(2 * time.Hour)},
+	}
+
+	ranked := RankMemories(memories, "Write a Go test for the services", 0)
+	if len(ranked) != 3 || ranked[0].ID != "go" || ranked[1].ID != "recent" || ranked[2].ID != "old" {
+		t.Errorf("Expected relevant then recent memories, got %+v", ranked)
+	}
+	if ranked := RankMemories(memories, "", 2); len(ranked) != 2 || ranked[0].ID != "recent" {
+		t.Errorf("Expected the two most recent memories, got %+v", ranked)
+	}
+}
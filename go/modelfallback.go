@@ -0,0 +1,214 @@
+package copilot
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+)
+
+// DefaultFallbackErrorTypes are the session.error types that
+// [IsModelUnavailable] treats as the model being unavailable.
+var DefaultFallbackErrorTypes = []string{"quota", "rate_limit", "model_unavailable"}
+
+// modelFallbackIdleGrace is how long a turn that failed mid-turn waits for the
+// session to become idle before it is sent again with a fallback model.
+const modelFallbackIdleGrace = 5 * time.Second
+
+// ModelFallbackConfig configures the models a session's turns fall back to when
+// the session's model is unavailable, such as when its quota is exhausted
+// during an incident, so that turns degrade in quality instead of failing.
+//
+// Each turn starts on the session's model, or the model its experiment
+// variant assigns. If the turn fails with an error ShouldFallback accepts,
+// it is sent again with the next model of Chain, and an [SDKModelFallback]
+// event records the switch. The turn fails once the chain is exhausted.
+// Each model is sent the turn as a new message with a fresh idempotency key,
+// so the server does not take it for a retry of the failed one; a
+// [SendError] reports the key of the last message sent.
+//
+// Failures of the session.send request fall back with every send. Failures
+// reported by a session.error event during the turn fall back only with
+// [Session.SendAndCollect] and [Session.SendAndWait], which wait for the
+// turn's outcome.
+//
+// Example:
+//
+//	session, err := client.CreateSession(&copilot.SessionConfig{
+//	    Model: "claude-sonnet-4.5",
+//	    ModelFallback: &copilot.ModelFallbackConfig{
+//	        Chain: []copilot.ModelFallback{
+//	            {Model: "gpt-5"},
+//	            {Model: "gpt-5-mini", Instructions: "Keep answers brief and say if a task is beyond you."},
+//	        },
+//	    },
+//	})
+type ModelFallbackConfig struct {
+	// Chain lists the models to fall back to, in order.
+	Chain []ModelFallback
+	// ShouldFallback reports whether a turn that failed with err should be
+	// sent again with the next model. Defaults to [IsModelUnavailable].
+	ShouldFallback func(err error) bool
+}
+
+// ModelFallback is a model of a [ModelFallbackConfig] chain.
+type ModelFallback struct {
+	// Model is the model to send the turn to. It requires server support for
+	// per-message model selection.
+	Model string
+	// Instructions, if set, are appended to the prompt when the turn is sent
+	// to Model, for example to adjust for a less capable model.
+	Instructions string
+}
+
+// IsModelUnavailable reports whether err means that the model could not
+// serve a turn: a request that failed with one of the [DefaultOverloadCodes],
+// or a [*TurnFailedError] with one of the [DefaultFallbackErrorTypes].
+func IsModelUnavailable(err error) bool {
+	var rpcErr *JSONRPCError
+	if errors.As(err, &rpcErr) {
+		return slices.Contains(DefaultOverloadCodes, rpcErr.Code)
+	}
+	var turnErr *TurnFailedError
+	if errors.As(err, &turnErr) {
+		return slices.Contains(DefaultFallbackErrorTypes, turnErr.ErrorType)
+	}
+	return false
+}
+
+// modelFallbackState is a session's fallback chain.
+type modelFallbackState struct {
+	config *ModelFallbackConfig
+	// model is the session's configured model, if known.
+	model string
+}
+
+// modelFallbackAttempt tracks a turn's position in the session's fallback
+// chain, and the message it sends.
+type modelFallbackAttempt struct {
+	params map[string]interface{}
+	// prompt is the prompt before any fallback instructions.
+	prompt string
+	// next is the position of the model the turn is sent to next: 0 for the
+	// session's model, and i for the i-th model of the chain.
+	next int
+	// model is the model the turn was last sent to.
+	model string
+	// cause is the failure that moved the turn to the next model.
+	cause error
+}
+
+// idempotencyKey returns the idempotency key of the message last sent.
+func (a *modelFallbackAttempt) idempotencyKey() string {
+	key, _ := a.params["idempotencyKey"].(string)
+	return key
+}
+
+// registerModelFallback sets the fallback chain for this session, whose model
+// is model.
+func (s *Session) registerModelFallback(config *ModelFallbackConfig, model string) {
+	s.modelFallbackMux.Lock()
+	defer s.modelFallbackMux.Unlock()
+	s.modelFallback = modelFallbackState{config: config, model: model}
+}
+
+// modelFallbackConfig returns the fallback chain for a session, which is the
+// client's unless the session sets its own.
+func (c *Client) modelFallbackConfig(config *ModelFallbackConfig) *ModelFallbackConfig {
+	if config != nil {
+		return config
+	}
+	return c.options.ModelFallback
+}
+
+// hasModelFallback reports whether the session has a fallback chain.
+func (s *Session) hasModelFallback() bool {
+	s.modelFallbackMux.RLock()
+	defer s.modelFallbackMux.RUnlock()
+	return s.modelFallback.config != nil
+}
+
+// nextModelFallback reports whether a turn that failed with err should be sent
+// again with the next model of the chain, and if so moves attempt to it.
+func (s *Session) nextModelFallback(attempt *modelFallbackAttempt, err error) bool {
+	s.modelFallbackMux.RLock()
+	config := s.modelFallback.config
+	s.modelFallbackMux.RUnlock()
+	if config == nil || attempt.next >= len(config.Chain) {
+		return false
+	}
+	shouldFallback := config.ShouldFallback
+	if shouldFallback == nil {
+		shouldFallback = IsModelUnavailable
+	}
+	if !shouldFallback(err) {
+		return false
+	}
+	attempt.next++
+	attempt.cause = err
+	return true
+}
+
+// applyModelFallback sets the model and instructions of the fallback the
+// turn is at in its params, with a new idempotency key, and dispatches an [SDKModelFallback] event for
+// the switch.
+func (s *Session) applyModelFallback(attempt *modelFallbackAttempt) {
+	s.modelFallbackMux.RLock()
+	state := s.modelFallback
+	s.modelFallbackMux.RUnlock()
+
+	if attempt.next == 0 {
+		attempt.prompt, _ = attempt.params["prompt"].(string)
+		attempt.model, _ = attempt.params["model"].(string)
+		if attempt.model == "" {
+			attempt.model = state.model
+		}
+		return
+	}
+
+	fallback := state.config.Chain[attempt.next-1]
+	attempt.params["model"] = fallback.Model
+	attempt.params["idempotencyKey"] = NewIdempotencyKey()
+	attempt.params["prompt"] = attempt.prompt
+	if fallback.Instructions != "" {
+		attempt.params["prompt"] = appendPromptInstructions(attempt.prompt, fallback.Instructions)
+	}
+	s.emit(SDKModelFallback, map[string]interface{}{
+		"fromModel": attempt.model,
+		"toModel":   fallback.Model,
+		"attempt":   attempt.next,
+		"error":     attempt.cause.Error(),
+	})
+	attempt.model = fallback.Model
+}
+
+// requestTurn sends a turn's message, falling back along the session's chain
+// while the request fails with errors that warrant it.
+func (s *Session) requestTurn(attempt *modelFallbackAttempt) (map[string]interface{}, error) {
+	s.applyModelFallback(attempt)
+	result, err := s.client.Request("session.send", attempt.params)
+	for err != nil && s.nextModelFallback(attempt, err) {
+		s.applyModelFallback(attempt)
+		result, err = s.client.Request("session.send", attempt.params)
+	}
+	return result, err
+}
+
+// resendWithFallback sends a turn that failed during the turn again, with
+// the model attempt has moved to, keeping the turn in flight that the failed
+// attempt held. onStart is called just before it is sent.
+func (s *Session) resendWithFallback(attempt *modelFallbackAttempt, onStart func()) (string, error) {
+	s.resumeTurn()
+	onStart()
+	result, err := s.requestTurn(attempt)
+	if err != nil {
+		s.finishTurn()
+		return "", &SendError{IdempotencyKey: attempt.idempotencyKey(), Err: err}
+	}
+	messageID, ok := result["messageId"].(string)
+	if !ok {
+		s.finishTurn()
+		return "", fmt.Errorf("invalid response: missing messageId")
+	}
+	return messageID, nil
+}
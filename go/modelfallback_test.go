@@ -0,0 +1,158 @@
+package copilot
+
+import (
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestModelFallback(t *testing.T) {
+	chain := &ModelFallbackConfig{Chain: []ModelFallback{
+		{Model: "model-b"},
+		{Model: "model-c", Instructions: "Be brief."},
+	}}
+
+	t.Run("falls back when the send request fails", func(t *testing.T) {
+		var mu sync.Mutex
+		var sent []map[string]interface{}
+		session, _ := newFakeSession(t, func(server *fakeServer, method string, params map[string]interface{}) (map[string]interface{}, *JSONRPCError) {
+			mu.Lock()
+			sent = append(sent, params)
+			mu.Unlock()
+			if params["model"] != "model-c" {
+				return nil, &JSONRPCError{Code: 429, Message: "quota exceeded"}
+			}
+			return map[string]interface{}{"messageId": "msg-1"}, nil
+		})
+		session.registerModelFallback(chain, "model-a")
+		var events []SessionEvent
+		session.On(func(event SessionEvent) {
+			if event.Type == SDKModelFallback {
+				events = append(events, event)
+			}
+		})
+
+		if _, err := session.Send(MessageOptions{Prompt: "Summarize"}); err != nil {
+			t.Fatalf("Expected the send to fall back, got %v", err)
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		if len(sent) != 3 || sent[1]["model"] != "model-b" || sent[1]["prompt"] != "Summarize" || sent[2]["prompt"] != "Summarize\n\nBe brief." {
+			t.Fatalf("Unexpected requests %+v", sent)
+		}
+		keys := map[interface{}]bool{}
+		for _, params := range sent {
+			keys[params["idempotencyKey"]] = true
+		}
+		if len(keys) != 3 || keys[nil] || keys[""] {
+			t.Errorf("Expected a fresh idempotency key for each model, got %+v", sent)
+		}
+		if len(events) != 2 {
+			t.Fatalf("Expected 2 fallback events, got %d", len(events))
+		}
+		variables := events[1].Data.Metadata.Variables
+		if variables["fromModel"] != "model-b" || variables["toModel"] != "model-c" || variables["attempt"] != 2 || !strings.Contains(variables["error"].(string), "quota exceeded") {
+			t.Errorf("Unexpected event %+v", variables)
+		}
+		if sdkEventString(events[0], "fromModel") != "model-a" {
+			t.Errorf("Expected the first fallback from the session's model, got %+v", events[0].Data.Metadata.Variables)
+		}
+	})
+
+	t.Run("fails once the chain is exhausted", func(t *testing.T) {
+		session, _ := newFakeSession(t, func(server *fakeServer, method string, params map[string]interface{}) (map[string]interface{}, *JSONRPCError) {
+			return nil, &JSONRPCError{Code: 503, Message: "unavailable"}
+		})
+		session.registerModelFallback(chain, "")
+		_, err := session.Send(MessageOptions{Prompt: "hi"})
+		var rpcErr *JSONRPCError
+		if !errors.As(err, &rpcErr) || rpcErr.Code != 503 {
+			t.Errorf("Expected the last error, got %v", err)
+		}
+	})
+
+	t.Run("does not fall back for other errors", func(t *testing.T) {
+		calls := 0
+		session, _ := newFakeSession(t, func(server *fakeServer, method string, params map[string]interface{}) (map[string]interface{}, *JSONRPCError) {
+			calls++
+			return nil, &JSONRPCError{Code: -32602, Message: "invalid params"}
+		})
+		session.registerModelFallback(chain, "")
+		if _, err := session.Send(MessageOptions{Prompt: "hi"}); err == nil || calls != 1 {
+			t.Errorf("Expected one failed request, got %d, %v", calls, err)
+		}
+	})
+
+	t.Run("falls back when the turn fails", func(t *testing.T) {
+		session, _ := newFakeSession(t, func(server *fakeServer, method string, params map[string]interface{}) (map[string]interface{}, *JSONRPCError) {
+			model, _ := params["model"].(string)
+			go func() {
+				if model == "" {
+					server.emitEvent(SessionError, "e1", map[string]interface{}{"errorType": "quota", "message": "quota exhausted"})
+				} else {
+					server.emitEvent(AssistantMessage, "e2", map[string]interface{}{"messageId": "m2", "content": "from " + model})
+				}
+				server.emitEvent(SessionIdle, "e3-"+model, map[string]interface{}{})
+			}()
+			return map[string]interface{}{"messageId": "msg-" + model}, nil
+		})
+		session.registerModelFallback(chain, "")
+
+		turn, err := session.SendAndCollect(MessageOptions{Prompt: "hi"}, 5*time.Second)
+		if err != nil {
+			t.Fatalf("Expected the turn to fall back, got %v", err)
+		}
+		if turn.Content != "from model-b" || turn.MessageID != "msg-model-b" || turn.FallbackModel != "model-b" {
+			t.Errorf("Unexpected turn %+v", turn)
+		}
+		for _, event := range turn.Events {
+			if event.Type == SessionError {
+				t.Error("Expected the failed attempt's events to be dropped")
+			}
+		}
+	})
+
+	t.Run("reports the key of a failed resend", func(t *testing.T) {
+		var mu sync.Mutex
+		var keys []string
+		session, _ := newFakeSession(t, func(server *fakeServer, method string, params map[string]interface{}) (map[string]interface{}, *JSONRPCError) {
+			mu.Lock()
+			keys = append(keys, params["idempotencyKey"].(string))
+			mu.Unlock()
+			if params["model"] != nil {
+				return nil, &JSONRPCError{Code: -32602, Message: "invalid params"}
+			}
+			go func() {
+				server.emitEvent(SessionError, "e1", map[string]interface{}{"errorType": "quota", "message": "quota exhausted"})
+				server.emitEvent(SessionIdle, "e2", map[string]interface{}{})
+			}()
+			return map[string]interface{}{"messageId": "msg-1"}, nil
+		})
+		session.registerModelFallback(chain, "")
+
+		_, err := session.SendAndCollect(MessageOptions{Prompt: "hi", IdempotencyKey: "original"}, 5*time.Second)
+		var sendErr *SendError
+		if !errors.As(err, &sendErr) {
+			t.Fatalf("Expected a SendError, got %v", err)
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		if len(keys) != 2 || keys[0] != "original" || keys[1] == "original" || sendErr.IdempotencyKey != keys[1] {
+			t.Errorf("Expected the resend's own key to be reported, got %q for requests %q", sendErr.IdempotencyKey, keys)
+		}
+	})
+
+	t.Run("reports turn errors without a chain", func(t *testing.T) {
+		session, _ := newFakeSession(t, func(server *fakeServer, method string, params map[string]interface{}) (map[string]interface{}, *JSONRPCError) {
+			go server.emitEvent(SessionError, "e1", map[string]interface{}{"errorType": "quota", "message": "quota exhausted"})
+			return map[string]interface{}{"messageId": "msg-1"}, nil
+		})
+		_, err := session.SendAndCollect(MessageOptions{Prompt: "hi"}, 5*time.Second)
+		var turnErr *TurnFailedError
+		if !errors.As(err, &turnErr) || turnErr.ErrorType != "quota" || !IsModelUnavailable(err) {
+			t.Errorf("Expected a quota TurnFailedError, got %v", err)
+		}
+	})
+}
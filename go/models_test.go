@@ -0,0 +1,136 @@
+package copilot
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestClient_ListModels(t *testing.T) {
+	var calls atomic.Int32
+	rpc, _ := newFakeServer(t, func(method string, params map[string]interface{}) (map[string]interface{}, *JSONRPCError) {
+		if method != "models.list" {
+			return nil, nil
+		}
+		calls.Add(1)
+		return map[string]interface{}{"models": []interface{}{
+			map[string]interface{}{
+				"id":   "gpt-5",
+				"name": "GPT-5",
+				"capabilities": map[string]interface{}{
+					"supports": map[string]interface{}{"vision": true, "reasoningEffort": true},
+					"limits":   map[string]interface{}{"max_context_window_tokens": float64(400000)},
+				},
+			},
+			map[string]interface{}{"id": "small", "name": "Small"},
+		}}, nil
+	})
+
+	t.Run("decodes capabilities and caches the list", func(t *testing.T) {
+		calls.Store(0)
+		client := &Client{client: rpc, state: StateConnected, sessions: make(map[string]*Session)}
+		model, err := client.GetModel("gpt-5")
+		if err != nil {
+			t.Fatalf("GetModel failed: %v", err)
+		}
+		supports := model.Capabilities.Supports
+		if !supports.Vision || !supports.ReasoningEffort || model.Capabilities.Limits.MaxContextWindowTokens != 400000 {
+			t.Errorf("Unexpected capabilities %+v", model.Capabilities)
+		}
+		if _, err := client.ListModels(); err != nil {
+			t.Fatalf("ListModels failed: %v", err)
+		}
+		if calls.Load() != 1 {
+			t.Errorf("Expected the list to be fetched once, got %d", calls.Load())
+		}
+		if _, err := client.GetModel("missing"); err == nil || !strings.Contains(err.Error(), "not available") {
+			t.Errorf("Expected an error for an unknown model, got %v", err)
+		}
+	})
+
+	t.Run("refetches once the TTL expires", func(t *testing.T) {
+		calls.Store(0)
+		client := &Client{client: rpc, state: StateConnected, sessions: make(map[string]*Session), options: ClientOptions{ModelsCacheTTL: time.Minute}}
+		if _, err := client.ListModels(); err != nil {
+			t.Fatalf("ListModels failed: %v", err)
+		}
+		if _, err := client.ListModels(); err != nil || calls.Load() != 1 {
+			t.Fatalf("Expected a cached list, got %d fetches, %v", calls.Load(), err)
+		}
+		client.modelsCachedAt = client.modelsCachedAt.Add(-2 * time.Minute)
+		if _, err := client.ListModels(); err != nil || calls.Load() != 2 {
+			t.Errorf("Expected the expired list to be refetched, got %d fetches, %v", calls.Load(), err)
+		}
+	})
+}
+
+func TestClient_ListModelsContext(t *testing.T) {
+	release := make(chan struct{})
+	rpc, _ := newFakeServer(t, func(method string, params map[string]interface{}) (map[string]interface{}, *JSONRPCError) {
+		<-release
+		return map[string]interface{}{"models": []interface{}{}}, nil
+	})
+	t.Cleanup(func() { close(release) })
+	client := &Client{client: rpc, state: StateConnected, sessions: make(map[string]*Session)}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := client.ListModelsContext(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected the slow request to be abandoned, got %v", err)
+	}
+	if client.modelsCache != nil {
+		t.Error("Expected nothing to be cached")
+	}
+}
+
+func TestClient_ListModelsContext_SharesRequest(t *testing.T) {
+	var calls atomic.Int32
+	release := make(chan struct{})
+	rpc, _ := newFakeServer(t, func(method string, params map[string]interface{}) (map[string]interface{}, *JSONRPCError) {
+		calls.Add(1)
+		<-release
+		return map[string]interface{}{"models": []interface{}{map[string]interface{}{"id": "gpt-5", "name": "GPT-5"}}}, nil
+	})
+	client := &Client{client: rpc, state: StateConnected, sessions: make(map[string]*Session)}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	canceled := make(chan error, 1)
+	go func() {
+		_, err := client.ListModelsContext(ctx)
+		canceled <- err
+	}()
+	waiting := make(chan []ModelInfo, 1)
+	go func() {
+		models, err := client.ListModels()
+		if err != nil {
+			t.Errorf("ListModels failed: %v", err)
+		}
+		waiting <- models
+	}()
+	for deadline := time.Now().Add(5 * time.Second); calls.Load() == 0; time.Sleep(time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatal("Timed out waiting for the request")
+		}
+	}
+
+	cancel()
+	select {
+	case err := <-canceled:
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("Expected the canceled call to return context.Canceled, got %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected the canceled call to stop waiting for the shared request")
+	}
+
+	close(release)
+	if models := <-waiting; len(models) != 1 || models[0].ID != "gpt-5" {
+		t.Errorf("Expected the other caller to get the models, got %+v", models)
+	}
+	if _, err := client.ListModels(); err != nil || calls.Load() != 1 {
+		t.Errorf("Expected one request for every call, got %d, %v", calls.Load(), err)
+	}
+}
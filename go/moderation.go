@@ -0,0 +1,204 @@
+package copilot
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// ModerationAction is what a [PreSendModerator] decided to do with a prompt.
+type ModerationAction string
+
+const (
+	// ModerationAllow sends the prompt unchanged. It is the action of a nil
+	// result or a result with no Action.
+	ModerationAllow ModerationAction = "allow"
+	// ModerationRewrite sends [ModerationResult.Prompt] in place of the prompt,
+	// for example with personal data redacted.
+	ModerationRewrite ModerationAction = "rewrite"
+	// ModerationBlock stops the prompt from being sent. The send fails with a
+	// [*PromptBlockedError].
+	ModerationBlock ModerationAction = "block"
+)
+
+// PreSendModerator reviews a prompt before it leaves the process, so that a
+// compliance gate runs before any data reaches the agent backend. It is
+// consulted by [Session.Send] before every message, ahead of the turn queue,
+// memory recall, and experiment assignment, which all see the moderated prompt.
+//
+// A moderator may decide locally or call out to a remote moderation service;
+// the send waits for it, bounded by [ModerationConfig.Timeout], and ctx is
+// canceled when the timeout expires. Returning an error fails the send, so
+// prompts are never sent unmoderated.
+//
+// Example:
+//
+//	moderator := func(ctx context.Context, req copilot.ModerationRequest) (*copilot.ModerationResult, error) {
+//	    verdict, err := compliance.Check(ctx, req.Prompt)
+//	    if err != nil {
+//	        return nil, err
+//	    }
+//	    switch {
+//	    case verdict.Forbidden:
+//	        return &copilot.ModerationResult{Action: copilot.ModerationBlock, Reason: verdict.Rule}, nil
+//	    case verdict.Redacted != req.Prompt:
+//	        return &copilot.ModerationResult{Action: copilot.ModerationRewrite, Prompt: verdict.Redacted, Reason: "pii"}, nil
+//	    }
+//	    return &copilot.ModerationResult{Labels: map[string]string{"policy": verdict.PolicyVersion}}, nil
+//	}
+type PreSendModerator func(ctx context.Context, request ModerationRequest) (*ModerationResult, error)
+
+// ModerationConfig configures pre-send moderation for a session. See
+// [SessionConfig.Moderation].
+type ModerationConfig struct {
+	// Moderator reviews every prompt before it is sent.
+	Moderator PreSendModerator
+	// Timeout bounds how long a send waits for Moderator. When it expires the
+	// send fails and Moderator's context is canceled. Zero waits indefinitely.
+	Timeout time.Duration
+}
+
+// ModerationRequest describes the prompt a [PreSendModerator] is reviewing.
+type ModerationRequest struct {
+	// SessionID is the session the prompt is sent to.
+	SessionID string
+	// Prompt is the prompt being sent.
+	Prompt string
+	// Attachments are the message's attachments. They are sent as given.
+	Attachments []Attachment
+}
+
+// ModerationResult is a [PreSendModerator]'s decision about a prompt. It is
+// attached to the turn as [Turn.Moderation] and dispatched as an
+// [SDKPromptModerated] event.
+type ModerationResult struct {
+	// Action is what to do with the prompt. Defaults to [ModerationAllow].
+	Action ModerationAction
+	// Prompt is the prompt to send instead, for [ModerationRewrite].
+	Prompt string
+	// Reason explains the decision, for example the policy that matched.
+	Reason string
+	// Labels annotate the turn, for example with the moderation policy version
+	// or detected categories. They are not sent to the server.
+	Labels map[string]string
+}
+
+// PromptBlockedError is returned by [Session.Send] when a [PreSendModerator]
+// blocks the prompt.
+type PromptBlockedError struct {
+	// Result is the moderator's decision.
+	Result *ModerationResult
+}
+
+func (e *PromptBlockedError) Error() string {
+	if e.Result.Reason == "" {
+		return "prompt blocked by moderation"
+	}
+	return fmt.Sprintf("prompt blocked by moderation: %s", e.Result.Reason)
+}
+
+// registerModeration sets the pre-send moderation for this session.
+func (s *Session) registerModeration(config *ModerationConfig) {
+	s.moderationMux.Lock()
+	defer s.moderationMux.Unlock()
+	s.moderation = config
+}
+
+// moderatePrompt consults the session's moderator about options and returns
+// its result, with the prompt to send in Prompt, or nil if the session has no
+// moderator. Blocked prompts are reported as an [SDKPromptModerated] event and
+// a [*PromptBlockedError].
+func (s *Session) moderatePrompt(options MessageOptions) (*ModerationResult, error) {
+	s.moderationMux.RLock()
+	config := s.moderation
+	s.moderationMux.RUnlock()
+	if config == nil || config.Moderator == nil {
+		return nil, nil
+	}
+
+	ctx, cancel := context.Background(), context.CancelFunc(func() {})
+	if config.Timeout > 0 {
+		ctx, cancel = context.WithTimeout(ctx, config.Timeout)
+	}
+	defer cancel()
+
+	type outcome struct {
+		result *ModerationResult
+		err    error
+	}
+	done := make(chan outcome, 1)
+	go func() {
+		result, err := config.Moderator(ctx, ModerationRequest{
+			SessionID:   s.SessionID,
+			Prompt:      options.Prompt,
+			Attachments: options.Attachments,
+		})
+		done <- outcome{result, err}
+	}()
+
+	var result *ModerationResult
+	select {
+	case o := <-done:
+		if o.err != nil {
+			return nil, fmt.Errorf("prompt moderation failed: %w", o.err)
+		}
+		result = o.result
+	case <-ctx.Done():
+		return nil, fmt.Errorf("prompt moderation failed: %w", ctx.Err())
+	}
+
+	moderated := ModerationResult{Action: ModerationAllow}
+	if result != nil {
+		moderated = *result
+	}
+	switch moderated.Action {
+	case "", ModerationAllow:
+		moderated.Action = ModerationAllow
+		moderated.Prompt = options.Prompt
+	case ModerationRewrite:
+	case ModerationBlock:
+		moderated.Prompt = ""
+		s.emitPromptModerated(&moderated, "")
+		return nil, &PromptBlockedError{Result: &moderated}
+	default:
+		return nil, fmt.Errorf("prompt moderation failed: unknown action %q", moderated.Action)
+	}
+	return &moderated, nil
+}
+
+// emitPromptModerated dispatches an [SDKPromptModerated] event for result.
+func (s *Session) emitPromptModerated(result *ModerationResult, messageID string) {
+	variables := map[string]interface{}{
+		"action":    string(result.Action),
+		"reason":    result.Reason,
+		"labels":    result.Labels,
+		"messageId": messageID,
+	}
+	if result.Action == ModerationRewrite {
+		variables["prompt"] = result.Prompt
+	}
+	s.emit(SDKPromptModerated, variables)
+}
+
+// PromptModeration returns the moderation result carried by an
+// [SDKPromptModerated] event, and false for other events.
+func PromptModeration(event SessionEvent) (*ModerationResult, bool) {
+	if event.Type != SDKPromptModerated || event.Data.Metadata == nil {
+		return nil, false
+	}
+	result := &ModerationResult{
+		Action: ModerationAction(sdkEventString(event, "action")),
+		Prompt: sdkEventString(event, "prompt"),
+		Reason: sdkEventString(event, "reason"),
+	}
+	switch labels := event.Data.Metadata.Variables["labels"].(type) {
+	case map[string]string:
+		result.Labels = labels
+	case map[string]interface{}:
+		result.Labels = make(map[string]string, len(labels))
+		for key, value := range labels {
+			result.Labels[key], _ = value.(string)
+		}
+	}
+	return result, true
+}
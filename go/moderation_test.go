@@ -0,0 +1,147 @@
+package copilot
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestPreSendModerator(t *testing.T) {
+	newModeratedSession := func(t *testing.T, config *ModerationConfig) (*Session, func() []map[string]interface{}) {
+		var mu sync.Mutex
+		var sent []map[string]interface{}
+		session, _ := newFakeSession(t, func(server *fakeServer, method string, params map[string]interface{}) (map[string]interface{}, *JSONRPCError) {
+			mu.Lock()
+			sent = append(sent, params)
+			mu.Unlock()
+			go server.emitEvent(SessionIdle, "e1", map[string]interface{}{})
+			return map[string]interface{}{"messageId": "msg-1"}, nil
+		})
+		session.registerModeration(config)
+		return session, func() []map[string]interface{} {
+			mu.Lock()
+			defer mu.Unlock()
+			return append([]map[string]interface{}(nil), sent...)
+		}
+	}
+
+	t.Run("rewrites the prompt and attaches the result to the turn", func(t *testing.T) {
+		var requests []ModerationRequest
+		session, sent := newModeratedSession(t, &ModerationConfig{
+			Moderator: func(ctx context.Context, req ModerationRequest) (*ModerationResult, error) {
+				requests = append(requests, req)
+				return &ModerationResult{
+					Action: ModerationRewrite,
+					Prompt: "My email is [redacted]",
+					Reason: "pii",
+					Labels: map[string]string{"policy": "v2"},
+				}, nil
+			},
+		})
+
+		turn, err := session.SendAndCollect(MessageOptions{Prompt: "My email is a@example.com"}, 5*time.Second)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if len(requests) != 1 || requests[0].Prompt != "My email is a@example.com" || requests[0].SessionID != session.SessionID {
+			t.Errorf("Unexpected moderation requests %+v", requests)
+		}
+		if params := sent(); len(params) != 1 || params[0]["prompt"] != "My email is [redacted]" {
+			t.Errorf("Expected the rewritten prompt to be sent, got %v", params)
+		}
+		if turn.Moderation == nil || turn.Moderation.Action != ModerationRewrite || turn.Moderation.Reason != "pii" ||
+			turn.Moderation.Labels["policy"] != "v2" || turn.Moderation.Prompt != "My email is [redacted]" {
+			t.Errorf("Expected the turn to record the moderation, got %+v", turn.Moderation)
+		}
+	})
+
+	t.Run("allows and annotates unchanged prompts", func(t *testing.T) {
+		session, sent := newModeratedSession(t, &ModerationConfig{
+			Moderator: func(ctx context.Context, req ModerationRequest) (*ModerationResult, error) {
+				return &ModerationResult{Labels: map[string]string{"category": "general"}}, nil
+			},
+		})
+		turn, err := session.SendAndCollect(MessageOptions{Prompt: "Hello"}, 5*time.Second)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if params := sent(); len(params) != 1 || params[0]["prompt"] != "Hello" {
+			t.Errorf("Expected the prompt to be sent unchanged, got %v", params)
+		}
+		if turn.Moderation == nil || turn.Moderation.Action != ModerationAllow || turn.Moderation.Labels["category"] != "general" {
+			t.Errorf("Unexpected moderation %+v", turn.Moderation)
+		}
+	})
+
+	t.Run("blocks prompts without sending them", func(t *testing.T) {
+		session, sent := newModeratedSession(t, &ModerationConfig{
+			Moderator: func(ctx context.Context, req ModerationRequest) (*ModerationResult, error) {
+				return &ModerationResult{Action: ModerationBlock, Reason: "export-controlled"}, nil
+			},
+		})
+		var events []SessionEvent
+		session.On(func(event SessionEvent) {
+			if event.Type == SDKPromptModerated {
+				events = append(events, event)
+			}
+		})
+
+		_, err := session.Send(MessageOptions{Prompt: "secret"})
+		var blocked *PromptBlockedError
+		if !errors.As(err, &blocked) || blocked.Result.Reason != "export-controlled" {
+			t.Fatalf("Expected a PromptBlockedError, got %v", err)
+		}
+		if params := sent(); len(params) != 0 {
+			t.Errorf("Expected nothing to be sent, got %v", params)
+		}
+		if len(events) != 1 {
+			t.Fatalf("Expected one moderation event, got %d", len(events))
+		}
+		if result, ok := PromptModeration(events[0]); !ok || result.Action != ModerationBlock {
+			t.Errorf("Unexpected moderation event %+v", result)
+		}
+	})
+
+	t.Run("fails closed on errors and timeouts", func(t *testing.T) {
+		session, sent := newModeratedSession(t, &ModerationConfig{
+			Moderator: func(ctx context.Context, req ModerationRequest) (*ModerationResult, error) {
+				return nil, errors.New("service unavailable")
+			},
+		})
+		if _, err := session.Send(MessageOptions{Prompt: "Hello"}); err == nil {
+			t.Error("Expected a moderator error to fail the send")
+		}
+
+		canceled := make(chan struct{})
+		session.registerModeration(&ModerationConfig{
+			Timeout: 10 * time.Millisecond,
+			Moderator: func(ctx context.Context, req ModerationRequest) (*ModerationResult, error) {
+				<-ctx.Done()
+				close(canceled)
+				return nil, nil
+			},
+		})
+		if _, err := session.Send(MessageOptions{Prompt: "Hello"}); !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("Expected a timeout, got %v", err)
+		}
+		select {
+		case <-canceled:
+		case <-time.After(time.Second):
+			t.Error("Expected the moderator's context to be canceled")
+		}
+
+		session.registerModeration(&ModerationConfig{
+			Moderator: func(ctx context.Context, req ModerationRequest) (*ModerationResult, error) {
+				return &ModerationResult{Action: "quarantine"}, nil
+			},
+		})
+		if _, err := session.Send(MessageOptions{Prompt: "Hello"}); err == nil {
+			t.Error("Expected an unknown action to fail the send")
+		}
+		if params := sent(); len(params) != 0 {
+			t.Errorf("Expected nothing to be sent, got %v", params)
+		}
+	})
+}
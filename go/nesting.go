@@ -0,0 +1,106 @@
+package copilot
+
+import (
+	"fmt"
+	"strings"
+)
+
+// DefaultMaxSessionDepth is the maximum nesting depth of sessions when no
+// MaxDepth is configured. See [SessionConfig.ParentSessionID].
+const DefaultMaxSessionDepth = 4
+
+// MaxDepthError is returned when creating a nested session would exceed the
+// maximum nesting depth, which usually means sub-agents are recursing.
+type MaxDepthError struct {
+	// Depth is the depth the session would have had.
+	Depth int
+	// MaxDepth is the maximum depth in effect.
+	MaxDepth int
+	// Ancestry are the IDs of the sessions the new session would have been nested
+	// in, root first.
+	Ancestry []string
+}
+
+func (e *MaxDepthError) Error() string {
+	return fmt.Sprintf("session depth %d exceeds maximum depth %d (ancestry: %s)",
+		e.Depth, e.MaxDepth, strings.Join(e.Ancestry, " > "))
+}
+
+// nesting is the position of a session in a tree of nested sessions.
+type nesting struct {
+	depth    int
+	ancestry []string
+	maxDepth int
+}
+
+// Depth returns how deeply the session is nested: 0 for a session without a
+// parent, 1 for a session whose parent has none, and so on.
+func (s *Session) Depth() int {
+	return s.nesting.depth
+}
+
+// Ancestry returns the IDs of the sessions this session is nested in, root
+// first, or nil for a session without a parent.
+func (s *Session) Ancestry() []string {
+	return append([]string(nil), s.nesting.ancestry...)
+}
+
+// resolveNesting returns the nesting of a new session with the given parent and
+// MaxDepth, or a [*MaxDepthError] if it would be nested too deeply. The limit
+// in effect is the smallest configured along the ancestry, so a nested session
+// can lower it but not raise it.
+func (c *Client) resolveNesting(parentID string, maxDepth int) (nesting, error) {
+	if parentID == "" {
+		if maxDepth <= 0 {
+			maxDepth = DefaultMaxSessionDepth
+		}
+		return nesting{maxDepth: maxDepth}, nil
+	}
+
+	c.sessionsMux.Lock()
+	parent, ok := c.sessions[parentID]
+	c.sessionsMux.Unlock()
+	if !ok {
+		return nesting{}, fmt.Errorf("unknown parent session %s", parentID)
+	}
+
+	n := nesting{
+		depth:    parent.nesting.depth + 1,
+		ancestry: append(parent.Ancestry(), parentID),
+		maxDepth: parent.nesting.maxDepth,
+	}
+	if maxDepth > 0 && maxDepth < n.maxDepth {
+		n.maxDepth = maxDepth
+	}
+	if n.depth > n.maxDepth {
+		err := &MaxDepthError{Depth: n.depth, MaxDepth: n.maxDepth, Ancestry: n.ancestry}
+		parent.emit(SDKMaxDepthExceeded, map[string]interface{}{
+			"parentSessionId": parentID,
+			"depth":           n.depth,
+			"maxDepth":        n.maxDepth,
+			"ancestry":        n.ancestry,
+		})
+		return nesting{}, err
+	}
+	return n, nil
+}
+
+// registerNesting records the session's position and announces it to its parent.
+func (c *Client) registerNesting(session *Session, n nesting) {
+	session.nesting = n
+	if n.depth == 0 {
+		return
+	}
+	parentID := n.ancestry[len(n.ancestry)-1]
+	c.sessionsMux.Lock()
+	parent, ok := c.sessions[parentID]
+	c.sessionsMux.Unlock()
+	if ok {
+		parent.emit(SDKSessionNested, map[string]interface{}{
+			"sessionId":       session.SessionID,
+			"parentSessionId": parentID,
+			"depth":           n.depth,
+			"ancestry":        n.ancestry,
+		})
+	}
+}
@@ -0,0 +1,73 @@
+package copilot
+
+import (
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"testing"
+)
+
+func TestSessionNesting(t *testing.T) {
+	var created atomic.Int32
+	rpc, _ := newFakeServer(t, func(method string, params map[string]interface{}) (map[string]interface{}, *JSONRPCError) {
+		if method != "session.create" {
+			return map[string]interface{}{}, nil
+		}
+		return map[string]interface{}{"sessionId": fmt.Sprintf("s%d", created.Add(1))}, nil
+	})
+	client := &Client{client: rpc, state: StateConnected, sessions: make(map[string]*Session)}
+
+	root, err := client.CreateSession(&SessionConfig{MaxDepth: 2})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	var nested []SessionEvent
+	root.On(func(event SessionEvent) {
+		if event.Type == SDKSessionNested {
+			nested = append(nested, event)
+		}
+	})
+
+	child, err := client.CreateSession(&SessionConfig{ParentSessionID: root.SessionID})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	grandchild, err := client.CreateSession(&SessionConfig{ParentSessionID: child.SessionID, MaxDepth: 10})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if root.Depth() != 0 || child.Depth() != 1 || grandchild.Depth() != 2 {
+		t.Errorf("Unexpected depths %d, %d, %d", root.Depth(), child.Depth(), grandchild.Depth())
+	}
+	if ancestry := grandchild.Ancestry(); len(ancestry) != 2 || ancestry[0] != "s1" || ancestry[1] != "s2" {
+		t.Errorf("Unexpected ancestry %v", ancestry)
+	}
+	if len(nested) != 1 || sdkEventString(nested[0], "sessionId") != child.SessionID {
+		t.Errorf("Expected the root to be told about its child, got %+v", nested)
+	}
+
+	var exceeded []SessionEvent
+	grandchild.On(func(event SessionEvent) {
+		if event.Type == SDKMaxDepthExceeded {
+			exceeded = append(exceeded, event)
+		}
+	})
+	_, err = client.CreateSession(&SessionConfig{ParentSessionID: grandchild.SessionID})
+	var depthErr *MaxDepthError
+	if !errors.As(err, &depthErr) {
+		t.Fatalf("Expected a MaxDepthError, got %v", err)
+	}
+	if depthErr.Depth != 3 || depthErr.MaxDepth != 2 || len(depthErr.Ancestry) != 3 {
+		t.Errorf("Unexpected error %+v", depthErr)
+	}
+	if created.Load() != 3 {
+		t.Errorf("Expected the server not to be asked to create the session, got %d creations", created.Load())
+	}
+	if len(exceeded) != 1 {
+		t.Errorf("Expected one max depth event, got %d", len(exceeded))
+	}
+
+	if _, err := client.CreateSession(&SessionConfig{ParentSessionID: "missing"}); err == nil {
+		t.Error("Expected an error for an unknown parent")
+	}
+}
@@ -0,0 +1,169 @@
+package copilot
+
+import (
+	"encoding/json"
+	"fmt"
+	"iter"
+)
+
+// ListOptions selects a page of a list operation such as
+// [Client.ListSessionsPage].
+type ListOptions struct {
+	// Cursor is the NextCursor of the previous page, or empty for the first
+	// page.
+	Cursor string
+	// Limit, if positive, asks the server for at most this many items. Servers
+	// may return fewer.
+	Limit int
+}
+
+// PageInfo describes where a page sits in a list.
+type PageInfo struct {
+	// NextCursor is the cursor of the next page, or empty if this is the last.
+	NextCursor string
+}
+
+// HasNextPage reports whether there are more items after this page.
+func (p PageInfo) HasNextPage() bool {
+	return p.NextCursor != ""
+}
+
+// Page is one page of a list operation.
+type Page[T any] struct {
+	Items    []T
+	PageInfo PageInfo
+}
+
+// params returns the request parameters for opts, which may be nil.
+func (opts *ListOptions) params() map[string]interface{} {
+	params := map[string]interface{}{}
+	if opts == nil {
+		return params
+	}
+	if opts.Cursor != "" {
+		params["cursor"] = opts.Cursor
+	}
+	if opts.Limit > 0 {
+		params["limit"] = opts.Limit
+	}
+	return params
+}
+
+// paginate iterates over the items of the pages fetch returns, starting at
+// opts, until the last page or the first error.
+func paginate[T any](opts *ListOptions, fetch func(opts *ListOptions) (*Page[T], error)) iter.Seq2[T, error] {
+	return func(yield func(T, error) bool) {
+		next := ListOptions{}
+		if opts != nil {
+			next = *opts
+		}
+		seen := make(map[string]bool)
+		for {
+			page, err := fetch(&next)
+			if err != nil {
+				var zero T
+				yield(zero, err)
+				return
+			}
+			for _, item := range page.Items {
+				if !yield(item, nil) {
+					return
+				}
+			}
+			cursor := page.PageInfo.NextCursor
+			if cursor == "" {
+				return
+			}
+			if seen[cursor] {
+				var zero T
+				yield(zero, fmt.Errorf("server returned cursor %q more than once", cursor))
+				return
+			}
+			seen[cursor] = true
+			next.Cursor = cursor
+		}
+	}
+}
+
+// listPage requests a page of method and decodes its items from the field
+// named key.
+func listPage[T any](c *Client, method, key string, opts *ListOptions) (*Page[T], error) {
+	client, err := c.autoConnect()
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := client.Request(method, opts.params())
+	if err != nil {
+		return nil, err
+	}
+	jsonBytes, err := json.Marshal(result)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal %s response: %w", method, err)
+	}
+	var response map[string]json.RawMessage
+	if err := json.Unmarshal(jsonBytes, &response); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal %s response: %w", method, err)
+	}
+	page := &Page[T]{}
+	if raw, ok := response["nextCursor"]; ok {
+		if err := json.Unmarshal(raw, &page.PageInfo.NextCursor); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal %s cursor: %w", method, err)
+		}
+	}
+	if raw, ok := response[key]; ok {
+		if err := json.Unmarshal(raw, &page.Items); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal %s response: %w", method, err)
+		}
+	}
+	return page, nil
+}
+
+// ListSessionsPage returns one page of the sessions known to the server. Pass
+// the returned PageInfo.NextCursor in opts to get the next page, or use
+// [Client.AllSessions] to iterate over every page. Servers that do not
+// paginate return every session in the first page.
+//
+// Example:
+//
+//	page, err := client.ListSessionsPage(&copilot.ListOptions{Limit: 50})
+//	for err == nil {
+//	    for _, session := range page.Items {
+//	        fmt.Println(session.SessionID)
+//	    }
+//	    if !page.PageInfo.HasNextPage() {
+//	        break
+//	    }
+//	    page, err = client.ListSessionsPage(&copilot.ListOptions{Limit: 50, Cursor: page.PageInfo.NextCursor})
+//	}
+func (c *Client) ListSessionsPage(opts *ListOptions) (*Page[SessionMetadata], error) {
+	return listPage[SessionMetadata](c, "session.list", "sessions", opts)
+}
+
+// AllSessions iterates over the sessions known to the server, fetching pages
+// as needed, starting at opts, which may be nil. Iteration stops at the first
+// error, which is yielded.
+//
+// Example:
+//
+//	for session, err := range client.AllSessions(&copilot.ListOptions{Limit: 100}) {
+//	    if err != nil {
+//	        log.Fatal(err)
+//	    }
+//	    fmt.Println(session.SessionID)
+//	}
+func (c *Client) AllSessions(opts *ListOptions) iter.Seq2[SessionMetadata, error] {
+	return paginate(opts, c.ListSessionsPage)
+}
+
+// ListModelsPage returns one page of the available models. Unlike
+// [Client.ListModels], pages are not cached.
+func (c *Client) ListModelsPage(opts *ListOptions) (*Page[ModelInfo], error) {
+	return listPage[ModelInfo](c, "models.list", "models", opts)
+}
+
+// AllModels iterates over the available models, fetching pages as needed. See
+// [Client.AllSessions].
+func (c *Client) AllModels(opts *ListOptions) iter.Seq2[ModelInfo, error] {
+	return paginate(opts, c.ListModelsPage)
+}
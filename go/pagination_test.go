@@ -0,0 +1,108 @@
+package copilot
+
+import (
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestPagination(t *testing.T) {
+	// pagedServer serves sessions s0..s4 two at a time, with the offset as the
+	// cursor.
+	var mu sync.Mutex
+	var requests []map[string]interface{}
+	rpc, _ := newFakeServer(t, func(method string, params map[string]interface{}) (map[string]interface{}, *JSONRPCError) {
+		mu.Lock()
+		requests = append(requests, params)
+		mu.Unlock()
+		switch method {
+		case "session.list":
+			ids := []string{"s0", "s1", "s2", "s3", "s4"}
+			start := 0
+			if cursor, ok := params["cursor"].(string); ok {
+				start = int(cursor[0] - '0')
+			}
+			end := min(start+2, len(ids))
+			var sessions []interface{}
+			for _, id := range ids[start:end] {
+				sessions = append(sessions, map[string]interface{}{"sessionId": id})
+			}
+			result := map[string]interface{}{"sessions": sessions}
+			if end < len(ids) {
+				result["nextCursor"] = string(rune('0' + end))
+			}
+			return result, nil
+		case "models.list":
+			return map[string]interface{}{"models": []interface{}{map[string]interface{}{"id": "m1"}}, "nextCursor": "again"}, nil
+		}
+		return nil, &JSONRPCError{Code: -32601, Message: "unknown method"}
+	})
+	client := &Client{client: rpc, state: StateConnected}
+
+	t.Run("returns a page and its cursor", func(t *testing.T) {
+		page, err := client.ListSessionsPage(&ListOptions{Limit: 2, Cursor: "2"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(page.Items) != 2 || page.Items[0].SessionID != "s2" || page.PageInfo.NextCursor != "4" || !page.PageInfo.HasNextPage() {
+			t.Errorf("Unexpected page %+v", page)
+		}
+		mu.Lock()
+		last := requests[len(requests)-1]
+		mu.Unlock()
+		if last["cursor"] != "2" || last["limit"] != float64(2) {
+			t.Errorf("Unexpected params %v", last)
+		}
+	})
+
+	t.Run("iterates over every page", func(t *testing.T) {
+		var ids []string
+		for session, err := range client.AllSessions(nil) {
+			if err != nil {
+				t.Fatal(err)
+			}
+			ids = append(ids, session.SessionID)
+		}
+		if strings.Join(ids, ",") != "s0,s1,s2,s3,s4" {
+			t.Errorf("Unexpected sessions %v", ids)
+		}
+	})
+
+	t.Run("stops fetching when the loop breaks", func(t *testing.T) {
+		mu.Lock()
+		before := len(requests)
+		mu.Unlock()
+		for range client.AllSessions(nil) {
+			break
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		if len(requests)-before != 1 {
+			t.Errorf("Expected one request, got %d", len(requests)-before)
+		}
+	})
+
+	t.Run("fails on a repeated cursor", func(t *testing.T) {
+		var models []string
+		var lastErr error
+		for model, err := range client.AllModels(nil) {
+			if err != nil {
+				lastErr = err
+				break
+			}
+			models = append(models, model.ID)
+		}
+		if len(models) != 2 || lastErr == nil || !strings.Contains(lastErr.Error(), "more than once") {
+			t.Errorf("Unexpected models %v, error %v", models, lastErr)
+		}
+	})
+
+	t.Run("yields request errors", func(t *testing.T) {
+		disconnected := &Client{}
+		for _, err := range disconnected.AllSessions(nil) {
+			if err == nil {
+				t.Error("Expected an error")
+			}
+		}
+	})
+}
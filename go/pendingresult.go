@@ -0,0 +1,228 @@
+package copilot
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// ErrNoPendingToolCall is returned by [Session.CompleteToolCall] for an ID that
+// is not pending, including one that has already been completed.
+var ErrNoPendingToolCall = errors.New("no pending tool call")
+
+// PendingToolCall describes a tool call deferred with
+// [ToolInvocation.PendingResult] that has not been completed yet.
+type PendingToolCall struct {
+	// ID is the pending ID passed to [Session.CompleteToolCall].
+	ID         string
+	ToolCallID string
+	ToolName   string
+	Arguments  interface{}
+	// DeferredAt is when the handler deferred the call.
+	DeferredAt time.Time
+}
+
+// PendingResult returns a result that defers the tool call until
+// [Session.CompleteToolCall] is called with id, so a handler that waits on
+// something slow, such as a human approval in chat, can return at once instead
+// of holding a goroutine for minutes. The ID defaults to the tool call ID. The
+// server keeps waiting for the result, and the turn stays in progress, until
+// the call is completed or the session is destroyed.
+//
+// The handler must return the result unchanged; the ID is reserved as soon as
+// PendingResult is called, so the call may be completed from another goroutine
+// before the handler returns. If id is already pending, the returned result
+// is a failure instead. The completed result is sent as given: result limits
+// and post-processors apply only to results the handler returns.
+//
+// Example:
+//
+//	tool := copilot.DefineTool("deploy", "Deploy a build after a human approves it",
+//	    func(params DeployParams, inv copilot.ToolInvocation) (copilot.ToolResult, error) {
+//	        approvals.Ask(params.Build, inv.ToolCallID)
+//	        return inv.PendingResult(inv.ToolCallID), nil
+//	    })
+//
+//	// Later, from the approval webhook:
+//	session.CompleteToolCall(toolCallID, copilot.ToolResult{
+//	    TextResultForLLM: "Deployed " + build,
+//	    ResultType:       "success",
+//	})
+func (inv ToolInvocation) PendingResult(id string) ToolResult {
+	if id == "" {
+		id = inv.ToolCallID
+	}
+	if inv.pending != nil && !inv.pending.reserve(PendingToolCall{
+		ID:         id,
+		ToolCallID: inv.ToolCallID,
+		ToolName:   inv.ToolName,
+		Arguments:  inv.Arguments,
+		DeferredAt: time.Now(),
+	}) {
+		return buildFailedToolResult(fmt.Sprintf("tool call %s is already pending", id))
+	}
+	return ToolResult{pending: id}
+}
+
+// CompleteToolCall completes the tool call deferred under id with result,
+// sending it to the server as if the handler had returned it. To fail the call,
+// set ResultType to "failure". It returns [ErrNoPendingToolCall] if no call is
+// pending under id.
+func (s *Session) CompleteToolCall(id string, result ToolResult) error {
+	if !s.pendingCalls.complete(id, result) {
+		return fmt.Errorf("failed to complete tool call %s: %w", id, ErrNoPendingToolCall)
+	}
+	return nil
+}
+
+// PendingToolCalls returns the session's deferred tool calls that have not been
+// completed, oldest first.
+func (s *Session) PendingToolCalls() []PendingToolCall {
+	return s.pendingCalls.list()
+}
+
+// failPendingToolCalls completes every pending tool call with a failure for
+// cause.
+func (s *Session) failPendingToolCalls(cause error) {
+	s.pendingCalls.fail(buildFailedToolResult(cause.Error()))
+}
+
+// pendingToolCalls holds a session's deferred tool calls by pending ID.
+type pendingToolCalls struct {
+	mu    sync.Mutex
+	calls map[string]*pendingToolCall
+}
+
+// pendingToolCall is a deferred tool call. It is delivered once it has both a
+// result and a way to send it.
+type pendingToolCall struct {
+	info PendingToolCall
+	// finish post-processes the result once the handler has returned.
+	finish func(ToolResult) ToolResult
+	// send responds to the server's tool call request.
+	send func(ToolResult)
+	// result is a completion that arrived before send was attached.
+	result *ToolResult
+}
+
+// deliver sends the result of call.
+func (call *pendingToolCall) deliver(result ToolResult) {
+	if call.finish != nil {
+		result = call.finish(result)
+	}
+	call.send(result)
+}
+
+// reserve records info as pending, and reports false if its ID already is.
+func (p *pendingToolCalls) reserve(info PendingToolCall) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if _, ok := p.calls[info.ID]; ok {
+		return false
+	}
+	if p.calls == nil {
+		p.calls = make(map[string]*pendingToolCall)
+	}
+	p.calls[info.ID] = &pendingToolCall{info: info}
+	return true
+}
+
+// prepare sets the function that post-processes the result of id.
+func (p *pendingToolCalls) prepare(id string, finish func(ToolResult) ToolResult) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if call, ok := p.calls[id]; ok {
+		call.finish = finish
+	}
+}
+
+// attach sets the function that sends the result of id, delivering it at once
+// if the call has already been completed.
+func (p *pendingToolCalls) attach(id string, send func(ToolResult)) {
+	p.mu.Lock()
+	call, ok := p.calls[id]
+	if !ok {
+		p.mu.Unlock()
+		return
+	}
+	call.send = send
+	if call.result == nil {
+		p.mu.Unlock()
+		return
+	}
+	delete(p.calls, id)
+	p.mu.Unlock()
+	call.deliver(*call.result)
+}
+
+// complete records the result of id, delivering it if it can be sent, and
+// reports false if id is not pending.
+func (p *pendingToolCalls) complete(id string, result ToolResult) bool {
+	p.mu.Lock()
+	call, ok := p.calls[id]
+	if !ok || call.result != nil {
+		p.mu.Unlock()
+		return false
+	}
+	if call.send == nil {
+		call.result = &result
+		p.mu.Unlock()
+		return true
+	}
+	delete(p.calls, id)
+	p.mu.Unlock()
+	call.deliver(result)
+	return true
+}
+
+// drop forgets id without delivering a result, for a reserved call whose
+// handler did not return its pending result.
+func (p *pendingToolCalls) drop(id string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	delete(p.calls, id)
+}
+
+// fail delivers result to every pending call and forgets them.
+func (p *pendingToolCalls) fail(result ToolResult) {
+	p.mu.Lock()
+	calls := p.calls
+	p.calls = nil
+	p.mu.Unlock()
+
+	for _, call := range calls {
+		if call.send != nil {
+			call.deliver(result)
+		}
+	}
+}
+
+// list returns the calls awaiting completion, oldest first.
+func (p *pendingToolCalls) list() []PendingToolCall {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	infos := make([]PendingToolCall, 0, len(p.calls))
+	for _, call := range p.calls {
+		if call.result == nil {
+			infos = append(infos, call.info)
+		}
+	}
+	sort.Slice(infos, func(i, j int) bool { return infos[i].DeferredAt.Before(infos[j].DeferredAt) })
+	return infos
+}
+
+// emitToolCallDeferred announces that a tool call was deferred with an
+// [SDKToolCallDeferred] event.
+func (c *Client) emitToolCallDeferred(inv ToolInvocation, id string) {
+	session := c.sessionByID(inv.SessionID)
+	if session == nil {
+		return
+	}
+	session.emit(SDKToolCallDeferred, map[string]interface{}{
+		"toolCallId": inv.ToolCallID,
+		"toolName":   inv.ToolName,
+		"pendingId":  id,
+	})
+}
@@ -0,0 +1,156 @@
+package copilot
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"io"
+	"testing"
+	"time"
+)
+
+func TestPendingResult(t *testing.T) {
+	// callDeferred calls a tool that defers with PendingResult, calling
+	// onDefer before the handler returns, and returns the response sent once
+	// the call is completed.
+	callDeferred := func(t *testing.T, session *Session, onDefer func(id string)) <-chan map[string]interface{} {
+		t.Helper()
+		session.registerTools([]Tool{{
+			Name: "deploy",
+			Handler: func(inv ToolInvocation) (ToolResult, error) {
+				result := inv.PendingResult("approval-1")
+				onDefer("approval-1")
+				return result, nil
+			},
+		}})
+		client := &Client{sessions: map[string]*Session{"s1": session}}
+		_, rpcErr := client.handleToolCallRequest(map[string]interface{}{
+			"sessionId":  "s1",
+			"toolCallId": "call-1",
+			"toolName":   "deploy",
+			"arguments":  map[string]interface{}{},
+		})
+		if rpcErr == nil || rpcErr.handoff == nil {
+			t.Fatalf("Expected the response to be deferred, got %v", rpcErr)
+		}
+		responses := make(chan map[string]interface{}, 1)
+		rpcErr.handoff(func(result map[string]interface{}, err *JSONRPCError) {
+			responses <- result
+		})
+		return responses
+	}
+	resultOf := func(t *testing.T, responses <-chan map[string]interface{}) ToolResult {
+		t.Helper()
+		select {
+		case response := <-responses:
+			return response["result"].(ToolResult)
+		case <-time.After(time.Second):
+			t.Fatal("Timed out waiting for the response")
+			return ToolResult{}
+		}
+	}
+
+	t.Run("responds when the call is completed", func(t *testing.T) {
+		session := NewSession("s1", nil, "")
+		var deferred []SessionEvent
+		session.On(func(event SessionEvent) {
+			if event.Type == SDKToolCallDeferred {
+				deferred = append(deferred, event)
+			}
+		})
+		responses := callDeferred(t, session, func(string) {})
+
+		if pending := session.PendingToolCalls(); len(pending) != 1 || pending[0].ID != "approval-1" || pending[0].ToolName != "deploy" {
+			t.Fatalf("Expected one pending call, got %+v", pending)
+		}
+		if len(deferred) != 1 || sdkEventString(deferred[0], "pendingId") != "approval-1" {
+			t.Errorf("Expected a deferred event, got %+v", deferred)
+		}
+		select {
+		case <-responses:
+			t.Fatal("Expected no response before the call is completed")
+		default:
+		}
+
+		if err := session.CompleteToolCall("approval-1", ToolResult{TextResultForLLM: "Deployed", ResultType: "success"}); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if result := resultOf(t, responses); result.TextResultForLLM != "Deployed" {
+			t.Errorf("Expected the completed result, got %+v", result)
+		}
+		if err := session.CompleteToolCall("approval-1", ToolResult{}); !errors.Is(err, ErrNoPendingToolCall) {
+			t.Errorf("Expected ErrNoPendingToolCall for a completed call, got %v", err)
+		}
+		if pending := session.PendingToolCalls(); len(pending) != 0 {
+			t.Errorf("Expected no pending calls, got %+v", pending)
+		}
+	})
+
+	t.Run("delivers a completion made before the handler returns", func(t *testing.T) {
+		session := NewSession("s1", nil, "")
+		responses := callDeferred(t, session, func(id string) {
+			if err := session.CompleteToolCall(id, ToolResult{TextResultForLLM: "Approved", ResultType: "success"}); err != nil {
+				t.Errorf("Unexpected error: %v", err)
+			}
+		})
+		if result := resultOf(t, responses); result.TextResultForLLM != "Approved" {
+			t.Errorf("Expected the early result, got %+v", result)
+		}
+	})
+
+	t.Run("fails pending calls when the session is destroyed", func(t *testing.T) {
+		session := NewSession("s1", nil, "")
+		responses := callDeferred(t, session, func(string) {})
+		session.failPendingToolCalls(ErrSessionDestroyed)
+		if result := resultOf(t, responses); result.ResultType != "failure" {
+			t.Errorf("Expected a failure, got %+v", result)
+		}
+	})
+
+	t.Run("rejects an ID that is already pending", func(t *testing.T) {
+		pending := &pendingToolCalls{}
+		inv := ToolInvocation{ToolCallID: "call-1", pending: pending}
+		if result := inv.PendingResult(""); result.pending != "call-1" {
+			t.Fatalf("Expected the ID to default to the tool call ID, got %q", result.pending)
+		}
+		if result := inv.PendingResult("call-1"); result.ResultType != "failure" {
+			t.Errorf("Expected a failure for a duplicate ID, got %+v", result)
+		}
+	})
+}
+
+func TestJSONRPCClient_DeferredResponse(t *testing.T) {
+	requestsReader, requestsWriter := io.Pipe()
+	responsesReader, responsesWriter := io.Pipe()
+	server := &fakeServer{writer: responsesWriter}
+	rpc := NewJSONRPCClient(requestsWriter, responsesReader)
+	handoffs := make(chan responder, 1)
+	rpc.SetRequestHandler("tool.call", func(params map[string]interface{}) (map[string]interface{}, *JSONRPCError) {
+		return nil, deferResponse(func(respond responder) { handoffs <- respond })
+	})
+	rpc.Start()
+	t.Cleanup(func() {
+		rpc.Stop()
+		requestsWriter.Close()
+		responsesWriter.Close()
+	})
+
+	server.send(JSONRPCRequest{JSONRPC: "2.0", ID: json.RawMessage(`7`), Method: "tool.call"})
+	respond := <-handoffs
+	go func() {
+		respond(map[string]interface{}{"ok": true}, nil)
+		respond(map[string]interface{}{"ok": false}, nil)
+	}()
+
+	body, err := readFrame(bufio.NewReader(requestsReader))
+	if err != nil {
+		t.Fatalf("Failed to read the response: %v", err)
+	}
+	var response JSONRPCResponse
+	if err := json.Unmarshal(body, &response); err != nil {
+		t.Fatalf("Failed to decode the response: %v", err)
+	}
+	if string(response.ID) != "7" || response.Result["ok"] != true || response.Error != nil {
+		t.Errorf("Expected the deferred result, got %s", body)
+	}
+}
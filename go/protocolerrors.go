@@ -0,0 +1,139 @@
+package copilot
+
+import (
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// DefaultProtocolErrorHistory is the number of protocol errors a [Client]
+// retains for [Client.RecentErrors] unless
+// [ClientOptions.ProtocolErrorHistory] is set.
+const DefaultProtocolErrorHistory = 100
+
+// maxProtocolErrorPayload is the number of bytes of a frame's payload kept
+// with a protocol error.
+const maxProtocolErrorPayload = 512
+
+// ProtocolErrorKind classifies a [ProtocolError].
+type ProtocolErrorKind string
+
+const (
+	// ProtocolErrorParse is a frame that is not valid JSON, or not a JSON-RPC
+	// request, response, or notification. It is ignored.
+	ProtocolErrorParse ProtocolErrorKind = "parse"
+	// ProtocolErrorFraming is a stream that could not be split into frames,
+	// such as one with an invalid Content-Length header or cut off mid-frame.
+	// The connection is closed.
+	ProtocolErrorFraming ProtocolErrorKind = "framing"
+	// ProtocolErrorOrphanResponse is a response to no pending request, such
+	// as one with an unknown ID or a response sent after the request ended.
+	ProtocolErrorOrphanResponse ProtocolErrorKind = "orphan_response"
+	// ProtocolErrorMethodNotFound is a method-not-found error the server
+	// replied to a request of the client with, usually because the server is
+	// older than the SDK.
+	ProtocolErrorMethodNotFound ProtocolErrorKind = "method_not_found"
+	// ProtocolErrorUnhandledRequest is a request from the server for a method
+	// the client has no handler for. The client replied method-not-found.
+	ProtocolErrorUnhandledRequest ProtocolErrorKind = "unhandled_request"
+)
+
+// ProtocolError is a protocol-level anomaly on a client's connection, as
+// returned by [Client.RecentErrors].
+type ProtocolError struct {
+	// Time is when the anomaly was seen.
+	Time time.Time
+	Kind ProtocolErrorKind
+	// Method is the JSON-RPC method involved, if known.
+	Method string
+	// Message describes the anomaly.
+	Message string
+	// Payload is the start of the frame involved, if any, cut to at most 512
+	// bytes, and PayloadBytes the size of the whole frame.
+	Payload      string
+	PayloadBytes int
+}
+
+// protocolErrorLog is a ring buffer of the most recent protocol errors.
+type protocolErrorLog struct {
+	mu      sync.Mutex
+	entries []ProtocolError
+	// next is the index the next entry is written at, once entries is full.
+	next int
+	size int
+}
+
+// newProtocolErrorLog returns a log of the last size errors, or nil if size
+// is negative. Zero means [DefaultProtocolErrorHistory].
+func newProtocolErrorLog(size int) *protocolErrorLog {
+	if size < 0 {
+		return nil
+	}
+	if size == 0 {
+		size = DefaultProtocolErrorHistory
+	}
+	return &protocolErrorLog{size: size}
+}
+
+// record adds an error, evicting the oldest if the log is full.
+func (l *protocolErrorLog) record(kind ProtocolErrorKind, method, message string, payload []byte) {
+	if l == nil {
+		return
+	}
+	entry := ProtocolError{
+		Time:         time.Now(),
+		Kind:         kind,
+		Method:       method,
+		Message:      message,
+		Payload:      truncatePayload(payload),
+		PayloadBytes: len(payload),
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if len(l.entries) < l.size {
+		l.entries = append(l.entries, entry)
+		return
+	}
+	l.entries[l.next] = entry
+	l.next = (l.next + 1) % l.size
+}
+
+// recent returns the retained errors, oldest first.
+func (l *protocolErrorLog) recent() []ProtocolError {
+	if l == nil {
+		return nil
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	recent := make([]ProtocolError, 0, len(l.entries))
+	recent = append(recent, l.entries[l.next:]...)
+	return append(recent, l.entries[:l.next]...)
+}
+
+// truncatePayload returns the start of a payload, cut at a character boundary.
+func truncatePayload(payload []byte) string {
+	if len(payload) <= maxProtocolErrorPayload {
+		return string(payload)
+	}
+	cut := maxProtocolErrorPayload
+	for cut > 0 && !utf8.RuneStart(payload[cut]) {
+		cut--
+	}
+	return string(payload[:cut]) + "..."
+}
+
+// RecentErrors returns the last protocol-level anomalies on the client's
+// connections, oldest first: frames that could not be parsed, responses to no
+// pending request, and method-not-found errors in either direction. The
+// history spans reconnects and holds the last
+// [ClientOptions.ProtocolErrorHistory] errors, so operators can diagnose a
+// flaky connection without raising log verbosity.
+//
+// Example:
+//
+//	for _, e := range client.RecentErrors() {
+//	    log.Printf("%s %s %s: %s", e.Time.Format(time.RFC3339), e.Kind, e.Method, e.Message)
+//	}
+func (c *Client) RecentErrors() []ProtocolError {
+	return c.protocolErrors.recent()
+}
@@ -0,0 +1,130 @@
+package copilot
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestProtocolErrorLog(t *testing.T) {
+	t.Run("keeps the most recent errors, oldest first", func(t *testing.T) {
+		log := newProtocolErrorLog(3)
+		for _, method := range []string{"a", "b", "c", "d", "e"} {
+			log.record(ProtocolErrorParse, method, "bad", nil)
+		}
+		recent := log.recent()
+		if len(recent) != 3 || recent[0].Method != "c" || recent[2].Method != "e" {
+			t.Errorf("Expected the last 3 errors in order, got %+v", recent)
+		}
+	})
+
+	t.Run("truncates payloads", func(t *testing.T) {
+		log := newProtocolErrorLog(0)
+		payload := strings.Repeat("é", maxProtocolErrorPayload)
+		log.record(ProtocolErrorParse, "", "bad", []byte(payload))
+		entry := log.recent()[0]
+		if entry.PayloadBytes != len(payload) || !strings.HasSuffix(entry.Payload, "...") || len(entry.Payload) > maxProtocolErrorPayload+3 {
+			t.Errorf("Expected a truncated payload, got %d bytes of %d", len(entry.Payload), entry.PayloadBytes)
+		}
+		if !strings.HasPrefix(entry.Payload, "éé") || strings.ContainsRune(entry.Payload, '�') {
+			t.Errorf("Expected the payload cut at a character boundary")
+		}
+	})
+
+	t.Run("can be disabled", func(t *testing.T) {
+		log := newProtocolErrorLog(-1)
+		log.record(ProtocolErrorParse, "", "bad", nil)
+		if recent := log.recent(); len(recent) != 0 {
+			t.Errorf("Expected no errors, got %+v", recent)
+		}
+	})
+}
+
+func TestJSONRPCClient_RecordsProtocolErrors(t *testing.T) {
+	requestsReader, requestsWriter := io.Pipe()
+	responsesReader, responsesWriter := io.Pipe()
+	server := &fakeServer{writer: responsesWriter}
+	go func() {
+		reader := bufio.NewReader(requestsReader)
+		for {
+			body, err := readFrame(reader)
+			if err != nil {
+				return
+			}
+			var request JSONRPCRequest
+			if err := json.Unmarshal(body, &request); err != nil || request.Method == "" {
+				continue
+			}
+			server.send(JSONRPCResponse{JSONRPC: "2.0", ID: request.ID, Error: &JSONRPCError{Code: -32601, Message: "Method not found: " + request.Method}})
+		}
+	}()
+
+	client := NewJSONRPCClient(requestsWriter, responsesReader)
+	client.protocolErrors = newProtocolErrorLog(10)
+	client.Start()
+	t.Cleanup(func() {
+		client.Stop()
+		requestsWriter.Close()
+		responsesWriter.Close()
+	})
+
+	if _, err := client.Request("session.fork", nil); err == nil {
+		t.Fatal("Expected a method-not-found error")
+	}
+	io.WriteString(responsesWriter, frame(`{"jsonrpc":"2.0","id":`))
+	io.WriteString(responsesWriter, frame(`{"jsonrpc":"2.0"}`))
+	server.send(JSONRPCResponse{JSONRPC: "2.0", ID: json.RawMessage(`"unknown"`), Result: map[string]interface{}{}})
+	server.send(JSONRPCResponse{JSONRPC: "2.0", ID: json.RawMessage(`7`), Result: map[string]interface{}{}})
+	server.send(JSONRPCRequest{JSONRPC: "2.0", ID: json.RawMessage(`"r1"`), Method: "sampling.create"})
+
+	want := []ProtocolErrorKind{
+		ProtocolErrorMethodNotFound,
+		ProtocolErrorParse,
+		ProtocolErrorParse,
+		ProtocolErrorOrphanResponse,
+		ProtocolErrorOrphanResponse,
+		ProtocolErrorUnhandledRequest,
+	}
+	deadline := time.Now().Add(2 * time.Second)
+	var recent []ProtocolError
+	for time.Now().Before(deadline) {
+		if recent = client.protocolErrors.recent(); len(recent) >= len(want) {
+			break
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	if len(recent) != len(want) {
+		t.Fatalf("Expected %d errors, got %+v", len(want), recent)
+	}
+	for i, kind := range want {
+		if recent[i].Kind != kind {
+			t.Errorf("Error %d: expected %s, got %s (%s)", i, kind, recent[i].Kind, recent[i].Message)
+		}
+	}
+	if recent[0].Method != "session.fork" || recent[5].Method != "sampling.create" {
+		t.Errorf("Expected methods to be recorded, got %q and %q", recent[0].Method, recent[5].Method)
+	}
+	if !strings.HasPrefix(recent[1].Message, "invalid JSON") || recent[1].Payload != `{"jsonrpc":"2.0","id":` {
+		t.Errorf("Expected the invalid frame to be recorded, got %+v", recent[1])
+	}
+	if recent[2].Message != "frame is not a request, response, or notification" {
+		t.Errorf("Unexpected message %q", recent[2].Message)
+	}
+}
+
+func TestClient_RecentErrors(t *testing.T) {
+	client := NewClient(&ClientOptions{ProtocolErrorHistory: 2})
+	client.protocolErrors.record(ProtocolErrorFraming, "", "unexpected EOF", nil)
+	if recent := client.RecentErrors(); len(recent) != 1 || recent[0].Kind != ProtocolErrorFraming {
+		t.Errorf("Expected the recorded error, got %+v", recent)
+	}
+	if snapshot := client.DebugDump(); len(snapshot.ProtocolErrors) != 1 {
+		t.Errorf("Expected the debug snapshot to include protocol errors, got %+v", snapshot.ProtocolErrors)
+	}
+	if recent := NewClient(&ClientOptions{ProtocolErrorHistory: -1}).RecentErrors(); recent != nil {
+		t.Errorf("Expected no history, got %+v", recent)
+	}
+}
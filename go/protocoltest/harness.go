@@ -0,0 +1,623 @@
+package protocoltest
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	copilot "github.com/github/copilot-sdk/go"
+)
+
+// Methods used by the harness.
+const (
+	callMethod    = "protocoltest.call"
+	noteMethod    = "protocoltest.note"
+	eventMethod   = "protocoltest.event"
+	echoMethod    = "protocoltest.echo"
+	failMethod    = "protocoltest.fail"
+	panicMethod   = "protocoltest.panic"
+	unknownMethod = "protocoltest.unknown"
+)
+
+// failCode is the error code returned by the fail handler and by error replies.
+const failCode = -32042
+
+// replyPlan is how the server answers a client request.
+type replyPlan int
+
+const (
+	replyResult replyPlan = iota
+	replyError
+	replyTwice
+	replyAfterNoise
+	replyDeferred
+	replyNever
+	numReplyPlans
+)
+
+// framing is a valid variant of Content-Length framing.
+type framing int
+
+const (
+	framingCanonical framing = iota
+	framingLowercase
+	framingExtraHeader
+	framingBlankLines
+	framingBareNewlines
+	numFramings
+)
+
+func frame(body []byte, f framing) []byte {
+	var header string
+	switch f {
+	case framingLowercase:
+		header = fmt.Sprintf("content-length:  %d \r\n\r\n", len(body))
+	case framingExtraHeader:
+		header = fmt.Sprintf("Content-Type: application/vscode-jsonrpc; charset=utf-8\r\nContent-Length: %d\r\n\r\n", len(body))
+	case framingBlankLines:
+		header = fmt.Sprintf("\r\n\r\nContent-Length: %d\r\n\r\n", len(body))
+	case framingBareNewlines:
+		header = fmt.Sprintf("Content-Length: %d\n\n", len(body))
+	default:
+		header = fmt.Sprintf("Content-Length: %d\r\n\r\n", len(body))
+	}
+	return append([]byte(header), body...)
+}
+
+// nearValidBodies are well-framed messages the client must ignore.
+var nearValidBodies = []string{
+	`{`,
+	`{}`,
+	`[]`,
+	`null`,
+	`42`,
+	`"protocoltest"`,
+	`{"jsonrpc":"2.0","id":"protocoltest-unknown","result":{}}`,
+	`{"jsonrpc":"2.0","id":17,"result":{}}`,
+	`{"jsonrpc":"2.0","id":"protocoltest-unknown","result":5}`,
+	`{"jsonrpc":"2.0","id":"protocoltest-unknown","error":{"code":-1,"message":"late"}}`,
+	`{"jsonrpc":"2.0","method":""}`,
+	`{"jsonrpc":"2.0","method":"` + eventMethod + `","params":"not an object"}`,
+}
+
+// message is a decoded frame received by the server.
+type message struct {
+	ID     json.RawMessage        `json:"id"`
+	Method string                 `json:"method"`
+	Params map[string]interface{} `json:"params"`
+	Result map[string]interface{} `json:"result"`
+	Error  *copilot.JSONRPCError  `json:"error"`
+}
+
+// serverCall is a request sent by the server to the client.
+type serverCall struct {
+	method  string
+	token   string
+	answers int
+}
+
+// plannedReply is a client request the server has been told how to answer.
+type plannedReply struct {
+	plan    replyPlan
+	framing framing
+}
+
+type harness struct {
+	config Config
+	rng    *rand.Rand
+	client *copilot.JSONRPCClient
+	server io.ReadWriteCloser
+	out    *frameQueue
+
+	mu          sync.Mutex
+	failures    []string
+	plans       map[string]plannedReply
+	deferred    [][]byte
+	serverCalls map[string]*serverCall
+	notes       []int
+	events      []int
+
+	answered   sync.WaitGroup
+	unanswered []chan error
+}
+
+func (h *harness) fail(format string, args ...interface{}) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.failures = append(h.failures, fmt.Sprintf(format, args...))
+}
+
+func runSequence(config Config, seed int64) error {
+	clientConn, serverConn, err := config.Pipe()
+	if err != nil {
+		return fmt.Errorf("failed to create pipe: %w", err)
+	}
+	defer serverConn.Close()
+
+	h := &harness{
+		config:      config,
+		rng:         rand.New(rand.NewSource(seed)),
+		client:      copilot.NewJSONRPCClient(clientConn, clientConn),
+		server:      serverConn,
+		out:         newFrameQueue(),
+		plans:       make(map[string]plannedReply),
+		serverCalls: make(map[string]*serverCall),
+	}
+	h.registerHandlers()
+	h.client.Start()
+
+	readerDone := make(chan struct{})
+	writerDone := make(chan struct{})
+	go func() {
+		defer close(readerDone)
+		h.readLoop()
+	}()
+	go func() {
+		defer close(writerDone)
+		h.out.drain(serverConn, rand.New(rand.NewSource(seed+1)))
+	}()
+
+	h.runSteps()
+	h.finish()
+
+	h.out.close()
+	serverConn.Close()
+	h.await(readerDone, "the server reader did not exit after the transport was closed")
+	h.await(writerDone, "the server writer did not exit after the transport was closed")
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return errFailures(h.failures)
+}
+
+func (h *harness) registerHandlers() {
+	h.client.SetNotificationHandler(func(method string, params map[string]interface{}) {
+		if method != eventMethod {
+			h.fail("unexpected notification %q delivered", method)
+			return
+		}
+		seq, ok := params["seq"].(float64)
+		if !ok {
+			h.fail("notification delivered with params %v", params)
+			return
+		}
+		h.mu.Lock()
+		h.events = append(h.events, int(seq))
+		h.mu.Unlock()
+	})
+	h.client.SetRequestHandler(echoMethod, func(params map[string]interface{}) (map[string]interface{}, *copilot.JSONRPCError) {
+		return map[string]interface{}{"token": params["token"]}, nil
+	})
+	h.client.SetRequestHandler(failMethod, func(params map[string]interface{}) (map[string]interface{}, *copilot.JSONRPCError) {
+		return nil, &copilot.JSONRPCError{Code: failCode, Message: "failed"}
+	})
+	h.client.SetRequestHandler(panicMethod, func(params map[string]interface{}) (map[string]interface{}, *copilot.JSONRPCError) {
+		panic("protocoltest")
+	})
+}
+
+// runSteps performs the random operations of the sequence.
+func (h *harness) runSteps() {
+	var notes, events int
+	for i := 0; i < h.config.Steps; i++ {
+		if h.rng.Float64() < h.config.NearValidRate {
+			body := nearValidBodies[h.rng.Intn(len(nearValidBodies))]
+			h.out.push(frame([]byte(body), framing(h.rng.Intn(int(numFramings)))))
+			continue
+		}
+		switch h.rng.Intn(4) {
+		case 0:
+			h.clientRequest(fmt.Sprintf("r%d", i), replyPlan(h.rng.Intn(int(numReplyPlans))))
+		case 1:
+			if err := h.client.Notify(noteMethod, map[string]interface{}{"seq": notes}); err != nil {
+				h.fail("Notify failed: %v", err)
+			}
+			notes++
+		case 2:
+			h.serverNotify(events)
+			events++
+		case 3:
+			h.serverRequest(i)
+		}
+	}
+
+	// Answer deferred requests, in reverse order, then check the stream is
+	// still in sync with a final request.
+	h.waitFor(func() bool {
+		h.mu.Lock()
+		defer h.mu.Unlock()
+		return len(h.plans) == 0
+	}, "deadlock: client requests did not reach the server")
+	h.mu.Lock()
+	deferred := h.deferred
+	h.deferred = nil
+	h.mu.Unlock()
+	for i := len(deferred) - 1; i >= 0; i-- {
+		h.out.push(deferred[i])
+	}
+	h.clientRequest("probe", replyResult)
+
+	h.waitFor(func() bool {
+		h.mu.Lock()
+		defer h.mu.Unlock()
+		return len(h.notes) == notes && len(h.events) == events
+	}, "notifications were not all delivered")
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for i, seq := range h.notes {
+		if seq != i {
+			h.failures = append(h.failures, fmt.Sprintf("client notifications arrived out of order: %v", h.notes))
+			break
+		}
+	}
+	for i, seq := range h.events {
+		if seq != i {
+			h.failures = append(h.failures, fmt.Sprintf("server notifications were delivered out of order: %v", h.events))
+			break
+		}
+	}
+}
+
+// clientRequest sends a request from the client, which the server answers
+// according to plan.
+func (h *harness) clientRequest(token string, plan replyPlan) {
+	h.mu.Lock()
+	h.plans[token] = plannedReply{plan: plan, framing: framing(h.rng.Intn(int(numFramings)))}
+	h.mu.Unlock()
+
+	var result chan error
+	if plan == replyNever {
+		result = make(chan error, 1)
+		h.unanswered = append(h.unanswered, result)
+	} else {
+		h.answered.Add(1)
+	}
+
+	go func() {
+		res, err := h.client.Request(callMethod, map[string]interface{}{"token": token})
+		if plan == replyNever {
+			result <- err
+			return
+		}
+		defer h.answered.Done()
+
+		if plan == replyError {
+			var rpcErr *copilot.JSONRPCError
+			if !errors.As(err, &rpcErr) || rpcErr.Code != failCode || rpcErr.Message != token {
+				h.fail("request %s: expected error %d %q, got %v", token, failCode, token, err)
+			}
+			return
+		}
+		if err != nil {
+			h.fail("request %s: unexpected error: %v", token, err)
+			return
+		}
+		if res["token"] != token {
+			h.fail("request %s: received the response to request %v", token, res["token"])
+		}
+	}()
+}
+
+func (h *harness) serverNotify(seq int) {
+	body, _ := json.Marshal(map[string]interface{}{
+		"jsonrpc": "2.0",
+		"method":  eventMethod,
+		"params":  map[string]interface{}{"seq": seq},
+	})
+	h.out.push(frame(body, framing(h.rng.Intn(int(numFramings)))))
+}
+
+// serverRequest sends a request from the server to the client, alternating
+// between string and number IDs.
+func (h *harness) serverRequest(i int) {
+	methods := []string{echoMethod, failMethod, panicMethod, unknownMethod}
+	call := &serverCall{method: methods[h.rng.Intn(len(methods))], token: fmt.Sprintf("s%d", i)}
+
+	var id json.RawMessage
+	if h.rng.Intn(2) == 0 {
+		id = json.RawMessage(strconv.Itoa(i))
+	} else {
+		id = json.RawMessage(strconv.Quote(call.token))
+	}
+	h.mu.Lock()
+	h.serverCalls[string(id)] = call
+	h.mu.Unlock()
+
+	body, _ := json.Marshal(map[string]interface{}{
+		"jsonrpc": "2.0",
+		"id":      id,
+		"method":  call.method,
+		"params":  map[string]interface{}{"token": call.token},
+	})
+	h.out.push(frame(body, framing(h.rng.Intn(int(numFramings)))))
+}
+
+// readLoop handles the frames the client sends to the server.
+func (h *harness) readLoop() {
+	reader := bufio.NewReader(h.server)
+	for {
+		body, err := readFrame(reader)
+		if err != nil {
+			return
+		}
+		var msg message
+		if err := json.Unmarshal(body, &msg); err != nil {
+			h.fail("client sent invalid JSON: %s", body)
+			continue
+		}
+		switch {
+		case msg.Method != "" && len(msg.ID) > 0:
+			h.handleClientRequest(msg)
+		case msg.Method != "":
+			h.handleClientNotification(msg)
+		case len(msg.ID) > 0:
+			h.handleClientResponse(msg)
+		default:
+			h.fail("client sent an invalid message: %s", body)
+		}
+	}
+}
+
+func (h *harness) handleClientRequest(msg message) {
+	token, _ := msg.Params["token"].(string)
+	h.mu.Lock()
+	planned, ok := h.plans[token]
+	delete(h.plans, token)
+	h.mu.Unlock()
+	if msg.Method != callMethod || !ok {
+		h.fail("client sent unexpected request %s with params %v", msg.Method, msg.Params)
+		return
+	}
+
+	reply, _ := json.Marshal(map[string]interface{}{
+		"jsonrpc": "2.0",
+		"id":      msg.ID,
+		"result":  map[string]interface{}{"token": token},
+	})
+	switch planned.plan {
+	case replyResult:
+		h.out.push(frame(reply, planned.framing))
+	case replyError:
+		errReply, _ := json.Marshal(map[string]interface{}{
+			"jsonrpc": "2.0",
+			"id":      msg.ID,
+			"error":   map[string]interface{}{"code": failCode, "message": token},
+		})
+		h.out.push(frame(errReply, planned.framing))
+	case replyTwice:
+		h.out.push(frame(reply, planned.framing))
+		h.out.push(frame(reply, planned.framing))
+	case replyAfterNoise:
+		h.out.push(frame([]byte(nearValidBodies[0]), planned.framing))
+		h.out.push(frame(reply, planned.framing))
+	case replyDeferred:
+		h.mu.Lock()
+		h.deferred = append(h.deferred, frame(reply, planned.framing))
+		h.mu.Unlock()
+	}
+}
+
+func (h *harness) handleClientNotification(msg message) {
+	seq, ok := msg.Params["seq"].(float64)
+	if msg.Method != noteMethod || !ok {
+		h.fail("client sent unexpected notification %s with params %v", msg.Method, msg.Params)
+		return
+	}
+	h.mu.Lock()
+	h.notes = append(h.notes, int(seq))
+	h.mu.Unlock()
+}
+
+func (h *harness) handleClientResponse(msg message) {
+	h.mu.Lock()
+	call, ok := h.serverCalls[string(msg.ID)]
+	if ok {
+		call.answers++
+	}
+	h.mu.Unlock()
+	if !ok {
+		h.fail("client sent a response with unknown ID %s", msg.ID)
+		return
+	}
+	if call.answers > 1 {
+		h.fail("client answered request %s more than once", msg.ID)
+		return
+	}
+
+	expectedCode := map[string]int{failMethod: failCode, panicMethod: -32603, unknownMethod: -32601}[call.method]
+	switch {
+	case call.method == echoMethod && (msg.Error != nil || msg.Result["token"] != call.token):
+		h.fail("request %s to %s: expected result with token %s, got result %v error %v", msg.ID, call.method, call.token, msg.Result, msg.Error)
+	case call.method != echoMethod && (msg.Error == nil || msg.Error.Code != expectedCode):
+		h.fail("request %s to %s: expected error %d, got result %v error %v", msg.ID, call.method, expectedCode, msg.Result, msg.Error)
+	}
+}
+
+// finish waits for outstanding work, stops the client, and checks that nothing
+// is left pending.
+func (h *harness) finish() {
+	answered := make(chan struct{})
+	go func() {
+		h.answered.Wait()
+		close(answered)
+	}()
+	h.await(answered, "deadlock: answered requests did not complete")
+
+	h.waitFor(func() bool {
+		h.mu.Lock()
+		defer h.mu.Unlock()
+		for _, call := range h.serverCalls {
+			if call.answers == 0 {
+				return false
+			}
+		}
+		return true
+	}, "deadlock: server requests were not answered")
+
+	if pending := h.client.PendingRequests(); pending != len(h.unanswered) {
+		h.fail("expected %d pending requests before stop, got %d", len(h.unanswered), pending)
+	}
+
+	stopped := make(chan struct{})
+	go func() {
+		h.client.Stop()
+		h.client.Stop()
+		close(stopped)
+	}()
+	if !h.await(stopped, "deadlock: Stop did not return") {
+		return
+	}
+
+	for _, result := range h.unanswered {
+		select {
+		case err := <-result:
+			if err == nil {
+				h.fail("an unanswered request succeeded")
+			}
+		case <-time.After(h.config.Timeout):
+			h.fail("deadlock: an unanswered request did not fail after Stop")
+			return
+		}
+	}
+	if pending := h.client.PendingRequests(); pending != 0 {
+		h.fail("orphaned pending requests after Stop: %d", pending)
+	}
+
+	afterStop := make(chan error, 1)
+	go func() {
+		_, err := h.client.Request(callMethod, map[string]interface{}{"token": "after-stop"})
+		afterStop <- err
+	}()
+	select {
+	case err := <-afterStop:
+		if err == nil {
+			h.fail("a request after Stop succeeded")
+		}
+	case <-time.After(h.config.Timeout):
+		h.fail("deadlock: a request after Stop did not fail")
+	}
+}
+
+// await waits for done, recording failure if it times out.
+func (h *harness) await(done <-chan struct{}, failure string) bool {
+	select {
+	case <-done:
+		return true
+	case <-time.After(h.config.Timeout):
+		h.fail("%s", failure)
+		return false
+	}
+}
+
+// waitFor polls cond, recording failure if it does not hold within the timeout.
+func (h *harness) waitFor(cond func() bool, failure string) {
+	deadline := time.Now().Add(h.config.Timeout)
+	for !cond() {
+		if time.Now().After(deadline) {
+			h.fail("%s", failure)
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+// frameQueue is an unbounded queue of frames written to the server end by a
+// single goroutine, so that the server never blocks reading the client while
+// writing to it.
+type frameQueue struct {
+	mu     sync.Mutex
+	frames [][]byte
+	closed bool
+	wake   chan struct{}
+}
+
+func newFrameQueue() *frameQueue {
+	return &frameQueue{wake: make(chan struct{}, 1)}
+}
+
+func (q *frameQueue) push(frame []byte) {
+	q.mu.Lock()
+	q.frames = append(q.frames, frame)
+	q.mu.Unlock()
+	q.signal()
+}
+
+func (q *frameQueue) close() {
+	q.mu.Lock()
+	q.closed = true
+	q.mu.Unlock()
+	q.signal()
+}
+
+func (q *frameQueue) signal() {
+	select {
+	case q.wake <- struct{}{}:
+	default:
+	}
+}
+
+// drain writes queued frames to w until the queue is closed or a write fails.
+// Frames are randomly split across several writes.
+func (q *frameQueue) drain(w io.Writer, rng *rand.Rand) {
+	for {
+		q.mu.Lock()
+		if len(q.frames) == 0 {
+			closed := q.closed
+			q.mu.Unlock()
+			if closed {
+				return
+			}
+			<-q.wake
+			continue
+		}
+		frame := q.frames[0]
+		q.frames = q.frames[1:]
+		q.mu.Unlock()
+
+		for len(frame) > 0 {
+			n := len(frame)
+			if rng.Intn(4) == 0 {
+				n = 1 + rng.Intn(n)
+			}
+			if _, err := w.Write(frame[:n]); err != nil {
+				return
+			}
+			frame = frame[n:]
+		}
+	}
+}
+
+// readFrame reads a Content-Length framed message. The client always writes
+// canonical framing, so other headers are ignored.
+func readFrame(reader *bufio.Reader) ([]byte, error) {
+	length := -1
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+		line = strings.TrimSpace(line)
+		if line == "" {
+			break
+		}
+		if name, value, ok := strings.Cut(line, ":"); ok && strings.EqualFold(name, "Content-Length") {
+			if length, err = strconv.Atoi(strings.TrimSpace(value)); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if length < 0 {
+		return nil, errors.New("missing Content-Length")
+	}
+	body := make([]byte, length)
+	_, err := io.ReadFull(reader, body)
+	return body, err
+}
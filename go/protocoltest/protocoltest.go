@@ -0,0 +1,144 @@
+// Package protocoltest checks that a [copilot.JSONRPCClient] behaves correctly
+// over a transport, by driving it with random sequences of valid and
+// near-valid protocol messages and asserting invariants of the client.
+//
+// It is used by the SDK's own tests and is meant to be run by implementors of
+// alternative transports, such as sockets or WebSocket bridges, to check that
+// their transport preserves the protocol:
+//
+//	func TestTransport(t *testing.T) {
+//	    protocoltest.Run(t, protocoltest.Config{
+//	        Pipe: func() (client, server io.ReadWriteCloser, err error) {
+//	            return myTransportPair()
+//	        },
+//	    })
+//	}
+//
+//	func FuzzTransport(f *testing.F) {
+//	    protocoltest.Fuzz(f, protocoltest.Config{Pipe: myPipe})
+//	}
+//
+// Each sequence interleaves requests in both directions, notifications in both
+// directions, responses that arrive late, twice, out of order, or never, and
+// near-valid frames: well-framed messages with malformed JSON, unknown or
+// mistyped IDs, or unexpected shapes, written with varied but valid framing and
+// split across writes. The invariants checked are:
+//
+//   - No deadlocks: every answered request and every server request completes
+//     within the configured timeout, and Stop returns.
+//   - Responses are routed to the request they answer, and server requests are
+//     answered exactly once, with their own ID and the handler's result or error.
+//   - Notifications are delivered exactly once, in order, in both directions.
+//   - Near-valid frames are ignored without desynchronizing the stream.
+//   - No orphaned pending requests: after Stop, requests that were never
+//     answered fail, no requests remain pending, and new requests fail.
+package protocoltest
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"testing"
+	"time"
+)
+
+// Pipe creates a connected transport: bytes written to client can be read from
+// server, and bytes written to server can be read from client. Closing an end
+// must unblock reads and writes in progress on it.
+type Pipe func() (client, server io.ReadWriteCloser, err error)
+
+// InMemoryPipe is a [Pipe] backed by [net.Pipe]. It is the default, and a
+// baseline to compare other transports with.
+func InMemoryPipe() (client, server io.ReadWriteCloser, err error) {
+	c, s := net.Pipe()
+	return c, s, nil
+}
+
+// Config configures the harness. The zero value is ready to use.
+type Config struct {
+	// Pipe creates the transport for each sequence. Defaults to [InMemoryPipe].
+	Pipe Pipe
+	// Seed seeds the first sequence; sequence i uses Seed+i. Zero picks a seed
+	// from the clock. Errors include the seed of the failing sequence, so that
+	// it can be reproduced by setting Seed and Sequences to 1.
+	Seed int64
+	// Sequences is the number of sequences to run. Defaults to 10.
+	Sequences int
+	// Steps is the number of operations in each sequence. Defaults to 100.
+	Steps int
+	// NearValidRate is the probability that an operation sends a near-valid
+	// frame. Defaults to 0.2; a negative value sends none.
+	NearValidRate float64
+	// Timeout is how long a sequence waits for the client to make progress
+	// before reporting a deadlock. Defaults to 10 seconds.
+	Timeout time.Duration
+}
+
+func (c Config) withDefaults() Config {
+	if c.Pipe == nil {
+		c.Pipe = InMemoryPipe
+	}
+	if c.Seed == 0 {
+		c.Seed = time.Now().UnixNano()
+	}
+	if c.Sequences <= 0 {
+		c.Sequences = 10
+	}
+	if c.Steps <= 0 {
+		c.Steps = 100
+	}
+	if c.NearValidRate == 0 {
+		c.NearValidRate = 0.2
+	}
+	if c.Timeout <= 0 {
+		c.Timeout = 10 * time.Second
+	}
+	return c
+}
+
+// Check runs the configured sequences and returns an error describing the
+// invariants violated by the first sequence that fails, or nil.
+func Check(config Config) error {
+	config = config.withDefaults()
+	for i := 0; i < config.Sequences; i++ {
+		seed := config.Seed + int64(i)
+		if err := runSequence(config, seed); err != nil {
+			return fmt.Errorf("protocoltest: sequence with seed %d failed: %w", seed, err)
+		}
+	}
+	return nil
+}
+
+// Run runs [Check] and fails t if it returns an error.
+func Run(t testing.TB, config Config) {
+	t.Helper()
+	if err := Check(config); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// Fuzz runs the harness as a fuzz target, with one sequence per fuzzer-chosen
+// seed. Run it with go test -fuzz.
+func Fuzz(f *testing.F, config Config) {
+	for seed := int64(1); seed <= 8; seed++ {
+		f.Add(seed)
+	}
+	f.Fuzz(func(t *testing.T, seed int64) {
+		if err := runSequence(config.withDefaults(), seed); err != nil {
+			t.Fatalf("protocoltest: sequence with seed %d failed: %v", seed, err)
+		}
+	})
+}
+
+// errFailures joins the failures of a sequence.
+func errFailures(failures []string) error {
+	if len(failures) == 0 {
+		return nil
+	}
+	errs := make([]error, len(failures))
+	for i, failure := range failures {
+		errs[i] = errors.New(failure)
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,82 @@
+package protocoltest
+
+import (
+	"io"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestRun(t *testing.T) {
+	t.Run("in-memory pipe", func(t *testing.T) {
+		Run(t, Config{Seed: 1})
+	})
+
+	t.Run("TCP loopback", func(t *testing.T) {
+		Run(t, Config{Seed: 100, Sequences: 3, Pipe: tcpPipe})
+	})
+}
+
+func TestCheckReportsStalledTransport(t *testing.T) {
+	err := Check(Config{
+		Seed:      1,
+		Sequences: 1,
+		Timeout:   200 * time.Millisecond,
+		Pipe: func() (io.ReadWriteCloser, io.ReadWriteCloser, error) {
+			client, server := net.Pipe()
+			return client, &stallingConn{Conn: server, remaining: 512}, nil
+		},
+	})
+	if err == nil || !strings.Contains(err.Error(), "deadlock") {
+		t.Errorf("Expected a deadlock to be reported, got %v", err)
+	}
+	if err != nil && !strings.Contains(err.Error(), "seed 1") {
+		t.Errorf("Expected the error to include the seed, got %v", err)
+	}
+}
+
+func FuzzJSONRPCClient(f *testing.F) {
+	Fuzz(f, Config{})
+}
+
+func tcpPipe() (io.ReadWriteCloser, io.ReadWriteCloser, error) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, nil, err
+	}
+	defer listener.Close()
+
+	accepted := make(chan net.Conn, 1)
+	go func() {
+		conn, _ := listener.Accept()
+		accepted <- conn
+	}()
+	client, err := net.Dial("tcp", listener.Addr().String())
+	if err != nil {
+		return nil, nil, err
+	}
+	return client, <-accepted, nil
+}
+
+// stallingConn silently discards everything written after the first remaining
+// bytes, like a transport that stops delivering without reporting an error.
+type stallingConn struct {
+	net.Conn
+	mu        sync.Mutex
+	remaining int
+}
+
+func (c *stallingConn) Write(p []byte) (int, error) {
+	c.mu.Lock()
+	n := min(len(p), c.remaining)
+	c.remaining -= n
+	c.mu.Unlock()
+	if n > 0 {
+		if _, err := c.Conn.Write(p[:n]); err != nil {
+			return 0, err
+		}
+	}
+	return len(p), nil
+}
@@ -0,0 +1,334 @@
+package copilot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"mime"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// DefaultResourceMaxBytes is the most content of a resource returned by one
+// read unless [ResourceCatalogOptions] sets MaxBytes.
+const DefaultResourceMaxBytes = 64 * 1024
+
+// Names of the tools a [ResourceCatalog] offers the model.
+const (
+	ListResourcesToolName = "list_resources"
+	ReadResourceToolName  = "read_resource"
+)
+
+// Resource is read-only data the client offers to the model, such as a
+// document, a configuration file, or a database table export. Its contents are
+// loaded only when the model reads it.
+type Resource struct {
+	// URI identifies the resource, such as "file:///docs/handbook.md" or
+	// "db://orders/schema".
+	URI string
+	// Name is a short human-readable name.
+	Name string
+	// Description tells the model what the resource holds.
+	Description string
+	// MimeType is the format of the contents, such as "text/markdown". Text
+	// and JSON contents are returned to the model as text; others as a binary
+	// attachment.
+	MimeType string
+	// Load returns the contents. It is called with the context of the tool
+	// call that reads the resource.
+	Load func(ctx context.Context) ([]byte, error)
+}
+
+// ResourceCatalogOptions configures a [ResourceCatalog].
+type ResourceCatalogOptions struct {
+	// MaxBytes is the most text returned by one read, and the largest binary
+	// resource that can be read. Longer text is returned in pages. Defaults to
+	// [DefaultResourceMaxBytes].
+	MaxBytes int
+	// CacheTTL is how long loaded contents are reused. Zero caches them until
+	// [ResourceCatalog.Invalidate]; a negative value disables caching.
+	CacheTTL time.Duration
+}
+
+// ResourceCatalog is a set of resources the model can list and read on
+// demand, complementing tools for read-only data. Attach it with
+// [SessionConfig.Resources]; the session then offers the model a
+// list_resources tool and a read_resource tool. A catalog can be shared by
+// many sessions, which share its cache.
+//
+// Contents are loaded on first read and cached. Concurrent reads of a resource
+// share one load.
+//
+// Example:
+//
+//	resources := copilot.NewResourceCatalog(&copilot.ResourceCatalogOptions{CacheTTL: 10 * time.Minute})
+//	resources.Register(copilot.Resource{
+//	    URI:         "docs://handbook",
+//	    Name:        "Support handbook",
+//	    Description: "Policies for refunds, escalations, and SLAs",
+//	    MimeType:    "text/markdown",
+//	    Load: func(ctx context.Context) ([]byte, error) {
+//	        return os.ReadFile("handbook.md")
+//	    },
+//	})
+//	session, _ := client.CreateSession(&copilot.SessionConfig{Resources: resources})
+type ResourceCatalog struct {
+	mu        sync.Mutex
+	resources map[string]*resourceEntry
+	maxBytes  int
+	cacheTTL  time.Duration
+	now       func() time.Time
+}
+
+// resourceEntry is a registered resource and its cached contents.
+type resourceEntry struct {
+	resource Resource
+	// loading serializes loads, so that concurrent reads share one.
+	loading  sync.Mutex
+	data     []byte
+	loaded   bool
+	loadedAt time.Time
+}
+
+// NewResourceCatalog creates an empty catalog. opts may be nil.
+func NewResourceCatalog(opts *ResourceCatalogOptions) *ResourceCatalog {
+	c := &ResourceCatalog{
+		resources: make(map[string]*resourceEntry),
+		maxBytes:  DefaultResourceMaxBytes,
+		now:       time.Now,
+	}
+	if opts != nil {
+		if opts.MaxBytes > 0 {
+			c.maxBytes = opts.MaxBytes
+		}
+		c.cacheTTL = opts.CacheTTL
+	}
+	return c
+}
+
+// Register adds resources to the catalog. It fails without changing the
+// catalog if a resource has no URI or loader, or its URI is already
+// registered.
+func (c *ResourceCatalog) Register(resources ...Resource) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	added := make(map[string]bool, len(resources))
+	for _, resource := range resources {
+		if resource.URI == "" {
+			return errors.New("resource URI is required")
+		}
+		if resource.Load == nil {
+			return fmt.Errorf("resource %s has no loader", resource.URI)
+		}
+		if _, ok := c.resources[resource.URI]; ok || added[resource.URI] {
+			return fmt.Errorf("resource %s is already registered", resource.URI)
+		}
+		added[resource.URI] = true
+	}
+	for _, resource := range resources {
+		c.resources[resource.URI] = &resourceEntry{resource: resource}
+	}
+	return nil
+}
+
+// Unregister removes the resources with the given URIs. Unknown URIs are
+// ignored.
+func (c *ResourceCatalog) Unregister(uris ...string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, uri := range uris {
+		delete(c.resources, uri)
+	}
+}
+
+// Resources returns the registered resources in URI order.
+func (c *ResourceCatalog) Resources() []Resource {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	resources := make([]Resource, 0, len(c.resources))
+	for _, entry := range c.resources {
+		resources = append(resources, entry.resource)
+	}
+	sort.Slice(resources, func(i, j int) bool { return resources[i].URI < resources[j].URI })
+	return resources
+}
+
+// Invalidate drops the cached contents of the resources with the given URIs,
+// or of every resource if none are given, so that the next read loads them
+// again.
+func (c *ResourceCatalog) Invalidate(uris ...string) {
+	c.mu.Lock()
+	var entries []*resourceEntry
+	if len(uris) == 0 {
+		for _, entry := range c.resources {
+			entries = append(entries, entry)
+		}
+	} else {
+		for _, uri := range uris {
+			if entry, ok := c.resources[uri]; ok {
+				entries = append(entries, entry)
+			}
+		}
+	}
+	c.mu.Unlock()
+
+	for _, entry := range entries {
+		entry.loading.Lock()
+		entry.data, entry.loaded = nil, false
+		entry.loading.Unlock()
+	}
+}
+
+// Read returns the contents of the resource with the given URI, loading them
+// if they are not cached.
+func (c *ResourceCatalog) Read(ctx context.Context, uri string) ([]byte, error) {
+	c.mu.Lock()
+	entry, ok := c.resources[uri]
+	c.mu.Unlock()
+	if !ok {
+		return nil, fmt.Errorf("resource %s is not registered", uri)
+	}
+
+	entry.loading.Lock()
+	defer entry.loading.Unlock()
+	if entry.loaded && (c.cacheTTL == 0 || c.now().Sub(entry.loadedAt) < c.cacheTTL) {
+		return entry.data, nil
+	}
+	data, err := entry.resource.Load(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load resource %s: %w", uri, err)
+	}
+	if c.cacheTTL >= 0 {
+		entry.data, entry.loaded, entry.loadedAt = data, true, c.now()
+	}
+	return data, nil
+}
+
+// Tools returns the tools that let the model list and read the catalog's
+// resources. Sessions with [SessionConfig.Resources] set add them
+// automatically; use Tools to offer the resources some other way, for example
+// in a [ToolNamespace].
+func (c *ResourceCatalog) Tools() []Tool {
+	if c == nil {
+		return nil
+	}
+	type listParams struct{}
+	type readParams struct {
+		URI    string `json:"uri" jsonschema:"URI of the resource, as listed by list_resources"`
+		Offset int    `json:"offset" jsonschema:"optional,minimum=0,Byte offset to read text from, for resources returned in pages"`
+	}
+	return []Tool{
+		DefineTool(ListResourcesToolName, "List the read-only resources available in this session, with their URIs, formats, and descriptions.",
+			func(params listParams, inv ToolInvocation) (string, error) {
+				return c.renderList(), nil
+			}),
+		DefineTool(ReadResourceToolName, "Read the contents of a resource listed by list_resources.",
+			func(params readParams, inv ToolInvocation) (ToolResult, error) {
+				return c.readResult(inv.Context(), params.URI, params.Offset), nil
+			}),
+	}
+}
+
+// renderList describes the catalog's resources for the model.
+func (c *ResourceCatalog) renderList() string {
+	resources := c.Resources()
+	if len(resources) == 0 {
+		return "No resources are available."
+	}
+	var b strings.Builder
+	for _, resource := range resources {
+		fmt.Fprintf(&b, "- %s", resource.URI)
+		if resource.Name != "" {
+			fmt.Fprintf(&b, " (%s)", resource.Name)
+		}
+		if resource.MimeType != "" {
+			fmt.Fprintf(&b, " [%s]", resource.MimeType)
+		}
+		if resource.Description != "" {
+			fmt.Fprintf(&b, ": %s", resource.Description)
+		}
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+// readResult returns the result of reading uri from offset.
+func (c *ResourceCatalog) readResult(ctx context.Context, uri string, offset int) ToolResult {
+	c.mu.Lock()
+	entry, ok := c.resources[uri]
+	c.mu.Unlock()
+	if !ok {
+		return ToolResult{
+			TextResultForLLM: fmt.Sprintf("There is no resource %s. Call %s to see the available resources.", uri, ListResourcesToolName),
+			ResultType:       "failure",
+			Error:            fmt.Sprintf("resource %s is not registered", uri),
+		}
+	}
+	data, err := c.Read(ctx, uri)
+	if err != nil {
+		return buildFailedToolResult(err.Error())
+	}
+	telemetry := map[string]interface{}{"resourceBytes": len(data)}
+
+	if !isTextMimeType(entry.resource.MimeType, data) {
+		if len(data) > c.maxBytes {
+			return ToolResult{
+				TextResultForLLM: fmt.Sprintf("Resource %s is %d bytes, over the limit of %d bytes, and cannot be read.", uri, len(data), c.maxBytes),
+				ResultType:       "failure",
+				Error:            fmt.Sprintf("resource %s exceeds %d bytes", uri, c.maxBytes),
+				ToolTelemetry:    telemetry,
+			}
+		}
+		return ToolResult{
+			ResultType:    "success",
+			Attachments:   []ToolResultAttachment{{Data: data, MimeType: entry.resource.MimeType, Name: entry.resource.Name}},
+			ToolTelemetry: telemetry,
+		}
+	}
+
+	if offset > len(data) {
+		offset = len(data)
+	}
+	end := offset + c.maxBytes
+	if end >= len(data) {
+		return ToolResult{TextResultForLLM: string(data[offset:]), ResultType: "success", ToolTelemetry: telemetry}
+	}
+	// End the page on a character boundary.
+	for end > offset && !utf8.RuneStart(data[end]) {
+		end--
+	}
+	telemetry["truncated"] = true
+	return ToolResult{
+		TextResultForLLM: fmt.Sprintf("%s\n\n[Showing bytes %d-%d of %d. Call %s with offset %d to read more.]", data[offset:end], offset, end, len(data), ReadResourceToolName, end),
+		ResultType:       "success",
+		ToolTelemetry:    telemetry,
+	}
+}
+
+// isTextMimeType reports whether contents of mimeType are text. Without a
+// MIME type, valid UTF-8 is treated as text.
+func isTextMimeType(mimeType string, data []byte) bool {
+	if mimeType == "" {
+		return utf8.Valid(data)
+	}
+	mediaType, _, err := mime.ParseMediaType(mimeType)
+	if err != nil {
+		return false
+	}
+	return strings.HasPrefix(mediaType, "text/") ||
+		mediaType == "application/json" || strings.HasSuffix(mediaType, "+json") ||
+		mediaType == "application/xml" || strings.HasSuffix(mediaType, "+xml") ||
+		mediaType == "application/yaml" || mediaType == "application/x-yaml"
+}
+
+// withResourceTools returns tools followed by the tools of resources, if any.
+func withResourceTools(tools []Tool, resources *ResourceCatalog) []Tool {
+	if resources == nil {
+		return tools
+	}
+	return append(append(make([]Tool, 0, len(tools)+2), tools...), resources.Tools()...)
+}
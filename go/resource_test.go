@@ -0,0 +1,217 @@
+package copilot
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func staticResource(uri, mimeType, content string, loads *int32) Resource {
+	return Resource{
+		URI:      uri,
+		Name:     uri,
+		MimeType: mimeType,
+		Load: func(ctx context.Context) ([]byte, error) {
+			if loads != nil {
+				atomic.AddInt32(loads, 1)
+			}
+			return []byte(content), nil
+		},
+	}
+}
+
+func TestResourceCatalog_Register(t *testing.T) {
+	catalog := NewResourceCatalog(nil)
+	if err := catalog.Register(Resource{Load: func(context.Context) ([]byte, error) { return nil, nil }}); err == nil {
+		t.Error("Expected an error for a resource without a URI")
+	}
+	if err := catalog.Register(Resource{URI: "docs://a"}); err == nil {
+		t.Error("Expected an error for a resource without a loader")
+	}
+	if err := catalog.Register(staticResource("docs://b", "", "b", nil), staticResource("docs://a", "", "a", nil)); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if err := catalog.Register(staticResource("docs://c", "", "", nil), staticResource("docs://a", "", "", nil)); err == nil {
+		t.Error("Expected an error for a duplicate URI")
+	}
+
+	var uris []string
+	for _, resource := range catalog.Resources() {
+		uris = append(uris, resource.URI)
+	}
+	if got := strings.Join(uris, ","); got != "docs://a,docs://b" {
+		t.Errorf("Expected a failed registration to leave the catalog unchanged, got %s", got)
+	}
+
+	catalog.Unregister("docs://a")
+	if _, err := catalog.Read(context.Background(), "docs://a"); err == nil {
+		t.Error("Expected an error reading an unregistered resource")
+	}
+}
+
+func TestResourceCatalog_Read(t *testing.T) {
+	t.Run("loads lazily and once for concurrent reads", func(t *testing.T) {
+		var loads int32
+		catalog := NewResourceCatalog(nil)
+		catalog.Register(staticResource("docs://a", "text/plain", "hello", &loads))
+		if loads != 0 {
+			t.Fatal("Expected registration not to load the resource")
+		}
+
+		var wg sync.WaitGroup
+		for i := 0; i < 8; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				if data, err := catalog.Read(context.Background(), "docs://a"); err != nil || string(data) != "hello" {
+					t.Errorf("Unexpected read %q, %v", data, err)
+				}
+			}()
+		}
+		wg.Wait()
+		if loads != 1 {
+			t.Errorf("Expected one load, got %d", loads)
+		}
+
+		catalog.Invalidate("docs://a")
+		catalog.Read(context.Background(), "docs://a")
+		if loads != 2 {
+			t.Errorf("Expected a reload after invalidation, got %d loads", loads)
+		}
+	})
+
+	t.Run("expires cached contents", func(t *testing.T) {
+		var loads int32
+		now := time.Unix(0, 0)
+		catalog := NewResourceCatalog(&ResourceCatalogOptions{CacheTTL: time.Minute})
+		catalog.now = func() time.Time { return now }
+		catalog.Register(staticResource("docs://a", "", "a", &loads))
+
+		catalog.Read(context.Background(), "docs://a")
+		now = now.Add(30 * time.Second)
+		catalog.Read(context.Background(), "docs://a")
+		if loads != 1 {
+			t.Errorf("Expected cached contents within the TTL, got %d loads", loads)
+		}
+		now = now.Add(time.Minute)
+		catalog.Read(context.Background(), "docs://a")
+		if loads != 2 {
+			t.Errorf("Expected a reload after the TTL, got %d loads", loads)
+		}
+	})
+
+	t.Run("does not cache failed loads", func(t *testing.T) {
+		var loads int32
+		catalog := NewResourceCatalog(nil)
+		catalog.Register(Resource{URI: "docs://a", Load: func(context.Context) ([]byte, error) {
+			if atomic.AddInt32(&loads, 1) == 1 {
+				return nil, errors.New("unavailable")
+			}
+			return []byte("a"), nil
+		}})
+		if _, err := catalog.Read(context.Background(), "docs://a"); err == nil || !strings.Contains(err.Error(), "unavailable") {
+			t.Errorf("Expected the load error, got %v", err)
+		}
+		if data, err := catalog.Read(context.Background(), "docs://a"); err != nil || string(data) != "a" {
+			t.Errorf("Unexpected read %q, %v", data, err)
+		}
+	})
+}
+
+func TestResourceCatalog_Tools(t *testing.T) {
+	catalog := NewResourceCatalog(&ResourceCatalogOptions{MaxBytes: 10})
+	catalog.Register(
+		Resource{
+			URI: "docs://handbook", Name: "Handbook", Description: "Support policies", MimeType: "text/markdown",
+			Load: func(context.Context) ([]byte, error) { return []byte("0123456789abcdeé"), nil },
+		},
+		staticResource("img://logo", "image/png", "\x89PNG", nil),
+		staticResource("img://banner", "image/png", strings.Repeat("x", 11), nil),
+	)
+	tools := make(map[string]Tool)
+	for _, tool := range catalog.Tools() {
+		tools[tool.Name] = tool
+	}
+	call := func(name string, args map[string]interface{}) ToolResult {
+		t.Helper()
+		result, err := tools[name].Handler(ToolInvocation{ToolCallID: "call-1", ToolName: name, Arguments: args})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		return result
+	}
+
+	t.Run("lists resources", func(t *testing.T) {
+		result := call(ListResourcesToolName, map[string]interface{}{})
+		if !strings.Contains(result.TextResultForLLM, "- docs://handbook (Handbook) [text/markdown]: Support policies") {
+			t.Errorf("Unexpected listing %q", result.TextResultForLLM)
+		}
+	})
+
+	t.Run("pages text over the limit", func(t *testing.T) {
+		result := call(ReadResourceToolName, map[string]interface{}{"uri": "docs://handbook"})
+		if !strings.HasPrefix(result.TextResultForLLM, "0123456789\n") || !strings.Contains(result.TextResultForLLM, "offset 10") {
+			t.Errorf("Unexpected first page %q", result.TextResultForLLM)
+		}
+		result = call(ReadResourceToolName, map[string]interface{}{"uri": "docs://handbook", "offset": 10})
+		if result.TextResultForLLM != "abcdeé" {
+			t.Errorf("Unexpected second page %q", result.TextResultForLLM)
+		}
+	})
+
+	t.Run("returns binary resources as attachments", func(t *testing.T) {
+		result := call(ReadResourceToolName, map[string]interface{}{"uri": "img://logo"})
+		if len(result.Attachments) != 1 || result.Attachments[0].MimeType != "image/png" || string(result.Attachments[0].Data) != "\x89PNG" {
+			t.Errorf("Unexpected result %+v", result)
+		}
+		if result := call(ReadResourceToolName, map[string]interface{}{"uri": "img://banner"}); result.ResultType != "failure" {
+			t.Errorf("Expected binary resources over the limit to fail, got %+v", result)
+		}
+	})
+
+	t.Run("reports unknown resources", func(t *testing.T) {
+		result := call(ReadResourceToolName, map[string]interface{}{"uri": "docs://missing"})
+		if result.ResultType != "failure" || !strings.Contains(result.TextResultForLLM, ListResourcesToolName) {
+			t.Errorf("Unexpected result %+v", result)
+		}
+	})
+}
+
+func TestResourceCatalog_AdvertisedWithSession(t *testing.T) {
+	var advertised []string
+	rpc, _ := newFakeServer(t, func(method string, params map[string]interface{}) (map[string]interface{}, *JSONRPCError) {
+		if method == "session.create" {
+			advertised = advertisedNames(params["tools"])
+			return map[string]interface{}{"sessionId": "s1"}, nil
+		}
+		return nil, nil
+	})
+	client := &Client{client: rpc, state: StateConnected, sessions: make(map[string]*Session)}
+
+	catalog := NewResourceCatalog(nil)
+	catalog.Register(staticResource("docs://a", "text/plain", "hello", nil))
+	tools := make([]Tool, 1, 2)
+	tools[0] = namedTool("lookup", "lookup")
+	session, err := client.CreateSession(&SessionConfig{Tools: tools, Resources: catalog})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if got := strings.Join(advertised, ","); got != "lookup,list_resources,read_resource" {
+		t.Errorf("Unexpected tools %s", got)
+	}
+	if tools[:2][1].Name != "" {
+		t.Error("Expected the session's tools not to be appended to in place")
+	}
+	handler, ok := session.getToolHandler(ReadResourceToolName)
+	if !ok {
+		t.Fatal("Expected a read_resource handler")
+	}
+	result, err := handler(ToolInvocation{ToolName: ReadResourceToolName, Arguments: map[string]interface{}{"uri": "docs://a"}})
+	if err != nil || result.TextResultForLLM != "hello" {
+		t.Errorf("Unexpected result %+v, %v", result, err)
+	}
+}
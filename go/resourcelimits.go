@@ -0,0 +1,137 @@
+package copilot
+
+import (
+	"fmt"
+	"time"
+)
+
+// ResourceLimits constrains the CLI process spawned by a [Client], so a runaway
+// agent cannot starve the host it runs on.
+//
+// Limits are applied only when the client spawns the CLI itself; they cannot be
+// combined with [ClientOptions.CLIUrl]. They are currently supported on Linux
+// only; on other platforms [Client.Start] fails if any limit is set.
+type ResourceLimits struct {
+	// Nice adjusts the scheduling priority of the CLI process and every thread
+	// and child it starts, from -20 (highest priority) to 19 (lowest). Zero
+	// leaves the priority unchanged. Negative values usually require elevated
+	// privileges.
+	Nice int
+	// MemoryLimitBytes caps the memory of the CLI process and its children. The
+	// process is started in a new cgroup (cgroup v2) with memory.max set to this
+	// value, so the kernel reclaims or OOM-kills within the group instead of
+	// starving the host. Zero means no limit.
+	MemoryLimitBytes int64
+	// CgroupParent is the cgroup v2 directory under which the per-process cgroup is
+	// created when MemoryLimitBytes is set. It must be writable by the current
+	// process and have the memory controller enabled for its children.
+	// Default: "/sys/fs/cgroup".
+	CgroupParent string
+	// CPUAffinity restricts the CLI process and every thread and child it
+	// starts to the listed CPU indexes. When MemoryLimitBytes is set and the
+	// cgroup has the cpuset controller, the CPUs are also set in its
+	// cpuset.cpus, so the process cannot widen its own affinity. Empty means
+	// no restriction.
+	CPUAffinity []int
+}
+
+// validate reports configuration errors that do not depend on the platform.
+func (l *ResourceLimits) validate() error {
+	if l.Nice < -20 || l.Nice > 19 {
+		return fmt.Errorf("invalid Nice %d: must be between -20 and 19", l.Nice)
+	}
+	if l.MemoryLimitBytes < 0 {
+		return fmt.Errorf("invalid MemoryLimitBytes %d: must not be negative", l.MemoryLimitBytes)
+	}
+	for _, cpu := range l.CPUAffinity {
+		if cpu < 0 {
+			return fmt.Errorf("invalid CPU index %d in CPUAffinity", cpu)
+		}
+	}
+	return nil
+}
+
+func (l *ResourceLimits) empty() bool {
+	return l == nil || (l.Nice == 0 && l.MemoryLimitBytes == 0 && len(l.CPUAffinity) == 0)
+}
+
+// ClientStats reports resource usage of the CLI process spawned by a [Client].
+type ClientStats struct {
+	// PID is the process ID of the CLI process.
+	PID int
+	// RSSBytes is the resident set size of the CLI process.
+	RSSBytes int64
+	// UserCPUTime and SystemCPUTime are the CPU time the CLI process has consumed
+	// in user and kernel mode.
+	UserCPUTime   time.Duration
+	SystemCPUTime time.Duration
+	// MemoryLimitBytes is the memory limit applied through [ResourceLimits], or
+	// zero if none.
+	MemoryLimitBytes int64
+	// Concurrency is the state of the client's [AdaptiveConcurrency].
+	Concurrency ConcurrencyStats
+	// FlowControl is the state of the client's [FlowControl].
+	FlowControl FlowControlStats
+}
+
+// Stats returns resource usage of the CLI process spawned by this client.
+//
+// Returns an error if the client is connected to an external server, the CLI
+// process is not running, or process statistics are not supported on this
+// platform. Concurrency and FlowControl are reported even then.
+//
+// Example:
+//
+//	stats, err := client.Stats()
+//	if err == nil && stats.RSSBytes > 2<<30 {
+//	    log.Printf("CLI is using %d MiB", stats.RSSBytes>>20)
+//	}
+func (c *Client) Stats() (ClientStats, error) {
+	concurrency := ClientStats{Concurrency: c.concurrency.stats(), FlowControl: c.flow.stats()}
+	if c.isExternalServer {
+		return concurrency, fmt.Errorf("stats are not available for an external server")
+	}
+	if c.process == nil || c.process.Process == nil {
+		return concurrency, fmt.Errorf("CLI process is not running")
+	}
+	stats, err := processStats(c.process.Process.Pid)
+	if err != nil {
+		return concurrency, err
+	}
+	stats.Concurrency = concurrency.Concurrency
+	stats.FlowControl = concurrency.FlowControl
+	if c.options.ResourceLimits != nil {
+		stats.MemoryLimitBytes = c.options.ResourceLimits.MemoryLimitBytes
+	}
+	return stats, nil
+}
+
+// startProcess starts the CLI process with the configured resource limits applied.
+func (c *Client) startProcess() error {
+	limits := c.options.ResourceLimits
+	if limits.empty() {
+		return c.process.Start()
+	}
+	if err := limits.validate(); err != nil {
+		return err
+	}
+
+	applied, err := prepareResourceLimits(c.process, limits)
+	if err != nil {
+		return fmt.Errorf("failed to apply resource limits: %w", err)
+	}
+	if err := applied.start(c.process); err != nil {
+		applied.release()
+		return err
+	}
+	c.appliedLimits = applied
+	return nil
+}
+
+// releaseResourceLimits cleans up resources created for the CLI process's limits.
+func (c *Client) releaseResourceLimits() {
+	if c.appliedLimits != nil {
+		c.appliedLimits.release()
+		c.appliedLimits = nil
+	}
+}
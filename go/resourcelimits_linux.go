@@ -0,0 +1,213 @@
+//go:build linux
+
+package copilot
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"syscall"
+	"time"
+	"unsafe"
+)
+
+// clockTicksPerSecond is the USER_HZ unit of CPU times in /proc, which is 100 on
+// every supported Linux architecture.
+const clockTicksPerSecond = 100
+
+const defaultCgroupParent = "/sys/fs/cgroup"
+
+var cgroupCounter atomic.Uint64
+
+// appliedResourceLimits holds the state needed to start the process with its
+// limits and to clean them up when it stops.
+type appliedResourceLimits struct {
+	limits    *ResourceLimits
+	cgroupDir string
+	cgroupFD  *os.File
+}
+
+// prepareResourceLimits configures cmd before it starts. When a memory limit is
+// set, it creates a cgroup and arranges for the process to be started inside it,
+// so it is never running unconstrained.
+func prepareResourceLimits(cmd *exec.Cmd, limits *ResourceLimits) (*appliedResourceLimits, error) {
+	applied := &appliedResourceLimits{limits: limits}
+	if limits.MemoryLimitBytes == 0 {
+		return applied, nil
+	}
+
+	parent := limits.CgroupParent
+	if parent == "" {
+		parent = defaultCgroupParent
+	}
+	dir := filepath.Join(parent, fmt.Sprintf("copilot-sdk-%d-%d", os.Getpid(), cgroupCounter.Add(1)))
+	if err := os.Mkdir(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create cgroup: %w", err)
+	}
+	applied.cgroupDir = dir
+
+	if err := os.WriteFile(filepath.Join(dir, "memory.max"), []byte(strconv.FormatInt(limits.MemoryLimitBytes, 10)), 0o644); err != nil {
+		applied.release()
+		return nil, fmt.Errorf("failed to set memory limit (is the memory controller enabled in %s?): %w", parent, err)
+	}
+
+	if len(limits.CPUAffinity) > 0 {
+		if err := setCgroupCPUs(dir, limits.CPUAffinity); err != nil {
+			applied.release()
+			return nil, err
+		}
+	}
+
+	fd, err := os.Open(dir)
+	if err != nil {
+		applied.release()
+		return nil, fmt.Errorf("failed to open cgroup: %w", err)
+	}
+	applied.cgroupFD = fd
+
+	if cmd.SysProcAttr == nil {
+		cmd.SysProcAttr = &syscall.SysProcAttr{}
+	}
+	cmd.SysProcAttr.UseCgroupFD = true
+	cmd.SysProcAttr.CgroupFD = int(fd.Fd())
+	return applied, nil
+}
+
+// start starts cmd with the nice level and CPU affinity of the limits.
+//
+// Both are per-thread attributes that a new process inherits from the thread
+// that forks it, so they are set on a dedicated thread before the fork rather
+// than on the process after it starts, when they would only reach its main
+// thread. That thread is never unlocked, so the runtime discards it afterwards
+// instead of reusing it with the changed attributes.
+func (a *appliedResourceLimits) start(cmd *exec.Cmd) error {
+	defer func() {
+		if a.cgroupFD != nil {
+			a.cgroupFD.Close()
+			a.cgroupFD = nil
+		}
+	}()
+	if a.limits.Nice == 0 && len(a.limits.CPUAffinity) == 0 {
+		return cmd.Start()
+	}
+	errCh := make(chan error, 1)
+	go func() {
+		runtime.LockOSThread()
+		if a.limits.Nice != 0 {
+			if err := syscall.Setpriority(syscall.PRIO_PROCESS, syscall.Gettid(), a.limits.Nice); err != nil {
+				errCh <- fmt.Errorf("failed to apply resource limits: failed to set nice level: %w", err)
+				return
+			}
+		}
+		if len(a.limits.CPUAffinity) > 0 {
+			if err := setCPUAffinity(0, a.limits.CPUAffinity); err != nil {
+				errCh <- fmt.Errorf("failed to apply resource limits: failed to set CPU affinity: %w", err)
+				return
+			}
+		}
+		errCh <- cmd.Start()
+	}()
+	return <-errCh
+}
+
+// release removes the cgroup created for the process. The cgroup can only be
+// removed once the process has exited, so this retries briefly.
+func (a *appliedResourceLimits) release() {
+	if a.cgroupFD != nil {
+		a.cgroupFD.Close()
+		a.cgroupFD = nil
+	}
+	if a.cgroupDir == "" {
+		return
+	}
+	dir := a.cgroupDir
+	a.cgroupDir = ""
+	go func() {
+		for i := 0; i < 50; i++ {
+			if err := os.Remove(dir); err == nil || os.IsNotExist(err) {
+				return
+			}
+			time.Sleep(100 * time.Millisecond)
+		}
+	}()
+}
+
+// setCgroupCPUs restricts the cgroup dir to cpus, if it has the cpuset
+// controller.
+func setCgroupCPUs(dir string, cpus []int) error {
+	path := filepath.Join(dir, "cpuset.cpus")
+	if _, err := os.Stat(path); err != nil {
+		return nil
+	}
+	list := make([]string, len(cpus))
+	for i, cpu := range cpus {
+		list[i] = strconv.Itoa(cpu)
+	}
+	if err := os.WriteFile(path, []byte(strings.Join(list, ",")), 0o644); err != nil {
+		return fmt.Errorf("failed to set cgroup CPUs: %w", err)
+	}
+	return nil
+}
+
+// setCPUAffinity sets the CPU affinity of the thread pid, or of the calling
+// thread if pid is zero.
+func setCPUAffinity(pid int, cpus []int) error {
+	var mask [16]uint64 // up to 1024 CPUs, matching glibc's cpu_set_t
+	for _, cpu := range cpus {
+		if cpu >= len(mask)*64 {
+			return fmt.Errorf("CPU index %d out of range", cpu)
+		}
+		mask[cpu/64] |= 1 << (uint(cpu) % 64)
+	}
+	_, _, errno := syscall.RawSyscall(syscall.SYS_SCHED_SETAFFINITY, uintptr(pid), unsafe.Sizeof(mask), uintptr(unsafe.Pointer(&mask)))
+	if errno != 0 {
+		return errno
+	}
+	return nil
+}
+
+// processStats reads resource usage for pid from /proc.
+func processStats(pid int) (ClientStats, error) {
+	stat, err := os.ReadFile(fmt.Sprintf("/proc/%d/stat", pid))
+	if err != nil {
+		return ClientStats{}, fmt.Errorf("failed to read process stats: %w", err)
+	}
+	stats, err := parseProcStat(string(stat))
+	if err != nil {
+		return ClientStats{}, err
+	}
+	stats.PID = pid
+	return stats, nil
+}
+
+// parseProcStat parses CPU times and RSS from the contents of /proc/<pid>/stat.
+func parseProcStat(stat string) (ClientStats, error) {
+	// The command name is parenthesized and may contain spaces, so fields are
+	// counted from the last closing parenthesis.
+	end := strings.LastIndexByte(stat, ')')
+	if end < 0 {
+		return ClientStats{}, fmt.Errorf("malformed process stats")
+	}
+	// Fields after the command start at field 3 (state); utime, stime, and rss
+	// are fields 14, 15, and 24.
+	fields := strings.Fields(stat[end+1:])
+	if len(fields) < 22 {
+		return ClientStats{}, fmt.Errorf("malformed process stats")
+	}
+	utime, err1 := strconv.ParseInt(fields[11], 10, 64)
+	stime, err2 := strconv.ParseInt(fields[12], 10, 64)
+	rssPages, err3 := strconv.ParseInt(fields[21], 10, 64)
+	if err1 != nil || err2 != nil || err3 != nil {
+		return ClientStats{}, fmt.Errorf("malformed process stats")
+	}
+	return ClientStats{
+		RSSBytes:      rssPages * int64(os.Getpagesize()),
+		UserCPUTime:   time.Duration(utime) * time.Second / clockTicksPerSecond,
+		SystemCPUTime: time.Duration(stime) * time.Second / clockTicksPerSecond,
+	}, nil
+}
@@ -0,0 +1,96 @@
+//go:build linux
+
+package copilot
+
+import (
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestParseProcStat(t *testing.T) {
+	t.Run("parses times and rss with spaces in the command name", func(t *testing.T) {
+		stat := "1234 (copilot (server)) S 1 1234 1234 0 -1 4194304 100 0 0 0 250 75 0 0 20 0 12 0 100 1000000 512 18446744073709551615"
+		stats, err := parseProcStat(stat)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if stats.UserCPUTime != 2500*time.Millisecond || stats.SystemCPUTime != 750*time.Millisecond {
+			t.Errorf("Unexpected CPU times %+v", stats)
+		}
+		if stats.RSSBytes != 512*int64(os.Getpagesize()) {
+			t.Errorf("Unexpected RSS %d", stats.RSSBytes)
+		}
+	})
+
+	t.Run("rejects malformed input", func(t *testing.T) {
+		if _, err := parseProcStat("1234 (x) S 1"); err == nil {
+			t.Error("Expected error for truncated stats")
+		}
+	})
+}
+
+func TestClient_ResourceLimits(t *testing.T) {
+	t.Run("applies nice level and CPU affinity to the spawned process", func(t *testing.T) {
+		client := NewClient(&ClientOptions{ResourceLimits: &ResourceLimits{Nice: 5, CPUAffinity: []int{0}}})
+		client.process = exec.Command("sleep", "10")
+		if err := client.startProcess(); err != nil {
+			t.Fatalf("Failed to start process: %v", err)
+		}
+		defer client.process.Process.Kill()
+
+		stat, err := os.ReadFile("/proc/" + strconv.Itoa(client.process.Process.Pid) + "/stat")
+		if err != nil {
+			t.Fatalf("Failed to read stat: %v", err)
+		}
+		fields := strings.Fields(string(stat)[strings.LastIndexByte(string(stat), ')')+1:])
+		if fields[16] != "5" {
+			t.Errorf("Expected nice 5, got %s", fields[16])
+		}
+		status, err := os.ReadFile("/proc/" + strconv.Itoa(client.process.Process.Pid) + "/status")
+		if err != nil {
+			t.Fatalf("Failed to read status: %v", err)
+		}
+		if !strings.Contains(string(status), "Cpus_allowed_list:\t0\n") {
+			t.Errorf("Expected the process to be pinned to CPU 0, got %s", status)
+		}
+		if nice, err := syscall.Getpriority(syscall.PRIO_PROCESS, 0); err != nil || 20-nice != 0 {
+			t.Errorf("Expected this process's priority to be unchanged, got nice %d, %v", 20-nice, err)
+		}
+
+		stats, err := client.Stats()
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if stats.PID != client.process.Process.Pid || stats.RSSBytes < 0 {
+			t.Errorf("Unexpected stats %+v", stats)
+		}
+	})
+
+	t.Run("rejects invalid limits", func(t *testing.T) {
+		client := NewClient(&ClientOptions{ResourceLimits: &ResourceLimits{Nice: 40}})
+		client.process = exec.Command("true")
+		if err := client.startProcess(); err == nil || !strings.Contains(err.Error(), "Nice") {
+			t.Errorf("Expected invalid Nice error, got %v", err)
+		}
+	})
+
+	t.Run("panics when combined with CLIUrl", func(t *testing.T) {
+		defer func() {
+			if r := recover(); r == nil {
+				t.Error("Expected panic for ResourceLimits with CLIUrl")
+			}
+		}()
+		NewClient(&ClientOptions{CLIUrl: "localhost:8080", ResourceLimits: &ResourceLimits{Nice: 1}})
+	})
+
+	t.Run("stats require a running process", func(t *testing.T) {
+		if _, err := NewClient(nil).Stats(); err == nil {
+			t.Error("Expected error before the process is started")
+		}
+	})
+}
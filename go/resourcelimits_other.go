@@ -0,0 +1,23 @@
+//go:build !linux
+
+package copilot
+
+import (
+	"fmt"
+	"os/exec"
+	"runtime"
+)
+
+type appliedResourceLimits struct{}
+
+func prepareResourceLimits(cmd *exec.Cmd, limits *ResourceLimits) (*appliedResourceLimits, error) {
+	return nil, fmt.Errorf("resource limits are not supported on %s", runtime.GOOS)
+}
+
+func (a *appliedResourceLimits) start(cmd *exec.Cmd) error { return cmd.Start() }
+
+func (a *appliedResourceLimits) release() {}
+
+func processStats(pid int) (ClientStats, error) {
+	return ClientStats{}, fmt.Errorf("process stats are not supported on %s", runtime.GOOS)
+}
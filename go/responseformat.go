@@ -0,0 +1,150 @@
+package copilot
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// ResponseFormatType identifies the shape the assistant's reply should take.
+type ResponseFormatType string
+
+const (
+	// ResponseFormatMarkdown asks for a Markdown reply.
+	ResponseFormatMarkdown ResponseFormatType = "markdown"
+	// ResponseFormatPlainText asks for a reply without any Markdown formatting.
+	ResponseFormatPlainText ResponseFormatType = "plain_text"
+	// ResponseFormatJSON asks for a reply that is a single JSON value, optionally
+	// conforming to a schema.
+	ResponseFormatJSON ResponseFormatType = "json"
+	// ResponseFormatCodeBlockOnly asks for a reply that is a single fenced code
+	// block with no surrounding prose.
+	ResponseFormatCodeBlockOnly ResponseFormatType = "code_block_only"
+)
+
+// ResponseFormat is a hint about the format of the assistant's reply to a message.
+//
+// The format is sent with the message as a "responseFormat" field for servers
+// that support it, and is also translated into instructions appended to the
+// prompt, so callers no longer need to maintain their own prompt suffixes.
+//
+// Example:
+//
+//	type Summary struct {
+//	    Title string   `json:"title"`
+//	    Risks []string `json:"risks"`
+//	}
+//
+//	turn, err := session.SendAndCollect(copilot.MessageOptions{
+//	    Prompt:         "Summarize this pull request",
+//	    ResponseFormat: copilot.JSONResponseFormatFor[Summary](),
+//	}, 0)
+//	var summary Summary
+//	err = turn.DecodeJSON(&summary)
+type ResponseFormat struct {
+	// Type is the requested format.
+	Type ResponseFormatType `json:"type"`
+	// Schema is the JSON schema the reply must conform to. Only used with
+	// ResponseFormatJSON.
+	Schema map[string]interface{} `json:"schema,omitempty"`
+	// Language is the language of the code block, for example "go". Only used with
+	// ResponseFormatCodeBlockOnly.
+	Language string `json:"language,omitempty"`
+}
+
+// JSONResponseFormat returns a JSON response format constrained by schema, which
+// may be nil for any JSON value.
+func JSONResponseFormat(schema map[string]interface{}) *ResponseFormat {
+	return &ResponseFormat{Type: ResponseFormatJSON, Schema: schema}
+}
+
+// JSONResponseFormatFor returns a JSON response format whose schema is generated
+// from T, in the same way [DefineTool] generates parameter schemas.
+func JSONResponseFormatFor[T any]() *ResponseFormat {
+	var zero T
+	return JSONResponseFormat(generateSchemaForType(reflect.TypeOf(zero)))
+}
+
+// instructions returns the prompt snippet describing the format.
+func (f *ResponseFormat) instructions() (string, error) {
+	switch f.Type {
+	case ResponseFormatMarkdown:
+		return "Format your response as Markdown.", nil
+	case ResponseFormatPlainText:
+		return "Respond in plain text only. Do not use Markdown formatting, code fences, headings, or bullet syntax.", nil
+	case ResponseFormatJSON:
+		text := "Respond with a single valid JSON value and nothing else: no prose and no code fences."
+		if f.Schema != nil {
+			schema, err := json.Marshal(f.Schema)
+			if err != nil {
+				return "", fmt.Errorf("invalid response format schema: %w", err)
+			}
+			text += " The value must conform to this JSON schema:\n" + string(schema)
+		}
+		return text, nil
+	case ResponseFormatCodeBlockOnly:
+		if f.Language != "" {
+			return fmt.Sprintf("Respond with a single fenced %s code block and nothing else, with no explanation before or after it.", f.Language), nil
+		}
+		return "Respond with a single fenced code block and nothing else, with no explanation before or after it.", nil
+	default:
+		return "", fmt.Errorf("unknown response format %q", f.Type)
+	}
+}
+
+// applyResponseFormat adds the response format to the session.send params.
+func applyResponseFormat(params map[string]interface{}, format *ResponseFormat) error {
+	if format == nil {
+		return nil
+	}
+	instructions, err := format.instructions()
+	if err != nil {
+		return err
+	}
+	prompt, _ := params["prompt"].(string)
+	params["prompt"] = appendPromptInstructions(prompt, instructions)
+	params["responseFormat"] = format
+	return nil
+}
+
+// DecodeJSON decodes the turn's content as JSON into v. A surrounding Markdown
+// code fence is tolerated, since models sometimes add one despite instructions.
+func (t *Turn) DecodeJSON(v interface{}) error {
+	content := strings.TrimSpace(t.Content)
+	if code, ok := extractCodeBlock(content); ok {
+		content = code
+	}
+	if err := json.Unmarshal([]byte(content), v); err != nil {
+		return fmt.Errorf("turn content is not valid JSON: %w", err)
+	}
+	return nil
+}
+
+// CodeBlock returns the contents of the first fenced code block in the turn's
+// content, or the whole content and false if there is none.
+func (t *Turn) CodeBlock() (string, bool) {
+	if code, ok := extractCodeBlock(t.Content); ok {
+		return code, true
+	}
+	return t.Content, false
+}
+
+// extractCodeBlock returns the body of the first ``` fenced block in s.
+func extractCodeBlock(s string) (string, bool) {
+	start := strings.Index(s, "```")
+	if start < 0 {
+		return "", false
+	}
+	rest := s[start+3:]
+	newline := strings.IndexByte(rest, '\n')
+	if newline < 0 {
+		return "", false
+	}
+	body := rest[newline+1:]
+	end := strings.Index(body, "```")
+	if end < 0 {
+		return "", false
+	}
+	return strings.TrimRight(body[:end], "\n"), true
+}
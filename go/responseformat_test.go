@@ -0,0 +1,68 @@
+package copilot
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestApplyResponseFormat(t *testing.T) {
+	t.Run("appends instructions and sends the protocol field", func(t *testing.T) {
+		type Summary struct {
+			Title string `json:"title"`
+		}
+		format := JSONResponseFormatFor[Summary]()
+		params := map[string]interface{}{"prompt": "Summarize"}
+		if err := applyResponseFormat(params, format); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		prompt := params["prompt"].(string)
+		if !strings.HasPrefix(prompt, "Summarize\n\nRespond with a single valid JSON value") || !strings.Contains(prompt, `"title"`) {
+			t.Errorf("Unexpected prompt %q", prompt)
+		}
+		if params["responseFormat"] != format {
+			t.Errorf("Expected responseFormat param, got %v", params["responseFormat"])
+		}
+	})
+
+	t.Run("describes each format", func(t *testing.T) {
+		for format, want := range map[*ResponseFormat]string{
+			{Type: ResponseFormatMarkdown}:                      "Markdown",
+			{Type: ResponseFormatPlainText}:                     "plain text",
+			{Type: ResponseFormatCodeBlockOnly, Language: "go"}: "fenced go code block",
+		} {
+			params := map[string]interface{}{"prompt": "x"}
+			if err := applyResponseFormat(params, format); err != nil || !strings.Contains(params["prompt"].(string), want) {
+				t.Errorf("Expected %q in prompt, got %q (%v)", want, params["prompt"], err)
+			}
+		}
+	})
+
+	t.Run("rejects unknown formats", func(t *testing.T) {
+		if err := applyResponseFormat(map[string]interface{}{}, &ResponseFormat{Type: "yaml"}); err == nil {
+			t.Error("Expected error for unknown format")
+		}
+	})
+}
+
+func TestTurn_DecodeJSON(t *testing.T) {
+	t.Run("decodes bare and fenced JSON", func(t *testing.T) {
+		for _, content := range []string{`{"title":"x"}`, "Here you go:\n```json\n{\"title\":\"x\"}\n```"} {
+			var v struct {
+				Title string `json:"title"`
+			}
+			if err := (&Turn{Content: content}).DecodeJSON(&v); err != nil || v.Title != "x" {
+				t.Errorf("Failed to decode %q: %v", content, err)
+			}
+		}
+	})
+
+	t.Run("extracts code blocks", func(t *testing.T) {
+		code, ok := (&Turn{Content: "```go\nfmt.Println()\n```"}).CodeBlock()
+		if !ok || code != "fmt.Println()" {
+			t.Errorf("Unexpected code block %q, %v", code, ok)
+		}
+		if _, ok := (&Turn{Content: "no code"}).CodeBlock(); ok {
+			t.Error("Expected no code block")
+		}
+	})
+}
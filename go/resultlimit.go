@@ -0,0 +1,178 @@
+package copilot
+
+import (
+	"context"
+	"fmt"
+	"unicode/utf8"
+)
+
+// DefaultMaxResultBytes is the limit of a [ResultLimit] whose MaxBytes is zero.
+const DefaultMaxResultBytes = 64 * 1024
+
+// TruncationStrategy shortens a tool result's TextResultForLLM to at most
+// maxBytes bytes. See [TruncateHead], [TruncateTail], [TruncateMiddle], and
+// [Summarize].
+type TruncationStrategy func(inv ToolInvocation, text string, maxBytes int) (string, error)
+
+// ResultLimit caps the size of the text a tool returns to the model, so that a
+// tool that dumps a large file does not fill the model's context.
+//
+// A truncated result reports it in ToolTelemetry: "truncated" is true, and
+// "originalBytes" and "resultBytes" are the sizes before and after.
+type ResultLimit struct {
+	// MaxBytes is the largest TextResultForLLM passed on unchanged. Defaults to
+	// [DefaultMaxResultBytes].
+	MaxBytes int
+	// Strategy shortens longer results. Defaults to [TruncateMiddle]. A result
+	// the strategy leaves too long, or fails to shorten, is truncated with
+	// TruncateMiddle instead.
+	Strategy TruncationStrategy
+}
+
+// WithResultLimit caps the size of the tool's results, overriding the session's
+// [SessionConfig.ResultLimit]. See [Tool.ResultLimit].
+//
+// Example:
+//
+//	tool := copilot.DefineTool("read_log", "Read a log file", readLog,
+//	    copilot.WithResultLimit(copilot.ResultLimit{MaxBytes: 16 * 1024, Strategy: copilot.TruncateTail}))
+func WithResultLimit(limit ResultLimit) ToolOption {
+	return func(o *toolOptions) {
+		o.resultLimit = &limit
+	}
+}
+
+// TruncateHead keeps the beginning of the text.
+func TruncateHead(inv ToolInvocation, text string, maxBytes int) (string, error) {
+	marker := fmt.Sprintf("\n[... %d bytes truncated]", len(text))
+	keep := maxBytes - len(marker)
+	if keep <= 0 {
+		return truncateUTF8(text, maxBytes), nil
+	}
+	head := truncateUTF8(text, keep)
+	return head + fmt.Sprintf("\n[... %d bytes truncated]", len(text)-len(head)), nil
+}
+
+// TruncateTail keeps the end of the text, for output such as logs where the
+// latest lines matter most.
+func TruncateTail(inv ToolInvocation, text string, maxBytes int) (string, error) {
+	marker := fmt.Sprintf("[%d bytes truncated ...]\n", len(text))
+	keep := maxBytes - len(marker)
+	if keep <= 0 {
+		return suffixUTF8(text, maxBytes), nil
+	}
+	tail := suffixUTF8(text, keep)
+	return fmt.Sprintf("[%d bytes truncated ...]\n", len(text)-len(tail)) + tail, nil
+}
+
+// TruncateMiddle keeps the beginning and end of the text and replaces the middle
+// with a marker.
+func TruncateMiddle(inv ToolInvocation, text string, maxBytes int) (string, error) {
+	marker := fmt.Sprintf("\n[... %d bytes truncated ...]\n", len(text))
+	keep := maxBytes - len(marker)
+	if keep <= 0 {
+		return truncateUTF8(text, maxBytes), nil
+	}
+	head := truncateUTF8(text, keep-keep/2)
+	tail := suffixUTF8(text, keep/2)
+	return head + fmt.Sprintf("\n[... %d bytes truncated ...]\n", len(text)-len(head)-len(tail)) + tail, nil
+}
+
+// Summarize shortens results with summarize, such as a call to a smaller model,
+// which receives the call's context. If summarize fails or returns more than
+// maxBytes, the result is truncated with [TruncateMiddle] instead.
+func Summarize(summarize func(ctx context.Context, text string, maxBytes int) (string, error)) TruncationStrategy {
+	return func(inv ToolInvocation, text string, maxBytes int) (string, error) {
+		summary, err := summarize(inv.Context(), text, maxBytes)
+		if err != nil {
+			return "", fmt.Errorf("failed to summarize result: %w", err)
+		}
+		return summary, nil
+	}
+}
+
+// truncateUTF8 returns the longest prefix of text of at most n bytes that does
+// not split a character.
+func truncateUTF8(text string, n int) string {
+	if len(text) <= n {
+		return text
+	}
+	for n > 0 && !utf8.RuneStart(text[n]) {
+		n--
+	}
+	return text[:n]
+}
+
+// suffixUTF8 returns the longest suffix of text of at most n bytes that does not
+// split a character.
+func suffixUTF8(text string, n int) string {
+	if len(text) <= n {
+		return text
+	}
+	start := len(text) - n
+	for start < len(text) && !utf8.RuneStart(text[start]) {
+		start++
+	}
+	return text[start:]
+}
+
+// apply shortens result's text if it is over the limit.
+func (l ResultLimit) apply(inv ToolInvocation, result ToolResult) ToolResult {
+	maxBytes := l.MaxBytes
+	if maxBytes <= 0 {
+		maxBytes = DefaultMaxResultBytes
+	}
+	original := len(result.TextResultForLLM)
+	if original <= maxBytes {
+		return result
+	}
+
+	telemetry := make(map[string]interface{}, len(result.ToolTelemetry)+4)
+	for key, value := range result.ToolTelemetry {
+		telemetry[key] = value
+	}
+
+	strategy := l.Strategy
+	if strategy == nil {
+		strategy = TruncateMiddle
+	}
+	text, err := strategy(inv, result.TextResultForLLM, maxBytes)
+	if err != nil {
+		telemetry["truncationError"] = err.Error()
+	}
+	if err != nil || len(text) > maxBytes {
+		text, _ = TruncateMiddle(inv, result.TextResultForLLM, maxBytes)
+	}
+
+	telemetry["truncated"] = true
+	telemetry["originalBytes"] = original
+	telemetry["resultBytes"] = len(text)
+	result.TextResultForLLM = text
+	result.ToolTelemetry = telemetry
+	return result
+}
+
+func (s *Session) registerResultLimit(limit *ResultLimit) {
+	s.toolHandlersM.Lock()
+	defer s.toolHandlersM.Unlock()
+	s.resultLimit = limit
+}
+
+// withResultLimit wraps handler so that its results are shortened to limit, the
+// tool's own or else the session's. The caller holds s.toolHandlersM.
+func (s *Session) withResultLimit(limit *ResultLimit, handler ToolHandler) ToolHandler {
+	if limit == nil {
+		limit = s.resultLimit
+	}
+	if limit == nil {
+		return handler
+	}
+	l := *limit
+	return func(inv ToolInvocation) (ToolResult, error) {
+		result, err := handler(inv)
+		if err != nil || result.pending != "" {
+			return result, err
+		}
+		return l.apply(inv, result), nil
+	}
+}
@@ -0,0 +1,122 @@
+package copilot
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestTruncationStrategies(t *testing.T) {
+	text := strings.Repeat("a", 100) + strings.Repeat("z", 100)
+
+	tests := []struct {
+		name     string
+		strategy TruncationStrategy
+		prefix   string
+		suffix   string
+	}{
+		{"head", TruncateHead, "aaaa", "bytes truncated]"},
+		{"tail", TruncateTail, "[", "zzzz"},
+		{"middle", TruncateMiddle, "aaaa", "zzzz"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.strategy(ToolInvocation{}, text, 80)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if len(got) > 80 || !strings.HasPrefix(got, tt.prefix) || !strings.HasSuffix(got, tt.suffix) {
+				t.Errorf("Unexpected truncation %q (%d bytes)", got, len(got))
+			}
+			if !strings.Contains(got, "bytes truncated") {
+				t.Errorf("Expected a truncation marker in %q", got)
+			}
+		})
+	}
+
+	t.Run("does not split characters", func(t *testing.T) {
+		got, _ := TruncateMiddle(ToolInvocation{}, strings.Repeat("日本語", 50), 60)
+		if !utf8.ValidString(got) || len(got) > 60 {
+			t.Errorf("Expected valid UTF-8 within the limit, got %q", got)
+		}
+	})
+}
+
+func TestResultLimit(t *testing.T) {
+	big := strings.Repeat("x", 1000)
+
+	t.Run("passes small results through", func(t *testing.T) {
+		result := ResultLimit{MaxBytes: 1000}.apply(ToolInvocation{}, ToolResult{TextResultForLLM: big})
+		if result.TextResultForLLM != big || result.ToolTelemetry != nil {
+			t.Errorf("Expected the result to be unchanged, got %+v", result.ToolTelemetry)
+		}
+	})
+
+	t.Run("reports truncation in telemetry", func(t *testing.T) {
+		result := ResultLimit{MaxBytes: 100}.apply(ToolInvocation{}, ToolResult{
+			TextResultForLLM: big,
+			ToolTelemetry:    map[string]interface{}{"rows": 10},
+		})
+		if len(result.TextResultForLLM) > 100 {
+			t.Errorf("Expected at most 100 bytes, got %d", len(result.TextResultForLLM))
+		}
+		telemetry := result.ToolTelemetry
+		if telemetry["truncated"] != true || telemetry["originalBytes"] != 1000 || telemetry["resultBytes"] != len(result.TextResultForLLM) || telemetry["rows"] != 10 {
+			t.Errorf("Unexpected telemetry %v", telemetry)
+		}
+	})
+
+	t.Run("summarizes with a callback", func(t *testing.T) {
+		strategy := Summarize(func(ctx context.Context, text string, maxBytes int) (string, error) {
+			return "1000 x characters", nil
+		})
+		result := ResultLimit{MaxBytes: 100, Strategy: strategy}.apply(ToolInvocation{}, ToolResult{TextResultForLLM: big})
+		if result.TextResultForLLM != "1000 x characters" {
+			t.Errorf("Expected the summary, got %q", result.TextResultForLLM)
+		}
+	})
+
+	t.Run("falls back when the strategy fails or overruns", func(t *testing.T) {
+		failing := Summarize(func(ctx context.Context, text string, maxBytes int) (string, error) {
+			return "", errors.New("model unavailable")
+		})
+		result := ResultLimit{MaxBytes: 100, Strategy: failing}.apply(ToolInvocation{}, ToolResult{TextResultForLLM: big})
+		if len(result.TextResultForLLM) > 100 || !strings.Contains(result.ToolTelemetry["truncationError"].(string), "model unavailable") {
+			t.Errorf("Expected a middle truncation and the error, got %q, %v", result.TextResultForLLM, result.ToolTelemetry)
+		}
+
+		overrun := func(inv ToolInvocation, text string, maxBytes int) (string, error) { return text, nil }
+		result = ResultLimit{MaxBytes: 100, Strategy: overrun}.apply(ToolInvocation{}, ToolResult{TextResultForLLM: big})
+		if len(result.TextResultForLLM) > 100 {
+			t.Errorf("Expected the limit to be enforced, got %d bytes", len(result.TextResultForLLM))
+		}
+	})
+}
+
+func TestSession_ResultLimit(t *testing.T) {
+	type Params struct{}
+	dump := func(params Params, inv ToolInvocation) (string, error) {
+		return strings.Repeat("line\n", 1000), nil
+	}
+
+	session := NewSession("s1", nil, "")
+	session.registerResultLimit(&ResultLimit{MaxBytes: 200})
+	session.registerTools([]Tool{
+		DefineTool("dump", "Dump a file", dump),
+		DefineTool("tail", "Tail a file", dump, WithResultLimit(ResultLimit{MaxBytes: 50, Strategy: TruncateTail})),
+	})
+
+	handler, _ := session.getToolHandler("dump")
+	result, _ := handler(ToolInvocation{Arguments: map[string]interface{}{}})
+	if len(result.TextResultForLLM) > 200 || result.ToolTelemetry["truncated"] != true {
+		t.Errorf("Expected the session limit, got %d bytes", len(result.TextResultForLLM))
+	}
+
+	handler, _ = session.getToolHandler("tail")
+	result, _ = handler(ToolInvocation{Arguments: map[string]interface{}{}})
+	if len(result.TextResultForLLM) > 50 || !strings.HasSuffix(result.TextResultForLLM, "line\n") {
+		t.Errorf("Expected the tool's tail limit, got %q", result.TextResultForLLM)
+	}
+}
@@ -0,0 +1,361 @@
+package copilot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"iter"
+	"time"
+)
+
+// RetentionVerdict is what a [RetentionRule] decides about a stored session.
+type RetentionVerdict int
+
+const (
+	// RetentionAbstain leaves the decision to the rules that follow.
+	RetentionAbstain RetentionVerdict = iota
+	// RetentionKeep keeps the session, whatever the rules that follow decide.
+	RetentionKeep
+	// RetentionDelete deletes the session, unless an earlier rule kept it.
+	RetentionDelete
+)
+
+// RetentionDecision is the verdict of a [RetentionRule] and why it was reached,
+// for reports.
+type RetentionDecision struct {
+	Verdict RetentionVerdict
+	Reason  string
+}
+
+// RetentionCandidate is a stored session considered by a [RetentionCollector].
+type RetentionCandidate struct {
+	// Source is the name of the [RetentionSource] the session is stored in.
+	Source string
+	// SessionID is the ID the session is stored under. For transcripts stored
+	// through a [TenantTranscriptStore], it is prefixed with the tenant.
+	SessionID string
+	// Tenant is the tenant that owns the session, if any.
+	Tenant string
+	// Tags are the session's labels, such as a legal hold flag.
+	Tags []string
+	// CreatedAt is when the session started, if known.
+	CreatedAt time.Time
+	// UpdatedAt is when the session was last modified.
+	UpdatedAt time.Time
+}
+
+// HasTag reports whether the candidate has the given tag.
+func (c RetentionCandidate) HasTag(tag string) bool {
+	for _, existing := range c.Tags {
+		if existing == tag {
+			return true
+		}
+	}
+	return false
+}
+
+// RetentionRule decides whether a stored session is kept or deleted. Rules are
+// evaluated in order and the first that does not abstain decides; a session no
+// rule decides on is kept. Put holds, such as [KeepTagged], before the rules
+// that delete.
+type RetentionRule func(candidate RetentionCandidate, now time.Time) RetentionDecision
+
+// KeepTagged returns a rule that keeps sessions with tag, for example a legal
+// hold flag.
+func KeepTagged(tag string) RetentionRule {
+	return func(c RetentionCandidate, now time.Time) RetentionDecision {
+		if c.HasTag(tag) {
+			return RetentionDecision{Verdict: RetentionKeep, Reason: fmt.Sprintf("tagged %s", tag)}
+		}
+		return RetentionDecision{}
+	}
+}
+
+// DeleteOlderThan returns a rule that deletes sessions last updated more than
+// age ago.
+func DeleteOlderThan(age time.Duration) RetentionRule {
+	return func(c RetentionCandidate, now time.Time) RetentionDecision {
+		if idle := now.Sub(c.UpdatedAt); idle > age {
+			return RetentionDecision{Verdict: RetentionDelete, Reason: fmt.Sprintf("not updated for %s, over %s", idle.Round(time.Second), age)}
+		}
+		return RetentionDecision{}
+	}
+}
+
+// PerTenant returns a rule that applies the rule of the candidate's tenant. It
+// abstains for sessions of tenants without a rule, and for sessions without a
+// tenant.
+//
+// Example:
+//
+//	copilot.PerTenant(map[string]copilot.RetentionRule{
+//	    "acme":    copilot.DeleteOlderThan(30 * 24 * time.Hour),
+//	    "initech": copilot.DeleteOlderThan(7 * 24 * time.Hour),
+//	})
+func PerTenant(rules map[string]RetentionRule) RetentionRule {
+	return func(c RetentionCandidate, now time.Time) RetentionDecision {
+		rule, ok := rules[c.Tenant]
+		if !ok || c.Tenant == "" {
+			return RetentionDecision{}
+		}
+		decision := rule(c, now)
+		if decision.Verdict != RetentionAbstain {
+			decision.Reason = fmt.Sprintf("tenant %s: %s", c.Tenant, decision.Reason)
+		}
+		return decision
+	}
+}
+
+// RetentionSource is a store of sessions a [RetentionCollector] can delete
+// from, such as [TranscriptRetentionSource] and [SessionRetentionSource].
+type RetentionSource interface {
+	// Name identifies the source in reports.
+	Name() string
+	// Candidates iterates over the stored sessions. A session that cannot be
+	// read is reported as an error without stopping the iteration.
+	Candidates() iter.Seq2[RetentionCandidate, error]
+	// Delete removes a stored session.
+	Delete(sessionID string) error
+}
+
+// TranscriptRetentionSource returns a [RetentionSource] for the transcripts in
+// store. Pass the base store of a [TenantTranscriptStore] to collect across
+// tenants, with each candidate's Tenant set.
+func TranscriptRetentionSource(store TranscriptStore) RetentionSource {
+	return transcriptRetentionSource{store}
+}
+
+type transcriptRetentionSource struct {
+	store TranscriptStore
+}
+
+func (s transcriptRetentionSource) Name() string { return "transcripts" }
+
+func (s transcriptRetentionSource) Candidates() iter.Seq2[RetentionCandidate, error] {
+	return func(yield func(RetentionCandidate, error) bool) {
+		for transcript, err := range s.store.All() {
+			if err != nil {
+				if !yield(RetentionCandidate{}, err) {
+					return
+				}
+				continue
+			}
+			candidate := RetentionCandidate{
+				Source:    s.Name(),
+				SessionID: transcript.SessionID,
+				Tenant:    transcript.Tenant,
+				Tags:      transcript.Tags,
+				CreatedAt: transcript.CreatedAt,
+				UpdatedAt: transcript.UpdatedAt,
+			}
+			if !yield(candidate, nil) {
+				return
+			}
+		}
+	}
+}
+
+func (s transcriptRetentionSource) Delete(sessionID string) error {
+	return s.store.Delete(sessionID)
+}
+
+// SessionRetentionSource returns a [RetentionSource] for the sessions the
+// client's server stores, as listed by [Client.ListSessions] and deleted with
+// [Client.DeleteSession].
+func SessionRetentionSource(client *Client) RetentionSource {
+	return sessionRetentionSource{client}
+}
+
+type sessionRetentionSource struct {
+	client *Client
+}
+
+func (s sessionRetentionSource) Name() string { return "sessions" }
+
+func (s sessionRetentionSource) Candidates() iter.Seq2[RetentionCandidate, error] {
+	return func(yield func(RetentionCandidate, error) bool) {
+		sessions, err := s.client.ListSessions()
+		if err != nil {
+			yield(RetentionCandidate{}, err)
+			return
+		}
+		for _, session := range sessions {
+			candidate := RetentionCandidate{Source: s.Name(), SessionID: session.SessionID}
+			candidate.CreatedAt, _ = time.Parse(time.RFC3339, session.StartTime)
+			updatedAt, err := time.Parse(time.RFC3339, session.ModifiedTime)
+			if err != nil {
+				// Without a modification time, age-based rules cannot apply.
+				if !yield(RetentionCandidate{}, fmt.Errorf("session %s has an invalid modified time %q", session.SessionID, session.ModifiedTime)) {
+					return
+				}
+				continue
+			}
+			candidate.UpdatedAt = updatedAt
+			if !yield(candidate, nil) {
+				return
+			}
+		}
+	}
+}
+
+func (s sessionRetentionSource) Delete(sessionID string) error {
+	return s.client.DeleteSession(sessionID)
+}
+
+// RetentionConfig configures a [RetentionCollector].
+type RetentionConfig struct {
+	// Rules decide which sessions are deleted. See [RetentionRule].
+	Rules []RetentionRule
+	// DryRun reports the sessions that would be deleted without deleting
+	// them.
+	DryRun bool
+	// Interval is how often [RetentionCollector.Run] collects. Defaults to
+	// one hour.
+	Interval time.Duration
+	// OnReport, if set, is called with the report of each collection by
+	// [RetentionCollector.Run].
+	OnReport func(report *RetentionReport)
+	// Now returns the current time. Defaults to [time.Now].
+	Now func() time.Time
+}
+
+// RetentionAction is a session a collection deleted, would have deleted in a
+// dry run, or kept because a rule held it.
+type RetentionAction struct {
+	Candidate RetentionCandidate
+	// Reason is the reason of the deciding rule.
+	Reason string
+}
+
+// RetentionReport describes one collection.
+type RetentionReport struct {
+	// StartedAt is when the collection started.
+	StartedAt time.Time
+	// Duration is how long the collection took.
+	Duration time.Duration
+	// DryRun reports whether sessions were left in place.
+	DryRun bool
+	// Scanned is the number of sessions considered.
+	Scanned int
+	// Deleted are the sessions deleted, or that would have been deleted in a
+	// dry run.
+	Deleted []RetentionAction
+	// Held are the sessions a rule decided to keep, such as those under a legal
+	// hold. Sessions no rule decided on are not listed.
+	Held []RetentionAction
+	// Errors are the failures to list or delete sessions.
+	Errors []error
+}
+
+// RetentionCollector deletes stored sessions that retention rules no longer
+// keep, so operators can enforce retention without scripts against the
+// stores' formats.
+//
+// Example:
+//
+//	collector := copilot.NewRetentionCollector(copilot.RetentionConfig{
+//	    Rules: []copilot.RetentionRule{
+//	        copilot.KeepTagged("legal-hold"),
+//	        copilot.PerTenant(map[string]copilot.RetentionRule{"acme": copilot.DeleteOlderThan(7 * 24 * time.Hour)}),
+//	        copilot.DeleteOlderThan(90 * 24 * time.Hour),
+//	    },
+//	    OnReport: func(r *copilot.RetentionReport) {
+//	        log.Printf("retention: deleted %d of %d sessions", len(r.Deleted), r.Scanned)
+//	    },
+//	}, copilot.TranscriptRetentionSource(store), copilot.SessionRetentionSource(client))
+//	go collector.Run(ctx)
+type RetentionCollector struct {
+	config  RetentionConfig
+	sources []RetentionSource
+}
+
+// NewRetentionCollector creates a collector that applies config to sources.
+func NewRetentionCollector(config RetentionConfig, sources ...RetentionSource) *RetentionCollector {
+	if config.Interval <= 0 {
+		config.Interval = time.Hour
+	}
+	if config.Now == nil {
+		config.Now = time.Now
+	}
+	return &RetentionCollector{config: config, sources: sources}
+}
+
+// Decide returns the decision of the collector's rules about candidate.
+func (g *RetentionCollector) Decide(candidate RetentionCandidate) RetentionDecision {
+	now := g.config.Now()
+	for _, rule := range g.config.Rules {
+		if decision := rule(candidate, now); decision.Verdict != RetentionAbstain {
+			return decision
+		}
+	}
+	return RetentionDecision{Verdict: RetentionKeep}
+}
+
+// Collect runs one collection over every source, deleting the sessions the
+// rules decide to delete unless DryRun is set. The error joins the report's
+// Errors, or is ctx's error if the collection was canceled; the report
+// describes the sessions handled either way.
+func (g *RetentionCollector) Collect(ctx context.Context) (*RetentionReport, error) {
+	report := &RetentionReport{StartedAt: g.config.Now(), DryRun: g.config.DryRun}
+	defer func() { report.Duration = g.config.Now().Sub(report.StartedAt) }()
+
+	for _, source := range g.sources {
+		var doomed []RetentionAction
+		for candidate, err := range source.Candidates() {
+			if ctxErr := ctx.Err(); ctxErr != nil {
+				return report, ctxErr
+			}
+			if err != nil {
+				report.Errors = append(report.Errors, fmt.Errorf("failed to list %s: %w", source.Name(), err))
+				continue
+			}
+			report.Scanned++
+			decision := g.Decide(candidate)
+			action := RetentionAction{Candidate: candidate, Reason: decision.Reason}
+			switch {
+			case decision.Verdict == RetentionDelete:
+				doomed = append(doomed, action)
+			case decision.Reason != "":
+				report.Held = append(report.Held, action)
+			}
+		}
+
+		// Sessions are deleted after listing, so that stores need not support
+		// deletion while they are iterated.
+		for _, action := range doomed {
+			if ctxErr := ctx.Err(); ctxErr != nil {
+				return report, ctxErr
+			}
+			if !g.config.DryRun {
+				if err := source.Delete(action.Candidate.SessionID); err != nil {
+					report.Errors = append(report.Errors, fmt.Errorf("failed to delete %s %s: %w", source.Name(), action.Candidate.SessionID, err))
+					continue
+				}
+			}
+			report.Deleted = append(report.Deleted, action)
+		}
+	}
+	return report, errors.Join(report.Errors...)
+}
+
+// Run collects every Interval, starting at once, until ctx is done, and
+// returns ctx's error. Reports are passed to OnReport; failures within a
+// collection do not stop the loop.
+func (g *RetentionCollector) Run(ctx context.Context) error {
+	ticker := time.NewTicker(g.config.Interval)
+	defer ticker.Stop()
+	for {
+		report, _ := g.Collect(ctx)
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if g.config.OnReport != nil {
+			g.config.OnReport(report)
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,173 @@
+package copilot
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestRetentionCollector(t *testing.T) {
+	now := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	daysAgo := func(days int) time.Time { return now.AddDate(0, 0, -days) }
+	newStore := func() TranscriptStore {
+		store := NewMemoryTranscriptStore()
+		store.Save(&Transcript{SessionID: "fresh", CreatedAt: daysAgo(1), UpdatedAt: daysAgo(1)})
+		store.Save(&Transcript{SessionID: "stale", CreatedAt: daysAgo(100), UpdatedAt: daysAgo(100)})
+		store.Save(&Transcript{SessionID: "held", Tags: []string{"legal-hold"}, CreatedAt: daysAgo(200), UpdatedAt: daysAgo(200)})
+		store.Save(&Transcript{SessionID: "acme/s1", Tenant: "acme", CreatedAt: daysAgo(10), UpdatedAt: daysAgo(10)})
+		return store
+	}
+	rules := []RetentionRule{
+		KeepTagged("legal-hold"),
+		PerTenant(map[string]RetentionRule{"acme": DeleteOlderThan(7 * 24 * time.Hour)}),
+		DeleteOlderThan(90 * 24 * time.Hour),
+	}
+	ids := func(actions []RetentionAction) string {
+		var ids []string
+		for _, action := range actions {
+			ids = append(ids, action.Candidate.SessionID)
+		}
+		return strings.Join(ids, ",")
+	}
+
+	t.Run("deletes what the rules do not keep", func(t *testing.T) {
+		store := newStore()
+		collector := NewRetentionCollector(RetentionConfig{Rules: rules, Now: func() time.Time { return now }}, TranscriptRetentionSource(store))
+		report, err := collector.Collect(context.Background())
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if report.Scanned != 4 || ids(report.Deleted) != "stale,acme/s1" || ids(report.Held) != "held" {
+			t.Errorf("Unexpected report %+v", report)
+		}
+		if !strings.HasPrefix(report.Deleted[1].Reason, "tenant acme: ") || report.Held[0].Reason != "tagged legal-hold" {
+			t.Errorf("Unexpected reasons %q, %q", report.Deleted[1].Reason, report.Held[0].Reason)
+		}
+		for _, id := range []string{"stale", "acme/s1"} {
+			if _, err := store.Load(id); !errors.Is(err, ErrTranscriptNotFound) {
+				t.Errorf("Expected %s to be deleted, got %v", id, err)
+			}
+		}
+		for _, id := range []string{"fresh", "held"} {
+			if _, err := store.Load(id); err != nil {
+				t.Errorf("Expected %s to be kept, got %v", id, err)
+			}
+		}
+	})
+
+	t.Run("dry run leaves sessions in place", func(t *testing.T) {
+		store := newStore()
+		collector := NewRetentionCollector(RetentionConfig{Rules: rules, DryRun: true, Now: func() time.Time { return now }}, TranscriptRetentionSource(store))
+		report, err := collector.Collect(context.Background())
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if !report.DryRun || ids(report.Deleted) != "stale,acme/s1" {
+			t.Errorf("Unexpected report %+v", report)
+		}
+		if _, err := store.Load("stale"); err != nil {
+			t.Errorf("Expected a dry run to keep the session, got %v", err)
+		}
+	})
+
+	t.Run("keeps sessions no rule decides on", func(t *testing.T) {
+		collector := NewRetentionCollector(RetentionConfig{})
+		if decision := collector.Decide(RetentionCandidate{SessionID: "s1"}); decision.Verdict != RetentionKeep {
+			t.Errorf("Expected the default to keep, got %+v", decision)
+		}
+	})
+
+	t.Run("reports delete failures and keeps going", func(t *testing.T) {
+		source := &failingRetentionSource{TranscriptRetentionSource(newStore()), "stale"}
+		collector := NewRetentionCollector(RetentionConfig{Rules: rules, Now: func() time.Time { return now }}, source)
+		report, err := collector.Collect(context.Background())
+		if err == nil || !strings.Contains(err.Error(), "failed to delete transcripts stale") {
+			t.Errorf("Expected the delete failure, got %v", err)
+		}
+		if len(report.Errors) != 1 || ids(report.Deleted) != "acme/s1" {
+			t.Errorf("Unexpected report %+v", report)
+		}
+	})
+
+	t.Run("runs on an interval", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		var mu sync.Mutex
+		var reports []*RetentionReport
+		collector := NewRetentionCollector(RetentionConfig{
+			Rules:    rules,
+			Interval: time.Millisecond,
+			Now:      func() time.Time { return now },
+			OnReport: func(report *RetentionReport) {
+				mu.Lock()
+				defer mu.Unlock()
+				if reports = append(reports, report); len(reports) == 2 {
+					cancel()
+				}
+			},
+		}, TranscriptRetentionSource(newStore()))
+		if err := collector.Run(ctx); !errors.Is(err, context.Canceled) {
+			t.Errorf("Expected the context's error, got %v", err)
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		if len(reports) != 2 || len(reports[0].Deleted) != 2 || len(reports[1].Deleted) != 0 {
+			t.Errorf("Expected the second collection to find nothing left, got %+v", reports)
+		}
+	})
+}
+
+func TestSessionRetentionSource(t *testing.T) {
+	var mu sync.Mutex
+	var deleted []string
+	rpc, _ := newFakeServer(t, func(method string, params map[string]interface{}) (map[string]interface{}, *JSONRPCError) {
+		switch method {
+		case "session.list":
+			return map[string]interface{}{"sessions": []interface{}{
+				map[string]interface{}{"sessionId": "old", "startTime": "2024-01-01T00:00:00Z", "modifiedTime": "2024-01-02T00:00:00Z"},
+				map[string]interface{}{"sessionId": "new", "startTime": "2024-05-31T00:00:00Z", "modifiedTime": "2024-05-31T12:00:00Z"},
+				map[string]interface{}{"sessionId": "broken", "modifiedTime": "yesterday"},
+			}}, nil
+		case "session.delete":
+			mu.Lock()
+			deleted = append(deleted, params["sessionId"].(string))
+			mu.Unlock()
+			return map[string]interface{}{"success": true}, nil
+		}
+		return nil, &JSONRPCError{Code: -32601, Message: "unknown method"}
+	})
+	client := &Client{client: rpc, state: StateConnected, sessions: make(map[string]*Session)}
+
+	now := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	collector := NewRetentionCollector(RetentionConfig{
+		Rules: []RetentionRule{DeleteOlderThan(30 * 24 * time.Hour)},
+		Now:   func() time.Time { return now },
+	}, SessionRetentionSource(client))
+	report, err := collector.Collect(context.Background())
+	if err == nil || !strings.Contains(err.Error(), `invalid modified time "yesterday"`) {
+		t.Errorf("Expected the unparseable session to be reported, got %v", err)
+	}
+	if report.Scanned != 2 || len(report.Deleted) != 1 || !report.Deleted[0].Candidate.CreatedAt.Equal(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("Unexpected report %+v", report)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if strings.Join(deleted, ",") != "old" {
+		t.Errorf("Expected only the old session to be deleted, got %v", deleted)
+	}
+}
+
+// failingRetentionSource fails to delete one session.
+type failingRetentionSource struct {
+	RetentionSource
+	failing string
+}
+
+func (s *failingRetentionSource) Delete(sessionID string) error {
+	if sessionID == s.failing {
+		return errors.New("disk on fire")
+	}
+	return s.RetentionSource.Delete(sessionID)
+}
@@ -0,0 +1,505 @@
+package copilot
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/google/jsonschema-go/jsonschema"
+)
+
+// schemaForType generates the JSON schema for t and applies this package's
+// field conventions on top of the jsonschema library's:
+//
+//   - A field is optional, and left out of its object's "required" array, if it
+//     is a pointer, if its json tag has omitempty or omitzero, or if its
+//     jsonschema tag has the "optional" directive.
+//   - The "required" directive makes a field required even if it would
+//     otherwise be optional.
+//   - The enum, minimum, maximum, pattern, and format directives constrain the
+//     field's values, or its elements' values for slices and arrays.
+//   - A default tag, as in `default:"celsius"`, sets the field's "default" and
+//     makes it optional. Values of string, number, integer, and boolean fields
+//     are written as is; values of other fields are written as JSON, as in
+//     `default:"[\"txt\",\"md\"]"`.
+//
+// Directives are comma-separated at the start of the jsonschema tag. The rest
+// of the tag is the field's description, as in
+// `jsonschema:"optional,enum=celsius|fahrenheit,Temperature unit"`. Write \, for
+// a comma inside a directive value.
+//
+// Maps have an "additionalProperties" schema of their values; maps with
+// integer keys also have a "propertyNames" pattern. time.Time values are
+// strings of format "date-time", and time.Duration values strings of format
+// "duration", such as "1h30m" or "PT1H30M", which decodeArguments parses.
+//
+// Values of interface types registered with [RegisterUnionVariant] have a
+// oneOf schema of the variants. Fields without a description in their tag
+// take the description registered with [RegisterFieldDescriptions], if any.
+//
+// Schemas are cached by type; each call returns a copy the caller may modify.
+func schemaForType(t reflect.Type) (map[string]interface{}, error) {
+	return cachedSchema(t, func() (map[string]interface{}, error) {
+		return generateSchemaMap(t)
+	})
+}
+
+// generateSchemaMap generates the schema for t as described by schemaForType,
+// bypassing the cache.
+func generateSchemaMap(t reflect.Type) (map[string]interface{}, error) {
+	schema, err := schemaObjectForType(t, make(map[reflect.Type]bool))
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := json.Marshal(schema)
+	if err != nil {
+		return nil, err
+	}
+	var schemaMap map[string]interface{}
+	if err := json.Unmarshal(data, &schemaMap); err != nil {
+		return nil, err
+	}
+	return schemaMap, nil
+}
+
+// schemaObjectForType generates the schema for t as described by
+// schemaForType. resolving holds the unions whose schemas are being generated.
+func schemaObjectForType(t reflect.Type, resolving map[reflect.Type]bool) (*jsonschema.Schema, error) {
+	// The jsonschema library treats the whole tag as a description and rejects
+	// tags that start with "key=", so it is given a copy of t with the
+	// directives removed from its tags.
+	shadow, _, err := schemaShadowType(t, make(map[reflect.Type]bool))
+	if err != nil {
+		return nil, err
+	}
+	schema, err := jsonschema.ForType(shadow, nil)
+	if err != nil {
+		return nil, err
+	}
+	if err := applyFieldTags(t, schema, resolving); err != nil {
+		return nil, err
+	}
+	return schema, nil
+}
+
+// schemaTag is a parsed jsonschema struct tag.
+type schemaTag struct {
+	optional, required bool
+	enum               []string
+	minimum, maximum   *float64
+	pattern, format    string
+	description        string
+	// hasDirectives is whether the tag is more than a description.
+	hasDirectives bool
+}
+
+var keyValueDirective = regexp.MustCompile(`^[^ \t\n]*=`)
+
+// parseSchemaTag parses the directives at the start of a jsonschema tag. The
+// first item that is not a directive starts the description.
+func parseSchemaTag(tag string) (schemaTag, error) {
+	var parsed schemaTag
+	for rest := tag; rest != ""; {
+		item, next := cutSchemaTagItem(rest)
+		word := strings.TrimSpace(item)
+		key, value, isKeyValue := strings.Cut(word, "=")
+		isKeyValue = isKeyValue && keyValueDirective.MatchString(word)
+
+		switch {
+		case word == "optional":
+			parsed.optional = true
+		case word == "required":
+			parsed.required = true
+		case isKeyValue && key == "enum":
+			parsed.enum = strings.Split(value, "|")
+		case isKeyValue && (key == "minimum" || key == "maximum"):
+			n, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				return schemaTag{}, fmt.Errorf("invalid %s %q", key, value)
+			}
+			if key == "minimum" {
+				parsed.minimum = &n
+			} else {
+				parsed.maximum = &n
+			}
+		case isKeyValue && key == "pattern":
+			if _, err := regexp.Compile(value); err != nil {
+				return schemaTag{}, fmt.Errorf("invalid pattern %q: %w", value, err)
+			}
+			parsed.pattern = value
+		case isKeyValue && key == "format":
+			parsed.format = value
+		case isKeyValue:
+			return schemaTag{}, fmt.Errorf("unknown jsonschema directive %q", key)
+		default:
+			parsed.description = strings.TrimSpace(rest)
+			return parsed, nil
+		}
+		parsed.hasDirectives = true
+		rest = next
+	}
+	if parsed.optional && parsed.required {
+		return schemaTag{}, fmt.Errorf("field cannot be both optional and required")
+	}
+	return parsed, nil
+}
+
+// cutSchemaTagItem returns the first comma-separated item of s, with \,
+// unescaped, and the text after its comma.
+func cutSchemaTagItem(s string) (item, rest string) {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		switch {
+		case s[i] == '\\' && i+1 < len(s) && s[i+1] == ',':
+			b.WriteByte(',')
+			i++
+		case s[i] == ',':
+			return b.String(), s[i+1:]
+		default:
+			b.WriteByte(s[i])
+		}
+	}
+	return b.String(), ""
+}
+
+// schemaShadowType returns a copy of t in which jsonschema tags hold only
+// descriptions, and whether it differs from t. Struct copies have the fields
+// the jsonschema library would see: the exported fields of t, with embedded
+// structs flattened.
+func schemaShadowType(t reflect.Type, seen map[reflect.Type]bool) (reflect.Type, bool, error) {
+	if t == durationType {
+		// Durations are written as strings, such as "1h30m", rather than as
+		// the nanoseconds encoding/json uses.
+		return reflect.TypeOf(""), true, nil
+	}
+	switch t.Kind() {
+	case reflect.Ptr:
+		elem, changed, err := schemaShadowType(t.Elem(), seen)
+		if err != nil || !changed {
+			return t, false, err
+		}
+		return reflect.PointerTo(elem), true, nil
+	case reflect.Slice:
+		elem, changed, err := schemaShadowType(t.Elem(), seen)
+		if err != nil || !changed {
+			return t, false, err
+		}
+		return reflect.SliceOf(elem), true, nil
+	case reflect.Array:
+		elem, changed, err := schemaShadowType(t.Elem(), seen)
+		if err != nil || !changed {
+			return t, false, err
+		}
+		return reflect.ArrayOf(t.Len(), elem), true, nil
+	case reflect.Map:
+		elem, changed, err := schemaShadowType(t.Elem(), seen)
+		if err != nil {
+			return t, false, err
+		}
+		// Keys are object property names, whatever their Go type.
+		key := t.Key()
+		if key.Kind() != reflect.String {
+			key, changed = reflect.TypeOf(""), true
+		}
+		if !changed {
+			return t, false, nil
+		}
+		return reflect.MapOf(key, elem), true, nil
+	case reflect.Struct:
+	default:
+		return t, false, nil
+	}
+
+	// Leave cycles for the jsonschema library to report.
+	if seen[t] {
+		return t, false, nil
+	}
+	seen[t] = true
+	defer delete(seen, t)
+
+	var fields []reflect.StructField
+	changed := false
+	for _, field := range reflect.VisibleFields(t) {
+		if !field.IsExported() || field.Anonymous {
+			continue
+		}
+		fieldType, fieldChanged, err := schemaShadowType(field.Type, seen)
+		if err != nil {
+			return nil, false, err
+		}
+		tag := field.Tag
+		var parsed schemaTag
+		if value, ok := tag.Lookup("jsonschema"); ok {
+			if parsed, err = parseSchemaTag(value); err != nil {
+				return nil, false, fmt.Errorf("field %s.%s: %w", t, field.Name, err)
+			}
+		}
+		description := parsed.description
+		if description == "" {
+			description = registeredFieldDescription(t, field)
+		}
+		if parsed.hasDirectives || description != parsed.description {
+			tag = withSchemaDescription(tag, description)
+			fieldChanged = true
+		}
+		changed = changed || fieldChanged
+		fields = append(fields, reflect.StructField{Name: field.Name, Type: fieldType, Tag: tag})
+	}
+	if !changed {
+		return t, false, nil
+	}
+	return reflect.StructOf(fields), true, nil
+}
+
+// withSchemaDescription returns tag with its jsonschema value replaced by
+// description, or removed if description is empty.
+func withSchemaDescription(tag reflect.StructTag, description string) reflect.StructTag {
+	var parts []string
+	if value, ok := tag.Lookup("json"); ok {
+		parts = append(parts, "json:"+strconv.Quote(value))
+	}
+	if description != "" {
+		parts = append(parts, "jsonschema:"+strconv.Quote(description))
+	}
+	return reflect.StructTag(strings.Join(parts, " "))
+}
+
+// applyFieldTags walks t alongside its generated schema, rewriting the
+// "required" arrays of struct schemas and adding constraints according to
+// field tags. Schemas of union-typed values are set with applyUnion.
+func applyFieldTags(t reflect.Type, schema *jsonschema.Schema, resolving map[reflect.Type]bool) error {
+	if schema == nil {
+		return nil
+	}
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	switch t {
+	case timeType:
+		if schema.Format == "" {
+			schema.Format = dateTimeFormat
+		}
+		return nil
+	case durationType:
+		if schema.Format == "" {
+			schema.Format = durationFormat
+		}
+		if schema.Description == "" {
+			schema.Description = durationDescription
+		}
+		return nil
+	}
+
+	switch t.Kind() {
+	case reflect.Slice, reflect.Array:
+		return applyFieldTags(t.Elem(), schema.Items, resolving)
+	case reflect.Map:
+		if pattern := mapKeyPattern(t.Key()); pattern != "" {
+			schema.PropertyNames = &jsonschema.Schema{Type: "string", Pattern: pattern}
+		}
+		return applyFieldTags(t.Elem(), schema.AdditionalProperties, resolving)
+	case reflect.Interface:
+		return applyUnion(t, schema, resolving)
+	case reflect.Struct:
+	default:
+		return nil
+	}
+
+	optional := make(map[string]bool)
+	for _, field := range reflect.VisibleFields(t) {
+		name, omitEmpty, ok := jsonFieldName(field)
+		if !ok {
+			continue
+		}
+		property := schema.Properties[name]
+		if property == nil {
+			continue
+		}
+
+		isOptional := omitEmpty || field.Type.Kind() == reflect.Ptr
+		if value, ok := field.Tag.Lookup("default"); ok {
+			if err := applyDefault(property, value); err != nil {
+				return fmt.Errorf("field %s.%s: %w", t, field.Name, err)
+			}
+			isOptional = true
+		}
+		if value, ok := field.Tag.Lookup("jsonschema"); ok {
+			// The tag was validated when the shadow type was built.
+			parsed, _ := parseSchemaTag(value)
+			if parsed.optional {
+				isOptional = true
+			} else if parsed.required {
+				isOptional = false
+			}
+			if err := applyConstraints(property, parsed); err != nil {
+				return fmt.Errorf("field %s.%s: %w", t, field.Name, err)
+			}
+		}
+		optional[name] = isOptional
+		if err := applyFieldTags(field.Type, property, resolving); err != nil {
+			return err
+		}
+	}
+
+	var required []string
+	for _, name := range schema.PropertyOrder {
+		if isOptional, ok := optional[name]; ok && !isOptional {
+			required = append(required, name)
+		}
+	}
+	schema.Required = required
+	return nil
+}
+
+// mapKeyPattern returns the pattern the property names of a map with integer
+// keys must match, or "" for other maps.
+func mapKeyPattern(key reflect.Type) string {
+	switch key.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return `^-?[0-9]+$`
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return `^[0-9]+$`
+	}
+	return ""
+}
+
+// applyConstraints adds the constraints in tag to a property's schema, or to
+// its items' schema for arrays.
+func applyConstraints(property *jsonschema.Schema, tag schemaTag) error {
+	target := property
+	if nonNullType(property) == "array" && property.Items != nil {
+		target = property.Items
+	}
+	kind := nonNullType(target)
+
+	if tag.enum != nil {
+		enum := make([]any, 0, len(tag.enum)+1)
+		for _, value := range tag.enum {
+			converted, err := enumValue(kind, value)
+			if err != nil {
+				return err
+			}
+			enum = append(enum, converted)
+		}
+		for _, t := range target.Types {
+			if t == "null" {
+				enum = append(enum, nil)
+			}
+		}
+		target.Enum = enum
+	}
+	if tag.minimum != nil || tag.maximum != nil {
+		if kind != "integer" && kind != "number" {
+			return fmt.Errorf("minimum and maximum require a numeric field, not %s", kind)
+		}
+		if tag.minimum != nil {
+			target.Minimum = tag.minimum
+		}
+		if tag.maximum != nil {
+			target.Maximum = tag.maximum
+		}
+	}
+	if tag.pattern != "" || tag.format != "" {
+		if kind != "string" {
+			return fmt.Errorf("pattern and format require a string field, not %s", kind)
+		}
+		target.Pattern = tag.pattern
+		target.Format = tag.format
+	}
+	return nil
+}
+
+// applyDefault sets the default of a property's schema from the value of a
+// default tag.
+func applyDefault(property *jsonschema.Schema, value string) error {
+	var converted any
+	switch kind := nonNullType(property); kind {
+	case "string", "integer", "number", "boolean":
+		var err error
+		if converted, err = enumValue(kind, value); err != nil {
+			return fmt.Errorf("invalid %s default %q", kind, value)
+		}
+	default:
+		if err := json.Unmarshal([]byte(value), &converted); err != nil {
+			return fmt.Errorf("invalid default %q: %s fields take a JSON value", value, kind)
+		}
+	}
+	data, err := json.Marshal(converted)
+	if err != nil {
+		return err
+	}
+	property.Default = data
+	return nil
+}
+
+// nonNullType returns the non-null type of a schema.
+func nonNullType(schema *jsonschema.Schema) string {
+	if schema.Type != "" {
+		return schema.Type
+	}
+	for _, t := range schema.Types {
+		if t != "null" {
+			return t
+		}
+	}
+	return ""
+}
+
+// enumValue converts an enum value from a tag to the JSON type of the field.
+func enumValue(kind, value string) (any, error) {
+	switch kind {
+	case "string", "":
+		return value, nil
+	case "integer":
+		n, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid integer enum value %q", value)
+		}
+		return n, nil
+	case "number":
+		n, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid number enum value %q", value)
+		}
+		return n, nil
+	case "boolean":
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid boolean enum value %q", value)
+		}
+		return b, nil
+	}
+	return nil, fmt.Errorf("enum is not supported for %s fields", kind)
+}
+
+// jsonFieldName returns the JSON name of a struct field and whether it is
+// omitted when empty. It returns false for fields that are not serialized as
+// properties of the struct.
+func jsonFieldName(field reflect.StructField) (name string, omitEmpty, ok bool) {
+	if !field.IsExported() || field.Anonymous {
+		return "", false, false
+	}
+	name = field.Name
+	tag, hasTag := field.Tag.Lookup("json")
+	if !hasTag {
+		return name, false, true
+	}
+	tagName, options, _ := strings.Cut(tag, ",")
+	if tag == "-" {
+		return "", false, false
+	}
+	if tagName != "" {
+		name = tagName
+	}
+	for _, option := range strings.Split(options, ",") {
+		if option == "omitempty" || option == "omitzero" {
+			omitEmpty = true
+		}
+	}
+	return name, omitEmpty, true
+}
@@ -0,0 +1,59 @@
+package copilot
+
+import (
+	"reflect"
+	"sync"
+)
+
+// schemaCache holds the schemas generated by schemaForType, keyed by type, so
+// that tools defined repeatedly from the same parameter type, such as a tool
+// set rebuilt for each session, pay for reflection once.
+var schemaCache sync.Map // reflect.Type -> map[string]interface{}
+
+// cachedSchema returns a copy of the cached schema for t, generating it with
+// generate if it is not cached. Errors are not cached.
+func cachedSchema(t reflect.Type, generate func() (map[string]interface{}, error)) (map[string]interface{}, error) {
+	if schema, ok := schemaCache.Load(t); ok {
+		return copySchemaMap(schema.(map[string]interface{})), nil
+	}
+	schema, err := generate()
+	if err != nil {
+		return nil, err
+	}
+	schemaCache.Store(t, schema)
+	return copySchemaMap(schema), nil
+}
+
+// resetSchemaCache drops the cached schemas, which are stale once a union they
+// may contain gains a variant.
+func resetSchemaCache() {
+	schemaCache.Range(func(key, _ interface{}) bool {
+		schemaCache.Delete(key)
+		return true
+	})
+}
+
+// copySchemaMap returns a deep copy of a schema decoded from JSON, so that
+// callers can modify the schemas they are given without affecting the cache.
+func copySchemaMap(schema map[string]interface{}) map[string]interface{} {
+	copied := make(map[string]interface{}, len(schema))
+	for key, value := range schema {
+		copied[key] = copySchemaValue(value)
+	}
+	return copied
+}
+
+func copySchemaValue(value interface{}) interface{} {
+	switch value := value.(type) {
+	case map[string]interface{}:
+		return copySchemaMap(value)
+	case []interface{}:
+		copied := make([]interface{}, len(value))
+		for i, item := range value {
+			copied[i] = copySchemaValue(item)
+		}
+		return copied
+	default:
+		return value
+	}
+}
@@ -0,0 +1,57 @@
+package copilot
+
+import (
+	"reflect"
+	"testing"
+)
+
+type schemaCacheParams struct {
+	Query   string   `json:"query" jsonschema:"Search query"`
+	Limit   int      `json:"limit,omitempty" jsonschema:"minimum=1,maximum=100"`
+	Filters []string `json:"filters,omitempty"`
+	Sort    struct {
+		Field string `json:"field" jsonschema:"enum=name|date"`
+		Desc  bool   `json:"desc,omitempty"`
+	} `json:"sort,omitempty"`
+}
+
+func TestSchemaCache(t *testing.T) {
+	typ := reflect.TypeOf(schemaCacheParams{})
+	first := generateSchemaForType(typ)
+	first["properties"].(map[string]interface{})["query"].(map[string]interface{})["description"] = "changed"
+	first["required"].([]interface{})[0] = "changed"
+
+	second := generateSchemaForType(typ)
+	if second["properties"].(map[string]interface{})["query"].(map[string]interface{})["description"] != "Search query" {
+		t.Errorf("Expected changes to a returned schema not to reach the cache, got %v", second)
+	}
+	if second["required"].([]interface{})[0] != "query" {
+		t.Errorf("Expected required to be copied, got %v", second["required"])
+	}
+
+	uncached, err := generateSchemaMap(typ)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(second, uncached) {
+		t.Errorf("Expected the cached schema to match a generated one:\n%v\n%v", second, uncached)
+	}
+}
+
+func BenchmarkDefineToolSchema(b *testing.B) {
+	typ := reflect.TypeOf(schemaCacheParams{})
+	b.Run("uncached", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			if _, err := generateSchemaMap(typ); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+	b.Run("cached", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			DefineTool("search", "Search", func(params schemaCacheParams, inv ToolInvocation) (string, error) {
+				return "", nil
+			})
+		}
+	})
+}
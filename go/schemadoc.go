@@ -0,0 +1,71 @@
+package copilot
+
+import (
+	"fmt"
+	"reflect"
+	"sync"
+)
+
+var (
+	fieldDescriptionsMu sync.RWMutex
+	fieldDescriptions   = make(map[reflect.Type]map[string]string)
+)
+
+// RegisterFieldDescriptions sets the schema descriptions of fields of the
+// struct type T, keyed by Go field name, for fields whose jsonschema tag has no
+// description of its own. It lets parameter types be documented with doc
+// comments instead of tags; the copilot-schemagen command generates the calls
+// from the comments:
+//
+//	//go:generate go run github.com/github/copilot-sdk/go/cmd/copilot-schemagen -type GetWeatherParams
+//
+//	type GetWeatherParams struct {
+//	    // City is the name of the city, such as "Paris".
+//	    City string `json:"city"`
+//	}
+//
+// Register descriptions at init time, before defining the tools that use
+// them. Later calls for T add to or replace earlier descriptions. It panics if
+// T is not a struct type or if a name is not one of its fields.
+func RegisterFieldDescriptions[T any](descriptions map[string]string) {
+	typ := reflect.TypeOf((*T)(nil)).Elem()
+	if typ.Kind() != reflect.Struct {
+		panic(fmt.Sprintf("copilot: RegisterFieldDescriptions requires a struct type, not %s", typ))
+	}
+	for name := range descriptions {
+		if field, ok := typ.FieldByName(name); !ok || len(field.Index) != 1 {
+			panic(fmt.Sprintf("copilot: %s has no field %s", typ, name))
+		}
+	}
+
+	fieldDescriptionsMu.Lock()
+	defer fieldDescriptionsMu.Unlock()
+	registered := fieldDescriptions[typ]
+	if registered == nil {
+		registered = make(map[string]string, len(descriptions))
+		fieldDescriptions[typ] = registered
+	}
+	for name, description := range descriptions {
+		registered[name] = description
+	}
+	resetSchemaCache()
+}
+
+// registeredFieldDescription returns the description registered for a field
+// of t, as returned by [reflect.VisibleFields], looking it up on the struct
+// that declares the field.
+func registeredFieldDescription(t reflect.Type, field reflect.StructField) string {
+	fieldDescriptionsMu.RLock()
+	defer fieldDescriptionsMu.RUnlock()
+	if len(fieldDescriptions) == 0 {
+		return ""
+	}
+	owner := t
+	for _, i := range field.Index[:len(field.Index)-1] {
+		owner = owner.Field(i).Type
+		if owner.Kind() == reflect.Ptr {
+			owner = owner.Elem()
+		}
+	}
+	return fieldDescriptions[owner][field.Name]
+}
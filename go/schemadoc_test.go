@@ -0,0 +1,61 @@
+package copilot
+
+import (
+	"reflect"
+	"testing"
+)
+
+type describedBase struct {
+	Owner string `json:"owner"`
+}
+
+type describedParams struct {
+	describedBase
+	City string `json:"city"`
+	Unit string `json:"unit" jsonschema:"enum=celsius|fahrenheit,Temperature unit"`
+	Days int    `json:"days" jsonschema:"optional"`
+}
+
+func TestRegisterFieldDescriptions(t *testing.T) {
+	RegisterFieldDescriptions[describedBase](map[string]string{"Owner": "Owner of the city"})
+	RegisterFieldDescriptions[describedParams](map[string]string{
+		"City": "City is the name of the city.",
+		"Unit": "Unit is ignored in favor of the tag.",
+		"Days": "Days is the number of days to forecast.",
+	})
+
+	schema, err := schemaForType(reflect.TypeOf(describedParams{}))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	props := schema["properties"].(map[string]interface{})
+	want := map[string]string{
+		"owner": "Owner of the city",
+		"city":  "City is the name of the city.",
+		"unit":  "Temperature unit",
+		"days":  "Days is the number of days to forecast.",
+	}
+	for name, description := range want {
+		if got := props[name].(map[string]interface{})["description"]; got != description {
+			t.Errorf("Expected %s to be described as %q, got %v", name, description, got)
+		}
+	}
+	if enum := props["unit"].(map[string]interface{})["enum"]; enum == nil {
+		t.Error("Expected the tag's directives to be kept")
+	}
+
+	for name, register := range map[string]func(){
+		"not a struct":   func() { RegisterFieldDescriptions[string](nil) },
+		"unknown field":  func() { RegisterFieldDescriptions[describedParams](map[string]string{"Country": "x"}) },
+		"promoted field": func() { RegisterFieldDescriptions[describedParams](map[string]string{"Owner": "x"}) },
+	} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("%s: expected a panic", name)
+				}
+			}()
+			register()
+		}()
+	}
+}
@@ -0,0 +1,124 @@
+package copilot
+
+import "time"
+
+// Event types dispatched by the SDK itself rather than the server. They are
+// delivered to [Session.On] handlers like server events and use the "sdk."
+// prefix. Their payload is carried in Data.Metadata.Variables.
+const (
+	// SDKExperimentAssigned is dispatched when an [ExperimentProvider] assigns a
+	// variant to a turn. Variables: experiment, variant, turn, and messageId.
+	SDKExperimentAssigned SessionEventType = "sdk.experiment_assigned"
+	// SDKTurnQueued is dispatched when a prompt is queued because a turn is in
+	// flight. See [TurnQueueConfig]. Variables: queueId, prompt, and position
+	// (1 for the next prompt to be sent).
+	SDKTurnQueued SessionEventType = "sdk.turn_queued"
+	// SDKMemoriesStored is dispatched when memories extracted from a turn are
+	// stored. See [MemoryConfig]. Variables: userId and memoryIds.
+	SDKMemoriesStored SessionEventType = "sdk.memories_stored"
+	// SDKToolSimulated is dispatched when a tool call is simulated in dry-run
+	// mode. See [DryRunConfig]. Variables: toolCallId and toolName.
+	SDKToolSimulated SessionEventType = "sdk.tool_simulated"
+	// SDKToolTimedOut is dispatched when a tool call exceeds its timeout and is
+	// abandoned. See [Tool.Timeout]. Variables: toolCallId, toolName, and
+	// timeoutMs.
+	SDKToolTimedOut SessionEventType = "sdk.tool_timed_out"
+	// SDKToolRetried is dispatched when a failed tool call is about to be
+	// retried. See [RetryPolicy]. Variables: toolCallId, toolName, attempt (the
+	// number of the attempt about to start), delayMs, and error.
+	SDKToolRetried SessionEventType = "sdk.tool_retried"
+	// SDKToolDenied is dispatched when a call of a tool that requires approval
+	// is not approved. See [Tool.RequiresApproval]. Variables: toolCallId,
+	// toolName, and reason.
+	SDKToolDenied SessionEventType = "sdk.tool_denied"
+	// SDKToolCostBudgetExceeded is dispatched when a call is not run because
+	// its cost would exceed a budget. See [CostBudget]. Variables: toolCallId,
+	// toolName, scope ("tool" or "session"), period ("turn" or "session"),
+	// unit, limit, spent, and cost (the call's cost in unit).
+	SDKToolCostBudgetExceeded SessionEventType = "sdk.tool_cost_budget_exceeded"
+	// SDKToolResultPresented is dispatched when a tool's post-processors
+	// describe how to present its result. See [Tool.PostProcessors] and
+	// [ToolResultPresentation]. Variables: toolCallId, toolName, language,
+	// preview, and collapsible.
+	SDKToolResultPresented SessionEventType = "sdk.tool_result_presented"
+	// SDKToolResultDisplayed is dispatched when a tool returns a result with
+	// [ToolResult.DisplayData] or a MimeType. See [ToolResultDisplay].
+	// Variables: toolCallId, toolName, mimeType, and displayData.
+	SDKToolResultDisplayed SessionEventType = "sdk.tool_result_displayed"
+	// SDKToolCallDeferred is dispatched when a tool handler defers its call
+	// with [ToolInvocation.PendingResult]. Variables: toolCallId, toolName, and
+	// pendingId.
+	SDKToolCallDeferred SessionEventType = "sdk.tool_call_deferred"
+	// SDKToolResultRedacted is dispatched when a [ResultSanitizer] redacts a
+	// tool result. Variables: toolCallId, toolName, patterns (the names of the
+	// patterns that matched), and redactions (the matches by pattern name).
+	SDKToolResultRedacted SessionEventType = "sdk.tool_result_redacted"
+	// SDKToolNotification is dispatched for each notification a tool handler
+	// emitted with [ToolInvocation.Notify], once the call succeeds. See
+	// [ToolNotificationOf]. Variables: toolCallId, toolName, kind, and data.
+	SDKToolNotification SessionEventType = "sdk.tool_notification"
+	// SDKSessionNested is dispatched on a session when a session nested in it is
+	// created. See [SessionConfig.ParentSessionID]. Variables: sessionId,
+	// parentSessionId, depth, and ancestry (session IDs, root first).
+	SDKSessionNested SessionEventType = "sdk.session_nested"
+	// SDKMaxDepthExceeded is dispatched on a session when creating a session
+	// nested in it fails with a [*MaxDepthError]. Variables: parentSessionId,
+	// depth, maxDepth, and ancestry.
+	SDKMaxDepthExceeded SessionEventType = "sdk.max_depth_exceeded"
+	// SDKTurnAborted is dispatched when a turn is aborted, by this client or by
+	// the server. See [CancelCause] and [TurnAbortCause]. Variables: reason and
+	// message.
+	SDKTurnAborted SessionEventType = "sdk.turn_aborted"
+	// SDKPromptModerated is dispatched when a [PreSendModerator] allows,
+	// rewrites, or blocks a prompt. See [PromptModeration]. Variables: action,
+	// reason, labels, prompt (for rewrites), and messageId (empty for blocked
+	// prompts).
+	SDKPromptModerated SessionEventType = "sdk.prompt_moderated"
+	// SDKTurnTruncated is dispatched when [Session.SendAndCollect] or
+	// [Session.SendAndWait] stops waiting for a turn at its timeout. See
+	// [PartialResultError]. Variables: messageId, lastEventId and eventCount
+	// (the last event collected and how many were), content (the partial
+	// text), and timeoutMs.
+	SDKTurnTruncated SessionEventType = "sdk.turn_truncated"
+	// SDKStreamingDowngraded is dispatched once on a session that requested
+	// streaming when the server sends a message without streaming it. The
+	// message, and every later unstreamed one, is delivered as a single delta
+	// followed by its final event. See [Session.StreamingDowngraded].
+	// Variables: eventType and eventId (of the first unstreamed message).
+	SDKStreamingDowngraded SessionEventType = "sdk.streaming_downgraded"
+	// SDKToolDeprecated is dispatched when the model calls a deprecated tool.
+	// See [Tool.Deprecated].
+	// Variables: toolCallId, toolName, replacedBy, and message.
+	SDKToolDeprecated SessionEventType = "sdk.tool_deprecated"
+	// SDKModelFallback is dispatched when a turn is sent again with the next
+	// model of the session's fallback chain. See [ModelFallbackConfig].
+	// Variables: fromModel, toModel, attempt (the 1-based position in the
+	// chain), and error (the failure that caused the fallback).
+	SDKModelFallback SessionEventType = "sdk.model_fallback"
+)
+
+// newSDKEvent creates an SDK-originated event with the given payload.
+func newSDKEvent(eventType SessionEventType, variables map[string]interface{}) SessionEvent {
+	ephemeral := true
+	return SessionEvent{
+		ID:        generateUUID(),
+		Type:      eventType,
+		Timestamp: time.Now(),
+		Ephemeral: &ephemeral,
+		Data:      Data{Metadata: &Metadata{Variables: variables}},
+	}
+}
+
+// emit dispatches an SDK-originated event to this session's handlers.
+func (s *Session) emit(eventType SessionEventType, variables map[string]interface{}) {
+	s.dispatchEvent(newSDKEvent(eventType, variables))
+}
+
+// sdkEventString returns a string variable from an SDK event's payload.
+func sdkEventString(event SessionEvent, key string) string {
+	if event.Data.Metadata == nil {
+		return ""
+	}
+	value, _ := event.Data.Metadata.Variables[key].(string)
+	return value
+}
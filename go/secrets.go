@@ -0,0 +1,127 @@
+package copilot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// SecretProvider supplies the secrets tool handlers need, such as API keys, so
+// that handlers fetch them the same way wherever they are stored. Set it as
+// [ClientOptions.Secrets] and read secrets with [ToolInvocation.Secret].
+// Implementations must be safe for concurrent use, and should return an error
+// wrapping [ErrSecretNotFound] for secrets they do not have.
+type SecretProvider interface {
+	Get(ctx context.Context, name string) (string, error)
+}
+
+// ErrSecretNotFound is returned for a secret a [SecretProvider] does not have.
+var ErrSecretNotFound = errors.New("secret not found")
+
+// EnvSecretProvider reads secrets from environment variables. The variable of
+// a secret is its name with Prefix prepended, so with Prefix "MYAPP_" the
+// secret "GITHUB_TOKEN" is read from MYAPP_GITHUB_TOKEN. An unset or empty
+// variable is not found.
+type EnvSecretProvider struct {
+	Prefix string
+}
+
+// Get returns the value of the secret's environment variable.
+func (p EnvSecretProvider) Get(ctx context.Context, name string) (string, error) {
+	value, ok := os.LookupEnv(p.Prefix + name)
+	if !ok || value == "" {
+		return "", fmt.Errorf("%w: environment variable %s is not set", ErrSecretNotFound, p.Prefix+name)
+	}
+	return value, nil
+}
+
+// FileSecretProvider reads each secret from a file of the same name in Dir,
+// as mounted by Kubernetes and Docker secrets. Trailing newlines are removed.
+// Names that are not plain file names are rejected, so that secrets cannot be
+// read from outside Dir.
+type FileSecretProvider struct {
+	Dir string
+}
+
+// Get returns the contents of the secret's file.
+func (p FileSecretProvider) Get(ctx context.Context, name string) (string, error) {
+	if name == "" || name != filepath.Base(name) || name == "." || name == ".." {
+		return "", fmt.Errorf("invalid secret name %q", name)
+	}
+	data, err := os.ReadFile(filepath.Join(p.Dir, name))
+	if errors.Is(err, os.ErrNotExist) {
+		return "", fmt.Errorf("%w: no file %s in %s", ErrSecretNotFound, name, p.Dir)
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to read secret %s: %w", name, err)
+	}
+	return strings.TrimRight(string(data), "\r\n"), nil
+}
+
+// SecretAccess records a tool call reading a secret, for audit logs. It never
+// holds the secret's value.
+type SecretAccess struct {
+	SessionID  string
+	ToolCallID string
+	ToolName   string
+	// Name is the name of the secret.
+	Name string
+	// Time is when the secret was read.
+	Time time.Time
+	// Err is why the secret could not be read, if it could not.
+	Err error
+}
+
+// secretSource is the provider of a tool invocation's secrets and the audit
+// log of its reads.
+type secretSource struct {
+	provider SecretProvider
+	onAccess func(SecretAccess)
+}
+
+// Secret returns the secret name from the client's [ClientOptions.Secrets],
+// and reports the read to [ClientOptions.OnSecretAccess]. It fails if the
+// client has no SecretProvider.
+//
+// Example:
+//
+//	token, err := inv.Secret("GITHUB_TOKEN")
+//	if err != nil {
+//	    return nil, err
+//	}
+//	req.Header.Set("Authorization", "Bearer "+token)
+func (inv ToolInvocation) Secret(name string) (string, error) {
+	if inv.secrets == nil || inv.secrets.provider == nil {
+		return "", fmt.Errorf("failed to get secret %s: no SecretProvider is configured", name)
+	}
+	value, err := inv.secrets.provider.Get(inv.Context(), name)
+	if err != nil {
+		err = fmt.Errorf("failed to get secret %s: %w", name, err)
+	}
+	if inv.secrets.onAccess != nil {
+		inv.secrets.onAccess(SecretAccess{
+			SessionID:  inv.SessionID,
+			ToolCallID: inv.ToolCallID,
+			ToolName:   inv.ToolName,
+			Name:       name,
+			Time:       time.Now(),
+			Err:        err,
+		})
+	}
+	if err != nil {
+		return "", err
+	}
+	return value, nil
+}
+
+// WithSecrets returns a copy of the invocation that reads its secrets from
+// provider, for example to call a handler directly in a test. Reads are not
+// reported to the client's OnSecretAccess.
+func (inv ToolInvocation) WithSecrets(provider SecretProvider) ToolInvocation {
+	inv.secrets = &secretSource{provider: provider}
+	return inv
+}
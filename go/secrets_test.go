@@ -0,0 +1,111 @@
+package copilot
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+)
+
+func TestSecrets(t *testing.T) {
+	t.Run("reads secrets from the client's provider and audits reads", func(t *testing.T) {
+		session := NewSession("s1", nil, "")
+		var mu sync.Mutex
+		var accesses []SecretAccess
+		client := &Client{
+			sessions: map[string]*Session{"s1": session},
+			options: ClientOptions{
+				Secrets: FileSecretProvider{Dir: writeSecrets(t, map[string]string{"API_KEY": "s3cret\n"})},
+				OnSecretAccess: func(access SecretAccess) {
+					mu.Lock()
+					defer mu.Unlock()
+					accesses = append(accesses, access)
+				},
+			},
+		}
+
+		var got string
+		handler := func(inv ToolInvocation) (ToolResult, error) {
+			value, err := inv.Secret("API_KEY")
+			if err != nil {
+				return ToolResult{}, err
+			}
+			got = value
+			if _, err := inv.Secret("MISSING"); !errors.Is(err, ErrSecretNotFound) {
+				t.Errorf("Expected ErrSecretNotFound, got %v", err)
+			}
+			return ToolResult{ResultType: "success"}, nil
+		}
+		client.executeToolCall(session.toolContext(), "s1", "call-1", "fetch", nil, handler)
+
+		if got != "s3cret" {
+			t.Errorf("Expected secret without trailing newline, got %q", got)
+		}
+		if len(accesses) != 2 {
+			t.Fatalf("Expected 2 accesses, got %d", len(accesses))
+		}
+		first := accesses[0]
+		if first.Name != "API_KEY" || first.ToolName != "fetch" || first.ToolCallID != "call-1" || first.SessionID != "s1" || first.Err != nil {
+			t.Errorf("Unexpected access record: %+v", first)
+		}
+		if accesses[1].Name != "MISSING" || !errors.Is(accesses[1].Err, ErrSecretNotFound) {
+			t.Errorf("Expected failed access to be recorded, got %+v", accesses[1])
+		}
+	})
+
+	t.Run("fails without a provider", func(t *testing.T) {
+		if _, err := (ToolInvocation{}).Secret("API_KEY"); err == nil {
+			t.Error("Expected error without a SecretProvider")
+		}
+	})
+
+	t.Run("uses a provider set on the invocation", func(t *testing.T) {
+		t.Setenv("TEST_APP_TOKEN", "from-env")
+		inv := ToolInvocation{}.WithSecrets(EnvSecretProvider{Prefix: "TEST_APP_"})
+		value, err := inv.Secret("TOKEN")
+		if err != nil || value != "from-env" {
+			t.Errorf("Expected secret from environment, got %q, %v", value, err)
+		}
+	})
+}
+
+func TestEnvSecretProvider(t *testing.T) {
+	t.Setenv("SECRET_TEST_EMPTY", "")
+	provider := EnvSecretProvider{Prefix: "SECRET_TEST_"}
+	for _, name := range []string{"EMPTY", "UNSET"} {
+		if _, err := provider.Get(context.Background(), name); !errors.Is(err, ErrSecretNotFound) {
+			t.Errorf("Expected ErrSecretNotFound for %s, got %v", name, err)
+		}
+	}
+}
+
+func TestFileSecretProvider(t *testing.T) {
+	dir := writeSecrets(t, map[string]string{"token": "abc\r\n"})
+	provider := FileSecretProvider{Dir: dir}
+
+	value, err := provider.Get(context.Background(), "token")
+	if err != nil || value != "abc" {
+		t.Errorf("Expected trimmed secret, got %q, %v", value, err)
+	}
+	if _, err := provider.Get(context.Background(), "missing"); !errors.Is(err, ErrSecretNotFound) {
+		t.Errorf("Expected ErrSecretNotFound, got %v", err)
+	}
+	for _, name := range []string{"", "..", "../token", "sub/token"} {
+		if _, err := provider.Get(context.Background(), name); err == nil || errors.Is(err, ErrSecretNotFound) {
+			t.Errorf("Expected invalid name error for %q, got %v", name, err)
+		}
+	}
+}
+
+func writeSecrets(t *testing.T, secrets map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, value := range secrets {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(value), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
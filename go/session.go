@@ -2,15 +2,20 @@
 package copilot
 
 import (
-	"encoding/json"
 	"fmt"
 	"sync"
 	"time"
 )
 
 type sessionHandler struct {
-	id uint64
-	fn SessionEventHandler
+	id     uint64
+	fn     SessionEventHandler
+	filter *EventFilter
+	// sequenced, if set, is called instead of fn with the event's cursor.
+	sequenced func(SequencedEvent)
+	// sized, if set, is called instead of fn with the size of the event as
+	// received, or zero for events the SDK dispatched itself.
+	sized func(event SessionEvent, size int)
 }
 
 // Session represents a single conversation session with the Copilot CLI.
@@ -46,13 +51,40 @@ type sessionHandler struct {
 //	})
 type Session struct {
 	// SessionID is the unique identifier for this session.
-	SessionID         string
-	workspacePath     string
-	client            *JSONRPCClient
-	handlers          []sessionHandler
-	nextHandlerID     uint64
-	handlerMutex      sync.RWMutex
-	toolHandlers      map[string]ToolHandler
+	SessionID        string
+	workspacePath    string
+	client           *JSONRPCClient
+	handlers         []sessionHandler
+	nextHandlerID    uint64
+	handlerMutex     sync.RWMutex
+	toolHandlers     map[string]ToolHandler
+	baseTools        []Tool
+	registryMux      sync.Mutex // serializes ToolRegistry updates
+	registryVersion  uint64     // last ToolRegistry change applied
+	registryTools    []Tool     // ToolRegistry tools as of registryVersion
+	toolFilter       func(Tool) bool
+	toolMiddleware   ToolMiddleware
+	resultLimit      *ResultLimit
+	resultSanitizer  *ResultSanitizer
+	costBudget       *CostBudget
+	advertiseLimits  toolLimitPolicy
+	costs            costLedger
+	streamFallback   streamFallback
+	toolPanicHandler ToolPanicHandler
+	approvalHandler  ToolApprovalHandler
+	// invocationLimit bounds the tool calls running at once across the
+	// session's tools, or is nil if they are unbounded.
+	invocationLimit *invocationLimiter
+	// toolLimits holds the per-tool limiters by tool name, kept across tool
+	// updates so that calls already running still count.
+	toolLimits map[string]*invocationLimiter
+	// mutexGroups holds the limiters of [Tool.MutexGroups] by group name,
+	// kept across tool updates like toolLimits.
+	mutexGroups       map[string]*invocationLimiter
+	toolKV            *ToolKV
+	toolKVOnce        sync.Once
+	pendingCalls      pendingToolCalls
+	toolCatalogSize   ToolCatalogSize
 	toolHandlersM     sync.RWMutex
 	permissionHandler PermissionHandler
 	permissionMux     sync.RWMutex
@@ -60,6 +92,34 @@ type Session struct {
 	userInputMux      sync.RWMutex
 	hooks             *SessionHooks
 	hooksMux          sync.RWMutex
+	toolCallNames     toolCallNames
+	toolCallNamesMux  sync.Mutex
+	skills            sessionSkills
+	skillsMux         sync.RWMutex
+	citations         map[string][]Citation
+	citationOrder     []string
+	citationsMux      sync.Mutex
+	experiment        experimentState
+	experimentMux     sync.Mutex
+	moderation        *ModerationConfig
+	moderationMux     sync.RWMutex
+	modelFallback     modelFallbackState
+	modelFallbackMux  sync.RWMutex
+	turns             turnQueue
+	memory            memoryState
+	memoryMux         sync.Mutex
+	destroyHooks      []func()
+	destroyMux        sync.Mutex
+	tools             toolContexts
+	dryRun            dryRunState
+	dryRunMux         sync.Mutex
+	nesting           nesting
+	eventLog          eventLog
+	eventLogMux       sync.Mutex
+	// pendingAbort is the cause of an abort requested by this client, until
+	// the server reports it.
+	pendingAbort *CancelCause
+	abortMux     sync.Mutex
 }
 
 // WorkspacePath returns the path to the session workspace directory when infinite
@@ -94,6 +154,12 @@ func NewSession(sessionID string, client *JSONRPCClient, workspacePath string) *
 // Returns the message ID of the response, which can be used to correlate events,
 // or an error if the session has been destroyed or the connection fails.
 //
+// If the session was created with a [TurnQueueConfig] and a turn is in flight,
+// Send blocks until the prompt leaves the queue; see [TurnQueueConfig].
+//
+// If the session was created with a [ModerationConfig], the prompt is moderated
+// first, and a blocked prompt fails with a [*PromptBlockedError].
+//
 // Example:
 //
 //	messageID, err := session.Send(copilot.MessageOptions{
@@ -106,6 +172,23 @@ func NewSession(sessionID string, client *JSONRPCClient, workspacePath string) *
 //	    log.Printf("Failed to send message: %v", err)
 //	}
 func (s *Session) Send(options MessageOptions) (string, error) {
+	return s.send(options, nil, nil, nil)
+}
+
+// send sends a message. If the session has a turn queue, it waits for the turn
+// in flight to finish first. onStart, if set, is called once the message is no
+// longer queued, just before it is sent. attempt, if set, receives the message
+// and the model it was sent to, so the turn can fall back to another model
+// later. cancel, if set, cancels the message when closed while it is queued.
+func (s *Session) send(options MessageOptions, onStart func(), attempt *modelFallbackAttempt, cancel <-chan struct{}) (string, error) {
+	moderation, err := s.moderatePrompt(options)
+	if err != nil {
+		return "", err
+	}
+	if moderation != nil {
+		options.Prompt = moderation.Prompt
+	}
+
 	params := map[string]interface{}{
 		"sessionId": s.SessionID,
 		"prompt":    options.Prompt,
@@ -117,17 +200,59 @@ func (s *Session) Send(options MessageOptions) (string, error) {
 	if options.Mode != "" {
 		params["mode"] = options.Mode
 	}
+	idempotencyKey := options.IdempotencyKey
+	if idempotencyKey == "" {
+		idempotencyKey = NewIdempotencyKey()
+	}
+	params["idempotencyKey"] = idempotencyKey
+	if err := applyResponseFormat(params, options.ResponseFormat); err != nil {
+		return "", err
+	}
+
+	if err := s.acquireTurn(options.Prompt, cancel); err != nil {
+		return "", err
+	}
+	s.costs.startTurn()
+	if onStart != nil {
+		onStart()
+	}
+	s.recallMemories(options.Prompt, params)
+	s.reportSkillChanges(params)
 
-	result, err := s.client.Request("session.send", params)
+	assignment, turn, err := s.assignExperiment(options.Prompt, params)
 	if err != nil {
-		return "", fmt.Errorf("failed to send message: %w", err)
+		s.finishTurn()
+		return "", err
+	}
+
+	if attempt == nil {
+		attempt = &modelFallbackAttempt{}
+	}
+	attempt.params = params
+	result, err := s.requestTurn(attempt)
+	if err != nil {
+		s.finishTurn()
+		return "", &SendError{IdempotencyKey: attempt.idempotencyKey(), Err: err}
 	}
 
 	messageID, ok := result["messageId"].(string)
 	if !ok {
+		s.finishTurn()
 		return "", fmt.Errorf("invalid response: missing messageId")
 	}
 
+	if moderation != nil {
+		s.emitPromptModerated(moderation, messageID)
+	}
+	if assignment != nil {
+		s.emit(SDKExperimentAssigned, map[string]interface{}{
+			"experiment": assignment.Experiment,
+			"variant":    assignment.Variant,
+			"turn":       turn,
+			"messageId":  messageID,
+		})
+	}
+
 	return messageID, nil
 }
 
@@ -145,7 +270,9 @@ func (s *Session) Send(options MessageOptions) (string, error) {
 //     Controls how long to wait; does not abort in-flight agent work.
 //
 // Returns the final assistant message event, or nil if none was received.
-// Returns an error if the timeout is reached or the connection fails.
+// Returns an error if the timeout is reached or the connection fails. On
+// timeout the error is a [*PartialResultError] holding the text generated
+// before the cutoff.
 //
 // Example:
 //
@@ -159,56 +286,11 @@ func (s *Session) Send(options MessageOptions) (string, error) {
 //	    fmt.Println(*response.Data.Content)
 //	}
 func (s *Session) SendAndWait(options MessageOptions, timeout time.Duration) (*SessionEvent, error) {
-	if timeout == 0 {
-		timeout = 60 * time.Second
-	}
-
-	idleCh := make(chan struct{}, 1)
-	errCh := make(chan error, 1)
-	var lastAssistantMessage *SessionEvent
-	var mu sync.Mutex
-
-	unsubscribe := s.On(func(event SessionEvent) {
-		switch event.Type {
-		case AssistantMessage:
-			mu.Lock()
-			eventCopy := event
-			lastAssistantMessage = &eventCopy
-			mu.Unlock()
-		case SessionIdle:
-			select {
-			case idleCh <- struct{}{}:
-			default:
-			}
-		case SessionError:
-			errMsg := "session error"
-			if event.Data.Message != nil {
-				errMsg = *event.Data.Message
-			}
-			select {
-			case errCh <- fmt.Errorf("session error: %s", errMsg):
-			default:
-			}
-		}
-	})
-	defer unsubscribe()
-
-	_, err := s.Send(options)
+	turn, err := s.SendAndCollect(options, timeout)
 	if err != nil {
 		return nil, err
 	}
-
-	select {
-	case <-idleCh:
-		mu.Lock()
-		result := lastAssistantMessage
-		mu.Unlock()
-		return result, nil
-	case err := <-errCh:
-		return nil, err
-	case <-time.After(timeout):
-		return nil, fmt.Errorf("timeout after %v waiting for session.idle", timeout)
-	}
+	return turn.FinalMessage, nil
 }
 
 // On subscribes to events from this session.
@@ -234,12 +316,48 @@ func (s *Session) SendAndWait(options MessageOptions, timeout time.Duration) (*S
 //	// Later, to stop receiving events:
 //	unsubscribe()
 func (s *Session) On(handler SessionEventHandler) func() {
+	return s.subscribe(handler, nil)
+}
+
+// OnFiltered subscribes to events from this session that match the given filter.
+//
+// The filter is evaluated on the dispatching goroutine before the handler is
+// invoked, so handlers are never woken for events they are not interested in.
+// Otherwise it behaves like [Session.On].
+//
+// Example:
+//
+//	// Only tool events for git tools, without streaming deltas
+//	unsubscribe := session.OnFiltered(copilot.EventFilter{
+//	    ToolNames:     []string{"git_*"},
+//	    ExcludeDeltas: true,
+//	}, func(event copilot.SessionEvent) {
+//	    fmt.Println("Git tool event:", event.Type)
+//	})
+//	defer unsubscribe()
+func (s *Session) OnFiltered(filter EventFilter, handler SessionEventHandler) func() {
+	return s.subscribe(handler, &filter)
+}
+
+// subscribe registers a handler with an optional filter and returns its unsubscribe function.
+func (s *Session) subscribe(handler SessionEventHandler, filter *EventFilter) func() {
+	return s.addHandler(sessionHandler{fn: handler, filter: filter})
+}
+
+// subscribeSequenced registers a handler of sequenced events and returns its
+// unsubscribe function.
+func (s *Session) subscribeSequenced(handler func(SequencedEvent)) func() {
+	return s.addHandler(sessionHandler{sequenced: handler})
+}
+
+func (s *Session) addHandler(h sessionHandler) func() {
 	s.handlerMutex.Lock()
 	defer s.handlerMutex.Unlock()
 
 	id := s.nextHandlerID
 	s.nextHandlerID++
-	s.handlers = append(s.handlers, sessionHandler{id: id, fn: handler})
+	h.id = id
+	s.handlers = append(s.handlers, h)
 
 	// Return unsubscribe function
 	return func() {
@@ -270,8 +388,9 @@ func (s *Session) registerTools(tools []Tool) {
 		if tool.Name == "" || tool.Handler == nil {
 			continue
 		}
-		s.toolHandlers[tool.Name] = tool.Handler
+		s.toolHandlers[tool.Name] = s.wrapToolHandler(tool)
 	}
+	s.registerDeprecatedTools(tools)
 }
 
 // getToolHandler retrieves a registered tool handler by name.
@@ -553,12 +672,28 @@ func parseErrorOccurredInput(input map[string]interface{}) ErrorOccurredHookInpu
 // This is an internal method; handlers are called synchronously and any panics
 // are recovered to prevent crashing the event dispatcher.
 func (s *Session) dispatchEvent(event SessionEvent) {
+	s.dispatchSizedEvent(event, 0)
+}
+
+// dispatchSizedEvent dispatches an event received from the server as size
+// bytes of JSON.
+func (s *Session) dispatchSizedEvent(event SessionEvent, size int) {
+	toolName := s.trackToolCallName(event)
+
+	// Number the event and take the handlers together, so that a subscription
+	// resuming from a cursor either replays the event or receives it live.
+	s.eventLogMux.Lock()
+	sequenced := s.eventLog.append(event)
 	s.handlerMutex.RLock()
-	handlers := make([]SessionEventHandler, 0, len(s.handlers))
+	handlers := make([]sessionHandler, 0, len(s.handlers))
 	for _, h := range s.handlers {
-		handlers = append(handlers, h.fn)
+		if h.filter != nil && !h.filter.matches(event, toolName) {
+			continue
+		}
+		handlers = append(handlers, h)
 	}
 	s.handlerMutex.RUnlock()
+	s.eventLogMux.Unlock()
 
 	for _, handler := range handlers {
 		// Call handler - don't let panics crash the dispatcher
@@ -568,9 +703,78 @@ func (s *Session) dispatchEvent(event SessionEvent) {
 					fmt.Printf("Error in session event handler: %v\n", r)
 				}
 			}()
-			handler(event)
+			if handler.sequenced != nil {
+				handler.sequenced(sequenced)
+				return
+			}
+			if handler.sized != nil {
+				handler.sized(event, size)
+				return
+			}
+			handler.fn(event)
 		}()
 	}
+
+	switch event.Type {
+	case SessionIdle, SessionError, UserMessage:
+		s.trackTurnEnd(event)
+	case Abort:
+		s.handleAbortEvent()
+	}
+}
+
+// trackToolCallName returns the name of the tool an event refers to, or empty if
+// the event is not tool-related.
+func (s *Session) trackToolCallName(event SessionEvent) string {
+	s.toolCallNamesMux.Lock()
+	defer s.toolCallNamesMux.Unlock()
+
+	if s.toolCallNames == nil {
+		s.toolCallNames = make(toolCallNames)
+	}
+	return s.toolCallNames.track(event)
+}
+
+// toolCallNames remembers tool names by tool call ID. Not every tool event
+// carries the tool name, so names announced by tool requests and execution
+// starts are remembered until the execution completes.
+type toolCallNames map[string]string
+
+// track records the tool names announced by event and returns the name of the
+// tool the event refers to, or empty if the event is not tool-related. Names
+// are forgotten when their call completes or the turn ends.
+func (n toolCallNames) track(event SessionEvent) string {
+	for _, req := range event.Data.ToolRequests {
+		if req.ToolCallID != "" && req.Name != "" {
+			n[req.ToolCallID] = req.Name
+		}
+	}
+
+	var toolCallID string
+	if event.Data.ToolCallID != nil {
+		toolCallID = *event.Data.ToolCallID
+	}
+
+	name := ""
+	if event.Data.ToolName != nil {
+		name = *event.Data.ToolName
+		if toolCallID != "" {
+			n[toolCallID] = name
+		}
+	} else if toolCallID != "" {
+		name = n[toolCallID]
+	}
+
+	switch {
+	case event.Type == ToolExecutionComplete && toolCallID != "":
+		delete(n, toolCallID)
+	case event.Type == SessionIdle || event.Type == Abort:
+		// Calls that were aborted, denied, or never executed have no
+		// completion event, and none are pending once the turn is over.
+		clear(n)
+	}
+
+	return name
 }
 
 // GetMessages retrieves all events and messages from this session's history.
@@ -594,38 +798,11 @@ func (s *Session) dispatchEvent(event SessionEvent) {
 //	    }
 //	}
 func (s *Session) GetMessages() ([]SessionEvent, error) {
-	params := map[string]interface{}{
-		"sessionId": s.SessionID,
-	}
-
-	result, err := s.client.Request("session.getMessages", params)
+	history, err := s.History()
 	if err != nil {
-		return nil, fmt.Errorf("failed to get messages: %w", err)
-	}
-
-	eventsRaw, ok := result["events"].([]interface{})
-	if !ok {
-		return nil, fmt.Errorf("invalid response: missing events")
-	}
-
-	// Convert to SessionEvent structs
-	events := make([]SessionEvent, 0, len(eventsRaw))
-	for _, eventRaw := range eventsRaw {
-		// Marshal back to JSON and unmarshal into typed struct
-		eventJSON, err := json.Marshal(eventRaw)
-		if err != nil {
-			continue
-		}
-
-		event, err := UnmarshalSessionEvent(eventJSON)
-		if err != nil {
-			continue
-		}
-
-		events = append(events, event)
+		return nil, err
 	}
-
-	return events, nil
+	return history.Events, nil
 }
 
 // Destroy destroys this session and releases all associated resources.
@@ -643,6 +820,12 @@ func (s *Session) GetMessages() ([]SessionEvent, error) {
 //	    log.Printf("Failed to destroy session: %v", err)
 //	}
 func (s *Session) Destroy() error {
+	s.cancelTools(ErrSessionDestroyed, true)
+	s.failPendingToolCalls(ErrSessionDestroyed)
+	s.runDestroyHooks()
+	// Release queued prompts even if the server fails to destroy the session.
+	defer s.ClearTurnQueue()
+
 	params := map[string]interface{}{
 		"sessionId": s.SessionID,
 	}
@@ -668,10 +851,31 @@ func (s *Session) Destroy() error {
 	return nil
 }
 
+// onDestroy registers fn to run when the session is destroyed, before the server
+// is told to destroy it.
+func (s *Session) onDestroy(fn func()) {
+	s.destroyMux.Lock()
+	defer s.destroyMux.Unlock()
+	s.destroyHooks = append(s.destroyHooks, fn)
+}
+
+func (s *Session) runDestroyHooks() {
+	s.destroyMux.Lock()
+	hooks := s.destroyHooks
+	s.destroyHooks = nil
+	s.destroyMux.Unlock()
+
+	for _, hook := range hooks {
+		hook()
+	}
+}
+
 // Abort aborts the currently processing message in this session.
 //
 // Use this to cancel a long-running request. The session remains valid
-// and can continue to be used for new messages.
+// and can continue to be used for new messages. Tool calls in flight are
+// canceled with a [*CancelCause] of reason [CancelUserInterrupt]; use
+// [Session.AbortWithCause] to give another reason.
 //
 // Returns an error if the session has been destroyed or the connection fails.
 //
@@ -690,14 +894,5 @@ func (s *Session) Destroy() error {
 //	    log.Printf("Failed to abort: %v", err)
 //	}
 func (s *Session) Abort() error {
-	params := map[string]interface{}{
-		"sessionId": s.SessionID,
-	}
-
-	_, err := s.client.Request("session.abort", params)
-	if err != nil {
-		return fmt.Errorf("failed to abort session: %w", err)
-	}
-
-	return nil
+	return s.AbortWithCause(&CancelCause{Reason: CancelUserInterrupt})
 }
@@ -0,0 +1,316 @@
+package copilot
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Skill bundles a group of related tools with the instructions and examples that
+// tell the model when and how to use them, so a capability pack (for example
+// "GitHub management") can be shipped and toggled as a single unit.
+//
+// When a session is created with skills, each enabled skill's tools are
+// registered alongside [SessionConfig.Tools] and its description, instructions,
+// and examples are appended to the system message. Skills listed in
+// [SessionConfig.DisabledSkills] are left out entirely. Skills can also be
+// toggled on a live session with [Session.DisableSkill] and [Session.EnableSkill],
+// which update the session's tools on the server and tell the model about the
+// change with the next message.
+//
+// Example:
+//
+//	github := copilot.Skill{
+//	    Name:         "github",
+//	    Description:  "Use when the user asks about issues or pull requests.",
+//	    Instructions: "Always link to the issue you are talking about.",
+//	    Examples:     []string{"Summarize my open pull requests"},
+//	    Tools:        []copilot.Tool{listIssues, getPullRequest},
+//	}
+//
+//	session, err := client.CreateSession(&copilot.SessionConfig{
+//	    Skills: []copilot.Skill{github},
+//	})
+type Skill struct {
+	// Name uniquely identifies the skill within a session.
+	Name string
+	// Description explains when the skill applies. It is shown to the model as
+	// the trigger for using the skill's tools.
+	Description string
+	// Instructions are additional guidance for the model when using the skill.
+	Instructions string
+	// Examples are sample user requests the skill is meant to handle.
+	Examples []string
+	// Tools are the tools the skill provides.
+	Tools []Tool
+}
+
+// skillState tracks a skill registered on a session.
+type skillState struct {
+	skill   Skill
+	enabled bool
+}
+
+// sessionSkills holds the skills of a session and the rest of its own tools,
+// from which its tools are rebuilt when a skill is toggled.
+type sessionSkills struct {
+	skills    []*skillState
+	tools     []Tool
+	resources *ResourceCatalog
+	// changed holds the toggles not yet reported to the model, by skill name.
+	changed map[string]bool
+}
+
+// baseTools returns the session's own tools with the enabled skills' tools.
+func (s *sessionSkills) baseTools() []Tool {
+	return withResourceTools(skillTools(s.tools, s.enabled()), s.resources)
+}
+
+func (s *sessionSkills) enabled() []Skill {
+	var result []Skill
+	for _, state := range s.skills {
+		if state.enabled {
+			result = append(result, state.skill)
+		}
+	}
+	return result
+}
+
+func (s *sessionSkills) find(name string) *skillState {
+	for _, state := range s.skills {
+		if state.skill.Name == name {
+			return state
+		}
+	}
+	return nil
+}
+
+// enabledSkills returns the skills whose names are not in disabled.
+func enabledSkills(skills []Skill, disabled []string) []Skill {
+	if len(skills) == 0 {
+		return nil
+	}
+	disabledSet := make(map[string]bool, len(disabled))
+	for _, name := range disabled {
+		disabledSet[name] = true
+	}
+	result := make([]Skill, 0, len(skills))
+	for _, skill := range skills {
+		if !disabledSet[skill.Name] {
+			result = append(result, skill)
+		}
+	}
+	return result
+}
+
+// skillTools returns tools followed by every tool provided by skills.
+func skillTools(tools []Tool, skills []Skill) []Tool {
+	if len(skills) == 0 {
+		return tools
+	}
+	result := make([]Tool, 0, len(tools))
+	result = append(result, tools...)
+	for _, skill := range skills {
+		result = append(result, skill.Tools...)
+	}
+	return result
+}
+
+// renderSkillInstructions renders the system message section describing skills.
+func renderSkillInstructions(skills []Skill) string {
+	if len(skills) == 0 {
+		return ""
+	}
+	var b strings.Builder
+	b.WriteString("The following skills are available in this session. Use a skill's tools when its description applies.\n")
+	for _, skill := range skills {
+		fmt.Fprintf(&b, "\n## Skill: %s\n", skill.Name)
+		if skill.Description != "" {
+			fmt.Fprintf(&b, "When to use: %s\n", skill.Description)
+		}
+		if len(skill.Tools) > 0 {
+			names := make([]string, 0, len(skill.Tools))
+			for _, tool := range skill.Tools {
+				names = append(names, tool.Name)
+			}
+			fmt.Fprintf(&b, "Tools: %s\n", strings.Join(names, ", "))
+		}
+		if skill.Instructions != "" {
+			fmt.Fprintf(&b, "%s\n", strings.TrimSpace(skill.Instructions))
+		}
+		if len(skill.Examples) > 0 {
+			b.WriteString("Examples:\n")
+			for _, example := range skill.Examples {
+				fmt.Fprintf(&b, "- %s\n", example)
+			}
+		}
+	}
+	return b.String()
+}
+
+// withSkillInstructions returns a system message config with the skill
+// instructions appended to its content.
+func withSkillInstructions(systemMessage *SystemMessageConfig, skills []Skill) *SystemMessageConfig {
+	instructions := renderSkillInstructions(skills)
+	if instructions == "" {
+		return systemMessage
+	}
+	if systemMessage == nil {
+		return &SystemMessageConfig{Content: instructions}
+	}
+	merged := *systemMessage
+	if merged.Content != "" {
+		merged.Content += "\n\n" + instructions
+	} else {
+		merged.Content = instructions
+	}
+	return &merged
+}
+
+// registerSkills records the skills available on this session, whose other
+// tools are tools and the tools of resources. Every skill is tracked, including
+// ones disabled at creation, so that they can be toggled later.
+func (s *Session) registerSkills(skills []Skill, disabled []string, tools []Tool, resources *ResourceCatalog) {
+	s.skillsMux.Lock()
+	enabled := make(map[string]bool)
+	for _, skill := range enabledSkills(skills, disabled) {
+		enabled[skill.Name] = true
+	}
+	s.skills = sessionSkills{tools: tools, resources: resources, changed: make(map[string]bool)}
+	for _, skill := range skills {
+		s.skills.skills = append(s.skills.skills, &skillState{skill: skill, enabled: enabled[skill.Name]})
+	}
+	base := s.skills.baseTools()
+	s.skillsMux.Unlock()
+
+	s.toolHandlersM.Lock()
+	s.baseTools = base
+	s.toolHandlersM.Unlock()
+}
+
+// EnableSkill enables a skill on this session, including one listed in
+// [SessionConfig.DisabledSkills], advertising its tools to the server and
+// adding its instructions to the next message sent.
+func (s *Session) EnableSkill(name string) error {
+	return s.setSkillEnabled(name, true)
+}
+
+// DisableSkill disables a skill on this session, withdrawing its tools from
+// the server and telling the model with the next message sent. Calls the model
+// still makes to the skill's tools fail with a result telling it the skill is
+// unavailable.
+func (s *Session) DisableSkill(name string) error {
+	return s.setSkillEnabled(name, false)
+}
+
+// SkillEnabled reports whether the named skill is registered and enabled on this session.
+func (s *Session) SkillEnabled(name string) bool {
+	s.skillsMux.RLock()
+	defer s.skillsMux.RUnlock()
+	state := s.skills.find(name)
+	return state != nil && state.enabled
+}
+
+func (s *Session) setSkillEnabled(name string, enabled bool) error {
+	s.registryMux.Lock()
+	defer s.registryMux.Unlock()
+
+	s.skillsMux.Lock()
+	state := s.skills.find(name)
+	if state == nil {
+		s.skillsMux.Unlock()
+		return fmt.Errorf("unknown skill: %s", name)
+	}
+	if state.enabled == enabled {
+		s.skillsMux.Unlock()
+		return nil
+	}
+	state.enabled = enabled
+	base := s.skills.baseTools()
+	s.skillsMux.Unlock()
+
+	s.toolHandlersM.Lock()
+	previous := s.baseTools
+	s.baseTools = base
+	s.toolHandlersM.Unlock()
+	tools, err := s.setToolHandlers(s.registryTools)
+	if err != nil {
+		s.toolHandlersM.Lock()
+		s.baseTools = previous
+		s.toolHandlersM.Unlock()
+		s.skillsMux.Lock()
+		state.enabled = !enabled
+		s.skillsMux.Unlock()
+		return err
+	}
+
+	s.skillsMux.Lock()
+	if reported, ok := s.skills.changed[name]; ok && reported != enabled {
+		// Toggled back before the model was told: nothing changed for it.
+		delete(s.skills.changed, name)
+	} else {
+		s.skills.changed[name] = enabled
+	}
+	s.skillsMux.Unlock()
+
+	if err := s.advertiseTools(tools); err != nil {
+		return fmt.Errorf("failed to update tools: %w", err)
+	}
+	return nil
+}
+
+// reportSkillChanges adds the instructions of the skills enabled since the last
+// message, and a note naming the skills disabled since then, to params.
+func (s *Session) reportSkillChanges(params map[string]interface{}) {
+	s.skillsMux.Lock()
+	var enabled []Skill
+	var disabled []string
+	for _, state := range s.skills.skills {
+		if on, ok := s.skills.changed[state.skill.Name]; !ok {
+			continue
+		} else if on {
+			enabled = append(enabled, state.skill)
+		} else {
+			disabled = append(disabled, state.skill.Name)
+		}
+	}
+	clear(s.skills.changed)
+	s.skillsMux.Unlock()
+
+	instructions := renderSkillInstructions(enabled)
+	if len(disabled) > 0 {
+		instructions = appendPromptInstructions(instructions, fmt.Sprintf("The following skills are no longer available in this session; do not use their tools: %s.", strings.Join(disabled, ", ")))
+	}
+	if instructions != "" {
+		params["prompt"] = appendPromptInstructions(params["prompt"].(string), instructions)
+	}
+}
+
+// disabledSkillForTool returns the name of a disabled skill providing the
+// given tool, or empty if there is none. It is only consulted for tools the
+// session has no handler for, so a tool of the same name from elsewhere in
+// the session is never mistaken for the skill's.
+func (s *Session) disabledSkillForTool(toolName string) string {
+	s.skillsMux.RLock()
+	defer s.skillsMux.RUnlock()
+	for _, state := range s.skills.skills {
+		if state.enabled {
+			continue
+		}
+		for _, tool := range state.skill.Tools {
+			if tool.Name == toolName {
+				return state.skill.Name
+			}
+		}
+	}
+	return ""
+}
+
+// buildDisabledSkillToolResult creates a failure ToolResult for a tool whose skill is disabled.
+func buildDisabledSkillToolResult(toolName, skillName string) ToolResult {
+	return ToolResult{
+		TextResultForLLM: fmt.Sprintf("Tool '%s' is part of the '%s' skill, which is currently disabled in this session.", toolName, skillName),
+		ResultType:       "failure",
+		Error:            fmt.Sprintf("skill '%s' disabled", skillName),
+		ToolTelemetry:    map[string]interface{}{},
+	}
+}
@@ -0,0 +1,152 @@
+package copilot
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSkills(t *testing.T) {
+	echo := Tool{
+		Name: "gh_list_issues",
+		Handler: func(inv ToolInvocation) (ToolResult, error) {
+			return ToolResult{TextResultForLLM: "issues", ResultType: "success"}, nil
+		},
+	}
+	github := Skill{
+		Name:         "github",
+		Description:  "Use for GitHub questions.",
+		Instructions: "Link to issues.",
+		Examples:     []string{"List my issues"},
+		Tools:        []Tool{echo},
+	}
+
+	t.Run("appends skill instructions to the system message", func(t *testing.T) {
+		merged := withSkillInstructions(&SystemMessageConfig{Content: "Be brief."}, []Skill{github})
+		if !strings.HasPrefix(merged.Content, "Be brief.\n\n") {
+			t.Errorf("Expected existing content to be preserved, got %q", merged.Content)
+		}
+		for _, want := range []string{"## Skill: github", "When to use: Use for GitHub questions.", "Tools: gh_list_issues", "Link to issues.", "- List my issues"} {
+			if !strings.Contains(merged.Content, want) {
+				t.Errorf("Expected system message to contain %q, got %q", want, merged.Content)
+			}
+		}
+
+		if withSkillInstructions(nil, nil) != nil {
+			t.Error("Expected nil system message when there are no skills")
+		}
+	})
+
+	t.Run("disabled skills contribute no tools", func(t *testing.T) {
+		tools := skillTools(nil, enabledSkills([]Skill{github}, []string{"github"}))
+		if len(tools) != 0 {
+			t.Errorf("Expected no tools, got %d", len(tools))
+		}
+		tools = skillTools([]Tool{{Name: "other"}}, enabledSkills([]Skill{github}, nil))
+		if len(tools) != 2 || tools[1].Name != "gh_list_issues" {
+			t.Errorf("Expected skill tools to follow session tools, got %+v", tools)
+		}
+	})
+
+	t.Run("toggling a skill updates the session's tools", func(t *testing.T) {
+		var advertised [][]string
+		var prompts []string
+		session, _ := newFakeSession(t, func(server *fakeServer, method string, params map[string]interface{}) (map[string]interface{}, *JSONRPCError) {
+			switch method {
+			case "session.updateTools":
+				advertised = append(advertised, advertisedNames(params["tools"]))
+			case "session.send":
+				prompts = append(prompts, params["prompt"].(string))
+				return map[string]interface{}{"messageId": "m1"}, nil
+			}
+			return map[string]interface{}{}, nil
+		})
+		other := Tool{Name: "local"}
+		session.registerTools(skillTools([]Tool{other}, nil))
+		session.registerSkills([]Skill{github}, []string{"github"}, []Tool{other}, nil)
+		client := &Client{sessions: map[string]*Session{"s1": session}}
+
+		call := func() ToolResult {
+			response, rpcErr := client.handleToolCallRequest(map[string]interface{}{
+				"sessionId":  "s1",
+				"toolCallId": "1",
+				"toolName":   "gh_list_issues",
+				"arguments":  map[string]interface{}{},
+			})
+			if rpcErr != nil {
+				t.Fatalf("Unexpected error: %v", rpcErr)
+			}
+			return response["result"].(ToolResult)
+		}
+
+		if result := call(); result.ResultType != "failure" || result.Error != "skill 'github' disabled" {
+			t.Errorf("Expected disabled failure for a skill disabled at creation, got %+v", result)
+		}
+
+		if err := session.EnableSkill("github"); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if !session.SkillEnabled("github") || len(advertised) != 1 || strings.Join(advertised[0], ",") != "local,gh_list_issues" {
+			t.Errorf("Expected the skill's tools to be advertised, got %v", advertised)
+		}
+		if result := call(); result.ResultType != "success" {
+			t.Errorf("Expected success once enabled, got %+v", result)
+		}
+		if _, err := session.Send(MessageOptions{Prompt: "hi"}); err != nil {
+			t.Fatalf("Send failed: %v", err)
+		}
+		if !strings.Contains(prompts[0], "## Skill: github") || !strings.Contains(prompts[0], "Link to issues.") {
+			t.Errorf("Expected the skill's instructions with the next message, got %q", prompts[0])
+		}
+
+		if err := session.DisableSkill("github"); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if session.SkillEnabled("github") || len(advertised) != 2 || strings.Join(advertised[1], ",") != "local" {
+			t.Errorf("Expected the skill's tools to be withdrawn, got %v", advertised)
+		}
+		if result := call(); result.ResultType != "failure" || result.Error != "skill 'github' disabled" {
+			t.Errorf("Expected disabled failure, got %+v", result)
+		}
+		if _, err := session.Send(MessageOptions{Prompt: "hi"}); err != nil {
+			t.Fatalf("Send failed: %v", err)
+		}
+		if !strings.Contains(prompts[1], "no longer available in this session; do not use their tools: github.") {
+			t.Errorf("Expected the model to be told the skill is disabled, got %q", prompts[1])
+		}
+		if _, err := session.Send(MessageOptions{Prompt: "hi"}); err != nil || prompts[2] != "hi" {
+			t.Errorf("Expected a change to be reported once, got %q, %v", prompts[2], err)
+		}
+
+		if err := session.EnableSkill("missing"); err == nil {
+			t.Error("Expected error for unknown skill")
+		}
+	})
+
+	t.Run("a disabled skill does not block a tool of the same name", func(t *testing.T) {
+		session, _ := newFakeSession(t, func(server *fakeServer, method string, params map[string]interface{}) (map[string]interface{}, *JSONRPCError) {
+			return map[string]interface{}{}, nil
+		})
+		own := Tool{
+			Name: "gh_list_issues",
+			Handler: func(inv ToolInvocation) (ToolResult, error) {
+				return ToolResult{TextResultForLLM: "own", ResultType: "success"}, nil
+			},
+		}
+		session.registerTools([]Tool{own})
+		session.registerSkills([]Skill{github}, []string{"github"}, []Tool{own}, nil)
+		client := &Client{sessions: map[string]*Session{"s1": session}}
+
+		response, rpcErr := client.handleToolCallRequest(map[string]interface{}{
+			"sessionId":  "s1",
+			"toolCallId": "1",
+			"toolName":   "gh_list_issues",
+			"arguments":  map[string]interface{}{},
+		})
+		if rpcErr != nil {
+			t.Fatalf("Unexpected error: %v", rpcErr)
+		}
+		if result := response["result"].(ToolResult); result.TextResultForLLM != "own" {
+			t.Errorf("Expected the session's own tool to run, got %+v", result)
+		}
+	})
+}
@@ -0,0 +1,365 @@
+package copilot
+
+import (
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// StdioChannel secures the stdin and stdout pipes between a [Client] and the
+// CLI process it spawns. The default, with no channel set, is plain stdio.
+// See [ClientOptions.StdioChannel].
+//
+// A client may start several processes over its lifetime, so a channel that
+// keeps state for a process should key it by the process's command.
+type StdioChannel interface {
+	// Configure prepares the CLI command before it is started, such as by
+	// adding arguments, environment variables, or extra files. It is called
+	// each time the process is started, including on restarts.
+	Configure(cmd *exec.Cmd) error
+	// Wrap returns the streams the client writes requests to and reads
+	// responses from, given the stdin and stdout of the process cmd started.
+	// It is called once the process has started; if it fails, the process is
+	// killed.
+	Wrap(cmd *exec.Cmd, stdin io.WriteCloser, stdout io.ReadCloser) (io.WriteCloser, io.ReadCloser, error)
+}
+
+// abortStdioChannel releases what channel's Configure set up for cmd when
+// it failed to start, for channels that hold resources until Wrap.
+func abortStdioChannel(channel StdioChannel, cmd *exec.Cmd) {
+	if a, ok := channel.(interface{ abort(cmd *exec.Cmd) }); ok {
+		a.abort(cmd)
+	}
+}
+
+// keyExchange selects how an encrypted stdio channel hands the channel key to
+// the CLI process.
+type keyExchange string
+
+const (
+	// keyExchangeEnv passes the key base64-encoded in the
+	// COPILOT_SDK_CHANNEL_KEY environment variable of the process.
+	keyExchangeEnv keyExchange = "env"
+	// keyExchangeFD writes the key, base64-encoded, to a pipe inherited by the
+	// process as file descriptor 3, which it reads to the end. Unlike the
+	// environment, the pipe cannot be read from /proc once the process has
+	// read it. Not supported on Windows.
+	keyExchangeFD keyExchange = "fd"
+)
+
+// channelKeyEnv is the environment variable that carries the channel key
+// with [keyExchangeEnv].
+const channelKeyEnv = "COPILOT_SDK_CHANNEL_KEY"
+
+// channelEncryptionFlag selects encrypted stdio on the CLI's command line. A
+// CLI that supports encrypted stdio lists it in its --help output.
+const channelEncryptionFlag = "--channel-encryption"
+
+// channelProbeTimeout bounds how long the CLI may take to print its help.
+const channelProbeTimeout = 10 * time.Second
+
+// maxChannelRecord is the largest encrypted record a channel accepts, in
+// bytes.
+const maxChannelRecord = 16 << 20
+
+// encryptedStdioChannel returns a [StdioChannel] that encrypts and
+// authenticates the traffic on the CLI's stdin and stdout, for hosts shared
+// with other users who might read the pipes or attach a debugger to them. A
+// fresh AES-256 key is generated for each process start and handed over as
+// selected by exchange; an empty exchange means [keyExchangeEnv].
+//
+// The CLI flags it passes are not part of any released CLI, so the channel
+// stays unexported until the CLI supports encrypted stdio. Before the first
+// start, it runs the CLI with --help and fails unless the output lists the
+// --channel-encryption flag, rather than starting a CLI that would reject
+// the flags.
+//
+// On the wire, each direction is a sequence of records: a 4-byte big-endian
+// length followed by an AES-GCM ciphertext of that length. The nonce is the
+// direction (0 for client to CLI, 1 for CLI to client) as 4 big-endian bytes
+// followed by the record's 8-byte big-endian sequence number, starting at 0,
+// so records that are replayed, reordered, or dropped fail to decrypt.
+func encryptedStdioChannel(exchange keyExchange) StdioChannel {
+	if exchange == "" {
+		exchange = keyExchangeEnv
+	}
+	return &encryptedStdio{exchange: exchange, probe: supportsChannelEncryption}
+}
+
+type encryptedStdio struct {
+	exchange keyExchange
+	// probe reports whether a CLI command supports encrypted stdio.
+	probe func(cmd *exec.Cmd) (bool, error)
+
+	mu sync.Mutex
+	// supported caches the probe result of each CLI executable.
+	supported map[string]bool
+	// pending holds the keys of processes configured but not yet wrapped.
+	pending map[*exec.Cmd]*channelKey
+}
+
+// channelKey is the key of one CLI process.
+type channelKey struct {
+	key []byte
+	// file is the read end of the key pipe, which the parent closes once the
+	// process has inherited it.
+	file *os.File
+}
+
+func (e *encryptedStdio) Configure(cmd *exec.Cmd) error {
+	if err := e.checkSupported(cmd); err != nil {
+		return err
+	}
+
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		return fmt.Errorf("failed to generate channel key: %w", err)
+	}
+	encoded := base64.StdEncoding.EncodeToString(key)
+	configured := &channelKey{key: key}
+
+	switch e.exchange {
+	case keyExchangeEnv:
+		cmd.Args = append(cmd.Args, channelEncryptionFlag, "aes-256-gcm", "--channel-key-env", channelKeyEnv)
+		if cmd.Env == nil {
+			cmd.Env = os.Environ()
+		}
+		cmd.Env = append(cmd.Env, channelKeyEnv+"="+encoded)
+	case keyExchangeFD:
+		reader, writer, err := os.Pipe()
+		if err != nil {
+			return fmt.Errorf("failed to create channel key pipe: %w", err)
+		}
+		// The pipe holds far more than a key, so the write does not block on
+		// the process reading it.
+		_, err = io.WriteString(writer, encoded)
+		writer.Close()
+		if err != nil {
+			reader.Close()
+			return fmt.Errorf("failed to write channel key: %w", err)
+		}
+		fd := 3 + len(cmd.ExtraFiles)
+		cmd.ExtraFiles = append(cmd.ExtraFiles, reader)
+		cmd.Args = append(cmd.Args, channelEncryptionFlag, "aes-256-gcm", "--channel-key-fd", strconv.Itoa(fd))
+		configured.file = reader
+	default:
+		return fmt.Errorf("unknown key exchange %q", e.exchange)
+	}
+
+	e.mu.Lock()
+	if e.pending == nil {
+		e.pending = make(map[*exec.Cmd]*channelKey)
+	}
+	e.pending[cmd] = configured
+	e.mu.Unlock()
+	return nil
+}
+
+// take removes and returns the key configured for cmd, closing its key pipe.
+func (e *encryptedStdio) take(cmd *exec.Cmd) []byte {
+	e.mu.Lock()
+	configured := e.pending[cmd]
+	delete(e.pending, cmd)
+	e.mu.Unlock()
+	if configured == nil {
+		return nil
+	}
+	if configured.file != nil {
+		configured.file.Close()
+	}
+	return configured.key
+}
+
+// checkSupported fails unless the CLI cmd runs supports encrypted stdio.
+func (e *encryptedStdio) checkSupported(cmd *exec.Cmd) error {
+	e.mu.Lock()
+	supported, probed := e.supported[cmd.Path]
+	e.mu.Unlock()
+	if !probed {
+		var err error
+		if supported, err = e.probe(cmd); err != nil {
+			return fmt.Errorf("failed to check the CLI for encrypted stdio support: %w", err)
+		}
+		e.mu.Lock()
+		if e.supported == nil {
+			e.supported = make(map[string]bool)
+		}
+		e.supported[cmd.Path] = supported
+		e.mu.Unlock()
+	}
+	if !supported {
+		return fmt.Errorf("the CLI does not support encrypted stdio: its --help does not list %s", channelEncryptionFlag)
+	}
+	return nil
+}
+
+// supportsChannelEncryption runs the CLI cmd would start with --help, and
+// reports whether the output lists [channelEncryptionFlag].
+func supportsChannelEncryption(cmd *exec.Cmd) (bool, error) {
+	// Keep the arguments before the server flags, such as the script run by
+	// node.
+	var args []string
+	for _, arg := range cmd.Args[1:] {
+		if arg == "--server" {
+			break
+		}
+		args = append(args, arg)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), channelProbeTimeout)
+	defer cancel()
+	help := exec.CommandContext(ctx, cmd.Path, append(args, "--help")...)
+	help.Dir, help.Env = cmd.Dir, cmd.Env
+	output, err := help.CombinedOutput()
+	if ctx.Err() != nil {
+		return false, ctx.Err()
+	}
+	var exitErr *exec.ExitError
+	if err != nil && !errors.As(err, &exitErr) {
+		return false, err
+	}
+	// Some CLIs exit with a nonzero status after printing their help.
+	return bytes.Contains(output, []byte(channelEncryptionFlag)), nil
+}
+
+// abort closes the key pipe of cmd, which was configured but not started.
+func (e *encryptedStdio) abort(cmd *exec.Cmd) {
+	e.take(cmd)
+}
+
+func (e *encryptedStdio) Wrap(cmd *exec.Cmd, stdin io.WriteCloser, stdout io.ReadCloser) (io.WriteCloser, io.ReadCloser, error) {
+	key := e.take(cmd)
+	if key == nil {
+		return nil, nil, errors.New("channel key not configured")
+	}
+	writer, err := newSealingWriter(stdin, key, 0)
+	if err != nil {
+		return nil, nil, err
+	}
+	reader, err := newOpeningReader(stdout, key, 1)
+	if err != nil {
+		return nil, nil, err
+	}
+	return writer, reader, nil
+}
+
+// newChannelAEAD returns the AES-GCM cipher for a channel key.
+func newChannelAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create channel cipher: %w", err)
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create channel cipher: %w", err)
+	}
+	return aead, nil
+}
+
+// channelNonce returns the nonce of a direction's record number seq.
+func channelNonce(direction uint32, seq uint64) []byte {
+	nonce := make([]byte, 12)
+	binary.BigEndian.PutUint32(nonce, direction)
+	binary.BigEndian.PutUint64(nonce[4:], seq)
+	return nonce
+}
+
+// sealingWriter encrypts each Write as a record, splitting writes larger
+// than a record.
+type sealingWriter struct {
+	mu        sync.Mutex
+	w         io.WriteCloser
+	aead      cipher.AEAD
+	direction uint32
+	seq       uint64
+}
+
+func newSealingWriter(w io.WriteCloser, key []byte, direction uint32) (*sealingWriter, error) {
+	aead, err := newChannelAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+	return &sealingWriter{w: w, aead: aead, direction: direction}, nil
+}
+
+func (s *sealingWriter) Write(p []byte) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for written := 0; written < len(p); {
+		chunk := p[written:]
+		if max := maxChannelRecord - s.aead.Overhead(); len(chunk) > max {
+			chunk = chunk[:max]
+		}
+		record := make([]byte, 4, 4+len(chunk)+s.aead.Overhead())
+		record = s.aead.Seal(record, channelNonce(s.direction, s.seq), chunk, nil)
+		binary.BigEndian.PutUint32(record, uint32(len(record)-4))
+		if _, err := s.w.Write(record); err != nil {
+			return written, err
+		}
+		s.seq++
+		written += len(chunk)
+	}
+	return len(p), nil
+}
+
+func (s *sealingWriter) Close() error {
+	return s.w.Close()
+}
+
+// openingReader decrypts records and returns their plaintext.
+type openingReader struct {
+	r         io.ReadCloser
+	aead      cipher.AEAD
+	direction uint32
+	seq       uint64
+	pending   []byte
+}
+
+func newOpeningReader(r io.ReadCloser, key []byte, direction uint32) (*openingReader, error) {
+	aead, err := newChannelAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+	return &openingReader{r: r, aead: aead, direction: direction}, nil
+}
+
+func (o *openingReader) Read(p []byte) (int, error) {
+	for len(o.pending) == 0 {
+		var header [4]byte
+		if _, err := io.ReadFull(o.r, header[:]); err != nil {
+			return 0, err
+		}
+		size := binary.BigEndian.Uint32(header[:])
+		if size > maxChannelRecord || int(size) < o.aead.Overhead() {
+			return 0, fmt.Errorf("invalid channel record of %d bytes", size)
+		}
+		record := make([]byte, size)
+		if _, err := io.ReadFull(o.r, record); err != nil {
+			return 0, fmt.Errorf("channel record cut off: %w", io.ErrUnexpectedEOF)
+		}
+		plaintext, err := o.aead.Open(record[:0], channelNonce(o.direction, o.seq), record, nil)
+		if err != nil {
+			return 0, errors.New("channel record failed authentication")
+		}
+		o.seq++
+		o.pending = plaintext
+	}
+	n := copy(p, o.pending)
+	o.pending = o.pending[n:]
+	return n, nil
+}
+
+func (o *openingReader) Close() error {
+	return o.r.Close()
+}
@@ -0,0 +1,194 @@
+package copilot
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+	"testing"
+)
+
+func channelKeyFromEnv(t *testing.T, cmd *exec.Cmd) []byte {
+	t.Helper()
+	for _, entry := range cmd.Env {
+		if value, ok := strings.CutPrefix(entry, channelKeyEnv+"="); ok {
+			key, err := base64.StdEncoding.DecodeString(value)
+			if err != nil {
+				t.Fatalf("Invalid key: %v", err)
+			}
+			return key
+		}
+	}
+	t.Fatal("Expected the key in the environment")
+	return nil
+}
+
+// supportedChannel makes an encrypted stdio channel treat every CLI as
+// supporting encrypted stdio.
+func supportedChannel(channel StdioChannel) StdioChannel {
+	channel.(*encryptedStdio).probe = func(cmd *exec.Cmd) (bool, error) { return true, nil }
+	return channel
+}
+
+func TestEncryptedStdio(t *testing.T) {
+	t.Run("round-trips traffic in both directions", func(t *testing.T) {
+		channel := supportedChannel(encryptedStdioChannel(""))
+		cmd := exec.Command("copilot")
+		if err := channel.Configure(cmd); err != nil {
+			t.Fatalf("Configure failed: %v", err)
+		}
+		if !strings.Contains(strings.Join(cmd.Args, " "), "--channel-key-env "+channelKeyEnv) {
+			t.Errorf("Expected the key variable to be passed, got %v", cmd.Args)
+		}
+		key := channelKeyFromEnv(t, cmd)
+
+		var toCLI, fromCLI bytes.Buffer
+		cliWriter, _ := newSealingWriter(nopWriteCloser{&fromCLI}, key, 1)
+		io.WriteString(cliWriter, "Content-Length: 2\r\n\r\n{}")
+
+		stdin, stdout, err := channel.Wrap(cmd, nopWriteCloser{&toCLI}, io.NopCloser(&fromCLI))
+		if err != nil {
+			t.Fatalf("Wrap failed: %v", err)
+		}
+		io.WriteString(stdin, "Content-Length: 17\r\n\r\n")
+		io.WriteString(stdin, `{"jsonrpc":"2.0"}`)
+		if strings.Contains(toCLI.String(), "jsonrpc") {
+			t.Error("Expected traffic to the CLI to be encrypted")
+		}
+
+		cliReader, _ := newOpeningReader(io.NopCloser(&toCLI), key, 0)
+		if got, _ := io.ReadAll(cliReader); string(got) != "Content-Length: 17\r\n\r\n"+`{"jsonrpc":"2.0"}` {
+			t.Errorf("Unexpected plaintext %q", got)
+		}
+		if got, _ := io.ReadAll(stdout); string(got) != "Content-Length: 2\r\n\r\n{}" {
+			t.Errorf("Unexpected plaintext %q", got)
+		}
+	})
+
+	t.Run("keeps the key of each process", func(t *testing.T) {
+		channel := supportedChannel(encryptedStdioChannel(""))
+		first, second := exec.Command("copilot"), exec.Command("copilot")
+		for _, cmd := range []*exec.Cmd{first, second} {
+			if err := channel.Configure(cmd); err != nil {
+				t.Fatalf("Configure failed: %v", err)
+			}
+		}
+
+		var toCLI bytes.Buffer
+		stdin, _, err := channel.Wrap(first, nopWriteCloser{&toCLI}, io.NopCloser(strings.NewReader("")))
+		if err != nil {
+			t.Fatalf("Wrap failed: %v", err)
+		}
+		io.WriteString(stdin, "hello")
+		cliReader, _ := newOpeningReader(io.NopCloser(&toCLI), channelKeyFromEnv(t, first), 0)
+		if got, err := io.ReadAll(cliReader); err != nil || string(got) != "hello" {
+			t.Errorf("Expected the first process's key to be used, got %q, %v", got, err)
+		}
+		if _, _, err := channel.Wrap(second, nopWriteCloser{io.Discard}, io.NopCloser(strings.NewReader(""))); err != nil {
+			t.Errorf("Expected the second process to keep its key, got %v", err)
+		}
+		if _, _, err := channel.Wrap(first, nopWriteCloser{io.Discard}, io.NopCloser(strings.NewReader(""))); err == nil {
+			t.Error("Expected a key to be used once")
+		}
+	})
+
+	t.Run("rejects tampered and replayed records", func(t *testing.T) {
+		key := bytes.Repeat([]byte{7}, 32)
+		var wire bytes.Buffer
+		writer, _ := newSealingWriter(nopWriteCloser{&wire}, key, 0)
+		io.WriteString(writer, "first")
+		record := append([]byte(nil), wire.Bytes()...)
+
+		tampered := append([]byte(nil), record...)
+		tampered[len(tampered)-1] ^= 1
+		reader, _ := newOpeningReader(io.NopCloser(bytes.NewReader(tampered)), key, 0)
+		if _, err := io.ReadAll(reader); err == nil || !strings.Contains(err.Error(), "authentication") {
+			t.Errorf("Expected an authentication error, got %v", err)
+		}
+
+		replayed := append(append([]byte(nil), record...), record...)
+		reader, _ = newOpeningReader(io.NopCloser(bytes.NewReader(replayed)), key, 0)
+		if got, err := io.ReadAll(reader); err == nil || string(got) != "first" {
+			t.Errorf("Expected the replayed record to be rejected, got %q, %v", got, err)
+		}
+
+		reader, _ = newOpeningReader(io.NopCloser(bytes.NewReader(record)), key, 1)
+		if _, err := io.ReadAll(reader); err == nil {
+			t.Error("Expected a record to fail in the other direction")
+		}
+	})
+
+	t.Run("hands the key over a pipe", func(t *testing.T) {
+		channel := supportedChannel(encryptedStdioChannel(keyExchangeFD))
+		cmd := exec.Command("copilot")
+		if err := channel.Configure(cmd); err != nil {
+			t.Fatalf("Configure failed: %v", err)
+		}
+		if len(cmd.ExtraFiles) != 1 || !strings.Contains(strings.Join(cmd.Args, " "), "--channel-key-fd 3") {
+			t.Fatalf("Expected the key pipe as fd 3, got %v", cmd.Args)
+		}
+		for _, entry := range cmd.Env {
+			if strings.HasPrefix(entry, channelKeyEnv+"=") {
+				t.Error("Expected no key in the environment")
+			}
+		}
+		encoded, err := io.ReadAll(cmd.ExtraFiles[0])
+		if err != nil {
+			t.Fatal(err)
+		}
+		if key, err := base64.StdEncoding.DecodeString(string(encoded)); err != nil || len(key) != 32 {
+			t.Errorf("Expected a 32-byte key, got %q", encoded)
+		}
+		if _, _, err := channel.Wrap(cmd, nopWriteCloser{io.Discard}, io.NopCloser(strings.NewReader(""))); err != nil {
+			t.Fatalf("Wrap failed: %v", err)
+		}
+	})
+
+	t.Run("refuses CLIs without encrypted stdio", func(t *testing.T) {
+		channel := encryptedStdioChannel("")
+		probes := 0
+		channel.(*encryptedStdio).probe = func(cmd *exec.Cmd) (bool, error) {
+			probes++
+			return false, nil
+		}
+		for i := 0; i < 2; i++ {
+			cmd := exec.Command("copilot", "--server")
+			if err := channel.Configure(cmd); err == nil || !strings.Contains(err.Error(), "does not support encrypted stdio") {
+				t.Fatalf("Expected an unsupported error, got %v", err)
+			}
+			if len(cmd.Args) != 2 || len(cmd.Env) != 0 {
+				t.Errorf("Expected the command to be unchanged, got %v", cmd.Args)
+			}
+		}
+		if probes != 1 {
+			t.Errorf("Expected the probe result to be cached, got %d probes", probes)
+		}
+	})
+
+	t.Run("closes the key pipe when the process fails to start", func(t *testing.T) {
+		channel := supportedChannel(encryptedStdioChannel(keyExchangeFD))
+		cmd := exec.Command("copilot")
+		if err := channel.Configure(cmd); err != nil {
+			t.Fatalf("Configure failed: %v", err)
+		}
+		abortStdioChannel(channel, cmd)
+		if _, err := cmd.ExtraFiles[0].Read(make([]byte, 1)); !errors.Is(err, os.ErrClosed) {
+			t.Errorf("Expected the key pipe to be closed, got %v", err)
+		}
+		if _, _, err := channel.Wrap(cmd, nopWriteCloser{io.Discard}, io.NopCloser(strings.NewReader(""))); err == nil {
+			t.Error("Expected Wrap to fail without a configured key")
+		}
+	})
+
+	t.Run("requires stdio", func(t *testing.T) {
+		defer func() {
+			if recover() == nil {
+				t.Error("Expected a panic")
+			}
+		}()
+		NewClient(&ClientOptions{UseStdio: Bool(false), StdioChannel: encryptedStdioChannel("")})
+	})
+}
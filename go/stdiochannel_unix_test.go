@@ -0,0 +1,34 @@
+//go:build unix
+
+package copilot
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+)
+
+func TestSupportsChannelEncryption(t *testing.T) {
+	fakeCLI := func(t *testing.T, help string) string {
+		t.Helper()
+		path := filepath.Join(t.TempDir(), "copilot")
+		script := "#!/bin/sh\n[ \"$1\" = --help ] || exit 2\necho '" + help + "'\n"
+		if err := os.WriteFile(path, []byte(script), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+
+	supported, err := supportsChannelEncryption(exec.Command(fakeCLI(t, "--channel-encryption <cipher>"), "--server", "--stdio"))
+	if err != nil || !supported {
+		t.Errorf("Expected a CLI listing the flag to be supported, got %v, %v", supported, err)
+	}
+	supported, err = supportsChannelEncryption(exec.Command(fakeCLI(t, "--stdio"), "--server", "--stdio"))
+	if err != nil || supported {
+		t.Errorf("Expected a CLI without the flag to be unsupported, got %v, %v", supported, err)
+	}
+	if _, err := supportsChannelEncryption(exec.Command(filepath.Join(t.TempDir(), "missing"))); err == nil {
+		t.Error("Expected an error for a missing CLI")
+	}
+}
@@ -0,0 +1,118 @@
+package copilot
+
+import "sync"
+
+// streamFallback delivers whole messages as a delta and a final event on
+// sessions that requested streaming from a server that does not stream, so
+// that handlers written for deltas see every message either way.
+//
+// A message is considered unstreamed when its final assistant.message or
+// assistant.reasoning event arrives without a delta for the same ID before it.
+type streamFallback struct {
+	mu sync.Mutex
+	// enabled is set when the session requested streaming.
+	enabled bool
+	// streamed holds the IDs of messages and reasoning blocks that received
+	// deltas and have not yet been completed.
+	streamed map[string]bool
+	// downgraded is set once a message was found unstreamed.
+	downgraded bool
+}
+
+func (s *Session) registerStreaming(streaming bool) {
+	s.streamFallback.mu.Lock()
+	defer s.streamFallback.mu.Unlock()
+	s.streamFallback.enabled = streaming
+}
+
+// streamKey returns the key under which event's message or reasoning block is
+// tracked, and whether event is a delta or a final event that is tracked.
+func streamKey(event SessionEvent) (key string, delta, final bool) {
+	switch event.Type {
+	case AssistantMessageDelta, AssistantMessage:
+		if event.Data.MessageID == nil {
+			return "", false, false
+		}
+		return "message:" + *event.Data.MessageID, event.Type == AssistantMessageDelta, event.Type == AssistantMessage
+	case AssistantReasoningDelta, AssistantReasoning:
+		if event.Data.ReasoningID == nil {
+			return "", false, false
+		}
+		return "reasoning:" + *event.Data.ReasoningID, event.Type == AssistantReasoningDelta, event.Type == AssistantReasoning
+	}
+	return "", false, false
+}
+
+// dispatchServerEvent dispatches an event received from the server as size
+// bytes of JSON, preceded by a synthesized delta if the event completes a
+// message the server did not stream.
+func (s *Session) dispatchServerEvent(event SessionEvent, size int) {
+	if delta, downgraded := s.fallbackDelta(event); delta != nil {
+		if downgraded {
+			s.emit(SDKStreamingDowngraded, map[string]interface{}{
+				"eventType": string(event.Type),
+				"eventId":   event.ID,
+			})
+		}
+		s.dispatchEvent(*delta)
+	}
+	s.dispatchSizedEvent(event, size)
+}
+
+// fallbackDelta returns the delta to dispatch before event, if event
+// completes a message that received no deltas, and whether this is the first
+// such message of the session.
+func (s *Session) fallbackDelta(event SessionEvent) (*SessionEvent, bool) {
+	key, isDelta, isFinal := streamKey(event)
+	if key == "" {
+		return nil, false
+	}
+	f := &s.streamFallback
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if !f.enabled {
+		return nil, false
+	}
+	if isDelta {
+		if f.streamed == nil {
+			f.streamed = make(map[string]bool)
+		}
+		f.streamed[key] = true
+		return nil, false
+	}
+	if !isFinal {
+		return nil, false
+	}
+	if f.streamed[key] {
+		delete(f.streamed, key)
+		return nil, false
+	}
+	if event.Data.Content == nil || *event.Data.Content == "" {
+		return nil, false
+	}
+
+	deltaType := AssistantMessageDelta
+	if event.Type == AssistantReasoning {
+		deltaType = AssistantReasoningDelta
+	}
+	ephemeral := true
+	delta := event
+	delta.ID = generateUUID()
+	delta.Type = deltaType
+	delta.Ephemeral = &ephemeral
+	delta.Data.DeltaContent = event.Data.Content
+	delta.Data.Content = nil
+
+	downgraded := !f.downgraded
+	f.downgraded = true
+	return &delta, downgraded
+}
+
+// StreamingDowngraded reports whether the session requested streaming but
+// received a message the server did not stream, which was then delivered as a
+// single delta followed by its final event.
+func (s *Session) StreamingDowngraded() bool {
+	s.streamFallback.mu.Lock()
+	defer s.streamFallback.mu.Unlock()
+	return s.streamFallback.downgraded
+}
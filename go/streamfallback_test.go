@@ -0,0 +1,107 @@
+package copilot
+
+import (
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestStreamFallback(t *testing.T) {
+	setup := func(t *testing.T, streaming bool) (*Session, *fakeServer, func() []SessionEvent, <-chan struct{}) {
+		session, server := newFakeSession(t, func(server *fakeServer, method string, params map[string]interface{}) (map[string]interface{}, *JSONRPCError) {
+			return nil, nil
+		})
+		session.registerStreaming(streaming)
+		var mu sync.Mutex
+		var events []SessionEvent
+		idle := make(chan struct{})
+		session.On(func(event SessionEvent) {
+			mu.Lock()
+			defer mu.Unlock()
+			events = append(events, event)
+			if event.Type == SessionIdle {
+				close(idle)
+			}
+		})
+		return session, server, func() []SessionEvent {
+			mu.Lock()
+			defer mu.Unlock()
+			return append([]SessionEvent(nil), events...)
+		}, idle
+	}
+	waitIdle := func(t *testing.T, idle <-chan struct{}) {
+		t.Helper()
+		select {
+		case <-idle:
+		case <-time.After(2 * time.Second):
+			t.Fatal("Timed out waiting for session.idle")
+		}
+	}
+	describe := func(events []SessionEvent) string {
+		var parts []string
+		for _, e := range events {
+			part := string(e.Type)
+			if e.Data.DeltaContent != nil {
+				part += "(" + *e.Data.DeltaContent + ")"
+			}
+			parts = append(parts, part)
+		}
+		return strings.Join(parts, " ")
+	}
+
+	t.Run("synthesizes a delta for unstreamed messages", func(t *testing.T) {
+		session, server, events, idle := setup(t, true)
+		server.emitEvent(AssistantReasoning, "e1", map[string]interface{}{"reasoningId": "r1", "content": "Thinking"})
+		server.emitEvent(AssistantMessage, "e2", map[string]interface{}{"messageId": "m1", "content": "Hello", "parentToolCallId": "call-1"})
+		server.emitEvent(AssistantMessage, "e3", map[string]interface{}{"messageId": "m2", "content": "Again"})
+		server.emitEvent(SessionIdle, "e4", map[string]interface{}{})
+		waitIdle(t, idle)
+
+		got := events()
+		want := "sdk.streaming_downgraded assistant.reasoning_delta(Thinking) assistant.reasoning " +
+			"assistant.message_delta(Hello) assistant.message assistant.message_delta(Again) assistant.message session.idle"
+		if describe(got) != want {
+			t.Fatalf("Expected %s, got %s", want, describe(got))
+		}
+		delta := got[3]
+		if *delta.Data.MessageID != "m1" || delta.Data.Content != nil || delta.Ephemeral == nil || !*delta.Ephemeral || delta.ID == "e2" {
+			t.Errorf("Unexpected synthesized delta %+v", delta)
+		}
+		if delta.Data.ParentToolCallID == nil || *delta.Data.ParentToolCallID != "call-1" {
+			t.Errorf("Expected the delta to keep the message's other fields, got %+v", delta.Data)
+		}
+		if !session.StreamingDowngraded() || sdkEventString(got[0], "eventId") != "e1" {
+			t.Errorf("Expected the downgrade to be reported, got %+v", got[0])
+		}
+	})
+
+	t.Run("passes streamed messages through", func(t *testing.T) {
+		session, server, events, idle := setup(t, true)
+		server.emitEvent(AssistantMessageDelta, "e1", map[string]interface{}{"messageId": "m1", "deltaContent": "Hel"})
+		server.emitEvent(AssistantMessageDelta, "e2", map[string]interface{}{"messageId": "m1", "deltaContent": "lo"})
+		server.emitEvent(AssistantMessage, "e3", map[string]interface{}{"messageId": "m1", "content": "Hello"})
+		server.emitEvent(AssistantMessage, "e4", map[string]interface{}{"messageId": "m2", "content": ""})
+		server.emitEvent(SessionIdle, "e5", map[string]interface{}{})
+		waitIdle(t, idle)
+
+		want := "assistant.message_delta(Hel) assistant.message_delta(lo) assistant.message assistant.message session.idle"
+		if got := describe(events()); got != want {
+			t.Errorf("Expected %s, got %s", want, got)
+		}
+		if session.StreamingDowngraded() {
+			t.Error("Expected no downgrade")
+		}
+	})
+
+	t.Run("does nothing without streaming", func(t *testing.T) {
+		_, server, events, idle := setup(t, false)
+		server.emitEvent(AssistantMessage, "e1", map[string]interface{}{"messageId": "m1", "content": "Hello"})
+		server.emitEvent(SessionIdle, "e2", map[string]interface{}{})
+		waitIdle(t, idle)
+
+		if got := describe(events()); got != "assistant.message session.idle" {
+			t.Errorf("Expected the events unchanged, got %s", got)
+		}
+	})
+}
@@ -0,0 +1,225 @@
+package copilot
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"text/template"
+)
+
+// ConversationTemplate describes a known starting state for a session:
+// instructions and synthetic turns, including tool calls with fabricated
+// results, that the model treats as having already happened. Use it to
+// reproduce a support scenario or to start a guided flow part way through.
+//
+// Every string, including the string values of tool call arguments, is a
+// text/template executed with the variables passed to
+// [ConversationTemplate.Render], over Defaults. A variable the template uses
+// but no one sets is an error.
+//
+// The protocol has no way to insert past turns, so the template is rendered
+// into the session's system message.
+//
+// Example:
+//
+//	refund := &copilot.ConversationTemplate{
+//	    Name:         "refund",
+//	    Instructions: "You are helping {{.customer}} with a refund.",
+//	    Turns: []copilot.TemplateTurn{{
+//	        User: "Where is my order {{.order}}?",
+//	        ToolCalls: []copilot.TemplateToolCall{{
+//	            ToolName:  "lookup_order",
+//	            Arguments: map[string]interface{}{"id": "{{.order}}"},
+//	            Result:    `{"status": "lost in transit"}`,
+//	        }},
+//	        Assistant: "Order {{.order}} was lost in transit. Would you like a refund?",
+//	    }},
+//	}
+//	session, err := client.CreateSessionFromTemplate(refund,
+//	    map[string]interface{}{"customer": "Ada", "order": "A-1042"},
+//	    &copilot.SessionConfig{Tools: supportTools})
+type ConversationTemplate struct {
+	// Name identifies the template in errors.
+	Name string
+	// Instructions are added to the system message before the turns.
+	Instructions string
+	// Turns are the synthetic turns, oldest first.
+	Turns []TemplateTurn
+	// Defaults are the values of variables not passed to Render.
+	Defaults map[string]interface{}
+}
+
+// TemplateTurn is a synthetic turn of a [ConversationTemplate].
+type TemplateTurn struct {
+	// User is the user's message.
+	User string
+	// ToolCalls are the tools the assistant called, in order, with their
+	// results.
+	ToolCalls []TemplateToolCall
+	// Assistant is the assistant's reply.
+	Assistant string
+}
+
+// TemplateToolCall is a tool call with a fabricated result in a
+// [TemplateTurn].
+type TemplateToolCall struct {
+	ToolName  string
+	Arguments map[string]interface{}
+	// Result is the text the tool returned to the model.
+	Result string
+}
+
+// templatePreamble introduces the synthetic turns to the model.
+const templatePreamble = "The conversation below took place before this session started. Continue it as if you had taken part: the tool results shown are what the tools returned, and you do not need to call those tools again for the same information."
+
+// Render returns the system message section for the template with vars set.
+func (t *ConversationTemplate) Render(vars map[string]interface{}) (string, error) {
+	data := make(map[string]interface{}, len(t.Defaults)+len(vars))
+	for key, value := range t.Defaults {
+		data[key] = value
+	}
+	for key, value := range vars {
+		data[key] = value
+	}
+	r := &templateRenderer{name: t.Name, data: data}
+
+	var b strings.Builder
+	if instructions := strings.TrimSpace(r.render("instructions", t.Instructions)); instructions != "" {
+		b.WriteString(instructions)
+	}
+	if len(t.Turns) > 0 {
+		if b.Len() > 0 {
+			b.WriteString("\n\n")
+		}
+		b.WriteString(templatePreamble)
+		b.WriteString("\n\n<conversation>\n")
+		for i, turn := range t.Turns {
+			field := fmt.Sprintf("turn %d", i+1)
+			if user := r.render(field+" user", turn.User); user != "" {
+				fmt.Fprintf(&b, "User: %s\n", user)
+			}
+			for j, call := range turn.ToolCalls {
+				callField := fmt.Sprintf("%s tool call %d", field, j+1)
+				if call.ToolName == "" {
+					r.fail(fmt.Errorf("%s has no tool name", callField))
+				}
+				args, err := json.Marshal(r.renderValue(callField+" arguments", call.Arguments))
+				if err != nil {
+					r.fail(fmt.Errorf("failed to encode %s arguments: %w", callField, err))
+				}
+				fmt.Fprintf(&b, "Tool call: %s(%s)\n", call.ToolName, args)
+				fmt.Fprintf(&b, "Tool result: %s\n", r.render(callField+" result", call.Result))
+			}
+			if assistant := r.render(field+" assistant", turn.Assistant); assistant != "" {
+				fmt.Fprintf(&b, "Assistant: %s\n", assistant)
+			}
+		}
+		b.WriteString("</conversation>")
+	}
+	if r.err != nil {
+		return "", r.err
+	}
+	return b.String(), nil
+}
+
+// SessionConfig returns a copy of config, which may be nil, with the rendered
+// template appended to its system message.
+func (t *ConversationTemplate) SessionConfig(config *SessionConfig, vars map[string]interface{}) (*SessionConfig, error) {
+	rendered, err := t.Render(vars)
+	if err != nil {
+		return nil, err
+	}
+	var merged SessionConfig
+	if config != nil {
+		merged = *config
+	}
+	if rendered == "" {
+		return &merged, nil
+	}
+	systemMessage := &SystemMessageConfig{Content: rendered}
+	if merged.SystemMessage != nil {
+		copied := *merged.SystemMessage
+		if copied.Content != "" {
+			copied.Content += "\n\n" + rendered
+		} else {
+			copied.Content = rendered
+		}
+		systemMessage = &copied
+	}
+	merged.SystemMessage = systemMessage
+	return &merged, nil
+}
+
+// CreateSessionFromTemplate creates a session that starts in the state
+// template describes, with vars set. config, which may be nil, configures the
+// session as for [Client.CreateSession].
+func (c *Client) CreateSessionFromTemplate(template *ConversationTemplate, vars map[string]interface{}, config *SessionConfig) (*Session, error) {
+	merged, err := template.SessionConfig(config, vars)
+	if err != nil {
+		return nil, err
+	}
+	return c.CreateSession(merged)
+}
+
+// templateRenderer executes the strings of a template, keeping the first
+// error.
+type templateRenderer struct {
+	name string
+	data map[string]interface{}
+	err  error
+}
+
+func (r *templateRenderer) fail(err error) {
+	if r.err == nil {
+		if r.name != "" {
+			err = fmt.Errorf("template %s: %w", r.name, err)
+		}
+		r.err = err
+	}
+}
+
+// render executes text, naming it field in errors.
+func (r *templateRenderer) render(field, text string) string {
+	if r.err != nil || !strings.Contains(text, "{{") {
+		return text
+	}
+	tmpl, err := template.New(field).Option("missingkey=error").Parse(text)
+	if err != nil {
+		r.fail(fmt.Errorf("invalid %s: %w", field, err))
+		return ""
+	}
+	var b strings.Builder
+	if err := tmpl.Execute(&b, r.data); err != nil {
+		r.fail(fmt.Errorf("failed to render %s: %w", field, err))
+		return ""
+	}
+	return b.String()
+}
+
+// renderValue returns a copy of value with its strings rendered.
+func (r *templateRenderer) renderValue(field string, value interface{}) interface{} {
+	switch value := value.(type) {
+	case string:
+		return r.render(field, value)
+	case map[string]interface{}:
+		keys := make([]string, 0, len(value))
+		for key := range value {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		rendered := make(map[string]interface{}, len(value))
+		for _, key := range keys {
+			rendered[key] = r.renderValue(field+"."+key, value[key])
+		}
+		return rendered
+	case []interface{}:
+		rendered := make([]interface{}, len(value))
+		for i, item := range value {
+			rendered[i] = r.renderValue(fmt.Sprintf("%s[%d]", field, i), item)
+		}
+		return rendered
+	default:
+		return value
+	}
+}
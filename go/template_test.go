@@ -0,0 +1,87 @@
+package copilot
+
+import (
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestConversationTemplate(t *testing.T) {
+	refund := &ConversationTemplate{
+		Name:         "refund",
+		Instructions: "You are helping {{.customer}} with a refund.",
+		Turns: []TemplateTurn{{
+			User: "Where is my order {{.order}}?",
+			ToolCalls: []TemplateToolCall{{
+				ToolName:  "lookup_order",
+				Arguments: map[string]interface{}{"id": "{{.order}}", "verbose": true},
+				Result:    `{"status": "lost in transit"}`,
+			}},
+			Assistant: "Order {{.order}} was lost in transit.",
+		}},
+		Defaults: map[string]interface{}{"customer": "a customer"},
+	}
+
+	t.Run("renders instructions and turns", func(t *testing.T) {
+		rendered, err := refund.Render(map[string]interface{}{"order": "A-1042"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, want := range []string{
+			"You are helping a customer with a refund.",
+			"User: Where is my order A-1042?\n",
+			`Tool call: lookup_order({"id":"A-1042","verbose":true})` + "\n",
+			`Tool result: {"status": "lost in transit"}` + "\n",
+			"Assistant: Order A-1042 was lost in transit.\n</conversation>",
+		} {
+			if !strings.Contains(rendered, want) {
+				t.Errorf("Expected %q in:\n%s", want, rendered)
+			}
+		}
+	})
+
+	t.Run("reports missing variables", func(t *testing.T) {
+		_, err := refund.Render(nil)
+		if err == nil || !strings.Contains(err.Error(), "template refund") || !strings.Contains(err.Error(), "turn 1 user") {
+			t.Errorf("Unexpected error %v", err)
+		}
+	})
+
+	t.Run("appends to the system message", func(t *testing.T) {
+		config := &SessionConfig{Model: "gpt-5", SystemMessage: &SystemMessageConfig{Mode: "append", Content: "Be brief."}}
+		merged, err := refund.SessionConfig(config, map[string]interface{}{"order": "A-1"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if merged.Model != "gpt-5" || merged.SystemMessage.Mode != "append" || !strings.HasPrefix(merged.SystemMessage.Content, "Be brief.\n\nYou are helping") {
+			t.Errorf("Unexpected config %+v", merged.SystemMessage)
+		}
+		if config.SystemMessage.Content != "Be brief." {
+			t.Error("Expected the original config to be unchanged")
+		}
+	})
+
+	t.Run("creates sessions", func(t *testing.T) {
+		var mu sync.Mutex
+		var systemMessage map[string]interface{}
+		rpc, _ := newFakeServer(t, func(method string, params map[string]interface{}) (map[string]interface{}, *JSONRPCError) {
+			if method == "session.create" {
+				mu.Lock()
+				systemMessage, _ = params["systemMessage"].(map[string]interface{})
+				mu.Unlock()
+				return map[string]interface{}{"sessionId": "s1"}, nil
+			}
+			return map[string]interface{}{}, nil
+		})
+		client := &Client{client: rpc, state: StateConnected, sessions: make(map[string]*Session)}
+		if _, err := client.CreateSessionFromTemplate(refund, map[string]interface{}{"order": "A-1"}, nil); err != nil {
+			t.Fatal(err)
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		content, _ := systemMessage["content"].(string)
+		if !strings.Contains(content, "User: Where is my order A-1?") {
+			t.Errorf("Unexpected system message %v", systemMessage)
+		}
+	})
+}
@@ -0,0 +1,268 @@
+package copilot
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"iter"
+	"strings"
+)
+
+// tenantSeparator separates the tenant from the session ID in the IDs a
+// [TenantTranscriptStore] stores transcripts under.
+const tenantSeparator = "/"
+
+// TenantKeys provides the key each tenant's transcripts are encrypted with.
+type TenantKeys interface {
+	// Key returns the tenant's 16, 24, or 32 byte AES key.
+	Key(tenant string) ([]byte, error)
+}
+
+// TenantKeyFunc adapts a function to [TenantKeys].
+type TenantKeyFunc func(tenant string) ([]byte, error)
+
+// Key implements [TenantKeys].
+func (f TenantKeyFunc) Key(tenant string) ([]byte, error) {
+	return f(tenant)
+}
+
+// TenantTranscriptStore isolates the transcripts of many tenants in one shared
+// [TranscriptStore]. Each tenant's transcripts are stored under IDs namespaced
+// by the tenant and, when keys are provided, have their events encrypted with
+// the tenant's key using AES-GCM.
+//
+// Encryption binds each transcript to its tenant and session, so a transcript
+// copied into another tenant's namespace fails to load. Destroying a tenant's
+// key makes its transcripts' events unreadable even in backups of the store.
+// Only the events are encrypted: the tenant, session ID, Tags, CreatedAt, and
+// UpdatedAt of each transcript stay in plaintext so the store can list and
+// filter them, and must not hold anything confidential.
+//
+// Example:
+//
+//	store, _ := copilot.NewFileTranscriptStore("./transcripts")
+//	tenants := copilot.NewTenantTranscriptStore(store, copilot.TenantKeyFunc(kms.TenantKey))
+//	recorder := copilot.NewTranscriptRecorder(session, tenants.ForTenant("acme"))
+type TenantTranscriptStore struct {
+	base TranscriptStore
+	keys TenantKeys
+}
+
+// NewTenantTranscriptStore creates a tenant-isolating store on top of base. If
+// keys is nil, transcripts are namespaced but not encrypted.
+func NewTenantTranscriptStore(base TranscriptStore, keys TenantKeys) *TenantTranscriptStore {
+	return &TenantTranscriptStore{base: base, keys: keys}
+}
+
+// ForTenant returns the [TranscriptStore] of one tenant. Its transcripts keep
+// their own session IDs; only transcripts of the tenant are visible through it.
+// Operations on it fail if the tenant name is empty or contains "/".
+func (s *TenantTranscriptStore) ForTenant(tenant string) TranscriptStore {
+	return &tenantTranscripts{store: s, tenant: tenant}
+}
+
+// Tenants returns the tenants that have stored transcripts, in order of their
+// oldest transcript.
+func (s *TenantTranscriptStore) Tenants() ([]string, error) {
+	var tenants []string
+	seen := make(map[string]bool)
+	for transcript, err := range s.base.All() {
+		if err != nil {
+			return nil, err
+		}
+		if transcript.Tenant != "" && !seen[transcript.Tenant] {
+			seen[transcript.Tenant] = true
+			tenants = append(tenants, transcript.Tenant)
+		}
+	}
+	return tenants, nil
+}
+
+// List iterates over the transcripts of a tenant, oldest first. It is
+// equivalent to ForTenant(tenant).All().
+func (s *TenantTranscriptStore) List(tenant string) iter.Seq2[*Transcript, error] {
+	return s.ForTenant(tenant).All()
+}
+
+// DeleteTenant removes every transcript of a tenant, for example to honor an
+// erasure request, and returns how many were removed. A transcript that cannot
+// be read or deleted does not stop the others from being deleted; the errors
+// are joined in the returned error, and an unreadable transcript is left in the
+// store since its tenant cannot be told.
+func (s *TenantTranscriptStore) DeleteTenant(tenant string) (int, error) {
+	if err := validateTenant(tenant); err != nil {
+		return 0, err
+	}
+	prefix := tenant + tenantSeparator
+	var ids []string
+	var errs []error
+	for transcript, err := range s.base.All() {
+		if err != nil {
+			errs = append(errs, fmt.Errorf("failed to list transcripts of tenant %s: %w", tenant, err))
+			continue
+		}
+		if transcript.Tenant == tenant || strings.HasPrefix(transcript.SessionID, prefix) {
+			ids = append(ids, transcript.SessionID)
+		}
+	}
+	deleted := 0
+	for _, id := range ids {
+		if err := s.base.Delete(id); err != nil {
+			errs = append(errs, fmt.Errorf("failed to delete transcripts of tenant %s: %w", tenant, err))
+			continue
+		}
+		deleted++
+	}
+	return deleted, errors.Join(errs...)
+}
+
+func validateTenant(tenant string) error {
+	if tenant == "" || strings.Contains(tenant, tenantSeparator) {
+		return fmt.Errorf("invalid tenant %q", tenant)
+	}
+	return nil
+}
+
+// aead returns the cipher of a tenant, or nil if the store does not encrypt.
+func (s *TenantTranscriptStore) aead(tenant string) (cipher.AEAD, error) {
+	if s.keys == nil {
+		return nil, nil
+	}
+	key, err := s.keys.Key(tenant)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get key of tenant %s: %w", tenant, err)
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("invalid key of tenant %s: %w", tenant, err)
+	}
+	return cipher.NewGCM(block)
+}
+
+// tenantTranscripts is the [TranscriptStore] returned by
+// [TenantTranscriptStore.ForTenant].
+type tenantTranscripts struct {
+	store  *TenantTranscriptStore
+	tenant string
+}
+
+func (t *tenantTranscripts) id(sessionID string) string {
+	return t.tenant + tenantSeparator + sessionID
+}
+
+// additionalData binds a sealed transcript to its tenant and session.
+func (t *tenantTranscripts) additionalData(sessionID string) []byte {
+	return []byte(t.id(sessionID))
+}
+
+func (t *tenantTranscripts) Save(transcript *Transcript) error {
+	if err := validateTenant(t.tenant); err != nil {
+		return err
+	}
+	stored := transcript.clone()
+	stored.SessionID = t.id(transcript.SessionID)
+	stored.Tenant = t.tenant
+
+	aead, err := t.store.aead(t.tenant)
+	if err != nil {
+		return err
+	}
+	if aead != nil {
+		events, err := json.Marshal(transcript.Events)
+		if err != nil {
+			return fmt.Errorf("failed to encode transcript events: %w", err)
+		}
+		nonce := make([]byte, aead.NonceSize(), aead.NonceSize()+len(events)+aead.Overhead())
+		if _, err := rand.Read(nonce); err != nil {
+			return fmt.Errorf("failed to generate nonce: %w", err)
+		}
+		stored.Sealed = aead.Seal(nonce, nonce, events, t.additionalData(transcript.SessionID))
+		stored.Events = nil
+	}
+	return t.store.base.Save(stored)
+}
+
+func (t *tenantTranscripts) Load(sessionID string) (*Transcript, error) {
+	if err := validateTenant(t.tenant); err != nil {
+		return nil, err
+	}
+	stored, err := t.store.base.Load(t.id(sessionID))
+	if err != nil {
+		return nil, err
+	}
+	if stored.Tenant != t.tenant {
+		return nil, ErrTranscriptNotFound
+	}
+	return t.open(stored, sessionID)
+}
+
+// open strips the namespace from a stored transcript and decrypts its events.
+// When the store has tenant keys, transcripts that are not encrypted are
+// rejected.
+func (t *tenantTranscripts) open(stored *Transcript, sessionID string) (*Transcript, error) {
+	stored.SessionID = sessionID
+	aead, err := t.store.aead(t.tenant)
+	if err != nil {
+		return nil, err
+	}
+	if stored.Sealed == nil {
+		// With tenant keys, every transcript is saved sealed, so a plaintext
+		// one was written around the store and is not trusted.
+		if aead != nil {
+			return nil, fmt.Errorf("transcript %s is not encrypted", sessionID)
+		}
+		return stored, nil
+	}
+	if aead == nil {
+		return nil, fmt.Errorf("transcript %s is encrypted but the store has no tenant keys", sessionID)
+	}
+	if len(stored.Sealed) < aead.NonceSize() {
+		return nil, fmt.Errorf("failed to decrypt transcript %s: sealed data is too short", sessionID)
+	}
+	nonce, ciphertext := stored.Sealed[:aead.NonceSize()], stored.Sealed[aead.NonceSize():]
+	events, err := aead.Open(nil, nonce, ciphertext, t.additionalData(sessionID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt transcript %s: %w", sessionID, err)
+	}
+	if err := json.Unmarshal(events, &stored.Events); err != nil {
+		return nil, fmt.Errorf("failed to decode transcript %s events: %w", sessionID, err)
+	}
+	stored.Sealed = nil
+	return stored, nil
+}
+
+func (t *tenantTranscripts) All() iter.Seq2[*Transcript, error] {
+	return func(yield func(*Transcript, error) bool) {
+		if err := validateTenant(t.tenant); err != nil {
+			yield(nil, err)
+			return
+		}
+		prefix := t.id("")
+		for stored, err := range t.store.base.All() {
+			if err != nil {
+				// The transcript may belong to another tenant. Report the
+				// failure without its details.
+				if !yield(nil, errors.New("failed to read a stored transcript")) {
+					return
+				}
+				continue
+			}
+			if stored.Tenant != t.tenant || !strings.HasPrefix(stored.SessionID, prefix) {
+				continue
+			}
+			if !yield(t.open(stored, strings.TrimPrefix(stored.SessionID, prefix))) {
+				return
+			}
+		}
+	}
+}
+
+func (t *tenantTranscripts) Delete(sessionID string) error {
+	if err := validateTenant(t.tenant); err != nil {
+		return err
+	}
+	return t.store.base.Delete(t.id(sessionID))
+}
@@ -0,0 +1,172 @@
+package copilot
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTenantTranscriptStore(t *testing.T) {
+	keys := map[string][]byte{
+		"acme":   bytes.Repeat([]byte{1}, 32),
+		"globex": bytes.Repeat([]byte{2}, 32),
+	}
+	keyFunc := TenantKeyFunc(func(tenant string) ([]byte, error) {
+		key, ok := keys[tenant]
+		if !ok {
+			return nil, errors.New("no key")
+		}
+		return key, nil
+	})
+	secret := "the secret plan"
+	transcript := func(id string, hour int) *Transcript {
+		return &Transcript{
+			SessionID: id,
+			CreatedAt: time.Date(2024, 5, 1, hour, 0, 0, 0, time.UTC),
+			Events:    []SessionEvent{{ID: "e1", Type: UserMessage, Data: Data{Content: &secret}}},
+		}
+	}
+
+	t.Run("isolates and encrypts each tenant's transcripts", func(t *testing.T) {
+		base := NewMemoryTranscriptStore()
+		store := NewTenantTranscriptStore(base, keyFunc)
+		acme, globex := store.ForTenant("acme"), store.ForTenant("globex")
+		if err := acme.Save(transcript("s1", 0)); err != nil {
+			t.Fatalf("Failed to save: %v", err)
+		}
+		globex.Save(transcript("s1", 1))
+		globex.Save(transcript("s2", 2))
+
+		loaded, err := acme.Load("s1")
+		if err != nil {
+			t.Fatalf("Failed to load: %v", err)
+		}
+		if loaded.SessionID != "s1" || loaded.Tenant != "acme" || len(loaded.Events) != 1 || *loaded.Events[0].Data.Content != secret {
+			t.Errorf("Unexpected transcript %+v", loaded)
+		}
+		if _, err := acme.Load("s2"); !errors.Is(err, ErrTranscriptNotFound) {
+			t.Errorf("Expected another tenant's session to be invisible, got %v", err)
+		}
+
+		for stored := range base.All() {
+			if len(stored.Events) != 0 || bytes.Contains(stored.Sealed, []byte(secret)) {
+				t.Errorf("Expected %s to be stored encrypted", stored.SessionID)
+			}
+		}
+
+		var ids []string
+		for transcript, err := range store.List("globex") {
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			ids = append(ids, transcript.SessionID)
+		}
+		if strings.Join(ids, ",") != "s1,s2" {
+			t.Errorf("Expected globex's transcripts, got %v", ids)
+		}
+
+		tenants, err := store.Tenants()
+		if err != nil || strings.Join(tenants, ",") != "acme,globex" {
+			t.Errorf("Expected both tenants, got %v, %v", tenants, err)
+		}
+	})
+
+	t.Run("rejects transcripts moved between tenants", func(t *testing.T) {
+		base := NewMemoryTranscriptStore()
+		store := NewTenantTranscriptStore(base, keyFunc)
+		store.ForTenant("acme").Save(transcript("s1", 0))
+
+		stolen, _ := base.Load("acme/s1")
+		stolen.SessionID, stolen.Tenant = "globex/s1", "globex"
+		base.Save(stolen)
+		if _, err := store.ForTenant("globex").Load("s1"); err == nil || !strings.Contains(err.Error(), "failed to decrypt") {
+			t.Errorf("Expected decryption to fail, got %v", err)
+		}
+	})
+
+	t.Run("rejects plaintext transcripts when the tenant has a key", func(t *testing.T) {
+		base := NewMemoryTranscriptStore()
+		store := NewTenantTranscriptStore(base, keyFunc)
+		injected := transcript("acme/s1", 0)
+		injected.Tenant = "acme"
+		base.Save(injected)
+
+		if _, err := store.ForTenant("acme").Load("s1"); err == nil || !strings.Contains(err.Error(), "not encrypted") {
+			t.Errorf("Expected the plaintext transcript to be rejected, got %v", err)
+		}
+		listed := 0
+		for _, err := range store.List("acme") {
+			listed++
+			if err == nil {
+				t.Error("Expected listing to reject the plaintext transcript")
+			}
+		}
+		if listed != 1 {
+			t.Errorf("Expected one result from listing, got %d", listed)
+		}
+	})
+
+	t.Run("deletes every transcript of a tenant", func(t *testing.T) {
+		base, _ := NewFileTranscriptStore(t.TempDir())
+		store := NewTenantTranscriptStore(base, keyFunc)
+		store.ForTenant("acme").Save(transcript("s1", 0))
+		store.ForTenant("acme").Save(transcript("s2", 1))
+		store.ForTenant("globex").Save(transcript("s1", 2))
+
+		n, err := store.DeleteTenant("acme")
+		if err != nil || n != 2 {
+			t.Fatalf("Expected two deleted transcripts, got %d, %v", n, err)
+		}
+		if _, err := store.ForTenant("acme").Load("s1"); !errors.Is(err, ErrTranscriptNotFound) {
+			t.Errorf("Expected acme's transcripts to be deleted, got %v", err)
+		}
+		if _, err := store.ForTenant("globex").Load("s1"); err != nil {
+			t.Errorf("Expected globex's transcript to remain, got %v", err)
+		}
+	})
+
+	t.Run("deletes past transcripts that cannot be read", func(t *testing.T) {
+		dir := t.TempDir()
+		base, _ := NewFileTranscriptStore(dir)
+		store := NewTenantTranscriptStore(base, keyFunc)
+		store.ForTenant("acme").Save(transcript("s1", 0))
+		store.ForTenant("acme").Save(transcript("s2", 1))
+		if err := os.WriteFile(filepath.Join(dir, "broken.json"), []byte("{"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+
+		n, err := store.DeleteTenant("acme")
+		if n != 2 || err == nil || !strings.Contains(err.Error(), "failed to list transcripts of tenant acme") {
+			t.Fatalf("Expected two deleted transcripts and the read error, got %d, %v", n, err)
+		}
+		for _, id := range []string{"s1", "s2"} {
+			if _, err := store.ForTenant("acme").Load(id); !errors.Is(err, ErrTranscriptNotFound) {
+				t.Errorf("Expected %s to be deleted, got %v", id, err)
+			}
+		}
+	})
+
+	t.Run("namespaces without encrypting when there are no keys", func(t *testing.T) {
+		base := NewMemoryTranscriptStore()
+		store := NewTenantTranscriptStore(base, nil)
+		store.ForTenant("acme").Save(transcript("s1", 0))
+		stored, err := base.Load("acme/s1")
+		if err != nil || len(stored.Events) != 1 || stored.Sealed != nil {
+			t.Errorf("Expected a plain namespaced transcript, got %+v, %v", stored, err)
+		}
+	})
+
+	t.Run("rejects invalid tenants and missing keys", func(t *testing.T) {
+		store := NewTenantTranscriptStore(NewMemoryTranscriptStore(), keyFunc)
+		if err := store.ForTenant("a/b").Save(transcript("s1", 0)); err == nil {
+			t.Error("Expected a tenant containing / to be rejected")
+		}
+		if err := store.ForTenant("initech").Save(transcript("s1", 0)); err == nil || !strings.Contains(err.Error(), "no key") {
+			t.Errorf("Expected the missing key to be reported, got %v", err)
+		}
+	})
+}
@@ -45,5 +45,8 @@ echo
 
 go test -v ./...
 
+# wasmplugin is a separate module, so that the SDK does not depend on wazero.
+(cd wasmplugin && go test -v ./...)
+
 echo
 echo "✅ All tests passed!"
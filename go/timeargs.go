@@ -0,0 +1,75 @@
+package copilot
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strconv"
+	"time"
+)
+
+var (
+	timeType     = reflect.TypeOf(time.Time{})
+	durationType = reflect.TypeOf(time.Duration(0))
+)
+
+// Formats of the schemas generated for time.Time and time.Duration values.
+const (
+	dateTimeFormat = "date-time"
+	durationFormat = "duration"
+)
+
+// durationDescription describes time.Duration fields that have no description
+// of their own, since models rarely guess the format from "duration" alone.
+const durationDescription = `A duration, such as "90s", "1h30m", or "PT1H30M".`
+
+var isoDuration = regexp.MustCompile(`^(-)?P(?:(\d+(?:\.\d+)?)D)?(?:T(?:(\d+(?:\.\d+)?)H)?(?:(\d+(?:\.\d+)?)M)?(?:(\d+(?:\.\d+)?)S)?)?$`)
+
+// parseDurationArgument parses a duration argument written as a Go duration,
+// such as "1h30m", or as an ISO 8601 duration of days, hours, minutes, and
+// seconds, such as "PT1H30M".
+func parseDurationArgument(s string) (time.Duration, error) {
+	if d, err := time.ParseDuration(s); err == nil {
+		return d, nil
+	}
+	match := isoDuration.FindStringSubmatch(s)
+	if match == nil || s == "P" || s == "-P" || s[len(s)-1] == 'T' {
+		return 0, fmt.Errorf("invalid duration %q", s)
+	}
+	var total float64
+	for i, unit := range []time.Duration{24 * time.Hour, time.Hour, time.Minute, time.Second} {
+		if match[i+2] == "" {
+			continue
+		}
+		n, err := strconv.ParseFloat(match[i+2], 64)
+		if err != nil {
+			return 0, fmt.Errorf("invalid duration %q", s)
+		}
+		total += n * float64(unit)
+	}
+	if match[1] == "-" {
+		total = -total
+	}
+	return time.Duration(total), nil
+}
+
+// decodeDuration decodes raw, a value decoded from JSON with numbers as
+// [json.Number], into a time.Duration. Strings are parsed with
+// parseDurationArgument; numbers are nanoseconds, as encoding/json decodes
+// them.
+func decodeDuration(raw interface{}) (time.Duration, error) {
+	switch value := raw.(type) {
+	case string:
+		return parseDurationArgument(value)
+	case json.Number:
+		n, err := value.Int64()
+		if err != nil {
+			return 0, fmt.Errorf("invalid duration %s", value)
+		}
+		return time.Duration(n), nil
+	case float64:
+		return time.Duration(value), nil
+	}
+	return 0, fmt.Errorf("cannot decode %s into time.Duration", jsonTypeName(raw))
+}
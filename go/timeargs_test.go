@@ -0,0 +1,144 @@
+package copilot
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+type scheduleParams struct {
+	Start    time.Time         `json:"start"`
+	End      *time.Time        `json:"end,omitempty"`
+	Timeout  time.Duration     `json:"timeout" default:"30s"`
+	Interval time.Duration     `json:"interval,omitempty" jsonschema:"how often to repeat"`
+	Labels   map[string]string `json:"labels,omitempty"`
+	Retries  map[int]int       `json:"retries,omitempty"`
+	Windows  map[string][]struct {
+		From time.Time `json:"from"`
+	} `json:"windows,omitempty"`
+}
+
+func TestTimeAndMapSchemas(t *testing.T) {
+	schema, err := schemaForType(reflect.TypeOf(scheduleParams{}))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	props := schema["properties"].(map[string]interface{})
+	property := func(name string) map[string]interface{} {
+		return props[name].(map[string]interface{})
+	}
+
+	if start := property("start"); start["type"] != "string" || start["format"] != "date-time" {
+		t.Errorf("Expected a date-time string, got %v", start)
+	}
+	if end := property("end"); end["format"] != "date-time" {
+		t.Errorf("Expected a date-time pointer, got %v", end)
+	}
+	timeout := property("timeout")
+	if timeout["type"] != "string" || timeout["format"] != "duration" || timeout["default"] != "30s" {
+		t.Errorf("Expected a duration string with a default, got %v", timeout)
+	}
+	if desc, _ := timeout["description"].(string); !strings.Contains(desc, "1h30m") {
+		t.Errorf("Expected a description of the duration format, got %q", desc)
+	}
+	if desc := property("interval")["description"]; desc != "how often to repeat" {
+		t.Errorf("Expected the field's own description, got %v", desc)
+	}
+
+	labels := property("labels")
+	if labels["additionalProperties"].(map[string]interface{})["type"] != "string" {
+		t.Errorf("Expected string values, got %v", labels)
+	}
+	retries := property("retries")
+	if retries["type"] != "object" || retries["additionalProperties"].(map[string]interface{})["type"] != "integer" {
+		t.Errorf("Expected an object of integers, got %v", retries)
+	}
+	if names, _ := retries["propertyNames"].(map[string]interface{}); names["pattern"] != "^-?[0-9]+$" {
+		t.Errorf("Expected integer property names, got %v", retries["propertyNames"])
+	}
+	from := property("windows")["additionalProperties"].(map[string]interface{})["items"].(map[string]interface{})["properties"].(map[string]interface{})["from"].(map[string]interface{})
+	if from["format"] != "date-time" {
+		t.Errorf("Expected nested date-time, got %v", from)
+	}
+}
+
+func TestTimeAndMapArguments(t *testing.T) {
+	var received scheduleParams
+	tool := DefineTool("schedule", "Schedule a job", func(params scheduleParams, inv ToolInvocation) (string, error) {
+		received = params
+		return "ok", nil
+	})
+	call := func(args map[string]interface{}) ToolResult {
+		t.Helper()
+		result, err := tool.Handler(ToolInvocation{ToolName: "schedule", Arguments: args})
+		if err != nil {
+			t.Fatalf("Handler returned error: %v", err)
+		}
+		return result
+	}
+
+	t.Run("decodes times, durations, and integer keys", func(t *testing.T) {
+		result := call(map[string]interface{}{
+			"start":    "2024-05-01T09:00:00Z",
+			"interval": "PT1H30M",
+			"retries":  map[string]interface{}{"3": 2},
+		})
+		if result.ResultType != "success" {
+			t.Fatalf("Expected success, got %+v", result)
+		}
+		if !received.Start.Equal(time.Date(2024, 5, 1, 9, 0, 0, 0, time.UTC)) {
+			t.Errorf("Unexpected start %v", received.Start)
+		}
+		if received.Timeout != 30*time.Second {
+			t.Errorf("Expected default timeout of 30s, got %v", received.Timeout)
+		}
+		if received.Interval != 90*time.Minute {
+			t.Errorf("Expected interval of 1h30m, got %v", received.Interval)
+		}
+		if received.Retries[3] != 2 {
+			t.Errorf("Expected retries keyed by int, got %v", received.Retries)
+		}
+	})
+
+	t.Run("rejects malformed values", func(t *testing.T) {
+		result := call(map[string]interface{}{
+			"start":   "May 1st",
+			"timeout": "soon",
+			"retries": map[string]interface{}{"three": 2},
+		})
+		if result.ResultType != "failure" {
+			t.Fatalf("Expected failure, got %+v", result)
+		}
+		for _, field := range []string{"start", "timeout", "retries.three"} {
+			if !strings.Contains(result.TextResultForLLM, field) {
+				t.Errorf("Expected an error for %s, got %q", field, result.TextResultForLLM)
+			}
+		}
+	})
+}
+
+func TestParseDurationArgument(t *testing.T) {
+	valid := map[string]time.Duration{
+		"90s":       90 * time.Second,
+		"1h30m":     90 * time.Minute,
+		"-5m":       -5 * time.Minute,
+		"PT1H30M":   90 * time.Minute,
+		"P1DT2H":    26 * time.Hour,
+		"PT0.5S":    500 * time.Millisecond,
+		"-PT10S":    -10 * time.Second,
+		"P2D":       48 * time.Hour,
+		"PT1M30.5S": 90*time.Second + 500*time.Millisecond,
+	}
+	for input, want := range valid {
+		got, err := parseDurationArgument(input)
+		if err != nil || got != want {
+			t.Errorf("parseDurationArgument(%q) = %v, %v; want %v", input, got, err, want)
+		}
+	}
+	for _, input := range []string{"", "P", "PT", "P1DT", "soon", "1 hour", "P1H"} {
+		if _, err := parseDurationArgument(input); err == nil {
+			t.Errorf("parseDurationArgument(%q): expected an error", input)
+		}
+	}
+}
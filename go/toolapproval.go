@@ -0,0 +1,132 @@
+package copilot
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// ToolApprovalRequest asks the host app whether a call of a tool that requires
+// approval may run. See [Tool.RequiresApproval].
+type ToolApprovalRequest struct {
+	SessionID  string
+	ToolCallID string
+	ToolName   string
+	Arguments  interface{}
+	// Context is the tool call's context. It is canceled if the turn is aborted
+	// while the app is deciding.
+	Context context.Context
+}
+
+// ToolApproval is the host app's decision on a [ToolApprovalRequest].
+type ToolApproval struct {
+	Approved bool
+	// Reason, if set, tells the model why the call was denied.
+	Reason string
+}
+
+// ToolApprovalHandler decides whether a tool call may run, for example by
+// asking the user. Returning an error denies the call. See
+// [SessionConfig.OnToolApproval].
+type ToolApprovalHandler func(request ToolApprovalRequest) (ToolApproval, error)
+
+// WithApprovalRequired makes calls of the tool wait for the session's
+// [ToolApprovalHandler] before running. See [Tool.RequiresApproval].
+//
+// Example:
+//
+//	tool := copilot.DefineTool("delete_branch", "Delete a git branch", deleteBranch,
+//	    copilot.WithApprovalRequired())
+func WithApprovalRequired() ToolOption {
+	return func(o *toolOptions) {
+		o.requiresApproval = true
+	}
+}
+
+// WithApprovalPolicy makes calls of the tool for which policy returns true
+// wait for the session's [ToolApprovalHandler] before running. See
+// [Tool.ApprovalPolicy].
+//
+// Example:
+//
+//	tool := copilot.DefineTool("deploy", "Deploy a service", deploy,
+//	    copilot.WithApprovalPolicy(func(inv copilot.ToolInvocation) bool {
+//	        args, _ := inv.Arguments.(map[string]interface{})
+//	        return args["environment"] == "production"
+//	    }))
+func WithApprovalPolicy(policy func(inv ToolInvocation) bool) ToolOption {
+	return func(o *toolOptions) {
+		o.approvalPolicy = policy
+	}
+}
+
+func (s *Session) registerApprovalHandler(handler ToolApprovalHandler) {
+	s.toolHandlersM.Lock()
+	defer s.toolHandlersM.Unlock()
+	s.approvalHandler = handler
+}
+
+// withApproval wraps handler so that calls the tool's approval settings cover
+// run only once the session's approval handler approves them.
+func (s *Session) withApproval(tool Tool, handler ToolHandler) ToolHandler {
+	if !tool.RequiresApproval && tool.ApprovalPolicy == nil {
+		return handler
+	}
+	requiresApproval, policy := tool.RequiresApproval, tool.ApprovalPolicy
+	return func(inv ToolInvocation) (ToolResult, error) {
+		needsApproval := requiresApproval
+		if policy != nil {
+			needsApproval = policy(inv)
+		}
+		if !needsApproval {
+			return handler(inv)
+		}
+
+		s.toolHandlersM.RLock()
+		approve := s.approvalHandler
+		s.toolHandlersM.RUnlock()
+
+		approval := ToolApproval{Reason: "no approval handler is configured"}
+		if approve != nil {
+			var err error
+			approval, err = approve(ToolApprovalRequest{
+				SessionID:  inv.SessionID,
+				ToolCallID: inv.ToolCallID,
+				ToolName:   inv.ToolName,
+				Arguments:  inv.Arguments,
+				Context:    inv.Context(),
+			})
+			if err != nil {
+				approval = ToolApproval{Reason: err.Error()}
+			}
+		}
+		if approval.Approved && inv.Context().Err() == nil {
+			return handler(inv)
+		}
+		if approval.Approved {
+			approval = ToolApproval{Reason: "the turn was aborted"}
+		}
+
+		s.emit(SDKToolDenied, map[string]interface{}{
+			"toolCallId": inv.ToolCallID,
+			"toolName":   inv.ToolName,
+			"reason":     approval.Reason,
+		})
+		return buildDeniedToolResult(inv.ToolName, approval.Reason), nil
+	}
+}
+
+// buildDeniedToolResult creates a denied ToolResult for a tool call the host
+// app did not approve.
+func buildDeniedToolResult(toolName, reason string) ToolResult {
+	text := fmt.Sprintf("The user did not approve running tool '%s'.", toolName)
+	if reason != "" {
+		text = fmt.Sprintf("The user did not approve running tool '%s': %s.", toolName, strings.TrimRight(reason, "."))
+	}
+	return ToolResult{
+		TextResultForLLM: text + " Do not retry it unless the user asks.",
+		ResultType:       "denied",
+		Error:            fmt.Sprintf("tool '%s' was not approved", toolName),
+		ToolTelemetry:    map[string]interface{}{"approved": false},
+	}
+}
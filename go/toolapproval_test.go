@@ -0,0 +1,98 @@
+package copilot
+
+import (
+	"errors"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+func TestToolApproval(t *testing.T) {
+	// newApprovalSession returns a session with a deploy tool that counts its
+	// runs, and the sdk.tool_denied events it dispatches.
+	newApprovalSession := func(tool Tool, approve ToolApprovalHandler) (*Session, *atomic.Int32, *[]SessionEvent) {
+		var runs atomic.Int32
+		var denied []SessionEvent
+		tool.Name = "deploy"
+		tool.Handler = func(inv ToolInvocation) (ToolResult, error) {
+			runs.Add(1)
+			return ToolResult{TextResultForLLM: "deployed", ResultType: "success"}, nil
+		}
+		session := NewSession("s1", nil, "")
+		session.On(func(event SessionEvent) {
+			if event.Type == SDKToolDenied {
+				denied = append(denied, event)
+			}
+		})
+		session.registerApprovalHandler(approve)
+		session.registerTools([]Tool{tool})
+		return session, &runs, &denied
+	}
+	call := func(session *Session, args map[string]interface{}) ToolResult {
+		handler, _ := session.getToolHandler("deploy")
+		result, _ := handler(ToolInvocation{SessionID: "s1", ToolCallID: "call-1", ToolName: "deploy", Arguments: args})
+		return result
+	}
+
+	t.Run("runs approved calls", func(t *testing.T) {
+		var requests []ToolApprovalRequest
+		session, runs, _ := newApprovalSession(Tool{RequiresApproval: true}, func(request ToolApprovalRequest) (ToolApproval, error) {
+			requests = append(requests, request)
+			return ToolApproval{Approved: true}, nil
+		})
+		result := call(session, map[string]interface{}{"environment": "prod"})
+		if result.ResultType != "success" || runs.Load() != 1 {
+			t.Errorf("Unexpected result %+v", result)
+		}
+		if len(requests) != 1 || requests[0].ToolName != "deploy" || requests[0].ToolCallID != "call-1" || requests[0].Context == nil {
+			t.Errorf("Unexpected requests %+v", requests)
+		}
+	})
+
+	t.Run("denies rejected calls", func(t *testing.T) {
+		session, runs, denied := newApprovalSession(Tool{RequiresApproval: true}, func(request ToolApprovalRequest) (ToolApproval, error) {
+			return ToolApproval{Reason: "deploys are frozen this week"}, nil
+		})
+		result := call(session, nil)
+		if result.ResultType != "denied" || runs.Load() != 0 || !strings.Contains(result.TextResultForLLM, "deploys are frozen this week") {
+			t.Errorf("Unexpected result %+v", result)
+		}
+		if len(*denied) != 1 || sdkEventString((*denied)[0], "reason") != "deploys are frozen this week" {
+			t.Errorf("Unexpected events %+v", *denied)
+		}
+	})
+
+	t.Run("denies calls when the handler fails or is missing", func(t *testing.T) {
+		session, runs, _ := newApprovalSession(Tool{RequiresApproval: true}, func(request ToolApprovalRequest) (ToolApproval, error) {
+			return ToolApproval{Approved: true}, errors.New("approval service unavailable")
+		})
+		if result := call(session, nil); result.ResultType != "denied" || runs.Load() != 0 {
+			t.Errorf("Unexpected result %+v", result)
+		}
+		session, runs, _ = newApprovalSession(Tool{RequiresApproval: true}, nil)
+		if result := call(session, nil); result.ResultType != "denied" || runs.Load() != 0 {
+			t.Errorf("Unexpected result %+v", result)
+		}
+	})
+
+	t.Run("asks only when the policy says so", func(t *testing.T) {
+		var asked atomic.Int32
+		tool := DefineTool("deploy", "Deploy", func(params struct {
+			Environment string `json:"environment"`
+		}, inv ToolInvocation) (string, error) {
+			return "deployed", nil
+		}, WithApprovalPolicy(func(inv ToolInvocation) bool {
+			args, _ := inv.Arguments.(map[string]interface{})
+			return args["environment"] == "production"
+		}))
+		session, runs, _ := newApprovalSession(tool, func(request ToolApprovalRequest) (ToolApproval, error) {
+			asked.Add(1)
+			return ToolApproval{}, nil
+		})
+		call(session, map[string]interface{}{"environment": "staging"})
+		call(session, map[string]interface{}{"environment": "production"})
+		if asked.Load() != 1 || runs.Load() != 1 {
+			t.Errorf("Expected one approval request and one run, got %d and %d", asked.Load(), runs.Load())
+		}
+	})
+}
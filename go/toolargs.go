@@ -0,0 +1,179 @@
+package copilot
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+)
+
+// StringArg returns the string argument name, for handlers written without
+// [DefineTool]. It fails if the argument is missing, null, or not a string.
+//
+// StringArg and the other argument accessors fail with an
+// [*InvalidArgumentsError] naming the argument, which [RenderToolError]
+// renders for the model.
+//
+// Example:
+//
+//	handler := func(inv copilot.ToolInvocation) (copilot.ToolResult, error) {
+//	    city, err := inv.StringArg("city")
+//	    if err != nil {
+//	        return copilot.ToolResult{}, err
+//	    }
+//	    days := 3
+//	    if inv.HasArg("days") {
+//	        if days, err = inv.IntArg("days"); err != nil {
+//	            return copilot.ToolResult{}, err
+//	        }
+//	    }
+//	    ...
+//	}
+func (inv ToolInvocation) StringArg(name string) (string, error) {
+	value, err := inv.arg(name)
+	if err != nil {
+		return "", err
+	}
+	s, ok := value.(string)
+	if !ok {
+		return "", inv.argError(name, "must be a string, got %s", jsonTypeName(value))
+	}
+	return s, nil
+}
+
+// IntArg returns the integer argument name. It fails if the argument is
+// missing, null, or not a whole number that fits in an int.
+func (inv ToolInvocation) IntArg(name string) (int, error) {
+	value, err := inv.arg(name)
+	if err != nil {
+		return 0, err
+	}
+	switch n := value.(type) {
+	case int:
+		return n, nil
+	case int64:
+		if n >= math.MinInt && n <= math.MaxInt {
+			return int(n), nil
+		}
+	case float64:
+		if n == math.Trunc(n) && n >= math.MinInt && n <= math.MaxInt {
+			return int(n), nil
+		}
+	case json.Number:
+		if i, err := n.Int64(); err == nil && i >= math.MinInt && i <= math.MaxInt {
+			return int(i), nil
+		}
+	default:
+		return 0, inv.argError(name, "must be an integer, got %s", jsonTypeName(value))
+	}
+	return 0, inv.argError(name, "must be an integer that fits in an int, got %v", value)
+}
+
+// FloatArg returns the numeric argument name. It fails if the argument is
+// missing, null, or not a number.
+func (inv ToolInvocation) FloatArg(name string) (float64, error) {
+	value, err := inv.arg(name)
+	if err != nil {
+		return 0, err
+	}
+	switch n := value.(type) {
+	case float64:
+		return n, nil
+	case int:
+		return float64(n), nil
+	case int64:
+		return float64(n), nil
+	case json.Number:
+		if f, err := n.Float64(); err == nil {
+			return f, nil
+		}
+	}
+	return 0, inv.argError(name, "must be a number, got %s", jsonTypeName(value))
+}
+
+// BoolArg returns the boolean argument name. It fails if the argument is
+// missing, null, or not a boolean.
+func (inv ToolInvocation) BoolArg(name string) (bool, error) {
+	value, err := inv.arg(name)
+	if err != nil {
+		return false, err
+	}
+	b, ok := value.(bool)
+	if !ok {
+		return false, inv.argError(name, "must be a boolean, got %s", jsonTypeName(value))
+	}
+	return b, nil
+}
+
+// HasArg reports whether the argument name is present and not null, for
+// optional arguments.
+func (inv ToolInvocation) HasArg(name string) bool {
+	args, err := inv.argsObject()
+	return err == nil && args[name] != nil
+}
+
+// Decode decodes the arguments into v, which must be a pointer, the way
+// [DefineTool] decodes them into a handler's parameters, including
+// [Union] fields.
+//
+// Example:
+//
+//	var params struct {
+//	    City string `json:"city"`
+//	    Days int    `json:"days"`
+//	}
+//	if err := inv.Decode(&params); err != nil {
+//	    return copilot.ToolResult{}, err
+//	}
+func (inv ToolInvocation) Decode(v interface{}) error {
+	if rv := reflect.ValueOf(v); rv.Kind() != reflect.Pointer || rv.IsNil() {
+		return fmt.Errorf("failed to decode arguments: %T is not a non-nil pointer", v)
+	}
+	data, err := json.Marshal(inv.Arguments)
+	if err != nil {
+		return fmt.Errorf("failed to marshal arguments: %w", err)
+	}
+	if err := decodeArguments(data, v); err != nil {
+		return fmt.Errorf("failed to unmarshal arguments into %T: %w", v, err)
+	}
+	return nil
+}
+
+// arg returns the argument name, failing if it is missing or null.
+func (inv ToolInvocation) arg(name string) (interface{}, error) {
+	args, err := inv.argsObject()
+	if err != nil {
+		return nil, err
+	}
+	value := args[name]
+	if value == nil {
+		return nil, inv.argError(name, "is required")
+	}
+	return value, nil
+}
+
+// argsObject returns the arguments as a JSON object.
+func (inv ToolInvocation) argsObject() (map[string]interface{}, error) {
+	switch args := inv.Arguments.(type) {
+	case map[string]interface{}:
+		return args, nil
+	case nil:
+		return map[string]interface{}{}, nil
+	}
+	data, err := json.Marshal(inv.Arguments)
+	if err != nil {
+		return nil, inv.argError("", "cannot be encoded as JSON: %v", err)
+	}
+	var args map[string]interface{}
+	if err := json.Unmarshal(data, &args); err != nil || args == nil {
+		return nil, inv.argError("", "must be an object, got %s", jsonTypeName(inv.Arguments))
+	}
+	return args, nil
+}
+
+func (inv ToolInvocation) argError(field, format string, args ...interface{}) error {
+	return &InvalidArgumentsError{
+		ToolName: inv.ToolName,
+		Errors:   []ArgumentError{{Field: field, Message: fmt.Sprintf(format, args...)}},
+	}
+}
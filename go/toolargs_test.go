@@ -0,0 +1,80 @@
+package copilot
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestToolInvocationArgs(t *testing.T) {
+	inv := ToolInvocation{ToolName: "forecast", Arguments: map[string]interface{}{
+		"city":    "Berlin",
+		"days":    float64(3),
+		"ratio":   0.5,
+		"metric":  true,
+		"count":   json.Number("7"),
+		"partial": 2.5,
+		"note":    nil,
+	}}
+
+	t.Run("returns typed arguments", func(t *testing.T) {
+		city, err := inv.StringArg("city")
+		if err != nil || city != "Berlin" {
+			t.Errorf("StringArg = %q, %v", city, err)
+		}
+		days, err := inv.IntArg("days")
+		if err != nil || days != 3 {
+			t.Errorf("IntArg = %d, %v", days, err)
+		}
+		if count, err := inv.IntArg("count"); err != nil || count != 7 {
+			t.Errorf("IntArg of a json.Number = %d, %v", count, err)
+		}
+		if ratio, err := inv.FloatArg("ratio"); err != nil || ratio != 0.5 {
+			t.Errorf("FloatArg = %v, %v", ratio, err)
+		}
+		if metric, err := inv.BoolArg("metric"); err != nil || !metric {
+			t.Errorf("BoolArg = %v, %v", metric, err)
+		}
+		if !inv.HasArg("city") || inv.HasArg("note") || inv.HasArg("units") {
+			t.Error("Expected HasArg to report only present, non-null arguments")
+		}
+	})
+
+	t.Run("reports missing and mistyped arguments", func(t *testing.T) {
+		cases := map[string]error{
+			"note: is required":                    func() error { _, err := inv.StringArg("note"); return err }(),
+			"units: is required":                   func() error { _, err := inv.StringArg("units"); return err }(),
+			"city: must be an integer, got string": func() error { _, err := inv.IntArg("city"); return err }(),
+			"partial: must be an integer":          func() error { _, err := inv.IntArg("partial"); return err }(),
+			"days: must be a boolean, got integer": func() error { _, err := inv.BoolArg("days"); return err }(),
+			"metric: must be a string":             func() error { _, err := inv.StringArg("metric"); return err }(),
+		}
+		for want, err := range cases {
+			var argErr *InvalidArgumentsError
+			if !errors.As(err, &argErr) || argErr.ToolName != "forecast" || !strings.Contains(err.Error(), want) {
+				t.Errorf("Expected an error containing %q, got %v", want, err)
+			}
+		}
+	})
+
+	t.Run("decodes arguments into a struct", func(t *testing.T) {
+		var params struct {
+			City string `json:"city"`
+			Days int    `json:"days"`
+		}
+		if err := inv.Decode(&params); err != nil || params.City != "Berlin" || params.Days != 3 {
+			t.Errorf("Decode = %+v, %v", params, err)
+		}
+		if err := inv.Decode(params); err == nil {
+			t.Error("Expected decoding into a non-pointer to fail")
+		}
+	})
+
+	t.Run("rejects arguments that are not an object", func(t *testing.T) {
+		inv := ToolInvocation{Arguments: []interface{}{"Berlin"}}
+		if _, err := inv.StringArg("city"); err == nil || !strings.Contains(err.Error(), "must be an object, got array") {
+			t.Errorf("Unexpected error %v", err)
+		}
+	})
+}
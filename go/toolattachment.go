@@ -0,0 +1,114 @@
+package copilot
+
+import (
+	"encoding/base64"
+	"fmt"
+	"mime"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ToolResultAttachment is a non-text artifact returned by a tool, such as a
+// screenshot, a generated chart, or a file. Attachments are sent to the agent
+// base64-encoded in [ToolResult.BinaryResultsForLLM].
+//
+// A [DefineTool] handler can return a ToolResultAttachment or a slice of them
+// directly, or a [ToolResult] with Attachments to include text as well.
+type ToolResultAttachment struct {
+	// Data is the raw content.
+	Data []byte
+	// MimeType is the content's media type, such as "image/png". If empty, it
+	// is detected from Name's extension, then from Data.
+	MimeType string
+	// Name is an optional file name, used to describe the attachment.
+	Name string
+	// Description is an optional description of the attachment for the model.
+	Description string
+}
+
+// AttachmentFromFile reads the file at path into an attachment named after the
+// file.
+func AttachmentFromFile(path string) (ToolResultAttachment, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return ToolResultAttachment{}, fmt.Errorf("failed to read attachment: %w", err)
+	}
+	return ToolResultAttachment{Data: data, Name: filepath.Base(path)}, nil
+}
+
+// AttachmentFromBase64 decodes standard base64 data, such as an image returned
+// by another API, into an attachment.
+func AttachmentFromBase64(encoded, mimeType string) (ToolResultAttachment, error) {
+	data, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return ToolResultAttachment{}, fmt.Errorf("failed to decode attachment: %w", err)
+	}
+	return ToolResultAttachment{Data: data, MimeType: mimeType}, nil
+}
+
+// mimeType returns the attachment's media type, detecting it if unset.
+func (a ToolResultAttachment) mimeType() string {
+	if a.MimeType != "" {
+		return a.MimeType
+	}
+	if byExtension := mime.TypeByExtension(filepath.Ext(a.Name)); byExtension != "" {
+		return byExtension
+	}
+	return detectContentType(a.Data)
+}
+
+// binaryResult encodes the attachment as the server expects it.
+func (a ToolResultAttachment) binaryResult() ToolBinaryResult {
+	mimeType := a.mimeType()
+	description := a.Description
+	if description == "" {
+		description = a.Name
+	}
+	return ToolBinaryResult{
+		Data:        base64.StdEncoding.EncodeToString(a.Data),
+		MimeType:    mimeType,
+		Type:        attachmentType(mimeType),
+		Description: description,
+	}
+}
+
+// attachmentType is the kind of binary result for a media type: "image",
+// "audio", or "resource" for anything else.
+func attachmentType(mimeType string) string {
+	switch {
+	case strings.HasPrefix(mimeType, "image/"):
+		return "image"
+	case strings.HasPrefix(mimeType, "audio/"):
+		return "audio"
+	default:
+		return "resource"
+	}
+}
+
+// encodeAttachments moves the result's attachments into BinaryResultsForLLM.
+// A result with attachments but no text gets a text listing them, for models
+// that cannot read binary results.
+func encodeAttachments(result ToolResult) ToolResult {
+	if len(result.Attachments) == 0 {
+		return result
+	}
+	binary := make([]ToolBinaryResult, 0, len(result.BinaryResultsForLLM)+len(result.Attachments))
+	binary = append(binary, result.BinaryResultsForLLM...)
+	var listing []string
+	for _, attachment := range result.Attachments {
+		encoded := attachment.binaryResult()
+		binary = append(binary, encoded)
+		label := encoded.Type
+		if attachment.Name != "" {
+			label += " " + attachment.Name
+		}
+		listing = append(listing, fmt.Sprintf("%s (%s, %d bytes)", label, encoded.MimeType, len(attachment.Data)))
+	}
+	result.BinaryResultsForLLM = binary
+	result.Attachments = nil
+	if result.TextResultForLLM == "" {
+		result.TextResultForLLM = "Returned " + strings.Join(listing, ", ") + "."
+	}
+	return result
+}
@@ -0,0 +1,94 @@
+package copilot
+
+import (
+	"context"
+	"encoding/base64"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestToolResultAttachment(t *testing.T) {
+	t.Run("detects media types", func(t *testing.T) {
+		png := []byte("\x89PNG\r\n\x1a\n0000")
+		tests := []struct {
+			attachment ToolResultAttachment
+			mimeType   string
+			kind       string
+		}{
+			{ToolResultAttachment{Data: []byte("x"), MimeType: "audio/wav"}, "audio/wav", "audio"},
+			{ToolResultAttachment{Data: []byte("a,b"), Name: "chart.svg"}, "image/svg+xml", "image"},
+			{ToolResultAttachment{Data: png}, "image/png", "image"},
+			{ToolResultAttachment{Data: []byte("plain text")}, "text/plain; charset=utf-8", "resource"},
+		}
+		for _, tt := range tests {
+			encoded := tt.attachment.binaryResult()
+			if encoded.MimeType != tt.mimeType || encoded.Type != tt.kind {
+				t.Errorf("Expected %s %s, got %s %s", tt.kind, tt.mimeType, encoded.Type, encoded.MimeType)
+			}
+		}
+	})
+
+	t.Run("from file", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "report.pdf")
+		if err := os.WriteFile(path, []byte("%PDF-1.7"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		attachment, err := AttachmentFromFile(path)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if attachment.Name != "report.pdf" || string(attachment.Data) != "%PDF-1.7" {
+			t.Errorf("Unexpected attachment %+v", attachment)
+		}
+		if _, err := AttachmentFromFile(filepath.Join(t.TempDir(), "missing")); err == nil {
+			t.Error("Expected an error for a missing file")
+		}
+	})
+
+	t.Run("from base64", func(t *testing.T) {
+		attachment, err := AttachmentFromBase64(base64.StdEncoding.EncodeToString([]byte("image")), "image/jpeg")
+		if err != nil || string(attachment.Data) != "image" || attachment.MimeType != "image/jpeg" {
+			t.Errorf("Unexpected attachment %+v, %v", attachment, err)
+		}
+		if _, err := AttachmentFromBase64("not base64!", "image/jpeg"); err == nil {
+			t.Error("Expected an error for invalid base64")
+		}
+	})
+}
+
+func TestExecuteToolCall_Attachments(t *testing.T) {
+	type ChartParams struct {
+		Title string `json:"title"`
+	}
+	tool := DefineTool("render_chart", "Render a chart", func(params ChartParams, inv ToolInvocation) (ToolResultAttachment, error) {
+		return ToolResultAttachment{Data: []byte("<svg/>"), Name: params.Title + ".svg"}, nil
+	})
+
+	client := &Client{}
+	result := client.executeToolCall(context.Background(), "s1", "call-1", "render_chart", map[string]interface{}{"title": "sales"}, tool.Handler)
+
+	if len(result.Attachments) != 0 || len(result.BinaryResultsForLLM) != 1 {
+		t.Fatalf("Expected the attachment to be encoded, got %+v", result)
+	}
+	binary := result.BinaryResultsForLLM[0]
+	if binary.Data != base64.StdEncoding.EncodeToString([]byte("<svg/>")) || binary.MimeType != "image/svg+xml" || binary.Type != "image" || binary.Description != "sales.svg" {
+		t.Errorf("Unexpected binary result %+v", binary)
+	}
+	if !strings.Contains(result.TextResultForLLM, "image sales.svg (image/svg+xml, 6 bytes)") {
+		t.Errorf("Expected a text listing of the attachment, got %q", result.TextResultForLLM)
+	}
+
+	t.Run("keeps text and existing binary results", func(t *testing.T) {
+		existing := ToolBinaryResult{Data: "AA==", MimeType: "image/png", Type: "image"}
+		result := encodeAttachments(ToolResult{
+			TextResultForLLM:    "Here is the chart",
+			BinaryResultsForLLM: []ToolBinaryResult{existing},
+			Attachments:         []ToolResultAttachment{{Data: []byte("x"), MimeType: "image/gif"}},
+		})
+		if result.TextResultForLLM != "Here is the chart" || len(result.BinaryResultsForLLM) != 2 || result.BinaryResultsForLLM[0] != existing {
+			t.Errorf("Unexpected result %+v", result)
+		}
+	})
+}
@@ -0,0 +1,187 @@
+package copilot
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Default limits of a [ToolBudgetConfig].
+const (
+	// DefaultMaxToolCatalogBytes is the default limit on the serialized size of
+	// all tool definitions advertised to the model.
+	DefaultMaxToolCatalogBytes = 32 * 1024
+	// DefaultMaxToolBytes is the default limit on the serialized size of a
+	// single tool definition.
+	DefaultMaxToolBytes = 4 * 1024
+)
+
+// ToolSize is the serialized size of one advertised tool definition.
+type ToolSize struct {
+	Name string
+	// Bytes is the size of the whole definition.
+	Bytes int
+	// DescriptionBytes is the size of the encoded description.
+	DescriptionBytes int
+	// ParametersBytes is the size of the encoded parameters schema.
+	ParametersBytes int
+}
+
+// ToolCatalogSize is the serialized size of the tool definitions advertised to
+// the model when a session is created or resumed.
+type ToolCatalogSize struct {
+	// Bytes is the size of all definitions, as sent to the server.
+	Bytes int
+	// Tools are the sizes of the individual definitions, largest first.
+	Tools []ToolSize
+}
+
+// MeasureTools returns the serialized size of the definitions advertised for
+// tools. Tools without a name are not advertised and are skipped.
+func MeasureTools(tools []Tool) (ToolCatalogSize, error) {
+	definitions := buildToolDefinitions(tools)
+	data, err := json.Marshal(definitions)
+	if err != nil {
+		return ToolCatalogSize{}, fmt.Errorf("failed to encode tool definitions: %w", err)
+	}
+
+	size := ToolCatalogSize{Bytes: len(data), Tools: make([]ToolSize, 0, len(definitions))}
+	for _, definition := range definitions {
+		name := definition["name"].(string)
+		toolSize := ToolSize{Name: name}
+		var err error
+		if toolSize.Bytes, err = encodedSize(definition); err != nil {
+			return ToolCatalogSize{}, fmt.Errorf("failed to encode tool %s: %w", name, err)
+		}
+		toolSize.DescriptionBytes, _ = encodedSize(definition["description"])
+		if parameters, ok := definition["parameters"]; ok {
+			toolSize.ParametersBytes, _ = encodedSize(parameters)
+		}
+		size.Tools = append(size.Tools, toolSize)
+	}
+	sort.SliceStable(size.Tools, func(i, j int) bool {
+		return size.Tools[i].Bytes > size.Tools[j].Bytes
+	})
+	return size, nil
+}
+
+func encodedSize(v interface{}) (int, error) {
+	data, err := json.Marshal(v)
+	return len(data), err
+}
+
+// ToolBudgetConfig warns when the advertised tools grow large enough to
+// degrade the model's tool selection. Oversized catalogs are otherwise
+// accepted silently.
+type ToolBudgetConfig struct {
+	// MaxCatalogBytes limits the size of all definitions. Defaults to
+	// [DefaultMaxToolCatalogBytes].
+	MaxCatalogBytes int
+	// MaxToolBytes limits the size of each definition. Defaults to
+	// [DefaultMaxToolBytes].
+	MaxToolBytes int
+	// OnWarning is called for each limit that is exceeded when the session is
+	// created or resumed.
+	OnWarning func(warning ToolBudgetWarning)
+}
+
+// ToolBudgetWarning reports a tool definition, or the whole catalog, that
+// exceeds its limit.
+type ToolBudgetWarning struct {
+	// ToolName is the oversized tool, or empty if the whole catalog exceeds
+	// MaxCatalogBytes.
+	ToolName string
+	Bytes    int
+	Limit    int
+	// Candidates are the tools to trim first. For a catalog warning they are the
+	// largest tools, enough of them that removing them would bring the catalog
+	// within its limit.
+	Candidates []string
+	// Suggestion describes how to get within the limit.
+	Suggestion string
+}
+
+func (w ToolBudgetWarning) String() string {
+	subject := "tool catalog"
+	if w.ToolName != "" {
+		subject = "tool " + w.ToolName
+	}
+	return fmt.Sprintf("%s is %d bytes, over its %d byte budget: %s", subject, w.Bytes, w.Limit, w.Suggestion)
+}
+
+// Check returns the limits size exceeds: one warning per oversized tool, then
+// one for the catalog if it is oversized.
+func (c *ToolBudgetConfig) Check(size ToolCatalogSize) []ToolBudgetWarning {
+	maxCatalog, maxTool := DefaultMaxToolCatalogBytes, DefaultMaxToolBytes
+	if c != nil && c.MaxCatalogBytes > 0 {
+		maxCatalog = c.MaxCatalogBytes
+	}
+	if c != nil && c.MaxToolBytes > 0 {
+		maxTool = c.MaxToolBytes
+	}
+
+	var warnings []ToolBudgetWarning
+	for _, tool := range size.Tools {
+		if tool.Bytes <= maxTool {
+			continue
+		}
+		suggestion := fmt.Sprintf("simplify its parameters schema (%d bytes)", tool.ParametersBytes)
+		if tool.DescriptionBytes > tool.ParametersBytes {
+			suggestion = fmt.Sprintf("shorten its description (%d bytes)", tool.DescriptionBytes)
+		}
+		warnings = append(warnings, ToolBudgetWarning{
+			ToolName:   tool.Name,
+			Bytes:      tool.Bytes,
+			Limit:      maxTool,
+			Candidates: []string{tool.Name},
+			Suggestion: suggestion,
+		})
+	}
+
+	if size.Bytes > maxCatalog {
+		var candidates []string
+		remaining := size.Bytes
+		for _, tool := range size.Tools {
+			if remaining <= maxCatalog {
+				break
+			}
+			candidates = append(candidates, tool.Name)
+			remaining -= tool.Bytes
+		}
+		warnings = append(warnings, ToolBudgetWarning{
+			Bytes:      size.Bytes,
+			Limit:      maxCatalog,
+			Candidates: candidates,
+			Suggestion: "trim or remove the largest tools: " + strings.Join(candidates, ", "),
+		})
+	}
+	return warnings
+}
+
+// ToolCatalogSize returns the serialized size of the tool definitions
+// advertised when the session was created or resumed, or last updated by a
+// [ToolRegistry].
+func (s *Session) ToolCatalogSize() ToolCatalogSize {
+	s.toolHandlersM.RLock()
+	defer s.toolHandlersM.RUnlock()
+	return s.toolCatalogSize
+}
+
+func (s *Session) registerToolBudget(config *ToolBudgetConfig, tools []Tool) {
+	size, err := MeasureTools(tools)
+	if err != nil {
+		// The definitions were already sent to the server, so this is not
+		// expected; leave the size unknown.
+		return
+	}
+	s.toolHandlersM.Lock()
+	s.toolCatalogSize = size
+	s.toolHandlersM.Unlock()
+	if config == nil || config.OnWarning == nil {
+		return
+	}
+	for _, warning := range config.Check(size) {
+		config.OnWarning(warning)
+	}
+}
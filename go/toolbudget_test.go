@@ -0,0 +1,115 @@
+package copilot
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestMeasureTools(t *testing.T) {
+	type SearchParams struct {
+		Query string `json:"query" jsonschema:"Search query"`
+	}
+	tools := []Tool{
+		{Name: "small", Description: "Small tool"},
+		DefineTool("search", "Search the web for pages that match a query", func(params SearchParams, inv ToolInvocation) (string, error) {
+			return "", nil
+		}),
+		{Description: "Not advertised"},
+	}
+
+	size, err := MeasureTools(tools)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	advertised, _ := json.Marshal(buildToolDefinitions(tools))
+	if size.Bytes != len(advertised) {
+		t.Errorf("Expected catalog size %d, got %d", len(advertised), size.Bytes)
+	}
+	if len(size.Tools) != 2 || size.Tools[0].Name != "search" || size.Tools[1].Name != "small" {
+		t.Fatalf("Expected tools largest first, got %+v", size.Tools)
+	}
+
+	search := size.Tools[0]
+	parameters, _ := json.Marshal(tools[1].Parameters)
+	if search.ParametersBytes != len(parameters) {
+		t.Errorf("Expected parameters size %d, got %d", len(parameters), search.ParametersBytes)
+	}
+	if want := len(`"Search the web for pages that match a query"`); search.DescriptionBytes != want {
+		t.Errorf("Expected description size %d, got %d", want, search.DescriptionBytes)
+	}
+	if size.Tools[1].ParametersBytes != 0 {
+		t.Errorf("Expected no parameters size for a tool without parameters, got %d", size.Tools[1].ParametersBytes)
+	}
+}
+
+func TestToolBudgetConfig_Check(t *testing.T) {
+	size := ToolCatalogSize{
+		Bytes: 1000,
+		Tools: []ToolSize{
+			{Name: "deploy", Bytes: 500, DescriptionBytes: 400, ParametersBytes: 80},
+			{Name: "search", Bytes: 300, DescriptionBytes: 50, ParametersBytes: 230},
+			{Name: "echo", Bytes: 200, DescriptionBytes: 20, ParametersBytes: 160},
+		},
+	}
+
+	t.Run("within budget", func(t *testing.T) {
+		var config *ToolBudgetConfig
+		if warnings := config.Check(size); len(warnings) != 0 {
+			t.Errorf("Expected no warnings with the defaults, got %+v", warnings)
+		}
+	})
+
+	t.Run("over budget", func(t *testing.T) {
+		config := &ToolBudgetConfig{MaxCatalogBytes: 400, MaxToolBytes: 250}
+		warnings := config.Check(size)
+		if len(warnings) != 3 {
+			t.Fatalf("Expected 3 warnings, got %+v", warnings)
+		}
+
+		if w := warnings[0]; w.ToolName != "deploy" || w.Limit != 250 || !strings.Contains(w.Suggestion, "shorten its description") {
+			t.Errorf("Unexpected deploy warning %+v", w)
+		}
+		if w := warnings[1]; w.ToolName != "search" || !strings.Contains(w.Suggestion, "simplify its parameters schema") {
+			t.Errorf("Unexpected search warning %+v", w)
+		}
+
+		catalog := warnings[2]
+		if catalog.ToolName != "" || catalog.Bytes != 1000 || catalog.Limit != 400 {
+			t.Errorf("Unexpected catalog warning %+v", catalog)
+		}
+		if len(catalog.Candidates) != 2 || catalog.Candidates[0] != "deploy" || catalog.Candidates[1] != "search" {
+			t.Errorf("Expected deploy and search as candidates, got %v", catalog.Candidates)
+		}
+		if got := catalog.String(); got != "tool catalog is 1000 bytes, over its 400 byte budget: trim or remove the largest tools: deploy, search" {
+			t.Errorf("Unexpected warning text %q", got)
+		}
+	})
+}
+
+func TestCreateSession_ToolBudget(t *testing.T) {
+	rpc, _ := newFakeServer(t, func(method string, params map[string]interface{}) (map[string]interface{}, *JSONRPCError) {
+		return map[string]interface{}{"sessionId": "s1"}, nil
+	})
+	client := &Client{client: rpc, state: StateConnected, sessions: make(map[string]*Session)}
+
+	var warnings []ToolBudgetWarning
+	session, err := client.CreateSession(&SessionConfig{
+		Tools: []Tool{{Name: "verbose", Description: strings.Repeat("very ", 100)}},
+		ToolBudget: &ToolBudgetConfig{
+			MaxToolBytes: 100,
+			OnWarning:    func(warning ToolBudgetWarning) { warnings = append(warnings, warning) },
+		},
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if size := session.ToolCatalogSize(); len(size.Tools) != 1 || size.Tools[0].Bytes <= 500 {
+		t.Errorf("Unexpected catalog size %+v", size)
+	}
+	if len(warnings) != 1 || warnings[0].ToolName != "verbose" {
+		t.Errorf("Expected a warning for the verbose tool, got %+v", warnings)
+	}
+}
@@ -0,0 +1,189 @@
+package copilot
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// WithMaxConcurrentInvocations limits how many calls of the tool run at once.
+// See [Tool.MaxConcurrentInvocations].
+//
+// Example:
+//
+//	tool := copilot.DefineToolCtx("query_db", "Run a read-only SQL query", queryDB,
+//	    copilot.WithMaxConcurrentInvocations(2))
+func WithMaxConcurrentInvocations(n int) ToolOption {
+	return func(o *toolOptions) {
+		o.maxConcurrent = n
+	}
+}
+
+// WithMutexGroup adds the tool to mutex groups, so that it never runs at the
+// same time as the other tools of a group. See [Tool.MutexGroups].
+//
+// Example:
+//
+//	writeFile := copilot.DefineToolCtx("write_file", "Write a file", writeFile,
+//	    copilot.WithMutexGroup("workspace"))
+//	runTests := copilot.DefineToolCtx("run_tests", "Run the test suite", runTests,
+//	    copilot.WithMutexGroup("workspace"))
+func WithMutexGroup(groups ...string) ToolOption {
+	return func(o *toolOptions) {
+		o.mutexGroups = append(o.mutexGroups, groups...)
+	}
+}
+
+// invocationLimiter is a semaphore that admits waiters in the order they
+// arrived.
+type invocationLimiter struct {
+	limit   int
+	mu      sync.Mutex
+	active  int
+	waiters []chan struct{}
+}
+
+func newInvocationLimiter(limit int) *invocationLimiter {
+	return &invocationLimiter{limit: limit}
+}
+
+// acquire waits for a free slot, or until ctx is done.
+func (l *invocationLimiter) acquire(ctx context.Context) error {
+	l.mu.Lock()
+	if l.active < l.limit && len(l.waiters) == 0 {
+		l.active++
+		l.mu.Unlock()
+		return nil
+	}
+	ready := make(chan struct{})
+	l.waiters = append(l.waiters, ready)
+	l.mu.Unlock()
+
+	select {
+	case <-ready:
+		return nil
+	case <-ctx.Done():
+	}
+
+	l.mu.Lock()
+	for i, waiter := range l.waiters {
+		if waiter == ready {
+			l.waiters = append(l.waiters[:i], l.waiters[i+1:]...)
+			l.mu.Unlock()
+			return context.Cause(ctx)
+		}
+	}
+	l.mu.Unlock()
+	// The slot was handed over as ctx was done: pass it on.
+	l.release()
+	return context.Cause(ctx)
+}
+
+// release frees a slot, handing it to the longest waiting caller, if any.
+func (l *invocationLimiter) release() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if len(l.waiters) > 0 {
+		close(l.waiters[0])
+		l.waiters = l.waiters[1:]
+		return
+	}
+	l.active--
+}
+
+func (s *Session) registerInvocationLimit(limit int) {
+	s.toolHandlersM.Lock()
+	defer s.toolHandlersM.Unlock()
+	s.invocationLimit = nil
+	if limit > 0 {
+		s.invocationLimit = newInvocationLimiter(limit)
+	}
+}
+
+// withInvocationLimits wraps handler so that calls wait for a slot of the
+// tool's own limit, then for each of its mutex groups, then for a slot of the
+// session's limit. A call whose context is done while it waits fails without
+// running. The caller holds s.toolHandlersM.
+func (s *Session) withInvocationLimits(tool Tool, handler ToolHandler) ToolHandler {
+	name := tool.Name
+	var limiters []*invocationLimiter
+	if limit := tool.MaxConcurrentInvocations; limit > 0 {
+		toolLimit := s.toolLimits[name]
+		if toolLimit == nil || toolLimit.limit != limit {
+			toolLimit = newInvocationLimiter(limit)
+			if s.toolLimits == nil {
+				s.toolLimits = make(map[string]*invocationLimiter)
+			}
+			s.toolLimits[name] = toolLimit
+		}
+		// The tool's own slot is taken first, so that calls queued for a busy
+		// tool do not hold session slots other tools could use.
+		limiters = append(limiters, toolLimit)
+	}
+	limiters = append(limiters, s.mutexGroupLimiters(tool.MutexGroups)...)
+	if s.invocationLimit != nil {
+		limiters = append(limiters, s.invocationLimit)
+	}
+	if len(limiters) == 0 {
+		return handler
+	}
+
+	return func(inv ToolInvocation) (ToolResult, error) {
+		start := time.Now()
+		for i, limiter := range limiters {
+			if err := limiter.acquire(inv.Context()); err != nil {
+				for _, held := range limiters[:i] {
+					held.release()
+				}
+				return ToolResult{}, fmt.Errorf("tool %s was canceled while waiting to run: %w", name, err)
+			}
+		}
+		defer func() {
+			for _, held := range limiters {
+				held.release()
+			}
+		}()
+		queued := time.Since(start)
+
+		result, err := handler(inv)
+		if err != nil || queued < time.Millisecond {
+			return result, err
+		}
+		telemetry := make(map[string]interface{}, len(result.ToolTelemetry)+1)
+		for key, value := range result.ToolTelemetry {
+			telemetry[key] = value
+		}
+		telemetry["queuedMs"] = queued.Milliseconds()
+		result.ToolTelemetry = telemetry
+		return result, nil
+	}
+}
+
+// mutexGroupLimiters returns the limiters of the named mutex groups, in group
+// name order so that tools sharing several groups cannot deadlock. The caller
+// holds s.toolHandlersM.
+func (s *Session) mutexGroupLimiters(groups []string) []*invocationLimiter {
+	if len(groups) == 0 {
+		return nil
+	}
+	names := append([]string(nil), groups...)
+	sort.Strings(names)
+	var limiters []*invocationLimiter
+	for i, group := range names {
+		if group == "" || (i > 0 && group == names[i-1]) {
+			continue
+		}
+		limiter := s.mutexGroups[group]
+		if limiter == nil {
+			limiter = newInvocationLimiter(1)
+			if s.mutexGroups == nil {
+				s.mutexGroups = make(map[string]*invocationLimiter)
+			}
+			s.mutexGroups[group] = limiter
+		}
+		limiters = append(limiters, limiter)
+	}
+	return limiters
+}
@@ -0,0 +1,243 @@
+package copilot
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestInvocationLimits(t *testing.T) {
+	// tracking returns a handler that records the most calls running at once
+	// and blocks until release is closed.
+	tracking := func(running, peak *atomic.Int32, release <-chan struct{}) ToolHandler {
+		return func(inv ToolInvocation) (ToolResult, error) {
+			n := running.Add(1)
+			defer running.Add(-1)
+			for {
+				p := peak.Load()
+				if n <= p || peak.CompareAndSwap(p, n) {
+					break
+				}
+			}
+			<-release
+			return ToolResult{TextResultForLLM: "ok", ResultType: "success"}, nil
+		}
+	}
+	invoke := func(session *Session, name string, n int) []ToolResult {
+		handler, _ := session.getToolHandler(name)
+		results := make([]ToolResult, n)
+		var wg sync.WaitGroup
+		for i := 0; i < n; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				results[i], _ = handler(ToolInvocation{ToolName: name, ctx: context.Background()})
+			}()
+		}
+		wg.Wait()
+		return results
+	}
+
+	t.Run("limits calls of a tool and queues the rest", func(t *testing.T) {
+		var running, peak atomic.Int32
+		release := make(chan struct{})
+		session := NewSession("s1", nil, "")
+		session.registerTools([]Tool{{Name: "build", Handler: tracking(&running, &peak, release), MaxConcurrentInvocations: 2}})
+
+		go func() {
+			time.Sleep(20 * time.Millisecond)
+			close(release)
+		}()
+		results := invoke(session, "build", 5)
+		if peak.Load() != 2 {
+			t.Errorf("Expected at most 2 concurrent calls, got %d", peak.Load())
+		}
+		var queued int
+		for _, result := range results {
+			if result.ResultType != "success" {
+				t.Errorf("Expected every queued call to run, got %+v", result)
+			}
+			if _, ok := result.ToolTelemetry["queuedMs"]; ok {
+				queued++
+			}
+		}
+		if queued != 3 {
+			t.Errorf("Expected 3 calls to report queueing, got %d", queued)
+		}
+	})
+
+	t.Run("limits calls across the session's tools", func(t *testing.T) {
+		var running, peak atomic.Int32
+		release := make(chan struct{})
+		session := NewSession("s1", nil, "")
+		session.registerInvocationLimit(1)
+		session.registerTools([]Tool{
+			{Name: "a", Handler: tracking(&running, &peak, release)},
+			{Name: "b", Handler: tracking(&running, &peak, release)},
+		})
+
+		go func() {
+			time.Sleep(20 * time.Millisecond)
+			close(release)
+		}()
+		var wg sync.WaitGroup
+		for _, name := range []string{"a", "b"} {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				invoke(session, name, 2)
+			}()
+		}
+		wg.Wait()
+		if peak.Load() != 1 {
+			t.Errorf("Expected one call at a time, got %d", peak.Load())
+		}
+	})
+
+	t.Run("admits queued calls in order", func(t *testing.T) {
+		limiter := newInvocationLimiter(1)
+		limiter.acquire(context.Background())
+
+		var mu sync.Mutex
+		var order []int
+		var wg sync.WaitGroup
+		for i := 0; i < 3; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				limiter.acquire(context.Background())
+				mu.Lock()
+				order = append(order, i)
+				mu.Unlock()
+				limiter.release()
+			}()
+			// Wait for the call to queue before starting the next.
+			for {
+				limiter.mu.Lock()
+				n := len(limiter.waiters)
+				limiter.mu.Unlock()
+				if n == i+1 {
+					break
+				}
+				time.Sleep(time.Millisecond)
+			}
+		}
+		limiter.release()
+		wg.Wait()
+		if len(order) != 3 || order[0] != 0 || order[1] != 1 || order[2] != 2 {
+			t.Errorf("Expected calls in arrival order, got %v", order)
+		}
+	})
+
+	t.Run("fails calls canceled while queued", func(t *testing.T) {
+		release := make(chan struct{})
+		defer close(release)
+		var running, peak atomic.Int32
+		session := NewSession("s1", nil, "")
+		session.registerTools([]Tool{{Name: "build", Handler: tracking(&running, &peak, release), MaxConcurrentInvocations: 1}})
+		handler, _ := session.getToolHandler("build")
+
+		go handler(ToolInvocation{ToolName: "build", ctx: context.Background()})
+		for running.Load() == 0 {
+			time.Sleep(time.Millisecond)
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+		_, err := handler(ToolInvocation{ToolName: "build", ctx: ctx})
+		if err == nil || !strings.Contains(err.Error(), "canceled while waiting to run") {
+			t.Errorf("Expected the queued call to fail, got %v", err)
+		}
+		if session.toolLimits["build"].active != 1 || len(session.toolLimits["build"].waiters) != 0 {
+			t.Error("Expected the canceled call to leave the queue")
+		}
+	})
+
+	t.Run("keeps per-tool limits across tool updates", func(t *testing.T) {
+		session := NewSession("s1", nil, "")
+		tool := Tool{Name: "build", Handler: func(ToolInvocation) (ToolResult, error) { return ToolResult{}, nil }, MaxConcurrentInvocations: 1}
+		session.registerTools([]Tool{tool})
+		limiter := session.toolLimits["build"]
+		session.registerTools([]Tool{tool})
+		if session.toolLimits["build"] != limiter {
+			t.Error("Expected the tool's limiter to be reused")
+		}
+	})
+
+	t.Run("serializes calls of tools in a mutex group", func(t *testing.T) {
+		var running, peak, otherRunning, otherPeak atomic.Int32
+		release := make(chan struct{})
+		session := NewSession("s1", nil, "")
+		session.registerTools([]Tool{
+			{Name: "write_file", Handler: tracking(&running, &peak, release), MutexGroups: []string{"workspace"}},
+			{Name: "run_tests", Handler: tracking(&running, &peak, release), MutexGroups: []string{"workspace", "workspace"}},
+			{Name: "search", Handler: tracking(&otherRunning, &otherPeak, release), MutexGroups: []string{"index"}},
+		})
+
+		go func() {
+			time.Sleep(20 * time.Millisecond)
+			close(release)
+		}()
+		var wg sync.WaitGroup
+		for _, name := range []string{"write_file", "run_tests", "search"} {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				invoke(session, name, 2)
+			}()
+		}
+		wg.Wait()
+		if peak.Load() != 1 {
+			t.Errorf("Expected calls in the workspace group to run one at a time, got %d", peak.Load())
+		}
+		if otherPeak.Load() != 1 {
+			t.Errorf("Expected calls in the index group to run one at a time, got %d", otherPeak.Load())
+		}
+	})
+
+	t.Run("acquires groups in a fixed order", func(t *testing.T) {
+		session := NewSession("s1", nil, "")
+		session.registerTools([]Tool{
+			{Name: "a", Handler: func(ToolInvocation) (ToolResult, error) { return ToolResult{}, nil }, MutexGroups: []string{"x", "y"}},
+			{Name: "b", Handler: func(ToolInvocation) (ToolResult, error) { return ToolResult{}, nil }, MutexGroups: []string{"y", "x"}},
+		})
+		limiters := session.mutexGroupLimiters([]string{"y", "x"})
+		if len(limiters) != 2 || limiters[0] != session.mutexGroups["x"] || limiters[1] != session.mutexGroups["y"] {
+			t.Error("Expected group limiters in name order")
+		}
+
+		var wg sync.WaitGroup
+		for i := 0; i < 20; i++ {
+			for _, name := range []string{"a", "b"} {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					invoke(session, name, 1)
+				}()
+			}
+		}
+		done := make(chan struct{})
+		go func() {
+			wg.Wait()
+			close(done)
+		}()
+		select {
+		case <-done:
+		case <-time.After(5 * time.Second):
+			t.Fatal("Expected tools sharing several groups not to deadlock")
+		}
+	})
+
+	t.Run("DefineTool option", func(t *testing.T) {
+		tool := DefineTool("q", "Q", func(params struct{}, inv ToolInvocation) (string, error) { return "", nil },
+			WithMaxConcurrentInvocations(3), WithMutexGroup("workspace"))
+		if tool.MaxConcurrentInvocations != 3 {
+			t.Errorf("Expected MaxConcurrentInvocations 3, got %d", tool.MaxConcurrentInvocations)
+		}
+		if len(tool.MutexGroups) != 1 || tool.MutexGroups[0] != "workspace" {
+			t.Errorf("Expected MutexGroups [workspace], got %v", tool.MutexGroups)
+		}
+	})
+}
@@ -0,0 +1,98 @@
+package copilot
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// Causes of the cancellation of a tool handler's context, as returned by
+// [context.Cause].
+var (
+	// ErrSessionAborted is matched by the cause when the turn running the tool
+	// is aborted, by [Session.Abort] or by the server. The cause is a
+	// [*CancelCause] telling why.
+	ErrSessionAborted = errors.New("session aborted")
+	// ErrSessionDestroyed is the cause when the session is destroyed.
+	ErrSessionDestroyed = errors.New("session destroyed")
+	// ErrClientStopped is the cause when the client is stopped.
+	ErrClientStopped = errors.New("client stopped")
+)
+
+// Context returns the context of the tool call. It is canceled when the turn is
+// aborted, the session is destroyed, or the client is stopped; [context.Cause]
+// reports which. Long-running handlers should stop when it is done.
+func (inv ToolInvocation) Context() context.Context {
+	if inv.ctx == nil {
+		return context.Background()
+	}
+	return inv.ctx
+}
+
+// DefineToolCtx is like [DefineTool], for handlers that take the tool call's
+// context as their first argument. See [ToolInvocation.Context].
+//
+// Example:
+//
+//	tool := copilot.DefineToolCtx("run_tests", "Run the test suite",
+//	    func(ctx context.Context, params RunTestsParams, inv copilot.ToolInvocation) (string, error) {
+//	        out, err := exec.CommandContext(ctx, "go", "test", params.Package).CombinedOutput()
+//	        return string(out), err
+//	    })
+func DefineToolCtx[T any, U any](name, description string, handler func(context.Context, T, ToolInvocation) (U, error), opts ...ToolOption) Tool {
+	return DefineTool(name, description, func(params T, inv ToolInvocation) (U, error) {
+		return handler(inv.Context(), params, inv)
+	}, opts...)
+}
+
+// TryDefineToolCtx is like [DefineToolCtx], but returns an error instead of
+// panicking. See [TryDefineTool].
+func TryDefineToolCtx[T any, U any](name, description string, handler func(context.Context, T, ToolInvocation) (U, error), opts ...ToolOption) (Tool, error) {
+	return TryDefineTool(name, description, func(params T, inv ToolInvocation) (U, error) {
+		return handler(inv.Context(), params, inv)
+	}, opts...)
+}
+
+// toolContexts tracks the context shared by the tool calls of a session. It is
+// replaced after every abort, so that later turns get a live context, and it is
+// canceled for good when the session ends.
+type toolContexts struct {
+	mu     sync.Mutex
+	ctx    context.Context
+	cancel context.CancelCauseFunc
+	ended  bool
+}
+
+// toolContext returns the context for a new tool call.
+func (s *Session) toolContext() context.Context {
+	s.tools.mu.Lock()
+	defer s.tools.mu.Unlock()
+	if s.tools.ctx == nil {
+		s.tools.ctx, s.tools.cancel = context.WithCancelCause(context.Background())
+	}
+	return s.tools.ctx
+}
+
+// cancelTools cancels the context of the tool calls in flight with cause. If
+// end is false, later tool calls get a new context.
+func (s *Session) cancelTools(cause error, end bool) {
+	s.tools.mu.Lock()
+	defer s.tools.mu.Unlock()
+	if s.tools.ended {
+		return
+	}
+	if s.tools.cancel != nil {
+		s.tools.cancel(cause)
+	}
+	if end {
+		// Reuse the canceled context, or create one, so that tool calls that
+		// arrive late are canceled from the start.
+		if s.tools.ctx == nil {
+			s.tools.ctx, s.tools.cancel = context.WithCancelCause(context.Background())
+			s.tools.cancel(cause)
+		}
+		s.tools.ended = true
+		return
+	}
+	s.tools.ctx, s.tools.cancel = nil, nil
+}
@@ -0,0 +1,101 @@
+package copilot
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+type waitParams struct {
+	Name string `json:"name"`
+}
+
+// startBlockingTool registers a tool that blocks until its context is done, and
+// calls it. It returns the cause of the cancellation.
+func startBlockingTool(t *testing.T, session *Session, client *Client) <-chan error {
+	t.Helper()
+	started := make(chan struct{})
+	session.registerTools([]Tool{DefineToolCtx("wait", "Wait until canceled",
+		func(ctx context.Context, params waitParams, inv ToolInvocation) (string, error) {
+			close(started)
+			<-ctx.Done()
+			return "", context.Cause(ctx)
+		})})
+
+	causes := make(chan error, 1)
+	go func() {
+		response, _ := client.handleToolCallRequest(map[string]interface{}{
+			"sessionId":  "s1",
+			"toolCallId": "call-1",
+			"toolName":   "wait",
+			"arguments":  map[string]interface{}{"name": "x"},
+		})
+		result := response["result"].(ToolResult)
+		causes <- errors.New(result.Error)
+	}()
+	<-started
+	return causes
+}
+
+func waitForCause(t *testing.T, causes <-chan error, want error) {
+	t.Helper()
+	select {
+	case cause := <-causes:
+		if cause.Error() != want.Error() {
+			t.Errorf("Expected cause %v, got %v", want, cause)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Timed out waiting for the tool to be canceled")
+	}
+}
+
+func TestDefineToolCtx(t *testing.T) {
+	t.Run("Abort cancels tool calls in flight", func(t *testing.T) {
+		session, _ := newFakeSession(t, func(server *fakeServer, method string, params map[string]interface{}) (map[string]interface{}, *JSONRPCError) {
+			return map[string]interface{}{}, nil
+		})
+		client := &Client{sessions: map[string]*Session{"s1": session}}
+
+		causes := startBlockingTool(t, session, client)
+		if err := session.Abort(); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		waitForCause(t, causes, ErrSessionAborted)
+
+		if err := session.toolContext().Err(); err != nil {
+			t.Errorf("Expected a live context for later tool calls, got %v", err)
+		}
+	})
+
+	t.Run("abort events cancel tool calls in flight", func(t *testing.T) {
+		session, server := newFakeSession(t, func(server *fakeServer, method string, params map[string]interface{}) (map[string]interface{}, *JSONRPCError) {
+			return map[string]interface{}{}, nil
+		})
+		client := &Client{sessions: map[string]*Session{"s1": session}}
+
+		causes := startBlockingTool(t, session, client)
+		server.emitEvent(Abort, "e1", map[string]interface{}{"reason": "user"})
+		waitForCause(t, causes, ErrSessionAborted)
+	})
+
+	t.Run("stopping the client cancels tool calls for good", func(t *testing.T) {
+		session := NewSession("s1", nil, "")
+		client := &Client{sessions: map[string]*Session{"s1": session}}
+
+		causes := startBlockingTool(t, session, client)
+		client.ForceStop()
+		waitForCause(t, causes, ErrClientStopped)
+
+		ctx := session.toolContext()
+		if !errors.Is(context.Cause(ctx), ErrClientStopped) {
+			t.Errorf("Expected later tool calls to be canceled, got %v", context.Cause(ctx))
+		}
+	})
+
+	t.Run("handlers called directly get a background context", func(t *testing.T) {
+		if (ToolInvocation{}).Context() != context.Background() {
+			t.Error("Expected a background context")
+		}
+	})
+}
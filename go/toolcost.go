@@ -0,0 +1,329 @@
+package copilot
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// ToolCost is the cost of tool calls by unit, such as API credits or
+// estimated seconds. Units are named by the caller:
+//
+//	copilot.ToolCost{"credits": 2, "seconds": 30}
+type ToolCost map[string]float64
+
+// add adds other to c, allocating c if needed, and returns it.
+func (c ToolCost) add(other ToolCost) ToolCost {
+	if len(other) == 0 {
+		return c
+	}
+	if c == nil {
+		c = make(ToolCost, len(other))
+	}
+	for unit, amount := range other {
+		c[unit] += amount
+	}
+	return c
+}
+
+// clone returns a copy of c, or nil if c is empty.
+func (c ToolCost) clone() ToolCost {
+	if len(c) == 0 {
+		return nil
+	}
+	return ToolCost(nil).add(c)
+}
+
+// String formats the cost as "2 credits, 30 seconds", with units in sorted
+// order.
+func (c ToolCost) String() string {
+	units := make([]string, 0, len(c))
+	for unit := range c {
+		units = append(units, unit)
+	}
+	sort.Strings(units)
+	parts := make([]string, len(units))
+	for i, unit := range units {
+		parts[i] = formatCost(c[unit], unit)
+	}
+	return strings.Join(parts, ", ")
+}
+
+func formatCost(amount float64, unit string) string {
+	return strconv.FormatFloat(amount, 'g', -1, 64) + " " + unit
+}
+
+// CostBudget limits the cost of tool calls. Each limit applies to the units it
+// lists; other units are unbounded. A call that would take a total over a
+// limit is not run: the model receives a failure result explaining which
+// budget it would exceed, and an [SDKToolCostBudgetExceeded] event is
+// dispatched.
+type CostBudget struct {
+	// PerTurn limits the cost of the calls made in one turn, from one
+	// [Session.Send] to the next.
+	PerTurn ToolCost
+	// PerSession limits the cost of all calls made in the session.
+	PerSession ToolCost
+	// DefaultCost, if set on a session's budget, is charged for each call of
+	// a tool without a cost function, so that the budget also stops runaway
+	// loops over free tools. For example, a PerSession limit of {"calls": 200}
+	// with a DefaultCost of {"calls": 1} allows 200 calls. It is ignored on a
+	// tool's budget.
+	DefaultCost ToolCost
+}
+
+// FixedCost returns a cost function, for [WithToolCost] or [Tool.Cost], that
+// charges each call the same estimated cost, such as a weight:
+//
+//	copilot.WithToolCost(copilot.FixedCost(copilot.ToolCost{"weight": 5}))
+func FixedCost(cost ToolCost) func(inv ToolInvocation) ToolCost {
+	cost = cost.clone()
+	return func(inv ToolInvocation) ToolCost {
+		return cost.clone()
+	}
+}
+
+// WithToolCost declares the cost of each call of the tool, computed from its
+// invocation before it runs. See [Tool.Cost].
+//
+// Example:
+//
+//	tool := copilot.DefineTool("geocode", "Geocode addresses", geocode,
+//	    copilot.WithToolCost(func(inv copilot.ToolInvocation) copilot.ToolCost {
+//	        args, _ := inv.Arguments.(map[string]interface{})
+//	        addresses, _ := args["addresses"].([]interface{})
+//	        return copilot.ToolCost{"credits": float64(len(addresses))}
+//	    }),
+//	    copilot.WithCostBudget(copilot.CostBudget{PerTurn: copilot.ToolCost{"credits": 100}}))
+func WithToolCost(cost func(inv ToolInvocation) ToolCost) ToolOption {
+	return func(o *toolOptions) {
+		o.cost = cost
+	}
+}
+
+// WithCostBudget limits the cost of the tool's calls. See [Tool.CostBudget].
+func WithCostBudget(budget CostBudget) ToolOption {
+	return func(o *toolOptions) {
+		o.costBudget = &budget
+	}
+}
+
+// ToolCostUsage is the cost of one tool's calls.
+type ToolCostUsage struct {
+	// Calls is the number of calls charged in the session.
+	Calls int
+	// Turn is the cost of the calls in the current turn.
+	Turn ToolCost
+	// Session is the cost of all calls in the session.
+	Session ToolCost
+}
+
+// ToolCostReport is the cost of a session's tool calls, as returned by
+// [Session.ToolCosts].
+type ToolCostReport struct {
+	// Turn is the cost of the calls in the current turn.
+	Turn ToolCost
+	// Session is the cost of all calls in the session.
+	Session ToolCost
+	// Tools are the costs by tool name, for tools with a cost function that
+	// were called.
+	Tools map[string]ToolCostUsage
+}
+
+// costLedger accumulates the cost of a session's tool calls. The zero value
+// is ready to use.
+type costLedger struct {
+	mu      sync.Mutex
+	turn    ToolCost
+	session ToolCost
+	tools   map[string]*ToolCostUsage
+}
+
+// costOverrun describes the budget a call would exceed.
+type costOverrun struct {
+	// scope is "tool" or "session", and period "turn" or "session".
+	scope, period string
+	unit          string
+	limit, spent  float64
+}
+
+func (o *costOverrun) String() string {
+	owner := "the session's"
+	if o.scope == "tool" {
+		owner = "the tool's"
+	}
+	return fmt.Sprintf("%s per-%s budget of %s (%s spent)", owner, o.period, formatCost(o.limit, o.unit), strconv.FormatFloat(o.spent, 'g', -1, 64))
+}
+
+// charge adds cost to the totals of tool unless that would exceed a budget,
+// in which case it charges nothing and returns the budget exceeded.
+func (l *costLedger) charge(tool string, cost ToolCost, toolBudget, sessionBudget *CostBudget) *costOverrun {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	usage := l.tools[tool]
+	if usage == nil {
+		usage = &ToolCostUsage{}
+	}
+	checks := []struct {
+		scope, period string
+		limits        ToolCost
+		spent         ToolCost
+	}{
+		{"tool", "turn", budgetLimits(toolBudget, true), usage.Turn},
+		{"tool", "session", budgetLimits(toolBudget, false), usage.Session},
+		{"session", "turn", budgetLimits(sessionBudget, true), l.turn},
+		{"session", "session", budgetLimits(sessionBudget, false), l.session},
+	}
+	units := make([]string, 0, len(cost))
+	for unit := range cost {
+		units = append(units, unit)
+	}
+	sort.Strings(units)
+	for _, check := range checks {
+		for _, unit := range units {
+			limit, ok := check.limits[unit]
+			if ok && check.spent[unit]+cost[unit] > limit {
+				return &costOverrun{scope: check.scope, period: check.period, unit: unit, limit: limit, spent: check.spent[unit]}
+			}
+		}
+	}
+
+	usage.Calls++
+	usage.Turn = usage.Turn.add(cost)
+	usage.Session = usage.Session.add(cost)
+	if l.tools == nil {
+		l.tools = make(map[string]*ToolCostUsage)
+	}
+	l.tools[tool] = usage
+	l.turn = l.turn.add(cost)
+	l.session = l.session.add(cost)
+	return nil
+}
+
+func budgetLimits(budget *CostBudget, perTurn bool) ToolCost {
+	switch {
+	case budget == nil:
+		return nil
+	case perTurn:
+		return budget.PerTurn
+	default:
+		return budget.PerSession
+	}
+}
+
+// startTurn resets the totals of the current turn.
+func (l *costLedger) startTurn() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.turn = nil
+	for _, usage := range l.tools {
+		usage.Turn = nil
+	}
+}
+
+func (l *costLedger) report() ToolCostReport {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	report := ToolCostReport{
+		Turn:    l.turn.clone(),
+		Session: l.session.clone(),
+		Tools:   make(map[string]ToolCostUsage, len(l.tools)),
+	}
+	for name, usage := range l.tools {
+		report.Tools[name] = ToolCostUsage{Calls: usage.Calls, Turn: usage.Turn.clone(), Session: usage.Session.clone()}
+	}
+	return report
+}
+
+// ToolCosts returns the cost of the session's tool calls, in total and by
+// tool, for the current turn and the whole session. Only tools with a
+// [Tool.Cost] function are charged, unless the session's budget sets a
+// [CostBudget.DefaultCost].
+//
+// Example:
+//
+//	report := session.ToolCosts()
+//	fmt.Printf("this turn: %s; session: %s\n", report.Turn, report.Session)
+func (s *Session) ToolCosts() ToolCostReport {
+	return s.costs.report()
+}
+
+func (s *Session) registerCostBudget(budget *CostBudget) {
+	s.toolHandlersM.Lock()
+	defer s.toolHandlersM.Unlock()
+	s.costBudget = budget
+}
+
+// withCost wraps handler so that its calls are charged their cost, or the
+// session budget's DefaultCost for tools without a cost function, and not
+// run if that would exceed the tool's or the session's budget. The cost is
+// reported as "cost" in the result's ToolTelemetry. The caller holds
+// s.toolHandlersM.
+func (s *Session) withCost(tool Tool, handler ToolHandler) ToolHandler {
+	costOf, toolBudget := tool.Cost, tool.CostBudget
+	if costOf == nil {
+		if s.costBudget == nil || len(s.costBudget.DefaultCost) == 0 {
+			return handler
+		}
+		costOf = FixedCost(s.costBudget.DefaultCost)
+	}
+	return func(inv ToolInvocation) (ToolResult, error) {
+		cost := costOf(inv)
+		s.toolHandlersM.RLock()
+		sessionBudget := s.costBudget
+		s.toolHandlersM.RUnlock()
+
+		if overrun := s.costs.charge(inv.ToolName, cost, toolBudget, sessionBudget); overrun != nil {
+			s.emit(SDKToolCostBudgetExceeded, map[string]interface{}{
+				"toolCallId": inv.ToolCallID,
+				"toolName":   inv.ToolName,
+				"scope":      overrun.scope,
+				"period":     overrun.period,
+				"unit":       overrun.unit,
+				"limit":      overrun.limit,
+				"spent":      overrun.spent,
+				"cost":       cost[overrun.unit],
+			})
+			return buildBudgetExceededToolResult(inv.ToolName, cost, overrun), nil
+		}
+
+		result, err := handler(inv)
+		if err != nil || result.pending != "" || len(cost) == 0 {
+			return result, err
+		}
+		telemetry := make(map[string]interface{}, len(result.ToolTelemetry)+1)
+		for key, value := range result.ToolTelemetry {
+			telemetry[key] = value
+		}
+		telemetry["cost"] = cost.clone()
+		result.ToolTelemetry = telemetry
+		return result, nil
+	}
+}
+
+// buildBudgetExceededToolResult creates a failure ToolResult for a call that
+// was not run because it would exceed a cost budget.
+func buildBudgetExceededToolResult(toolName string, cost ToolCost, overrun *costOverrun) ToolResult {
+	return ToolResult{
+		TextResultForLLM: fmt.Sprintf("Tool '%s' was not run: its cost of %s would exceed %s. Do not retry it unless the user asks.",
+			toolName, formatCost(cost[overrun.unit], overrun.unit), overrun),
+		ResultType: "failure",
+		Error:      fmt.Sprintf("tool '%s' would exceed its cost budget", toolName),
+		ToolTelemetry: map[string]interface{}{
+			"errorType": "budget_exceeded",
+			"cost":      cost.clone(),
+		},
+	}
+}
+
+// chargedCost returns the cost reported in result's ToolTelemetry by a call
+// that was charged it.
+func chargedCost(result ToolResult) ToolCost {
+	if result.ToolTelemetry["errorType"] == "budget_exceeded" {
+		return nil
+	}
+	cost, _ := result.ToolTelemetry["cost"].(ToolCost)
+	return cost
+}
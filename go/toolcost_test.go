@@ -0,0 +1,188 @@
+package copilot
+
+import (
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestToolCost(t *testing.T) {
+	type geocodeParams struct {
+		Addresses []string `json:"addresses"`
+	}
+	var runs int
+	geocode := DefineTool("geocode", "Geocode addresses",
+		func(params geocodeParams, inv ToolInvocation) (string, error) {
+			runs++
+			return "ok", nil
+		},
+		WithToolCost(func(inv ToolInvocation) ToolCost {
+			args, _ := inv.Arguments.(map[string]interface{})
+			addresses, _ := args["addresses"].([]interface{})
+			return ToolCost{"credits": float64(len(addresses)), "seconds": 2}
+		}),
+		WithCostBudget(CostBudget{PerTurn: ToolCost{"credits": 5}}),
+	)
+	search := DefineTool("search", "Search",
+		func(params struct{}, inv ToolInvocation) (string, error) { return "ok", nil },
+		WithToolCost(func(inv ToolInvocation) ToolCost { return ToolCost{"credits": 1} }),
+	)
+	free := DefineTool("free", "Free", func(params struct{}, inv ToolInvocation) (string, error) { return "ok", nil })
+
+	setup := func(budget *CostBudget) (*Session, *[]SessionEvent) {
+		runs = 0
+		session := NewSession("s1", nil, "")
+		session.registerCostBudget(budget)
+		session.registerTools([]Tool{geocode, search, free})
+		var exceeded []SessionEvent
+		session.On(func(event SessionEvent) {
+			if event.Type == SDKToolCostBudgetExceeded {
+				exceeded = append(exceeded, event)
+			}
+		})
+		return session, &exceeded
+	}
+	call := func(session *Session, name string, addresses ...interface{}) ToolResult {
+		handler, _ := session.getToolHandler(name)
+		arguments := map[string]interface{}{}
+		if len(addresses) > 0 {
+			arguments["addresses"] = addresses
+		}
+		result, err := handler(ToolInvocation{ToolCallID: "call-" + name, ToolName: name, Arguments: arguments})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		return result
+	}
+
+	t.Run("charges calls and reports totals", func(t *testing.T) {
+		session, _ := setup(nil)
+		result := call(session, "geocode", "a", "b")
+		call(session, "search")
+		call(session, "free")
+
+		if cost, _ := result.ToolTelemetry["cost"].(ToolCost); cost["credits"] != 2 || cost["seconds"] != 2 {
+			t.Errorf("Expected the cost in telemetry, got %v", result.ToolTelemetry)
+		}
+		report := session.ToolCosts()
+		if report.Session["credits"] != 3 || report.Turn["credits"] != 3 || report.Session["seconds"] != 2 {
+			t.Errorf("Unexpected totals %v, %v", report.Session, report.Turn)
+		}
+		if len(report.Tools) != 2 || report.Tools["geocode"].Calls != 1 || report.Tools["search"].Session["credits"] != 1 {
+			t.Errorf("Unexpected tool costs %+v", report.Tools)
+		}
+		if got := report.Session.String(); got != "3 credits, 2 seconds" {
+			t.Errorf("Unexpected formatting %q", got)
+		}
+	})
+
+	t.Run("enforces the tool's per-turn budget", func(t *testing.T) {
+		session, exceeded := setup(nil)
+		call(session, "geocode", "a", "b", "c")
+		result := call(session, "geocode", "d", "e", "f")
+		if runs != 1 || result.ResultType != "failure" || result.ToolTelemetry["errorType"] != "budget_exceeded" {
+			t.Fatalf("Expected the second call to be refused, got %d runs, %+v", runs, result)
+		}
+		if !strings.Contains(result.TextResultForLLM, "3 credits would exceed the tool's per-turn budget of 5 credits (3 spent)") {
+			t.Errorf("Unexpected explanation %q", result.TextResultForLLM)
+		}
+		if len(*exceeded) != 1 || sdkEventString((*exceeded)[0], "scope") != "tool" || sdkEventString((*exceeded)[0], "unit") != "credits" {
+			t.Errorf("Expected one budget event, got %+v", *exceeded)
+		}
+		if report := session.ToolCosts(); report.Session["credits"] != 3 || report.Tools["geocode"].Calls != 1 {
+			t.Errorf("Expected the refused call not to be charged, got %+v", report)
+		}
+
+		session.costs.startTurn()
+		call(session, "geocode", "d", "e", "f")
+		if report := session.ToolCosts(); runs != 2 || report.Turn["credits"] != 3 || report.Session["credits"] != 6 {
+			t.Errorf("Expected a new turn to reset the turn budget, got %d runs, %+v", runs, report)
+		}
+	})
+
+	t.Run("enforces the session budget across tools", func(t *testing.T) {
+		session, exceeded := setup(&CostBudget{PerSession: ToolCost{"credits": 3}})
+		call(session, "geocode", "a", "b")
+		call(session, "search")
+		session.costs.startTurn()
+		result := call(session, "search")
+		if result.ToolTelemetry["errorType"] != "budget_exceeded" || !strings.Contains(result.TextResultForLLM, "the session's per-session budget") {
+			t.Errorf("Expected the session budget to be exceeded, got %q", result.TextResultForLLM)
+		}
+		if len(*exceeded) != 1 || sdkEventString((*exceeded)[0], "period") != "session" {
+			t.Errorf("Unexpected events %+v", *exceeded)
+		}
+		if result := call(session, "free"); result.ResultType != "success" {
+			t.Errorf("Expected tools without a cost to run, got %+v", result)
+		}
+	})
+
+	t.Run("charges tools without a cost the default cost", func(t *testing.T) {
+		session, exceeded := setup(&CostBudget{PerSession: ToolCost{"calls": 2}, DefaultCost: ToolCost{"calls": 1}})
+		call(session, "free")
+		call(session, "free")
+		result := call(session, "free")
+		if result.ToolTelemetry["errorType"] != "budget_exceeded" || !strings.Contains(result.TextResultForLLM, "1 calls would exceed the session's per-session budget of 2 calls") {
+			t.Errorf("Expected the third call to be refused, got %q", result.TextResultForLLM)
+		}
+		if len(*exceeded) != 1 || sdkEventString((*exceeded)[0], "toolName") != "free" {
+			t.Errorf("Unexpected events %+v", *exceeded)
+		}
+		if report := session.ToolCosts(); report.Tools["free"].Calls != 2 || report.Session["calls"] != 2 {
+			t.Errorf("Unexpected report %+v", report)
+		}
+		if result := call(session, "search"); result.ResultType != "success" {
+			t.Errorf("Expected tools with their own cost not to be charged the default, got %+v", result)
+		}
+	})
+
+	t.Run("charges fixed costs", func(t *testing.T) {
+		costOf := FixedCost(ToolCost{"weight": 5})
+		first := costOf(ToolInvocation{})
+		first["weight"] = 100
+		if second := costOf(ToolInvocation{}); second["weight"] != 5 {
+			t.Errorf("Expected each call to get its own copy of the cost, got %v", second)
+		}
+	})
+
+	t.Run("is safe for concurrent calls", func(t *testing.T) {
+		var ledger costLedger
+		budget := &CostBudget{PerSession: ToolCost{"credits": 10}}
+		var wg sync.WaitGroup
+		var mu sync.Mutex
+		charged := 0
+		for i := 0; i < 50; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				if ledger.charge("t", ToolCost{"credits": 1}, nil, budget) == nil {
+					mu.Lock()
+					charged++
+					mu.Unlock()
+				}
+			}()
+		}
+		wg.Wait()
+		if charged != 10 || ledger.report().Session["credits"] != 10 {
+			t.Errorf("Expected exactly 10 calls to be charged, got %d", charged)
+		}
+	})
+}
+
+func TestToolObserver_Cost(t *testing.T) {
+	var infos []ToolResultInfo
+	client := &Client{options: ClientOptions{ToolObserver: ToolObserverFuncs{
+		Result: func(info ToolResultInfo) { infos = append(infos, info) },
+	}}}
+	observe := client.observeToolInvocation(ToolInvocation{ToolName: "search"})
+	observe(ToolResult{ResultType: "success", ToolTelemetry: map[string]interface{}{"cost": ToolCost{"credits": 1}}}, nil)
+	observe = client.observeToolInvocation(ToolInvocation{ToolName: "search"})
+	observe(buildBudgetExceededToolResult("search", ToolCost{"credits": 1}, &costOverrun{scope: "tool", period: "turn", unit: "credits", limit: 1, spent: 1}), nil)
+
+	if len(infos) != 2 || infos[0].Cost["credits"] != 1 {
+		t.Fatalf("Expected the charged cost to be observed, got %+v", infos)
+	}
+	if infos[1].Cost != nil || infos[1].ErrorClass != ToolErrorBudgetExceeded {
+		t.Errorf("Expected a refused call to be classified and not charged, got %+v", infos[1])
+	}
+}
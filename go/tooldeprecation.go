@@ -0,0 +1,138 @@
+package copilot
+
+// ToolDeprecation marks a [Tool] as deprecated, so that a tool API can change
+// without breaking agents that still call the tool by its old name. See
+// [Tool.Deprecated].
+type ToolDeprecation struct {
+	// ReplacedBy is the name of the tool that replaces the deprecated one.
+	// Calls of the deprecated tool run the replacement's handler, with its
+	// middleware and policies, and see the replacement's name in
+	// [ToolInvocation.ToolName]. Without it, the deprecated tool's own Handler
+	// runs.
+	ReplacedBy string
+	// Message explains the deprecation, such as what changed in the
+	// replacement. It is shown to the model in the tool's description.
+	Message string
+}
+
+// DeprecatedAlias returns a tool that keeps accepting calls under name, the
+// former name of the tool now named replacedBy, and forwards them to it. The
+// alias is advertised with the replacement's parameters and a description
+// that points to the replacement.
+//
+// Example:
+//
+//	tools := []copilot.Tool{
+//	    searchCode, // renamed from "grep"
+//	    copilot.DeprecatedAlias("grep", "search_code"),
+//	}
+func DeprecatedAlias(name, replacedBy string) Tool {
+	return Tool{Name: name, Deprecated: &ToolDeprecation{ReplacedBy: replacedBy}}
+}
+
+// notice returns the text that starts the description of a deprecated tool.
+func (d *ToolDeprecation) notice() string {
+	notice := "Deprecated."
+	if d.ReplacedBy != "" {
+		notice = "Deprecated: use " + d.ReplacedBy + " instead."
+	}
+	if d.Message != "" {
+		notice += " " + d.Message
+	}
+	return notice
+}
+
+// withDeprecatedTools returns tools with the descriptions of deprecated tools
+// starting with their deprecation notice, and deprecated tools without
+// parameters taking those of their replacement.
+func withDeprecatedTools(tools []Tool) []Tool {
+	byName := make(map[string]Tool, len(tools))
+	deprecated := false
+	for _, tool := range tools {
+		byName[tool.Name] = tool
+		deprecated = deprecated || tool.Deprecated != nil
+	}
+	if !deprecated {
+		return tools
+	}
+
+	advertised := make([]Tool, len(tools))
+	for i, tool := range tools {
+		if tool.Deprecated != nil {
+			replacement, ok := byName[resolveReplacement(byName, tool.Name)]
+			if tool.Description == "" && ok {
+				tool.Description = replacement.Description
+			}
+			if tool.Parameters == nil && ok {
+				tool.Parameters = replacement.Parameters
+			}
+			tool.Description = joinDescription(tool.Deprecated.notice(), tool.Description)
+		}
+		advertised[i] = tool
+	}
+	return advertised
+}
+
+// joinDescription joins a notice and a description.
+func joinDescription(notice, description string) string {
+	if description == "" {
+		return notice
+	}
+	return notice + " " + description
+}
+
+// resolveReplacement returns the tool that calls of name run, following
+// chains of deprecated tools. It returns name if the tool is not replaced,
+// and "" if the chain ends at a tool not in byName or loops.
+func resolveReplacement(byName map[string]Tool, name string) string {
+	seen := make(map[string]bool)
+	for {
+		tool, ok := byName[name]
+		if !ok || seen[name] {
+			return ""
+		}
+		if tool.Deprecated == nil || tool.Deprecated.ReplacedBy == "" {
+			return name
+		}
+		seen[name] = true
+		name = tool.Deprecated.ReplacedBy
+	}
+}
+
+// registerDeprecatedTools sets the handlers of the deprecated tools among
+// tools, forwarding calls to their replacement's handler. A deprecated tool
+// whose replacement the session does not have gets no handler. The caller must
+// hold toolHandlersM, after registering the other tools' handlers.
+func (s *Session) registerDeprecatedTools(tools []Tool) {
+	byName := make(map[string]Tool, len(tools))
+	for _, tool := range tools {
+		byName[tool.Name] = tool
+	}
+	for _, tool := range tools {
+		if tool.Name == "" || tool.Deprecated == nil {
+			continue
+		}
+		target := resolveReplacement(byName, tool.Name)
+		handler, ok := s.toolHandlers[target]
+		if target == "" || !ok {
+			delete(s.toolHandlers, tool.Name)
+			continue
+		}
+		s.toolHandlers[tool.Name] = s.withDeprecation(tool, target, handler)
+	}
+}
+
+// withDeprecation wraps the handler that calls of a deprecated tool run,
+// dispatching an [SDKToolDeprecated] event for each call.
+func (s *Session) withDeprecation(tool Tool, target string, handler ToolHandler) ToolHandler {
+	return func(inv ToolInvocation) (ToolResult, error) {
+		s.emit(SDKToolDeprecated, map[string]interface{}{
+			"toolCallId": inv.ToolCallID,
+			"toolName":   inv.ToolName,
+			"replacedBy": tool.Deprecated.ReplacedBy,
+			"message":    tool.Deprecated.Message,
+		})
+		inv.ToolName = target
+		return handler(inv)
+	}
+}
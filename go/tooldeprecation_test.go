@@ -0,0 +1,104 @@
+package copilot
+
+import "testing"
+
+func TestToolDeprecation(t *testing.T) {
+	searchCode := Tool{
+		Name:        "search_code",
+		Description: "Search the repository.",
+		Parameters:  map[string]interface{}{"type": "object"},
+		Handler: func(inv ToolInvocation) (ToolResult, error) {
+			return ToolResult{TextResultForLLM: "searched by " + inv.ToolName, ResultType: "success"}, nil
+		},
+	}
+
+	t.Run("advertises deprecated tools with a notice", func(t *testing.T) {
+		tools := withDeprecatedTools([]Tool{
+			searchCode,
+			DeprecatedAlias("grep", "search_code"),
+			{Name: "legacy", Description: "Old tool.", Deprecated: &ToolDeprecation{Message: "It will be removed."}},
+		})
+		if tools[1].Description != "Deprecated: use search_code instead. Search the repository." || tools[1].Parameters == nil {
+			t.Errorf("Expected the alias to take the replacement's description and parameters, got %+v", tools[1])
+		}
+		if tools[2].Description != "Deprecated. It will be removed. Old tool." {
+			t.Errorf("Unexpected description %q", tools[2].Description)
+		}
+		if tools[0].Description != searchCode.Description {
+			t.Errorf("Expected the replacement to be unchanged, got %q", tools[0].Description)
+		}
+	})
+
+	t.Run("forwards calls to the replacement and emits an event", func(t *testing.T) {
+		session := NewSession("s1", nil, "")
+		var events []SessionEvent
+		session.On(func(event SessionEvent) {
+			if event.Type == SDKToolDeprecated {
+				events = append(events, event)
+			}
+		})
+		session.registerTools([]Tool{
+			searchCode,
+			{Name: "find", Deprecated: &ToolDeprecation{ReplacedBy: "grep", Message: "Renamed twice."}},
+			DeprecatedAlias("grep", "search_code"),
+		})
+
+		for _, name := range []string{"grep", "find"} {
+			handler, ok := session.getToolHandler(name)
+			if !ok {
+				t.Fatalf("Expected a handler for %s", name)
+			}
+			result, err := handler(ToolInvocation{ToolCallID: "call-" + name, ToolName: name})
+			if err != nil || result.TextResultForLLM != "searched by search_code" {
+				t.Errorf("Expected the call of %s to be forwarded, got %+v, %v", name, result, err)
+			}
+		}
+		if len(events) != 2 {
+			t.Fatalf("Expected 2 deprecation events, got %d", len(events))
+		}
+		if sdkEventString(events[1], "toolName") != "find" || sdkEventString(events[1], "replacedBy") != "grep" || sdkEventString(events[1], "message") != "Renamed twice." {
+			t.Errorf("Unexpected event %+v", events[1].Data.Metadata.Variables)
+		}
+	})
+
+	t.Run("drops aliases of missing tools", func(t *testing.T) {
+		session := NewSession("s1", nil, "")
+		session.registerTools([]Tool{
+			DeprecatedAlias("grep", "search_code"),
+			{Name: "a", Deprecated: &ToolDeprecation{ReplacedBy: "b"}},
+			{Name: "b", Deprecated: &ToolDeprecation{ReplacedBy: "a"}},
+		})
+		for _, name := range []string{"grep", "a", "b"} {
+			if _, ok := session.getToolHandler(name); ok {
+				t.Errorf("Expected no handler for %s", name)
+			}
+		}
+	})
+
+	t.Run("keeps the handler of a tool deprecated without replacement", func(t *testing.T) {
+		session := NewSession("s1", nil, "")
+		called := false
+		session.registerTools([]Tool{{
+			Name:       "legacy",
+			Deprecated: &ToolDeprecation{},
+			Handler: func(inv ToolInvocation) (ToolResult, error) {
+				called = inv.ToolName == "legacy"
+				return ToolResult{ResultType: "success"}, nil
+			},
+		}})
+		handler, ok := session.getToolHandler("legacy")
+		if !ok {
+			t.Fatal("Expected a handler")
+		}
+		if _, err := handler(ToolInvocation{ToolName: "legacy"}); err != nil || !called {
+			t.Errorf("Expected the tool's own handler to run, got %v", err)
+		}
+	})
+
+	t.Run("leaves tools without deprecations alone", func(t *testing.T) {
+		tools := []Tool{searchCode}
+		if got := withDeprecatedTools(tools); &got[0] != &tools[0] {
+			t.Error("Expected the same slice")
+		}
+	})
+}
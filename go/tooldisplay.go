@@ -0,0 +1,38 @@
+package copilot
+
+// emitToolResultDisplay announces the display data of a tool result with an
+// [SDKToolResultDisplayed] event, if it has any.
+func (c *Client) emitToolResultDisplay(inv ToolInvocation, result ToolResult) {
+	if result.DisplayData == nil && result.MimeType == "" {
+		return
+	}
+	session := c.sessionByID(inv.SessionID)
+	if session == nil {
+		return
+	}
+	session.emit(SDKToolResultDisplayed, map[string]interface{}{
+		"toolCallId":  inv.ToolCallID,
+		"toolName":    inv.ToolName,
+		"mimeType":    result.MimeType,
+		"displayData": result.DisplayData,
+	})
+}
+
+// ToolResultDisplay returns the display data carried by an
+// [SDKToolResultDisplayed] event, its MIME type, and the ID of the tool call
+// that returned it, and false for other events.
+//
+// Example:
+//
+//	session.On(func(event copilot.SessionEvent) {
+//	    if _, mimeType, data, ok := copilot.ToolResultDisplay(event); ok && mimeType == "application/vnd.example.table+json" {
+//	        ui.RenderTable(data["columns"], data["rows"])
+//	    }
+//	})
+func ToolResultDisplay(event SessionEvent) (toolCallID, mimeType string, data map[string]interface{}, ok bool) {
+	if event.Type != SDKToolResultDisplayed || event.Data.Metadata == nil {
+		return "", "", nil, false
+	}
+	data, _ = event.Data.Metadata.Variables["displayData"].(map[string]interface{})
+	return sdkEventString(event, "toolCallId"), sdkEventString(event, "mimeType"), data, true
+}
@@ -0,0 +1,89 @@
+package copilot
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestToolResultDisplay(t *testing.T) {
+	session := NewSession("s1", nil, "")
+	client := &Client{sessions: map[string]*Session{"s1": session}}
+
+	var events []SessionEvent
+	session.On(func(event SessionEvent) {
+		if event.Type == SDKToolResultDisplayed {
+			events = append(events, event)
+		}
+	})
+
+	type DiffParams struct {
+		Path string `json:"path"`
+	}
+	tool := DefineTool("diff", "Diff a file", func(params DiffParams, inv ToolInvocation) (*ToolResult, error) {
+		return &ToolResult{
+			TextResultForLLM: "1 line changed in " + params.Path,
+			ResultType:       "success",
+			MimeType:         "text/x-diff",
+			DisplayData:      map[string]interface{}{"hunks": []interface{}{"-a", "+b"}},
+		}, nil
+	})
+
+	t.Run("dispatches display data without sending it to the model", func(t *testing.T) {
+		events = nil
+		result := client.executeToolCall(session.toolContext(), "s1", "call-1", "diff", map[string]interface{}{"path": "main.go"}, tool.Handler)
+		if result.TextResultForLLM != "1 line changed in main.go" || result.MimeType != "text/x-diff" {
+			t.Errorf("Unexpected result %+v", result)
+		}
+		encoded, err := json.Marshal(result)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if strings.Contains(string(encoded), "hunks") || strings.Contains(string(encoded), "x-diff") {
+			t.Errorf("Expected display data to stay out of the wire result, got %s", encoded)
+		}
+
+		if len(events) != 1 {
+			t.Fatalf("Expected one display event, got %d", len(events))
+		}
+		toolCallID, mimeType, data, ok := ToolResultDisplay(events[0])
+		if !ok || toolCallID != "call-1" || mimeType != "text/x-diff" {
+			t.Errorf("Unexpected display %s %s %v", toolCallID, mimeType, ok)
+		}
+		if hunks, _ := data["hunks"].([]interface{}); len(hunks) != 2 {
+			t.Errorf("Unexpected display data %+v", data)
+		}
+	})
+
+	t.Run("skips results without display data", func(t *testing.T) {
+		events = nil
+		client.executeToolCall(session.toolContext(), "s1", "call-2", "plain", nil, func(inv ToolInvocation) (ToolResult, error) {
+			return ToolResult{TextResultForLLM: "ok", ResultType: "success"}, nil
+		})
+		if len(events) != 0 {
+			t.Errorf("Expected no display events, got %d", len(events))
+		}
+		if _, _, _, ok := ToolResultDisplay(SessionEvent{Type: SDKToolResultPresented}); ok {
+			t.Error("Expected other events to carry no display data")
+		}
+	})
+}
+
+func TestNormalizeResult_PreservesDisplayData(t *testing.T) {
+	display := map[string]interface{}{"rows": 3}
+	for _, value := range []interface{}{
+		ToolResult{ResultType: "success", MimeType: "application/json", DisplayData: display},
+		&ToolResult{ResultType: "success", MimeType: "application/json", DisplayData: display},
+	} {
+		result, err := normalizeResult(value)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if result.MimeType != "application/json" || result.DisplayData["rows"] != 3 {
+			t.Errorf("Expected display data to pass through, got %+v", result)
+		}
+	}
+	if result, err := normalizeResult((*ToolResult)(nil)); err != nil || result.ResultType != "success" {
+		t.Errorf("Unexpected result for a nil *ToolResult: %+v, %v", result, err)
+	}
+}
@@ -0,0 +1,205 @@
+package copilot
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ToolCallError describes a single failed tool call.
+type ToolCallError struct {
+	// ToolCallID identifies the failed call.
+	ToolCallID string
+	// ToolName is the name of the tool, if known.
+	ToolName string
+	// Message is the failure reported for the call.
+	Message string
+	// Code is the error code reported by the server, if any.
+	Code string
+}
+
+func (e *ToolCallError) Error() string {
+	name := e.ToolName
+	if name == "" {
+		name = "tool call " + e.ToolCallID
+	}
+	if e.Message == "" {
+		return name + " failed"
+	}
+	return name + ": " + e.Message
+}
+
+// MultiToolError aggregates the tool calls that failed during a turn, in the
+// order their executions completed. Range over Errors to inspect each failure;
+// [errors.As] also finds the individual [ToolCallError] values.
+type MultiToolError struct {
+	Errors []*ToolCallError
+}
+
+func (e *MultiToolError) Error() string {
+	if len(e.Errors) == 1 {
+		return "tool call failed: " + e.Errors[0].Error()
+	}
+	messages := make([]string, len(e.Errors))
+	for i, err := range e.Errors {
+		messages[i] = err.Error()
+	}
+	return fmt.Sprintf("%d tool calls failed: %s", len(e.Errors), strings.Join(messages, "; "))
+}
+
+// Unwrap returns the individual tool call errors.
+func (e *MultiToolError) Unwrap() []error {
+	errs := make([]error, len(e.Errors))
+	for i, err := range e.Errors {
+		errs[i] = err
+	}
+	return errs
+}
+
+// toolCallErrorFromEvent returns the failure recorded by a tool.execution_complete
+// event, or nil if the execution succeeded.
+func toolCallErrorFromEvent(event SessionEvent, toolName string) *ToolCallError {
+	if event.Type != ToolExecutionComplete || event.Data.Success == nil || *event.Data.Success {
+		return nil
+	}
+	err := &ToolCallError{ToolName: toolName}
+	if event.Data.ToolCallID != nil {
+		err.ToolCallID = *event.Data.ToolCallID
+	}
+	if e := event.Data.Error; e != nil {
+		switch {
+		case e.ErrorClass != nil:
+			err.Message = e.ErrorClass.Message
+			if e.ErrorClass.Code != nil {
+				err.Code = *e.ErrorClass.Code
+			}
+		case e.String != nil:
+			err.Message = *e.String
+		}
+	}
+	return err
+}
+
+// ToolError is an error a tool handler returns to fail the call with a
+// structured error the model can act on, instead of the generic failure
+// message it sees for other errors. The call's failure result has the
+// message as its text, and its ToolTelemetry has errorType "tool_error",
+// "errorCode", "retryable", and any "errorDetails".
+//
+// A ToolError can be returned as the handler's error, wrapped or not, or as
+// its result. It is mapped to a failure result whether or not the tool has an
+// [ErrorRenderer], and [IsRetryableToolError] reports its Retryable field.
+//
+// Example:
+//
+//	if resp.StatusCode == http.StatusNotFound {
+//	    return nil, &copilot.ToolError{
+//	        Code:    "issue_not_found",
+//	        Message: fmt.Sprintf("Issue #%d does not exist. Search for the issue by title instead.", params.ID),
+//	    }
+//	}
+type ToolError struct {
+	// Code identifies the kind of failure, such as "not_found" or
+	// "rate_limited", for the model and for analytics.
+	Code string
+	// Message is the explanation reported to the model. Defaults to the
+	// message of Err.
+	Message string
+	// Retryable reports whether calling the tool again with the same
+	// arguments may succeed.
+	Retryable bool
+	// Details is structured data about the failure, such as the field that
+	// was invalid, reported in the result's ToolTelemetry.
+	Details map[string]interface{}
+	// Err is the underlying error, if any. It is recorded in the result's
+	// Error but not shown to the model.
+	Err error
+}
+
+func (e *ToolError) Error() string {
+	message := e.Message
+	if e.Err != nil {
+		if message == "" {
+			message = e.Err.Error()
+		} else {
+			message += ": " + e.Err.Error()
+		}
+	}
+	if e.Code == "" {
+		return message
+	}
+	if message == "" {
+		return e.Code
+	}
+	return e.Code + ": " + message
+}
+
+func (e *ToolError) Unwrap() error {
+	return e.Err
+}
+
+// asToolError returns the [*ToolError] in err's chain, if any.
+func asToolError(err error) (*ToolError, bool) {
+	var toolErr *ToolError
+	if err == nil || !errors.As(err, &toolErr) || toolErr == nil {
+		return nil, false
+	}
+	return toolErr, true
+}
+
+// buildToolErrorResult creates a failure ToolResult for err, which returned
+// toolErr from a handler. The model sees the code and message, and err is
+// kept so that a [RetryPolicy] can classify it.
+func buildToolErrorResult(err error, toolErr *ToolError) ToolResult {
+	message := toolErr.Message
+	if message == "" && toolErr.Err != nil {
+		message = trimErrorNoise(toolErr.Err.Error())
+	}
+	if message == "" {
+		message = "the tool failed"
+	}
+	text := "Error: " + message
+	if toolErr.Code != "" {
+		text = fmt.Sprintf("Error [%s]: %s", toolErr.Code, message)
+	}
+	if toolErr.Retryable {
+		text += "\nThis error is transient; the call may succeed if retried."
+	}
+
+	telemetry := map[string]interface{}{
+		"errorType": "tool_error",
+		"retryable": toolErr.Retryable,
+	}
+	if toolErr.Code != "" {
+		telemetry["errorCode"] = toolErr.Code
+	}
+	if len(toolErr.Details) > 0 {
+		telemetry["errorDetails"] = toolErr.Details
+	}
+	return ToolResult{
+		TextResultForLLM: text,
+		ResultType:       "failure",
+		Error:            err.Error(),
+		ToolTelemetry:    telemetry,
+		renderedErr:      err,
+	}
+}
+
+// toolErrorCode returns the code of the [ToolError] result was built from.
+func toolErrorCode(result ToolResult) string {
+	if result.ToolTelemetry["errorType"] != "tool_error" {
+		return ""
+	}
+	code, _ := result.ToolTelemetry["errorCode"].(string)
+	return code
+}
+
+// buildHandlerErrorResult creates a failure ToolResult for an error returned
+// by a tool handler: a structured result for a [*ToolError], and a generic
+// one that hides the error from the model otherwise.
+func buildHandlerErrorResult(err error) ToolResult {
+	if toolErr, ok := asToolError(err); ok {
+		return buildToolErrorResult(err, toolErr)
+	}
+	return buildFailedToolResult(err.Error())
+}
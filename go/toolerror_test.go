@@ -0,0 +1,151 @@
+package copilot
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTurn_ToolErrors(t *testing.T) {
+	t.Run("aggregates parallel tool failures with details", func(t *testing.T) {
+		collector := newTurnCollector(&Session{})
+		events := []string{
+			`{"type":"assistant.message","data":{"content":"","toolRequests":[{"toolCallId":"1","name":"fetch"},{"toolCallId":"2","name":"lint"},{"toolCallId":"3","name":"test"}]}}`,
+			`{"type":"tool.execution_complete","data":{"toolCallId":"2","success":false,"error":{"message":"lint crashed","code":"E_LINT"}}}`,
+			`{"type":"tool.execution_complete","data":{"toolCallId":"1","success":true}}`,
+			`{"type":"tool.execution_complete","data":{"toolCallId":"3","success":false,"error":"3 tests failed"}}`,
+		}
+		for _, raw := range events {
+			var event SessionEvent
+			if err := json.Unmarshal([]byte(raw), &event); err != nil {
+				t.Fatalf("Bad fixture: %v", err)
+			}
+			collector.add(event)
+		}
+
+		turn := collector.result()
+		if turn.ToolErrors == nil || len(turn.ToolErrors.Errors) != 2 {
+			t.Fatalf("Expected 2 tool errors, got %+v", turn.ToolErrors)
+		}
+		first, second := turn.ToolErrors.Errors[0], turn.ToolErrors.Errors[1]
+		if first.ToolName != "lint" || first.Code != "E_LINT" || first.Message != "lint crashed" {
+			t.Errorf("Unexpected first error %+v", first)
+		}
+		if second.ToolName != "test" || second.ToolCallID != "3" || second.Message != "3 tests failed" {
+			t.Errorf("Unexpected second error %+v", second)
+		}
+		if got := turn.ToolErrors.Error(); got != "2 tool calls failed: lint: lint crashed; test: 3 tests failed" {
+			t.Errorf("Unexpected message %q", got)
+		}
+
+		var target *ToolCallError
+		if !errors.As(turn.ToolErrors, &target) || target != first {
+			t.Error("Expected errors.As to find the first tool error")
+		}
+	})
+
+	t.Run("is nil when no tool failed", func(t *testing.T) {
+		if turn := newTurnCollector(&Session{}).result(); turn.ToolErrors != nil {
+			t.Errorf("Expected nil ToolErrors, got %+v", turn.ToolErrors)
+		}
+	})
+}
+
+func TestToolError(t *testing.T) {
+	type Params struct {
+		ID int `json:"id,omitempty"`
+	}
+	notFound := &ToolError{
+		Code:    "issue_not_found",
+		Message: "Issue #7 does not exist.",
+		Details: map[string]interface{}{"id": 7},
+		Err:     errors.New("GET /issues/7: 404"),
+	}
+
+	t.Run("maps a returned error to a structured failure", func(t *testing.T) {
+		tool := DefineTool("lookup_issue", "Look up an issue", func(params Params, inv ToolInvocation) (string, error) {
+			return "", fmt.Errorf("failed to look up issue: %w", notFound)
+		})
+		result, err := tool.Handler(ToolInvocation{ToolName: "lookup_issue", Arguments: map[string]interface{}{"id": 7}})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if result.ResultType != "failure" || result.TextResultForLLM != "Error [issue_not_found]: Issue #7 does not exist." {
+			t.Errorf("Unexpected result %+v", result)
+		}
+		if result.Error != "failed to look up issue: issue_not_found: Issue #7 does not exist.: GET /issues/7: 404" {
+			t.Errorf("Expected the full error to be recorded, got %q", result.Error)
+		}
+		telemetry := result.ToolTelemetry
+		if telemetry["errorType"] != "tool_error" || telemetry["errorCode"] != "issue_not_found" || telemetry["retryable"] != false {
+			t.Errorf("Unexpected telemetry %v", telemetry)
+		}
+		if details, _ := telemetry["errorDetails"].(map[string]interface{}); details["id"] != 7 {
+			t.Errorf("Expected the details in telemetry, got %v", telemetry)
+		}
+	})
+
+	t.Run("takes precedence over an error renderer", func(t *testing.T) {
+		tool := DefineTool("lookup_issue", "Look up an issue", func(params Params, inv ToolInvocation) (string, error) {
+			return "", &ToolError{Code: "rate_limited", Err: errors.New("429 Too Many Requests\n\tat client.go:12"), Retryable: true}
+		}, WithErrorRenderer(func(err error) string { return "rendered" }))
+		result, _ := tool.Handler(ToolInvocation{ToolName: "lookup_issue", Arguments: map[string]interface{}{}})
+		want := "Error [rate_limited]: 429 Too Many Requests\nThis error is transient; the call may succeed if retried."
+		if result.TextResultForLLM != want || result.ToolTelemetry["retryable"] != true {
+			t.Errorf("Expected %q, got %+v", want, result)
+		}
+	})
+
+	t.Run("maps a returned result", func(t *testing.T) {
+		for _, value := range []any{*notFound, notFound} {
+			result, err := normalizeResult(value)
+			if err != nil || result.ResultType != "failure" || result.ToolTelemetry["errorCode"] != "issue_not_found" {
+				t.Errorf("Unexpected result %+v, error %v", result, err)
+			}
+		}
+	})
+
+	t.Run("is retried when retryable", func(t *testing.T) {
+		calls := 0
+		tool := DefineTool("search", "Search", func(params Params, inv ToolInvocation) (string, error) {
+			if calls++; calls == 1 {
+				return "", &ToolError{Code: "unavailable", Retryable: true}
+			}
+			return "", &ToolError{Code: "bad_query"}
+		}, WithRetryPolicy(RetryPolicy{MaxAttempts: 3, InitialBackoff: time.Millisecond}))
+		session := NewSession("s1", nil, "")
+		session.registerTools([]Tool{tool})
+		handler, _ := session.getToolHandler("search")
+		result, err := handler(ToolInvocation{ToolName: "search", ToolCallID: "call-1", Arguments: map[string]interface{}{}, ctx: context.Background()})
+		if err != nil || calls != 2 || result.ToolTelemetry["errorCode"] != "bad_query" {
+			t.Errorf("Expected one retry, got %d calls, %+v, error %v", calls, result, err)
+		}
+	})
+
+	t.Run("maps raw handler errors and is observed", func(t *testing.T) {
+		var infos []ToolResultInfo
+		client := &Client{options: ClientOptions{ToolObserver: ToolObserverFuncs{
+			Result: func(info ToolResultInfo) { infos = append(infos, info) },
+		}}}
+		result := client.executeToolCall(context.Background(), "s1", "call-1", "lookup_issue", nil, func(inv ToolInvocation) (ToolResult, error) {
+			return ToolResult{}, notFound
+		})
+		if result.TextResultForLLM != "Error [issue_not_found]: Issue #7 does not exist." {
+			t.Errorf("Unexpected result %+v", result)
+		}
+		if len(infos) != 1 || infos[0].ErrorCode != "issue_not_found" || infos[0].ErrorClass != ToolErrorHandler {
+			t.Errorf("Expected the error code to be observed, got %+v", infos)
+		}
+
+		result = client.executeToolCall(context.Background(), "s1", "call-2", "lookup_issue", nil, func(inv ToolInvocation) (ToolResult, error) {
+			return ToolResult{}, errors.New("internal detail")
+		})
+		if strings.Contains(result.TextResultForLLM, "internal detail") || infos[1].ErrorCode != "" {
+			t.Errorf("Expected other errors to stay hidden, got %+v", result)
+		}
+	})
+}
@@ -0,0 +1,34 @@
+package copilot
+
+// filterTools returns the tools that filter keeps, or tools if filter is nil.
+// A deprecated tool is kept only if the tool its calls forward to is kept
+// too, so that an alias is never advertised without a handler.
+func filterTools(tools []Tool, filter func(Tool) bool) []Tool {
+	if filter == nil {
+		return tools
+	}
+	kept := make([]Tool, 0, len(tools))
+	byName := make(map[string]Tool, len(tools))
+	for _, tool := range tools {
+		if filter(tool) {
+			kept = append(kept, tool)
+			byName[tool.Name] = tool
+		}
+	}
+	filtered := kept[:0]
+	for _, tool := range kept {
+		if tool.Deprecated != nil && tool.Deprecated.ReplacedBy != "" && resolveReplacement(byName, tool.Name) == "" {
+			continue
+		}
+		filtered = append(filtered, tool)
+	}
+	return filtered
+}
+
+// registerToolFilter sets the filter that selects which of the session's
+// tools, including tools its registry adds later, it exposes.
+func (s *Session) registerToolFilter(filter func(Tool) bool) {
+	s.toolHandlersM.Lock()
+	defer s.toolHandlersM.Unlock()
+	s.toolFilter = filter
+}
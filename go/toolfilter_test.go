@@ -0,0 +1,74 @@
+package copilot
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestToolFilter(t *testing.T) {
+	var mu sync.Mutex
+	advertised := make(map[string]string)
+	rpc, _ := newFakeServer(t, func(method string, params map[string]interface{}) (map[string]interface{}, *JSONRPCError) {
+		switch method {
+		case "session.create":
+			mu.Lock()
+			id := fmt.Sprintf("s%d", len(advertised)+1)
+			advertised[id] = strings.Join(advertisedNames(params["tools"]), ",")
+			mu.Unlock()
+			return map[string]interface{}{"sessionId": id}, nil
+		case "session.updateTools":
+			mu.Lock()
+			advertised[params["sessionId"].(string)] = strings.Join(advertisedNames(params["tools"]), ",")
+			mu.Unlock()
+		}
+		return nil, nil
+	})
+	client := &Client{client: rpc, state: StateConnected, sessions: make(map[string]*Session)}
+	registry, _ := NewToolRegistry(namedTool("read_file", "read"), namedTool("write_file", "write"))
+	readOnly := func(tool Tool) bool { return strings.HasPrefix(tool.Name, "read_") }
+
+	trusted, err := client.CreateSession(&SessionConfig{ToolRegistry: registry})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	untrusted, err := client.CreateSession(&SessionConfig{
+		Tools:        []Tool{namedTool("write_note", "note"), DeprecatedAlias("cat", "read_file"), DeprecatedAlias("save", "write_file")},
+		ToolRegistry: registry,
+		ToolFilter: func(tool Tool) bool {
+			return readOnly(tool) || tool.Deprecated != nil
+		},
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	advertisedTo := func(session *Session) string {
+		mu.Lock()
+		defer mu.Unlock()
+		return advertised[session.SessionID]
+	}
+	if got := advertisedTo(trusted); got != "read_file,write_file" {
+		t.Errorf("Expected every tool for the unfiltered session, got %s", got)
+	}
+	if got := advertisedTo(untrusted); got != "cat,read_file" {
+		t.Errorf("Expected only read-only tools and their aliases, got %s", got)
+	}
+	if _, ok := untrusted.getToolHandler("write_file"); ok {
+		t.Error("Expected a filtered-out tool to have no handler")
+	}
+	if _, ok := untrusted.getToolHandler("cat"); !ok {
+		t.Error("Expected the alias of a kept tool to have a handler")
+	}
+
+	if err := registry.Register(namedTool("read_dir", "list"), namedTool("delete_file", "delete")); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if got := advertisedTo(untrusted); got != "cat,read_dir,read_file" {
+		t.Errorf("Expected the filter to apply to registry updates, got %s", got)
+	}
+	if got := advertisedTo(trusted); got != "delete_file,read_dir,read_file,write_file" {
+		t.Errorf("Unexpected tools for the unfiltered session: %s", got)
+	}
+}
@@ -0,0 +1,279 @@
+package copilot
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"unicode"
+)
+
+// ToolMethodDescriber can be implemented by the value passed to
+// [DefineToolsFromInterface] to document the tools made from its methods.
+type ToolMethodDescriber interface {
+	// DescribeToolMethod describes the tool made from the named method.
+	DescribeToolMethod(method string) ToolMethodDescription
+}
+
+// ToolMethodDescription describes the tool made from a method by
+// [DefineToolsFromInterface].
+type ToolMethodDescription struct {
+	// Name is the tool's name. Defaults to the method name in snake case, such
+	// as "get_weather" for GetWeather.
+	Name string
+	// Description is the tool's description. Defaults to a sentence naming the
+	// method.
+	Description string
+	// ArgNames name the method's arguments other than a context.Context or
+	// ToolInvocation, for methods that take them as separate values rather than
+	// a single struct. Defaults to "arg1", "arg2", and so on.
+	ArgNames []string
+	// Skip leaves the method out.
+	Skip bool
+}
+
+var (
+	contextType        = reflect.TypeOf((*context.Context)(nil)).Elem()
+	toolInvocationType = reflect.TypeOf(ToolInvocation{})
+	errorType          = reflect.TypeOf((*error)(nil)).Elem()
+)
+
+// DefineToolsFromInterface creates a tool for each exported method of impl, so
+// that a service with a Go API can be exposed to the model without writing a
+// handler per method. Tools are returned in method name order, and opts apply
+// to every tool.
+//
+// A method's arguments become the tool's parameters: a single struct (or
+// pointer to struct) argument is used as the parameters struct, as with
+// [DefineTool], and other arguments become parameters named by
+// [ToolMethodDescription.ArgNames]. Arguments of type context.Context receive
+// the call's context, and arguments of type [ToolInvocation] the invocation.
+// A method may return nothing, an error, a result, or a result and an error;
+// results are handled as with DefineTool.
+//
+// Implement [ToolMethodDescriber] on impl to name and describe the tools, or
+// to skip methods. Variadic methods, methods returning other values, and
+// methods whose names collide in snake case are errors.
+//
+// Example:
+//
+//	type Weather struct{ client *forecast.Client }
+//
+//	type ForecastParams struct {
+//	    City string `json:"city" jsonschema:"City name"`
+//	    Days int    `json:"days" jsonschema:"optional,minimum=1,maximum=14"`
+//	}
+//
+//	func (w *Weather) Forecast(ctx context.Context, params ForecastParams) ([]forecast.Day, error) { ... }
+//	func (w *Weather) Alerts(ctx context.Context, region string) ([]forecast.Alert, error) { ... }
+//
+//	func (w *Weather) DescribeToolMethod(method string) copilot.ToolMethodDescription {
+//	    switch method {
+//	    case "Forecast":
+//	        return copilot.ToolMethodDescription{Description: "Get the forecast for a city"}
+//	    case "Alerts":
+//	        return copilot.ToolMethodDescription{Description: "List weather alerts", ArgNames: []string{"region"}}
+//	    }
+//	    return copilot.ToolMethodDescription{}
+//	}
+//
+//	tools, err := copilot.DefineToolsFromInterface(&Weather{client: client}) // forecast, alerts
+func DefineToolsFromInterface(impl any, opts ...ToolOption) ([]Tool, error) {
+	if impl == nil {
+		return nil, errors.New("failed to define tools: impl is nil")
+	}
+	value := reflect.ValueOf(impl)
+	implType := value.Type()
+	describer, _ := impl.(ToolMethodDescriber)
+
+	var tools []Tool
+	methods := make(map[string]string)
+	for i := 0; i < implType.NumMethod(); i++ {
+		method := implType.Method(i)
+		if method.Name == "DescribeToolMethod" && describer != nil {
+			continue
+		}
+		var description ToolMethodDescription
+		if describer != nil {
+			description = describer.DescribeToolMethod(method.Name)
+		}
+		if description.Skip {
+			continue
+		}
+		if description.Name == "" {
+			description.Name = snakeCase(method.Name)
+		}
+		if description.Description == "" {
+			description.Description = fmt.Sprintf("Calls %s.%s.", strings.TrimPrefix(implType.String(), "*"), method.Name)
+		}
+		if other, ok := methods[description.Name]; ok {
+			return nil, fmt.Errorf("failed to define tools: methods %s and %s are both named %s", other, method.Name, description.Name)
+		}
+		methods[description.Name] = method.Name
+
+		tool, err := defineMethodTool(value.Method(i), method.Name, description, opts)
+		if err != nil {
+			return nil, err
+		}
+		tools = append(tools, tool)
+	}
+	return tools, nil
+}
+
+// methodArg is how an argument of a method tool is filled in.
+type methodArg struct {
+	kind  methodArgKind
+	field int
+}
+
+type methodArgKind int
+
+const (
+	methodArgContext methodArgKind = iota
+	methodArgInvocation
+	// methodArgParams is a single struct argument, decoded from the arguments.
+	methodArgParams
+	// methodArgField is one of several arguments, decoded from a field of a
+	// generated parameters struct.
+	methodArgField
+)
+
+// defineMethodTool creates the tool for method, named name.
+func defineMethodTool(method reflect.Value, name string, description ToolMethodDescription, opts []ToolOption) (Tool, error) {
+	fail := func(format string, args ...interface{}) (Tool, error) {
+		return Tool{}, fmt.Errorf("failed to define tool %s from method %s: %s", description.Name, name, fmt.Sprintf(format, args...))
+	}
+	methodType := method.Type()
+	if methodType.IsVariadic() {
+		return fail("variadic methods are not supported")
+	}
+
+	// Classify the arguments.
+	var values []int
+	args := make([]methodArg, methodType.NumIn())
+	for i := 0; i < methodType.NumIn(); i++ {
+		switch in := methodType.In(i); {
+		case in == contextType:
+			args[i] = methodArg{kind: methodArgContext}
+		case in == toolInvocationType:
+			args[i] = methodArg{kind: methodArgInvocation}
+		default:
+			values = append(values, i)
+		}
+	}
+	var paramsType reflect.Type
+	if len(values) == 1 && isStructOrStructPointer(methodType.In(values[0])) {
+		args[values[0]] = methodArg{kind: methodArgParams}
+		paramsType = methodType.In(values[0])
+		if paramsType.Kind() == reflect.Ptr {
+			paramsType = paramsType.Elem()
+		}
+	} else {
+		if len(description.ArgNames) > 0 && len(description.ArgNames) != len(values) {
+			return fail("%d argument names given for %d arguments", len(description.ArgNames), len(values))
+		}
+		fields := make([]reflect.StructField, len(values))
+		for field, i := range values {
+			argName := fmt.Sprintf("arg%d", field+1)
+			if len(description.ArgNames) > 0 {
+				argName = description.ArgNames[field]
+			}
+			fields[field] = reflect.StructField{
+				Name: fmt.Sprintf("Arg%d", field+1),
+				Type: methodType.In(i),
+				Tag:  reflect.StructTag(fmt.Sprintf(`json:"%s"`, argName)),
+			}
+			args[i] = methodArg{kind: methodArgField, field: field}
+		}
+		paramsType = reflect.StructOf(fields)
+	}
+
+	// Check the results.
+	var resultType reflect.Type
+	returnsError := methodType.NumOut() > 0 && methodType.Out(methodType.NumOut()-1) == errorType
+	switch {
+	case methodType.NumOut() == 0, methodType.NumOut() == 1 && returnsError:
+	case methodType.NumOut() == 1, methodType.NumOut() == 2 && returnsError:
+		resultType = methodType.Out(0)
+	default:
+		return fail("methods must return a result, an error, or both")
+	}
+
+	schema, err := tryGenerateSchemaForType(paramsType)
+	if err != nil {
+		return fail("%v", err)
+	}
+	handler := func(raw json.RawMessage, inv ToolInvocation) (interface{}, error) {
+		params := reflect.New(paramsType)
+		if len(raw) > 0 && string(raw) != "null" {
+			if err := decodeArguments(raw, params.Interface()); err != nil {
+				return nil, fmt.Errorf("failed to unmarshal arguments into %s: %w", paramsType, err)
+			}
+		}
+		in := make([]reflect.Value, len(args))
+		for i, arg := range args {
+			switch arg.kind {
+			case methodArgContext:
+				in[i] = reflect.ValueOf(inv.Context())
+			case methodArgInvocation:
+				in[i] = reflect.ValueOf(inv)
+			case methodArgParams:
+				if methodType.In(i).Kind() == reflect.Ptr {
+					in[i] = params
+				} else {
+					in[i] = params.Elem()
+				}
+			case methodArgField:
+				in[i] = params.Elem().Field(arg.field)
+			}
+		}
+		out := method.Call(in)
+		if returnsError {
+			if err, _ := out[len(out)-1].Interface().(error); err != nil {
+				return nil, err
+			}
+		}
+		if resultType == nil {
+			return nil, nil
+		}
+		return out[0].Interface(), nil
+	}
+
+	tool, err := TryDefineTool(description.Name, description.Description, handler, append([]ToolOption{WithParameters(schema)}, opts...)...)
+	if err != nil {
+		return Tool{}, err
+	}
+	tool.resultSchema = resultSchemaForType(resultType)
+	return tool, nil
+}
+
+func isStructOrStructPointer(t reflect.Type) bool {
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return t.Kind() == reflect.Struct
+}
+
+// snakeCase converts a Go identifier to snake case, keeping initialisms
+// together: "GetURLPath" becomes "get_url_path" and "ListPRs" "list_prs".
+func snakeCase(name string) string {
+	runes := []rune(name)
+	var b strings.Builder
+	for i, r := range runes {
+		if unicode.IsUpper(r) && i > 0 {
+			previous := runes[i-1]
+			nextIsLower := i+1 < len(runes) && unicode.IsLower(runes[i+1])
+			// A lone "s" pluralizes the initialism before it, as in "URLs".
+			if nextIsLower && runes[i+1] == 's' && (i+2 == len(runes) || !unicode.IsLower(runes[i+2])) {
+				nextIsLower = false
+			}
+			if unicode.IsLower(previous) || unicode.IsDigit(previous) || (unicode.IsUpper(previous) && nextIsLower) {
+				b.WriteByte('_')
+			}
+		}
+		b.WriteRune(unicode.ToLower(r))
+	}
+	return b.String()
+}
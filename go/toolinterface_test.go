@@ -0,0 +1,155 @@
+package copilot
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+type interfaceToolsParams struct {
+	City string `json:"city" jsonschema:"City name"`
+	Days int    `json:"days" jsonschema:"optional,minimum=1"`
+}
+
+type interfaceToolsService struct {
+	calls []string
+}
+
+func (s *interfaceToolsService) GetForecast(ctx context.Context, params interfaceToolsParams) (map[string]interface{}, error) {
+	if ctx == nil {
+		return nil, errors.New("missing context")
+	}
+	return map[string]interface{}{"city": params.City, "days": params.Days}, nil
+}
+
+func (s *interfaceToolsService) Alerts(region string, severe bool) string {
+	return region + ":" + map[bool]string{true: "severe", false: "all"}[severe]
+}
+
+func (s *interfaceToolsService) ListURLs(inv ToolInvocation) ([]string, error) {
+	return []string{inv.ToolCallID}, nil
+}
+
+func (s *interfaceToolsService) Reset() error {
+	s.calls = append(s.calls, "reset")
+	return errors.New("reset is disabled")
+}
+
+func (s *interfaceToolsService) Internal() {}
+
+func (s *interfaceToolsService) DescribeToolMethod(method string) ToolMethodDescription {
+	switch method {
+	case "GetForecast":
+		return ToolMethodDescription{Description: "Get the forecast"}
+	case "Alerts":
+		return ToolMethodDescription{ArgNames: []string{"region", "severe"}}
+	case "Internal":
+		return ToolMethodDescription{Skip: true}
+	}
+	return ToolMethodDescription{}
+}
+
+func TestDefineToolsFromInterface(t *testing.T) {
+	service := &interfaceToolsService{}
+	tools, err := DefineToolsFromInterface(service, WithErrorRenderer(RenderToolError))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	byName := make(map[string]Tool)
+	var names []string
+	for _, tool := range tools {
+		byName[tool.Name] = tool
+		names = append(names, tool.Name)
+	}
+	if got := strings.Join(names, ","); got != "alerts,get_forecast,list_urls,reset" {
+		t.Fatalf("Unexpected tools %s", got)
+	}
+	call := func(name string, args map[string]interface{}) ToolResult {
+		t.Helper()
+		result, err := byName[name].Handler(ToolInvocation{ToolCallID: "call-1", ToolName: name, Arguments: args})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		return result
+	}
+
+	t.Run("uses a struct argument as the parameters", func(t *testing.T) {
+		tool := byName["get_forecast"]
+		if tool.Description != "Get the forecast" {
+			t.Errorf("Unexpected description %q", tool.Description)
+		}
+		properties, _ := tool.Parameters["properties"].(map[string]interface{})
+		if _, ok := properties["city"]; !ok || len(properties) != 2 {
+			t.Errorf("Unexpected parameters %+v", tool.Parameters)
+		}
+		if result := call("get_forecast", map[string]interface{}{"city": "Oslo", "days": 3}); result.TextResultForLLM != `{"city":"Oslo","days":3}` {
+			t.Errorf("Unexpected result %+v", result)
+		}
+		if result := call("get_forecast", map[string]interface{}{"days": 3}); result.ResultType != "failure" {
+			t.Errorf("Expected missing required arguments to fail validation, got %+v", result)
+		}
+	})
+
+	t.Run("names separate arguments", func(t *testing.T) {
+		tool := byName["alerts"]
+		if tool.Description != "Calls copilot.interfaceToolsService.Alerts." {
+			t.Errorf("Unexpected description %q", tool.Description)
+		}
+		if result := call("alerts", map[string]interface{}{"region": "north", "severe": true}); result.TextResultForLLM != "north:severe" {
+			t.Errorf("Unexpected result %+v", result)
+		}
+	})
+
+	t.Run("passes the invocation and reports errors", func(t *testing.T) {
+		if result := call("list_urls", map[string]interface{}{}); result.TextResultForLLM != `["call-1"]` {
+			t.Errorf("Unexpected result %+v", result)
+		}
+		if result := call("reset", map[string]interface{}{}); result.ResultType != "failure" || !strings.Contains(result.TextResultForLLM, "reset is disabled") {
+			t.Errorf("Expected the method's error to be rendered, got %+v", result)
+		}
+		if len(service.calls) != 1 {
+			t.Errorf("Expected Reset to be called once, got %v", service.calls)
+		}
+	})
+}
+
+type collidingToolsService struct{}
+
+func (collidingToolsService) GetURL() string { return "" }
+func (collidingToolsService) GetUrl() string { return "" }
+
+type badToolsService struct{}
+
+func (badToolsService) Sum(values ...int) int           { return 0 }
+func (badToolsService) Pair() (string, string)          { return "", "" }
+func (badToolsService) Check(a string) (string, string) { return "", "" }
+
+func TestDefineToolsFromInterface_Errors(t *testing.T) {
+	if _, err := DefineToolsFromInterface(nil); err == nil {
+		t.Error("Expected an error for a nil impl")
+	}
+	if _, err := DefineToolsFromInterface(collidingToolsService{}); err == nil || !strings.Contains(err.Error(), "both named get_url") {
+		t.Errorf("Expected a name collision error, got %v", err)
+	}
+	if _, err := DefineToolsFromInterface(badToolsService{}); err == nil || !strings.Contains(err.Error(), "must return") {
+		t.Errorf("Expected an error for unsupported results, got %v", err)
+	}
+}
+
+func TestSnakeCase(t *testing.T) {
+	for input, want := range map[string]string{
+		"GetWeather": "get_weather",
+		"ListPRs":    "list_prs",
+		"GetURLPath": "get_url_path",
+		"HTTPGet":    "http_get",
+		"V2Search":   "v2_search",
+		"Run":        "run",
+		"GetURLsFor": "get_urls_for",
+		"HTTPServer": "http_server",
+	} {
+		if got := snakeCase(input); got != want {
+			t.Errorf("snakeCase(%q) = %q, want %q", input, got, want)
+		}
+	}
+}
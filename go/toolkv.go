@@ -0,0 +1,149 @@
+package copilot
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+)
+
+// ToolKV is a key-value store shared by the tool calls of a session, for state
+// such as auth tokens or open database handles that handlers would otherwise
+// keep in global variables. Get it with [ToolInvocation.KV] in a handler, or
+// [Session.ToolKV] to seed it before a turn. It is safe for concurrent use.
+//
+// Values that implement [io.Closer] are closed when the session is destroyed;
+// errors from closing them are ignored.
+//
+// Example:
+//
+//	tool := copilot.DefineToolCtx("query_db", "Run a read-only SQL query",
+//	    func(ctx context.Context, params QueryParams, inv copilot.ToolInvocation) ([]Row, error) {
+//	        db, err := copilot.LoadOrCreate(inv.KV(), "db", func() (*sql.DB, error) {
+//	            return sql.Open("postgres", dsn)
+//	        })
+//	        if err != nil {
+//	            return nil, err
+//	        }
+//	        return queryRows(ctx, db, params.SQL)
+//	    })
+type ToolKV struct {
+	mu     sync.Mutex
+	values map[string]interface{}
+	// creating serializes LoadOrCreate calls, so that a value is created once.
+	creating sync.Mutex
+}
+
+// NewToolKV creates an empty store, for example to call a handler directly in
+// a test with [ToolInvocation.WithKV].
+func NewToolKV() *ToolKV {
+	return &ToolKV{values: make(map[string]interface{})}
+}
+
+// Get returns the value stored under key, and whether there is one.
+func (kv *ToolKV) Get(key string) (interface{}, bool) {
+	kv.mu.Lock()
+	defer kv.mu.Unlock()
+	value, ok := kv.values[key]
+	return value, ok
+}
+
+// Set stores value under key, replacing any previous value without closing it.
+func (kv *ToolKV) Set(key string, value interface{}) {
+	kv.mu.Lock()
+	defer kv.mu.Unlock()
+	kv.values[key] = value
+}
+
+// Delete removes the value stored under key, without closing it, and returns
+// it.
+func (kv *ToolKV) Delete(key string) (interface{}, bool) {
+	kv.mu.Lock()
+	defer kv.mu.Unlock()
+	value, ok := kv.values[key]
+	delete(kv.values, key)
+	return value, ok
+}
+
+// Keys returns the stored keys in order.
+func (kv *ToolKV) Keys() []string {
+	kv.mu.Lock()
+	defer kv.mu.Unlock()
+	keys := make([]string, 0, len(kv.values))
+	for key := range kv.values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// LoadOrCreate returns the value of type T stored under key, storing the value
+// create returns if there is none. Concurrent calls create the value once; if
+// create fails, nothing is stored and a later call tries again. It fails if the
+// stored value is not a T.
+func LoadOrCreate[T any](kv *ToolKV, key string, create func() (T, error)) (T, error) {
+	var zero T
+	kv.creating.Lock()
+	defer kv.creating.Unlock()
+
+	if value, ok := kv.Get(key); ok {
+		typed, ok := value.(T)
+		if !ok {
+			return zero, fmt.Errorf("tool KV value %s is a %T, not a %T", key, value, zero)
+		}
+		return typed, nil
+	}
+	value, err := create()
+	if err != nil {
+		return zero, err
+	}
+	kv.Set(key, value)
+	return value, nil
+}
+
+// close closes the stored values that implement [io.Closer] and empties the
+// store.
+func (kv *ToolKV) close() error {
+	kv.mu.Lock()
+	values := kv.values
+	kv.values = make(map[string]interface{})
+	kv.mu.Unlock()
+
+	var errs []error
+	for key, value := range values {
+		if closer, ok := value.(io.Closer); ok {
+			if err := closer.Close(); err != nil {
+				errs = append(errs, fmt.Errorf("failed to close tool KV value %s: %w", key, err))
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// KV returns the store shared by the tool calls of the invocation's session.
+// Invocations not made by a session, such as a handler called directly in a
+// test, get an empty store of their own unless one is set with
+// [ToolInvocation.WithKV].
+func (inv ToolInvocation) KV() *ToolKV {
+	if inv.kv == nil {
+		return NewToolKV()
+	}
+	return inv.kv
+}
+
+// WithKV returns a copy of the invocation that uses kv as its store.
+func (inv ToolInvocation) WithKV(kv *ToolKV) ToolInvocation {
+	inv.kv = kv
+	return inv
+}
+
+// ToolKV returns the store shared by the session's tool calls. See [ToolKV].
+func (s *Session) ToolKV() *ToolKV {
+	s.toolKVOnce.Do(func() {
+		s.toolKV = NewToolKV()
+		kv := s.toolKV
+		s.onDestroy(func() { kv.close() })
+	})
+	return s.toolKV
+}
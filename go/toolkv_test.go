@@ -0,0 +1,97 @@
+package copilot
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+type closingValue struct{ closed atomic.Bool }
+
+func (v *closingValue) Close() error {
+	v.closed.Store(true)
+	return nil
+}
+
+func TestToolKV(t *testing.T) {
+	t.Run("shares state across calls in a session", func(t *testing.T) {
+		session := NewSession("s1", nil, "")
+		other := NewSession("s2", nil, "")
+		client := &Client{sessions: map[string]*Session{"s1": session, "s2": other}}
+		session.ToolKV().Set("token", "seeded")
+
+		var seen []interface{}
+		handler := func(inv ToolInvocation) (ToolResult, error) {
+			value, _ := inv.KV().Get("token")
+			seen = append(seen, value)
+			inv.KV().Set("token", "refreshed")
+			return ToolResult{ResultType: "success"}, nil
+		}
+		client.executeToolCall(session.toolContext(), "s1", "call-1", "auth", nil, handler)
+		client.executeToolCall(session.toolContext(), "s1", "call-2", "auth", nil, handler)
+		client.executeToolCall(other.toolContext(), "s2", "call-3", "auth", nil, handler)
+
+		if len(seen) != 3 || seen[0] != "seeded" || seen[1] != "refreshed" || seen[2] != nil {
+			t.Errorf("Expected state shared within the session only, got %v", seen)
+		}
+	})
+
+	t.Run("creates values once", func(t *testing.T) {
+		kv := NewToolKV()
+		var creates atomic.Int32
+		var wg sync.WaitGroup
+		for i := 0; i < 8; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				value, err := LoadOrCreate(kv, "db", func() (*closingValue, error) {
+					creates.Add(1)
+					return &closingValue{}, nil
+				})
+				if err != nil || value == nil {
+					t.Errorf("Unexpected result %v, %v", value, err)
+				}
+			}()
+		}
+		wg.Wait()
+		if creates.Load() != 1 {
+			t.Errorf("Expected one create, got %d", creates.Load())
+		}
+
+		if _, err := LoadOrCreate(kv, "db", func() (string, error) { return "", nil }); err == nil {
+			t.Error("Expected an error for a value of another type")
+		}
+		if _, err := LoadOrCreate(kv, "cache", func() (int, error) { return 0, errors.New("unavailable") }); err == nil {
+			t.Error("Expected the create error")
+		}
+		if keys := kv.Keys(); len(keys) != 1 || keys[0] != "db" {
+			t.Errorf("Expected a failed create to store nothing, got %v", keys)
+		}
+	})
+
+	t.Run("closes values when the session is destroyed", func(t *testing.T) {
+		session := NewSession("s1", nil, "")
+		value := &closingValue{}
+		session.ToolKV().Set("db", value)
+		if removed, ok := session.ToolKV().Delete("missing"); ok || removed != nil {
+			t.Error("Expected no value for a missing key")
+		}
+		session.runDestroyHooks()
+		if !value.closed.Load() {
+			t.Error("Expected the value to be closed")
+		}
+		if keys := session.ToolKV().Keys(); len(keys) != 0 {
+			t.Errorf("Expected an empty store, got %v", keys)
+		}
+	})
+
+	t.Run("WithKV sets the store of a direct call", func(t *testing.T) {
+		kv := NewToolKV()
+		inv := ToolInvocation{}.WithKV(kv)
+		inv.KV().Set("k", 1)
+		if value, ok := kv.Get("k"); !ok || value != 1 {
+			t.Errorf("Expected the call to use the given store, got %v", value)
+		}
+	})
+}
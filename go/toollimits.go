@@ -0,0 +1,310 @@
+package copilot
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// ToolLimits are limits on the tools a session can advertise. The server
+// declares its limits in the handshake when the client connects; a tool that
+// exceeds them would not be shown to the model. Zero means no limit.
+type ToolLimits struct {
+	// MaxNameLength limits the length of tool names, in characters.
+	MaxNameLength int `json:"maxNameLength,omitempty"`
+	// MaxDescriptionLength limits the length of tool descriptions, in
+	// characters.
+	MaxDescriptionLength int `json:"maxDescriptionLength,omitempty"`
+	// MaxTools limits the number of tools a session advertises.
+	MaxTools int `json:"maxTools,omitempty"`
+}
+
+// orElse returns l with the limits it does not set taken from fallback.
+func (l ToolLimits) orElse(fallback ToolLimits) ToolLimits {
+	if l.MaxNameLength == 0 {
+		l.MaxNameLength = fallback.MaxNameLength
+	}
+	if l.MaxDescriptionLength == 0 {
+		l.MaxDescriptionLength = fallback.MaxDescriptionLength
+	}
+	if l.MaxTools == 0 {
+		l.MaxTools = fallback.MaxTools
+	}
+	return l
+}
+
+// ToolLimitMode is what a [Client] does with tools that exceed its
+// [ToolLimits].
+type ToolLimitMode string
+
+const (
+	// ToolLimitsStrict fails the session's creation, resumption, or tool
+	// update with a [*ToolLimitError]. It is the default.
+	ToolLimitsStrict ToolLimitMode = "strict"
+	// ToolLimitsTruncate shortens names and descriptions that are too long,
+	// and drops the last tools beyond MaxTools, reporting each change to
+	// OnWarning.
+	ToolLimitsTruncate ToolLimitMode = "truncate"
+	// ToolLimitsIgnore advertises tools as they are.
+	ToolLimitsIgnore ToolLimitMode = "ignore"
+)
+
+// ToolLimitsConfig configures how a [Client] enforces the tool limits the
+// server declares. Without it, tools that exceed them fail the session's
+// creation.
+//
+// Example:
+//
+//	client := copilot.NewClient(&copilot.ClientOptions{
+//	    ToolLimits: &copilot.ToolLimitsConfig{
+//	        Mode:      copilot.ToolLimitsTruncate,
+//	        OnWarning: func(v copilot.ToolLimitViolation) { log.Printf("copilot: %s", v) },
+//	    },
+//	})
+type ToolLimitsConfig struct {
+	// Mode is what to do with tools that exceed a limit. Defaults to
+	// [ToolLimitsStrict].
+	Mode ToolLimitMode
+	// Limits are the limits to enforce where the server declares none.
+	Limits ToolLimits
+	// OnWarning is called for each change [ToolLimitsTruncate] makes.
+	OnWarning func(violation ToolLimitViolation)
+}
+
+// validate reports an unknown Mode.
+func (c *ToolLimitsConfig) validate() error {
+	switch c.Mode {
+	case "", ToolLimitsStrict, ToolLimitsTruncate, ToolLimitsIgnore:
+		return nil
+	}
+	return fmt.Errorf("invalid ToolLimits: unknown Mode %q", c.Mode)
+}
+
+// ToolLimitKind names the limit a [ToolLimitViolation] exceeds.
+type ToolLimitKind string
+
+const (
+	// ToolLimitName is the limit on the length of tool names.
+	ToolLimitName ToolLimitKind = "name"
+	// ToolLimitDescription is the limit on the length of tool descriptions.
+	ToolLimitDescription ToolLimitKind = "description"
+	// ToolLimitCount is the limit on the number of tools.
+	ToolLimitCount ToolLimitKind = "count"
+)
+
+// ToolLimitViolation describes a tool, or the set of tools, that exceeds a
+// [ToolLimits] limit.
+type ToolLimitViolation struct {
+	// ToolName is the tool that exceeds the limit, or empty if there are too
+	// many tools.
+	ToolName string
+	Kind     ToolLimitKind
+	// Length is the tool's name or description length, or the number of
+	// tools, and Limit the limit it exceeds.
+	Length int
+	Limit  int
+	// Dropped are the tools [ToolLimitsTruncate] did not advertise because
+	// there were too many.
+	Dropped []string
+}
+
+func (v ToolLimitViolation) String() string {
+	switch v.Kind {
+	case ToolLimitName:
+		return fmt.Sprintf("tool name %q is %d characters, over the limit of %d: shorten it", v.ToolName, v.Length, v.Limit)
+	case ToolLimitDescription:
+		return fmt.Sprintf("description of tool %s is %d characters, over the limit of %d: shorten it, or move details into its parameter descriptions", v.ToolName, v.Length, v.Limit)
+	default:
+		message := fmt.Sprintf("%d tools are advertised, over the limit of %d: remove %d of them, or split them across sessions", v.Length, v.Limit, v.Length-v.Limit)
+		if len(v.Dropped) > 0 {
+			message += "; dropped " + strings.Join(v.Dropped, ", ")
+		}
+		return message
+	}
+}
+
+// ToolLimitError is returned when tools exceed the client's [ToolLimits] in
+// [ToolLimitsStrict] mode, or when truncating them would make two tool names
+// the same.
+type ToolLimitError struct {
+	Violations []ToolLimitViolation
+}
+
+func (e *ToolLimitError) Error() string {
+	messages := make([]string, len(e.Violations))
+	for i, violation := range e.Violations {
+		messages[i] = violation.String()
+	}
+	return "tools exceed the tool limits: " + strings.Join(messages, "; ")
+}
+
+// toolLimitPolicy is the enforcement of a client's tool limits.
+type toolLimitPolicy struct {
+	mode      ToolLimitMode
+	limits    ToolLimits
+	onWarning func(ToolLimitViolation)
+}
+
+// toolLimitPolicy returns the policy of the client, with the limits the
+// server declared.
+func (c *Client) toolLimitPolicy() toolLimitPolicy {
+	policy := toolLimitPolicy{mode: ToolLimitsStrict, limits: c.ToolLimits()}
+	if config := c.options.ToolLimits; config != nil {
+		if config.Mode != "" {
+			policy.mode = config.Mode
+		}
+		policy.onWarning = config.OnWarning
+	}
+	return policy
+}
+
+// ToolLimits returns the tool limits the client enforces: those the server
+// declared when the client connected, and [ToolLimitsConfig.Limits] for the
+// others.
+func (c *Client) ToolLimits() ToolLimits {
+	var limits ToolLimits
+	if declared := c.serverToolLimits.Load(); declared != nil {
+		limits = *declared
+	}
+	if c.options.ToolLimits != nil {
+		limits = limits.orElse(c.options.ToolLimits.Limits)
+	}
+	return limits
+}
+
+// enforce returns tools within the policy's limits. In strict mode it fails
+// if any tool exceeds them; in truncate mode it returns the tools changed to
+// fit and the changes made.
+func (p toolLimitPolicy) enforce(tools []Tool) ([]Tool, []ToolLimitViolation, error) {
+	if p.mode == ToolLimitsIgnore || p.limits == (ToolLimits{}) {
+		return tools, nil, nil
+	}
+
+	var violations []ToolLimitViolation
+	limited := make([]Tool, 0, len(tools))
+	advertised := 0
+	for _, tool := range tools {
+		if tool.Name == "" {
+			limited = append(limited, tool)
+			continue
+		}
+		advertised++
+		name := tool.Name
+		if n := utf8.RuneCountInString(name); p.limits.MaxNameLength > 0 && n > p.limits.MaxNameLength {
+			violations = append(violations, ToolLimitViolation{ToolName: name, Kind: ToolLimitName, Length: n, Limit: p.limits.MaxNameLength})
+			tool.Name = truncateRunes(name, p.limits.MaxNameLength, "")
+		}
+		if n := utf8.RuneCountInString(tool.Description); p.limits.MaxDescriptionLength > 0 && n > p.limits.MaxDescriptionLength {
+			violations = append(violations, ToolLimitViolation{ToolName: name, Kind: ToolLimitDescription, Length: n, Limit: p.limits.MaxDescriptionLength})
+			tool.Description = truncateRunes(tool.Description, p.limits.MaxDescriptionLength, "...")
+		}
+		limited = append(limited, tool)
+	}
+	if p.limits.MaxTools > 0 && advertised > p.limits.MaxTools {
+		violation := ToolLimitViolation{Kind: ToolLimitCount, Length: advertised, Limit: p.limits.MaxTools}
+		if p.mode == ToolLimitsTruncate {
+			kept, seen := limited[:0], 0
+			for _, tool := range limited {
+				if tool.Name != "" {
+					if seen++; seen > p.limits.MaxTools {
+						violation.Dropped = append(violation.Dropped, tool.Name)
+						continue
+					}
+				}
+				kept = append(kept, tool)
+			}
+			limited = kept
+		}
+		violations = append(violations, violation)
+	}
+	if len(violations) == 0 {
+		return tools, nil, nil
+	}
+	if p.mode != ToolLimitsTruncate {
+		return nil, nil, &ToolLimitError{Violations: violations}
+	}
+
+	names := make(map[string]bool, len(limited))
+	for _, tool := range limited {
+		if tool.Name == "" {
+			continue
+		}
+		if names[tool.Name] {
+			return nil, nil, &ToolLimitError{Violations: nameViolations(violations, tool.Name)}
+		}
+		names[tool.Name] = true
+	}
+	return limited, violations, nil
+}
+
+// nameViolations returns the name violations of the tools whose names were
+// truncated to name.
+func nameViolations(violations []ToolLimitViolation, name string) []ToolLimitViolation {
+	var collided []ToolLimitViolation
+	for _, violation := range violations {
+		if violation.Kind == ToolLimitName && strings.HasPrefix(violation.ToolName, name) {
+			collided = append(collided, violation)
+		}
+	}
+	return collided
+}
+
+// warn reports the changes enforce made to the policy's OnWarning.
+func (p toolLimitPolicy) warn(violations []ToolLimitViolation) {
+	if p.onWarning == nil {
+		return
+	}
+	for _, violation := range violations {
+		p.onWarning(violation)
+	}
+}
+
+// truncateRunes shortens s to at most max characters, ending with suffix.
+func truncateRunes(s string, max int, suffix string) string {
+	keep := max - utf8.RuneCountInString(suffix)
+	if keep < 0 {
+		keep, suffix = max, ""
+	}
+	runes := []rune(s)
+	return string(runes[:keep]) + suffix
+}
+
+// limitTools applies the client's tool limits to the tools of a session being
+// created or resumed.
+func (c *Client) limitTools(tools []Tool) ([]Tool, error) {
+	policy := c.toolLimitPolicy()
+	limited, violations, err := policy.enforce(tools)
+	if err != nil {
+		return nil, err
+	}
+	policy.warn(violations)
+	return limited, nil
+}
+
+func (s *Session) registerToolLimits(policy toolLimitPolicy) {
+	s.toolHandlersM.Lock()
+	defer s.toolHandlersM.Unlock()
+	s.advertiseLimits = policy
+}
+
+// parseToolLimits returns the tool limits declared in a handshake response,
+// or nil if it declares none.
+func parseToolLimits(value interface{}) *ToolLimits {
+	declared, ok := value.(map[string]interface{})
+	if !ok {
+		return nil
+	}
+	limit := func(key string) int {
+		n, _ := declared[key].(float64)
+		return int(n)
+	}
+	limits := ToolLimits{
+		MaxNameLength:        limit("maxNameLength"),
+		MaxDescriptionLength: limit("maxDescriptionLength"),
+		MaxTools:             limit("maxTools"),
+	}
+	if limits == (ToolLimits{}) {
+		return nil
+	}
+	return &limits
+}
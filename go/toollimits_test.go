@@ -0,0 +1,122 @@
+package copilot
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestToolLimits(t *testing.T) {
+	handler := func(inv ToolInvocation) (ToolResult, error) { return ToolResult{ResultType: "success"}, nil }
+	tools := []Tool{
+		{Name: "search_the_entire_issue_tracker", Description: "Search issues", Handler: handler},
+		{Name: "read_file", Description: strings.Repeat("Reads a file. ", 5), Handler: handler},
+		{Name: "write_file", Description: "Write a file", Handler: handler},
+	}
+	limits := ToolLimits{MaxNameLength: 20, MaxDescriptionLength: 30, MaxTools: 2}
+
+	t.Run("fails in strict mode with actionable errors", func(t *testing.T) {
+		_, _, err := toolLimitPolicy{mode: ToolLimitsStrict, limits: limits}.enforce(tools)
+		var limitErr *ToolLimitError
+		if !errors.As(err, &limitErr) || len(limitErr.Violations) != 3 {
+			t.Fatalf("Expected three violations, got %v", err)
+		}
+		for _, want := range []string{
+			`tool name "search_the_entire_issue_tracker" is 31 characters, over the limit of 20: shorten it`,
+			"description of tool read_file is 70 characters, over the limit of 30",
+			"3 tools are advertised, over the limit of 2: remove 1 of them",
+		} {
+			if !strings.Contains(err.Error(), want) {
+				t.Errorf("Expected %q in %q", want, err)
+			}
+		}
+	})
+
+	t.Run("truncates with warnings", func(t *testing.T) {
+		limited, violations, err := toolLimitPolicy{mode: ToolLimitsTruncate, limits: limits}.enforce(tools)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if len(limited) != 2 || limited[0].Name != "search_the_entire_is" || limited[1].Name != "read_file" {
+			t.Fatalf("Unexpected tools %+v", limited)
+		}
+		if len([]rune(limited[1].Description)) != 30 || !strings.HasSuffix(limited[1].Description, "...") {
+			t.Errorf("Expected the description to be truncated, got %q", limited[1].Description)
+		}
+		if len(violations) != 3 || violations[2].Kind != ToolLimitCount || len(violations[2].Dropped) != 1 || violations[2].Dropped[0] != "write_file" {
+			t.Errorf("Unexpected warnings %+v", violations)
+		}
+		if tools[0].Name != "search_the_entire_issue_tracker" {
+			t.Error("Expected the original tools to be unchanged")
+		}
+	})
+
+	t.Run("fails when truncated names collide", func(t *testing.T) {
+		_, _, err := toolLimitPolicy{mode: ToolLimitsTruncate, limits: ToolLimits{MaxNameLength: 6}}.enforce([]Tool{
+			{Name: "search_issues"}, {Name: "search_code"},
+		})
+		var limitErr *ToolLimitError
+		if !errors.As(err, &limitErr) || len(limitErr.Violations) != 2 {
+			t.Errorf("Expected the colliding names to be reported, got %v", err)
+		}
+	})
+
+	t.Run("applies to tool registry updates", func(t *testing.T) {
+		session := NewSession("s1", nil, "")
+		session.registerToolLimits(toolLimitPolicy{mode: ToolLimitsStrict, limits: limits})
+		if _, err := session.setToolHandlers(tools[1:2]); err == nil {
+			t.Error("Expected the update to fail")
+		}
+		if _, ok := session.getToolHandler("read_file"); ok {
+			t.Error("Expected the handlers to be unchanged")
+		}
+	})
+
+	t.Run("passes tools within limits through", func(t *testing.T) {
+		for _, policy := range []toolLimitPolicy{{mode: ToolLimitsIgnore, limits: limits}, {mode: ToolLimitsStrict}} {
+			if limited, _, err := policy.enforce(tools); err != nil || len(limited) != 3 {
+				t.Errorf("Expected %s mode to pass the tools through, got %v", policy.mode, err)
+			}
+		}
+	})
+}
+
+func TestClient_ToolLimits(t *testing.T) {
+	var created bool
+	rpc, _ := newFakeServer(t, func(method string, params map[string]interface{}) (map[string]interface{}, *JSONRPCError) {
+		switch method {
+		case "ping":
+			return map[string]interface{}{"protocolVersion": float64(SdkProtocolVersion), "toolLimits": map[string]interface{}{"maxNameLength": float64(10)}}, nil
+		case "session.create":
+			created = true
+			return map[string]interface{}{"sessionId": "s1"}, nil
+		}
+		return nil, nil
+	})
+	client := &Client{client: rpc, state: StateConnected, sessions: map[string]*Session{}, options: ClientOptions{ToolLimits: &ToolLimitsConfig{Limits: ToolLimits{MaxNameLength: 50, MaxTools: 8}}}}
+	if err := client.verifyProtocolVersion(rpc); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if got := client.ToolLimits(); got != (ToolLimits{MaxNameLength: 10, MaxTools: 8}) {
+		t.Errorf("Expected the server's limits to take precedence, got %+v", got)
+	}
+
+	_, err := client.CreateSession(&SessionConfig{Tools: []Tool{{Name: "lookup_issue_details"}}})
+	var limitErr *ToolLimitError
+	if !errors.As(err, &limitErr) || created {
+		t.Errorf("Expected the session not to be created, got %v", err)
+	}
+
+	var warnings []ToolLimitViolation
+	client.options.ToolLimits.Mode = ToolLimitsTruncate
+	client.options.ToolLimits.OnWarning = func(v ToolLimitViolation) { warnings = append(warnings, v) }
+	session, err := client.CreateSession(&SessionConfig{Tools: []Tool{{Name: "lookup_issue_details", Handler: func(inv ToolInvocation) (ToolResult, error) {
+		return ToolResult{ResultType: "success"}, nil
+	}}}})
+	if err != nil || len(warnings) != 1 {
+		t.Fatalf("Expected the session to be created with a warning, got %v, %+v", err, warnings)
+	}
+	if _, ok := session.getToolHandler("lookup_iss"); !ok {
+		t.Error("Expected the handler to be registered under the truncated name")
+	}
+}
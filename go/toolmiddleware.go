@@ -0,0 +1,69 @@
+package copilot
+
+// ToolMiddleware wraps the handler of every tool on a client or session, for
+// concerns that cut across tools such as logging, metrics, argument redaction,
+// or retries. It receives the next handler in the chain and returns the
+// handler to call instead; the invocation's ToolName identifies the tool.
+//
+// Middleware runs outside the tool's Timeout, so a middleware that retries
+// gives each attempt the full timeout.
+//
+// Example:
+//
+//	logging := func(next copilot.ToolHandler) copilot.ToolHandler {
+//	    return func(inv copilot.ToolInvocation) (copilot.ToolResult, error) {
+//	        start := time.Now()
+//	        result, err := next(inv)
+//	        log.Printf("tool %s took %s", inv.ToolName, time.Since(start))
+//	        return result, err
+//	    }
+//	}
+//	client := copilot.NewClient(&copilot.ClientOptions{
+//	    ToolMiddleware: []copilot.ToolMiddleware{logging},
+//	})
+type ToolMiddleware func(next ToolHandler) ToolHandler
+
+// ChainToolMiddleware composes middleware into one, with the first middleware
+// outermost: it sees each invocation first and each result last.
+func ChainToolMiddleware(middleware ...ToolMiddleware) ToolMiddleware {
+	return func(next ToolHandler) ToolHandler {
+		for i := len(middleware) - 1; i >= 0; i-- {
+			if middleware[i] != nil {
+				next = middleware[i](next)
+			}
+		}
+		return next
+	}
+}
+
+// registerToolMiddleware sets the middleware applied to the session's tools:
+// the client's, outermost, then the session's. It must be called before the
+// tools are registered.
+func (s *Session) registerToolMiddleware(client, session []ToolMiddleware) {
+	middleware := append(append([]ToolMiddleware(nil), client...), session...)
+	s.toolHandlersM.Lock()
+	defer s.toolHandlersM.Unlock()
+	s.toolMiddleware = nil
+	if len(middleware) > 0 {
+		s.toolMiddleware = ChainToolMiddleware(middleware...)
+	}
+}
+
+// wrapToolHandler returns the handler registered for tool: its own handler
+// with its timeout, retries, concurrency limits, post-processors, result
+// sanitizer, result limit, cost, and approval applied, wrapped in the session's
+// middleware. The caller holds s.toolHandlersM.
+func (s *Session) wrapToolHandler(tool Tool) ToolHandler {
+	handler := s.withToolTimeout(tool.Name, tool.Timeout, tool.Handler)
+	handler = s.withRetries(tool.Name, tool.RetryPolicy, handler)
+	handler = s.withInvocationLimits(tool, handler)
+	handler = s.withResultPresentation(tool.Name, tool.PostProcessors, handler)
+	handler = s.withResultSanitizer(handler)
+	handler = s.withResultLimit(tool.ResultLimit, handler)
+	handler = s.withCost(tool, handler)
+	handler = s.withApproval(tool, handler)
+	if s.toolMiddleware == nil {
+		return handler
+	}
+	return s.toolMiddleware(handler)
+}
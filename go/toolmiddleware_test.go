@@ -0,0 +1,116 @@
+package copilot
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func recordingMiddleware(name string, calls *[]string) ToolMiddleware {
+	return func(next ToolHandler) ToolHandler {
+		return func(inv ToolInvocation) (ToolResult, error) {
+			*calls = append(*calls, name+" before "+inv.ToolName)
+			result, err := next(inv)
+			*calls = append(*calls, name+" after "+inv.ToolName)
+			return result, err
+		}
+	}
+}
+
+func TestChainToolMiddleware(t *testing.T) {
+	var calls []string
+	chain := ChainToolMiddleware(recordingMiddleware("outer", &calls), nil, recordingMiddleware("inner", &calls))
+	handler := chain(func(inv ToolInvocation) (ToolResult, error) {
+		calls = append(calls, "handler")
+		return ToolResult{TextResultForLLM: "ok"}, nil
+	})
+
+	result, err := handler(ToolInvocation{ToolName: "search"})
+	if err != nil || result.TextResultForLLM != "ok" {
+		t.Fatalf("Unexpected result %+v, %v", result, err)
+	}
+	expected := "outer before search,inner before search,handler,inner after search,outer after search"
+	if got := strings.Join(calls, ","); got != expected {
+		t.Errorf("Expected %s, got %s", expected, got)
+	}
+}
+
+func TestCreateSession_ToolMiddleware(t *testing.T) {
+	rpc, _ := newFakeServer(t, func(method string, params map[string]interface{}) (map[string]interface{}, *JSONRPCError) {
+		return map[string]interface{}{"sessionId": "s1"}, nil
+	})
+
+	var calls []string
+	client := &Client{
+		client:   rpc,
+		state:    StateConnected,
+		sessions: make(map[string]*Session),
+		options:  ClientOptions{ToolMiddleware: []ToolMiddleware{recordingMiddleware("client", &calls)}},
+	}
+	registry, _ := NewToolRegistry()
+	session, err := client.CreateSession(&SessionConfig{
+		Tools:          []Tool{namedTool("search", "search")},
+		ToolRegistry:   registry,
+		ToolMiddleware: []ToolMiddleware{recordingMiddleware("session", &calls)},
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	run := func(name string) string {
+		calls = nil
+		handler, ok := session.getToolHandler(name)
+		if !ok {
+			t.Fatalf("Expected a handler for %s", name)
+		}
+		result := client.executeToolCall(session.toolContext(), "s1", "call-1", name, nil, handler)
+		if result.TextResultForLLM != name {
+			t.Errorf("Unexpected result %+v", result)
+		}
+		return strings.Join(calls, ",")
+	}
+
+	expected := "client before search,session before search,session after search,client after search"
+	if got := run("search"); got != expected {
+		t.Errorf("Expected %s, got %s", expected, got)
+	}
+
+	if err := registry.Register(namedTool("calendar", "calendar")); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if got := run("calendar"); !strings.HasPrefix(got, "client before calendar,session before calendar") {
+		t.Errorf("Expected middleware on a registry tool, got %s", got)
+	}
+}
+
+func TestToolMiddleware_Retry(t *testing.T) {
+	session := NewSession("s1", nil, "")
+	retry := func(next ToolHandler) ToolHandler {
+		return func(inv ToolInvocation) (ToolResult, error) {
+			result, err := next(inv)
+			for attempt := 1; err != nil && attempt < 3; attempt++ {
+				result, err = next(inv)
+			}
+			return result, err
+		}
+	}
+	session.registerToolMiddleware(nil, []ToolMiddleware{retry})
+
+	attempts := 0
+	session.registerTools([]Tool{{
+		Name: "flaky",
+		Handler: func(inv ToolInvocation) (ToolResult, error) {
+			attempts++
+			if attempts < 3 {
+				return ToolResult{}, errors.New("temporarily unavailable")
+			}
+			return ToolResult{TextResultForLLM: "done"}, nil
+		},
+	}})
+
+	handler, _ := session.getToolHandler("flaky")
+	result, err := handler(ToolInvocation{ToolName: "flaky"})
+	if err != nil || result.TextResultForLLM != "done" || attempts != 3 {
+		t.Errorf("Expected success on the third attempt, got %+v, %v after %d attempts", result, err, attempts)
+	}
+}
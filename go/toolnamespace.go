@@ -0,0 +1,118 @@
+package copilot
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// DefaultToolNamespaceSeparator joins a [ToolNamespace]'s name to the names of
+// its tools. Models only accept letters, digits, underscores, and hyphens in
+// tool names, so it is an underscore rather than a dot.
+const DefaultToolNamespaceSeparator = "_"
+
+// ToolNamespace is a bundle of tools, such as those of one Go package, whose
+// names are prefixed with the namespace's name when they are advertised, so
+// that bundles written independently can be combined without their names
+// colliding. Combine bundles with [ComposeTools].
+//
+// Handlers of the bundle's tools see the name they were defined with in
+// [ToolInvocation.ToolName], without the prefix, so they do not depend on the
+// namespace they are registered under; middleware sees the prefixed name.
+//
+// Example:
+//
+//	tools, err := copilot.ComposeTools(
+//	    copilot.ToolNamespace{Name: "github", Tools: github.Tools()}, // github_search, ...
+//	    copilot.ToolNamespace{Name: "jira", Tools: jira.Tools()},     // jira_search, ...
+//	)
+type ToolNamespace struct {
+	// Name is the prefix of the bundle's tools. An empty name adds the tools
+	// unprefixed.
+	Name string
+	// Separator joins Name to the tool names. Defaults to
+	// [DefaultToolNamespaceSeparator].
+	Separator string
+	Tools     []Tool
+}
+
+// Qualify returns the namespace's tools renamed with its prefix. It fails if a
+// tool has no name or two tools have the same name.
+func (n ToolNamespace) Qualify() ([]Tool, error) {
+	if strings.ContainsAny(n.Name, " \t\n") {
+		return nil, fmt.Errorf("invalid tool namespace %q", n.Name)
+	}
+	separator := n.Separator
+	if separator == "" {
+		separator = DefaultToolNamespaceSeparator
+	}
+	qualified := make([]Tool, 0, len(n.Tools))
+	names := make(map[string]bool, len(n.Tools))
+	for _, tool := range n.Tools {
+		if tool.Name == "" {
+			return nil, n.errorf("tool name is required")
+		}
+		if names[tool.Name] {
+			return nil, n.errorf("tool %s is defined twice", tool.Name)
+		}
+		names[tool.Name] = true
+		if n.Name != "" {
+			tool = namespacedTool(tool, n.Name+separator+tool.Name)
+		}
+		qualified = append(qualified, tool)
+	}
+	return qualified, nil
+}
+
+func (n ToolNamespace) errorf(format string, args ...interface{}) error {
+	if n.Name == "" {
+		return fmt.Errorf(format, args...)
+	}
+	return fmt.Errorf("tool namespace %s: %s", n.Name, fmt.Sprintf(format, args...))
+}
+
+// namespacedTool returns tool advertised as name, with its handler seeing its
+// original name.
+func namespacedTool(tool Tool, name string) Tool {
+	local, handler := tool.Name, tool.Handler
+	tool.Name = name
+	if handler != nil {
+		tool.Handler = func(inv ToolInvocation) (ToolResult, error) {
+			inv.ToolName = local
+			return handler(inv)
+		}
+	}
+	return tool
+}
+
+// ComposeTools returns the tools of namespaces, qualified with their prefixes,
+// in order. It fails if two tools end up with the same name, naming the
+// namespaces that define them.
+func ComposeTools(namespaces ...ToolNamespace) ([]Tool, error) {
+	var tools []Tool
+	owners := make(map[string]string)
+	var errs []error
+	for _, namespace := range namespaces {
+		qualified, err := namespace.Qualify()
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		owner := namespace.Name
+		if owner == "" {
+			owner = "(none)"
+		}
+		for _, tool := range qualified {
+			if other, ok := owners[tool.Name]; ok {
+				errs = append(errs, fmt.Errorf("tool %s is defined by namespace %s and namespace %s", tool.Name, other, owner))
+				continue
+			}
+			owners[tool.Name] = owner
+			tools = append(tools, tool)
+		}
+	}
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+	return tools, nil
+}
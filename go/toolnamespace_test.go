@@ -0,0 +1,81 @@
+package copilot
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestToolNamespace(t *testing.T) {
+	// echoName returns a tool whose result is the name its handler sees.
+	echoName := func(name string) Tool {
+		return Tool{Name: name, Handler: func(inv ToolInvocation) (ToolResult, error) {
+			return ToolResult{TextResultForLLM: inv.ToolName, ResultType: "success"}, nil
+		}}
+	}
+
+	t.Run("prefixes tool names", func(t *testing.T) {
+		tools, err := ComposeTools(
+			ToolNamespace{Name: "github", Tools: []Tool{echoName("search"), echoName("create_issue")}},
+			ToolNamespace{Name: "jira", Separator: "-", Tools: []Tool{echoName("search")}},
+			ToolNamespace{Tools: []Tool{echoName("ping")}},
+		)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var names []string
+		for _, tool := range tools {
+			names = append(names, tool.Name)
+		}
+		if strings.Join(names, ",") != "github_search,github_create_issue,jira-search,ping" {
+			t.Errorf("Unexpected names %v", names)
+		}
+		result, _ := tools[0].Handler(ToolInvocation{ToolName: "github_search"})
+		if result.TextResultForLLM != "search" {
+			t.Errorf("Expected the handler to see its own name, got %q", result.TextResultForLLM)
+		}
+	})
+
+	t.Run("middleware sees the prefixed name", func(t *testing.T) {
+		tools, _ := ToolNamespace{Name: "github", Tools: []Tool{echoName("search")}}.Qualify()
+		var seen string
+		session := NewSession("s1", nil, "")
+		session.registerToolMiddleware(nil, []ToolMiddleware{func(next ToolHandler) ToolHandler {
+			return func(inv ToolInvocation) (ToolResult, error) {
+				seen = inv.ToolName
+				return next(inv)
+			}
+		}})
+		session.registerTools(tools)
+		handler, ok := session.getToolHandler("github_search")
+		if !ok {
+			t.Fatal("Expected the prefixed tool to be registered")
+		}
+		handler(ToolInvocation{ToolName: "github_search"})
+		if seen != "github_search" {
+			t.Errorf("Expected middleware to see github_search, got %q", seen)
+		}
+	})
+
+	t.Run("detects collisions", func(t *testing.T) {
+		_, err := ComposeTools(
+			ToolNamespace{Name: "github", Tools: []Tool{echoName("search")}},
+			ToolNamespace{Tools: []Tool{echoName("github_search")}},
+		)
+		if err == nil || err.Error() != "tool github_search is defined by namespace github and namespace (none)" {
+			t.Errorf("Unexpected error %v", err)
+		}
+		_, err = ComposeTools(ToolNamespace{Name: "jira", Tools: []Tool{echoName("search"), echoName("search")}})
+		if err == nil || err.Error() != "tool namespace jira: tool search is defined twice" {
+			t.Errorf("Unexpected error %v", err)
+		}
+	})
+
+	t.Run("rejects invalid tools", func(t *testing.T) {
+		if _, err := (ToolNamespace{Name: "my tools"}).Qualify(); err == nil {
+			t.Error("Expected an error for a namespace with a space")
+		}
+		if _, err := (ToolNamespace{Name: "jira", Tools: []Tool{{}}}).Qualify(); err == nil {
+			t.Error("Expected an error for a tool without a name")
+		}
+	})
+}
@@ -0,0 +1,177 @@
+package copilot
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+// ToolErrorClass classifies how a tool call failed, for analytics.
+type ToolErrorClass string
+
+const (
+	// ToolErrorNone is the class of calls that succeeded.
+	ToolErrorNone ToolErrorClass = ""
+	// ToolErrorHandler is the class of calls whose handler returned an error or
+	// a failure result.
+	ToolErrorHandler ToolErrorClass = "error"
+	// ToolErrorInvalidArguments is the class of calls rejected by argument
+	// validation before the handler ran.
+	ToolErrorInvalidArguments ToolErrorClass = "invalid_arguments"
+	// ToolErrorTimeout is the class of calls that exceeded their timeout.
+	ToolErrorTimeout ToolErrorClass = "timeout"
+	// ToolErrorCanceled is the class of calls that failed because the turn was
+	// aborted or the session ended.
+	ToolErrorCanceled ToolErrorClass = "canceled"
+	// ToolErrorPanic is the class of calls whose handler panicked.
+	ToolErrorPanic ToolErrorClass = "panic"
+	// ToolErrorDenied is the class of calls the host app did not approve. See
+	// [Tool.RequiresApproval].
+	ToolErrorDenied ToolErrorClass = "denied"
+	// ToolErrorRejected is the class of calls whose result type is "rejected".
+	ToolErrorRejected ToolErrorClass = "rejected"
+	// ToolErrorBudgetExceeded is the class of calls not run because their cost
+	// would exceed a budget. See [CostBudget].
+	ToolErrorBudgetExceeded ToolErrorClass = "budget_exceeded"
+)
+
+// ToolInvocationInfo describes a tool call that is about to run.
+type ToolInvocationInfo struct {
+	SessionID  string
+	ToolCallID string
+	ToolName   string
+	// ArgumentBytes is the size of the call's arguments encoded as JSON.
+	ArgumentBytes int
+	// StartedAt is when the call started.
+	StartedAt time.Time
+}
+
+// ToolResultInfo describes a finished tool call.
+type ToolResultInfo struct {
+	ToolInvocationInfo
+	// Latency is how long the call took, including retries and time spent
+	// waiting for approval or a concurrency slot.
+	Latency time.Duration
+	// ResultBytes is the size of the result sent to the model: its text and
+	// its binary results as encoded.
+	ResultBytes int
+	// ResultType is the result's type, such as "success" or "failure".
+	ResultType string
+	// ErrorClass classifies the failure, or is [ToolErrorNone] for calls that
+	// succeeded.
+	ErrorClass ToolErrorClass
+	// ErrorCode is the Code of the [ToolError] the call failed with, if any.
+	ErrorCode string
+	// Cost is the cost the call was charged, for tools with a [Tool.Cost]
+	// function.
+	Cost ToolCost
+}
+
+// ToolObserver receives the usage of every tool call a [Client] executes, to
+// track which tools the agent uses and how they perform. Calls of different
+// tools and sessions are observed concurrently, so implementations must be
+// safe for concurrent use. Calls simulated in dry-run mode, and calls of
+// tools the session does not have, are not observed.
+//
+// Example:
+//
+//	client := copilot.NewClient(&copilot.ClientOptions{
+//	    ToolObserver: copilot.ToolObserverFuncs{
+//	        Result: func(info copilot.ToolResultInfo) {
+//	            metrics.Histogram("tool.latency", info.Latency, "tool", info.ToolName)
+//	            metrics.Count("tool.calls", 1, "tool", info.ToolName, "error", string(info.ErrorClass))
+//	        },
+//	    },
+//	})
+type ToolObserver interface {
+	// OnToolInvocation is called before a tool call runs.
+	OnToolInvocation(info ToolInvocationInfo)
+	// OnToolResult is called after a tool call finishes, successfully or not.
+	OnToolResult(info ToolResultInfo)
+}
+
+// ToolObserverFuncs is a [ToolObserver] made of functions. Nil functions are
+// skipped.
+type ToolObserverFuncs struct {
+	Invocation func(info ToolInvocationInfo)
+	Result     func(info ToolResultInfo)
+}
+
+// OnToolInvocation calls f.Invocation, if set.
+func (f ToolObserverFuncs) OnToolInvocation(info ToolInvocationInfo) {
+	if f.Invocation != nil {
+		f.Invocation(info)
+	}
+}
+
+// OnToolResult calls f.Result, if set.
+func (f ToolObserverFuncs) OnToolResult(info ToolResultInfo) {
+	if f.Result != nil {
+		f.Result(info)
+	}
+}
+
+// observeToolInvocation reports inv to the client's observer, if it has one,
+// and returns the function that reports its result.
+func (c *Client) observeToolInvocation(inv ToolInvocation) func(result ToolResult, err error) {
+	observer := c.options.ToolObserver
+	if observer == nil {
+		return func(ToolResult, error) {}
+	}
+	info := ToolInvocationInfo{
+		SessionID:  inv.SessionID,
+		ToolCallID: inv.ToolCallID,
+		ToolName:   inv.ToolName,
+		StartedAt:  time.Now(),
+	}
+	if inv.Arguments != nil {
+		if data, err := json.Marshal(inv.Arguments); err == nil {
+			info.ArgumentBytes = len(data)
+		}
+	}
+	observer.OnToolInvocation(info)
+	return func(result ToolResult, err error) {
+		resultBytes := len(result.TextResultForLLM)
+		for _, binary := range result.BinaryResultsForLLM {
+			resultBytes += len(binary.Data)
+		}
+		observer.OnToolResult(ToolResultInfo{
+			ToolInvocationInfo: info,
+			Latency:            time.Since(info.StartedAt),
+			ResultBytes:        resultBytes,
+			ResultType:         result.ResultType,
+			ErrorClass:         classifyToolError(inv, result, err),
+			ErrorCode:          toolErrorCode(result),
+			Cost:               chargedCost(result),
+		})
+	}
+}
+
+// classifyToolError classifies the outcome of a call that returned result,
+// or whose handler returned err.
+func classifyToolError(inv ToolInvocation, result ToolResult, err error) ToolErrorClass {
+	switch result.ResultType {
+	case "denied":
+		return ToolErrorDenied
+	case "rejected":
+		return ToolErrorRejected
+	case "failure":
+	default:
+		return ToolErrorNone
+	}
+	if errorType, _ := result.ToolTelemetry["errorType"].(string); errorType == "panic" {
+		return ToolErrorPanic
+	} else if errorType == "invalid_arguments" {
+		return ToolErrorInvalidArguments
+	} else if errorType == "budget_exceeded" {
+		return ToolErrorBudgetExceeded
+	}
+	if timedOut, _ := result.ToolTelemetry["timedOut"].(bool); timedOut {
+		return ToolErrorTimeout
+	}
+	if errors.Is(err, context.Canceled) || errors.Is(err, ErrClientStopped) || (inv.ctx != nil && inv.ctx.Err() != nil) {
+		return ToolErrorCanceled
+	}
+	return ToolErrorHandler
+}
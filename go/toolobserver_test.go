@@ -0,0 +1,115 @@
+package copilot
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+// recordingToolObserver records what it observes.
+type recordingToolObserver struct {
+	mu          sync.Mutex
+	invocations []ToolInvocationInfo
+	results     []ToolResultInfo
+}
+
+func (o *recordingToolObserver) OnToolInvocation(info ToolInvocationInfo) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.invocations = append(o.invocations, info)
+}
+
+func (o *recordingToolObserver) OnToolResult(info ToolResultInfo) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.results = append(o.results, info)
+}
+
+func TestToolObserver(t *testing.T) {
+	observer := &recordingToolObserver{}
+	session := NewSession("s1", nil, "")
+	client := &Client{sessions: map[string]*Session{"s1": session}, options: ClientOptions{ToolObserver: observer}}
+	lastResult := func() ToolResultInfo {
+		t.Helper()
+		if len(observer.results) == 0 {
+			t.Fatal("Expected an observed result")
+		}
+		return observer.results[len(observer.results)-1]
+	}
+
+	t.Run("reports sizes and latency of successful calls", func(t *testing.T) {
+		client.executeToolCall(context.Background(), "s1", "call-1", "search", map[string]interface{}{"q": "go"}, func(inv ToolInvocation) (ToolResult, error) {
+			time.Sleep(10 * time.Millisecond)
+			return ToolResult{TextResultForLLM: "12345", ResultType: "success", BinaryResultsForLLM: []ToolBinaryResult{{Data: "AAAA"}}}, nil
+		})
+		if len(observer.invocations) != 1 {
+			t.Fatalf("Expected one invocation, got %d", len(observer.invocations))
+		}
+		invocation := observer.invocations[0]
+		if invocation.ToolName != "search" || invocation.ToolCallID != "call-1" || invocation.SessionID != "s1" || invocation.ArgumentBytes != len(`{"q":"go"}`) {
+			t.Errorf("Unexpected invocation %+v", invocation)
+		}
+		result := lastResult()
+		if result.ResultBytes != 9 || result.ResultType != "success" || result.ErrorClass != ToolErrorNone || result.Latency < 10*time.Millisecond {
+			t.Errorf("Unexpected result %+v", result)
+		}
+	})
+
+	t.Run("classifies failures", func(t *testing.T) {
+		canceled, cancel := context.WithCancel(context.Background())
+		cancel()
+		tests := []struct {
+			name    string
+			ctx     context.Context
+			handler ToolHandler
+			class   ToolErrorClass
+		}{
+			{"handler error", context.Background(), func(inv ToolInvocation) (ToolResult, error) {
+				return ToolResult{}, errors.New("boom")
+			}, ToolErrorHandler},
+			{"failure result", context.Background(), func(inv ToolInvocation) (ToolResult, error) {
+				return ToolResult{ResultType: "failure"}, nil
+			}, ToolErrorHandler},
+			{"panic", context.Background(), func(inv ToolInvocation) (ToolResult, error) {
+				panic("nil map")
+			}, ToolErrorPanic},
+			{"timeout", context.Background(), func(inv ToolInvocation) (ToolResult, error) {
+				return buildTimeoutToolResult(&ToolTimeoutError{ToolName: "t", Timeout: time.Second}), nil
+			}, ToolErrorTimeout},
+			{"canceled", canceled, func(inv ToolInvocation) (ToolResult, error) {
+				return ToolResult{}, inv.Context().Err()
+			}, ToolErrorCanceled},
+			{"denied", context.Background(), func(inv ToolInvocation) (ToolResult, error) {
+				return buildDeniedToolResult("t", "no"), nil
+			}, ToolErrorDenied},
+		}
+		for _, tt := range tests {
+			client.executeToolCall(tt.ctx, "s1", "call-2", "t", nil, tt.handler)
+			if result := lastResult(); result.ErrorClass != tt.class || result.ArgumentBytes != 0 {
+				t.Errorf("%s: expected class %q, got %+v", tt.name, tt.class, result)
+			}
+		}
+	})
+
+	t.Run("observes nothing without an observer", func(t *testing.T) {
+		client := &Client{}
+		result := client.executeToolCall(context.Background(), "s1", "call-3", "t", nil, func(inv ToolInvocation) (ToolResult, error) {
+			return ToolResult{TextResultForLLM: "ok", ResultType: "success"}, nil
+		})
+		if result.TextResultForLLM != "ok" {
+			t.Errorf("Unexpected result %+v", result)
+		}
+	})
+}
+
+func TestToolObserverFuncs(t *testing.T) {
+	var names []string
+	var observer ToolObserver = ToolObserverFuncs{Result: func(info ToolResultInfo) { names = append(names, info.ToolName) }}
+	observer.OnToolInvocation(ToolInvocationInfo{ToolName: "a"})
+	observer.OnToolResult(ToolResultInfo{ToolInvocationInfo: ToolInvocationInfo{ToolName: "a"}})
+	if len(names) != 1 || names[0] != "a" {
+		t.Errorf("Unexpected names %v", names)
+	}
+}
@@ -0,0 +1,100 @@
+package copilot
+
+import "sync"
+
+// ToolNotification is a notification a tool handler emits with
+// [ToolInvocation.Notify], such as a progress update or a record of a change
+// for a UI to show.
+type ToolNotification struct {
+	// Kind identifies the notification to consumers, such as "file_saved".
+	Kind string
+	// Data is the notification's payload.
+	Data map[string]interface{}
+}
+
+// toolOutbox buffers the notifications of one tool call until its result is
+// known.
+type toolOutbox struct {
+	mu            sync.Mutex
+	notifications []ToolNotification
+	// done is set once the notifications are flushed or discarded, after which
+	// notifications from handlers still running, such as abandoned ones, are
+	// dropped.
+	done bool
+}
+
+// Notify queues a notification to be dispatched to the session's handlers as an
+// [SDKToolNotification] event once the tool call succeeds. If the handler
+// returns an error, a failure result, or panics, its notifications are
+// discarded, so consumers never see updates from a half-applied call.
+// Notifications are dispatched in the order they were emitted, before the result
+// is sent to the server.
+//
+// Notify is safe to call from goroutines started by the handler. Notifications
+// of invocations created by hand, such as in tests, are dropped.
+//
+// Example:
+//
+//	func saveFiles(params SaveParams, inv copilot.ToolInvocation) (string, error) {
+//	    for _, file := range params.Files {
+//	        if err := save(file); err != nil {
+//	            return "", err // earlier file_saved notifications are discarded
+//	        }
+//	        inv.Notify(copilot.ToolNotification{Kind: "file_saved", Data: map[string]interface{}{"path": file.Path}})
+//	    }
+//	    return "saved", nil
+//	}
+func (inv ToolInvocation) Notify(notification ToolNotification) {
+	if inv.outbox == nil {
+		return
+	}
+	inv.outbox.mu.Lock()
+	defer inv.outbox.mu.Unlock()
+	if !inv.outbox.done {
+		inv.outbox.notifications = append(inv.outbox.notifications, notification)
+	}
+}
+
+// take closes the outbox and returns its notifications.
+func (o *toolOutbox) take() []ToolNotification {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.done = true
+	notifications := o.notifications
+	o.notifications = nil
+	return notifications
+}
+
+// flushToolNotifications dispatches the notifications of a successful tool call
+// to its session.
+func (c *Client) flushToolNotifications(inv ToolInvocation, notifications []ToolNotification) {
+	if len(notifications) == 0 {
+		return
+	}
+	session := c.sessionByID(inv.SessionID)
+	if session == nil {
+		return
+	}
+	for _, notification := range notifications {
+		session.emit(SDKToolNotification, map[string]interface{}{
+			"toolCallId": inv.ToolCallID,
+			"toolName":   inv.ToolName,
+			"kind":       notification.Kind,
+			"data":       notification.Data,
+		})
+	}
+}
+
+// ToolNotificationOf returns the notification carried by an
+// [SDKToolNotification] event and the ID of the tool call that emitted it, and
+// false for other events.
+func ToolNotificationOf(event SessionEvent) (toolCallID string, notification ToolNotification, ok bool) {
+	if event.Type != SDKToolNotification || event.Data.Metadata == nil {
+		return "", ToolNotification{}, false
+	}
+	data, _ := event.Data.Metadata.Variables["data"].(map[string]interface{})
+	return sdkEventString(event, "toolCallId"), ToolNotification{
+		Kind: sdkEventString(event, "kind"),
+		Data: data,
+	}, true
+}
@@ -0,0 +1,73 @@
+package copilot
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestToolInvocation_Notify(t *testing.T) {
+	session := NewSession("s1", nil, "")
+	client := &Client{sessions: map[string]*Session{"s1": session}}
+
+	var notifications []ToolNotification
+	session.On(func(event SessionEvent) {
+		if toolCallID, notification, ok := ToolNotificationOf(event); ok {
+			if toolCallID != "call-1" {
+				t.Errorf("Expected notifications of call-1, got %s", toolCallID)
+			}
+			notifications = append(notifications, notification)
+		}
+	})
+
+	saving := func(fail error, resultType string) ToolHandler {
+		return func(inv ToolInvocation) (ToolResult, error) {
+			inv.Notify(ToolNotification{Kind: "file_saved", Data: map[string]interface{}{"path": "a.txt"}})
+			inv.Notify(ToolNotification{Kind: "file_saved", Data: map[string]interface{}{"path": "b.txt"}})
+			if fail != nil {
+				return ToolResult{}, fail
+			}
+			return ToolResult{TextResultForLLM: "saved", ResultType: resultType}, nil
+		}
+	}
+
+	t.Run("flushes notifications of successful calls in order", func(t *testing.T) {
+		notifications = nil
+		client.executeToolCall(session.toolContext(), "s1", "call-1", "save", nil, saving(nil, "success"))
+		if len(notifications) != 2 || notifications[0].Data["path"] != "a.txt" || notifications[1].Data["path"] != "b.txt" || notifications[0].Kind != "file_saved" {
+			t.Errorf("Unexpected notifications %+v", notifications)
+		}
+	})
+
+	t.Run("discards notifications of failed calls", func(t *testing.T) {
+		notifications = nil
+		client.executeToolCall(session.toolContext(), "s1", "call-1", "save", nil, saving(errors.New("disk full"), ""))
+		client.executeToolCall(session.toolContext(), "s1", "call-1", "save", nil, saving(nil, "failure"))
+		client.executeToolCall(session.toolContext(), "s1", "call-1", "save", nil, func(inv ToolInvocation) (ToolResult, error) {
+			inv.Notify(ToolNotification{Kind: "file_saved"})
+			panic("boom")
+		})
+		if len(notifications) != 0 {
+			t.Errorf("Expected no notifications, got %+v", notifications)
+		}
+	})
+
+	t.Run("drops notifications after the call ends", func(t *testing.T) {
+		notifications = nil
+		var leaked ToolInvocation
+		client.executeToolCall(session.toolContext(), "s1", "call-1", "save", nil, func(inv ToolInvocation) (ToolResult, error) {
+			leaked = inv
+			return ToolResult{ResultType: "success"}, nil
+		})
+		leaked.Notify(ToolNotification{Kind: "late"})
+		client.executeToolCall(session.toolContext(), "s1", "call-1", "save", nil, saving(nil, "success"))
+		for _, notification := range notifications {
+			if notification.Kind == "late" {
+				t.Error("Expected a notification after the call ended to be dropped")
+			}
+		}
+	})
+
+	t.Run("hand-built invocations drop notifications", func(t *testing.T) {
+		ToolInvocation{}.Notify(ToolNotification{Kind: "ignored"})
+	})
+}
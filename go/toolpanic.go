@@ -0,0 +1,85 @@
+package copilot
+
+import (
+	"errors"
+	"fmt"
+	"runtime/debug"
+)
+
+// ToolPanicError reports a panic in a tool handler. The SDK recovers the panic
+// and fails the tool call with a result whose Error holds the panic value and
+// stack trace, rather than crashing the process.
+type ToolPanicError struct {
+	ToolName   string
+	ToolCallID string
+	// Value is the value passed to panic.
+	Value interface{}
+	// Stack is the stack trace of the goroutine that panicked.
+	Stack []byte
+}
+
+func (e *ToolPanicError) Error() string {
+	return fmt.Sprintf("tool panic: %v", e.Value)
+}
+
+// ToolPanicHandler is called with each panic recovered from a tool handler, for
+// example to report it to an error tracker. See [SessionConfig.OnToolPanic].
+type ToolPanicHandler func(err *ToolPanicError)
+
+// newToolPanicError captures the stack of a panic being recovered. Call it from
+// the deferred function that recovered value.
+func newToolPanicError(inv ToolInvocation, value interface{}) *ToolPanicError {
+	return &ToolPanicError{ToolName: inv.ToolName, ToolCallID: inv.ToolCallID, Value: value, Stack: debug.Stack()}
+}
+
+// recoverToolPanic converts a panic in a tool handler into a *ToolPanicError
+// stored in *err. It must be deferred directly.
+func recoverToolPanic(inv ToolInvocation, err *error) {
+	if r := recover(); r != nil {
+		*err = newToolPanicError(inv, r)
+	}
+}
+
+// buildToolPanicResult creates a failure ToolResult for a tool call whose
+// handler panicked. The model gets the generic failure text; Error has the
+// panic value and stack trace.
+func buildToolPanicResult(err *ToolPanicError) ToolResult {
+	result := buildFailedToolResult(fmt.Sprintf("%s\n\n%s", err.Error(), err.Stack))
+	result.ToolTelemetry["errorType"] = "panic"
+	return result
+}
+
+func (s *Session) registerToolPanicHandler(handler ToolPanicHandler) {
+	s.toolHandlersM.Lock()
+	defer s.toolHandlersM.Unlock()
+	s.toolPanicHandler = handler
+}
+
+// handleToolPanic reports a recovered panic to the session's panic handler and
+// returns the failure result for it.
+func (c *Client) handleToolPanic(sessionID string, err *ToolPanicError) ToolResult {
+	fmt.Printf("Tool handler panic (%s): %v\n", err.ToolName, err.Value)
+	if session := c.sessionByID(sessionID); session != nil {
+		session.toolHandlersM.RLock()
+		handler := session.toolPanicHandler
+		session.toolHandlersM.RUnlock()
+		if handler != nil {
+			func() {
+				defer func() {
+					if r := recover(); r != nil {
+						fmt.Printf("Error in tool panic handler: %v\n", r)
+					}
+				}()
+				handler(err)
+			}()
+		}
+	}
+	return buildToolPanicResult(err)
+}
+
+// asToolPanic returns the *ToolPanicError in err's chain, if any.
+func asToolPanic(err error) (*ToolPanicError, bool) {
+	var panicErr *ToolPanicError
+	ok := errors.As(err, &panicErr)
+	return panicErr, ok
+}
@@ -0,0 +1,71 @@
+package copilot
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestDefineTool_RecoversPanics(t *testing.T) {
+	type Params struct{}
+	tool := DefineTool("broken", "A broken tool", func(params Params, inv ToolInvocation) (string, error) {
+		var m map[string]int
+		m["boom"] = 1
+		return "unreachable", nil
+	}, WithErrorRenderer(RenderToolError))
+
+	_, err := tool.Handler(ToolInvocation{ToolName: "broken", ToolCallID: "call-1", Arguments: map[string]interface{}{}})
+	panicErr, ok := asToolPanic(err)
+	if !ok {
+		t.Fatalf("Expected a *ToolPanicError despite the error renderer, got %v", err)
+	}
+	if panicErr.ToolName != "broken" || panicErr.ToolCallID != "call-1" || !strings.Contains(panicErr.Error(), "assignment to entry in nil map") {
+		t.Errorf("Unexpected panic error %+v", panicErr)
+	}
+	if !strings.Contains(string(panicErr.Stack), "toolpanic_test.go") {
+		t.Errorf("Expected the stack to include the panicking handler, got %s", panicErr.Stack)
+	}
+}
+
+func TestExecuteToolCall_Panics(t *testing.T) {
+	type Params struct{}
+	typed := DefineTool("typed", "Typed", func(params Params, inv ToolInvocation) (string, error) {
+		panic("typed boom")
+	})
+	raw := func(inv ToolInvocation) (ToolResult, error) {
+		panic(errors.New("raw boom"))
+	}
+
+	var panics []*ToolPanicError
+	session := NewSession("s1", nil, "")
+	session.registerToolPanicHandler(func(err *ToolPanicError) {
+		panics = append(panics, err)
+		panic("the panic handler panics too")
+	})
+	client := &Client{sessions: map[string]*Session{"s1": session}}
+
+	for _, tt := range []struct {
+		name    string
+		handler ToolHandler
+		value   string
+	}{
+		{"typed", typed.Handler, "typed boom"},
+		{"raw", raw, "raw boom"},
+	} {
+		result := client.executeToolCall(context.Background(), "s1", "call-"+tt.name, tt.name, map[string]interface{}{}, tt.handler)
+		if result.ResultType != "failure" || !strings.HasPrefix(result.Error, "tool panic: "+tt.value+"\n") || !strings.Contains(result.Error, "goroutine") {
+			t.Errorf("%s: expected a failure with the panic and stack, got %q", tt.name, result.Error)
+		}
+		if result.ToolTelemetry["errorType"] != "panic" {
+			t.Errorf("%s: expected errorType panic, got %v", tt.name, result.ToolTelemetry)
+		}
+		if strings.Contains(result.TextResultForLLM, "goroutine") {
+			t.Errorf("%s: expected the stack to be hidden from the model, got %q", tt.name, result.TextResultForLLM)
+		}
+	}
+
+	if len(panics) != 2 || panics[0].ToolCallID != "call-typed" || panics[1].ToolName != "raw" {
+		t.Errorf("Expected both panics to be reported, got %+v", panics)
+	}
+}
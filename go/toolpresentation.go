@@ -0,0 +1,159 @@
+package copilot
+
+import (
+	"path"
+	"strings"
+)
+
+// ToolPresentation is metadata describing how a UI should display a tool result.
+// It does not change what the model receives.
+type ToolPresentation struct {
+	// Language is the language to highlight the result as in a code block, such
+	// as "go" or "json". Empty means plain text.
+	Language string
+	// Preview is a short text to show in place of the full result, for example
+	// while it is collapsed.
+	Preview string
+	// Collapsible reports whether the result is long enough to be shown
+	// collapsed by default.
+	Collapsible bool
+}
+
+func (p ToolPresentation) isZero() bool {
+	return p == ToolPresentation{}
+}
+
+// ResultPostProcessor fills in the presentation of a successful tool result.
+// The processors of a tool run in order, each seeing the presentation left by
+// the previous one. See [Tool.PostProcessors].
+type ResultPostProcessor func(inv ToolInvocation, result ToolResult, presentation *ToolPresentation)
+
+// WithResultPostProcessors sets the processors that describe how UIs present
+// the tool's results. See [Tool.PostProcessors].
+//
+// Example:
+//
+//	tool := copilot.DefineTool("read_file", "Read a file", readFile,
+//	    copilot.WithResultPostProcessors(
+//	        copilot.LanguageFromArgument("path"),
+//	        copilot.TruncatedPreview(200),
+//	        copilot.CollapseOverLines(20),
+//	    ))
+func WithResultPostProcessors(processors ...ResultPostProcessor) ToolOption {
+	return func(o *toolOptions) {
+		o.postProcessors = append(o.postProcessors, processors...)
+	}
+}
+
+// CodeLanguage presents every result as a code block in language.
+func CodeLanguage(language string) ResultPostProcessor {
+	return func(inv ToolInvocation, result ToolResult, presentation *ToolPresentation) {
+		presentation.Language = language
+	}
+}
+
+// LanguageFromArgument presents results as code in the language implied by the
+// file extension of the named string argument, such as "go" for a "path" of
+// "main.go". Unknown extensions leave the language unchanged.
+func LanguageFromArgument(field string) ResultPostProcessor {
+	return func(inv ToolInvocation, result ToolResult, presentation *ToolPresentation) {
+		args, _ := inv.Arguments.(map[string]interface{})
+		file, _ := args[field].(string)
+		if language, ok := languagesByExtension[strings.ToLower(path.Ext(file))]; ok {
+			presentation.Language = language
+		}
+	}
+}
+
+var languagesByExtension = map[string]string{
+	".c":     "c",
+	".cpp":   "cpp",
+	".cs":    "csharp",
+	".css":   "css",
+	".go":    "go",
+	".h":     "c",
+	".html":  "html",
+	".java":  "java",
+	".js":    "javascript",
+	".json":  "json",
+	".jsx":   "jsx",
+	".kt":    "kotlin",
+	".md":    "markdown",
+	".php":   "php",
+	".py":    "python",
+	".rb":    "ruby",
+	".rs":    "rust",
+	".sh":    "bash",
+	".sql":   "sql",
+	".swift": "swift",
+	".toml":  "toml",
+	".ts":    "typescript",
+	".tsx":   "tsx",
+	".xml":   "xml",
+	".yaml":  "yaml",
+	".yml":   "yaml",
+}
+
+// TruncatedPreview previews results longer than maxRunes with their first
+// maxRunes characters followed by an ellipsis.
+func TruncatedPreview(maxRunes int) ResultPostProcessor {
+	return func(inv ToolInvocation, result ToolResult, presentation *ToolPresentation) {
+		text := []rune(result.TextResultForLLM)
+		if len(text) <= maxRunes {
+			return
+		}
+		presentation.Preview = strings.TrimRight(string(text[:maxRunes]), " \t\n") + "…"
+	}
+}
+
+// CollapseOverLines marks results with more than maxLines lines collapsible.
+func CollapseOverLines(maxLines int) ResultPostProcessor {
+	return func(inv ToolInvocation, result ToolResult, presentation *ToolPresentation) {
+		if strings.Count(strings.TrimRight(result.TextResultForLLM, "\n"), "\n")+1 > maxLines {
+			presentation.Collapsible = true
+		}
+	}
+}
+
+// withResultPresentation wraps handler so that successful results are run
+// through processors and, if they describe a presentation, announced with an
+// [SDKToolResultPresented] event.
+func (s *Session) withResultPresentation(name string, processors []ResultPostProcessor, handler ToolHandler) ToolHandler {
+	if len(processors) == 0 {
+		return handler
+	}
+	return func(inv ToolInvocation) (ToolResult, error) {
+		result, err := handler(inv)
+		if err != nil || result.ResultType == "failure" || result.pending != "" {
+			return result, err
+		}
+		var presentation ToolPresentation
+		for _, process := range processors {
+			process(inv, result, &presentation)
+		}
+		if !presentation.isZero() {
+			s.emit(SDKToolResultPresented, map[string]interface{}{
+				"toolCallId":  inv.ToolCallID,
+				"toolName":    name,
+				"language":    presentation.Language,
+				"preview":     presentation.Preview,
+				"collapsible": presentation.Collapsible,
+			})
+		}
+		return result, nil
+	}
+}
+
+// ToolResultPresentation returns the presentation carried by an
+// [SDKToolResultPresented] event, and false for other events.
+func ToolResultPresentation(event SessionEvent) (toolCallID string, presentation ToolPresentation, ok bool) {
+	if event.Type != SDKToolResultPresented || event.Data.Metadata == nil {
+		return "", ToolPresentation{}, false
+	}
+	collapsible, _ := event.Data.Metadata.Variables["collapsible"].(bool)
+	return sdkEventString(event, "toolCallId"), ToolPresentation{
+		Language:    sdkEventString(event, "language"),
+		Preview:     sdkEventString(event, "preview"),
+		Collapsible: collapsible,
+	}, true
+}
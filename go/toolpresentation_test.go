@@ -0,0 +1,94 @@
+package copilot
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestResultPostProcessors(t *testing.T) {
+	t.Run("language from argument", func(t *testing.T) {
+		var presentation ToolPresentation
+		LanguageFromArgument("path")(ToolInvocation{Arguments: map[string]interface{}{"path": "cmd/Main.GO"}}, ToolResult{}, &presentation)
+		if presentation.Language != "go" {
+			t.Errorf("Expected go, got %q", presentation.Language)
+		}
+		LanguageFromArgument("path")(ToolInvocation{Arguments: map[string]interface{}{"path": "notes.unknown"}}, ToolResult{}, &presentation)
+		if presentation.Language != "go" {
+			t.Errorf("Expected an unknown extension to keep the language, got %q", presentation.Language)
+		}
+	})
+
+	t.Run("truncated preview", func(t *testing.T) {
+		var presentation ToolPresentation
+		TruncatedPreview(5)(ToolInvocation{}, ToolResult{TextResultForLLM: "hello world"}, &presentation)
+		if presentation.Preview != "hello…" {
+			t.Errorf("Unexpected preview %q", presentation.Preview)
+		}
+		presentation = ToolPresentation{}
+		TruncatedPreview(5)(ToolInvocation{}, ToolResult{TextResultForLLM: "héllo"}, &presentation)
+		if presentation.Preview != "" {
+			t.Errorf("Expected no preview for a short result, got %q", presentation.Preview)
+		}
+	})
+
+	t.Run("collapse over lines", func(t *testing.T) {
+		var presentation ToolPresentation
+		CollapseOverLines(2)(ToolInvocation{}, ToolResult{TextResultForLLM: "a\nb\n"}, &presentation)
+		if presentation.Collapsible {
+			t.Error("Expected two lines not to be collapsible")
+		}
+		CollapseOverLines(2)(ToolInvocation{}, ToolResult{TextResultForLLM: "a\nb\nc"}, &presentation)
+		if !presentation.Collapsible {
+			t.Error("Expected three lines to be collapsible")
+		}
+	})
+}
+
+func TestToolPostProcessors_Event(t *testing.T) {
+	type ReadParams struct {
+		Path string `json:"path"`
+	}
+	session := NewSession("s1", nil, "")
+	session.registerTools([]Tool{
+		DefineTool("read_file", "Read a file", func(params ReadParams, inv ToolInvocation) (string, error) {
+			if params.Path == "missing.go" {
+				return "", errors.New("no such file")
+			}
+			return strings.Repeat("line\n", 30), nil
+		}, WithResultPostProcessors(LanguageFromArgument("path"), TruncatedPreview(9), CollapseOverLines(20))),
+	})
+
+	var presented []SessionEvent
+	session.On(func(event SessionEvent) {
+		if event.Type == SDKToolResultPresented {
+			presented = append(presented, event)
+		}
+	})
+
+	handler, _ := session.getToolHandler("read_file")
+	result, err := handler(ToolInvocation{ToolCallID: "call-1", ToolName: "read_file", Arguments: map[string]interface{}{"path": "main.go"}})
+	if err != nil || result.TextResultForLLM != strings.Repeat("line\n", 30) {
+		t.Fatalf("Expected the result to be unchanged, got %+v, %v", result, err)
+	}
+	if len(presented) != 1 {
+		t.Fatalf("Expected one presentation event, got %d", len(presented))
+	}
+	toolCallID, presentation, ok := ToolResultPresentation(presented[0])
+	if !ok || toolCallID != "call-1" {
+		t.Fatalf("Unexpected event %+v", presented[0])
+	}
+	expected := ToolPresentation{Language: "go", Preview: "line\nline…", Collapsible: true}
+	if presentation != expected {
+		t.Errorf("Expected %+v, got %+v", expected, presentation)
+	}
+
+	handler(ToolInvocation{ToolCallID: "call-2", ToolName: "read_file", Arguments: map[string]interface{}{"path": "missing.go"}})
+	if len(presented) != 1 {
+		t.Error("Expected no presentation event for a failed call")
+	}
+
+	if _, _, ok := ToolResultPresentation(SessionEvent{Type: SDKToolTimedOut}); ok {
+		t.Error("Expected other events to carry no presentation")
+	}
+}
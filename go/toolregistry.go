@@ -0,0 +1,353 @@
+package copilot
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// ErrToolRegistryFrozen is returned when changing a [ToolRegistry] after
+// [ToolRegistry.Freeze].
+var ErrToolRegistryFrozen = errors.New("tool registry is frozen")
+
+// ToolRegistry is a set of tools that can change while the sessions using it
+// are running. Attach a registry with [SessionConfig.ToolRegistry]; its tools
+// are advertised alongside the session's own Tools, and every change is pushed
+// to the CLI of each attached session. A session is detached when it is
+// destroyed, or when its client stops or loses its connection.
+//
+// A registry tool takes the place of a session tool with the same name.
+//
+// A registry is safe for concurrent use, so plugins can register their tools
+// from parallel goroutines at startup. Its tools are advertised in name order,
+// which does not depend on the order the goroutines ran in; call
+// [ToolRegistry.Freeze] once they are done to lock the set before creating
+// sessions.
+//
+// Example:
+//
+//	registry, _ := copilot.NewToolRegistry(searchTool)
+//	session, _ := client.CreateSession(&copilot.SessionConfig{ToolRegistry: registry})
+//
+//	// Later, once the user connects their calendar:
+//	if err := registry.Register(calendarTool); err != nil {
+//	    log.Printf("Failed to advertise the calendar tool: %v", err)
+//	}
+type ToolRegistry struct {
+	mu sync.Mutex
+	// tools are sorted by name.
+	tools  []Tool
+	frozen bool
+	// version counts changes, so that sessions skip updates older than one
+	// they have applied.
+	version  uint64
+	sessions map[*Session]struct{}
+}
+
+// NewToolRegistry creates a registry holding tools.
+func NewToolRegistry(tools ...Tool) (*ToolRegistry, error) {
+	r := &ToolRegistry{sessions: make(map[*Session]struct{})}
+	if err := r.validateNew(tools); err != nil {
+		return nil, err
+	}
+	r.insert(tools)
+	return r, nil
+}
+
+// Tools returns the registered tools in name order.
+func (r *ToolRegistry) Tools() []Tool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]Tool(nil), r.tools...)
+}
+
+// Register adds tools to the registry and advertises them to attached
+// sessions. It fails without changing the registry if a tool has no name or is
+// already registered, or if the registry is frozen.
+//
+// The returned error also reports sessions that could not be updated. The
+// registry is changed regardless, and the change is pushed again with the next
+// one.
+func (r *ToolRegistry) Register(tools ...Tool) error {
+	r.mu.Lock()
+	if r.frozen {
+		r.mu.Unlock()
+		return ErrToolRegistryFrozen
+	}
+	if err := r.validateNew(tools); err != nil {
+		r.mu.Unlock()
+		return err
+	}
+	r.insert(tools)
+	update := r.changedLocked()
+	r.mu.Unlock()
+	return update.push(true)
+}
+
+// Unregister removes the named tools from the registry and from attached
+// sessions. Calls to a removed tool that the model makes before the update
+// reaches it fail with an unsupported-tool result. It fails without changing
+// the registry if a tool is not registered or the registry is frozen. See
+// [ToolRegistry.Register] for update errors.
+func (r *ToolRegistry) Unregister(names ...string) error {
+	r.mu.Lock()
+	if r.frozen {
+		r.mu.Unlock()
+		return ErrToolRegistryFrozen
+	}
+	removed := make(map[string]bool, len(names))
+	for _, name := range names {
+		if r.index(name) < 0 {
+			r.mu.Unlock()
+			return fmt.Errorf("tool %s is not registered", name)
+		}
+		removed[name] = true
+	}
+	tools := r.tools[:0:0]
+	for _, tool := range r.tools {
+		if !removed[tool.Name] {
+			tools = append(tools, tool)
+		}
+	}
+	r.tools = tools
+	update := r.changedLocked()
+	r.mu.Unlock()
+	return update.push(true)
+}
+
+// Replace swaps the registered tool with the same name as tool for tool, for
+// example to change its description, schema, or handler. It fails without
+// changing the registry if no such tool is registered or the registry is
+// frozen. See [ToolRegistry.Register] for update errors.
+func (r *ToolRegistry) Replace(tool Tool) error {
+	r.mu.Lock()
+	if r.frozen {
+		r.mu.Unlock()
+		return ErrToolRegistryFrozen
+	}
+	i := r.index(tool.Name)
+	if i < 0 {
+		r.mu.Unlock()
+		return fmt.Errorf("tool %s is not registered", tool.Name)
+	}
+	tools := append([]Tool(nil), r.tools...)
+	tools[i] = tool
+	r.tools = tools
+	update := r.changedLocked()
+	r.mu.Unlock()
+	return update.push(true)
+}
+
+// Freeze locks the registry's set of tools: later calls to Register,
+// Unregister, and Replace fail with [ErrToolRegistryFrozen]. Sessions can still
+// be created with a frozen registry.
+func (r *ToolRegistry) Freeze() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.frozen = true
+}
+
+// Frozen reports whether [ToolRegistry.Freeze] has been called.
+func (r *ToolRegistry) Frozen() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.frozen
+}
+
+// validateNew checks that tools can be added to the registry.
+func (r *ToolRegistry) validateNew(tools []Tool) error {
+	added := make(map[string]bool, len(tools))
+	for _, tool := range tools {
+		if tool.Name == "" {
+			return errors.New("tool name is required")
+		}
+		if r.index(tool.Name) >= 0 {
+			return fmt.Errorf("tool %s is already registered", tool.Name)
+		}
+		if added[tool.Name] {
+			return fmt.Errorf("tool %s is registered twice", tool.Name)
+		}
+		added[tool.Name] = true
+	}
+	return nil
+}
+
+// insert adds validated tools, keeping r.tools sorted by name.
+func (r *ToolRegistry) insert(tools []Tool) {
+	merged := make([]Tool, 0, len(r.tools)+len(tools))
+	merged = append(append(merged, r.tools...), tools...)
+	sort.SliceStable(merged, func(i, j int) bool { return merged[i].Name < merged[j].Name })
+	r.tools = merged
+}
+
+// index returns the position of the named tool, or -1 if it is not
+// registered.
+func (r *ToolRegistry) index(name string) int {
+	i := sort.Search(len(r.tools), func(i int) bool { return r.tools[i].Name >= name })
+	if i < len(r.tools) && r.tools[i].Name == name {
+		return i
+	}
+	return -1
+}
+
+// registryUpdate is a change to a [ToolRegistry], to be pushed to the
+// sessions attached when it was made.
+type registryUpdate struct {
+	version  uint64
+	tools    []Tool
+	sessions []*Session
+}
+
+// changedLocked records a change to the registry's tools and returns the
+// update to push once the caller, which holds r.mu, releases it. Pushing
+// outside the lock keeps a slow session from blocking the registry.
+func (r *ToolRegistry) changedLocked() registryUpdate {
+	r.version++
+	update := registryUpdate{version: r.version, tools: r.tools, sessions: make([]*Session, 0, len(r.sessions))}
+	for session := range r.sessions {
+		update.sessions = append(update.sessions, session)
+	}
+	return update
+}
+
+// push applies the update to each of its sessions, advertising the tools to
+// the server too if notify is set.
+func (u registryUpdate) push(notify bool) error {
+	var errs []error
+	for _, session := range u.sessions {
+		if err := session.updateTools(u.tools, u.version, notify); err != nil {
+			errs = append(errs, fmt.Errorf("failed to update tools for session %s: %w", session.SessionID, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// withTools returns the tools advertised for a session whose own tools are
+// base: base followed by the registry's tools, with registry tools taking the
+// place of base tools with the same name. A nil registry returns base.
+func (r *ToolRegistry) withTools(base []Tool) []Tool {
+	if r == nil {
+		return base
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return mergeTools(base, r.tools)
+}
+
+func mergeTools(base, registered []Tool) []Tool {
+	if len(registered) == 0 {
+		return base
+	}
+	replaced := make(map[string]bool, len(registered))
+	for _, tool := range registered {
+		replaced[tool.Name] = true
+	}
+	merged := make([]Tool, 0, len(base)+len(registered))
+	for _, tool := range base {
+		if !replaced[tool.Name] {
+			merged = append(merged, tool)
+		}
+	}
+	return append(merged, registered...)
+}
+
+// registerToolRegistry attaches registry to the session, whose own tools are
+// base, until the session is destroyed or its connection ends, such as when
+// the client stops or the server goes away.
+func (s *Session) registerToolRegistry(registry *ToolRegistry, base []Tool) {
+	if registry == nil {
+		return
+	}
+	s.toolHandlersM.Lock()
+	s.baseTools = base
+	s.toolHandlersM.Unlock()
+
+	registry.mu.Lock()
+	registry.sessions[s] = struct{}{}
+	tools := registry.tools
+	registry.mu.Unlock()
+	s.registryMux.Lock()
+	s.registryTools = tools
+	s.registryMux.Unlock()
+
+	destroyed := make(chan struct{})
+	s.onDestroy(func() {
+		close(destroyed)
+		registry.detach(s)
+	})
+	if s.client != nil {
+		go func() {
+			select {
+			case <-s.client.Done():
+				registry.detach(s)
+			case <-destroyed:
+			}
+		}()
+	}
+}
+
+// detach stops pushing updates to session.
+func (r *ToolRegistry) detach(session *Session) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.sessions, session)
+}
+
+// updateTools replaces the session's registry tools with registered, version
+// of the registry, in its handlers and, if notify is set, on the server.
+// Updates are applied in order: one older than an update already applied is
+// skipped.
+func (s *Session) updateTools(registered []Tool, version uint64, notify bool) error {
+	s.registryMux.Lock()
+	defer s.registryMux.Unlock()
+	if version <= s.registryVersion {
+		return nil
+	}
+	tools, err := s.setToolHandlers(registered)
+	if err != nil {
+		return err
+	}
+	s.registryVersion = version
+	s.registryTools = registered
+	if !notify {
+		return nil
+	}
+	return s.advertiseTools(tools)
+}
+
+// advertiseTools replaces the tools the server can call on the session with
+// tools.
+func (s *Session) advertiseTools(tools []Tool) error {
+	_, err := s.client.Request("session.updateTools", map[string]interface{}{
+		"sessionId": s.SessionID,
+		"tools":     buildToolDefinitions(tools),
+	})
+	return err
+}
+
+// setToolHandlers replaces the session's registry tools with registered in its
+// handlers only, and returns the tools the session now has. It fails, leaving
+// the handlers unchanged, if the tools exceed the session's tool limits.
+func (s *Session) setToolHandlers(registered []Tool) ([]Tool, error) {
+	s.toolHandlersM.Lock()
+	tools, violations, err := s.advertiseLimits.enforce(filterTools(withDeprecatedTools(mergeTools(s.baseTools, registered)), s.toolFilter))
+	if err != nil {
+		s.toolHandlersM.Unlock()
+		return nil, err
+	}
+	defer s.advertiseLimits.warn(violations)
+	defer s.toolHandlersM.Unlock()
+	s.toolHandlers = make(map[string]ToolHandler, len(tools))
+	for _, tool := range tools {
+		if tool.Name == "" || tool.Handler == nil {
+			continue
+		}
+		s.toolHandlers[tool.Name] = s.wrapToolHandler(tool)
+	}
+	s.registerDeprecatedTools(tools)
+	if size, err := MeasureTools(tools); err == nil {
+		s.toolCatalogSize = size
+	}
+	return tools, nil
+}
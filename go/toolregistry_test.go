@@ -0,0 +1,335 @@
+package copilot
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func namedTool(name, description string) Tool {
+	return Tool{
+		Name:        name,
+		Description: description,
+		Handler: func(inv ToolInvocation) (ToolResult, error) {
+			return ToolResult{TextResultForLLM: description}, nil
+		},
+	}
+}
+
+func advertisedNames(tools interface{}) []string {
+	var names []string
+	for _, tool := range tools.([]interface{}) {
+		names = append(names, tool.(map[string]interface{})["name"].(string))
+	}
+	return names
+}
+
+func TestNewToolRegistry(t *testing.T) {
+	t.Run("rejects unnamed tools", func(t *testing.T) {
+		if _, err := NewToolRegistry(Tool{Description: "no name"}); err == nil {
+			t.Fatal("Expected an error for a tool without a name")
+		}
+	})
+
+	t.Run("rejects duplicate tools", func(t *testing.T) {
+		_, err := NewToolRegistry(namedTool("search", "a"), namedTool("search", "b"))
+		if err == nil || !strings.Contains(err.Error(), "registered twice") {
+			t.Fatalf("Expected a duplicate tool error, got %v", err)
+		}
+	})
+}
+
+func TestToolRegistry(t *testing.T) {
+	var mu sync.Mutex
+	var updates [][]string
+	rpc, _ := newFakeServer(t, func(method string, params map[string]interface{}) (map[string]interface{}, *JSONRPCError) {
+		switch method {
+		case "session.create":
+			mu.Lock()
+			updates = append(updates, advertisedNames(params["tools"]))
+			mu.Unlock()
+			return map[string]interface{}{"sessionId": "s1"}, nil
+		case "session.updateTools":
+			mu.Lock()
+			updates = append(updates, advertisedNames(params["tools"]))
+			mu.Unlock()
+		}
+		return nil, nil
+	})
+	client := &Client{client: rpc, state: StateConnected, sessions: make(map[string]*Session)}
+
+	registry, err := NewToolRegistry(namedTool("search", "registry search"))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	session, err := client.CreateSession(&SessionConfig{
+		Tools:        []Tool{namedTool("lookup", "lookup"), namedTool("search", "session search")},
+		ToolRegistry: registry,
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	lastUpdate := func() string {
+		mu.Lock()
+		defer mu.Unlock()
+		return strings.Join(updates[len(updates)-1], ",")
+	}
+	call := func(name string) string {
+		handler, ok := session.getToolHandler(name)
+		if !ok {
+			return ""
+		}
+		result, _ := handler(ToolInvocation{ToolName: name})
+		return result.TextResultForLLM
+	}
+
+	if got := lastUpdate(); got != "lookup,search" {
+		t.Errorf("Expected the registry tool to replace the session tool, got %s", got)
+	}
+	if got := call("search"); got != "registry search" {
+		t.Errorf("Expected the registry handler, got %q", got)
+	}
+
+	if err := registry.Register(namedTool("calendar", "calendar")); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if got := lastUpdate(); got != "lookup,calendar,search" {
+		t.Errorf("Unexpected tools after Register: %s", got)
+	}
+	if got := call("calendar"); got != "calendar" {
+		t.Errorf("Expected the calendar handler after Register, got %q", got)
+	}
+	if size := session.ToolCatalogSize(); len(size.Tools) != 3 {
+		t.Errorf("Expected the catalog size to cover 3 tools, got %+v", size)
+	}
+
+	if err := registry.Replace(namedTool("calendar", "calendar v2")); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if got := call("calendar"); got != "calendar v2" {
+		t.Errorf("Expected the replaced handler, got %q", got)
+	}
+
+	if err := registry.Unregister("search"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if got := lastUpdate(); got != "lookup,search,calendar" {
+		t.Errorf("Expected the session's own search tool to return, got %s", got)
+	}
+	if got := call("search"); got != "session search" {
+		t.Errorf("Expected the session handler after Unregister, got %q", got)
+	}
+
+	t.Run("rejects invalid changes without pushing", func(t *testing.T) {
+		mu.Lock()
+		before := len(updates)
+		mu.Unlock()
+		if err := registry.Register(namedTool("calendar", "again")); err == nil {
+			t.Error("Expected an error registering an existing tool")
+		}
+		if err := registry.Register(namedTool("a", "a"), namedTool("a", "a")); err == nil {
+			t.Error("Expected an error registering a tool twice")
+		}
+		if err := registry.Unregister("missing"); err == nil {
+			t.Error("Expected an error unregistering a missing tool")
+		}
+		if err := registry.Replace(namedTool("missing", "missing")); err == nil {
+			t.Error("Expected an error replacing a missing tool")
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		if len(updates) != before {
+			t.Errorf("Expected no updates, got %d", len(updates)-before)
+		}
+		if names := registry.Tools(); len(names) != 1 || names[0].Name != "calendar" {
+			t.Errorf("Expected the registry to be unchanged, got %+v", names)
+		}
+	})
+
+	t.Run("detaches destroyed sessions", func(t *testing.T) {
+		if err := session.Destroy(); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		mu.Lock()
+		before := len(updates)
+		mu.Unlock()
+		if err := registry.Register(namedTool("weather", "weather")); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		if len(updates) != before {
+			t.Error("Expected no update for a destroyed session")
+		}
+	})
+}
+
+func TestToolRegistry_ConcurrentRegistration(t *testing.T) {
+	registry, _ := NewToolRegistry()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			name := fmt.Sprintf("plugin_%02d", i)
+			if err := registry.Register(namedTool(name, name)); err != nil {
+				t.Errorf("Unexpected error: %v", err)
+			}
+		}(i)
+	}
+	wg.Wait()
+	registry.Freeze()
+
+	tools := registry.Tools()
+	if len(tools) != 50 {
+		t.Fatalf("Expected 50 tools, got %d", len(tools))
+	}
+	for i, tool := range tools {
+		if want := fmt.Sprintf("plugin_%02d", i); tool.Name != want {
+			t.Fatalf("Expected tools in name order, got %s at %d", tool.Name, i)
+		}
+	}
+
+	if !registry.Frozen() {
+		t.Error("Expected the registry to be frozen")
+	}
+	if err := registry.Register(namedTool("late", "late")); !errors.Is(err, ErrToolRegistryFrozen) {
+		t.Errorf("Expected ErrToolRegistryFrozen from Register, got %v", err)
+	}
+	if err := registry.Unregister("plugin_00"); !errors.Is(err, ErrToolRegistryFrozen) {
+		t.Errorf("Expected ErrToolRegistryFrozen from Unregister, got %v", err)
+	}
+	if err := registry.Replace(namedTool("plugin_00", "v2")); !errors.Is(err, ErrToolRegistryFrozen) {
+		t.Errorf("Expected ErrToolRegistryFrozen from Replace, got %v", err)
+	}
+
+	rpc, _ := newFakeServer(t, func(method string, params map[string]interface{}) (map[string]interface{}, *JSONRPCError) {
+		return map[string]interface{}{"sessionId": "s1"}, nil
+	})
+	client := &Client{client: rpc, state: StateConnected, sessions: make(map[string]*Session)}
+	session, err := client.CreateSession(&SessionConfig{ToolRegistry: registry})
+	if err != nil {
+		t.Fatalf("Unexpected error creating a session with a frozen registry: %v", err)
+	}
+	if _, ok := session.getToolHandler("plugin_49"); !ok {
+		t.Error("Expected the frozen registry's tools to be registered")
+	}
+}
+
+func TestToolRegistry_UpdateError(t *testing.T) {
+	rpc, _ := newFakeServer(t, func(method string, params map[string]interface{}) (map[string]interface{}, *JSONRPCError) {
+		if method == "session.updateTools" {
+			return nil, &JSONRPCError{Code: -32601, Message: "method not found"}
+		}
+		return map[string]interface{}{"sessionId": "s1"}, nil
+	})
+	client := &Client{client: rpc, state: StateConnected, sessions: make(map[string]*Session)}
+
+	registry, _ := NewToolRegistry()
+	if _, err := client.CreateSession(&SessionConfig{ToolRegistry: registry}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	err := registry.Register(namedTool("calendar", "calendar"))
+	if err == nil || !strings.Contains(err.Error(), "failed to update tools for session s1") {
+		t.Fatalf("Expected an update error, got %v", err)
+	}
+	if tools := registry.Tools(); len(tools) != 1 {
+		t.Errorf("Expected the registry to change despite the update error, got %+v", tools)
+	}
+}
+
+func TestToolRegistry_DetachesEndedSessions(t *testing.T) {
+	attached := func(registry *ToolRegistry) int {
+		registry.mu.Lock()
+		defer registry.mu.Unlock()
+		return len(registry.sessions)
+	}
+	waitDetached := func(t *testing.T, registry *ToolRegistry) {
+		t.Helper()
+		deadline := time.Now().Add(5 * time.Second)
+		for attached(registry) != 0 {
+			if time.Now().After(deadline) {
+				t.Fatal("Timed out waiting for the session to be detached")
+			}
+			time.Sleep(time.Millisecond)
+		}
+	}
+	handle := func(method string, params map[string]interface{}) (map[string]interface{}, *JSONRPCError) {
+		return map[string]interface{}{"sessionId": "s1"}, nil
+	}
+
+	t.Run("when the client force stops", func(t *testing.T) {
+		rpc, _ := newFakeServer(t, handle)
+		client := &Client{client: rpc, state: StateConnected, sessions: make(map[string]*Session)}
+		registry, _ := NewToolRegistry()
+		if _, err := client.CreateSession(&SessionConfig{ToolRegistry: registry}); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if attached(registry) != 1 {
+			t.Fatal("Expected the session to be attached")
+		}
+
+		client.ForceStop()
+		waitDetached(t, registry)
+		if err := registry.Register(namedTool("calendar", "calendar")); err != nil {
+			t.Errorf("Expected no updates to the stopped session, got %v", err)
+		}
+	})
+
+	t.Run("when the connection is lost", func(t *testing.T) {
+		rpc, server := newFakeServer(t, handle)
+		client := &Client{client: rpc, state: StateConnected, sessions: make(map[string]*Session)}
+		registry, _ := NewToolRegistry()
+		if _, err := client.CreateSession(&SessionConfig{ToolRegistry: registry}); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		server.writer.(io.Closer).Close()
+		waitDetached(t, registry)
+	})
+}
+
+func TestToolRegistry_SlowSession(t *testing.T) {
+	release := make(chan struct{})
+	updating := make(chan struct{}, 1)
+	rpc, _ := newFakeServer(t, func(method string, params map[string]interface{}) (map[string]interface{}, *JSONRPCError) {
+		if method == "session.updateTools" {
+			updating <- struct{}{}
+			<-release
+		}
+		return map[string]interface{}{"sessionId": "s1"}, nil
+	})
+	client := &Client{client: rpc, state: StateConnected, sessions: make(map[string]*Session)}
+	registry, _ := NewToolRegistry()
+	if _, err := client.CreateSession(&SessionConfig{ToolRegistry: registry}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	registered := make(chan error, 1)
+	go func() { registered <- registry.Register(namedTool("calendar", "calendar")) }()
+	<-updating
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		registry.Tools()
+		registry.withTools(nil)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected the registry to stay usable while a session update is pending")
+	}
+
+	close(release)
+	if err := <-registered; err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+}
@@ -0,0 +1,136 @@
+package copilot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"sort"
+	"sync"
+	"syscall"
+)
+
+// Sync replaces the registry's tools with tools, as a single update pushed to
+// every attached session, and reports how the advertised definitions changed.
+// Tools in both sets take their new handlers even if their definitions are
+// unchanged; if no definition changed, sessions are updated without a round
+// trip to the server.
+//
+// It fails without changing the registry if a tool has no name, two tools
+// have the same name, a schema is invalid, or the registry is frozen. See
+// [ToolRegistry.Register] for update errors.
+func (r *ToolRegistry) Sync(tools []Tool) (CatalogDiff, error) {
+	diff, update, err := r.sync(tools)
+	if err != nil {
+		return CatalogDiff{}, err
+	}
+	return diff, update.push(!diff.Empty())
+}
+
+// sync replaces the registry's tools with tools and returns the update to
+// push to attached sessions.
+func (r *ToolRegistry) sync(tools []Tool) (CatalogDiff, registryUpdate, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.frozen {
+		return CatalogDiff{}, registryUpdate{}, ErrToolRegistryFrozen
+	}
+	names := make(map[string]bool, len(tools))
+	for _, tool := range tools {
+		if tool.Name == "" {
+			return CatalogDiff{}, registryUpdate{}, errors.New("tool name is required")
+		}
+		if names[tool.Name] {
+			return CatalogDiff{}, registryUpdate{}, fmt.Errorf("tool %s is registered twice", tool.Name)
+		}
+		names[tool.Name] = true
+	}
+	oldCatalog, err := NewToolCatalog(r.tools)
+	if err != nil {
+		return CatalogDiff{}, registryUpdate{}, err
+	}
+	newCatalog, err := NewToolCatalog(tools)
+	if err != nil {
+		return CatalogDiff{}, registryUpdate{}, err
+	}
+
+	sorted := append([]Tool(nil), tools...)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].Name < sorted[j].Name })
+	r.tools = sorted
+	return DiffToolCatalogs(oldCatalog, newCatalog), r.changedLocked(), nil
+}
+
+// ToolLoader loads the current definitions of a set of tools, for example
+// from configuration files or a plugin directory.
+type ToolLoader func(ctx context.Context) ([]Tool, error)
+
+// ToolReloader reloads a [ToolRegistry] from a [ToolLoader], so that tool
+// configuration can change without restarting the process and dropping live
+// sessions. Reloads are serialized.
+//
+// Example:
+//
+//	registry, _ := copilot.NewToolRegistry()
+//	reloader := copilot.NewToolReloader(registry, func(ctx context.Context) ([]copilot.Tool, error) {
+//	    return runner.LoadDir(ctx, "/etc/myapp/plugins")
+//	})
+//	if _, err := reloader.Reload(ctx); err != nil {
+//	    log.Fatal(err)
+//	}
+//	go reloader.ReloadOnSignal(ctx, func(diff copilot.CatalogDiff, err error) {
+//	    if err != nil {
+//	        log.Printf("Tool reload failed: %v", err)
+//	        return
+//	    }
+//	    log.Printf("Tools reloaded:\n%s", diff.Markdown())
+//	})
+type ToolReloader struct {
+	registry *ToolRegistry
+	load     ToolLoader
+	mu       sync.Mutex
+}
+
+// NewToolReloader creates a reloader that syncs registry with the tools load
+// returns.
+func NewToolReloader(registry *ToolRegistry, load ToolLoader) *ToolReloader {
+	return &ToolReloader{registry: registry, load: load}
+}
+
+// Reload loads the tools and syncs the registry with them, returning what
+// changed. If loading fails the registry is left as it was. See
+// [ToolRegistry.Sync].
+func (r *ToolReloader) Reload(ctx context.Context) (CatalogDiff, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	tools, err := r.load(ctx)
+	if err != nil {
+		return CatalogDiff{}, fmt.Errorf("failed to load tools: %w", err)
+	}
+	return r.registry.Sync(tools)
+}
+
+// ReloadOnSignal reloads the tools each time the process receives one of
+// signals, SIGHUP by default, until ctx is done, passing the outcome of each
+// reload to report, which may be nil.
+func (r *ToolReloader) ReloadOnSignal(ctx context.Context, report func(diff CatalogDiff, err error), signals ...os.Signal) {
+	if len(signals) == 0 {
+		signals = []os.Signal{syscall.SIGHUP}
+	}
+	received := make(chan os.Signal, 1)
+	signal.Notify(received, signals...)
+	defer signal.Stop(received)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-received:
+			diff, err := r.Reload(ctx)
+			if report != nil {
+				report(diff, err)
+			}
+		}
+	}
+}
@@ -0,0 +1,137 @@
+package copilot
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestToolRegistry_Sync(t *testing.T) {
+	var mu sync.Mutex
+	var updates [][]string
+	rpc, _ := newFakeServer(t, func(method string, params map[string]interface{}) (map[string]interface{}, *JSONRPCError) {
+		switch method {
+		case "session.create":
+			return map[string]interface{}{"sessionId": "s1"}, nil
+		case "session.updateTools":
+			mu.Lock()
+			updates = append(updates, advertisedNames(params["tools"]))
+			mu.Unlock()
+		}
+		return nil, nil
+	})
+	client := &Client{client: rpc, state: StateConnected, sessions: make(map[string]*Session)}
+	updateCount := func() int {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(updates)
+	}
+
+	registry, _ := NewToolRegistry(namedTool("search", "search"), namedTool("calendar", "calendar"))
+	session, err := client.CreateSession(&SessionConfig{ToolRegistry: registry})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	call := func(name string) string {
+		handler, ok := session.getToolHandler(name)
+		if !ok {
+			return ""
+		}
+		result, _ := handler(ToolInvocation{ToolName: name})
+		return result.TextResultForLLM
+	}
+
+	t.Run("applies and reports changes in one update", func(t *testing.T) {
+		diff, err := registry.Sync([]Tool{namedTool("weather", "weather"), namedTool("search", "search v2")})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if strings.Join(diff.Added, ",") != "weather" || strings.Join(diff.Removed, ",") != "calendar" || len(diff.Changed) != 1 || diff.Changed[0].Name != "search" {
+			t.Errorf("Unexpected diff %+v", diff)
+		}
+		mu.Lock()
+		if len(updates) != 1 || strings.Join(updates[0], ",") != "search,weather" {
+			t.Errorf("Expected one update advertising search,weather, got %v", updates)
+		}
+		mu.Unlock()
+		if call("search") != "search v2" || call("calendar") != "" {
+			t.Errorf("Expected the session's handlers to follow the registry")
+		}
+	})
+
+	t.Run("swaps handlers without a round trip when definitions are unchanged", func(t *testing.T) {
+		before := updateCount()
+		tool := namedTool("search", "search v2")
+		tool.Handler = func(inv ToolInvocation) (ToolResult, error) {
+			return ToolResult{TextResultForLLM: "new handler"}, nil
+		}
+		diff, err := registry.Sync([]Tool{tool, namedTool("weather", "weather")})
+		if err != nil || !diff.Empty() {
+			t.Fatalf("Expected an empty diff, got %+v, %v", diff, err)
+		}
+		if updateCount() != before {
+			t.Error("Expected no update for unchanged definitions")
+		}
+		if got := call("search"); got != "new handler" {
+			t.Errorf("Expected the new handler, got %q", got)
+		}
+	})
+
+	t.Run("rejects invalid sets without changing the registry", func(t *testing.T) {
+		before := updateCount()
+		if _, err := registry.Sync([]Tool{namedTool("a", "a"), namedTool("a", "b")}); err == nil || !strings.Contains(err.Error(), "registered twice") {
+			t.Errorf("Expected a duplicate tool error, got %v", err)
+		}
+		if _, err := registry.Sync([]Tool{{Description: "unnamed"}}); err == nil {
+			t.Error("Expected an error for an unnamed tool")
+		}
+		if len(registry.Tools()) != 2 || updateCount() != before {
+			t.Error("Expected the registry to be unchanged")
+		}
+		frozen, _ := NewToolRegistry()
+		frozen.Freeze()
+		if _, err := frozen.Sync(nil); !errors.Is(err, ErrToolRegistryFrozen) {
+			t.Errorf("Expected ErrToolRegistryFrozen, got %v", err)
+		}
+	})
+
+	t.Run("reports sessions whose handlers cannot be swapped", func(t *testing.T) {
+		session.registerToolLimits(toolLimitPolicy{mode: ToolLimitsStrict, limits: ToolLimits{MaxNameLength: 3}})
+		diff, err := registry.Sync(registry.Tools())
+		if !diff.Empty() {
+			t.Fatalf("Expected an empty diff, got %+v", diff)
+		}
+		var limitErr *ToolLimitError
+		if !errors.As(err, &limitErr) || !strings.Contains(err.Error(), "failed to update tools for session s1") {
+			t.Errorf("Expected the tool limit error, got %v", err)
+		}
+	})
+}
+
+func TestToolReloader(t *testing.T) {
+	registry, _ := NewToolRegistry(namedTool("search", "search"))
+	var fail bool
+	var version string
+	reloader := NewToolReloader(registry, func(ctx context.Context) ([]Tool, error) {
+		if fail {
+			return nil, errors.New("manifest is invalid")
+		}
+		return []Tool{namedTool("search", "search "+version)}, nil
+	})
+
+	version = "v2"
+	diff, err := reloader.Reload(context.Background())
+	if err != nil || len(diff.Changed) != 1 || !diff.Changed[0].DescriptionChanged {
+		t.Fatalf("Unexpected diff %+v, %v", diff, err)
+	}
+
+	fail = true
+	if _, err := reloader.Reload(context.Background()); err == nil || !strings.Contains(err.Error(), "failed to load tools") {
+		t.Errorf("Expected a load error, got %v", err)
+	}
+	if tools := registry.Tools(); len(tools) != 1 || tools[0].Description != "search v2" {
+		t.Errorf("Expected a failed load to leave the registry unchanged, got %+v", tools)
+	}
+}
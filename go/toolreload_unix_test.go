@@ -0,0 +1,60 @@
+//go:build unix
+
+package copilot
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestToolReloader_ReloadOnSignal(t *testing.T) {
+	// Keep SIGUSR1 from terminating the test binary before the reloader
+	// subscribes to it.
+	ignored := make(chan os.Signal, 1)
+	signal.Notify(ignored, syscall.SIGUSR1)
+	defer signal.Stop(ignored)
+
+	registry, _ := NewToolRegistry(namedTool("search", "search"))
+	reloader := NewToolReloader(registry, func(ctx context.Context) ([]Tool, error) {
+		return []Tool{namedTool("search", "search v2")}, nil
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	reports := make(chan CatalogDiff, 1)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		reloader.ReloadOnSignal(ctx, func(diff CatalogDiff, err error) {
+			if err != nil {
+				t.Errorf("Unexpected error: %v", err)
+			}
+			select {
+			case reports <- diff:
+			default:
+			}
+		}, syscall.SIGUSR1)
+	}()
+
+	// Signal until the reloader has subscribed and reloaded.
+	var diff CatalogDiff
+	for received := false; !received; {
+		if err := syscall.Kill(syscall.Getpid(), syscall.SIGUSR1); err != nil {
+			t.Fatal(err)
+		}
+		select {
+		case diff = <-reports:
+			received = true
+		case <-time.After(20 * time.Millisecond):
+		}
+	}
+	cancel()
+	<-done
+
+	if len(diff.Changed) != 1 || registry.Tools()[0].Description != "search v2" {
+		t.Errorf("Unexpected diff %+v", diff)
+	}
+}
@@ -0,0 +1,274 @@
+package copilot
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+)
+
+// ToolRecording is a tool invocation recorded by a [ToolRecorder].
+type ToolRecording struct {
+	SessionID  string      `json:"sessionId,omitempty"`
+	ToolCallID string      `json:"toolCallId"`
+	ToolName   string      `json:"toolName"`
+	Arguments  interface{} `json:"arguments,omitempty"`
+	// Result is the result returned to the model. Its Attachments and
+	// DisplayData are not recorded.
+	Result ToolResult `json:"result"`
+	// Error is the message of the error the handler returned, if any, in
+	// which case Result is empty.
+	Error string `json:"error,omitempty"`
+	// Time is when the invocation started, and Latency how long it took.
+	Time    time.Time     `json:"time"`
+	Latency time.Duration `json:"latency"`
+}
+
+// ToolRecorder records every tool invocation of a client or session — its
+// arguments, result, and latency — as one JSON line per invocation, to be
+// replayed by a [ToolReplayer]. Install it with its Middleware, outermost, so
+// that it records the results the model receives. Calls deferred with
+// [ToolInvocation.PendingResult] are not recorded.
+//
+// Recordings hold arguments and results verbatim; set
+// [ClientOptions.ResultSanitizer] to keep secrets out of them.
+//
+// Example:
+//
+//	f, _ := os.Create("tools.jsonl")
+//	defer f.Close()
+//	recorder := copilot.NewToolRecorder(f)
+//	client := copilot.NewClient(&copilot.ClientOptions{
+//	    ToolMiddleware: []copilot.ToolMiddleware{recorder.Middleware()},
+//	})
+type ToolRecorder struct {
+	mu  sync.Mutex
+	w   io.Writer
+	err error
+	now func() time.Time
+}
+
+// NewToolRecorder returns a recorder that writes to w.
+func NewToolRecorder(w io.Writer) *ToolRecorder {
+	return &ToolRecorder{w: w, now: time.Now}
+}
+
+// Middleware returns the middleware that records the invocations it wraps.
+func (r *ToolRecorder) Middleware() ToolMiddleware {
+	return func(next ToolHandler) ToolHandler {
+		return func(inv ToolInvocation) (ToolResult, error) {
+			start := r.now()
+			result, err := next(inv)
+			if result.pending != "" && err == nil {
+				return result, err
+			}
+			recording := ToolRecording{
+				SessionID:  inv.SessionID,
+				ToolCallID: inv.ToolCallID,
+				ToolName:   inv.ToolName,
+				Arguments:  inv.Arguments,
+				Time:       start,
+				Latency:    r.now().Sub(start),
+			}
+			if err != nil {
+				recording.Error = err.Error()
+			} else {
+				recording.Result = result
+			}
+			r.write(recording)
+			return result, err
+		}
+	}
+}
+
+func (r *ToolRecorder) write(recording ToolRecording) {
+	data, err := json.Marshal(recording)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.err != nil {
+		return
+	}
+	if err != nil {
+		r.err = fmt.Errorf("failed to encode recording of tool call %s: %w", recording.ToolCallID, err)
+		return
+	}
+	if _, err := r.w.Write(append(data, '\n')); err != nil {
+		r.err = fmt.Errorf("failed to write tool recording: %w", err)
+	}
+}
+
+// Err returns the first error encoding or writing a recording. Invocations
+// are not recorded after it.
+func (r *ToolRecorder) Err() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.err
+}
+
+// ReadToolRecordings reads the recordings a [ToolRecorder] wrote, in order.
+func ReadToolRecordings(r io.Reader) ([]ToolRecording, error) {
+	var recordings []ToolRecording
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 64*1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		data := bytes.TrimSpace(scanner.Bytes())
+		if len(data) == 0 {
+			continue
+		}
+		var recording ToolRecording
+		if err := json.Unmarshal(data, &recording); err != nil {
+			return nil, fmt.Errorf("failed to decode tool recording on line %d: %w", line, err)
+		}
+		recordings = append(recordings, recording)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read tool recordings: %w", err)
+	}
+	return recordings, nil
+}
+
+// ToolReplayConfig configures a [ToolReplayer].
+type ToolReplayConfig struct {
+	// IgnoreArguments matches invocations to recordings by tool name only,
+	// in order, instead of by tool name and arguments.
+	IgnoreArguments bool
+	// Passthrough runs the real handler of invocations without a matching
+	// recording. By default they fail.
+	Passthrough bool
+}
+
+// ToolReplayer returns recorded results instead of running tool handlers, so
+// that prompts can be regression-tested without reaching real systems. Each
+// invocation is matched to the first unused recording of the same tool with
+// the same arguments, so repeated calls replay in recorded order. An
+// invocation without a match gets a failure result with errorType
+// "replay_miss", and is reported by Misses.
+//
+// Example:
+//
+//	f, _ := os.Open("testdata/tools.jsonl")
+//	recordings, err := copilot.ReadToolRecordings(f)
+//	if err != nil {
+//	    t.Fatal(err)
+//	}
+//	replayer := copilot.NewToolReplayer(recordings, nil)
+//	client := copilot.NewClient(&copilot.ClientOptions{
+//	    ToolMiddleware: []copilot.ToolMiddleware{replayer.Middleware()},
+//	})
+//	// ...
+//	if misses := replayer.Misses(); len(misses) > 0 {
+//	    t.Errorf("unrecorded tool calls: %v", misses)
+//	}
+type ToolReplayer struct {
+	config ToolReplayConfig
+
+	mu         sync.Mutex
+	recordings []ToolRecording
+	used       []bool
+	misses     []ToolRecording
+}
+
+// NewToolReplayer returns a replayer of recordings. config may be nil.
+func NewToolReplayer(recordings []ToolRecording, config *ToolReplayConfig) *ToolReplayer {
+	r := &ToolReplayer{
+		recordings: append([]ToolRecording(nil), recordings...),
+		used:       make([]bool, len(recordings)),
+	}
+	if config != nil {
+		r.config = *config
+	}
+	return r
+}
+
+// Middleware returns the middleware that replays the invocations it wraps.
+func (r *ToolReplayer) Middleware() ToolMiddleware {
+	return func(next ToolHandler) ToolHandler {
+		return func(inv ToolInvocation) (ToolResult, error) {
+			recording, ok := r.match(inv)
+			switch {
+			case ok && recording.Error != "":
+				return ToolResult{}, errors.New(recording.Error)
+			case ok:
+				return recording.Result, nil
+			case r.config.Passthrough:
+				return next(inv)
+			}
+			return buildReplayMissResult(inv.ToolName), nil
+		}
+	}
+}
+
+// match claims the first unused recording of inv, or records a miss.
+func (r *ToolReplayer) match(inv ToolInvocation) (ToolRecording, bool) {
+	arguments := canonicalArguments(inv.Arguments)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i, recording := range r.recordings {
+		if r.used[i] || recording.ToolName != inv.ToolName {
+			continue
+		}
+		if !r.config.IgnoreArguments && canonicalArguments(recording.Arguments) != arguments {
+			continue
+		}
+		r.used[i] = true
+		return recording, true
+	}
+	r.misses = append(r.misses, ToolRecording{
+		SessionID:  inv.SessionID,
+		ToolCallID: inv.ToolCallID,
+		ToolName:   inv.ToolName,
+		Arguments:  inv.Arguments,
+	})
+	return ToolRecording{}, false
+}
+
+// canonicalArguments encodes arguments so that equal arguments, such as a
+// recorded and a live map, encode the same.
+func canonicalArguments(arguments interface{}) string {
+	data, err := json.Marshal(arguments)
+	if err != nil {
+		return fmt.Sprintf("%v", arguments)
+	}
+	var decoded interface{}
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		return string(data)
+	}
+	data, _ = json.Marshal(decoded)
+	return string(data)
+}
+
+// Misses returns the invocations that had no matching recording, oldest
+// first, without results.
+func (r *ToolReplayer) Misses() []ToolRecording {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]ToolRecording(nil), r.misses...)
+}
+
+// Unused returns the recordings no invocation has replayed yet, in order.
+func (r *ToolReplayer) Unused() []ToolRecording {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var unused []ToolRecording
+	for i, recording := range r.recordings {
+		if !r.used[i] {
+			unused = append(unused, recording)
+		}
+	}
+	return unused
+}
+
+// buildReplayMissResult creates a failure ToolResult for an invocation that
+// has no recorded result.
+func buildReplayMissResult(toolName string) ToolResult {
+	return ToolResult{
+		TextResultForLLM: fmt.Sprintf("Tool '%s' has no recorded result for these arguments.", toolName),
+		ResultType:       "failure",
+		Error:            fmt.Sprintf("no recording of tool '%s' matches the invocation", toolName),
+		ToolTelemetry:    map[string]interface{}{"errorType": "replay_miss"},
+	}
+}
@@ -0,0 +1,97 @@
+package copilot
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestToolRecordAndReplay(t *testing.T) {
+	type lookupParams struct {
+		ID int `json:"id"`
+	}
+	var runs int
+	tools := func() []Tool {
+		return []Tool{
+			DefineTool("lookup", "Look up an issue", func(params lookupParams, inv ToolInvocation) (string, error) {
+				runs++
+				if params.ID == 0 {
+					return "", errors.New("issue not found")
+				}
+				return fmt.Sprintf("issue %d", params.ID), nil
+			}, WithoutErrorRenderer()),
+		}
+	}
+	session := func(middleware ToolMiddleware) *Session {
+		session := NewSession("s1", nil, "")
+		session.registerToolMiddleware([]ToolMiddleware{middleware}, nil)
+		session.registerTools(tools())
+		return session
+	}
+	call := func(session *Session, id int) (ToolResult, error) {
+		handler, _ := session.getToolHandler("lookup")
+		return handler(ToolInvocation{SessionID: "s1", ToolCallID: "call-1", ToolName: "lookup", Arguments: map[string]interface{}{"id": id}})
+	}
+
+	var buf bytes.Buffer
+	recorder := NewToolRecorder(&buf)
+	recording := session(recorder.Middleware())
+	call(recording, 1)
+	call(recording, 2)
+	call(recording, 1)
+	call(recording, 0)
+	if err := recorder.Err(); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	recordings, err := ReadToolRecordings(&buf)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(recordings) != 4 || recordings[0].ToolName != "lookup" || recordings[0].Result.TextResultForLLM != "issue 1" || recordings[0].Latency <= 0 {
+		t.Fatalf("Unexpected recordings %+v", recordings)
+	}
+	if recordings[3].Error != "issue not found" || recordings[3].SessionID != "s1" {
+		t.Errorf("Expected the handler error to be recorded, got %+v", recordings[3])
+	}
+
+	t.Run("replays recorded results without running handlers", func(t *testing.T) {
+		runs = 0
+		replayer := NewToolReplayer(recordings, nil)
+		replaying := session(replayer.Middleware())
+		if result, _ := call(replaying, 2); result.TextResultForLLM != "issue 2" {
+			t.Errorf("Expected the recorded result, got %+v", result)
+		}
+		if result, _ := call(replaying, 1); result.TextResultForLLM != "issue 1" {
+			t.Errorf("Expected the recorded result, got %+v", result)
+		}
+		if _, err := call(replaying, 0); err == nil || err.Error() != "issue not found" {
+			t.Errorf("Expected the recorded error, got %v", err)
+		}
+		if runs != 0 || len(replayer.Unused()) != 1 {
+			t.Errorf("Expected no handler to run and one recording left, got %d runs, %+v", runs, replayer.Unused())
+		}
+
+		call(replaying, 1)
+		result, _ := call(replaying, 1)
+		if result.ToolTelemetry["errorType"] != "replay_miss" || len(replayer.Misses()) != 1 {
+			t.Errorf("Expected a miss once the recordings are used up, got %+v", result)
+		}
+	})
+
+	t.Run("passes misses through when configured", func(t *testing.T) {
+		runs = 0
+		replayer := NewToolReplayer(recordings, &ToolReplayConfig{Passthrough: true})
+		if result, _ := call(session(replayer.Middleware()), 3); result.TextResultForLLM != "issue 3" || runs != 1 {
+			t.Errorf("Expected the handler to run, got %+v", result)
+		}
+	})
+
+	t.Run("matches by order when ignoring arguments", func(t *testing.T) {
+		replayer := NewToolReplayer(recordings, &ToolReplayConfig{IgnoreArguments: true})
+		if result, _ := call(session(replayer.Middleware()), 9); result.TextResultForLLM != "issue 1" {
+			t.Errorf("Expected the first recording, got %+v", result)
+		}
+	})
+}
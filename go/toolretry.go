@@ -0,0 +1,206 @@
+package copilot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net"
+	"syscall"
+	"time"
+)
+
+// Defaults of a [RetryPolicy].
+const (
+	DefaultRetryAttempts       = 3
+	DefaultRetryInitialBackoff = 200 * time.Millisecond
+	DefaultRetryMaxBackoff     = 10 * time.Second
+)
+
+// RetryPolicy retries calls of a tool that fail transiently, such as on a
+// network hiccup or a rate limit, before the failure is returned to the model.
+// Each attempt gets the tool's full Timeout, and calls wait between attempts
+// with exponential backoff. A retry is announced with an [SDKToolRetried]
+// event, and a result that took more than one attempt has ToolTelemetry
+// "attempts".
+//
+// Example:
+//
+//	tool := copilot.DefineToolCtx("search_issues", "Search the issue tracker", searchIssues,
+//	    copilot.WithRetryPolicy(copilot.RetryPolicy{MaxAttempts: 4}))
+type RetryPolicy struct {
+	// MaxAttempts is the number of attempts including the first. Defaults to
+	// [DefaultRetryAttempts].
+	MaxAttempts int
+	// InitialBackoff is the wait before the first retry. Defaults to
+	// [DefaultRetryInitialBackoff].
+	InitialBackoff time.Duration
+	// MaxBackoff caps the wait between attempts. Defaults to
+	// [DefaultRetryMaxBackoff].
+	MaxBackoff time.Duration
+	// Multiplier is what the wait is multiplied by after each retry. Defaults
+	// to 2.
+	Multiplier float64
+	// Retryable reports whether an attempt that returned result and err should
+	// be retried. err is the handler's error, including one a tool's
+	// [ErrorRenderer] turned into a failure result. Defaults to
+	// [IsRetryableToolError] of err.
+	Retryable func(result ToolResult, err error) bool
+}
+
+// RetryableError marks an error as transient, so that [IsRetryableToolError]
+// reports it as retryable. Handlers can return one for responses such as HTTP
+// 429 or 503.
+//
+// Example:
+//
+//	if resp.StatusCode == http.StatusTooManyRequests {
+//	    return nil, &copilot.RetryableError{Err: errors.New("rate limited"), RetryAfter: 2 * time.Second}
+//	}
+type RetryableError struct {
+	Err error
+	// RetryAfter, if positive, is how long to wait before retrying, instead of
+	// the policy's backoff.
+	RetryAfter time.Duration
+}
+
+func (e *RetryableError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *RetryableError) Unwrap() error {
+	return e.Err
+}
+
+// IsRetryableToolError reports whether err is likely transient: a
+// [*RetryableError], a network timeout, a refused or reset connection, an
+// unexpected EOF, or an error whose Temporary method reports true. Panics and
+// canceled contexts are not retryable. A [*ToolError] is retryable if its
+// Retryable field is set.
+func IsRetryableToolError(err error) bool {
+	if err == nil {
+		return false
+	}
+	if _, panicked := asToolPanic(err); panicked {
+		return false
+	}
+	if toolErr, ok := asToolError(err); ok {
+		return toolErr.Retryable
+	}
+	if errors.Is(err, context.Canceled) {
+		return false
+	}
+	var retryable *RetryableError
+	if errors.As(err, &retryable) {
+		return true
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return true
+	}
+	if errors.Is(err, syscall.ECONNREFUSED) || errors.Is(err, syscall.ECONNRESET) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return true
+	}
+	var temporary interface{ Temporary() bool }
+	return errors.As(err, &temporary) && temporary.Temporary()
+}
+
+// WithRetryPolicy retries failed calls of the tool. See [RetryPolicy].
+func WithRetryPolicy(policy RetryPolicy) ToolOption {
+	return func(o *toolOptions) {
+		o.retryPolicy = &policy
+	}
+}
+
+// backoff returns the wait before retry number n, starting at 1.
+func (p *RetryPolicy) backoff(n int) time.Duration {
+	initial, max, multiplier := p.InitialBackoff, p.MaxBackoff, p.Multiplier
+	if initial <= 0 {
+		initial = DefaultRetryInitialBackoff
+	}
+	if max <= 0 {
+		max = DefaultRetryMaxBackoff
+	}
+	if multiplier < 1 {
+		multiplier = 2
+	}
+	wait := float64(initial) * math.Pow(multiplier, float64(n-1))
+	if wait > float64(max) {
+		return max
+	}
+	return time.Duration(wait)
+}
+
+// withRetries wraps handler so that calls failing as policy allows are
+// retried, announcing each retry with an [SDKToolRetried] event.
+func (s *Session) withRetries(name string, policy *RetryPolicy, handler ToolHandler) ToolHandler {
+	if policy == nil {
+		return handler
+	}
+	attempts := policy.MaxAttempts
+	if attempts <= 0 {
+		attempts = DefaultRetryAttempts
+	}
+	if attempts == 1 {
+		return handler
+	}
+	retryable := policy.Retryable
+	if retryable == nil {
+		retryable = func(result ToolResult, err error) bool { return IsRetryableToolError(err) }
+	}
+
+	return func(inv ToolInvocation) (ToolResult, error) {
+		for attempt := 1; ; attempt++ {
+			result, err := handler(inv)
+			cause := err
+			if cause == nil {
+				cause = result.renderedErr
+			}
+			if attempt == attempts || (cause == nil && result.ResultType != "failure") || !retryable(result, cause) {
+				if attempt > 1 && err == nil {
+					result.ToolTelemetry = withTelemetry(result.ToolTelemetry, "attempts", attempt)
+				}
+				return result, err
+			}
+
+			wait := policy.backoff(attempt)
+			var after *RetryableError
+			if errors.As(cause, &after) && after.RetryAfter > 0 {
+				wait = after.RetryAfter
+			}
+			message := result.Error
+			if cause != nil {
+				message = cause.Error()
+			}
+			s.emit(SDKToolRetried, map[string]interface{}{
+				"toolCallId": inv.ToolCallID,
+				"toolName":   name,
+				"attempt":    attempt + 1,
+				"delayMs":    wait.Milliseconds(),
+				"error":      message,
+			})
+
+			timer := time.NewTimer(wait)
+			select {
+			case <-timer.C:
+			case <-inv.Context().Done():
+				timer.Stop()
+				if err == nil {
+					return result, nil
+				}
+				return ToolResult{}, fmt.Errorf("tool %s was canceled before retrying: %w", name, err)
+			}
+		}
+	}
+}
+
+// withTelemetry returns a copy of telemetry with key set to value.
+func withTelemetry(telemetry map[string]interface{}, key string, value interface{}) map[string]interface{} {
+	copied := make(map[string]interface{}, len(telemetry)+1)
+	for k, v := range telemetry {
+		copied[k] = v
+	}
+	copied[key] = value
+	return copied
+}
@@ -0,0 +1,151 @@
+package copilot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRetryPolicy(t *testing.T) {
+	callTool := func(session *Session, name string, ctx context.Context) (ToolResult, error) {
+		handler, _ := session.getToolHandler(name)
+		return handler(ToolInvocation{ToolName: name, ToolCallID: "call-1", Arguments: map[string]interface{}{}, ctx: ctx})
+	}
+	// flaky returns a handler that fails with err until it has been called n
+	// times.
+	flaky := func(calls *atomic.Int32, n int32, err error) ToolHandler {
+		return func(inv ToolInvocation) (ToolResult, error) {
+			if calls.Add(1) < n {
+				return ToolResult{}, err
+			}
+			return ToolResult{TextResultForLLM: "ok", ResultType: "success"}, nil
+		}
+	}
+	fast := &RetryPolicy{MaxAttempts: 3, InitialBackoff: time.Millisecond}
+
+	t.Run("retries transient failures", func(t *testing.T) {
+		var calls atomic.Int32
+		var retried []SessionEvent
+		session := NewSession("s1", nil, "")
+		session.On(func(event SessionEvent) {
+			if event.Type == SDKToolRetried {
+				retried = append(retried, event)
+			}
+		})
+		session.registerTools([]Tool{{Name: "fetch", Handler: flaky(&calls, 3, fmt.Errorf("failed to fetch: %w", io.ErrUnexpectedEOF)), RetryPolicy: fast}})
+
+		result, err := callTool(session, "fetch", context.Background())
+		if err != nil || result.ResultType != "success" || result.ToolTelemetry["attempts"] != 3 {
+			t.Fatalf("Unexpected result %+v, error %v", result, err)
+		}
+		if len(retried) != 2 || retried[1].Data.Metadata.Variables["attempt"] != 3 || sdkEventString(retried[0], "error") != "failed to fetch: unexpected EOF" {
+			t.Errorf("Unexpected retry events %+v", retried)
+		}
+	})
+
+	t.Run("gives up after MaxAttempts", func(t *testing.T) {
+		var calls atomic.Int32
+		session := NewSession("s1", nil, "")
+		session.registerTools([]Tool{{Name: "fetch", Handler: flaky(&calls, 10, &RetryableError{Err: errors.New("rate limited")}), RetryPolicy: fast}})
+		if _, err := callTool(session, "fetch", context.Background()); err == nil || err.Error() != "rate limited" || calls.Load() != 3 {
+			t.Errorf("Expected 3 attempts and the last error, got %d and %v", calls.Load(), err)
+		}
+	})
+
+	t.Run("does not retry other errors", func(t *testing.T) {
+		var calls atomic.Int32
+		session := NewSession("s1", nil, "")
+		session.registerTools([]Tool{{Name: "fetch", Handler: flaky(&calls, 10, errors.New("not found")), RetryPolicy: fast}})
+		if _, err := callTool(session, "fetch", context.Background()); err == nil || calls.Load() != 1 {
+			t.Errorf("Expected one attempt, got %d", calls.Load())
+		}
+	})
+
+	t.Run("classifies errors an ErrorRenderer rendered", func(t *testing.T) {
+		var calls atomic.Int32
+		tool := DefineTool("fetch", "Fetch", func(params struct{}, inv ToolInvocation) (string, error) {
+			if calls.Add(1) < 2 {
+				return "", &RetryableError{Err: errors.New("busy")}
+			}
+			return "ok", nil
+		}, WithErrorRenderer(RenderToolError), WithRetryPolicy(*fast))
+		session := NewSession("s1", nil, "")
+		session.registerTools([]Tool{tool})
+		if result, err := callTool(session, "fetch", context.Background()); err != nil || result.TextResultForLLM != "ok" || calls.Load() != 2 {
+			t.Errorf("Unexpected result %+v after %d calls", result, calls.Load())
+		}
+	})
+
+	t.Run("uses a custom classifier and RetryAfter", func(t *testing.T) {
+		var calls atomic.Int32
+		var delays []interface{}
+		session := NewSession("s1", nil, "")
+		session.On(func(event SessionEvent) {
+			if event.Type == SDKToolRetried {
+				delays = append(delays, event.Data.Metadata.Variables["delayMs"])
+			}
+		})
+		policy := &RetryPolicy{
+			MaxAttempts:    2,
+			InitialBackoff: time.Hour,
+			Retryable: func(result ToolResult, err error) bool {
+				return strings.Contains(result.Error, "try again")
+			},
+		}
+		session.registerTools([]Tool{{Name: "fetch", RetryPolicy: policy, Handler: func(inv ToolInvocation) (ToolResult, error) {
+			if calls.Add(1) == 1 {
+				return ToolResult{ResultType: "failure", Error: "try again", renderedErr: &RetryableError{Err: errors.New("x"), RetryAfter: 5 * time.Millisecond}}, nil
+			}
+			return ToolResult{ResultType: "success"}, nil
+		}}})
+		if result, _ := callTool(session, "fetch", context.Background()); result.ResultType != "success" || len(delays) != 1 || delays[0] != int64(5) {
+			t.Errorf("Unexpected result %+v, delays %v", result, delays)
+		}
+	})
+
+	t.Run("stops waiting when the call is canceled", func(t *testing.T) {
+		session := NewSession("s1", nil, "")
+		session.registerTools([]Tool{{Name: "fetch", Handler: func(inv ToolInvocation) (ToolResult, error) {
+			return ToolResult{}, &RetryableError{Err: errors.New("busy")}
+		}, RetryPolicy: &RetryPolicy{InitialBackoff: time.Hour}}})
+		ctx, cancel := context.WithCancel(context.Background())
+		time.AfterFunc(10*time.Millisecond, cancel)
+		if _, err := callTool(session, "fetch", ctx); err == nil || !strings.Contains(err.Error(), "canceled before retrying") {
+			t.Errorf("Unexpected error %v", err)
+		}
+	})
+}
+
+func TestRetryPolicyBackoff(t *testing.T) {
+	policy := &RetryPolicy{InitialBackoff: 100 * time.Millisecond, MaxBackoff: time.Second}
+	for n, want := range map[int]time.Duration{1: 100 * time.Millisecond, 2: 200 * time.Millisecond, 4: 800 * time.Millisecond, 5: time.Second} {
+		if got := policy.backoff(n); got != want {
+			t.Errorf("backoff(%d) = %v, want %v", n, got, want)
+		}
+	}
+}
+
+func TestIsRetryableToolError(t *testing.T) {
+	retryable := []error{
+		&RetryableError{Err: errors.New("rate limited")},
+		fmt.Errorf("read: %w", io.ErrUnexpectedEOF),
+		context.DeadlineExceeded,
+		fmt.Errorf("search: %w", &ToolError{Code: "rate_limited", Retryable: true}),
+	}
+	for _, err := range retryable {
+		if !IsRetryableToolError(err) {
+			t.Errorf("Expected %v to be retryable", err)
+		}
+	}
+	for _, err := range []error{nil, errors.New("not found"), context.Canceled, &ToolPanicError{ToolName: "x", Value: "boom"},
+		&ToolError{Code: "timeout", Err: context.DeadlineExceeded}} {
+		if IsRetryableToolError(err) {
+			t.Errorf("Expected %v not to be retryable", err)
+		}
+	}
+}
@@ -0,0 +1,168 @@
+// Package bio provides sequence-analysis tools for bio-agent workflows:
+// reverse complement, GC content, ORF finding, translation, and FASTA parsing.
+//
+// The underlying functions ([ReverseComplement], [GCContent], [FindORFs],
+// [Translate], [ParseFASTA]) can also be used directly.
+//
+// Example:
+//
+//	session, err := client.CreateSession(&copilot.SessionConfig{
+//	    Tools: bio.Tools(),
+//	})
+package bio
+
+import (
+	"fmt"
+	"strings"
+
+	copilot "github.com/github/copilot-sdk/go"
+)
+
+// defaultMinORFLength is the default minimum ORF length in nucleotides,
+// a common threshold for reporting putative coding regions.
+const defaultMinORFLength = 75
+
+// Tools returns every tool in this package.
+func Tools() []copilot.Tool {
+	return []copilot.Tool{
+		ReverseComplementTool(),
+		GCContentTool(),
+		FindORFsTool(),
+		TranslateTool(),
+		ParseFASTATool(),
+	}
+}
+
+// SequenceParams are the arguments of tools that take a single sequence.
+type SequenceParams struct {
+	Sequence string `json:"sequence" jsonschema:"nucleotide sequence (IUPAC codes; whitespace and digits are ignored)"`
+}
+
+// ReverseComplementResult is the result of the reverse_complement tool.
+type ReverseComplementResult struct {
+	ReverseComplement string `json:"reverseComplement"`
+	Length            int    `json:"length"`
+}
+
+// ReverseComplementTool returns the reverse_complement tool.
+func ReverseComplementTool() copilot.Tool {
+	return copilot.DefineTool("reverse_complement",
+		"Compute the reverse complement of a DNA or RNA sequence.",
+		func(params SequenceParams, inv copilot.ToolInvocation) (ReverseComplementResult, error) {
+			rc, err := ReverseComplement(params.Sequence)
+			if err != nil {
+				return ReverseComplementResult{}, err
+			}
+			return ReverseComplementResult{ReverseComplement: rc, Length: len(rc)}, nil
+		},
+		copilot.WithErrorRenderer(copilot.RenderToolError))
+}
+
+// GCContentTool returns the gc_content tool.
+func GCContentTool() copilot.Tool {
+	return copilot.DefineTool("gc_content",
+		"Compute the GC content and base counts of a nucleotide sequence.",
+		func(params SequenceParams, inv copilot.ToolInvocation) (GCStats, error) {
+			return GCContent(params.Sequence)
+		},
+		copilot.WithErrorRenderer(copilot.RenderToolError))
+}
+
+// FindORFsParams are the arguments of the find_orfs tool.
+type FindORFsParams struct {
+	Sequence    string `json:"sequence" jsonschema:"DNA sequence to search"`
+	MinLength   int    `json:"minLength,omitempty" jsonschema:"minimum ORF length in nucleotides including the stop codon (default 75)"`
+	BothStrands *bool  `json:"bothStrands,omitempty" jsonschema:"also search the reverse complement strand (default true)"`
+}
+
+// FindORFsResult is the result of the find_orfs tool.
+type FindORFsResult struct {
+	ORFs  []ORF `json:"orfs"`
+	Count int   `json:"count"`
+}
+
+// FindORFsTool returns the find_orfs tool.
+func FindORFsTool() copilot.Tool {
+	return copilot.DefineTool("find_orfs",
+		"Find open reading frames (ATG to stop codon, standard genetic code) in a DNA sequence.",
+		func(params FindORFsParams, inv copilot.ToolInvocation) (FindORFsResult, error) {
+			minLength := params.MinLength
+			if minLength <= 0 {
+				minLength = defaultMinORFLength
+			}
+			bothStrands := params.BothStrands == nil || *params.BothStrands
+			orfs, err := FindORFs(params.Sequence, minLength, bothStrands)
+			if err != nil {
+				return FindORFsResult{}, err
+			}
+			if orfs == nil {
+				orfs = []ORF{}
+			}
+			return FindORFsResult{ORFs: orfs, Count: len(orfs)}, nil
+		},
+		copilot.WithErrorRenderer(copilot.RenderToolError))
+}
+
+// TranslateResult is the result of the translate tool.
+type TranslateResult struct {
+	Protein string `json:"protein"`
+}
+
+// TranslateTool returns the translate tool.
+func TranslateTool() copilot.Tool {
+	return copilot.DefineTool("translate",
+		"Translate a DNA coding sequence into a protein sequence using the standard genetic code.",
+		func(params SequenceParams, inv copilot.ToolInvocation) (TranslateResult, error) {
+			if _, err := GCContent(params.Sequence); err != nil {
+				return TranslateResult{}, err
+			}
+			return TranslateResult{Protein: Translate(params.Sequence)}, nil
+		},
+		copilot.WithErrorRenderer(copilot.RenderToolError))
+}
+
+// ParseFASTAParams are the arguments of the parse_fasta tool.
+type ParseFASTAParams struct {
+	FASTA string `json:"fasta" jsonschema:"FASTA-formatted text with one or more records"`
+}
+
+// FASTASummary describes a parsed FASTA record.
+type FASTASummary struct {
+	FASTARecord
+	Length    int     `json:"length"`
+	GCContent float64 `json:"gcContent"`
+}
+
+// ParseFASTAResult is the result of the parse_fasta tool.
+type ParseFASTAResult struct {
+	Records []FASTASummary `json:"records"`
+}
+
+// ParseFASTATool returns the parse_fasta tool.
+func ParseFASTATool() copilot.Tool {
+	return copilot.DefineTool("parse_fasta",
+		"Parse FASTA text into records with their IDs, descriptions, sequences, lengths, and GC content.",
+		func(params ParseFASTAParams, inv copilot.ToolInvocation) (ParseFASTAResult, error) {
+			records, err := ParseFASTA(strings.NewReader(params.FASTA))
+			if err != nil {
+				return ParseFASTAResult{}, err
+			}
+			if len(records) == 0 {
+				return ParseFASTAResult{}, fmt.Errorf("no FASTA records found: each record must start with a '>' header line")
+			}
+			result := ParseFASTAResult{Records: make([]FASTASummary, 0, len(records))}
+			for _, record := range records {
+				stats, err := GCContent(record.Sequence)
+				if err != nil {
+					return ParseFASTAResult{}, fmt.Errorf("record %s: %w", record.ID, err)
+				}
+				result.Records = append(result.Records, FASTASummary{
+					FASTARecord: record,
+					Length:      len(record.Sequence),
+					GCContent:   stats.GCContent,
+				})
+			}
+			return result, nil
+		},
+		copilot.WithErrorRenderer(copilot.RenderToolError))
+}
@@ -0,0 +1,56 @@
+package bio
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	copilot "github.com/github/copilot-sdk/go"
+)
+
+func invoke(t *testing.T, tool copilot.Tool, args map[string]interface{}) copilot.ToolResult {
+	t.Helper()
+	result, err := tool.Handler(copilot.ToolInvocation{Arguments: args})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	return result
+}
+
+func TestTools(t *testing.T) {
+	t.Run("find_orfs defaults to both strands", func(t *testing.T) {
+		result := invoke(t, FindORFsTool(), map[string]interface{}{"sequence": "CCATGAAATAGGGTCAGGGCAT", "minLength": 9})
+		var decoded FindORFsResult
+		if err := json.Unmarshal([]byte(result.TextResultForLLM), &decoded); err != nil {
+			t.Fatalf("Failed to decode %q: %v", result.TextResultForLLM, err)
+		}
+		if decoded.Count != 2 {
+			t.Errorf("Expected 2 ORFs, got %+v", decoded)
+		}
+	})
+
+	t.Run("parse_fasta summarizes records", func(t *testing.T) {
+		result := invoke(t, ParseFASTATool(), map[string]interface{}{"fasta": ">a desc\nGGCC\n>b\nAATT"})
+		var decoded ParseFASTAResult
+		if err := json.Unmarshal([]byte(result.TextResultForLLM), &decoded); err != nil {
+			t.Fatalf("Failed to decode %q: %v", result.TextResultForLLM, err)
+		}
+		if len(decoded.Records) != 2 || decoded.Records[0].GCContent != 1 || decoded.Records[1].Length != 4 {
+			t.Errorf("Unexpected result %+v", decoded)
+		}
+	})
+
+	t.Run("invalid sequences are reported to the model", func(t *testing.T) {
+		result := invoke(t, ReverseComplementTool(), map[string]interface{}{"sequence": "ATGZ"})
+		if result.ResultType != "failure" || !strings.Contains(result.TextResultForLLM, "invalid nucleotide") {
+			t.Errorf("Unexpected result %+v", result)
+		}
+	})
+
+	t.Run("optional parameters are not required", func(t *testing.T) {
+		required, _ := FindORFsTool().Parameters["required"].([]interface{})
+		if len(required) != 1 || required[0] != "sequence" {
+			t.Errorf("Expected only sequence to be required, got %v", required)
+		}
+	})
+}
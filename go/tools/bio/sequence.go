@@ -0,0 +1,260 @@
+package bio
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// complements maps IUPAC nucleotide codes to their complements. Case is
+// preserved by complementing upper and lower case separately.
+var complements = map[byte]byte{
+	'A': 'T', 'T': 'A', 'U': 'A', 'G': 'C', 'C': 'G',
+	'R': 'Y', 'Y': 'R', 'S': 'S', 'W': 'W', 'K': 'M', 'M': 'K',
+	'B': 'V', 'V': 'B', 'D': 'H', 'H': 'D', 'N': 'N', '-': '-',
+}
+
+// normalizeSequence removes whitespace and digits, which commonly appear in
+// sequences pasted from GenBank or formatted output.
+func normalizeSequence(seq string) string {
+	var b strings.Builder
+	b.Grow(len(seq))
+	for i := 0; i < len(seq); i++ {
+		c := seq[i]
+		if c == ' ' || c == '\t' || c == '\n' || c == '\r' || (c >= '0' && c <= '9') {
+			continue
+		}
+		b.WriteByte(c)
+	}
+	return b.String()
+}
+
+// ReverseComplement returns the reverse complement of a DNA or RNA sequence.
+// IUPAC ambiguity codes are supported and case is preserved. If the sequence
+// contains U and no T, it is treated as RNA and complemented with U.
+// Whitespace and digits are ignored.
+func ReverseComplement(seq string) (string, error) {
+	seq = normalizeSequence(seq)
+	upper := strings.ToUpper(seq)
+	rna := strings.ContainsRune(upper, 'U') && !strings.ContainsRune(upper, 'T')
+
+	out := make([]byte, len(seq))
+	for i := 0; i < len(seq); i++ {
+		c := seq[i]
+		lower := c >= 'a' && c <= 'z'
+		comp, ok := complements[upper[i]]
+		if !ok {
+			return "", fmt.Errorf("invalid nucleotide %q at position %d", c, i+1)
+		}
+		if rna && comp == 'T' {
+			comp = 'U'
+		}
+		if lower {
+			comp += 'a' - 'A'
+		}
+		out[len(seq)-1-i] = comp
+	}
+	return string(out), nil
+}
+
+// GCStats summarizes the base composition of a sequence.
+type GCStats struct {
+	// Length is the number of bases counted, excluding gaps.
+	Length int `json:"length"`
+	// GC is the number of G, C, and S (strong) bases.
+	GC int `json:"gc"`
+	// Ambiguous is the number of ambiguity codes other than S, such as N.
+	Ambiguous int `json:"ambiguous"`
+	// GCContent is GC divided by the number of unambiguous bases, from 0 to 1.
+	GCContent float64 `json:"gcContent"`
+}
+
+// GCContent computes the GC content of a sequence. Ambiguous bases other than S
+// are excluded from the denominator; gaps are ignored.
+func GCContent(seq string) (GCStats, error) {
+	seq = strings.ToUpper(normalizeSequence(seq))
+	var stats GCStats
+	for i := 0; i < len(seq); i++ {
+		switch c := seq[i]; c {
+		case 'G', 'C', 'S':
+			stats.GC++
+		case 'A', 'T', 'U', 'W':
+		case '-':
+			continue
+		default:
+			if _, ok := complements[c]; !ok {
+				return GCStats{}, fmt.Errorf("invalid nucleotide %q at position %d", c, i+1)
+			}
+			stats.Ambiguous++
+		}
+		stats.Length++
+	}
+	if known := stats.Length - stats.Ambiguous; known > 0 {
+		stats.GCContent = float64(stats.GC) / float64(known)
+	}
+	return stats, nil
+}
+
+// ORF is an open reading frame.
+type ORF struct {
+	// Strand is "+" for the given sequence or "-" for its reverse complement.
+	Strand string `json:"strand"`
+	// Frame is the reading frame, 1 to 3, relative to the start of the strand.
+	Frame int `json:"frame"`
+	// Start and End are the 1-based, inclusive coordinates of the ORF on the
+	// given (+) strand, including the stop codon. Start > End on the - strand.
+	Start int `json:"start"`
+	End   int `json:"end"`
+	// Length is the length in nucleotides, including the stop codon.
+	Length int `json:"length"`
+	// Protein is the translated amino acid sequence, without the stop codon.
+	Protein string `json:"protein"`
+}
+
+// FindORFs finds open reading frames that start with ATG and end with a stop
+// codon (TAA, TAG, TGA), using the standard genetic code. ORFs shorter than
+// minLength nucleotides are skipped. When bothStrands is true the reverse
+// complement is searched too. Nested ORFs in the same frame are not reported.
+func FindORFs(seq string, minLength int, bothStrands bool) ([]ORF, error) {
+	seq = strings.ToUpper(normalizeSequence(seq))
+	seq = strings.ReplaceAll(seq, "U", "T")
+	for i := 0; i < len(seq); i++ {
+		if _, ok := complements[seq[i]]; !ok {
+			return nil, fmt.Errorf("invalid nucleotide %q at position %d", seq[i], i+1)
+		}
+	}
+
+	orfs := findStrandORFs(seq, "+", minLength)
+	if bothStrands {
+		rc, err := ReverseComplement(seq)
+		if err != nil {
+			return nil, err
+		}
+		for _, orf := range findStrandORFs(rc, "-", minLength) {
+			// Map coordinates back onto the + strand.
+			orf.Start, orf.End = len(seq)-orf.Start+1, len(seq)-orf.End+1
+			orfs = append(orfs, orf)
+		}
+	}
+	return orfs, nil
+}
+
+func findStrandORFs(seq, strand string, minLength int) []ORF {
+	var orfs []ORF
+	for frame := 0; frame < 3; frame++ {
+		start := -1
+		for i := frame; i+3 <= len(seq); i += 3 {
+			codon := seq[i : i+3]
+			if start < 0 && codon == "ATG" {
+				start = i
+				continue
+			}
+			if start >= 0 && isStopCodon(codon) {
+				length := i + 3 - start
+				if length >= minLength {
+					orfs = append(orfs, ORF{
+						Strand:  strand,
+						Frame:   frame + 1,
+						Start:   start + 1,
+						End:     i + 3,
+						Length:  length,
+						Protein: Translate(seq[start:i]),
+					})
+				}
+				start = -1
+			}
+		}
+	}
+	return orfs
+}
+
+func isStopCodon(codon string) bool {
+	return codon == "TAA" || codon == "TAG" || codon == "TGA"
+}
+
+// codonTable is the standard genetic code.
+var codonTable = func() map[string]byte {
+	const bases = "TCAG"
+	const aminoAcids = "FFLLSSSSYY**CC*WLLLLPPPPHHQQRRRRIIIMTTTTNNKKSSRRVVVVAAAADDEEGGGG"
+	table := make(map[string]byte, 64)
+	n := 0
+	for _, a := range bases {
+		for _, b := range bases {
+			for _, c := range bases {
+				table[string([]rune{a, b, c})] = aminoAcids[n]
+				n++
+			}
+		}
+	}
+	return table
+}()
+
+// Translate translates a DNA coding sequence into one-letter amino acid codes
+// using the standard genetic code. Stop codons become '*', codons containing
+// ambiguity codes become 'X', and a trailing partial codon is ignored.
+func Translate(seq string) string {
+	seq = strings.ReplaceAll(strings.ToUpper(normalizeSequence(seq)), "U", "T")
+	var b strings.Builder
+	for i := 0; i+3 <= len(seq); i += 3 {
+		if aa, ok := codonTable[seq[i:i+3]]; ok {
+			b.WriteByte(aa)
+		} else {
+			b.WriteByte('X')
+		}
+	}
+	return b.String()
+}
+
+// FASTARecord is a single record from a FASTA file.
+type FASTARecord struct {
+	// ID is the first word of the header line, without the leading '>'.
+	ID string `json:"id"`
+	// Description is the rest of the header line after the ID.
+	Description string `json:"description,omitempty"`
+	// Sequence is the record's sequence with line breaks removed.
+	Sequence string `json:"sequence"`
+}
+
+// ParseFASTA parses FASTA-formatted records from r. Blank lines and ';'
+// comment lines are ignored.
+func ParseFASTA(r io.Reader) ([]FASTARecord, error) {
+	var records []FASTARecord
+	var seq strings.Builder
+	var current *FASTARecord
+
+	flush := func() {
+		if current != nil {
+			current.Sequence = seq.String()
+			records = append(records, *current)
+			seq.Reset()
+		}
+	}
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := strings.TrimSpace(scanner.Text())
+		switch {
+		case text == "" || strings.HasPrefix(text, ";"):
+			continue
+		case strings.HasPrefix(text, ">"):
+			flush()
+			header := strings.TrimSpace(text[1:])
+			id, description, _ := strings.Cut(header, " ")
+			current = &FASTARecord{ID: id, Description: strings.TrimSpace(description)}
+		default:
+			if current == nil {
+				return nil, fmt.Errorf("line %d: sequence data before the first '>' header", line)
+			}
+			seq.WriteString(normalizeSequence(text))
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read FASTA: %w", err)
+	}
+	flush()
+	return records, nil
+}
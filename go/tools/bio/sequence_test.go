@@ -0,0 +1,95 @@
+package bio
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestReverseComplement(t *testing.T) {
+	cases := map[string]string{
+		"ATGC":         "GCAT",
+		"atgcN":        "Ngcat",
+		"AUGC":         "GCAU",
+		"ACGT RYKM\n1": "KMRYACGT",
+	}
+	for input, want := range cases {
+		got, err := ReverseComplement(input)
+		if err != nil || got != want {
+			t.Errorf("ReverseComplement(%q) = %q, %v; want %q", input, got, err, want)
+		}
+	}
+	if _, err := ReverseComplement("ATGX"); err == nil || !strings.Contains(err.Error(), "position 4") {
+		t.Errorf("Expected invalid nucleotide error, got %v", err)
+	}
+}
+
+func TestGCContent(t *testing.T) {
+	stats, err := GCContent("GGCCAATTNN--")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if stats.Length != 10 || stats.GC != 4 || stats.Ambiguous != 2 || stats.GCContent != 0.5 {
+		t.Errorf("Unexpected stats %+v", stats)
+	}
+}
+
+func TestFindORFs(t *testing.T) {
+	t.Run("finds ORFs on both strands", func(t *testing.T) {
+		// + strand: ATG AAA TAG at positions 3-11. - strand: the reverse
+		// complement of ATGCCCTGA is appended at the end.
+		seq := "CCATGAAATAGGG" + "TCAGGGCAT"
+		orfs, err := FindORFs(seq, 9, true)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if len(orfs) != 2 {
+			t.Fatalf("Expected 2 ORFs, got %+v", orfs)
+		}
+		if o := orfs[0]; o.Strand != "+" || o.Start != 3 || o.End != 11 || o.Protein != "MK" || o.Frame != 3 {
+			t.Errorf("Unexpected + strand ORF %+v", o)
+		}
+		if o := orfs[1]; o.Strand != "-" || o.Start != 22 || o.End != 14 || o.Protein != "MP" {
+			t.Errorf("Unexpected - strand ORF %+v", o)
+		}
+	})
+
+	t.Run("respects the minimum length", func(t *testing.T) {
+		orfs, _ := FindORFs("ATGAAATAG", 12, false)
+		if len(orfs) != 0 {
+			t.Errorf("Expected no ORFs, got %+v", orfs)
+		}
+	})
+}
+
+func TestTranslate(t *testing.T) {
+	if got := Translate("ATGGCCTAAAN"); got != "MA*" {
+		t.Errorf("Unexpected translation %q", got)
+	}
+	if got := Translate("ATGNNN"); got != "MX" {
+		t.Errorf("Unexpected translation %q", got)
+	}
+}
+
+func TestParseFASTA(t *testing.T) {
+	t.Run("parses multiple records", func(t *testing.T) {
+		records, err := ParseFASTA(strings.NewReader(";comment\n>seq1 first sequence\nATGC\nGG\n\n>seq2\nTTAA\n"))
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if len(records) != 2 {
+			t.Fatalf("Expected 2 records, got %+v", records)
+		}
+		if r := records[0]; r.ID != "seq1" || r.Description != "first sequence" || r.Sequence != "ATGCGG" {
+			t.Errorf("Unexpected record %+v", r)
+		}
+		if r := records[1]; r.ID != "seq2" || r.Sequence != "TTAA" {
+			t.Errorf("Unexpected record %+v", r)
+		}
+	})
+
+	t.Run("rejects sequence data before a header", func(t *testing.T) {
+		if _, err := ParseFASTA(strings.NewReader("ATGC\n>seq\nAT")); err == nil {
+			t.Error("Expected error")
+		}
+	})
+}
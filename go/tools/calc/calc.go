@@ -0,0 +1,82 @@
+// Package calc provides deterministic calculator and date tools that can be
+// registered on a session so the model does not have to do arithmetic or
+// calendar math in prose.
+//
+// Arithmetic is exact: expressions are evaluated with [math/big.Rat], so
+// 0.1 + 0.2 is exactly 0.3. Date math is timezone-aware and uses the IANA
+// timezone database.
+//
+// Example:
+//
+//	session, err := client.CreateSession(&copilot.SessionConfig{
+//	    Tools: calc.Tools(),
+//	})
+package calc
+
+import (
+	"math/big"
+	"time"
+
+	copilot "github.com/github/copilot-sdk/go"
+)
+
+// defaultPrecision is the number of fractional digits in decimal results when
+// the caller does not ask for a specific precision.
+const defaultPrecision = 20
+
+// now returns the current time. It is a variable so tests can pin the clock.
+var now = time.Now
+
+// Tools returns every tool in this package.
+func Tools() []copilot.Tool {
+	return []copilot.Tool{
+		Calculator(),
+		Now(),
+		DateAdd(),
+		DateDiff(),
+		ConvertTime(),
+	}
+}
+
+// CalculateParams are the arguments of the calculate tool.
+type CalculateParams struct {
+	Expression string `json:"expression" jsonschema:"arithmetic expression using numbers, + - * / % ^, parentheses, and abs, min, max, floor, ceil, round(x, places)"`
+	Precision  int    `json:"precision,omitempty" jsonschema:"maximum number of fractional digits in the decimal result (default 20)"`
+}
+
+// CalculateResult is the result of the calculate tool.
+type CalculateResult struct {
+	// Result is the exact value, as an integer or a reduced fraction such as "1/3".
+	Result string `json:"result"`
+	// Decimal is the value as a decimal rounded to the requested precision.
+	Decimal string `json:"decimal"`
+	// Exact reports whether Decimal represents the value exactly.
+	Exact bool `json:"exact"`
+}
+
+// Calculator returns the calculate tool, which evaluates arithmetic expressions
+// exactly. See [Evaluate] for the supported syntax.
+func Calculator() copilot.Tool {
+	return copilot.DefineTool("calculate",
+		"Evaluate an arithmetic expression exactly. Use this instead of doing arithmetic yourself.",
+		func(params CalculateParams, inv copilot.ToolInvocation) (CalculateResult, error) {
+			value, err := Evaluate(params.Expression)
+			if err != nil {
+				return CalculateResult{}, err
+			}
+			return newCalculateResult(value, params.Precision), nil
+		},
+		copilot.WithErrorRenderer(copilot.RenderToolError))
+}
+
+func newCalculateResult(value *big.Rat, precision int) CalculateResult {
+	if precision <= 0 || precision > 1000 {
+		precision = defaultPrecision
+	}
+	decimal, exact := FormatDecimal(value, precision)
+	return CalculateResult{
+		Result:  value.RatString(),
+		Decimal: decimal,
+		Exact:   exact,
+	}
+}
@@ -0,0 +1,104 @@
+package calc
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	copilot "github.com/github/copilot-sdk/go"
+)
+
+func invoke(t *testing.T, tool copilot.Tool, args map[string]interface{}) copilot.ToolResult {
+	t.Helper()
+	result, err := tool.Handler(copilot.ToolInvocation{Arguments: args})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	return result
+}
+
+func decode[T any](t *testing.T, result copilot.ToolResult) T {
+	t.Helper()
+	var v T
+	if err := json.Unmarshal([]byte(result.TextResultForLLM), &v); err != nil {
+		t.Fatalf("Failed to decode %q: %v", result.TextResultForLLM, err)
+	}
+	return v
+}
+
+func TestCalculator(t *testing.T) {
+	t.Run("returns exact and decimal results", func(t *testing.T) {
+		result := decode[CalculateResult](t, invoke(t, Calculator(), map[string]interface{}{"expression": "2/3", "precision": 4}))
+		if result.Result != "2/3" || result.Decimal != "0.6667" || result.Exact {
+			t.Errorf("Unexpected result %+v", result)
+		}
+	})
+
+	t.Run("reports invalid expressions to the model", func(t *testing.T) {
+		result := invoke(t, Calculator(), map[string]interface{}{"expression": "1/0"})
+		if result.ResultType != "failure" || !strings.Contains(result.TextResultForLLM, "division by zero") {
+			t.Errorf("Unexpected result %+v", result)
+		}
+	})
+
+	t.Run("marks optional parameters as optional", func(t *testing.T) {
+		required, _ := Calculator().Parameters["required"].([]interface{})
+		if len(required) != 1 || required[0] != "expression" {
+			t.Errorf("Expected only expression to be required, got %v", required)
+		}
+	})
+}
+
+func TestDateTools(t *testing.T) {
+	t.Run("current_time uses the requested timezone", func(t *testing.T) {
+		defer func(orig func() time.Time) { now = orig }(now)
+		now = func() time.Time { return time.Date(2024, 3, 10, 12, 0, 0, 0, time.UTC) }
+
+		result := decode[TimeResult](t, invoke(t, Now(), map[string]interface{}{"timezone": "Asia/Tokyo"}))
+		if result.Time != "2024-03-10T21:00:00+09:00" || result.Weekday != "Sunday" {
+			t.Errorf("Unexpected result %+v", result)
+		}
+	})
+
+	t.Run("date_add keeps wall-clock time across DST", func(t *testing.T) {
+		result := decode[TimeResult](t, invoke(t, DateAdd(), map[string]interface{}{
+			"date": "2024-03-09 09:00", "timezone": "America/New_York", "days": 1,
+		}))
+		if result.Time != "2024-03-10T09:00:00-04:00" {
+			t.Errorf("Unexpected result %+v", result)
+		}
+	})
+
+	t.Run("date_diff reports calendar and elapsed differences", func(t *testing.T) {
+		result := decode[DateDiffResult](t, invoke(t, DateDiff(), map[string]interface{}{
+			"start": "2023-01-31", "end": "2024-03-01",
+		}))
+		if result.Years != 1 || result.Months != 1 || result.Days != 1 || result.TotalDays != 395 {
+			t.Errorf("Unexpected result %+v", result)
+		}
+
+		result = decode[DateDiffResult](t, invoke(t, DateDiff(), map[string]interface{}{
+			"start": "2024-01-02T00:00:00Z", "end": "2024-01-01T12:00:00Z",
+		}))
+		if result.TotalSeconds != -43200 || result.TotalDays != -1 {
+			t.Errorf("Unexpected negative result %+v", result)
+		}
+	})
+
+	t.Run("convert_time converts between timezones", func(t *testing.T) {
+		result := decode[TimeResult](t, invoke(t, ConvertTime(), map[string]interface{}{
+			"time": "2024-07-01 09:30", "fromTimezone": "Europe/London", "toTimezone": "America/Los_Angeles",
+		}))
+		if result.Time != "2024-07-01T01:30:00-07:00" {
+			t.Errorf("Unexpected result %+v", result)
+		}
+	})
+
+	t.Run("invalid timezones are reported to the model", func(t *testing.T) {
+		result := invoke(t, Now(), map[string]interface{}{"timezone": "Mars/Olympus"})
+		if result.ResultType != "failure" || !strings.Contains(result.TextResultForLLM, "unknown timezone") {
+			t.Errorf("Unexpected result %+v", result)
+		}
+	})
+}
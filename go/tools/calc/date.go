@@ -0,0 +1,230 @@
+package calc
+
+import (
+	"fmt"
+	"time"
+
+	copilot "github.com/github/copilot-sdk/go"
+)
+
+// inputLayouts are the accepted formats for date and time arguments, tried in order.
+var inputLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05",
+	"2006-01-02T15:04",
+	"2006-01-02 15:04",
+	"2006-01-02",
+}
+
+// TimeResult describes a point in time.
+type TimeResult struct {
+	// Time is the time in RFC 3339 format in the requested timezone.
+	Time string `json:"time"`
+	// Timezone is the IANA name of the timezone Time is expressed in.
+	Timezone string `json:"timezone"`
+	// Weekday is the day of the week in that timezone.
+	Weekday string `json:"weekday"`
+	// Unix is the time as seconds since the Unix epoch.
+	Unix int64 `json:"unix"`
+}
+
+func newTimeResult(t time.Time) TimeResult {
+	return TimeResult{
+		Time:     t.Format(time.RFC3339),
+		Timezone: t.Location().String(),
+		Weekday:  t.Weekday().String(),
+		Unix:     t.Unix(),
+	}
+}
+
+// loadLocation loads an IANA timezone, defaulting to UTC when name is empty.
+func loadLocation(name string) (*time.Location, error) {
+	if name == "" {
+		return time.UTC, nil
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, fmt.Errorf("unknown timezone %q: use an IANA name such as \"America/New_York\"", name)
+	}
+	return loc, nil
+}
+
+// parseTime parses value in one of inputLayouts. Values without a UTC offset
+// are interpreted in loc.
+func parseTime(value string, loc *time.Location) (time.Time, error) {
+	for _, layout := range inputLayouts {
+		if t, err := time.ParseInLocation(layout, value, loc); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid time %q: use RFC 3339 (2006-01-02T15:04:05Z07:00) or 2006-01-02", value)
+}
+
+// NowParams are the arguments of the current_time tool.
+type NowParams struct {
+	Timezone string `json:"timezone,omitempty" jsonschema:"IANA timezone name, for example Europe/Paris (default UTC)"`
+}
+
+// Now returns the current_time tool, which reports the current time in a timezone.
+func Now() copilot.Tool {
+	return copilot.DefineTool("current_time",
+		"Get the current date and time in a timezone.",
+		func(params NowParams, inv copilot.ToolInvocation) (TimeResult, error) {
+			loc, err := loadLocation(params.Timezone)
+			if err != nil {
+				return TimeResult{}, err
+			}
+			return newTimeResult(now().In(loc)), nil
+		},
+		copilot.WithErrorRenderer(copilot.RenderToolError))
+}
+
+// DateAddParams are the arguments of the date_add tool.
+type DateAddParams struct {
+	Date     string `json:"date" jsonschema:"start date or time in RFC 3339 or YYYY-MM-DD format"`
+	Timezone string `json:"timezone,omitempty" jsonschema:"IANA timezone used for dates without an offset and for the result (default UTC)"`
+	Years    int    `json:"years,omitempty" jsonschema:"years to add (negative to subtract)"`
+	Months   int    `json:"months,omitempty" jsonschema:"months to add (negative to subtract)"`
+	Days     int    `json:"days,omitempty" jsonschema:"days to add (negative to subtract)"`
+	Hours    int    `json:"hours,omitempty" jsonschema:"hours to add (negative to subtract)"`
+	Minutes  int    `json:"minutes,omitempty" jsonschema:"minutes to add (negative to subtract)"`
+	Seconds  int    `json:"seconds,omitempty" jsonschema:"seconds to add (negative to subtract)"`
+}
+
+// DateAdd returns the date_add tool, which adds a calendar offset to a date.
+//
+// Years, months, and days are added in calendar terms in the given timezone, so
+// adding one day across a daylight saving transition keeps the wall-clock time.
+// As with [time.Time.AddDate], out-of-range results are normalized (October 31
+// plus one month is December 1).
+func DateAdd() copilot.Tool {
+	return copilot.DefineTool("date_add",
+		"Add or subtract years, months, days, hours, minutes, or seconds to a date.",
+		func(params DateAddParams, inv copilot.ToolInvocation) (TimeResult, error) {
+			loc, err := loadLocation(params.Timezone)
+			if err != nil {
+				return TimeResult{}, err
+			}
+			t, err := parseTime(params.Date, loc)
+			if err != nil {
+				return TimeResult{}, err
+			}
+			t = t.In(loc).AddDate(params.Years, params.Months, params.Days)
+			t = t.Add(time.Duration(params.Hours)*time.Hour +
+				time.Duration(params.Minutes)*time.Minute +
+				time.Duration(params.Seconds)*time.Second)
+			return newTimeResult(t), nil
+		},
+		copilot.WithErrorRenderer(copilot.RenderToolError))
+}
+
+// DateDiffParams are the arguments of the date_diff tool.
+type DateDiffParams struct {
+	Start    string `json:"start" jsonschema:"start date or time in RFC 3339 or YYYY-MM-DD format"`
+	End      string `json:"end" jsonschema:"end date or time in RFC 3339 or YYYY-MM-DD format"`
+	Timezone string `json:"timezone,omitempty" jsonschema:"IANA timezone used for dates without an offset and for calendar boundaries (default UTC)"`
+}
+
+// DateDiffResult is the result of the date_diff tool. All values are negative
+// when End is before Start.
+type DateDiffResult struct {
+	// Years, Months, and Days are the calendar difference, for example
+	// 1 year, 2 months, and 3 days.
+	Years  int `json:"years"`
+	Months int `json:"months"`
+	Days   int `json:"days"`
+	// TotalDays is the number of whole calendar days between the dates.
+	TotalDays int `json:"totalDays"`
+	// TotalSeconds is the exact elapsed time in seconds.
+	TotalSeconds int64 `json:"totalSeconds"`
+}
+
+// DateDiff returns the date_diff tool, which computes the difference between two dates.
+func DateDiff() copilot.Tool {
+	return copilot.DefineTool("date_diff",
+		"Compute the calendar and elapsed difference between two dates.",
+		func(params DateDiffParams, inv copilot.ToolInvocation) (DateDiffResult, error) {
+			loc, err := loadLocation(params.Timezone)
+			if err != nil {
+				return DateDiffResult{}, err
+			}
+			start, err := parseTime(params.Start, loc)
+			if err != nil {
+				return DateDiffResult{}, err
+			}
+			end, err := parseTime(params.End, loc)
+			if err != nil {
+				return DateDiffResult{}, err
+			}
+			return dateDiff(start.In(loc), end.In(loc)), nil
+		},
+		copilot.WithErrorRenderer(copilot.RenderToolError))
+}
+
+// dateDiff computes the difference from start to end. The calendar breakdown
+// compares dates only; TotalSeconds is the exact elapsed time.
+func dateDiff(start, end time.Time) DateDiffResult {
+	sign := 1
+	if end.Before(start) {
+		start, end = end, start
+		sign = -1
+	}
+
+	startDay := time.Date(start.Year(), start.Month(), start.Day(), 0, 0, 0, 0, time.UTC)
+	endDay := time.Date(end.Year(), end.Month(), end.Day(), 0, 0, 0, 0, time.UTC)
+
+	totalMonths := (endDay.Year()-startDay.Year())*12 + int(endDay.Month()) - int(startDay.Month())
+	if addMonthsClamped(startDay, totalMonths).After(endDay) {
+		totalMonths--
+	}
+	anchor := addMonthsClamped(startDay, totalMonths)
+
+	return DateDiffResult{
+		Years:        sign * (totalMonths / 12),
+		Months:       sign * (totalMonths % 12),
+		Days:         sign * int(endDay.Sub(anchor).Hours()/24),
+		TotalDays:    sign * int(endDay.Sub(startDay).Hours()/24),
+		TotalSeconds: int64(sign) * int64(end.Sub(start)/time.Second),
+	}
+}
+
+// addMonthsClamped adds months to a date, clamping the day to the end of the
+// resulting month (January 31 plus one month is February 28 or 29).
+func addMonthsClamped(t time.Time, months int) time.Time {
+	firstOfMonth := time.Date(t.Year(), t.Month()+time.Month(months), 1, 0, 0, 0, 0, t.Location())
+	lastDay := firstOfMonth.AddDate(0, 1, -1).Day()
+	return time.Date(firstOfMonth.Year(), firstOfMonth.Month(), min(t.Day(), lastDay), 0, 0, 0, 0, t.Location())
+}
+
+// ConvertTimeParams are the arguments of the convert_time tool.
+type ConvertTimeParams struct {
+	Time         string `json:"time" jsonschema:"time in RFC 3339 or YYYY-MM-DD HH:MM format"`
+	FromTimezone string `json:"fromTimezone,omitempty" jsonschema:"IANA timezone of time when it has no UTC offset (default UTC)"`
+	ToTimezone   string `json:"toTimezone" jsonschema:"IANA timezone to convert to"`
+}
+
+// ConvertTime returns the convert_time tool, which converts a time between timezones.
+func ConvertTime() copilot.Tool {
+	return copilot.DefineTool("convert_time",
+		"Convert a time from one timezone to another.",
+		func(params ConvertTimeParams, inv copilot.ToolInvocation) (TimeResult, error) {
+			from, err := loadLocation(params.FromTimezone)
+			if err != nil {
+				return TimeResult{}, err
+			}
+			if params.ToTimezone == "" {
+				return TimeResult{}, fmt.Errorf("toTimezone is required")
+			}
+			to, err := loadLocation(params.ToTimezone)
+			if err != nil {
+				return TimeResult{}, err
+			}
+			t, err := parseTime(params.Time, from)
+			if err != nil {
+				return TimeResult{}, err
+			}
+			return newTimeResult(t.In(to)), nil
+		},
+		copilot.WithErrorRenderer(copilot.RenderToolError))
+}
@@ -0,0 +1,393 @@
+package calc
+
+import (
+	"fmt"
+	"math/big"
+	"strings"
+	"unicode"
+)
+
+// Limits that keep evaluation of a hostile expression cheap.
+const (
+	maxExpressionLength = 4096
+	maxExponent         = 4096
+	maxNestingDepth     = 64
+	maxResultBits       = 1 << 20
+)
+
+// Evaluate evaluates an arithmetic expression exactly using rational arithmetic.
+//
+// Supported syntax: decimal numbers (with optional exponent, e.g. 1.5e3), the
+// operators + - * / % ^, parentheses, and the functions abs, min, max, floor,
+// ceil, and round. The exponent of ^ must be an integer, and % requires integer
+// operands.
+func Evaluate(expression string) (*big.Rat, error) {
+	if len(expression) > maxExpressionLength {
+		return nil, fmt.Errorf("expression is longer than %d characters", maxExpressionLength)
+	}
+	p := &parser{input: expression}
+	value, err := p.parseExpr()
+	if err != nil {
+		return nil, err
+	}
+	p.skipSpace()
+	if p.pos < len(p.input) {
+		return nil, p.errorf("unexpected %q", p.input[p.pos])
+	}
+	return value, nil
+}
+
+type parser struct {
+	input string
+	pos   int
+	depth int
+}
+
+func (p *parser) errorf(format string, args ...interface{}) error {
+	return fmt.Errorf("invalid expression at position %d: %s", p.pos+1, fmt.Sprintf(format, args...))
+}
+
+func (p *parser) skipSpace() {
+	for p.pos < len(p.input) && unicode.IsSpace(rune(p.input[p.pos])) {
+		p.pos++
+	}
+}
+
+// peek returns the next non-space byte, or 0 at the end of input.
+func (p *parser) peek() byte {
+	p.skipSpace()
+	if p.pos >= len(p.input) {
+		return 0
+	}
+	return p.input[p.pos]
+}
+
+func (p *parser) enter() error {
+	p.depth++
+	if p.depth > maxNestingDepth {
+		return p.errorf("expression is nested more than %d levels deep", maxNestingDepth)
+	}
+	return nil
+}
+
+// expr := term (('+' | '-') term)*
+func (p *parser) parseExpr() (*big.Rat, error) {
+	left, err := p.parseTerm()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		switch p.peek() {
+		case '+':
+			p.pos++
+			right, err := p.parseTerm()
+			if err != nil {
+				return nil, err
+			}
+			left.Add(left, right)
+		case '-':
+			p.pos++
+			right, err := p.parseTerm()
+			if err != nil {
+				return nil, err
+			}
+			left.Sub(left, right)
+		default:
+			return left, nil
+		}
+	}
+}
+
+// term := unary (('*' | '/' | '%') unary)*
+func (p *parser) parseTerm() (*big.Rat, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		op := p.peek()
+		if op != '*' && op != '/' && op != '%' {
+			return left, nil
+		}
+		p.pos++
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		switch op {
+		case '*':
+			left.Mul(left, right)
+		case '/':
+			if right.Sign() == 0 {
+				return nil, fmt.Errorf("division by zero")
+			}
+			left.Quo(left, right)
+		case '%':
+			if !left.IsInt() || !right.IsInt() {
+				return nil, fmt.Errorf("%% requires integer operands")
+			}
+			if right.Sign() == 0 {
+				return nil, fmt.Errorf("division by zero")
+			}
+			left.SetInt(new(big.Int).Rem(left.Num(), right.Num()))
+		}
+	}
+}
+
+// unary := ('+' | '-') unary | power
+func (p *parser) parseUnary() (*big.Rat, error) {
+	switch p.peek() {
+	case '-':
+		p.pos++
+		if err := p.enter(); err != nil {
+			return nil, err
+		}
+		defer func() { p.depth-- }()
+		value, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return value.Neg(value), nil
+	case '+':
+		p.pos++
+		if err := p.enter(); err != nil {
+			return nil, err
+		}
+		defer func() { p.depth-- }()
+		return p.parseUnary()
+	}
+	return p.parsePower()
+}
+
+// power := primary ('^' unary)?
+func (p *parser) parsePower() (*big.Rat, error) {
+	base, err := p.parsePrimary()
+	if err != nil {
+		return nil, err
+	}
+	if p.peek() != '^' {
+		return base, nil
+	}
+	p.pos++
+	if err := p.enter(); err != nil {
+		return nil, err
+	}
+	defer func() { p.depth-- }()
+	exponent, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	return pow(base, exponent)
+}
+
+// primary := number | '(' expr ')' | name '(' expr (',' expr)* ')'
+func (p *parser) parsePrimary() (*big.Rat, error) {
+	c := p.peek()
+	switch {
+	case c == '(':
+		p.pos++
+		if err := p.enter(); err != nil {
+			return nil, err
+		}
+		defer func() { p.depth-- }()
+		value, err := p.parseExpr()
+		if err != nil {
+			return nil, err
+		}
+		if p.peek() != ')' {
+			return nil, p.errorf("expected )")
+		}
+		p.pos++
+		return value, nil
+	case c >= '0' && c <= '9' || c == '.':
+		return p.parseNumber()
+	case unicode.IsLetter(rune(c)):
+		return p.parseCall()
+	case c == 0:
+		return nil, p.errorf("unexpected end of expression")
+	default:
+		return nil, p.errorf("unexpected %q", c)
+	}
+}
+
+func (p *parser) parseNumber() (*big.Rat, error) {
+	start := p.pos
+	for p.pos < len(p.input) && (isDigit(p.input[p.pos]) || p.input[p.pos] == '.') {
+		p.pos++
+	}
+	if p.pos < len(p.input) && (p.input[p.pos] == 'e' || p.input[p.pos] == 'E') {
+		end := p.pos + 1
+		if end < len(p.input) && (p.input[end] == '+' || p.input[end] == '-') {
+			end++
+		}
+		digits := end
+		for end < len(p.input) && isDigit(p.input[end]) {
+			end++
+		}
+		if end > digits {
+			if end-digits > 4 {
+				return nil, p.errorf("exponent too large")
+			}
+			p.pos = end
+		}
+	}
+	literal := p.input[start:p.pos]
+	value, ok := new(big.Rat).SetString(literal)
+	if !ok || strings.Count(literal, ".") > 1 {
+		p.pos = start
+		return nil, p.errorf("invalid number %q", literal)
+	}
+	return value, nil
+}
+
+func (p *parser) parseCall() (*big.Rat, error) {
+	start := p.pos
+	for p.pos < len(p.input) && (unicode.IsLetter(rune(p.input[p.pos])) || isDigit(p.input[p.pos])) {
+		p.pos++
+	}
+	name := strings.ToLower(p.input[start:p.pos])
+	fn, ok := functions[name]
+	if !ok {
+		p.pos = start
+		return nil, p.errorf("unknown function %q", name)
+	}
+	if p.peek() != '(' {
+		return nil, p.errorf("expected ( after %s", name)
+	}
+	p.pos++
+	if err := p.enter(); err != nil {
+		return nil, err
+	}
+	defer func() { p.depth-- }()
+
+	var args []*big.Rat
+	for {
+		arg, err := p.parseExpr()
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, arg)
+		if p.peek() != ',' {
+			break
+		}
+		p.pos++
+	}
+	if p.peek() != ')' {
+		return nil, p.errorf("expected ) to close %s(", name)
+	}
+	p.pos++
+	return fn(name, args)
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+var functions = map[string]func(name string, args []*big.Rat) (*big.Rat, error){
+	"abs": func(name string, args []*big.Rat) (*big.Rat, error) {
+		if len(args) != 1 {
+			return nil, fmt.Errorf("%s takes 1 argument", name)
+		}
+		return args[0].Abs(args[0]), nil
+	},
+	"min": func(name string, args []*big.Rat) (*big.Rat, error) {
+		result := args[0]
+		for _, arg := range args[1:] {
+			if arg.Cmp(result) < 0 {
+				result = arg
+			}
+		}
+		return result, nil
+	},
+	"max": func(name string, args []*big.Rat) (*big.Rat, error) {
+		result := args[0]
+		for _, arg := range args[1:] {
+			if arg.Cmp(result) > 0 {
+				result = arg
+			}
+		}
+		return result, nil
+	},
+	"floor": roundingFunc(func(x *big.Rat) *big.Int { return floor(x) }),
+	"ceil": roundingFunc(func(x *big.Rat) *big.Int {
+		return new(big.Int).Neg(floor(new(big.Rat).Neg(x)))
+	}),
+	"round": func(name string, args []*big.Rat) (*big.Rat, error) {
+		if len(args) < 1 || len(args) > 2 {
+			return nil, fmt.Errorf("%s takes 1 or 2 arguments", name)
+		}
+		places := 0
+		if len(args) == 2 {
+			if !args[1].IsInt() || !args[1].Num().IsInt64() || args[1].Num().Int64() < 0 || args[1].Num().Int64() > 100 {
+				return nil, fmt.Errorf("%s places must be an integer between 0 and 100", name)
+			}
+			places = int(args[1].Num().Int64())
+		}
+		return roundHalfAwayFromZero(args[0], places), nil
+	},
+}
+
+func roundingFunc(f func(*big.Rat) *big.Int) func(string, []*big.Rat) (*big.Rat, error) {
+	return func(name string, args []*big.Rat) (*big.Rat, error) {
+		if len(args) != 1 {
+			return nil, fmt.Errorf("%s takes 1 argument", name)
+		}
+		return new(big.Rat).SetInt(f(args[0])), nil
+	}
+}
+
+// floor returns the largest integer not greater than x.
+func floor(x *big.Rat) *big.Int {
+	// Euclidean division by a positive denominator rounds toward negative infinity.
+	return new(big.Int).Div(x.Num(), x.Denom())
+}
+
+// roundHalfAwayFromZero rounds x to the given number of decimal places.
+func roundHalfAwayFromZero(x *big.Rat, places int) *big.Rat {
+	scale := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(places)), nil)
+	scaled := new(big.Rat).Mul(new(big.Rat).Abs(x), new(big.Rat).SetInt(scale))
+	scaled.Add(scaled, big.NewRat(1, 2))
+	rounded := new(big.Rat).SetFrac(floor(scaled), scale)
+	if x.Sign() < 0 {
+		rounded.Neg(rounded)
+	}
+	return rounded
+}
+
+func pow(base, exponent *big.Rat) (*big.Rat, error) {
+	if !exponent.IsInt() {
+		return nil, fmt.Errorf("exponent must be an integer")
+	}
+	n := exponent.Num()
+	if n.CmpAbs(big.NewInt(maxExponent)) > 0 {
+		return nil, fmt.Errorf("exponent must be between -%d and %d", maxExponent, maxExponent)
+	}
+	e := n.Int64()
+	if e < 0 {
+		if base.Sign() == 0 {
+			return nil, fmt.Errorf("division by zero")
+		}
+		base = new(big.Rat).Inv(base)
+		e = -e
+	}
+	if int64(max(base.Num().BitLen(), base.Denom().BitLen()))*e > maxResultBits {
+		return nil, fmt.Errorf("result is too large")
+	}
+	num := new(big.Int).Exp(base.Num(), big.NewInt(e), nil)
+	den := new(big.Int).Exp(base.Denom(), big.NewInt(e), nil)
+	return new(big.Rat).SetFrac(num, den), nil
+}
+
+// FormatDecimal formats x as a decimal with at most precision fractional digits,
+// trimming trailing zeros. It reports whether the representation is exact.
+func FormatDecimal(x *big.Rat, precision int) (string, bool) {
+	s := x.FloatString(precision)
+	exact := new(big.Rat).Sub(x, roundHalfAwayFromZero(x, precision)).Sign() == 0
+	if strings.Contains(s, ".") {
+		s = strings.TrimRight(strings.TrimRight(s, "0"), ".")
+	}
+	if s == "-0" {
+		s = "0"
+	}
+	return s, exact
+}
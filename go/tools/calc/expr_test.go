@@ -0,0 +1,72 @@
+package calc
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestEvaluate(t *testing.T) {
+	t.Run("evaluates exactly", func(t *testing.T) {
+		cases := map[string]string{
+			"0.1 + 0.2":                           "3/10",
+			"1/3 + 1/6":                           "1/2",
+			"2^10":                                "1024",
+			"2^-2":                                "1/4",
+			"-2^2":                                "-4",
+			"2^3^2":                               "512",
+			"(1 + 2) * 3 - 4/2":                   "7",
+			"17 % 5":                              "2",
+			"1.5e3":                               "1500",
+			"abs(-3.5)":                           "7/2",
+			"min(3, 1, 2)":                        "1",
+			"max(3, 1, 2)":                        "3",
+			"floor(-2.5)":                         "-3",
+			"ceil(2.1)":                           "3",
+			"round(2.345, 2)":                     "47/20",
+			"round(-2.5)":                         "-3",
+			"123456789012345678901234567890 * 10": "1234567890123456789012345678900",
+		}
+		for expression, want := range cases {
+			value, err := Evaluate(expression)
+			if err != nil {
+				t.Errorf("Evaluate(%q) failed: %v", expression, err)
+				continue
+			}
+			if got := value.RatString(); got != want {
+				t.Errorf("Evaluate(%q) = %s, want %s", expression, got, want)
+			}
+		}
+	})
+
+	t.Run("reports errors", func(t *testing.T) {
+		cases := map[string]string{
+			"1 / 0":         "division by zero",
+			"1.5 % 2":       "integer operands",
+			"2 ^ 0.5":       "exponent must be an integer",
+			"(1 + 2":        "expected )",
+			"1 +":           "unexpected end",
+			"sqrt(4)":       "unknown function",
+			"1 2":           "unexpected",
+			"(2^4096)^4096": "too large",
+			"1..2":          "invalid number",
+			strings.Repeat("(", 100) + "1" + strings.Repeat(")", 100): "nested",
+		}
+		for expression, want := range cases {
+			_, err := Evaluate(expression)
+			if err == nil || !strings.Contains(err.Error(), want) {
+				t.Errorf("Evaluate(%q) error = %v, want containing %q", expression, err, want)
+			}
+		}
+	})
+}
+
+func TestFormatDecimal(t *testing.T) {
+	value, _ := Evaluate("1/3")
+	if s, exact := FormatDecimal(value, 5); s != "0.33333" || exact {
+		t.Errorf("Unexpected %q, exact=%v", s, exact)
+	}
+	value, _ = Evaluate("5/2")
+	if s, exact := FormatDecimal(value, 5); s != "2.5" || !exact {
+		t.Errorf("Unexpected %q, exact=%v", s, exact)
+	}
+}
@@ -0,0 +1,348 @@
+package table
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Limits that keep parsing of a hostile filter cheap.
+const (
+	maxFilterLength = 4096
+	maxFilterDepth  = 64
+)
+
+// Filter is a compiled row filter expression. See [ParseFilter].
+type Filter struct {
+	root filterNode
+}
+
+// ParseFilter compiles a filter expression over columns.
+//
+// An expression compares columns and literals with =, !=, <, <=, >, >=, and
+// contains, and combines comparisons with and, or, not, and parentheses, such
+// as:
+//
+//	country = "DE" and (amount >= 100 or status != 'paid')
+//
+// Columns are referred to by name, or in backticks if the name is not a plain
+// identifier, such as `order date`. Strings are quoted with double or single
+// quotes. Two values that both parse as numbers are compared as numbers;
+// others are compared as strings. Empty cells are empty strings, so
+// `note = ""` matches rows without a note.
+func ParseFilter(expression string, columns []string) (*Filter, error) {
+	if len(expression) > maxFilterLength {
+		return nil, fmt.Errorf("filter is longer than %d characters", maxFilterLength)
+	}
+	p := &filterParser{input: expression, columns: make(map[string]int, len(columns)), names: columns}
+	for i, name := range columns {
+		if _, exists := p.columns[name]; !exists {
+			p.columns[name] = i
+		}
+	}
+	root, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	p.skipSpace()
+	if p.pos < len(p.input) {
+		return nil, p.errorf("unexpected %q", p.input[p.pos:])
+	}
+	return &Filter{root: root}, nil
+}
+
+// Match reports whether a row, with cells in the order of the filter's
+// columns, passes the filter. Missing cells are empty.
+func (f *Filter) Match(row []string) bool {
+	return f.root.match(row)
+}
+
+type filterNode interface {
+	match(row []string) bool
+}
+
+type andNode struct{ left, right filterNode }
+
+func (n andNode) match(row []string) bool { return n.left.match(row) && n.right.match(row) }
+
+type orNode struct{ left, right filterNode }
+
+func (n orNode) match(row []string) bool { return n.left.match(row) || n.right.match(row) }
+
+type notNode struct{ operand filterNode }
+
+func (n notNode) match(row []string) bool { return !n.operand.match(row) }
+
+// operand is a column reference or a literal of a comparison.
+type operand struct {
+	column  int // -1 for a literal
+	literal string
+}
+
+func (o operand) value(row []string) string {
+	if o.column < 0 {
+		return o.literal
+	}
+	if o.column < len(row) {
+		return row[o.column]
+	}
+	return ""
+}
+
+type compareNode struct {
+	op          string
+	left, right operand
+}
+
+func (n compareNode) match(row []string) bool {
+	left, right := n.left.value(row), n.right.value(row)
+	if n.op == "contains" {
+		return strings.Contains(left, right)
+	}
+	var order int
+	if l, r, ok := parseNumbers(left, right); ok {
+		switch {
+		case l < r:
+			order = -1
+		case l > r:
+			order = 1
+		}
+	} else {
+		order = strings.Compare(left, right)
+	}
+	switch n.op {
+	case "=":
+		return order == 0
+	case "!=":
+		return order != 0
+	case "<":
+		return order < 0
+	case "<=":
+		return order <= 0
+	case ">":
+		return order > 0
+	default: // ">="
+		return order >= 0
+	}
+}
+
+// parseNumbers parses two cells as numbers, reporting whether both are.
+func parseNumbers(left, right string) (float64, float64, bool) {
+	l, err := strconv.ParseFloat(strings.TrimSpace(left), 64)
+	if err != nil {
+		return 0, 0, false
+	}
+	r, err := strconv.ParseFloat(strings.TrimSpace(right), 64)
+	if err != nil {
+		return 0, 0, false
+	}
+	return l, r, true
+}
+
+type filterParser struct {
+	input   string
+	pos     int
+	depth   int
+	columns map[string]int
+	names   []string
+}
+
+func (p *filterParser) errorf(format string, args ...interface{}) error {
+	return fmt.Errorf("invalid filter at position %d: %s", p.pos+1, fmt.Sprintf(format, args...))
+}
+
+func (p *filterParser) skipSpace() {
+	for p.pos < len(p.input) && unicode.IsSpace(rune(p.input[p.pos])) {
+		p.pos++
+	}
+}
+
+// keyword consumes word, case-insensitively, if it is the next word.
+func (p *filterParser) keyword(word string) bool {
+	p.skipSpace()
+	end := p.pos + len(word)
+	if end > len(p.input) || !strings.EqualFold(p.input[p.pos:end], word) {
+		return false
+	}
+	if end < len(p.input) && isIdentByte(p.input[end]) {
+		return false
+	}
+	p.pos = end
+	return true
+}
+
+// symbol consumes s if it comes next.
+func (p *filterParser) symbol(s string) bool {
+	p.skipSpace()
+	if strings.HasPrefix(p.input[p.pos:], s) {
+		p.pos += len(s)
+		return true
+	}
+	return false
+}
+
+func (p *filterParser) parseOr() (filterNode, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.keyword("or") || p.symbol("||") {
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = orNode{left, right}
+	}
+	return left, nil
+}
+
+func (p *filterParser) parseAnd() (filterNode, error) {
+	left, err := p.parseNot()
+	if err != nil {
+		return nil, err
+	}
+	for p.keyword("and") || p.symbol("&&") {
+		right, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		left = andNode{left, right}
+	}
+	return left, nil
+}
+
+func (p *filterParser) parseNot() (filterNode, error) {
+	p.depth++
+	defer func() { p.depth-- }()
+	if p.depth > maxFilterDepth {
+		return nil, p.errorf("filter is nested more than %d levels deep", maxFilterDepth)
+	}
+
+	if p.keyword("not") || (!strings.HasPrefix(p.input[p.pos:], "!=") && p.symbol("!")) {
+		operand, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		return notNode{operand}, nil
+	}
+	if p.symbol("(") {
+		inner, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if !p.symbol(")") {
+			return nil, p.errorf("missing )")
+		}
+		return inner, nil
+	}
+	return p.parseComparison()
+}
+
+func (p *filterParser) parseComparison() (filterNode, error) {
+	left, err := p.parseOperand()
+	if err != nil {
+		return nil, err
+	}
+	op, ok := p.parseOperator()
+	if !ok {
+		return nil, p.errorf("expected a comparison operator (=, !=, <, <=, >, >=, contains)")
+	}
+	right, err := p.parseOperand()
+	if err != nil {
+		return nil, err
+	}
+	return compareNode{op: op, left: left, right: right}, nil
+}
+
+func (p *filterParser) parseOperator() (string, bool) {
+	for _, op := range []string{"==", "!=", "<>", "<=", ">=", "=", "<", ">"} {
+		if p.symbol(op) {
+			switch op {
+			case "==":
+				return "=", true
+			case "<>":
+				return "!=", true
+			}
+			return op, true
+		}
+	}
+	if p.keyword("contains") {
+		return "contains", true
+	}
+	return "", false
+}
+
+func (p *filterParser) parseOperand() (operand, error) {
+	p.skipSpace()
+	if p.pos >= len(p.input) {
+		return operand{}, p.errorf("unexpected end of filter")
+	}
+	start := p.pos
+	switch c := p.input[p.pos]; {
+	case c == '"' || c == '\'':
+		text, err := p.parseQuoted(c)
+		if err != nil {
+			return operand{}, err
+		}
+		return operand{column: -1, literal: text}, nil
+	case c == '`':
+		name, err := p.parseQuoted('`')
+		if err != nil {
+			return operand{}, err
+		}
+		return p.columnOperand(name, start)
+	case c == '-' || c == '+' || c == '.' || (c >= '0' && c <= '9'):
+		p.pos++
+		for p.pos < len(p.input) && strings.IndexByte("0123456789.eE+-", p.input[p.pos]) >= 0 {
+			p.pos++
+		}
+		text := p.input[start:p.pos]
+		if _, err := strconv.ParseFloat(text, 64); err != nil {
+			p.pos = start
+			return operand{}, p.errorf("invalid number %q", text)
+		}
+		return operand{column: -1, literal: text}, nil
+	case isIdentByte(c):
+		for p.pos < len(p.input) && isIdentByte(p.input[p.pos]) {
+			p.pos++
+		}
+		return p.columnOperand(p.input[start:p.pos], start)
+	}
+	return operand{}, p.errorf("unexpected %q", p.input[p.pos])
+}
+
+func (p *filterParser) columnOperand(name string, start int) (operand, error) {
+	index, ok := p.columns[name]
+	if !ok {
+		p.pos = start
+		return operand{}, p.errorf("unknown column %q; columns are %s", name, strings.Join(p.names, ", "))
+	}
+	return operand{column: index}, nil
+}
+
+// parseQuoted parses text between quote characters, with backslash escapes.
+func (p *filterParser) parseQuoted(quote byte) (string, error) {
+	start := p.pos
+	p.pos++
+	var b strings.Builder
+	for p.pos < len(p.input) {
+		c := p.input[p.pos]
+		p.pos++
+		switch {
+		case c == quote:
+			return b.String(), nil
+		case c == '\\' && p.pos < len(p.input):
+			b.WriteByte(p.input[p.pos])
+			p.pos++
+		default:
+			b.WriteByte(c)
+		}
+	}
+	p.pos = start
+	return "", p.errorf("unterminated %c", quote)
+}
+
+func isIdentByte(c byte) bool {
+	return c == '_' || c == '.' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9') || c >= 0x80
+}
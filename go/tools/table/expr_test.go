@@ -0,0 +1,75 @@
+package table
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseFilter(t *testing.T) {
+	columns := []string{"country", "amount", "status", "order date"}
+	row := []string{"DE", "120", "open", "2024-05-01"}
+
+	t.Run("matches rows", func(t *testing.T) {
+		cases := map[string]bool{
+			`country = "DE"`:                     true,
+			`country == 'FR'`:                    false,
+			`amount > 100`:                       true,
+			`amount >= 120 and amount <= 120`:    true,
+			`amount < 20`:                        false,
+			`amount > 99.5e0`:                    true,
+			`status != 'paid'`:                   true,
+			`status <> "open"`:                   false,
+			`country = "FR" or amount > 100`:     true,
+			`not (country = "DE")`:               false,
+			`!(amount < 100) && status = "open"`: true,
+			`country = "DE" and (amount < 100 || status contains "pe")`: true,
+			"`order date` >= '2024-01-01'":                              true,
+			`amount = "120.0"`:                                          true,
+			`status > 100`:                                              true,
+		}
+		for expression, want := range cases {
+			filter, err := ParseFilter(expression, columns)
+			if err != nil {
+				t.Errorf("ParseFilter(%q) failed: %v", expression, err)
+				continue
+			}
+			if got := filter.Match(row); got != want {
+				t.Errorf("%q matched %v, want %v", expression, got, want)
+			}
+		}
+	})
+
+	t.Run("keywords are case-insensitive", func(t *testing.T) {
+		filter, err := ParseFilter(`amount = 1 OR NOT country = "FR"`, columns)
+		if err != nil || !filter.Match(row) {
+			t.Errorf("Expected a match, got %v", err)
+		}
+	})
+
+	t.Run("treats missing cells as empty", func(t *testing.T) {
+		filter, _ := ParseFilter(`status = ""`, columns)
+		if !filter.Match([]string{"DE", "1"}) {
+			t.Error("Expected a short row to match an empty status")
+		}
+	})
+
+	t.Run("reports invalid filters", func(t *testing.T) {
+		cases := map[string]string{
+			`amount >`:          "unexpected end",
+			`amount 100`:        "comparison operator",
+			`(amount > 1`:       "missing )",
+			`AMOUNT > 1`:        `unknown column "AMOUNT"`,
+			`price > 1`:         `unknown column "price"`,
+			`status = "open`:    "unterminated",
+			`amount > 1 status`: "unexpected",
+			`amount > 1-2`:      "invalid number",
+			strings.Repeat("(", 100) + "amount > 1" + strings.Repeat(")", 100): "nested",
+			strings.Repeat("a", maxFilterLength+1):                             "longer than",
+		}
+		for expression, want := range cases {
+			if _, err := ParseFilter(expression, columns); err == nil || !strings.Contains(err.Error(), want) {
+				t.Errorf("ParseFilter(%.20q) = %v, want an error containing %q", expression, err, want)
+			}
+		}
+	})
+}
@@ -0,0 +1,158 @@
+package table
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// RowReader reads the rows of a table.
+type RowReader interface {
+	// Columns returns the names of the table's columns.
+	Columns() []string
+	// Next returns the cells of the next row, in the order of Columns, or
+	// io.EOF after the last row. The row may be reused by the next call.
+	Next() ([]string, error)
+}
+
+// ReaderFunc opens a [RowReader] over the contents of a file.
+type ReaderFunc func(r io.Reader) (RowReader, error)
+
+// CSVReader is the [ReaderFunc] for comma-separated files with a header row.
+func CSVReader(r io.Reader) (RowReader, error) {
+	return newDelimitedReader(r, ',')
+}
+
+// TSVReader is the [ReaderFunc] for tab-separated files with a header row.
+func TSVReader(r io.Reader) (RowReader, error) {
+	return newDelimitedReader(r, '\t')
+}
+
+type delimitedReader struct {
+	r       *csv.Reader
+	columns []string
+}
+
+func newDelimitedReader(r io.Reader, comma rune) (RowReader, error) {
+	reader := csv.NewReader(r)
+	reader.Comma = comma
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+	reader.ReuseRecord = true
+	header, err := reader.Read()
+	if errors.Is(err, io.EOF) {
+		return nil, errors.New("file is empty")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read header: %w", err)
+	}
+	columns := make([]string, len(header))
+	seen := make(map[string]bool, len(header))
+	for i, name := range header {
+		name = strings.TrimSpace(strings.TrimPrefix(name, "\ufeff"))
+		if name == "" || seen[name] {
+			name = "column_" + strconv.Itoa(i+1)
+		}
+		seen[name] = true
+		columns[i] = name
+	}
+	return &delimitedReader{r: reader, columns: columns}, nil
+}
+
+func (d *delimitedReader) Columns() []string {
+	return d.columns
+}
+
+func (d *delimitedReader) Next() ([]string, error) {
+	row, err := d.r.Read()
+	if err != nil && !errors.Is(err, io.EOF) {
+		line, _ := d.r.FieldPos(0)
+		return nil, fmt.Errorf("failed to read row at line %d: %w", line, err)
+	}
+	return row, err
+}
+
+// table is an open table file.
+type table struct {
+	RowReader
+	file *os.File
+}
+
+func (t *table) Close() error {
+	return t.file.Close()
+}
+
+// open opens a table file under the root, checking it against the size cap
+// and picking the reader for its extension.
+func (c *Config) open(path string) (*table, error) {
+	resolved, err := c.resolve(path)
+	if err != nil {
+		return nil, err
+	}
+	ext := strings.ToLower(filepath.Ext(resolved))
+	readerFunc, ok := c.readers()[ext]
+	if !ok {
+		return nil, fmt.Errorf("unsupported file type %q; supported types are %s", ext, strings.Join(c.extensions(), ", "))
+	}
+	file, err := os.Open(resolved)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s: %w", path, err)
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, fmt.Errorf("failed to open %s: %w", path, err)
+	}
+	if info.IsDir() {
+		file.Close()
+		return nil, fmt.Errorf("%s is a directory", path)
+	}
+	if max := c.maxBytes(); info.Size() > max {
+		file.Close()
+		return nil, fmt.Errorf("%s is %d bytes, more than the limit of %d", path, info.Size(), max)
+	}
+	reader, err := readerFunc(file)
+	if err != nil {
+		file.Close()
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	return &table{RowReader: reader, file: file}, nil
+}
+
+// resolve returns the real path of path, which must be under the root.
+func (c *Config) resolve(path string) (string, error) {
+	if path == "" {
+		return "", errors.New("path is required")
+	}
+	root := c.Root
+	if root == "" {
+		root = "."
+	}
+	root, err := filepath.Abs(root)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve root: %w", err)
+	}
+	if root, err = filepath.EvalSymlinks(root); err != nil {
+		return "", fmt.Errorf("failed to resolve root: %w", err)
+	}
+	full := path
+	if !filepath.IsAbs(full) {
+		full = filepath.Join(root, full)
+	}
+	resolved, err := filepath.EvalSymlinks(full)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return "", fmt.Errorf("file not found: %s", path)
+		}
+		return "", fmt.Errorf("failed to resolve %s: %w", path, err)
+	}
+	if rel, err := filepath.Rel(root, resolved); err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("%s is outside the allowed directory", path)
+	}
+	return resolved, nil
+}
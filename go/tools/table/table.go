@@ -0,0 +1,716 @@
+// Package table provides tools for inspecting tabular data files: schema
+// preview, head and tail, row filters, and group-by aggregations. Every tool
+// reads files only under a configured root and caps the rows it scans,
+// the rows it returns, and the groups it keeps in memory, so that agents
+// can explore data without running arbitrary code over a shell.
+//
+// CSV and TSV files are supported out of the box. Other formats, such as
+// Parquet, are added by registering a [ReaderFunc] for their extension in
+// [Config.Readers].
+//
+// Filters are written in a small expression language; see [ParseFilter].
+//
+// Example:
+//
+//	session, err := client.CreateSession(&copilot.SessionConfig{
+//	    Tools: table.Tools(table.Config{Root: "./data"}),
+//	})
+package table
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+
+	copilot "github.com/github/copilot-sdk/go"
+)
+
+// Defaults for the limits of a [Config].
+const (
+	DefaultMaxRows     = 100
+	DefaultMaxScanRows = 1_000_000
+	DefaultMaxBytes    = 256 << 20
+	DefaultMaxGroups   = 10_000
+)
+
+// defaultPreviewRows is how many rows table_preview returns by default.
+const defaultPreviewRows = 10
+
+// maxExamples is how many example values table_schema reports per column,
+// and maxExampleLength the length they are cut to.
+const (
+	maxExamples      = 3
+	maxExampleLength = 64
+)
+
+// Config configures the tools of this package.
+type Config struct {
+	// Root is the directory the tools may read files from; paths are
+	// resolved against it, and paths that resolve outside it, including
+	// through symlinks, are rejected. Defaults to the working directory.
+	Root string
+	// MaxRows caps the rows a tool returns. Defaults to [DefaultMaxRows].
+	MaxRows int
+	// MaxScanRows caps the rows a tool reads from a file; results over larger
+	// files are marked truncated. Defaults to [DefaultMaxScanRows].
+	MaxScanRows int
+	// MaxBytes is the largest file the tools read. Defaults to
+	// [DefaultMaxBytes].
+	MaxBytes int64
+	// MaxGroups caps the groups table_aggregate keeps in memory; aggregations
+	// with more groups fail. Defaults to [DefaultMaxGroups].
+	MaxGroups int
+	// Readers adds or replaces readers by lowercase file extension, such as
+	// ".parquet". ".csv" and ".tsv" are supported by default.
+	Readers map[string]ReaderFunc
+}
+
+func (c *Config) maxRows() int {
+	if c.MaxRows > 0 {
+		return c.MaxRows
+	}
+	return DefaultMaxRows
+}
+
+func (c *Config) maxScanRows() int {
+	if c.MaxScanRows > 0 {
+		return c.MaxScanRows
+	}
+	return DefaultMaxScanRows
+}
+
+func (c *Config) maxBytes() int64 {
+	if c.MaxBytes > 0 {
+		return c.MaxBytes
+	}
+	return DefaultMaxBytes
+}
+
+func (c *Config) maxGroups() int {
+	if c.MaxGroups > 0 {
+		return c.MaxGroups
+	}
+	return DefaultMaxGroups
+}
+
+func (c *Config) readers() map[string]ReaderFunc {
+	readers := map[string]ReaderFunc{".csv": CSVReader, ".tsv": TSVReader}
+	for ext, reader := range c.Readers {
+		readers[strings.ToLower(ext)] = reader
+	}
+	return readers
+}
+
+func (c *Config) extensions() []string {
+	var extensions []string
+	for ext := range c.readers() {
+		extensions = append(extensions, ext)
+	}
+	sort.Strings(extensions)
+	return extensions
+}
+
+// limit returns the rows a tool returns for a requested count.
+func (c *Config) limit(requested, fallback int) int {
+	if requested <= 0 {
+		requested = fallback
+	}
+	return min(requested, c.maxRows())
+}
+
+// scan calls fn with each row of t until fn returns false or the scan cap is
+// reached, and reports the rows scanned and whether rows were left unread
+// because of the cap.
+func (c *Config) scan(t *table, fn func(row []string) (bool, error)) (scanned int, truncated bool, err error) {
+	maxScanRows := c.maxScanRows()
+	for {
+		row, err := t.Next()
+		if errors.Is(err, io.EOF) {
+			return scanned, false, nil
+		}
+		if err != nil {
+			return scanned, false, err
+		}
+		if scanned == maxScanRows {
+			return scanned, true, nil
+		}
+		scanned++
+		more, err := fn(row)
+		if err != nil || !more {
+			return scanned, false, err
+		}
+	}
+}
+
+// Tools returns every tool in this package.
+func Tools(cfg Config) []copilot.Tool {
+	return []copilot.Tool{
+		SchemaTool(cfg),
+		PreviewTool(cfg),
+		FilterTool(cfg),
+		AggregateTool(cfg),
+	}
+}
+
+// PathParams are the arguments of tools that take only a file.
+type PathParams struct {
+	Path string `json:"path" jsonschema:"path of a CSV or TSV file, relative to the data directory"`
+}
+
+// ColumnSchema describes a column of a table.
+type ColumnSchema struct {
+	Name string `json:"name"`
+	// Type is the narrowest type all non-empty values of the column parse as:
+	// integer, number, boolean, or string.
+	Type     string   `json:"type"`
+	Empty    int      `json:"empty"`
+	Examples []string `json:"examples"`
+}
+
+// SchemaResult is the result of the table_schema tool.
+type SchemaResult struct {
+	Columns   []ColumnSchema `json:"columns"`
+	Rows      int            `json:"rows"`
+	Truncated bool           `json:"truncated,omitempty"`
+}
+
+// SchemaTool returns the table_schema tool.
+func SchemaTool(cfg Config) copilot.Tool {
+	return copilot.DefineTool("table_schema",
+		"Describe the columns of a tabular data file: inferred types, empty-value counts, example values, and the row count.",
+		func(params PathParams, inv copilot.ToolInvocation) (SchemaResult, error) {
+			t, err := cfg.open(params.Path)
+			if err != nil {
+				return SchemaResult{}, err
+			}
+			defer t.Close()
+
+			columns := t.Columns()
+			schema := make([]ColumnSchema, len(columns))
+			types := make([]valueType, len(columns))
+			for i, name := range columns {
+				schema[i] = ColumnSchema{Name: name, Examples: []string{}}
+			}
+			rows, truncated, err := cfg.scan(t, func(row []string) (bool, error) {
+				for i := range columns {
+					value := cell(row, i)
+					if strings.TrimSpace(value) == "" {
+						schema[i].Empty++
+						continue
+					}
+					types[i] = types[i].merge(inferType(value))
+					if len(schema[i].Examples) < maxExamples && !contains(schema[i].Examples, value) {
+						schema[i].Examples = append(schema[i].Examples, truncate(value, maxExampleLength))
+					}
+				}
+				return true, nil
+			})
+			if err != nil {
+				return SchemaResult{}, err
+			}
+			for i := range schema {
+				schema[i].Type = types[i].String()
+			}
+			return SchemaResult{Columns: schema, Rows: rows, Truncated: truncated}, nil
+		},
+		copilot.WithErrorRenderer(copilot.RenderToolError))
+}
+
+// PreviewParams are the arguments of the table_preview tool.
+type PreviewParams struct {
+	Path string `json:"path" jsonschema:"path of a CSV or TSV file, relative to the data directory"`
+	Rows int    `json:"rows,omitempty" jsonschema:"number of rows to return (default 10)"`
+	Tail bool   `json:"tail,omitempty" jsonschema:"return the last rows instead of the first"`
+}
+
+// PreviewResult is the result of the table_preview tool.
+type PreviewResult struct {
+	Columns []string   `json:"columns"`
+	Rows    [][]string `json:"rows"`
+	Scanned int        `json:"scanned"`
+	// Truncated reports that the file has more rows than were scanned; for a
+	// tail, the rows are the last of those scanned.
+	Truncated bool `json:"truncated,omitempty"`
+}
+
+// PreviewTool returns the table_preview tool.
+func PreviewTool(cfg Config) copilot.Tool {
+	return copilot.DefineTool("table_preview",
+		"Return the first or last rows of a tabular data file.",
+		func(params PreviewParams, inv copilot.ToolInvocation) (PreviewResult, error) {
+			t, err := cfg.open(params.Path)
+			if err != nil {
+				return PreviewResult{}, err
+			}
+			defer t.Close()
+
+			n := cfg.limit(params.Rows, defaultPreviewRows)
+			rows := make([][]string, 0, n)
+			// For a tail, rows is a ring buffer of the last n rows, whose
+			// oldest row is at next once it is full.
+			next := 0
+			scanned, truncated, err := cfg.scan(t, func(row []string) (bool, error) {
+				row = padRow(row, len(t.Columns()))
+				switch {
+				case len(rows) < n:
+					rows = append(rows, row)
+				case params.Tail:
+					rows[next] = row
+					next = (next + 1) % n
+				}
+				return params.Tail || len(rows) < n, nil
+			})
+			if err != nil {
+				return PreviewResult{}, err
+			}
+			rows = append(rows[next:], rows[:next]...)
+			return PreviewResult{Columns: t.Columns(), Rows: rows, Scanned: scanned, Truncated: truncated}, nil
+		},
+		copilot.WithErrorRenderer(copilot.RenderToolError))
+}
+
+// FilterParams are the arguments of the table_filter tool.
+type FilterParams struct {
+	Path    string   `json:"path" jsonschema:"path of a CSV or TSV file, relative to the data directory"`
+	Where   string   `json:"where" jsonschema:"filter expression, such as: country = \"DE\" and (amount >= 100 or status != 'paid'); quote column names with spaces in backticks"`
+	Columns []string `json:"columns,omitempty" jsonschema:"columns to return (default all)"`
+	Limit   int      `json:"limit,omitempty" jsonschema:"maximum number of rows to return"`
+}
+
+// FilterResult is the result of the table_filter tool.
+type FilterResult struct {
+	Columns []string   `json:"columns"`
+	Rows    [][]string `json:"rows"`
+	// Matched is how many scanned rows matched the filter, including those
+	// beyond the returned rows.
+	Matched   int  `json:"matched"`
+	Scanned   int  `json:"scanned"`
+	Truncated bool `json:"truncated,omitempty"`
+}
+
+// FilterTool returns the table_filter tool.
+func FilterTool(cfg Config) copilot.Tool {
+	return copilot.DefineTool("table_filter",
+		"Return the rows of a tabular data file that match a filter expression, and how many rows match.",
+		func(params FilterParams, inv copilot.ToolInvocation) (FilterResult, error) {
+			t, err := cfg.open(params.Path)
+			if err != nil {
+				return FilterResult{}, err
+			}
+			defer t.Close()
+
+			filter, err := ParseFilter(params.Where, t.Columns())
+			if err != nil {
+				return FilterResult{}, err
+			}
+			columns := t.Columns()
+			indexes, err := columnIndexes(columns, params.Columns)
+			if err != nil {
+				return FilterResult{}, err
+			}
+			if indexes != nil {
+				columns = params.Columns
+			}
+
+			n := cfg.limit(params.Limit, cfg.maxRows())
+			result := FilterResult{Columns: columns, Rows: [][]string{}}
+			result.Scanned, result.Truncated, err = cfg.scan(t, func(row []string) (bool, error) {
+				if !filter.Match(row) {
+					return true, nil
+				}
+				result.Matched++
+				if len(result.Rows) < n {
+					result.Rows = append(result.Rows, project(row, indexes, len(columns)))
+				}
+				return true, nil
+			})
+			if err != nil {
+				return FilterResult{}, err
+			}
+			return result, nil
+		},
+		copilot.WithErrorRenderer(copilot.RenderToolError))
+}
+
+// Aggregate is an aggregation of the table_aggregate tool.
+type Aggregate struct {
+	Function string `json:"function" jsonschema:"one of count, sum, mean, min, max"`
+	Column   string `json:"column,omitempty" jsonschema:"column to aggregate; required except for count, which counts non-empty values of the column if set, and rows otherwise"`
+}
+
+// name returns the result column name of the aggregation, such as sum(amount).
+func (a Aggregate) name() string {
+	if a.Column == "" {
+		return a.Function
+	}
+	return a.Function + "(" + a.Column + ")"
+}
+
+// AggregateParams are the arguments of the table_aggregate tool.
+type AggregateParams struct {
+	Path       string      `json:"path" jsonschema:"path of a CSV or TSV file, relative to the data directory"`
+	GroupBy    []string    `json:"groupBy,omitempty" jsonschema:"columns to group rows by (default a single group of all rows)"`
+	Aggregates []Aggregate `json:"aggregates" jsonschema:"aggregations to compute per group"`
+	Where      string      `json:"where,omitempty" jsonschema:"filter expression selecting the rows to aggregate, as for table_filter"`
+	OrderBy    string      `json:"orderBy,omitempty" jsonschema:"result column to sort groups by, such as sum(amount) (default the group columns)"`
+	Descending bool        `json:"descending,omitempty" jsonschema:"sort groups in descending order"`
+	Limit      int         `json:"limit,omitempty" jsonschema:"maximum number of groups to return"`
+}
+
+// AggregateResult is the result of the table_aggregate tool. Each row holds
+// the group's values of the GroupBy columns followed by its aggregates; an
+// aggregate over no values is null.
+type AggregateResult struct {
+	Columns   []string        `json:"columns"`
+	Rows      [][]interface{} `json:"rows"`
+	Groups    int             `json:"groups"`
+	Scanned   int             `json:"scanned"`
+	Truncated bool            `json:"truncated,omitempty"`
+}
+
+// AggregateTool returns the table_aggregate tool.
+func AggregateTool(cfg Config) copilot.Tool {
+	return copilot.DefineTool("table_aggregate",
+		"Group the rows of a tabular data file and compute count, sum, mean, min, or max per group.",
+		func(params AggregateParams, inv copilot.ToolInvocation) (AggregateResult, error) {
+			if len(params.Aggregates) == 0 {
+				return AggregateResult{}, errors.New("at least one aggregate is required")
+			}
+			t, err := cfg.open(params.Path)
+			if err != nil {
+				return AggregateResult{}, err
+			}
+			defer t.Close()
+
+			var filter *Filter
+			if params.Where != "" {
+				if filter, err = ParseFilter(params.Where, t.Columns()); err != nil {
+					return AggregateResult{}, err
+				}
+			}
+			groupIndexes, err := columnIndexes(t.Columns(), params.GroupBy)
+			if err != nil {
+				return AggregateResult{}, err
+			}
+			aggregators, err := newAggregators(t.Columns(), params.Aggregates)
+			if err != nil {
+				return AggregateResult{}, err
+			}
+			columns := append([]string{}, params.GroupBy...)
+			for _, aggregate := range params.Aggregates {
+				columns = append(columns, aggregate.name())
+			}
+			orderBy := -1
+			if params.OrderBy != "" {
+				if orderBy = indexOf(columns, params.OrderBy); orderBy < 0 {
+					return AggregateResult{}, fmt.Errorf("unknown orderBy column %q; result columns are %s", params.OrderBy, strings.Join(columns, ", "))
+				}
+			}
+
+			groups := make(map[string]*group)
+			maxGroups := cfg.maxGroups()
+			result := AggregateResult{Columns: columns, Rows: [][]interface{}{}}
+			result.Scanned, result.Truncated, err = cfg.scan(t, func(row []string) (bool, error) {
+				if filter != nil && !filter.Match(row) {
+					return true, nil
+				}
+				key := project(row, groupIndexes, len(groupIndexes))
+				id := strings.Join(key, "\x00")
+				g, ok := groups[id]
+				if !ok {
+					if len(groups) == maxGroups {
+						return false, fmt.Errorf("more than %d groups; group by fewer columns or filter the rows", maxGroups)
+					}
+					g = &group{key: append([]string(nil), key...), states: make([]aggregateState, len(aggregators))}
+					groups[id] = g
+				}
+				for i, aggregator := range aggregators {
+					if err := aggregator.add(&g.states[i], row, result.Scanned+1); err != nil {
+						return false, err
+					}
+				}
+				return true, nil
+			})
+			if err != nil {
+				return AggregateResult{}, err
+			}
+
+			for _, g := range groups {
+				values := make([]interface{}, 0, len(columns))
+				for _, value := range g.key {
+					values = append(values, value)
+				}
+				for i, aggregator := range aggregators {
+					values = append(values, aggregator.value(&g.states[i]))
+				}
+				result.Rows = append(result.Rows, values)
+			}
+			result.Groups = len(result.Rows)
+			sortRows(result.Rows, orderBy, len(params.GroupBy), params.Descending)
+			if n := cfg.limit(params.Limit, cfg.maxRows()); len(result.Rows) > n {
+				result.Rows = result.Rows[:n]
+			}
+			return result, nil
+		},
+		copilot.WithErrorRenderer(copilot.RenderToolError))
+}
+
+type group struct {
+	key    []string
+	states []aggregateState
+}
+
+type aggregateState struct {
+	count    int
+	sum      float64
+	min, max float64
+}
+
+type aggregator struct {
+	function string
+	// column is the index of the aggregated column, or -1 for count of rows.
+	column int
+	name   string
+}
+
+func newAggregators(columns []string, aggregates []Aggregate) ([]aggregator, error) {
+	aggregators := make([]aggregator, len(aggregates))
+	for i, aggregate := range aggregates {
+		switch aggregate.Function {
+		case "count", "sum", "mean", "min", "max":
+		default:
+			return nil, fmt.Errorf("unknown aggregate function %q; functions are count, sum, mean, min, max", aggregate.Function)
+		}
+		column := -1
+		if aggregate.Column != "" {
+			if column = indexOf(columns, aggregate.Column); column < 0 {
+				return nil, fmt.Errorf("unknown column %q; columns are %s", aggregate.Column, strings.Join(columns, ", "))
+			}
+		} else if aggregate.Function != "count" {
+			return nil, fmt.Errorf("%s requires a column", aggregate.Function)
+		}
+		aggregators[i] = aggregator{function: aggregate.Function, column: column, name: aggregate.Column}
+	}
+	return aggregators, nil
+}
+
+// add adds the row numbered line to an aggregate state.
+func (a aggregator) add(state *aggregateState, row []string, line int) error {
+	if a.column < 0 {
+		state.count++
+		return nil
+	}
+	value := strings.TrimSpace(cell(row, a.column))
+	if value == "" {
+		return nil
+	}
+	if a.function == "count" {
+		state.count++
+		return nil
+	}
+	number, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return fmt.Errorf("column %s has the non-numeric value %q in row %d", a.name, truncate(value, maxExampleLength), line)
+	}
+	if state.count == 0 || number < state.min {
+		state.min = number
+	}
+	if state.count == 0 || number > state.max {
+		state.max = number
+	}
+	state.count++
+	state.sum += number
+	return nil
+}
+
+func (a aggregator) value(state *aggregateState) interface{} {
+	if a.function == "count" {
+		return state.count
+	}
+	if state.count == 0 {
+		return nil
+	}
+	switch a.function {
+	case "sum":
+		return state.sum
+	case "mean":
+		return state.sum / float64(state.count)
+	case "min":
+		return state.min
+	default: // "max"
+		return state.max
+	}
+}
+
+// sortRows sorts aggregate rows by the column at orderBy, or by the first
+// keys group columns if orderBy is -1.
+func sortRows(rows [][]interface{}, orderBy, keys int, descending bool) {
+	sort.SliceStable(rows, func(i, j int) bool {
+		order := 0
+		if orderBy >= 0 {
+			order = compareValues(rows[i][orderBy], rows[j][orderBy])
+		}
+		for k := 0; order == 0 && k < keys; k++ {
+			order = compareValues(rows[i][k], rows[j][k])
+		}
+		if descending {
+			return order > 0
+		}
+		return order < 0
+	})
+}
+
+// compareValues orders aggregate values: nulls first, then numbers, then
+// strings, which compare as numbers if both parse as numbers.
+func compareValues(a, b interface{}) int {
+	rank := func(v interface{}) int {
+		switch v.(type) {
+		case nil:
+			return 0
+		case string:
+			return 2
+		}
+		return 1
+	}
+	if ra, rb := rank(a), rank(b); ra != rb {
+		return ra - rb
+	}
+	switch a := a.(type) {
+	case string:
+		b := b.(string)
+		if x, y, ok := parseNumbers(a, b); ok {
+			return compareFloats(x, y)
+		}
+		return strings.Compare(a, b)
+	case int:
+		return compareFloats(float64(a), float64(b.(int)))
+	case float64:
+		return compareFloats(a, b.(float64))
+	}
+	return 0
+}
+
+func compareFloats(a, b float64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+// valueType is an inferred column type, ordered from narrowest to widest.
+type valueType int
+
+const (
+	typeUnknown valueType = iota
+	typeInteger
+	typeNumber
+	typeBoolean
+	typeString
+)
+
+func (v valueType) String() string {
+	switch v {
+	case typeInteger:
+		return "integer"
+	case typeNumber:
+		return "number"
+	case typeBoolean:
+		return "boolean"
+	}
+	return "string"
+}
+
+// merge returns the narrowest type that covers values of both types.
+func (v valueType) merge(other valueType) valueType {
+	switch {
+	case v == typeUnknown || v == other:
+		return other
+	case v <= typeNumber && other <= typeNumber:
+		return typeNumber
+	}
+	return typeString
+}
+
+func inferType(value string) valueType {
+	value = strings.TrimSpace(value)
+	if _, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return typeInteger
+	}
+	if _, err := strconv.ParseFloat(value, 64); err == nil {
+		return typeNumber
+	}
+	switch strings.ToLower(value) {
+	case "true", "false":
+		return typeBoolean
+	}
+	return typeString
+}
+
+// columnIndexes returns the indexes of names in columns, or nil if names is
+// empty.
+func columnIndexes(columns, names []string) ([]int, error) {
+	if len(names) == 0 {
+		return nil, nil
+	}
+	indexes := make([]int, len(names))
+	for i, name := range names {
+		if indexes[i] = indexOf(columns, name); indexes[i] < 0 {
+			return nil, fmt.Errorf("unknown column %q; columns are %s", name, strings.Join(columns, ", "))
+		}
+	}
+	return indexes, nil
+}
+
+// project returns a copy of the cells of row at indexes, or of the first n
+// cells if indexes is nil.
+func project(row []string, indexes []int, n int) []string {
+	if indexes == nil {
+		return padRow(row, n)
+	}
+	projected := make([]string, len(indexes))
+	for i, index := range indexes {
+		projected[i] = cell(row, index)
+	}
+	return projected
+}
+
+// padRow returns a copy of row with n cells, padding short rows with empty
+// cells.
+func padRow(row []string, n int) []string {
+	padded := make([]string, n)
+	copy(padded, row)
+	return padded
+}
+
+func cell(row []string, i int) string {
+	if i < len(row) {
+		return row[i]
+	}
+	return ""
+}
+
+func indexOf(values []string, value string) int {
+	for i, v := range values {
+		if v == value {
+			return i
+		}
+	}
+	return -1
+}
+
+func contains(values []string, value string) bool {
+	return indexOf(values, value) >= 0
+}
+
+func truncate(value string, n int) string {
+	if len(value) <= n {
+		return value
+	}
+	return strings.ToValidUTF8(value[:n], "") + "…"
+}
@@ -0,0 +1,265 @@
+package table
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	copilot "github.com/github/copilot-sdk/go"
+)
+
+func invoke(t *testing.T, tool copilot.Tool, args map[string]interface{}) copilot.ToolResult {
+	t.Helper()
+	result, err := tool.Handler(copilot.ToolInvocation{Arguments: args})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	return result
+}
+
+func decode[T any](t *testing.T, result copilot.ToolResult) T {
+	t.Helper()
+	var v T
+	if err := json.Unmarshal([]byte(result.TextResultForLLM), &v); err != nil {
+		t.Fatalf("Failed to decode %q: %v", result.TextResultForLLM, err)
+	}
+	return v
+}
+
+const ordersCSV = `id,country,amount,paid
+1,DE,120,true
+2,FR,80.5,false
+3,DE,,true
+4,US,300,true
+5,FR,20,false
+`
+
+func writeFile(t *testing.T, dir, name, content string) {
+	t.Helper()
+	if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func newConfig(t *testing.T) Config {
+	t.Helper()
+	dir := t.TempDir()
+	writeFile(t, dir, "orders.csv", ordersCSV)
+	return Config{Root: dir}
+}
+
+func TestSchemaTool(t *testing.T) {
+	t.Run("infers column types", func(t *testing.T) {
+		result := decode[SchemaResult](t, invoke(t, SchemaTool(newConfig(t)), map[string]interface{}{"path": "orders.csv"}))
+		if result.Rows != 5 || len(result.Columns) != 4 || result.Truncated {
+			t.Fatalf("Unexpected result %+v", result)
+		}
+		want := []string{"integer", "string", "number", "boolean"}
+		for i, column := range result.Columns {
+			if column.Type != want[i] {
+				t.Errorf("Expected %s to be %s, got %s", column.Name, want[i], column.Type)
+			}
+		}
+		if amount := result.Columns[2]; amount.Empty != 1 || strings.Join(amount.Examples, ",") != "120,80.5,300" {
+			t.Errorf("Unexpected amount column %+v", amount)
+		}
+	})
+
+	t.Run("marks results over the scan cap as truncated", func(t *testing.T) {
+		cfg := newConfig(t)
+		cfg.MaxScanRows = 2
+		result := decode[SchemaResult](t, invoke(t, SchemaTool(cfg), map[string]interface{}{"path": "orders.csv"}))
+		if result.Rows != 2 || !result.Truncated {
+			t.Errorf("Unexpected result %+v", result)
+		}
+	})
+
+	t.Run("names blank and duplicate headers", func(t *testing.T) {
+		cfg := newConfig(t)
+		writeFile(t, cfg.Root, "odd.tsv", "a\t\ta\nx\ty\tz\n")
+		result := decode[SchemaResult](t, invoke(t, SchemaTool(cfg), map[string]interface{}{"path": "odd.tsv"}))
+		var names []string
+		for _, column := range result.Columns {
+			names = append(names, column.Name)
+		}
+		if strings.Join(names, ",") != "a,column_2,column_3" {
+			t.Errorf("Unexpected columns %v", names)
+		}
+	})
+}
+
+func TestPreviewTool(t *testing.T) {
+	t.Run("returns the first rows", func(t *testing.T) {
+		result := decode[PreviewResult](t, invoke(t, PreviewTool(newConfig(t)), map[string]interface{}{"path": "orders.csv", "rows": 2}))
+		if len(result.Rows) != 2 || result.Rows[1][0] != "2" || strings.Join(result.Columns, ",") != "id,country,amount,paid" {
+			t.Errorf("Unexpected result %+v", result)
+		}
+	})
+
+	t.Run("returns the last rows", func(t *testing.T) {
+		result := decode[PreviewResult](t, invoke(t, PreviewTool(newConfig(t)), map[string]interface{}{"path": "orders.csv", "rows": 2, "tail": true}))
+		if len(result.Rows) != 2 || result.Rows[0][0] != "4" || result.Rows[1][0] != "5" || result.Scanned != 5 {
+			t.Errorf("Unexpected result %+v", result)
+		}
+	})
+
+	t.Run("caps the rows returned", func(t *testing.T) {
+		cfg := newConfig(t)
+		cfg.MaxRows = 3
+		result := decode[PreviewResult](t, invoke(t, PreviewTool(cfg), map[string]interface{}{"path": "orders.csv", "rows": 50}))
+		if len(result.Rows) != 3 {
+			t.Errorf("Expected 3 rows, got %d", len(result.Rows))
+		}
+	})
+}
+
+func TestFilterTool(t *testing.T) {
+	t.Run("returns matching rows", func(t *testing.T) {
+		result := decode[FilterResult](t, invoke(t, FilterTool(newConfig(t)), map[string]interface{}{
+			"path":    "orders.csv",
+			"where":   `paid = "true" and amount >= 100`,
+			"columns": []interface{}{"id", "amount"},
+		}))
+		if result.Matched != 2 || len(result.Rows) != 2 || strings.Join(result.Rows[1], ",") != "4,300" || strings.Join(result.Columns, ",") != "id,amount" {
+			t.Errorf("Unexpected result %+v", result)
+		}
+	})
+
+	t.Run("counts matches beyond the limit", func(t *testing.T) {
+		result := decode[FilterResult](t, invoke(t, FilterTool(newConfig(t)), map[string]interface{}{"path": "orders.csv", "where": `id > 0`, "limit": 1}))
+		if result.Matched != 5 || len(result.Rows) != 1 {
+			t.Errorf("Unexpected result %+v", result)
+		}
+	})
+
+	t.Run("reports invalid filters to the model", func(t *testing.T) {
+		result := invoke(t, FilterTool(newConfig(t)), map[string]interface{}{"path": "orders.csv", "where": `price > 1`})
+		if result.ResultType != "failure" || !strings.Contains(result.TextResultForLLM, "columns are id, country, amount, paid") {
+			t.Errorf("Unexpected result %+v", result)
+		}
+	})
+}
+
+func TestAggregateTool(t *testing.T) {
+	t.Run("aggregates groups", func(t *testing.T) {
+		result := decode[AggregateResult](t, invoke(t, AggregateTool(newConfig(t)), map[string]interface{}{
+			"path":    "orders.csv",
+			"groupBy": []interface{}{"country"},
+			"aggregates": []interface{}{
+				map[string]interface{}{"function": "count"},
+				map[string]interface{}{"function": "sum", "column": "amount"},
+				map[string]interface{}{"function": "mean", "column": "amount"},
+			},
+			"orderBy":    "sum(amount)",
+			"descending": true,
+		}))
+		got := fmt.Sprint(result.Rows)
+		if got != "[[US 1 300 300] [DE 2 120 120] [FR 2 100.5 50.25]]" || result.Groups != 3 {
+			t.Errorf("Unexpected result %s, %+v", got, result)
+		}
+		if strings.Join(result.Columns, ",") != "country,count,sum(amount),mean(amount)" {
+			t.Errorf("Unexpected columns %v", result.Columns)
+		}
+	})
+
+	t.Run("aggregates filtered rows as one group", func(t *testing.T) {
+		result := decode[AggregateResult](t, invoke(t, AggregateTool(newConfig(t)), map[string]interface{}{
+			"path":  "orders.csv",
+			"where": `country = "DE"`,
+			"aggregates": []interface{}{
+				map[string]interface{}{"function": "count", "column": "amount"},
+				map[string]interface{}{"function": "min", "column": "amount"},
+				map[string]interface{}{"function": "max", "column": "id"},
+			},
+		}))
+		if fmt.Sprint(result.Rows) != "[[1 120 3]]" {
+			t.Errorf("Unexpected result %+v", result)
+		}
+	})
+
+	t.Run("fails over the group cap", func(t *testing.T) {
+		cfg := newConfig(t)
+		cfg.MaxGroups = 2
+		result := invoke(t, AggregateTool(cfg), map[string]interface{}{
+			"path":       "orders.csv",
+			"groupBy":    []interface{}{"country"},
+			"aggregates": []interface{}{map[string]interface{}{"function": "count"}},
+		})
+		if result.ResultType != "failure" || !strings.Contains(result.TextResultForLLM, "more than 2 groups") {
+			t.Errorf("Unexpected result %+v", result)
+		}
+	})
+
+	t.Run("rejects non-numeric values", func(t *testing.T) {
+		result := invoke(t, AggregateTool(newConfig(t)), map[string]interface{}{
+			"path":       "orders.csv",
+			"aggregates": []interface{}{map[string]interface{}{"function": "sum", "column": "country"}},
+		})
+		if result.ResultType != "failure" || !strings.Contains(result.TextResultForLLM, `non-numeric value "DE" in row 1`) {
+			t.Errorf("Unexpected result %+v", result)
+		}
+	})
+}
+
+type lineReader struct {
+	lines []string
+}
+
+func (l *lineReader) Columns() []string { return []string{"line"} }
+
+func (l *lineReader) Next() ([]string, error) {
+	if len(l.lines) == 0 {
+		return nil, io.EOF
+	}
+	line := l.lines[0]
+	l.lines = l.lines[1:]
+	return []string{line}, nil
+}
+
+func TestConfig(t *testing.T) {
+	t.Run("confines paths to the root", func(t *testing.T) {
+		cfg := newConfig(t)
+		outside := t.TempDir()
+		writeFile(t, outside, "secret.csv", "a\n1\n")
+		if err := os.Symlink(filepath.Join(outside, "secret.csv"), filepath.Join(cfg.Root, "link.csv")); err != nil {
+			t.Skip("symlinks not supported")
+		}
+		for _, path := range []string{"../" + filepath.Base(outside) + "/secret.csv", filepath.Join(outside, "secret.csv"), "link.csv"} {
+			result := invoke(t, PreviewTool(cfg), map[string]interface{}{"path": path})
+			if result.ResultType != "failure" || !strings.Contains(result.TextResultForLLM, "outside the allowed directory") {
+				t.Errorf("Expected %s to be rejected, got %+v", path, result)
+			}
+		}
+	})
+
+	t.Run("rejects files over the size cap", func(t *testing.T) {
+		cfg := newConfig(t)
+		cfg.MaxBytes = 10
+		result := invoke(t, PreviewTool(cfg), map[string]interface{}{"path": "orders.csv"})
+		if result.ResultType != "failure" || !strings.Contains(result.TextResultForLLM, "more than the limit of 10") {
+			t.Errorf("Unexpected result %+v", result)
+		}
+	})
+
+	t.Run("uses readers for other formats", func(t *testing.T) {
+		cfg := newConfig(t)
+		writeFile(t, cfg.Root, "notes.LOG", "first\nsecond\n")
+		result := invoke(t, PreviewTool(cfg), map[string]interface{}{"path": "notes.LOG"})
+		if result.ResultType != "failure" || !strings.Contains(result.TextResultForLLM, "supported types are .csv, .tsv") {
+			t.Errorf("Unexpected result %+v", result)
+		}
+
+		cfg.Readers = map[string]ReaderFunc{".log": func(r io.Reader) (RowReader, error) {
+			content, err := io.ReadAll(r)
+			return &lineReader{lines: strings.Fields(string(content))}, err
+		}}
+		preview := decode[PreviewResult](t, invoke(t, PreviewTool(cfg), map[string]interface{}{"path": "notes.LOG"}))
+		if fmt.Sprint(preview.Rows) != "[[first] [second]]" {
+			t.Errorf("Unexpected result %+v", preview)
+		}
+	})
+}
@@ -0,0 +1,103 @@
+package copilot
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// ToolTimeoutError is the cause of the cancellation of a tool call's context
+// when the tool exceeds its timeout, and the error recorded in its result.
+// See [Tool.Timeout].
+type ToolTimeoutError struct {
+	ToolName   string
+	ToolCallID string
+	Timeout    time.Duration
+}
+
+func (e *ToolTimeoutError) Error() string {
+	return fmt.Sprintf("tool %s timed out after %s", e.ToolName, e.Timeout)
+}
+
+// Unwrap returns [context.DeadlineExceeded], so that errors.Is recognizes the
+// error as a timeout.
+func (e *ToolTimeoutError) Unwrap() error {
+	return context.DeadlineExceeded
+}
+
+// WithToolTimeout aborts calls of the tool that run longer than timeout. See
+// [Tool.Timeout].
+//
+// Example:
+//
+//	tool := copilot.DefineToolCtx("fetch_page", "Fetch a web page", fetchPage,
+//	    copilot.WithToolTimeout(30*time.Second))
+func WithToolTimeout(timeout time.Duration) ToolOption {
+	return func(o *toolOptions) {
+		o.timeout = timeout
+	}
+}
+
+// withToolTimeout wraps handler so that calls running longer than timeout are
+// abandoned: the handler's context is canceled with a [*ToolTimeoutError], a
+// timeout result is returned to the model, and an [SDKToolTimedOut] event is
+// dispatched.
+func (s *Session) withToolTimeout(name string, timeout time.Duration, handler ToolHandler) ToolHandler {
+	if timeout <= 0 {
+		return handler
+	}
+	return func(inv ToolInvocation) (ToolResult, error) {
+		timeoutErr := &ToolTimeoutError{ToolName: name, ToolCallID: inv.ToolCallID, Timeout: timeout}
+		ctx, cancel := context.WithTimeoutCause(inv.Context(), timeout, timeoutErr)
+		defer cancel()
+		inv.ctx = ctx
+
+		type outcome struct {
+			result ToolResult
+			err    error
+		}
+		done := make(chan outcome, 1)
+		go func() {
+			defer func() {
+				if r := recover(); r != nil {
+					done <- outcome{err: newToolPanicError(inv, r)}
+				}
+			}()
+			result, err := handler(inv)
+			done <- outcome{result, err}
+		}()
+
+		select {
+		case o := <-done:
+			return o.result, o.err
+		case <-ctx.Done():
+		}
+		if context.Cause(ctx) != timeoutErr {
+			// Canceled for another reason, such as an abort: the handler is
+			// expected to observe its context and return.
+			o := <-done
+			return o.result, o.err
+		}
+
+		s.emit(SDKToolTimedOut, map[string]interface{}{
+			"toolCallId": inv.ToolCallID,
+			"toolName":   name,
+			"timeoutMs":  timeout.Milliseconds(),
+		})
+		return buildTimeoutToolResult(timeoutErr), nil
+	}
+}
+
+// buildTimeoutToolResult creates a failure ToolResult for a tool call that
+// exceeded its timeout.
+func buildTimeoutToolResult(err *ToolTimeoutError) ToolResult {
+	return ToolResult{
+		TextResultForLLM: fmt.Sprintf("Tool '%s' did not finish within %s and was aborted.", err.ToolName, err.Timeout),
+		ResultType:       "failure",
+		Error:            err.Error(),
+		ToolTelemetry: map[string]interface{}{
+			"timedOut":  true,
+			"timeoutMs": err.Timeout.Milliseconds(),
+		},
+	}
+}
@@ -0,0 +1,94 @@
+package copilot
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestToolTimeout(t *testing.T) {
+	callTool := func(t *testing.T, tool Tool) ToolResult {
+		t.Helper()
+		session := NewSession("s1", nil, "")
+		session.registerTools([]Tool{tool})
+		client := &Client{sessions: map[string]*Session{"s1": session}}
+		response, rpcErr := client.handleToolCallRequest(map[string]interface{}{
+			"sessionId":  "s1",
+			"toolCallId": "call-1",
+			"toolName":   tool.Name,
+			"arguments":  map[string]interface{}{},
+		})
+		if rpcErr != nil {
+			t.Fatalf("Unexpected error: %v", rpcErr)
+		}
+		return response["result"].(ToolResult)
+	}
+
+	t.Run("aborts handlers that exceed the timeout", func(t *testing.T) {
+		causes := make(chan error, 1)
+		tool := DefineToolCtx("slow", "Slow", func(ctx context.Context, params struct{}, inv ToolInvocation) (string, error) {
+			if _, ok := ctx.Deadline(); !ok {
+				t.Error("Expected the context to carry a deadline")
+			}
+			<-ctx.Done()
+			causes <- context.Cause(ctx)
+			return "late", nil
+		}, WithToolTimeout(20*time.Millisecond))
+
+		var timedOut []SessionEvent
+		session := NewSession("s1", nil, "")
+		session.On(func(event SessionEvent) {
+			if event.Type == SDKToolTimedOut {
+				timedOut = append(timedOut, event)
+			}
+		})
+		session.registerTools([]Tool{tool})
+		client := &Client{sessions: map[string]*Session{"s1": session}}
+		response, _ := client.handleToolCallRequest(map[string]interface{}{
+			"sessionId":  "s1",
+			"toolCallId": "call-1",
+			"toolName":   "slow",
+			"arguments":  map[string]interface{}{},
+		})
+
+		result := response["result"].(ToolResult)
+		if result.ResultType != "failure" || result.Error != "tool slow timed out after 20ms" || result.ToolTelemetry["timedOut"] != true {
+			t.Errorf("Unexpected result %+v", result)
+		}
+		var timeoutErr *ToolTimeoutError
+		if cause := <-causes; !errors.As(cause, &timeoutErr) || !errors.Is(cause, context.DeadlineExceeded) {
+			t.Errorf("Expected a ToolTimeoutError cause, got %v", cause)
+		}
+		if len(timedOut) != 1 || sdkEventString(timedOut[0], "toolCallId") != "call-1" {
+			t.Errorf("Expected a timeout event, got %+v", timedOut)
+		}
+	})
+
+	t.Run("returns results of handlers that finish in time", func(t *testing.T) {
+		result := callTool(t, Tool{
+			Name:    "fast",
+			Timeout: time.Second,
+			Handler: func(inv ToolInvocation) (ToolResult, error) {
+				return ToolResult{TextResultForLLM: "done", ResultType: "success"}, nil
+			},
+		})
+		if result.TextResultForLLM != "done" {
+			t.Errorf("Unexpected result %+v", result)
+		}
+	})
+
+	t.Run("reports panics of handlers with a timeout", func(t *testing.T) {
+		result := callTool(t, Tool{
+			Name:    "broken",
+			Timeout: time.Second,
+			Handler: func(inv ToolInvocation) (ToolResult, error) {
+				panic("boom")
+			},
+		})
+		if result.ResultType != "failure" || !strings.HasPrefix(result.Error, "tool panic: boom\n") {
+			t.Errorf("Unexpected result %+v", result)
+		}
+	})
+}
@@ -0,0 +1,361 @@
+package copilot
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// Trace span categories, used as the "cat" field of exported trace events.
+const (
+	traceCategoryTurn      = "turn"
+	traceCategoryRPC       = "rpc"
+	traceCategoryTool      = "tool"
+	traceCategoryStreaming = "streaming"
+)
+
+// traceTracks is the order in which span categories are laid out as threads.
+var traceTracks = []struct {
+	category string
+	name     string
+}{
+	{traceCategoryTurn, "Turns"},
+	{traceCategoryRPC, "RPC"},
+	{traceCategoryTool, "Tools"},
+	{traceCategoryStreaming, "Streaming"},
+}
+
+type traceSpan struct {
+	name     string
+	category string
+	start    time.Time
+	end      time.Time
+	open     bool
+	args     map[string]interface{}
+}
+
+// TraceRecorder records the timing of a session — turns, JSON-RPC calls, tool
+// executions, and streamed responses — and exports it in Chrome's trace-event
+// format, which can be opened in chrome://tracing or https://ui.perfetto.dev.
+//
+// Times are measured by the SDK when it sends, receives, or dispatches each
+// message, so spans reflect latency as observed by the client.
+//
+// Example:
+//
+//	recorder := copilot.NewTraceRecorder(session)
+//	session.SendAndCollect(copilot.MessageOptions{Prompt: "Run the tests"}, 0)
+//	recorder.Stop()
+//
+//	f, _ := os.Create("trace.json")
+//	defer f.Close()
+//	recorder.WriteChromeTrace(f)
+type TraceRecorder struct {
+	sessionID   string
+	unsubscribe func()
+	unobserve   func()
+	mu          sync.Mutex
+	spans       []*traceSpan
+	instants    []*traceSpan
+	turn        *traceSpan
+	lastIdle    time.Time
+	tools       map[string]*traceSpan
+	streams     map[string]*traceSpan
+}
+
+// NewTraceRecorder starts recording the timing of session.
+func NewTraceRecorder(session *Session) *TraceRecorder {
+	r := &TraceRecorder{
+		sessionID: session.SessionID,
+		tools:     make(map[string]*traceSpan),
+		streams:   make(map[string]*traceSpan),
+	}
+	r.unsubscribe = session.On(r.recordEvent)
+	r.unobserve = session.client.observe(r.recordCall)
+	return r
+}
+
+// Stop stops recording. Spans still in progress are exported as ending at the
+// time of export.
+func (r *TraceRecorder) Stop() {
+	r.unsubscribe()
+	r.unobserve()
+}
+
+func (r *TraceRecorder) recordCall(call rpcCall) {
+	if sessionID, _ := call.Params["sessionId"].(string); sessionID != r.sessionID {
+		return
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	args := map[string]interface{}{}
+	if call.Incoming {
+		args["direction"] = "incoming"
+	} else {
+		args["direction"] = "outgoing"
+	}
+	if call.Err != nil {
+		args["error"] = call.Err.Error()
+	}
+	r.spans = append(r.spans, &traceSpan{
+		name:     call.Method,
+		category: traceCategoryRPC,
+		start:    call.Start,
+		end:      call.End,
+		args:     args,
+	})
+
+	if call.Method == "session.send" && !call.Incoming && call.Err == nil {
+		r.closeTurn(call.Start)
+		r.turn = &traceSpan{name: "turn", category: traceCategoryTurn, start: call.Start, open: true, args: map[string]interface{}{}}
+		if prompt, ok := call.Params["prompt"].(string); ok {
+			r.turn.args["prompt"] = truncateTraceString(prompt)
+		}
+		r.spans = append(r.spans, r.turn)
+		// The session may have become idle before the send returned.
+		if r.lastIdle.After(call.Start) {
+			r.closeTurn(r.lastIdle)
+		}
+	}
+}
+
+func (r *TraceRecorder) recordEvent(event SessionEvent) {
+	now := time.Now()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	switch event.Type {
+	case SessionIdle:
+		r.lastIdle = now
+		r.closeTurn(now)
+		for id, span := range r.streams {
+			r.closeSpan(span, span.end)
+			delete(r.streams, id)
+		}
+	case SessionError:
+		args := map[string]interface{}{}
+		if event.Data.Message != nil {
+			args["message"] = *event.Data.Message
+		}
+		r.instants = append(r.instants, &traceSpan{name: string(event.Type), category: traceCategoryTurn, start: now, args: args})
+	case ToolExecutionStart:
+		if event.Data.ToolCallID == nil {
+			return
+		}
+		name := "tool"
+		if event.Data.ToolName != nil {
+			name = *event.Data.ToolName
+		}
+		span := &traceSpan{
+			name:     name,
+			category: traceCategoryTool,
+			start:    now,
+			open:     true,
+			args:     map[string]interface{}{"toolCallId": *event.Data.ToolCallID},
+		}
+		r.tools[*event.Data.ToolCallID] = span
+		r.spans = append(r.spans, span)
+	case ToolExecutionComplete:
+		if event.Data.ToolCallID == nil {
+			return
+		}
+		if span, ok := r.tools[*event.Data.ToolCallID]; ok {
+			if event.Data.Success != nil {
+				span.args["success"] = *event.Data.Success
+			}
+			r.closeSpan(span, now)
+			delete(r.tools, *event.Data.ToolCallID)
+		}
+	case AssistantMessageDelta:
+		if event.Data.MessageID == nil {
+			return
+		}
+		span, ok := r.streams[*event.Data.MessageID]
+		if !ok {
+			span = &traceSpan{
+				name:     "assistant.message",
+				category: traceCategoryStreaming,
+				start:    now,
+				open:     true,
+				args:     map[string]interface{}{"messageId": *event.Data.MessageID, "deltas": 0},
+			}
+			r.streams[*event.Data.MessageID] = span
+			r.spans = append(r.spans, span)
+		}
+		span.end = now
+		span.args["deltas"] = span.args["deltas"].(int) + 1
+	case AssistantMessage:
+		if event.Data.MessageID == nil {
+			return
+		}
+		if span, ok := r.streams[*event.Data.MessageID]; ok {
+			r.closeSpan(span, now)
+			delete(r.streams, *event.Data.MessageID)
+		}
+	}
+}
+
+func (r *TraceRecorder) closeTurn(end time.Time) {
+	if r.turn != nil {
+		r.closeSpan(r.turn, end)
+		r.turn = nil
+	}
+}
+
+func (r *TraceRecorder) closeSpan(span *traceSpan, end time.Time) {
+	span.end = end
+	span.open = false
+}
+
+// maxTraceStringLength bounds the length of strings such as prompts copied into
+// trace event arguments.
+const maxTraceStringLength = 200
+
+func truncateTraceString(s string) string {
+	if len(s) <= maxTraceStringLength {
+		return s
+	}
+	n := maxTraceStringLength
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n] + "…"
+}
+
+// chromeTraceEvent is an event in Chrome's trace-event format.
+type chromeTraceEvent struct {
+	Name  string                 `json:"name"`
+	Cat   string                 `json:"cat,omitempty"`
+	Ph    string                 `json:"ph"`
+	Ts    float64                `json:"ts"`
+	Dur   *float64               `json:"dur,omitempty"`
+	Pid   int                    `json:"pid"`
+	Tid   int                    `json:"tid"`
+	Scope string                 `json:"s,omitempty"`
+	Args  map[string]interface{} `json:"args,omitempty"`
+}
+
+type chromeTrace struct {
+	TraceEvents     []chromeTraceEvent `json:"traceEvents"`
+	DisplayTimeUnit string             `json:"displayTimeUnit"`
+}
+
+func traceMicros(t time.Time) float64 {
+	return float64(t.UnixNano()) / 1e3
+}
+
+// WriteChromeTrace writes the recorded spans to w as Chrome trace-event JSON.
+//
+// Each kind of span is laid out on its own thread: turns, RPCs, tool calls, and
+// streaming intervals. Spans of the same kind that overlap, such as parallel
+// tool calls, are placed on additional threads so that none are hidden.
+func (r *TraceRecorder) WriteChromeTrace(w io.Writer) error {
+	now := time.Now()
+	r.mu.Lock()
+	spans := make([]traceSpan, len(r.spans))
+	for i, span := range r.spans {
+		spans[i] = *span
+		spans[i].args = cloneTraceArgs(span.args)
+		if span.open {
+			spans[i].end = now
+			spans[i].args["incomplete"] = true
+		}
+	}
+	instants := make([]traceSpan, len(r.instants))
+	for i, span := range r.instants {
+		instants[i] = *span
+	}
+	r.mu.Unlock()
+
+	sort.SliceStable(spans, func(i, j int) bool { return spans[i].start.Before(spans[j].start) })
+
+	const pid = 1
+	trace := chromeTrace{DisplayTimeUnit: "ms"}
+	trace.TraceEvents = append(trace.TraceEvents, chromeTraceEvent{
+		Name: "process_name",
+		Ph:   "M",
+		Pid:  pid,
+		Args: map[string]interface{}{"name": fmt.Sprintf("Copilot session %s", r.sessionID)},
+	})
+
+	tid := 0
+	for _, track := range traceTracks {
+		// laneEnds holds the end time of the last span placed on each lane.
+		var laneEnds []time.Time
+		firstTid := tid + 1
+		trace.TraceEvents = append(trace.TraceEvents, chromeTraceEvent{
+			Name: "thread_name",
+			Ph:   "M",
+			Pid:  pid,
+			Tid:  firstTid,
+			Args: map[string]interface{}{"name": track.name},
+		})
+		for _, span := range spans {
+			if span.category != track.category {
+				continue
+			}
+			lane := 0
+			for lane < len(laneEnds) && laneEnds[lane].After(span.start) {
+				lane++
+			}
+			if lane == len(laneEnds) {
+				laneEnds = append(laneEnds, time.Time{})
+				if lane > 0 {
+					trace.TraceEvents = append(trace.TraceEvents, chromeTraceEvent{
+						Name: "thread_name",
+						Ph:   "M",
+						Pid:  pid,
+						Tid:  firstTid + lane,
+						Args: map[string]interface{}{"name": fmt.Sprintf("%s (%d)", track.name, lane+1)},
+					})
+				}
+			}
+			laneEnds[lane] = span.end
+
+			dur := traceMicros(span.end) - traceMicros(span.start)
+			trace.TraceEvents = append(trace.TraceEvents, chromeTraceEvent{
+				Name: span.name,
+				Cat:  span.category,
+				Ph:   "X",
+				Ts:   traceMicros(span.start),
+				Dur:  &dur,
+				Pid:  pid,
+				Tid:  firstTid + lane,
+				Args: span.args,
+			})
+		}
+		tid += max(len(laneEnds), 1)
+	}
+
+	for _, instant := range instants {
+		trace.TraceEvents = append(trace.TraceEvents, chromeTraceEvent{
+			Name:  instant.name,
+			Cat:   instant.category,
+			Ph:    "i",
+			Ts:    traceMicros(instant.start),
+			Pid:   pid,
+			Tid:   1, // the Turns track
+			Scope: "p",
+			Args:  instant.args,
+		})
+	}
+
+	if err := json.NewEncoder(w).Encode(trace); err != nil {
+		return fmt.Errorf("failed to write trace: %w", err)
+	}
+	return nil
+}
+
+func cloneTraceArgs(args map[string]interface{}) map[string]interface{} {
+	c := make(map[string]interface{}, len(args)+1)
+	for k, v := range args {
+		c[k] = v
+	}
+	return c
+}
@@ -0,0 +1,138 @@
+package copilot
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+type decodedTraceEvent struct {
+	Name string                 `json:"name"`
+	Cat  string                 `json:"cat"`
+	Ph   string                 `json:"ph"`
+	Ts   float64                `json:"ts"`
+	Dur  float64                `json:"dur"`
+	Tid  int                    `json:"tid"`
+	Args map[string]interface{} `json:"args"`
+}
+
+func decodeTrace(t *testing.T, recorder *TraceRecorder) []decodedTraceEvent {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := recorder.WriteChromeTrace(&buf); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	var trace struct {
+		TraceEvents []decodedTraceEvent `json:"traceEvents"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &trace); err != nil {
+		t.Fatalf("Invalid trace JSON: %v", err)
+	}
+	return trace.TraceEvents
+}
+
+func TestTraceRecorder(t *testing.T) {
+	t.Run("records turns, RPCs, tool calls, and streaming", func(t *testing.T) {
+		session, _ := newFakeSession(t, func(server *fakeServer, method string, params map[string]interface{}) (map[string]interface{}, *JSONRPCError) {
+			go func() {
+				server.emitEvent(ToolExecutionStart, "e1", map[string]interface{}{"toolCallId": "c1", "toolName": "grep"})
+				server.emitEvent(ToolExecutionStart, "e2", map[string]interface{}{"toolCallId": "c2", "toolName": "view"})
+				server.emitEvent(ToolExecutionComplete, "e3", map[string]interface{}{"toolCallId": "c1", "success": true})
+				server.emitEvent(ToolExecutionComplete, "e4", map[string]interface{}{"toolCallId": "c2", "success": false})
+				server.emitEvent(AssistantMessageDelta, "e5", map[string]interface{}{"messageId": "m1", "deltaContent": "Hel"})
+				server.emitEvent(AssistantMessageDelta, "e6", map[string]interface{}{"messageId": "m1", "deltaContent": "lo"})
+				server.emitEvent(AssistantMessage, "e7", map[string]interface{}{"messageId": "m1", "content": "Hello"})
+				server.emitEvent(SessionIdle, "e8", map[string]interface{}{})
+			}()
+			return map[string]interface{}{"messageId": "msg-1"}, nil
+		})
+
+		recorder := NewTraceRecorder(session)
+		if _, err := session.SendAndCollect(MessageOptions{Prompt: "Find the bug"}, 5*time.Second); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		recorder.Stop()
+
+		spans := map[string][]decodedTraceEvent{}
+		threads := map[string]bool{}
+		for _, event := range decodeTrace(t, recorder) {
+			switch event.Ph {
+			case "X":
+				spans[event.Cat] = append(spans[event.Cat], event)
+			case "M":
+				if name, ok := event.Args["name"].(string); ok {
+					threads[name] = true
+				}
+			}
+		}
+
+		if turns := spans["turn"]; len(turns) != 1 || turns[0].Args["prompt"] != "Find the bug" || turns[0].Args["incomplete"] != nil {
+			t.Errorf("Expected one complete turn, got %+v", turns)
+		}
+		if rpcs := spans["rpc"]; len(rpcs) != 1 || rpcs[0].Name != "session.send" || rpcs[0].Args["direction"] != "outgoing" {
+			t.Errorf("Expected the session.send RPC, got %+v", rpcs)
+		}
+		tools := spans["tool"]
+		if len(tools) != 2 || tools[0].Name != "grep" || tools[1].Args["success"] != false {
+			t.Fatalf("Expected two tool spans, got %+v", tools)
+		}
+		if tools[0].Tid == tools[1].Tid {
+			t.Error("Expected overlapping tool calls on separate threads")
+		}
+		if !threads["Tools"] || !threads["Tools (2)"] {
+			t.Errorf("Expected named tool threads, got %v", threads)
+		}
+		if streams := spans["streaming"]; len(streams) != 1 || streams[0].Args["deltas"] != float64(2) {
+			t.Errorf("Expected one streaming span with two deltas, got %+v", streams)
+		}
+		for cat, events := range spans {
+			for _, event := range events {
+				if event.Dur < 0 {
+					t.Errorf("Negative duration for %s span %s", cat, event.Name)
+				}
+			}
+		}
+	})
+
+	t.Run("ignores RPCs of other sessions and marks open spans", func(t *testing.T) {
+		session, server := newFakeSession(t, func(server *fakeServer, method string, params map[string]interface{}) (map[string]interface{}, *JSONRPCError) {
+			return map[string]interface{}{"messageId": "msg-1"}, nil
+		})
+		recorder := NewTraceRecorder(session)
+		defer recorder.Stop()
+
+		session.client.Request("session.getMessages", map[string]interface{}{"sessionId": "other"})
+		session.Send(MessageOptions{Prompt: strings.Repeat("é", maxTraceStringLength)})
+
+		seen := make(chan struct{})
+		unsubscribe := session.On(func(event SessionEvent) {
+			if event.Type == ToolExecutionStart {
+				close(seen)
+			}
+		})
+		defer unsubscribe()
+		server.emitEvent(ToolExecutionStart, "e1", map[string]interface{}{"toolCallId": "c1", "toolName": "bash"})
+		<-seen
+
+		var rpcs, open int
+		for _, event := range decodeTrace(t, recorder) {
+			if event.Cat == "rpc" {
+				rpcs++
+			}
+			if event.Args["incomplete"] == true {
+				open++
+			}
+			if prompt, ok := event.Args["prompt"].(string); ok && !strings.HasSuffix(prompt, "é…") {
+				t.Errorf("Expected prompt to be truncated on a rune boundary, got %q", prompt)
+			}
+		}
+		if rpcs != 1 {
+			t.Errorf("Expected only this session's RPC, got %d", rpcs)
+		}
+		if open != 2 {
+			t.Errorf("Expected the turn and tool call to be incomplete, got %d", open)
+		}
+	})
+}
@@ -0,0 +1,344 @@
+package copilot
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"iter"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrTranscriptNotFound is returned by [TranscriptStore.Load] when no transcript
+// is stored for the session.
+var ErrTranscriptNotFound = errors.New("transcript not found")
+
+// Transcript is a recorded session: its events plus metadata used for querying.
+type Transcript struct {
+	// SessionID is the ID of the recorded session.
+	SessionID string `json:"sessionId"`
+	// Tags are caller-defined labels, for example a tenant or environment name.
+	Tags []string `json:"tags,omitempty"`
+	// CreatedAt is when recording started.
+	CreatedAt time.Time `json:"createdAt"`
+	// UpdatedAt is when the transcript was last saved.
+	UpdatedAt time.Time `json:"updatedAt"`
+	// Events are the recorded session events in the order they were dispatched.
+	Events []SessionEvent `json:"events"`
+	// Tenant is the tenant that owns the transcript when it is stored through a
+	// [TenantTranscriptStore].
+	Tenant string `json:"tenant,omitempty"`
+	// Sealed holds the encrypted events of a transcript stored through a
+	// [TenantTranscriptStore] with tenant keys. Events is empty while sealed.
+	Sealed []byte `json:"sealed,omitempty"`
+}
+
+// clone returns a copy of t that does not share slices with it.
+func (t *Transcript) clone() *Transcript {
+	c := *t
+	c.Tags = append([]string(nil), t.Tags...)
+	c.Events = append([]SessionEvent(nil), t.Events...)
+	c.Sealed = append([]byte(nil), t.Sealed...)
+	return &c
+}
+
+// HasTag reports whether the transcript has the given tag.
+func (t *Transcript) HasTag(tag string) bool {
+	for _, existing := range t.Tags {
+		if existing == tag {
+			return true
+		}
+	}
+	return false
+}
+
+// TranscriptStore persists transcripts. Implementations must be safe for
+// concurrent use.
+type TranscriptStore interface {
+	// Save stores the transcript, replacing any transcript for the same session.
+	Save(transcript *Transcript) error
+	// Load returns the transcript for a session, or [ErrTranscriptNotFound].
+	Load(sessionID string) (*Transcript, error)
+	// All iterates over every stored transcript, oldest first. A transcript that
+	// cannot be read is reported as an error without stopping the iteration.
+	All() iter.Seq2[*Transcript, error]
+	// Delete removes the transcript for a session. Deleting a transcript that is
+	// not stored is not an error.
+	Delete(sessionID string) error
+}
+
+// MemoryTranscriptStore is a [TranscriptStore] that keeps transcripts in memory.
+type MemoryTranscriptStore struct {
+	mu          sync.RWMutex
+	transcripts map[string]*Transcript
+}
+
+// NewMemoryTranscriptStore creates an empty in-memory transcript store.
+func NewMemoryTranscriptStore() *MemoryTranscriptStore {
+	return &MemoryTranscriptStore{transcripts: make(map[string]*Transcript)}
+}
+
+// Save implements [TranscriptStore].
+func (s *MemoryTranscriptStore) Save(transcript *Transcript) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.transcripts[transcript.SessionID] = transcript.clone()
+	return nil
+}
+
+// Load implements [TranscriptStore].
+func (s *MemoryTranscriptStore) Load(sessionID string) (*Transcript, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	transcript, ok := s.transcripts[sessionID]
+	if !ok {
+		return nil, ErrTranscriptNotFound
+	}
+	return transcript.clone(), nil
+}
+
+// All implements [TranscriptStore].
+func (s *MemoryTranscriptStore) All() iter.Seq2[*Transcript, error] {
+	return func(yield func(*Transcript, error) bool) {
+		s.mu.RLock()
+		transcripts := make([]*Transcript, 0, len(s.transcripts))
+		for _, transcript := range s.transcripts {
+			transcripts = append(transcripts, transcript.clone())
+		}
+		s.mu.RUnlock()
+
+		sortTranscripts(transcripts)
+		for _, transcript := range transcripts {
+			if !yield(transcript, nil) {
+				return
+			}
+		}
+	}
+}
+
+// Delete implements [TranscriptStore].
+func (s *MemoryTranscriptStore) Delete(sessionID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.transcripts, sessionID)
+	return nil
+}
+
+// FileTranscriptStore is a [TranscriptStore] that keeps one JSON file per
+// session in a directory.
+type FileTranscriptStore struct {
+	dir string
+	mu  sync.Mutex
+}
+
+// NewFileTranscriptStore creates a transcript store in dir, creating the
+// directory if it does not exist.
+func NewFileTranscriptStore(dir string) (*FileTranscriptStore, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create transcript directory: %w", err)
+	}
+	return &FileTranscriptStore{dir: dir}, nil
+}
+
+func (s *FileTranscriptStore) path(sessionID string) string {
+	return filepath.Join(s.dir, url.PathEscape(sessionID)+".json")
+}
+
+// Save implements [TranscriptStore]. The file is replaced atomically, so a
+// concurrent reader never observes a partially written transcript.
+func (s *FileTranscriptStore) Save(transcript *Transcript) error {
+	data, err := json.Marshal(transcript)
+	if err != nil {
+		return fmt.Errorf("failed to encode transcript: %w", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := writeFileAtomic(s.path(transcript.SessionID), data); err != nil {
+		return fmt.Errorf("failed to save transcript: %w", err)
+	}
+	return nil
+}
+
+// writeFileAtomic replaces the file at path with data by writing a temporary
+// file in the same directory and renaming it into place.
+func writeFileAtomic(path string, data []byte) error {
+	return writeReaderAtomic(path, bytes.NewReader(data))
+}
+
+// writeReaderAtomic is like writeFileAtomic, streaming the contents from r.
+func writeReaderAtomic(path string, r io.Reader) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := io.Copy(tmp, r); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// Load implements [TranscriptStore].
+func (s *FileTranscriptStore) Load(sessionID string) (*Transcript, error) {
+	return s.load(s.path(sessionID))
+}
+
+func (s *FileTranscriptStore) load(path string) (*Transcript, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrTranscriptNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read transcript: %w", err)
+	}
+	var transcript Transcript
+	if err := json.Unmarshal(data, &transcript); err != nil {
+		return nil, fmt.Errorf("failed to decode transcript %s: %w", filepath.Base(path), err)
+	}
+	return &transcript, nil
+}
+
+// All implements [TranscriptStore]. Every transcript is read to order them by
+// creation time.
+func (s *FileTranscriptStore) All() iter.Seq2[*Transcript, error] {
+	return func(yield func(*Transcript, error) bool) {
+		entries, err := os.ReadDir(s.dir)
+		if err != nil {
+			yield(nil, fmt.Errorf("failed to list transcripts: %w", err))
+			return
+		}
+
+		var transcripts []*Transcript
+		for _, entry := range entries {
+			name := entry.Name()
+			if entry.IsDir() || strings.HasPrefix(name, ".") || !strings.HasSuffix(name, ".json") {
+				continue
+			}
+			transcript, err := s.load(filepath.Join(s.dir, name))
+			if err != nil {
+				if !yield(nil, err) {
+					return
+				}
+				continue
+			}
+			transcripts = append(transcripts, transcript)
+		}
+
+		sortTranscripts(transcripts)
+		for _, transcript := range transcripts {
+			if !yield(transcript, nil) {
+				return
+			}
+		}
+	}
+}
+
+// Delete implements [TranscriptStore].
+func (s *FileTranscriptStore) Delete(sessionID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := os.Remove(s.path(sessionID)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to delete transcript: %w", err)
+	}
+	return nil
+}
+
+func sortTranscripts(transcripts []*Transcript) {
+	sort.SliceStable(transcripts, func(i, j int) bool {
+		if !transcripts[i].CreatedAt.Equal(transcripts[j].CreatedAt) {
+			return transcripts[i].CreatedAt.Before(transcripts[j].CreatedAt)
+		}
+		return transcripts[i].SessionID < transcripts[j].SessionID
+	})
+}
+
+// TranscriptRecorder records the events of a session into a [TranscriptStore].
+//
+// The transcript is saved each time the session becomes idle, and when
+// [TranscriptRecorder.Flush] or [TranscriptRecorder.Stop] is called.
+//
+// Example:
+//
+//	store, _ := copilot.NewFileTranscriptStore("./transcripts")
+//	recorder := copilot.NewTranscriptRecorder(session, store, "env:prod")
+//	defer recorder.Stop()
+type TranscriptRecorder struct {
+	store       TranscriptStore
+	unsubscribe func()
+	mu          sync.Mutex
+	transcript  Transcript
+	err         error
+}
+
+// NewTranscriptRecorder starts recording session into store with the given tags.
+func NewTranscriptRecorder(session *Session, store TranscriptStore, tags ...string) *TranscriptRecorder {
+	now := time.Now()
+	r := &TranscriptRecorder{
+		store: store,
+		transcript: Transcript{
+			SessionID: session.SessionID,
+			Tags:      append([]string(nil), tags...),
+			CreatedAt: now,
+			UpdatedAt: now,
+		},
+	}
+	r.unsubscribe = session.On(r.record)
+	return r
+}
+
+func (r *TranscriptRecorder) record(event SessionEvent) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.transcript.Events = append(r.transcript.Events, event)
+	if event.Type == SessionIdle {
+		r.saveLocked()
+	}
+}
+
+// Tag adds tags to the transcript. They are persisted on the next save.
+func (r *TranscriptRecorder) Tag(tags ...string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, tag := range tags {
+		if !r.transcript.HasTag(tag) {
+			r.transcript.Tags = append(r.transcript.Tags, tag)
+		}
+	}
+}
+
+// Flush saves the transcript now. It also reports any error from an earlier
+// automatic save.
+func (r *TranscriptRecorder) Flush() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.saveLocked()
+	err := r.err
+	r.err = nil
+	return err
+}
+
+// Stop stops recording and saves the transcript.
+func (r *TranscriptRecorder) Stop() error {
+	r.unsubscribe()
+	return r.Flush()
+}
+
+func (r *TranscriptRecorder) saveLocked() {
+	r.transcript.UpdatedAt = time.Now()
+	if err := r.store.Save(&r.transcript); err != nil {
+		r.err = err
+	}
+}
@@ -0,0 +1,120 @@
+package copilot
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestTranscriptStores(t *testing.T) {
+	fileStore, err := NewFileTranscriptStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("Failed to create file store: %v", err)
+	}
+	stores := map[string]TranscriptStore{
+		"memory": NewMemoryTranscriptStore(),
+		"file":   fileStore,
+	}
+
+	for name, store := range stores {
+		t.Run(name+" store saves, loads, and lists transcripts", func(t *testing.T) {
+			base := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+			content := "hello"
+			for i, id := range []string{"b/2", "a/1"} {
+				err := store.Save(&Transcript{
+					SessionID: id,
+					Tags:      []string{"prod"},
+					CreatedAt: base.Add(time.Duration(i) * time.Hour),
+					Events:    []SessionEvent{{ID: "e1", Type: UserMessage, Data: Data{Content: &content}}},
+				})
+				if err != nil {
+					t.Fatalf("Failed to save: %v", err)
+				}
+			}
+
+			loaded, err := store.Load("a/1")
+			if err != nil {
+				t.Fatalf("Failed to load: %v", err)
+			}
+			if len(loaded.Events) != 1 || *loaded.Events[0].Data.Content != "hello" || !loaded.HasTag("prod") {
+				t.Errorf("Unexpected transcript %+v", loaded)
+			}
+
+			if _, err := store.Load("missing"); !errors.Is(err, ErrTranscriptNotFound) {
+				t.Errorf("Expected ErrTranscriptNotFound, got %v", err)
+			}
+
+			var ids []string
+			for transcript, err := range store.All() {
+				if err != nil {
+					t.Fatalf("Unexpected error: %v", err)
+				}
+				ids = append(ids, transcript.SessionID)
+			}
+			if len(ids) != 2 || ids[0] != "b/2" || ids[1] != "a/1" {
+				t.Errorf("Expected transcripts oldest first, got %v", ids)
+			}
+
+			if err := store.Delete("a/1"); err != nil {
+				t.Fatalf("Failed to delete: %v", err)
+			}
+			if _, err := store.Load("a/1"); !errors.Is(err, ErrTranscriptNotFound) {
+				t.Errorf("Expected the deleted transcript to be gone, got %v", err)
+			}
+			if err := store.Delete("missing"); err != nil {
+				t.Errorf("Expected deleting a missing transcript to succeed, got %v", err)
+			}
+		})
+	}
+
+	t.Run("file store reports unreadable transcripts and continues", func(t *testing.T) {
+		dir := t.TempDir()
+		store, _ := NewFileTranscriptStore(dir)
+		store.Save(&Transcript{SessionID: "good"})
+		os.WriteFile(filepath.Join(dir, "bad.json"), []byte("{"), 0o644)
+
+		var good, bad int
+		for transcript, err := range store.All() {
+			if err != nil {
+				bad++
+			} else if transcript.SessionID == "good" {
+				good++
+			}
+		}
+		if good != 1 || bad != 1 {
+			t.Errorf("Expected one good and one bad transcript, got %d and %d", good, bad)
+		}
+	})
+}
+
+func TestTranscriptRecorder(t *testing.T) {
+	t.Run("saves events on idle and on stop", func(t *testing.T) {
+		session := &Session{SessionID: "s1"}
+		store := NewMemoryTranscriptStore()
+		recorder := NewTranscriptRecorder(session, store, "team:a")
+
+		session.dispatchEvent(SessionEvent{ID: "e1", Type: UserMessage})
+		if _, err := store.Load("s1"); !errors.Is(err, ErrTranscriptNotFound) {
+			t.Errorf("Expected nothing saved before idle, got %v", err)
+		}
+
+		session.dispatchEvent(SessionEvent{ID: "e2", Type: SessionIdle})
+		transcript, err := store.Load("s1")
+		if err != nil || len(transcript.Events) != 2 {
+			t.Fatalf("Expected 2 events saved on idle, got %+v, %v", transcript, err)
+		}
+
+		recorder.Tag("incident")
+		if err := recorder.Stop(); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		session.dispatchEvent(SessionEvent{ID: "e3", Type: UserMessage})
+
+		transcript, _ = store.Load("s1")
+		if len(transcript.Events) != 2 || !transcript.HasTag("team:a") || !transcript.HasTag("incident") {
+			t.Errorf("Unexpected transcript after stop %+v", transcript)
+		}
+	})
+}
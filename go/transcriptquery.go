@@ -0,0 +1,125 @@
+package copilot
+
+import (
+	"iter"
+	"path"
+	"strings"
+	"time"
+)
+
+// TranscriptQuery selects transcripts from a [TranscriptStore]. All non-zero
+// criteria must match.
+//
+// Example:
+//
+//	// Sessions where the deploy tool failed in the last week
+//	query := copilot.TranscriptQuery{
+//	    ToolName: "deploy",
+//	    HasError: true,
+//	    Since:    time.Now().AddDate(0, 0, -7),
+//	}
+//	for transcript, err := range copilot.QueryTranscripts(store, query) {
+//	    if err != nil {
+//	        log.Printf("skipping transcript: %v", err)
+//	        continue
+//	    }
+//	    fmt.Println(transcript.SessionID)
+//	}
+type TranscriptQuery struct {
+	// Tags that the transcript must all have.
+	Tags []string
+	// Since and Until select transcripts that were active in the time range
+	// [Since, Until). A zero value leaves that end of the range open.
+	Since time.Time
+	Until time.Time
+	// ToolName selects transcripts in which a matching tool was called. It may be
+	// a pattern as accepted by [path.Match], for example "git_*".
+	ToolName string
+	// HasError selects transcripts containing a session error or a failed tool
+	// execution. Combined with ToolName, it selects transcripts in which a
+	// matching tool failed.
+	HasError bool
+	// Text selects transcripts whose user or assistant messages contain every
+	// whitespace-separated term in Text, ignoring case.
+	Text string
+}
+
+// QueryTranscripts iterates over the transcripts in store that match query,
+// oldest first. Errors reading individual transcripts are yielded without
+// stopping the iteration.
+func QueryTranscripts(store TranscriptStore, query TranscriptQuery) iter.Seq2[*Transcript, error] {
+	return func(yield func(*Transcript, error) bool) {
+		for transcript, err := range store.All() {
+			if err != nil {
+				if !yield(nil, err) {
+					return
+				}
+				continue
+			}
+			if query.Matches(transcript) && !yield(transcript, nil) {
+				return
+			}
+		}
+	}
+}
+
+// Matches reports whether the transcript satisfies the query.
+func (q TranscriptQuery) Matches(t *Transcript) bool {
+	for _, tag := range q.Tags {
+		if !t.HasTag(tag) {
+			return false
+		}
+	}
+	if !q.Since.IsZero() && t.UpdatedAt.Before(q.Since) {
+		return false
+	}
+	if !q.Until.IsZero() && !t.CreatedAt.Before(q.Until) {
+		return false
+	}
+
+	terms := strings.Fields(strings.ToLower(q.Text))
+	needTool := q.ToolName != ""
+	needError := q.HasError
+	names := make(toolCallNames)
+
+	for _, event := range t.Events {
+		toolName := names.track(event)
+		toolMatches := q.ToolName == "" || (toolName != "" && matchToolName(q.ToolName, toolName))
+
+		if needTool && toolName != "" && toolMatches && (event.Type == ToolExecutionStart || event.Type == ToolExecutionComplete) {
+			needTool = false
+		}
+		if needError && toolMatches && isErrorEvent(event) {
+			needError = false
+		}
+		if len(terms) > 0 && (event.Type == UserMessage || event.Type == AssistantMessage) && event.Data.Content != nil {
+			content := strings.ToLower(*event.Data.Content)
+			remaining := terms[:0]
+			for _, term := range terms {
+				if !strings.Contains(content, term) {
+					remaining = append(remaining, term)
+				}
+			}
+			terms = remaining
+		}
+	}
+
+	return !needTool && !needError && len(terms) == 0
+}
+
+func matchToolName(pattern, name string) bool {
+	matched, err := path.Match(pattern, name)
+	return matched && err == nil
+}
+
+// isErrorEvent reports whether an event records a session error or a failed
+// tool execution.
+func isErrorEvent(event SessionEvent) bool {
+	switch event.Type {
+	case SessionError:
+		return true
+	case ToolExecutionComplete:
+		return event.Data.Success != nil && !*event.Data.Success
+	}
+	return false
+}
@@ -0,0 +1,89 @@
+package copilot
+
+import (
+	"testing"
+	"time"
+)
+
+func TestQueryTranscripts(t *testing.T) {
+	base := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	userMessage := func(text string) SessionEvent {
+		return SessionEvent{Type: UserMessage, Data: Data{Content: &text}}
+	}
+	toolRun := func(id, name string, success bool) []SessionEvent {
+		return []SessionEvent{
+			{Type: ToolExecutionStart, Data: Data{ToolCallID: &id, ToolName: &name}},
+			{Type: ToolExecutionComplete, Data: Data{ToolCallID: &id, Success: &success}},
+		}
+	}
+
+	store := NewMemoryTranscriptStore()
+	store.Save(&Transcript{
+		SessionID: "deploy-failed",
+		Tags:      []string{"prod"},
+		CreatedAt: base, UpdatedAt: base.Add(time.Hour),
+		Events: append([]SessionEvent{userMessage("Deploy the API service")}, toolRun("1", "deploy", false)...),
+	})
+	store.Save(&Transcript{
+		SessionID: "deploy-ok",
+		Tags:      []string{"staging"},
+		CreatedAt: base.AddDate(0, 0, 3), UpdatedAt: base.AddDate(0, 0, 3),
+		Events: append(toolRun("1", "deploy", true), toolRun("2", "git_status", false)...),
+	})
+	store.Save(&Transcript{
+		SessionID: "old",
+		CreatedAt: base.AddDate(0, -1, 0), UpdatedAt: base.AddDate(0, -1, 0),
+		Events: []SessionEvent{userMessage("hello"), {Type: SessionError}},
+	})
+
+	run := func(query TranscriptQuery) []string {
+		var ids []string
+		for transcript, err := range QueryTranscripts(store, query) {
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			ids = append(ids, transcript.SessionID)
+		}
+		return ids
+	}
+
+	cases := []struct {
+		name  string
+		query TranscriptQuery
+		want  []string
+	}{
+		{"empty query matches everything", TranscriptQuery{}, []string{"old", "deploy-failed", "deploy-ok"}},
+		{"tag", TranscriptQuery{Tags: []string{"prod"}}, []string{"deploy-failed"}},
+		{"date range", TranscriptQuery{Since: base, Until: base.AddDate(0, 0, 1)}, []string{"deploy-failed"}},
+		{"tool name", TranscriptQuery{ToolName: "deploy"}, []string{"deploy-failed", "deploy-ok"}},
+		{"tool failed", TranscriptQuery{ToolName: "deploy", HasError: true}, []string{"deploy-failed"}},
+		{"tool pattern failed", TranscriptQuery{ToolName: "git_*", HasError: true}, []string{"deploy-ok"}},
+		{"any error", TranscriptQuery{HasError: true}, []string{"old", "deploy-failed", "deploy-ok"}},
+		{"full text", TranscriptQuery{Text: "api DEPLOY"}, []string{"deploy-failed"}},
+		{"full text requires every term", TranscriptQuery{Text: "deploy hello"}, nil},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			got := run(tc.query)
+			if len(got) != len(tc.want) {
+				t.Fatalf("Expected %v, got %v", tc.want, got)
+			}
+			for i := range got {
+				if got[i] != tc.want[i] {
+					t.Fatalf("Expected %v, got %v", tc.want, got)
+				}
+			}
+		})
+	}
+
+	t.Run("stops when the caller breaks", func(t *testing.T) {
+		count := 0
+		for range QueryTranscripts(store, TranscriptQuery{}) {
+			count++
+			break
+		}
+		if count != 1 {
+			t.Errorf("Expected iteration to stop after 1, got %d", count)
+		}
+	})
+}
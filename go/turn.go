@@ -0,0 +1,333 @@
+package copilot
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Turn is the result of one request/response exchange with the assistant,
+// returned by [Session.SendAndCollect].
+type Turn struct {
+	// MessageID is the ID returned by the server when the message was sent.
+	MessageID string
+	// Events are all session events delivered while the turn was in progress, in
+	// the order they were dispatched.
+	Events []SessionEvent
+	// FinalMessage is the last assistant.message event of the turn, or nil if the
+	// assistant did not reply with a message.
+	FinalMessage *SessionEvent
+	// Content is the text of FinalMessage. If no final message was received, it is
+	// the concatenation of any streamed assistant.message_delta content. For a
+	// turn cut off by a timeout while a message was streaming, it is the text
+	// streamed for that message so far.
+	Content string
+	// Citations are the citations attached to the turn's assistant messages, in
+	// the order they were received.
+	Citations []Citation
+	// ToolErrors aggregates every tool call that failed during the turn, or is nil
+	// if none failed.
+	ToolErrors *MultiToolError
+	// Experiment identifies the experiment variant assigned to the turn by the
+	// session's [ExperimentProvider], or nil if none was assigned.
+	Experiment *TurnExperiment
+	// Moderation is the session's [PreSendModerator] decision about the turn's
+	// prompt, or nil if the session has no moderator.
+	Moderation *ModerationResult
+	// FallbackModel is the model of the session's [ModelFallbackConfig] chain
+	// the turn fell back to, or empty if it did not fall back.
+	FallbackModel string
+	// Partial reports that the turn was cut off by a timeout before the session
+	// became idle, so Events and Content stop at the truncation point. See
+	// [PartialResultError].
+	Partial bool
+}
+
+// PartialResultError is returned by [Session.SendAndCollect] and
+// [Session.SendAndWait] when the timeout expires before the turn finishes. Turn
+// holds everything received up to the cutoff, so UIs can show the text
+// generated so far; an [SDKTurnTruncated] event marks the truncation point.
+type PartialResultError struct {
+	// Turn is the turn as collected up to the timeout, with Partial set.
+	Turn *Turn
+	// Timeout is the timeout that expired.
+	Timeout time.Duration
+}
+
+func (e *PartialResultError) Error() string {
+	return fmt.Sprintf("timeout after %v waiting for session.idle (%d bytes of partial content)", e.Timeout, len(e.Turn.Content))
+}
+
+// Unwrap returns [context.DeadlineExceeded], so that errors.Is recognizes the
+// error as a timeout.
+func (e *PartialResultError) Unwrap() error {
+	return context.DeadlineExceeded
+}
+
+// TurnFailedError is returned by [Session.SendAndCollect] and
+// [Session.SendAndWait] when the session reports an error during the turn,
+// unless the turn falls back to another model. See [ModelFallbackConfig].
+type TurnFailedError struct {
+	// ErrorType classifies the error, such as "quota".
+	ErrorType string
+	// Message describes the error.
+	Message string
+}
+
+func (e *TurnFailedError) Error() string {
+	return "session error: " + e.Message
+}
+
+// TurnExperiment records the experiment variant a turn was assigned to.
+type TurnExperiment struct {
+	Experiment string
+	Variant    string
+}
+
+// turnCollector accumulates the events of a turn.
+type turnCollector struct {
+	citations func(SessionEvent) []Citation
+	mu        sync.Mutex
+	turn      Turn
+	deltas    strings.Builder
+	// pending is the content streamed since the last assistant.message.
+	pending strings.Builder
+	names   toolCallNames
+}
+
+func newTurnCollector(session *Session) *turnCollector {
+	return &turnCollector{citations: session.Citations, names: make(toolCallNames)}
+}
+
+// add records an event. It is safe to call from the dispatching goroutine while
+// the turn is being read with result.
+func (c *turnCollector) add(event SessionEvent) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.turn.Events = append(c.turn.Events, event)
+	toolName := c.names.track(event)
+	if err := toolCallErrorFromEvent(event, toolName); err != nil {
+		if c.turn.ToolErrors == nil {
+			c.turn.ToolErrors = &MultiToolError{}
+		}
+		c.turn.ToolErrors.Errors = append(c.turn.ToolErrors.Errors, err)
+	}
+	switch event.Type {
+	case AssistantMessage:
+		eventCopy := event
+		c.turn.FinalMessage = &eventCopy
+		c.pending.Reset()
+		c.turn.Citations = append(c.turn.Citations, c.citations(event)...)
+	case AssistantMessageDelta:
+		if event.Data.DeltaContent != nil {
+			c.deltas.WriteString(*event.Data.DeltaContent)
+			c.pending.WriteString(*event.Data.DeltaContent)
+		}
+	case SDKExperimentAssigned:
+		c.turn.Experiment = &TurnExperiment{
+			Experiment: sdkEventString(event, "experiment"),
+			Variant:    sdkEventString(event, "variant"),
+		}
+	case SDKPromptModerated:
+		c.turn.Moderation, _ = PromptModeration(event)
+	case SDKModelFallback:
+		c.turn.FallbackModel = sdkEventString(event, "toModel")
+	}
+}
+
+// result returns a snapshot of the collected turn.
+func (c *turnCollector) result() *Turn {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	turn := c.turn
+	turn.Events = append([]SessionEvent(nil), c.turn.Events...)
+	turn.Citations = append([]Citation(nil), c.turn.Citations...)
+	if c.turn.ToolErrors != nil {
+		turn.ToolErrors = &MultiToolError{Errors: append([]*ToolCallError(nil), c.turn.ToolErrors.Errors...)}
+	}
+	if turn.FinalMessage != nil && turn.FinalMessage.Data.Content != nil {
+		turn.Content = *turn.FinalMessage.Data.Content
+	} else {
+		turn.Content = c.deltas.String()
+	}
+	return &turn
+}
+
+// partialResult returns a snapshot of a turn cut off before it finished, whose
+// content is that of the message streaming at the cutoff, if any.
+func (c *turnCollector) partialResult() *Turn {
+	turn := c.result()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.pending.Len() > 0 {
+		turn.Content = c.pending.String()
+	}
+	turn.Partial = true
+	return turn
+}
+
+// SendAndCollect sends a message to this session, waits until the session becomes
+// idle, and returns everything that happened during the turn.
+//
+// It behaves like [Session.SendAndWait], but instead of only the final assistant
+// message it returns a [Turn] with all events delivered during the turn, the
+// response content, and any citations.
+//
+// Parameters:
+//   - options: The message options including the prompt and optional attachments.
+//   - timeout: How long to wait for completion. Defaults to 60 seconds if zero.
+//     Controls how long to wait; does not abort in-flight agent work.
+//
+// If the timeout expires first, SendAndCollect returns the partial turn along
+// with a [*PartialResultError]. With a [TurnQueueConfig], the timeout also
+// bounds the wait in the queue: a prompt still queued when it expires is
+// canceled with [ErrTurnCanceled].
+//
+// Example:
+//
+//	turn, err := session.SendAndCollect(copilot.MessageOptions{
+//	    Prompt: "Where is the retry logic implemented?",
+//	}, 0)
+//	if err != nil {
+//	    log.Fatal(err)
+//	}
+//	fmt.Println(turn.Content)
+//	for _, c := range turn.Citations {
+//	    fmt.Printf("- %s:%d-%d\n", c.Path, c.StartLine, c.EndLine)
+//	}
+func (s *Session) SendAndCollect(options MessageOptions, timeout time.Duration) (*Turn, error) {
+	if timeout == 0 {
+		timeout = 60 * time.Second
+	}
+
+	var collector atomic.Pointer[turnCollector]
+	collector.Store(newTurnCollector(s))
+	idleCh := make(chan struct{}, 1)
+	errCh := make(chan error, 1)
+
+	// With a turn queue, events dispatched while the message is still queued
+	// belong to the previous turn. A failed turn that may fall back is held in
+	// flight until it is sent again or given up.
+	var started, held atomic.Bool
+	fallbacks := s.hasModelFallback()
+	unsubscribe := s.On(func(event SessionEvent) {
+		if !started.Load() {
+			return
+		}
+		collector.Load().add(event)
+		switch event.Type {
+		case SessionIdle:
+			select {
+			case idleCh <- struct{}{}:
+			default:
+			}
+		case SessionError:
+			if fallbacks && !held.Load() {
+				held.Store(true)
+				s.holdTurn()
+			}
+			select {
+			case errCh <- turnFailedError(event):
+			default:
+			}
+		}
+	})
+	defer unsubscribe()
+	release := func() {
+		if held.Swap(false) {
+			s.releaseTurn()
+		}
+	}
+
+	cancel := make(chan struct{})
+	queueTimer := time.AfterFunc(timeout, func() { close(cancel) })
+	attempt := &modelFallbackAttempt{}
+	onStart := func() { started.Store(true) }
+	messageID, err := s.send(options, onStart, attempt, cancel)
+	queueTimer.Stop()
+	if err != nil {
+		return nil, err
+	}
+
+	deadline := time.After(timeout)
+	// fallback is set while a failed turn waits to become idle before it is
+	// sent again with the next model of the session's fallback chain.
+	var fallback <-chan time.Time
+	for {
+		select {
+		case <-idleCh:
+			if fallback == nil {
+				// An error is signaled before the idle that follows it, but
+				// both may be pending here.
+				select {
+				case err := <-errCh:
+					if !s.nextModelFallback(attempt, err) {
+						release()
+						return nil, err
+					}
+				default:
+					turn := collector.Load().result()
+					turn.MessageID = messageID
+					return turn, nil
+				}
+			}
+		case err := <-errCh:
+			if fallback != nil {
+				continue
+			}
+			if !s.nextModelFallback(attempt, err) {
+				release()
+				return nil, err
+			}
+			fallback = time.After(modelFallbackIdleGrace)
+			continue
+		case <-fallback:
+		case <-deadline:
+			release()
+			turn := collector.Load().partialResult()
+			turn.MessageID = messageID
+			s.emitTurnTruncated(turn, timeout)
+			return turn, &PartialResultError{Turn: turn, Timeout: timeout}
+		}
+
+		fallback = nil
+		started.Store(false)
+		held.Store(false)
+		collector.Store(newTurnCollector(s))
+		if messageID, err = s.resendWithFallback(attempt, onStart); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// turnFailedError returns the error a session.error event reports.
+func turnFailedError(event SessionEvent) *TurnFailedError {
+	err := &TurnFailedError{Message: "session error"}
+	if event.Data.ErrorType != nil {
+		err.ErrorType = *event.Data.ErrorType
+	}
+	if event.Data.Message != nil {
+		err.Message = *event.Data.Message
+	}
+	return err
+}
+
+// emitTurnTruncated dispatches an [SDKTurnTruncated] event for a partial turn.
+func (s *Session) emitTurnTruncated(turn *Turn, timeout time.Duration) {
+	lastEventID := ""
+	if len(turn.Events) > 0 {
+		lastEventID = turn.Events[len(turn.Events)-1].ID
+	}
+	s.emit(SDKTurnTruncated, map[string]interface{}{
+		"messageId":   turn.MessageID,
+		"lastEventId": lastEventID,
+		"eventCount":  len(turn.Events),
+		"content":     turn.Content,
+		"timeoutMs":   timeout.Milliseconds(),
+	})
+}
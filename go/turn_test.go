@@ -0,0 +1,104 @@
+package copilot
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestSession_SendAndCollect(t *testing.T) {
+	t.Run("collects events, content, and citations for the turn", func(t *testing.T) {
+		session, _ := newFakeSession(t, func(server *fakeServer, method string, params map[string]interface{}) (map[string]interface{}, *JSONRPCError) {
+			if method != "session.send" {
+				return nil, &JSONRPCError{Code: -32601, Message: "unexpected method " + method}
+			}
+			go func() {
+				server.emitEvent(AssistantMessageDelta, "e1", map[string]interface{}{"messageId": "m1", "deltaContent": "The answer"})
+				server.emitEvent(AssistantMessage, "e2", map[string]interface{}{
+					"messageId": "m1",
+					"content":   "The answer is in retry.go",
+					"citations": []interface{}{map[string]interface{}{"path": "retry.go", "startLine": 5}},
+				})
+				server.emitEvent(SessionIdle, "e3", map[string]interface{}{})
+			}()
+			return map[string]interface{}{"messageId": "msg-1"}, nil
+		})
+
+		turn, err := session.SendAndCollect(MessageOptions{Prompt: "Where?"}, 5*time.Second)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if turn.MessageID != "msg-1" {
+			t.Errorf("Expected message ID msg-1, got %q", turn.MessageID)
+		}
+		if len(turn.Events) != 3 {
+			t.Errorf("Expected 3 events, got %d", len(turn.Events))
+		}
+		if turn.Content != "The answer is in retry.go" || turn.FinalMessage == nil {
+			t.Errorf("Unexpected content %q", turn.Content)
+		}
+		if len(turn.Citations) != 1 || turn.Citations[0].Path != "retry.go" {
+			t.Errorf("Unexpected citations %+v", turn.Citations)
+		}
+	})
+
+	t.Run("falls back to streamed content and surfaces session errors", func(t *testing.T) {
+		collector := newTurnCollector(&Session{})
+		delta := "partial"
+		collector.add(SessionEvent{Type: AssistantMessageDelta, Data: Data{DeltaContent: &delta}})
+		if turn := collector.result(); turn.Content != "partial" || turn.FinalMessage != nil {
+			t.Errorf("Unexpected turn %+v", turn)
+		}
+
+		session, _ := newFakeSession(t, func(server *fakeServer, method string, params map[string]interface{}) (map[string]interface{}, *JSONRPCError) {
+			go server.emitEvent(SessionError, "e1", map[string]interface{}{"message": "boom"})
+			return map[string]interface{}{"messageId": "msg-1"}, nil
+		})
+		if _, err := session.SendAndCollect(MessageOptions{Prompt: "hi"}, 5*time.Second); err == nil || err.Error() != "session error: boom" {
+			t.Errorf("Expected session error, got %v", err)
+		}
+	})
+
+	t.Run("returns the partial turn on timeout", func(t *testing.T) {
+		session, _ := newFakeSession(t, func(server *fakeServer, method string, params map[string]interface{}) (map[string]interface{}, *JSONRPCError) {
+			go func() {
+				server.emitEvent(AssistantMessage, "e1", map[string]interface{}{"messageId": "m1", "content": "Let me check."})
+				server.emitEvent(AssistantMessageDelta, "e2", map[string]interface{}{"messageId": "m2", "deltaContent": "The answer "})
+				server.emitEvent(AssistantMessageDelta, "e3", map[string]interface{}{"messageId": "m2", "deltaContent": "is"})
+			}()
+			return map[string]interface{}{"messageId": "msg-1"}, nil
+		})
+		truncated := make(chan SessionEvent, 1)
+		session.On(func(event SessionEvent) {
+			if event.Type == SDKTurnTruncated {
+				truncated <- event
+			}
+		})
+
+		turn, err := session.SendAndCollect(MessageOptions{Prompt: "Where?"}, 200*time.Millisecond)
+		var partial *PartialResultError
+		if !errors.As(err, &partial) || !errors.Is(err, context.DeadlineExceeded) {
+			t.Fatalf("Expected a PartialResultError, got %v", err)
+		}
+		if turn == nil || partial.Turn != turn || !turn.Partial || turn.MessageID != "msg-1" {
+			t.Fatalf("Expected the partial turn to be returned, got %+v", turn)
+		}
+		if turn.Content != "The answer is" || len(turn.Events) != 3 || turn.FinalMessage == nil {
+			t.Errorf("Unexpected partial turn content %q with %d events", turn.Content, len(turn.Events))
+		}
+
+		select {
+		case event := <-truncated:
+			if sdkEventString(event, "lastEventId") != "e3" || sdkEventString(event, "content") != "The answer is" {
+				t.Errorf("Unexpected truncation event %+v", event.Data.Metadata.Variables)
+			}
+		default:
+			t.Error("Expected a truncation event")
+		}
+
+		if _, err := session.SendAndWait(MessageOptions{Prompt: "Again"}, 50*time.Millisecond); !errors.As(err, &partial) {
+			t.Errorf("Expected SendAndWait to return a PartialResultError, got %v", err)
+		}
+	})
+}
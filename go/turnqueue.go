@@ -0,0 +1,271 @@
+package copilot
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// ErrTurnQueueFull is returned by [Session.Send] when a turn is in flight and
+// the session's turn queue already holds [TurnQueueConfig.MaxDepth] prompts.
+var ErrTurnQueueFull = errors.New("turn queue is full")
+
+// ErrTurnCanceled is returned by [Session.Send] when a queued prompt is canceled
+// before it is sent, including when the connection closes or, for
+// [Session.SendAndCollect] and [Session.SendAndWait], the timeout elapses while
+// it is queued.
+var ErrTurnCanceled = errors.New("queued turn canceled")
+
+// TurnQueueConfig enables client-side turn serialization for a session.
+//
+// With a turn queue, a turn is in flight from the moment its prompt is sent
+// until the session next becomes idle or reports a session.error. A
+// [Session.Send] made while a turn is in flight does not reach the server: the
+// prompt is queued, an [SDKTurnQueued] event is dispatched, and Send blocks
+// until the prompt is sent or canceled. Queued prompts are sent one at a time
+// in the order they were queued. A turn that fails and is sent again with a
+// fallback model (see [ModelFallbackConfig]) stays in flight for the new
+// attempt.
+//
+// Example:
+//
+//	session, err := client.CreateSession(&copilot.SessionConfig{
+//	    TurnQueue: &copilot.TurnQueueConfig{MaxDepth: 5},
+//	})
+//
+//	// Type-ahead from a chat UI: each prompt waits for the previous turn.
+//	go session.SendAndCollect(copilot.MessageOptions{Prompt: "First question"}, 0)
+//	go session.SendAndCollect(copilot.MessageOptions{Prompt: "Follow-up"}, 0)
+type TurnQueueConfig struct {
+	// MaxDepth is the maximum number of prompts that may wait in the queue.
+	// Zero means the queue is unbounded.
+	MaxDepth int
+}
+
+// QueuedTurn describes a prompt waiting in a session's turn queue.
+type QueuedTurn struct {
+	// ID identifies the queued prompt for [Session.CancelQueuedTurn].
+	ID string
+	// Prompt is the queued prompt.
+	Prompt string
+	// QueuedAt is when the prompt was queued.
+	QueuedAt time.Time
+}
+
+type queuedTurn struct {
+	QueuedTurn
+	ready chan error
+}
+
+// turnQueue serializes the turns of a session.
+type turnQueue struct {
+	mu       sync.Mutex
+	enabled  bool
+	maxDepth int
+	busy     bool
+	// held is set while the turn in flight has failed and waits to be sent
+	// again with a fallback model, which keeps it in flight.
+	held bool
+	// failed is set once a session.error ends a turn, until the session.idle
+	// that follows it, which must not end the next turn too.
+	failed  bool
+	waiting []*queuedTurn
+}
+
+// registerTurnQueue enables turn serialization for this session.
+func (s *Session) registerTurnQueue(config *TurnQueueConfig) {
+	if config == nil {
+		return
+	}
+	s.turns.mu.Lock()
+	defer s.turns.mu.Unlock()
+	s.turns.enabled = true
+	s.turns.maxDepth = config.MaxDepth
+}
+
+// acquireTurn waits until the session can start a new turn, or until cancel
+// is closed or the session's connection closes. It returns immediately if
+// turn queuing is disabled.
+func (s *Session) acquireTurn(prompt string, cancel <-chan struct{}) error {
+	q := &s.turns
+	q.mu.Lock()
+	if !q.enabled {
+		q.mu.Unlock()
+		return nil
+	}
+	if !q.busy {
+		q.busy = true
+		q.mu.Unlock()
+		return nil
+	}
+	if q.maxDepth > 0 && len(q.waiting) >= q.maxDepth {
+		q.mu.Unlock()
+		return ErrTurnQueueFull
+	}
+	entry := &queuedTurn{
+		QueuedTurn: QueuedTurn{ID: generateUUID(), Prompt: prompt, QueuedAt: time.Now()},
+		ready:      make(chan error, 1),
+	}
+	q.waiting = append(q.waiting, entry)
+	position := len(q.waiting)
+	q.mu.Unlock()
+
+	s.emit(SDKTurnQueued, map[string]interface{}{
+		"queueId":  entry.ID,
+		"prompt":   prompt,
+		"position": position,
+	})
+
+	var closed <-chan struct{}
+	if s.client != nil {
+		closed = s.client.Done()
+	}
+	select {
+	case err := <-entry.ready:
+		return err
+	case <-cancel:
+		return s.abandonQueuedTurn(entry, fmt.Errorf("%w: timed out while queued", ErrTurnCanceled))
+	case <-closed:
+		return s.abandonQueuedTurn(entry, fmt.Errorf("%w: connection closed", ErrTurnCanceled))
+	}
+}
+
+// abandonQueuedTurn removes entry from the queue and returns err, unless it
+// was handed the session or canceled meanwhile. A session handed over is
+// passed on, since entry's prompt will not be sent.
+func (s *Session) abandonQueuedTurn(entry *queuedTurn, err error) error {
+	q := &s.turns
+	q.mu.Lock()
+	for i, waiting := range q.waiting {
+		if waiting == entry {
+			q.waiting = append(q.waiting[:i], q.waiting[i+1:]...)
+			q.mu.Unlock()
+			return err
+		}
+	}
+	q.mu.Unlock()
+	if readyErr := <-entry.ready; readyErr != nil {
+		return readyErr
+	}
+	s.finishTurn()
+	return err
+}
+
+// finishTurn ends the turn in flight and hands the session to the next queued
+// prompt, if any.
+func (s *Session) finishTurn() {
+	q := &s.turns
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.finishLocked()
+}
+
+func (q *turnQueue) finishLocked() {
+	if !q.enabled || !q.busy || q.held {
+		return
+	}
+	if len(q.waiting) == 0 {
+		q.busy = false
+		return
+	}
+	next := q.waiting[0]
+	q.waiting = q.waiting[1:]
+	next.ready <- nil
+}
+
+// trackTurnEnd ends the turn in flight when event reports that it is over.
+func (s *Session) trackTurnEnd(event SessionEvent) {
+	q := &s.turns
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	switch event.Type {
+	case SessionError:
+		q.failed = true
+		q.finishLocked()
+	case SessionIdle:
+		if q.failed {
+			q.failed = false
+			return
+		}
+		q.finishLocked()
+	case UserMessage:
+		// The next turn has started, so the failed one's idle event was not
+		// sent.
+		q.failed = false
+	}
+}
+
+// holdTurn keeps the turn in flight past its end, for it to be sent again
+// with a fallback model by [Session.resumeTurn], or ended by
+// [Session.releaseTurn].
+func (s *Session) holdTurn() {
+	q := &s.turns
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.enabled && q.busy {
+		q.held = true
+	}
+}
+
+// resumeTurn takes over the held turn for its next attempt, which ends the
+// turn as usual.
+func (s *Session) resumeTurn() {
+	q := &s.turns
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.held = false
+}
+
+// releaseTurn ends the held turn, if any, when it is not sent again.
+func (s *Session) releaseTurn() {
+	q := &s.turns
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.held {
+		q.held = false
+		q.finishLocked()
+	}
+}
+
+// QueuedTurns returns the prompts waiting in this session's turn queue, in the
+// order they will be sent. It is empty unless the session was created with a
+// [TurnQueueConfig].
+func (s *Session) QueuedTurns() []QueuedTurn {
+	s.turns.mu.Lock()
+	defer s.turns.mu.Unlock()
+	turns := make([]QueuedTurn, len(s.turns.waiting))
+	for i, entry := range s.turns.waiting {
+		turns[i] = entry.QueuedTurn
+	}
+	return turns
+}
+
+// CancelQueuedTurn removes a prompt from the turn queue. The [Session.Send]
+// call that queued it returns [ErrTurnCanceled]. It reports whether the prompt
+// was still queued.
+func (s *Session) CancelQueuedTurn(id string) bool {
+	s.turns.mu.Lock()
+	defer s.turns.mu.Unlock()
+	for i, entry := range s.turns.waiting {
+		if entry.ID == id {
+			s.turns.waiting = append(s.turns.waiting[:i], s.turns.waiting[i+1:]...)
+			entry.ready <- ErrTurnCanceled
+			return true
+		}
+	}
+	return false
+}
+
+// ClearTurnQueue cancels every queued prompt and returns how many were canceled.
+// The turn in flight, if any, is not affected; use [Session.Abort] to stop it.
+func (s *Session) ClearTurnQueue() int {
+	s.turns.mu.Lock()
+	defer s.turns.mu.Unlock()
+	canceled := len(s.turns.waiting)
+	for _, entry := range s.turns.waiting {
+		entry.ready <- ErrTurnCanceled
+	}
+	s.turns.waiting = nil
+	return canceled
+}
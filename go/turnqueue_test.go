@@ -0,0 +1,347 @@
+package copilot
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"testing"
+	"time"
+)
+
+// newQueuedSession returns a fake session with a turn queue whose server records
+// the prompts it receives without ending the turn.
+func newQueuedSession(t *testing.T, config *TurnQueueConfig) (*Session, *fakeServer, func() []string, <-chan SessionEvent) {
+	t.Helper()
+	var mu sync.Mutex
+	var prompts []string
+	session, server := newFakeSession(t, func(server *fakeServer, method string, params map[string]interface{}) (map[string]interface{}, *JSONRPCError) {
+		mu.Lock()
+		defer mu.Unlock()
+		prompts = append(prompts, params["prompt"].(string))
+		return map[string]interface{}{"messageId": fmt.Sprintf("msg-%d", len(prompts))}, nil
+	})
+	session.registerTurnQueue(config)
+
+	queued := make(chan SessionEvent, 10)
+	session.On(func(event SessionEvent) {
+		if event.Type == SDKTurnQueued {
+			queued <- event
+		}
+	})
+
+	sent := func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]string(nil), prompts...)
+	}
+	return session, server, sent, queued
+}
+
+func waitForQueued(t *testing.T, queued <-chan SessionEvent) SessionEvent {
+	t.Helper()
+	select {
+	case event := <-queued:
+		return event
+	case <-time.After(5 * time.Second):
+		t.Fatal("Timed out waiting for turn to be queued")
+		return SessionEvent{}
+	}
+}
+
+func TestTurnQueue(t *testing.T) {
+	t.Run("sends queued prompts after the session becomes idle", func(t *testing.T) {
+		session, server, sent, queued := newQueuedSession(t, &TurnQueueConfig{})
+
+		if _, err := session.Send(MessageOptions{Prompt: "first"}); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		result := make(chan string, 1)
+		go func() {
+			messageID, err := session.Send(MessageOptions{Prompt: "second"})
+			if err != nil {
+				t.Errorf("Unexpected error: %v", err)
+			}
+			result <- messageID
+		}()
+
+		event := waitForQueued(t, queued)
+		if sdkEventString(event, "prompt") != "second" || event.Data.Metadata.Variables["position"] != 1 {
+			t.Errorf("Unexpected queued event %+v", event.Data.Metadata.Variables)
+		}
+		if got := session.QueuedTurns(); len(got) != 1 || got[0].ID != sdkEventString(event, "queueId") {
+			t.Errorf("Expected the queued prompt to be listed, got %+v", got)
+		}
+		if got := sent(); len(got) != 1 {
+			t.Fatalf("Expected the queued prompt not to be sent yet, got %v", got)
+		}
+
+		server.emitEvent(SessionIdle, "e1", map[string]interface{}{})
+		select {
+		case messageID := <-result:
+			if messageID != "msg-2" {
+				t.Errorf("Expected msg-2, got %q", messageID)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("Timed out waiting for the queued prompt to be sent")
+		}
+		if got := sent(); len(got) != 2 || got[1] != "second" {
+			t.Errorf("Expected prompts in order, got %v", got)
+		}
+	})
+
+	t.Run("rejects prompts beyond the max depth", func(t *testing.T) {
+		session, _, _, queued := newQueuedSession(t, &TurnQueueConfig{MaxDepth: 1})
+
+		session.Send(MessageOptions{Prompt: "first"})
+		go session.Send(MessageOptions{Prompt: "second"})
+		waitForQueued(t, queued)
+
+		if _, err := session.Send(MessageOptions{Prompt: "third"}); !errors.Is(err, ErrTurnQueueFull) {
+			t.Errorf("Expected ErrTurnQueueFull, got %v", err)
+		}
+		session.ClearTurnQueue()
+	})
+
+	t.Run("canceled prompts are never sent", func(t *testing.T) {
+		session, server, sent, queued := newQueuedSession(t, &TurnQueueConfig{})
+
+		session.Send(MessageOptions{Prompt: "first"})
+		errCh := make(chan error, 1)
+		go func() {
+			_, err := session.Send(MessageOptions{Prompt: "second"})
+			errCh <- err
+		}()
+		event := waitForQueued(t, queued)
+
+		if !session.CancelQueuedTurn(sdkEventString(event, "queueId")) {
+			t.Fatal("Expected the queued prompt to be canceled")
+		}
+		if session.CancelQueuedTurn(sdkEventString(event, "queueId")) {
+			t.Error("Expected a second cancel to report false")
+		}
+		if err := <-errCh; !errors.Is(err, ErrTurnCanceled) {
+			t.Errorf("Expected ErrTurnCanceled, got %v", err)
+		}
+
+		server.emitEvent(SessionIdle, "e1", map[string]interface{}{})
+		if _, err := session.Send(MessageOptions{Prompt: "third"}); err != nil {
+			t.Fatalf("Expected the session to be free after idle, got %v", err)
+		}
+		if got := sent(); len(got) != 2 || got[1] != "third" {
+			t.Errorf("Expected the canceled prompt to be skipped, got %v", got)
+		}
+	})
+
+	t.Run("destroy releases queued prompts when the server fails", func(t *testing.T) {
+		session, _ := newFakeSession(t, func(server *fakeServer, method string, params map[string]interface{}) (map[string]interface{}, *JSONRPCError) {
+			if method == "session.destroy" {
+				return nil, &JSONRPCError{Code: -32000, Message: "unavailable"}
+			}
+			return map[string]interface{}{"messageId": "msg-1"}, nil
+		})
+		session.registerTurnQueue(&TurnQueueConfig{})
+		queued := make(chan SessionEvent, 1)
+		session.On(func(event SessionEvent) {
+			if event.Type == SDKTurnQueued {
+				queued <- event
+			}
+		})
+
+		session.Send(MessageOptions{Prompt: "first"})
+		errCh := make(chan error, 1)
+		go func() {
+			_, err := session.Send(MessageOptions{Prompt: "second"})
+			errCh <- err
+		}()
+		waitForQueued(t, queued)
+
+		if err := session.Destroy(); err == nil {
+			t.Fatal("Expected the destroy to fail")
+		}
+		select {
+		case err := <-errCh:
+			if !errors.Is(err, ErrTurnCanceled) {
+				t.Errorf("Expected ErrTurnCanceled, got %v", err)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("Timed out waiting for the queued prompt to be released")
+		}
+	})
+
+	t.Run("collects only the events of its own turn", func(t *testing.T) {
+		session, server, sent, queued := newQueuedSession(t, &TurnQueueConfig{})
+
+		session.Send(MessageOptions{Prompt: "first"})
+		result := make(chan *Turn, 1)
+		go func() {
+			turn, err := session.SendAndCollect(MessageOptions{Prompt: "second"}, 5*time.Second)
+			if err != nil {
+				t.Errorf("Unexpected error: %v", err)
+			}
+			result <- turn
+		}()
+		waitForQueued(t, queued)
+
+		server.emitEvent(AssistantMessage, "a1", map[string]interface{}{"content": "first answer"})
+		server.emitEvent(SessionIdle, "e1", map[string]interface{}{})
+		for deadline := time.Now().Add(5 * time.Second); len(sent()) < 2; time.Sleep(time.Millisecond) {
+			if time.Now().After(deadline) {
+				t.Fatal("Timed out waiting for the queued prompt to be sent")
+			}
+		}
+		server.emitEvent(AssistantMessage, "a2", map[string]interface{}{"content": "second answer"})
+		server.emitEvent(SessionIdle, "e2", map[string]interface{}{})
+
+		select {
+		case turn := <-result:
+			if turn == nil || turn.Content != "second answer" || turn.MessageID != "msg-2" {
+				t.Errorf("Expected the second turn, got %+v", turn)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("Timed out waiting for the queued turn")
+		}
+	})
+
+	t.Run("a failed turn ends the turn in flight", func(t *testing.T) {
+		session, server, sent, queued := newQueuedSession(t, &TurnQueueConfig{})
+
+		session.Send(MessageOptions{Prompt: "first"})
+		result := make(chan error, 1)
+		go func() {
+			_, err := session.Send(MessageOptions{Prompt: "second"})
+			result <- err
+		}()
+		waitForQueued(t, queued)
+
+		server.emitEvent(SessionError, "e1", map[string]interface{}{"message": "boom"})
+		select {
+		case err := <-result:
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("Timed out waiting for the queued prompt to be sent")
+		}
+		if got := sent(); len(got) != 2 || got[1] != "second" {
+			t.Errorf("Expected the queued prompt to be sent, got %v", got)
+		}
+
+		// The failed turn's idle event must not end the second turn too.
+		server.emitEvent(SessionIdle, "e2", map[string]interface{}{})
+		go session.Send(MessageOptions{Prompt: "third"})
+		waitForQueued(t, queued)
+		session.ClearTurnQueue()
+	})
+
+	t.Run("a queued prompt is canceled when the timeout elapses", func(t *testing.T) {
+		session, _, sent, _ := newQueuedSession(t, &TurnQueueConfig{})
+
+		session.Send(MessageOptions{Prompt: "first"})
+		if _, err := session.SendAndCollect(MessageOptions{Prompt: "second"}, 20*time.Millisecond); !errors.Is(err, ErrTurnCanceled) {
+			t.Errorf("Expected ErrTurnCanceled, got %v", err)
+		}
+		if got := session.QueuedTurns(); len(got) != 0 {
+			t.Errorf("Expected the prompt to leave the queue, got %+v", got)
+		}
+		if got := sent(); len(got) != 1 {
+			t.Errorf("Expected the canceled prompt not to be sent, got %v", got)
+		}
+	})
+
+	t.Run("queued prompts are released when the connection closes", func(t *testing.T) {
+		session, server, _, queued := newQueuedSession(t, &TurnQueueConfig{})
+
+		session.Send(MessageOptions{Prompt: "first"})
+		errCh := make(chan error, 1)
+		go func() {
+			_, err := session.Send(MessageOptions{Prompt: "second"})
+			errCh <- err
+		}()
+		waitForQueued(t, queued)
+
+		server.writer.(io.Closer).Close()
+		select {
+		case err := <-errCh:
+			if !errors.Is(err, ErrTurnCanceled) {
+				t.Errorf("Expected ErrTurnCanceled, got %v", err)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("Timed out waiting for the queued prompt to be released")
+		}
+	})
+
+	t.Run("a turn that falls back to another model stays in flight", func(t *testing.T) {
+		var mu sync.Mutex
+		var sends []string
+		session, server := newFakeSession(t, func(server *fakeServer, method string, params map[string]interface{}) (map[string]interface{}, *JSONRPCError) {
+			mu.Lock()
+			defer mu.Unlock()
+			model, _ := params["model"].(string)
+			sends = append(sends, params["prompt"].(string)+"@"+model)
+			return map[string]interface{}{"messageId": fmt.Sprintf("msg-%d", len(sends))}, nil
+		})
+		session.registerTurnQueue(&TurnQueueConfig{})
+		session.registerModelFallback(&ModelFallbackConfig{Chain: []ModelFallback{{Model: "model-b"}}}, "")
+		queued := make(chan SessionEvent, 1)
+		session.On(func(event SessionEvent) {
+			if event.Type == SDKTurnQueued {
+				queued <- event
+			}
+		})
+		waitForSends := func(n int) []string {
+			t.Helper()
+			for deadline := time.Now().Add(5 * time.Second); ; time.Sleep(time.Millisecond) {
+				mu.Lock()
+				got := append([]string(nil), sends...)
+				mu.Unlock()
+				if len(got) >= n {
+					return got
+				}
+				if time.Now().After(deadline) {
+					t.Fatalf("Timed out waiting for %d sends, got %v", n, got)
+				}
+			}
+		}
+
+		result := make(chan *Turn, 1)
+		go func() {
+			turn, err := session.SendAndCollect(MessageOptions{Prompt: "first"}, 5*time.Second)
+			if err != nil {
+				t.Errorf("Unexpected error: %v", err)
+			}
+			result <- turn
+		}()
+		waitForSends(1)
+		second := make(chan error, 1)
+		go func() {
+			_, err := session.Send(MessageOptions{Prompt: "second"})
+			second <- err
+		}()
+		waitForQueued(t, queued)
+
+		server.emitEvent(SessionError, "e1", map[string]interface{}{"errorType": "quota", "message": "quota exhausted"})
+		server.emitEvent(SessionIdle, "e2", map[string]interface{}{})
+		if got := waitForSends(2); got[1] != "first@model-b" {
+			t.Fatalf("Expected the failed turn to be sent again before the queued prompt, got %v", got)
+		}
+
+		server.emitEvent(AssistantMessage, "e3", map[string]interface{}{"content": "from model-b"})
+		server.emitEvent(SessionIdle, "e4", map[string]interface{}{})
+		select {
+		case turn := <-result:
+			if turn == nil || turn.Content != "from model-b" {
+				t.Errorf("Unexpected turn %+v", turn)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("Timed out waiting for the fallback turn")
+		}
+		if err := <-second; err != nil {
+			t.Errorf("Unexpected error: %v", err)
+		}
+		if got := waitForSends(3); got[2] != "second@" {
+			t.Errorf("Expected the queued prompt after the fallback turn, got %v", got)
+		}
+	})
+}
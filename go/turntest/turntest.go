@@ -0,0 +1,471 @@
+// Package turntest compares the [copilot.Turn] of an agent flow against an
+// expectation of the tools the agent calls and the message it replies with,
+// reporting differences as a readable diff. It makes behavioral tests of agent
+// flows practical to maintain:
+//
+//	func TestTriage(t *testing.T) {
+//	    turn, err := session.SendAndCollect(copilot.MessageOptions{Prompt: "Triage issue #42"}, 0)
+//	    if err != nil {
+//	        t.Fatal(err)
+//	    }
+//	    turntest.Assert(t, turn, turntest.Expectation{
+//	        ToolCalls: []turntest.ToolCall{
+//	            {Name: "get_issue", Arguments: turntest.JSONPath("$.number", turntest.Equals("42"))},
+//	            {Name: "add_label"},
+//	        },
+//	        Message: []turntest.Matcher{turntest.Contains("bug"), turntest.Regex(`(?i)label(l)?ed`)},
+//	    })
+//	}
+//
+// Expectations can also be kept in golden files with [AssertGolden], and
+// recorded from a turn by running the tests with COPILOT_UPDATE_GOLDEN=1.
+package turntest
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+
+	copilot "github.com/github/copilot-sdk/go"
+)
+
+// UpdateGoldenEnv is the environment variable that makes [AssertGolden] write
+// the expectation of the turn it is given instead of comparing against it.
+const UpdateGoldenEnv = "COPILOT_UPDATE_GOLDEN"
+
+// Matcher matches text: the content of a turn's message, or the arguments of a
+// tool call encoded as JSON. Create it with [Equals], [Contains], [Regex], or
+// [JSONPath]. Matchers encode as JSON objects such as {"contains": "bug"}, so
+// that they can be kept in golden files.
+type Matcher struct {
+	kind  string
+	value string
+	re    *regexp.Regexp
+	inner *Matcher
+}
+
+const (
+	matchEquals   = "equals"
+	matchContains = "contains"
+	matchRegex    = "regex"
+	matchJSONPath = "jsonPath"
+)
+
+// Equals matches text equal to s.
+func Equals(s string) Matcher {
+	return Matcher{kind: matchEquals, value: s}
+}
+
+// Contains matches text that contains s.
+func Contains(s string) Matcher {
+	return Matcher{kind: matchContains, value: s}
+}
+
+// Regex matches text that pattern matches. It panics if pattern does not
+// compile.
+func Regex(pattern string) Matcher {
+	return Matcher{kind: matchRegex, value: pattern, re: regexp.MustCompile(pattern)}
+}
+
+// JSONPath matches JSON text whose value at path m matches. String values are
+// matched as they are, and other values as compact JSON, so a number is
+// matched by Equals("42"). Paths are written as $.field.list[0].field, and
+// the leading $ is optional.
+func JSONPath(path string, m Matcher) Matcher {
+	return Matcher{kind: matchJSONPath, value: path, inner: &m}
+}
+
+// Match returns nil if text matches, or an error describing why it does not.
+func (m Matcher) Match(text string) error {
+	switch m.kind {
+	case matchEquals:
+		if text != m.value {
+			return fmt.Errorf("want %s, got %s", strconv.Quote(m.value), quoteExcerpt(text))
+		}
+	case matchContains:
+		if !strings.Contains(text, m.value) {
+			return fmt.Errorf("want text containing %s, got %s", strconv.Quote(m.value), quoteExcerpt(text))
+		}
+	case matchRegex:
+		if !m.re.MatchString(text) {
+			return fmt.Errorf("want text matching /%s/, got %s", m.value, quoteExcerpt(text))
+		}
+	case matchJSONPath:
+		var document interface{}
+		if err := json.Unmarshal([]byte(text), &document); err != nil {
+			return fmt.Errorf("want JSON for %s, got %s", m.value, quoteExcerpt(text))
+		}
+		value, err := lookupPath(document, m.value)
+		if err != nil {
+			return err
+		}
+		if err := m.inner.Match(value); err != nil {
+			return fmt.Errorf("at %s: %w", m.value, err)
+		}
+	default:
+		return errors.New("empty matcher")
+	}
+	return nil
+}
+
+// String describes the matcher, as in contains "bug".
+func (m Matcher) String() string {
+	switch m.kind {
+	case matchRegex:
+		return "matches /" + m.value + "/"
+	case matchJSONPath:
+		return m.value + " " + m.inner.String()
+	case "":
+		return "empty matcher"
+	}
+	return m.kind + " " + strconv.Quote(m.value)
+}
+
+// MarshalJSON encodes the matcher as {"equals": s}, {"contains": s},
+// {"regex": pattern}, or {"jsonPath": path, "match": matcher}.
+func (m Matcher) MarshalJSON() ([]byte, error) {
+	if m.kind == "" {
+		return nil, errors.New("turntest: cannot encode an empty matcher")
+	}
+	object := map[string]interface{}{m.kind: m.value}
+	if m.inner != nil {
+		object["match"] = m.inner
+	}
+	return json.Marshal(object)
+}
+
+// UnmarshalJSON decodes a matcher encoded by MarshalJSON.
+func (m *Matcher) UnmarshalJSON(data []byte) error {
+	var object map[string]json.RawMessage
+	if err := json.Unmarshal(data, &object); err != nil {
+		return fmt.Errorf("turntest: matcher must be an object: %w", err)
+	}
+	var kinds []string
+	for _, kind := range []string{matchEquals, matchContains, matchRegex, matchJSONPath} {
+		if _, ok := object[kind]; ok {
+			kinds = append(kinds, kind)
+		}
+	}
+	if len(kinds) != 1 {
+		return fmt.Errorf("turntest: matcher must have exactly one of equals, contains, regex, or jsonPath, got %s", data)
+	}
+	var value string
+	if err := json.Unmarshal(object[kinds[0]], &value); err != nil {
+		return fmt.Errorf("turntest: %s must be a string: %w", kinds[0], err)
+	}
+
+	switch kinds[0] {
+	case matchRegex:
+		re, err := regexp.Compile(value)
+		if err != nil {
+			return fmt.Errorf("turntest: invalid regex: %w", err)
+		}
+		*m = Matcher{kind: matchRegex, value: value, re: re}
+	case matchJSONPath:
+		raw, ok := object["match"]
+		if !ok {
+			return fmt.Errorf("turntest: jsonPath %s has no match", value)
+		}
+		var inner Matcher
+		if err := json.Unmarshal(raw, &inner); err != nil {
+			return err
+		}
+		*m = JSONPath(value, inner)
+	default:
+		*m = Matcher{kind: kinds[0], value: value}
+	}
+	return nil
+}
+
+// ToolCall is a tool call an [Expectation] expects.
+type ToolCall struct {
+	// Name is the name of the tool.
+	Name string `json:"name"`
+	// Arguments, if set, matches the call's arguments encoded as JSON.
+	Arguments *Matcher `json:"arguments,omitempty"`
+	// Failed, if set, is whether the call must have failed.
+	Failed *bool `json:"failed,omitempty"`
+}
+
+// Expectation is what a turn is expected to do. Unset fields are not checked.
+type Expectation struct {
+	// ToolCalls are the tool calls the turn makes, in the order they start.
+	// Nil does not check tool calls; an empty slice expects none.
+	ToolCalls []ToolCall `json:"toolCalls"`
+	// Message are matchers the turn's Content must all match.
+	Message []Matcher `json:"message,omitempty"`
+	// NoToolErrors, if set, expects no tool call of the turn to fail.
+	NoToolErrors bool `json:"noToolErrors,omitempty"`
+}
+
+// observedCall is a tool call a turn made.
+type observedCall struct {
+	name      string
+	arguments string
+	failed    bool
+}
+
+func (c observedCall) String() string {
+	s := c.name + "(" + excerpt(c.arguments) + ")"
+	if c.failed {
+		s += " [failed]"
+	}
+	return s
+}
+
+// toolCalls returns the tool calls of a turn, in the order they started.
+func toolCalls(turn *copilot.Turn) []observedCall {
+	var calls []observedCall
+	index := make(map[string]int)
+	for _, event := range turn.Events {
+		var id string
+		if event.Data.ToolCallID != nil {
+			id = *event.Data.ToolCallID
+		}
+		switch event.Type {
+		case copilot.ToolExecutionStart:
+			call := observedCall{arguments: "null"}
+			if event.Data.ToolName != nil {
+				call.name = *event.Data.ToolName
+			}
+			if data, err := json.Marshal(event.Data.Arguments); err == nil {
+				call.arguments = string(data)
+			}
+			if id != "" {
+				index[id] = len(calls)
+			}
+			calls = append(calls, call)
+		case copilot.ToolExecutionComplete:
+			if i, ok := index[id]; ok && event.Data.Success != nil && !*event.Data.Success {
+				calls[i].failed = true
+			}
+		}
+	}
+	return calls
+}
+
+// Diff returns a readable description of how turn differs from want, or ""
+// if it matches.
+func Diff(turn *copilot.Turn, want Expectation) string {
+	var b strings.Builder
+	calls := toolCalls(turn)
+
+	if want.ToolCalls != nil {
+		var lines []string
+		mismatch := false
+		for i := 0; i < len(want.ToolCalls) || i < len(calls); i++ {
+			switch {
+			case i >= len(calls):
+				mismatch = true
+				lines = append(lines, fmt.Sprintf("  - %d: missing %s", i+1, describeCall(want.ToolCalls[i])))
+			case i >= len(want.ToolCalls):
+				mismatch = true
+				lines = append(lines, fmt.Sprintf("  + %d: unexpected %s", i+1, calls[i]))
+			default:
+				if problem := compareCall(want.ToolCalls[i], calls[i]); problem != "" {
+					mismatch = true
+					lines = append(lines, fmt.Sprintf("  ~ %d: %s: %s", i+1, calls[i], problem))
+				} else {
+					lines = append(lines, fmt.Sprintf("    %d: %s", i+1, calls[i]))
+				}
+			}
+		}
+		if mismatch {
+			fmt.Fprintf(&b, "tool calls differ (- missing, + unexpected, ~ mismatched):\n%s\n", strings.Join(lines, "\n"))
+		}
+	}
+
+	if want.NoToolErrors {
+		for i, call := range calls {
+			if call.failed {
+				fmt.Fprintf(&b, "tool call %d failed: %s\n", i+1, call)
+			}
+		}
+	}
+
+	for _, m := range want.Message {
+		if err := m.Match(turn.Content); err != nil {
+			fmt.Fprintf(&b, "message: %v\n", err)
+		}
+	}
+	return strings.TrimSuffix(b.String(), "\n")
+}
+
+// describeCall describes an expected tool call.
+func describeCall(call ToolCall) string {
+	s := call.Name
+	if call.Arguments != nil {
+		s += " with arguments " + call.Arguments.String()
+	}
+	if call.Failed != nil && *call.Failed {
+		s += " [failed]"
+	}
+	return s
+}
+
+// compareCall returns how a tool call differs from its expectation, or "".
+func compareCall(want ToolCall, got observedCall) string {
+	var problems []string
+	if got.name != want.Name {
+		problems = append(problems, fmt.Sprintf("want tool %s", want.Name))
+	} else if want.Arguments != nil {
+		if err := want.Arguments.Match(got.arguments); err != nil {
+			problems = append(problems, "arguments: "+err.Error())
+		}
+	}
+	if want.Failed != nil && *want.Failed != got.failed {
+		if *want.Failed {
+			problems = append(problems, "want the call to fail")
+		} else {
+			problems = append(problems, "want the call to succeed")
+		}
+	}
+	return strings.Join(problems, "; ")
+}
+
+// Compare returns an error describing how turn differs from want, or nil if
+// it matches.
+func Compare(turn *copilot.Turn, want Expectation) error {
+	if diff := Diff(turn, want); diff != "" {
+		return errors.New("turntest: turn does not match expectation:\n" + diff)
+	}
+	return nil
+}
+
+// Assert runs [Compare] and reports the difference to t, returning whether
+// the turn matches.
+func Assert(t testing.TB, turn *copilot.Turn, want Expectation) bool {
+	t.Helper()
+	if err := Compare(turn, want); err != nil {
+		t.Error(err)
+		return false
+	}
+	return true
+}
+
+// Record returns the exact expectation of a turn: its tool calls, with their
+// arguments and whether they failed, and its message content. Edit the
+// expectation, such as to loosen Equals matchers to Contains, before committing
+// it as a golden file.
+func Record(turn *copilot.Turn) Expectation {
+	expectation := Expectation{ToolCalls: []ToolCall{}}
+	for _, call := range toolCalls(turn) {
+		arguments := Equals(call.arguments)
+		expected := ToolCall{Name: call.name, Arguments: &arguments}
+		if call.failed {
+			failed := true
+			expected.Failed = &failed
+		}
+		expectation.ToolCalls = append(expectation.ToolCalls, expected)
+	}
+	if turn.Content != "" {
+		expectation.Message = []Matcher{Equals(turn.Content)}
+	}
+	return expectation
+}
+
+// LoadExpectation reads an expectation from a JSON golden file.
+func LoadExpectation(path string) (Expectation, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Expectation{}, fmt.Errorf("turntest: failed to read expectation: %w", err)
+	}
+	var expectation Expectation
+	if err := json.Unmarshal(data, &expectation); err != nil {
+		return Expectation{}, fmt.Errorf("turntest: failed to decode expectation %s: %w", path, err)
+	}
+	return expectation, nil
+}
+
+// AssertGolden compares turn against the expectation in the JSON golden file
+// at path, like [Assert]. With the environment variable [UpdateGoldenEnv] set,
+// it instead writes the expectation [Record] returns to path, creating its
+// directory, and the test passes.
+func AssertGolden(t testing.TB, turn *copilot.Turn, path string) bool {
+	t.Helper()
+	if os.Getenv(UpdateGoldenEnv) != "" {
+		data, err := json.MarshalIndent(Record(turn), "", "  ")
+		if err == nil {
+			err = os.MkdirAll(filepath.Dir(path), 0o755)
+		}
+		if err == nil {
+			err = os.WriteFile(path, append(data, '\n'), 0o644)
+		}
+		if err != nil {
+			t.Fatalf("turntest: failed to write golden file: %v", err)
+		}
+		return true
+	}
+	want, err := LoadExpectation(path)
+	if err != nil {
+		t.Fatalf("%v (run with %s=1 to create it)", err, UpdateGoldenEnv)
+	}
+	if err := Compare(turn, want); err != nil {
+		t.Errorf("%v\n(golden file %s; run with %s=1 to update it)", err, path, UpdateGoldenEnv)
+		return false
+	}
+	return true
+}
+
+var pathSegment = regexp.MustCompile(`^(?:\.([^.\[\]]+)|\[(\d+)\]|\['([^']*)'\])`)
+
+// lookupPath returns the value at path in document, as a string for string
+// values and compact JSON for others.
+func lookupPath(document interface{}, path string) (string, error) {
+	rest := strings.TrimPrefix(path, "$")
+	if rest != "" && rest[0] != '.' && rest[0] != '[' {
+		rest = "." + rest
+	}
+	value := document
+	for traversed := "$"; rest != ""; {
+		match := pathSegment.FindStringSubmatch(rest)
+		if match == nil {
+			return "", fmt.Errorf("invalid JSON path %s", path)
+		}
+		rest = rest[len(match[0]):]
+		switch {
+		case match[2] != "":
+			items, ok := value.([]interface{})
+			i, _ := strconv.Atoi(match[2])
+			if !ok || i >= len(items) {
+				return "", fmt.Errorf("%s has no element %d", traversed, i)
+			}
+			value = items[i]
+		default:
+			key := match[1] + match[3]
+			object, ok := value.(map[string]interface{})
+			if _, present := object[key]; !ok || !present {
+				return "", fmt.Errorf("%s has no field %s", traversed, key)
+			}
+			value = object[key]
+		}
+		traversed += match[0]
+	}
+	if s, ok := value.(string); ok {
+		return s, nil
+	}
+	data, err := json.Marshal(value)
+	return string(data), err
+}
+
+// excerptLength is the length beyond which text in diffs is shortened.
+const excerptLength = 200
+
+// excerpt shortens long text for a diff.
+func excerpt(text string) string {
+	if runes := []rune(text); len(runes) > excerptLength {
+		return string(runes[:excerptLength]) + "..."
+	}
+	return text
+}
+
+// quoteExcerpt quotes a shortened text for a diff.
+func quoteExcerpt(text string) string {
+	return strconv.Quote(excerpt(text))
+}
@@ -0,0 +1,153 @@
+package turntest
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	copilot "github.com/github/copilot-sdk/go"
+)
+
+func toolStart(id, name string, args interface{}) copilot.SessionEvent {
+	return copilot.SessionEvent{Type: copilot.ToolExecutionStart, Data: copilot.Data{ToolCallID: &id, ToolName: &name, Arguments: args}}
+}
+
+func toolComplete(id string, success bool) copilot.SessionEvent {
+	return copilot.SessionEvent{Type: copilot.ToolExecutionComplete, Data: copilot.Data{ToolCallID: &id, Success: &success}}
+}
+
+func triageTurn() *copilot.Turn {
+	return &copilot.Turn{
+		Events: []copilot.SessionEvent{
+			toolStart("c1", "get_issue", map[string]interface{}{"number": 42}),
+			toolComplete("c1", true),
+			toolStart("c2", "add_label", map[string]interface{}{"labels": []string{"bug", "p1"}}),
+			toolComplete("c2", false),
+		},
+		Content: `{"summary": "Labelled bug", "labels": ["bug"]}`,
+	}
+}
+
+func TestDiff(t *testing.T) {
+	turn := triageTurn()
+
+	t.Run("matches", func(t *testing.T) {
+		failed := true
+		diff := Diff(turn, Expectation{
+			ToolCalls: []ToolCall{
+				{Name: "get_issue", Arguments: ptr(JSONPath("$.number", Equals("42")))},
+				{Name: "add_label", Arguments: ptr(JSONPath("labels[1]", Equals("p1"))), Failed: &failed},
+			},
+			Message: []Matcher{
+				Contains("Labelled"),
+				Regex(`(?i)label(l)?ed`),
+				JSONPath("$.labels", Equals(`["bug"]`)),
+			},
+		})
+		if diff != "" {
+			t.Errorf("Expected no diff, got:\n%s", diff)
+		}
+	})
+
+	t.Run("reports mismatched, missing, and unexpected calls", func(t *testing.T) {
+		diff := Diff(turn, Expectation{ToolCalls: []ToolCall{
+			{Name: "get_issue", Arguments: ptr(JSONPath("$.number", Equals("7")))},
+		}})
+		for _, want := range []string{
+			`~ 1: get_issue({"number":42}): arguments: at $.number: want "7", got "42"`,
+			`+ 2: unexpected add_label({"labels":["bug","p1"]}) [failed]`,
+		} {
+			if !strings.Contains(diff, want) {
+				t.Errorf("Expected diff to contain %q, got:\n%s", want, diff)
+			}
+		}
+
+		diff = Diff(&copilot.Turn{}, Expectation{ToolCalls: []ToolCall{{Name: "get_issue"}}})
+		if !strings.Contains(diff, "- 1: missing get_issue") {
+			t.Errorf("Expected a missing call, got:\n%s", diff)
+		}
+	})
+
+	t.Run("reports message and tool errors", func(t *testing.T) {
+		diff := Diff(turn, Expectation{
+			Message:      []Matcher{Contains("wontfix"), JSONPath("$.owner", Equals("me"))},
+			NoToolErrors: true,
+		})
+		for _, want := range []string{
+			`message: want text containing "wontfix"`,
+			"message: $ has no field owner",
+			"tool call 2 failed: add_label",
+		} {
+			if !strings.Contains(diff, want) {
+				t.Errorf("Expected diff to contain %q, got:\n%s", want, diff)
+			}
+		}
+	})
+
+	t.Run("expects no calls with an empty slice", func(t *testing.T) {
+		if diff := Diff(turn, Expectation{ToolCalls: []ToolCall{}}); !strings.Contains(diff, "unexpected get_issue") {
+			t.Errorf("Expected unexpected calls, got:\n%s", diff)
+		}
+		if diff := Diff(turn, Expectation{}); diff != "" {
+			t.Errorf("Expected nil ToolCalls not to be checked, got:\n%s", diff)
+		}
+	})
+}
+
+func TestMatcherJSON(t *testing.T) {
+	matchers := []Matcher{Equals("a"), Contains("b"), Regex(`^c+$`), JSONPath("$.d[0]", Contains("e"))}
+	data, err := json.Marshal(matchers)
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+	var decoded []Matcher
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	for i := range matchers {
+		if decoded[i].String() != matchers[i].String() {
+			t.Errorf("Expected %s, got %s", matchers[i], decoded[i])
+		}
+	}
+	if err := decoded[2].Match("ccc"); err != nil {
+		t.Errorf("Expected decoded regex to match: %v", err)
+	}
+
+	for _, invalid := range []string{`{}`, `{"equals": "a", "contains": "b"}`, `{"regex": "("}`, `{"jsonPath": "$.a"}`, `"a"`} {
+		var m Matcher
+		if err := json.Unmarshal([]byte(invalid), &m); err == nil {
+			t.Errorf("Expected an error decoding %s", invalid)
+		}
+	}
+}
+
+func TestAssertGolden(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "testdata", "triage.json")
+	turn := triageTurn()
+
+	t.Setenv(UpdateGoldenEnv, "1")
+	AssertGolden(t, turn, path)
+
+	t.Setenv(UpdateGoldenEnv, "")
+	if !AssertGolden(t, turn, path) {
+		t.Fatal("Expected the recorded expectation to match")
+	}
+
+	want, err := LoadExpectation(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(want.ToolCalls) != 2 || want.ToolCalls[1].Failed == nil || !*want.ToolCalls[1].Failed {
+		t.Errorf("Expected recorded calls with their failures, got %+v", want.ToolCalls)
+	}
+	changed := triageTurn()
+	changed.Content = "Closed as duplicate"
+	if err := Compare(changed, want); err == nil || !strings.Contains(err.Error(), "Closed as duplicate") {
+		t.Errorf("Expected a message diff, got %v", err)
+	}
+}
+
+func ptr(m Matcher) *Matcher {
+	return &m
+}
@@ -1,5 +1,11 @@
 package copilot
 
+import (
+	"context"
+	"log/slog"
+	"time"
+)
+
 // ConnectionState represents the client connection state
 type ConnectionState string
 
@@ -31,6 +37,15 @@ type ClientOptions struct {
 	// AutoStart automatically starts the CLI server on first use (default: true).
 	// Use Bool(false) to disable.
 	AutoStart *bool
+	// LazyConnect defers spawning and connecting to the CLI server until the first
+	// call that needs it, including Ping, GetStatus, GetAuthStatus, and ListModels,
+	// which otherwise require an explicit Start. Concurrent first calls share a
+	// single connection attempt. Cannot be combined with AutoStart: Bool(false).
+	LazyConnect bool
+	// ModelsCacheTTL, if positive, is how long [Client.ListModels] caches the
+	// models before fetching them again. By default they are cached until the
+	// client disconnects.
+	ModelsCacheTTL time.Duration
 	// AutoRestart automatically restarts the CLI server if it crashes (default: true).
 	// Use Bool(false) to disable.
 	AutoRestart *bool
@@ -50,6 +65,66 @@ type ClientOptions struct {
 	// Default: true (but defaults to false when GithubToken is provided).
 	// Use Bool(false) to explicitly disable.
 	UseLoggedInUser *bool
+	// ResourceLimits constrains the spawned CLI process (nice level, memory, CPU affinity).
+	// Cannot be used with CLIUrl. See [ResourceLimits] for platform support.
+	ResourceLimits *ResourceLimits
+	// ToolMiddleware wraps the handler of every tool on every session of this
+	// client, outside any session middleware. See [ToolMiddleware].
+	ToolMiddleware []ToolMiddleware
+	// ToolObserver, if set, receives the name, latency, argument and result
+	// sizes, and error class of every tool call. See [ToolObserver].
+	ToolObserver ToolObserver
+	// AdaptiveConcurrency, if set, limits the requests in flight to the server
+	// and adjusts the limit when the server signals overload. The current
+	// limit is reported by [Client.Stats]. See [AdaptiveConcurrency].
+	AdaptiveConcurrency *AdaptiveConcurrency
+	// Journal, if set, appends every frame sent to the server to a file before
+	// sending it, for crash forensics. See [JournalConfig].
+	Journal *JournalConfig
+	// FlowControl, if set, pauses reading from the server while subscribers
+	// such as [Session.EventsSeq] have too many event bytes queued, and
+	// resumes once they drain. See [FlowControl].
+	FlowControl *FlowControl
+	// ResultSanitizer, if set, redacts secrets and other sensitive data from
+	// the result of every tool call on every session of this client before it
+	// is sent to the model. See [ResultSanitizer].
+	ResultSanitizer *ResultSanitizer
+	// DryRun, if set, simulates the tool calls of every session of this
+	// client instead of executing their handlers, and records them, unless
+	// the session sets its own DryRun. Simulate may be called concurrently
+	// for different sessions. See [DryRunConfig].
+	DryRun *DryRunConfig
+	// ToolLimits configures how tools that exceed the limits the server
+	// declares are handled: by failing the session's creation (the default),
+	// or by truncating them. See [ToolLimitsConfig].
+	ToolLimits *ToolLimitsConfig
+	// Secrets, if set, supplies the secrets tool handlers read with
+	// [ToolInvocation.Secret]. See [SecretProvider].
+	Secrets SecretProvider
+	// OnSecretAccess, if set, is called each time a tool handler reads a
+	// secret, with the secret's name but not its value, for audit logs. It
+	// may be called concurrently.
+	OnSecretAccess func(access SecretAccess)
+	// ProtocolErrorHistory is the number of protocol errors retained for
+	// [Client.RecentErrors]. Defaults to [DefaultProtocolErrorHistory]; a
+	// negative value retains none.
+	ProtocolErrorHistory int
+	// StdioChannel, if set, secures the stdio pipes to the CLI process, such
+	// as on hosts shared with other users. Cannot be used with CLIUrl or the
+	// TCP transport.
+	StdioChannel StdioChannel
+	// ModelFallback, if set, is the fallback chain of every session of this
+	// client that does not set its own. See [ModelFallbackConfig].
+	ModelFallback *ModelFallbackConfig
+	// Logger, if set, receives the log and telemetry records the server
+	// sends, at their levels and with the session they are about as the
+	// sessionId attribute. Without it, such records are dropped. See
+	// [LogRecord].
+	Logger *slog.Logger
+	// OnLogRecord, if set, is called with each log and telemetry record the
+	// server sends. It is called on the connection's read loop, so it should
+	// return quickly.
+	OnLogRecord func(record LogRecord)
 }
 
 // Bool returns a pointer to the given bool value.
@@ -343,6 +418,13 @@ type SessionConfig struct {
 	OnPermissionRequest PermissionHandler
 	// OnUserInputRequest is a handler for user input requests from the agent (enables ask_user tool)
 	OnUserInputRequest UserInputHandler
+	// OnToolPanic, if set, is called with each panic recovered from a tool
+	// handler. The call fails with a result whose Error holds the panic value and
+	// stack trace either way.
+	OnToolPanic ToolPanicHandler
+	// OnToolApproval decides whether calls of tools that require approval may
+	// run. See [Tool.RequiresApproval].
+	OnToolApproval ToolApprovalHandler
 	// Hooks configures hook handlers for session lifecycle events
 	Hooks *SessionHooks
 	// WorkingDirectory is the working directory for the session.
@@ -360,8 +442,79 @@ type SessionConfig struct {
 	CustomAgents []CustomAgentConfig
 	// SkillDirectories is a list of directories to load skills from
 	SkillDirectories []string
-	// DisabledSkills is a list of skill names to disable
+	// Skills are SDK-defined tool groups with instructions, registered with the session.
+	// See [Skill].
+	Skills []Skill
+	// DisabledSkills is a list of skill names to disable.
+	// Applies to both skills loaded from SkillDirectories and SDK-defined Skills.
 	DisabledSkills []string
+	// ExperimentProvider, if set, is consulted before every message to assign the
+	// turn to an experiment variant. See [ExperimentProvider].
+	ExperimentProvider ExperimentProvider
+	// Moderation, if set, reviews every prompt before it is sent and can block
+	// or rewrite it. See [PreSendModerator].
+	Moderation *ModerationConfig
+	// ModelFallback, if set, lists models to send turns to when the session's
+	// model is unavailable, overriding [ClientOptions.ModelFallback]. See
+	// [ModelFallbackConfig].
+	ModelFallback *ModelFallbackConfig
+	// TurnQueue, if set, serializes turns on the client: prompts sent while a turn
+	// is in flight are queued instead of reaching the server. See [TurnQueueConfig].
+	TurnQueue *TurnQueueConfig
+	// Memory, if set, recalls long-term facts about the user into every prompt
+	// and extracts new ones after every turn. See [MemoryConfig].
+	Memory *MemoryConfig
+	// DryRun, if set, simulates tool calls instead of executing their handlers,
+	// and records them. See [DryRunConfig].
+	DryRun *DryRunConfig
+	// ToolBudget, if set, warns when the advertised tool definitions exceed size
+	// limits. See [ToolBudgetConfig] and [Session.ToolCatalogSize].
+	ToolBudget *ToolBudgetConfig
+	// ReplayBufferSize is how many recent events are retained for resuming
+	// subscriptions from a cursor. See [Session.ResumeFrom]. Defaults to
+	// [DefaultReplayBufferSize]; a negative size retains none.
+	ReplayBufferSize int
+	// ToolRegistry, if set, advertises the registry's tools along with Tools
+	// and keeps the session up to date as tools are registered, unregistered,
+	// or replaced. See [ToolRegistry].
+	ToolRegistry *ToolRegistry
+	// ToolFilter, if set, selects which of the session's tools, including its
+	// registry's, are exposed to the model: tools it returns false for are
+	// neither advertised nor callable. It lets sessions of one client expose
+	// different subsets of a shared registry, such as only read-only tools
+	// for untrusted prompts. It is called again whenever the registry changes.
+	ToolFilter func(tool Tool) bool
+	// Resources, if set, offers the catalog's resources to the model through
+	// list_resources and read_resource tools. See [ResourceCatalog].
+	Resources *ResourceCatalog
+	// ToolMiddleware wraps the handler of every tool on this session, in order,
+	// inside the client's ToolMiddleware. See [ToolMiddleware].
+	ToolMiddleware []ToolMiddleware
+	// ResultLimit, if set, caps the size of the text each tool returns to the
+	// model, truncating longer results. Tools can override it with
+	// [Tool.ResultLimit]. See [ResultLimit].
+	ResultLimit *ResultLimit
+	// CostBudget, if set, limits the cost of the session's tool calls, across
+	// all tools, per turn and per session. Tools declare their cost with
+	// [Tool.Cost]; other tools are charged the budget's DefaultCost, if set.
+	// See [CostBudget].
+	CostBudget *CostBudget
+	// MaxConcurrentInvocations, if positive, limits how many tool calls of this
+	// session run at once, across all tools. Further calls wait in a queue and
+	// start in the order they arrived. Tools can be limited individually with
+	// [Tool.MaxConcurrentInvocations].
+	MaxConcurrentInvocations int
+	// ParentSessionID, if set, nests this session in another session created by
+	// the same client, such as a sub-agent session created by one of the parent's
+	// tool handlers (use [ToolInvocation.SessionID]). Nesting is tracked by
+	// [Session.Depth] and [Session.Ancestry], and limited by MaxDepth.
+	ParentSessionID string
+	// MaxDepth is the maximum nesting depth of this session and the sessions
+	// nested in it. Creating a session deeper than that fails with a
+	// [*MaxDepthError]. Defaults to the parent's limit, or
+	// [DefaultMaxSessionDepth]; a nested session can lower the limit but not
+	// raise it.
+	MaxDepth int
 	// InfiniteSessions configures infinite sessions for persistent workspaces and automatic compaction.
 	// When enabled (default), sessions automatically manage context limits and persist state.
 	InfiniteSessions *InfiniteSessionConfig
@@ -373,6 +526,60 @@ type Tool struct {
 	Description string // optional
 	Parameters  map[string]interface{}
 	Handler     ToolHandler
+	// Timeout, if positive, limits how long a call may run. A call that runs
+	// longer is abandoned: its context is canceled with a [*ToolTimeoutError],
+	// the model receives a timeout failure result, and an [SDKToolTimedOut] event
+	// is dispatched. Handlers should observe [ToolInvocation.Context], which
+	// carries the deadline, so they stop doing work once abandoned.
+	Timeout time.Duration
+	// PostProcessors describe how UIs should present the tool's successful
+	// results, such as the language to highlight them as or a short preview.
+	// The presentation is announced with an [SDKToolResultPresented] event; the
+	// result sent to the model is unchanged.
+	PostProcessors []ResultPostProcessor
+	// ResultLimit, if set, caps the size of the tool's results, overriding the
+	// session's [SessionConfig.ResultLimit].
+	ResultLimit *ResultLimit
+	// MaxConcurrentInvocations, if positive, limits how many calls of the tool
+	// run at once, such as for a tool that calls a rate-limited API. Further
+	// calls wait in a queue and start in the order they arrived; the wait does
+	// not count toward Timeout.
+	MaxConcurrentInvocations int
+	// MutexGroups name groups of tools that must not run at the same time, such
+	// as tools that write to and build the same workspace. Within a session,
+	// calls of the tools in a group, including calls of the same tool, run one
+	// at a time. Further calls wait in a queue and start in the order they
+	// arrived; the wait does not count toward Timeout.
+	MutexGroups []string
+	// RetryPolicy, if set, retries calls that fail transiently before the
+	// failure is returned to the model. See [RetryPolicy].
+	RetryPolicy *RetryPolicy
+	// RequiresApproval makes calls of the tool wait for the session's
+	// [SessionConfig.OnToolApproval] handler, for destructive operations such as
+	// deleting files or deploying. A call that is not approved, or made on a
+	// session without a handler, is not run; the model gets a denied result.
+	RequiresApproval bool
+	// ApprovalPolicy, if set, decides for each call whether it requires
+	// approval, instead of RequiresApproval.
+	ApprovalPolicy func(inv ToolInvocation) bool
+	// Cost, if set, returns the cost of a call, such as the API credits or
+	// estimated seconds its arguments imply. Calls are charged before they
+	// run, and the totals are reported by [Session.ToolCosts].
+	Cost func(inv ToolInvocation) ToolCost
+	// CostBudget, if set, limits the cost of the tool's calls per turn and per
+	// session. Calls of tools without a Cost function are free. See
+	// [CostBudget].
+	CostBudget *CostBudget
+	// Deprecated, if set, marks the tool as deprecated. Its description tells
+	// the model to use the replacement, and each call dispatches an
+	// [SDKToolDeprecated] event. A tool with [ToolDeprecation.ReplacedBy] needs
+	// no Handler or Parameters: calls are forwarded to the replacement. See
+	// [DeprecatedAlias].
+	Deprecated *ToolDeprecation
+
+	// resultSchema is the schema of the handler's result type, if known. It is
+	// used to simulate results in dry-run mode.
+	resultSchema map[string]interface{}
 }
 
 // ToolInvocation describes a tool call initiated by Copilot
@@ -381,6 +588,16 @@ type ToolInvocation struct {
 	ToolCallID string
 	ToolName   string
 	Arguments  interface{}
+	// IdempotencyKey is derived from the session and tool call IDs, so it is the
+	// same for every delivery of a given tool call. Handlers with side effects can
+	// use it to detect redelivered calls.
+	IdempotencyKey string
+
+	ctx     context.Context
+	outbox  *toolOutbox
+	kv      *ToolKV
+	pending *pendingToolCalls
+	secrets *secretSource
 }
 
 // ToolHandler executes a tool invocation.
@@ -395,6 +612,25 @@ type ToolResult struct {
 	Error               string                 `json:"error,omitempty"`
 	SessionLog          string                 `json:"sessionLog,omitempty"`
 	ToolTelemetry       map[string]interface{} `json:"toolTelemetry,omitempty"`
+	// Attachments are images, files, or other binary artifacts to return with
+	// the result. They are encoded into BinaryResultsForLLM when the result is
+	// sent. See [ToolResultAttachment].
+	Attachments []ToolResultAttachment `json:"-"`
+	// DisplayData is structured data for host apps to render, such as a table,
+	// diff, or progress widget, in place of or beside TextResultForLLM. It is
+	// not sent to the model; it is dispatched in an [SDKToolResultDisplayed]
+	// event instead. See [ToolResultDisplay].
+	DisplayData map[string]interface{} `json:"-"`
+	// MimeType identifies the format of DisplayData, such as
+	// "application/vnd.example.table+json", so UIs can pick a renderer.
+	MimeType string `json:"-"`
+
+	// renderedErr is the handler error an [ErrorRenderer] turned into this
+	// result, so that a [RetryPolicy] can classify it.
+	renderedErr error
+	// pending is the ID under which [ToolInvocation.PendingResult] deferred
+	// the call.
+	pending string
 }
 
 // ResumeSessionConfig configures options when resuming a session
@@ -410,6 +646,13 @@ type ResumeSessionConfig struct {
 	OnPermissionRequest PermissionHandler
 	// OnUserInputRequest is a handler for user input requests from the agent (enables ask_user tool)
 	OnUserInputRequest UserInputHandler
+	// OnToolPanic, if set, is called with each panic recovered from a tool
+	// handler. The call fails with a result whose Error holds the panic value and
+	// stack trace either way.
+	OnToolPanic ToolPanicHandler
+	// OnToolApproval decides whether calls of tools that require approval may
+	// run. See [Tool.RequiresApproval].
+	OnToolApproval ToolApprovalHandler
 	// Hooks configures hook handlers for session lifecycle events
 	Hooks *SessionHooks
 	// WorkingDirectory is the working directory for the session.
@@ -425,8 +668,79 @@ type ResumeSessionConfig struct {
 	CustomAgents []CustomAgentConfig
 	// SkillDirectories is a list of directories to load skills from
 	SkillDirectories []string
-	// DisabledSkills is a list of skill names to disable
+	// Skills are SDK-defined tool groups with instructions, registered with the session.
+	// See [Skill].
+	Skills []Skill
+	// DisabledSkills is a list of skill names to disable.
+	// Applies to both skills loaded from SkillDirectories and SDK-defined Skills.
 	DisabledSkills []string
+	// ExperimentProvider, if set, is consulted before every message to assign the
+	// turn to an experiment variant. See [ExperimentProvider].
+	ExperimentProvider ExperimentProvider
+	// Moderation, if set, reviews every prompt before it is sent and can block
+	// or rewrite it. See [PreSendModerator].
+	Moderation *ModerationConfig
+	// ModelFallback, if set, lists models to send turns to when the session's
+	// model is unavailable, overriding [ClientOptions.ModelFallback]. See
+	// [ModelFallbackConfig].
+	ModelFallback *ModelFallbackConfig
+	// TurnQueue, if set, serializes turns on the client: prompts sent while a turn
+	// is in flight are queued instead of reaching the server. See [TurnQueueConfig].
+	TurnQueue *TurnQueueConfig
+	// Memory, if set, recalls long-term facts about the user into every prompt
+	// and extracts new ones after every turn. See [MemoryConfig].
+	Memory *MemoryConfig
+	// DryRun, if set, simulates tool calls instead of executing their handlers,
+	// and records them. See [DryRunConfig].
+	DryRun *DryRunConfig
+	// ToolBudget, if set, warns when the advertised tool definitions exceed size
+	// limits. See [ToolBudgetConfig] and [Session.ToolCatalogSize].
+	ToolBudget *ToolBudgetConfig
+	// ReplayBufferSize is how many recent events are retained for resuming
+	// subscriptions from a cursor. See [Session.ResumeFrom]. Defaults to
+	// [DefaultReplayBufferSize]; a negative size retains none.
+	ReplayBufferSize int
+	// ToolRegistry, if set, advertises the registry's tools along with Tools
+	// and keeps the session up to date as tools are registered, unregistered,
+	// or replaced. See [ToolRegistry].
+	ToolRegistry *ToolRegistry
+	// ToolFilter, if set, selects which of the session's tools, including its
+	// registry's, are exposed to the model: tools it returns false for are
+	// neither advertised nor callable. It lets sessions of one client expose
+	// different subsets of a shared registry, such as only read-only tools
+	// for untrusted prompts. It is called again whenever the registry changes.
+	ToolFilter func(tool Tool) bool
+	// Resources, if set, offers the catalog's resources to the model through
+	// list_resources and read_resource tools. See [ResourceCatalog].
+	Resources *ResourceCatalog
+	// ToolMiddleware wraps the handler of every tool on this session, in order,
+	// inside the client's ToolMiddleware. See [ToolMiddleware].
+	ToolMiddleware []ToolMiddleware
+	// ResultLimit, if set, caps the size of the text each tool returns to the
+	// model, truncating longer results. Tools can override it with
+	// [Tool.ResultLimit]. See [ResultLimit].
+	ResultLimit *ResultLimit
+	// CostBudget, if set, limits the cost of the session's tool calls, across
+	// all tools, per turn and per session. Tools declare their cost with
+	// [Tool.Cost]; other tools are charged the budget's DefaultCost, if set.
+	// See [CostBudget].
+	CostBudget *CostBudget
+	// MaxConcurrentInvocations, if positive, limits how many tool calls of this
+	// session run at once, across all tools. Further calls wait in a queue and
+	// start in the order they arrived. Tools can be limited individually with
+	// [Tool.MaxConcurrentInvocations].
+	MaxConcurrentInvocations int
+	// ParentSessionID, if set, nests this session in another session created by
+	// the same client, such as a sub-agent session created by one of the parent's
+	// tool handlers (use [ToolInvocation.SessionID]). Nesting is tracked by
+	// [Session.Depth] and [Session.Ancestry], and limited by MaxDepth.
+	ParentSessionID string
+	// MaxDepth is the maximum nesting depth of this session and the sessions
+	// nested in it. Creating a session deeper than that fails with a
+	// [*MaxDepthError]. Defaults to the parent's limit, or
+	// [DefaultMaxSessionDepth]; a nested session can lower the limit but not
+	// raise it.
+	MaxDepth int
 	// DisableResume, when true, skips emitting the session.resume event.
 	// Useful for reconnecting to a session without triggering resume-related side effects.
 	DisableResume bool
@@ -472,6 +786,13 @@ type MessageOptions struct {
 	Attachments []Attachment
 	// Mode is the message delivery mode (default: "enqueue")
 	Mode string
+	// IdempotencyKey identifies this message for deduplication by the server.
+	// If empty, [Session.Send] generates one, which a failed send reports in its
+	// [SendError]. Reuse the key when retrying a send after a reconnect.
+	IdempotencyKey string
+	// ResponseFormat, if set, asks for the reply in a specific format.
+	// See [ResponseFormat].
+	ResponseFormat *ResponseFormat
 }
 
 // SessionEventHandler is a callback for session events
@@ -482,6 +803,9 @@ type PingResponse struct {
 	Message         string `json:"message"`
 	Timestamp       int64  `json:"timestamp"`
 	ProtocolVersion *int   `json:"protocolVersion,omitempty"`
+	// ToolLimits are the limits on advertised tools the server declares, if
+	// any.
+	ToolLimits *ToolLimits `json:"toolLimits,omitempty"`
 }
 
 // SessionCreateResponse is the response from session.create
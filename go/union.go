@@ -0,0 +1,394 @@
+package copilot
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/google/jsonschema-go/jsonschema"
+)
+
+// DefaultUnionDiscriminator is the property that selects the variant of a
+// union registered with [RegisterUnionVariant], unless [RegisterUnion] names
+// another.
+const DefaultUnionDiscriminator = "type"
+
+// unionType is a registered interface type and its variants.
+type unionType struct {
+	discriminator string
+	variants      []unionVariant
+}
+
+// unionVariant is a struct type that implements a union's interface.
+type unionVariant struct {
+	tag string
+	typ reflect.Type
+	// pointer is whether *typ, rather than typ, implements the interface.
+	pointer bool
+}
+
+var (
+	unionsMu sync.RWMutex
+	unions   = make(map[reflect.Type]*unionType)
+)
+
+// RegisterUnion sets the property that selects the variant of values of the
+// interface type I, which defaults to [DefaultUnionDiscriminator]. It panics if
+// I is not an interface type.
+func RegisterUnion[I any](discriminator string) {
+	iface := unionInterface[I]()
+	unionsMu.Lock()
+	defer unionsMu.Unlock()
+	union := unions[iface]
+	if union == nil {
+		union = &unionType{}
+		unions[iface] = union
+	}
+	union.discriminator = discriminator
+	resetSchemaCache()
+}
+
+// RegisterUnionVariant registers the struct type V as a variant of the
+// interface type I, selected by tag. Parameters of tools created with
+// [DefineTool] can then have fields of type I: their schema is a oneOf of the
+// variants, each with a discriminator property whose value is the variant's
+// tag, and arguments are decoded into the variant the tag selects.
+//
+// Register variants at init time, before defining the tools that use them. It
+// panics if I is not an interface type, if V is not a struct type that
+// implements I directly or through a pointer, or if tag is already registered
+// for I.
+//
+// Example:
+//
+//	type Shape interface{ isShape() }
+//
+//	type Circle struct {
+//	    Radius float64 `json:"radius"`
+//	}
+//
+//	type Square struct {
+//	    Side float64 `json:"side"`
+//	}
+//
+//	func (Circle) isShape() {}
+//	func (Square) isShape() {}
+//
+//	func init() {
+//	    copilot.RegisterUnionVariant[Shape, Circle]("circle")
+//	    copilot.RegisterUnionVariant[Shape, Square]("square")
+//	}
+//
+//	type DrawParams struct {
+//	    Shape Shape `json:"shape"` // {"type": "circle", "radius": 2}
+//	}
+func RegisterUnionVariant[I any, V any](tag string) {
+	iface := unionInterface[I]()
+	typ := reflect.TypeOf((*V)(nil)).Elem()
+	if typ.Kind() != reflect.Struct {
+		panic(fmt.Sprintf("union variant %v of %v must be a struct type", typ, iface))
+	}
+	variant := unionVariant{tag: tag, typ: typ}
+	switch {
+	case typ.Implements(iface):
+	case reflect.PointerTo(typ).Implements(iface):
+		variant.pointer = true
+	default:
+		panic(fmt.Sprintf("union variant %v does not implement %v", typ, iface))
+	}
+
+	unionsMu.Lock()
+	defer unionsMu.Unlock()
+	union := unions[iface]
+	if union == nil {
+		union = &unionType{}
+		unions[iface] = union
+	}
+	for _, existing := range union.variants {
+		if existing.tag == tag {
+			panic(fmt.Sprintf("union variant %q of %v is already registered", tag, iface))
+		}
+	}
+	union.variants = append(union.variants, variant)
+	resetSchemaCache()
+}
+
+func unionInterface[I any]() reflect.Type {
+	iface := reflect.TypeOf((*I)(nil)).Elem()
+	if iface.Kind() != reflect.Interface {
+		panic(fmt.Sprintf("union type %v must be an interface type", iface))
+	}
+	return iface
+}
+
+// lookupUnion returns a snapshot of the union registered for t, or nil.
+func lookupUnion(t reflect.Type) *unionType {
+	unionsMu.RLock()
+	defer unionsMu.RUnlock()
+	union := unions[t]
+	if union == nil || len(union.variants) == 0 {
+		return nil
+	}
+	snapshot := *union
+	snapshot.variants = append([]unionVariant(nil), union.variants...)
+	if snapshot.discriminator == "" {
+		snapshot.discriminator = DefaultUnionDiscriminator
+	}
+	return &snapshot
+}
+
+// applyUnion sets the schema of a union-typed value to a oneOf of its
+// variants. resolving holds the unions whose schemas are being generated, to
+// reject recursive unions.
+func applyUnion(t reflect.Type, schema *jsonschema.Schema, resolving map[reflect.Type]bool) error {
+	union := lookupUnion(t)
+	if union == nil {
+		return nil
+	}
+	if resolving[t] {
+		return fmt.Errorf("union %v is recursive", t)
+	}
+	resolving[t] = true
+	defer delete(resolving, t)
+
+	variants := make([]*jsonschema.Schema, 0, len(union.variants))
+	for _, variant := range union.variants {
+		variantSchema, err := schemaObjectForType(variant.typ, resolving)
+		if err != nil {
+			return fmt.Errorf("union variant %q of %v: %w", variant.tag, t, err)
+		}
+		if _, ok := variantSchema.Properties[union.discriminator]; ok {
+			return fmt.Errorf("union variant %q of %v has a field named like the discriminator %q", variant.tag, t, union.discriminator)
+		}
+		if variantSchema.Properties == nil {
+			variantSchema.Properties = make(map[string]*jsonschema.Schema)
+		}
+		var tag any = variant.tag
+		variantSchema.Properties[union.discriminator] = &jsonschema.Schema{Type: "string", Const: &tag}
+		variantSchema.PropertyOrder = append([]string{union.discriminator}, variantSchema.PropertyOrder...)
+		variantSchema.Required = append([]string{union.discriminator}, variantSchema.Required...)
+		variants = append(variants, variantSchema)
+	}
+	schema.Type = "object"
+	schema.OneOf = variants
+	return nil
+}
+
+// containsUnion reports whether values of t can hold a registered union or a
+// time.Duration, so that they must be decoded with decodeUnionValue.
+func containsUnion(t reflect.Type, seen map[reflect.Type]bool) bool {
+	if seen[t] {
+		return false
+	}
+	seen[t] = true
+	if t == durationType {
+		return true
+	}
+	switch t.Kind() {
+	case reflect.Interface:
+		return lookupUnion(t) != nil
+	case reflect.Ptr, reflect.Slice, reflect.Array, reflect.Map:
+		return containsUnion(t.Elem(), seen)
+	case reflect.Struct:
+		for _, field := range reflect.VisibleFields(t) {
+			if _, _, ok := jsonFieldName(field); ok && containsUnion(field.Type, seen) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+var jsonUnmarshalerType = reflect.TypeOf((*json.Unmarshaler)(nil)).Elem()
+
+// decodeArguments decodes JSON arguments into target, a pointer, selecting the
+// variant of union-typed values by their discriminator, and parsing
+// time.Duration values written as strings such as "1h30m".
+func decodeArguments(data []byte, target interface{}) error {
+	value := reflect.ValueOf(target).Elem()
+	if !containsUnion(value.Type(), make(map[reflect.Type]bool)) {
+		return json.Unmarshal(data, target)
+	}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var raw interface{}
+	if err := decoder.Decode(&raw); err != nil {
+		return err
+	}
+	return decodeUnionValue(raw, value)
+}
+
+// decodeUnionValue decodes raw, a value decoded from JSON, into v. Values that
+// cannot hold a union are decoded with encoding/json.
+func decodeUnionValue(raw interface{}, v reflect.Value) error {
+	t := v.Type()
+	if !containsUnion(t, make(map[reflect.Type]bool)) || reflect.PointerTo(t).Implements(jsonUnmarshalerType) {
+		data, err := json.Marshal(raw)
+		if err != nil {
+			return err
+		}
+		return json.Unmarshal(data, v.Addr().Interface())
+	}
+	if raw == nil {
+		v.Set(reflect.Zero(t))
+		return nil
+	}
+	if t == durationType {
+		d, err := decodeDuration(raw)
+		if err != nil {
+			return err
+		}
+		v.SetInt(int64(d))
+		return nil
+	}
+
+	switch t.Kind() {
+	case reflect.Interface:
+		union := lookupUnion(t)
+		object, ok := raw.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("cannot decode %s into %v: expected an object", jsonTypeName(raw), t)
+		}
+		tag, _ := object[union.discriminator].(string)
+		for _, variant := range union.variants {
+			if variant.tag != tag {
+				continue
+			}
+			value := reflect.New(variant.typ)
+			if err := decodeUnionValue(object, value.Elem()); err != nil {
+				return err
+			}
+			if variant.pointer {
+				v.Set(value)
+			} else {
+				v.Set(value.Elem())
+			}
+			return nil
+		}
+		return fmt.Errorf("unknown %s %q for %v", union.discriminator, tag, t)
+	case reflect.Ptr:
+		value := reflect.New(t.Elem())
+		if err := decodeUnionValue(raw, value.Elem()); err != nil {
+			return err
+		}
+		v.Set(value)
+		return nil
+	case reflect.Slice, reflect.Array:
+		items, ok := raw.([]interface{})
+		if !ok {
+			return fmt.Errorf("cannot decode %s into %v", jsonTypeName(raw), t)
+		}
+		if t.Kind() == reflect.Slice {
+			v.Set(reflect.MakeSlice(t, len(items), len(items)))
+		}
+		for i := 0; i < len(items) && i < v.Len(); i++ {
+			if err := decodeUnionValue(items[i], v.Index(i)); err != nil {
+				return fmt.Errorf("[%d]: %w", i, err)
+			}
+		}
+		return nil
+	case reflect.Map:
+		object, ok := raw.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("cannot decode %s into %v", jsonTypeName(raw), t)
+		}
+		v.Set(reflect.MakeMapWithSize(t, len(object)))
+		for key, item := range object {
+			mapKey, err := decodeMapKey(key, t.Key())
+			if err != nil {
+				return err
+			}
+			value := reflect.New(t.Elem()).Elem()
+			if err := decodeUnionValue(item, value); err != nil {
+				return fmt.Errorf("%s: %w", key, err)
+			}
+			v.SetMapIndex(mapKey, value)
+		}
+		return nil
+	case reflect.Struct:
+		object, ok := raw.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("cannot decode %s into %v", jsonTypeName(raw), t)
+		}
+		for _, field := range reflect.VisibleFields(t) {
+			name, _, ok := jsonFieldName(field)
+			if !ok {
+				continue
+			}
+			item, present := lookupJSONField(object, name)
+			if !present {
+				continue
+			}
+			fieldValue, err := fieldByIndexAlloc(v, field.Index)
+			if err != nil {
+				return fmt.Errorf("%s: %w", name, err)
+			}
+			if err := decodeUnionValue(item, fieldValue); err != nil {
+				return fmt.Errorf("%s: %w", name, err)
+			}
+		}
+		return nil
+	}
+	return fmt.Errorf("cannot decode into %v", t)
+}
+
+// decodeMapKey decodes an object key into a map key of type t, as
+// encoding/json does for string and integer keys.
+func decodeMapKey(key string, t reflect.Type) (reflect.Value, error) {
+	value := reflect.New(t).Elem()
+	switch t.Kind() {
+	case reflect.String:
+		value.SetString(key)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(key, 10, t.Bits())
+		if err != nil {
+			return reflect.Value{}, fmt.Errorf("invalid %v key %q", t, key)
+		}
+		value.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		n, err := strconv.ParseUint(key, 10, t.Bits())
+		if err != nil {
+			return reflect.Value{}, fmt.Errorf("invalid %v key %q", t, key)
+		}
+		value.SetUint(n)
+	default:
+		return reflect.Value{}, fmt.Errorf("unsupported map key type %v", t)
+	}
+	return value, nil
+}
+
+// lookupJSONField returns the value of a field, matching its name exactly or,
+// like encoding/json, case-insensitively.
+func lookupJSONField(object map[string]interface{}, name string) (interface{}, bool) {
+	if value, ok := object[name]; ok {
+		return value, true
+	}
+	for key, value := range object {
+		if strings.EqualFold(key, name) {
+			return value, true
+		}
+	}
+	return nil, false
+}
+
+// fieldByIndexAlloc returns the field of v at index, allocating the embedded
+// struct pointers on the way.
+func fieldByIndexAlloc(v reflect.Value, index []int) (reflect.Value, error) {
+	for i, x := range index {
+		if i > 0 && v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				if !v.CanSet() {
+					return reflect.Value{}, fmt.Errorf("cannot set embedded pointer to unexported %v", v.Type().Elem())
+				}
+				v.Set(reflect.New(v.Type().Elem()))
+			}
+			v = v.Elem()
+		}
+		v = v.Field(x)
+	}
+	return v, nil
+}
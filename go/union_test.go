@@ -0,0 +1,175 @@
+package copilot
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+type testShape interface{ area() float64 }
+
+type testCircle struct {
+	Radius float64 `json:"radius"`
+}
+
+type testRect struct {
+	Width  float64 `json:"width"`
+	Height float64 `json:"height" jsonschema:"minimum=0"`
+}
+
+func (c testCircle) area() float64 { return 3 * c.Radius * c.Radius }
+func (r *testRect) area() float64  { return r.Width * r.Height }
+
+type testAction interface{ action() }
+
+type testMove struct {
+	To testShape `json:"to"`
+}
+
+func (testMove) action() {}
+
+func init() {
+	RegisterUnionVariant[testShape, testCircle]("circle")
+	RegisterUnionVariant[testShape, testRect]("rect")
+	RegisterUnion[testAction]("kind")
+	RegisterUnionVariant[testAction, testMove]("move")
+}
+
+func TestUnionSchema(t *testing.T) {
+	type Params struct {
+		Shape  testShape   `json:"shape"`
+		Shapes []testShape `json:"shapes,omitempty"`
+	}
+	tool := DefineTool("draw", "Draw", func(params Params, inv ToolInvocation) (string, error) { return "", nil })
+
+	shape := tool.Parameters["properties"].(map[string]interface{})["shape"].(map[string]interface{})
+	variants, ok := shape["oneOf"].([]interface{})
+	if !ok || len(variants) != 2 || shape["type"] != "object" {
+		t.Fatalf("Expected a oneOf of two variants, got %v", shape)
+	}
+	circle := variants[0].(map[string]interface{})
+	discriminator := circle["properties"].(map[string]interface{})["type"].(map[string]interface{})
+	if discriminator["const"] != "circle" || discriminator["type"] != "string" {
+		t.Errorf("Unexpected discriminator %v", discriminator)
+	}
+	if required := circle["required"].([]interface{}); len(required) != 2 || required[0] != "type" || required[1] != "radius" {
+		t.Errorf("Expected the discriminator to be required, got %v", required)
+	}
+	rect := variants[1].(map[string]interface{})
+	height := rect["properties"].(map[string]interface{})["height"].(map[string]interface{})
+	if height["minimum"] != float64(0) {
+		t.Errorf("Expected variant field tags to apply, got %v", height)
+	}
+
+	shapes := tool.Parameters["properties"].(map[string]interface{})["shapes"].(map[string]interface{})
+	if items := shapes["items"].(map[string]interface{}); len(items["oneOf"].([]interface{})) != 2 {
+		t.Errorf("Expected slice items to be unions, got %v", items)
+	}
+
+	t.Run("top-level unions and custom discriminators", func(t *testing.T) {
+		action := DefineTool("act", "Act", func(params testAction, inv ToolInvocation) (string, error) { return "", nil })
+		move := action.Parameters["oneOf"].([]interface{})[0].(map[string]interface{})
+		kind := move["properties"].(map[string]interface{})["kind"].(map[string]interface{})
+		if action.Parameters["type"] != "object" || kind["const"] != "move" {
+			t.Errorf("Unexpected schema %v", action.Parameters)
+		}
+		to := move["properties"].(map[string]interface{})["to"].(map[string]interface{})
+		if len(to["oneOf"].([]interface{})) != 2 {
+			t.Errorf("Expected nested unions, got %v", to)
+		}
+	})
+
+	t.Run("interfaces that are not unions accept anything", func(t *testing.T) {
+		tool := DefineTool("any", "Any", func(params interface{}, inv ToolInvocation) (string, error) { return "", nil })
+		if tool.Parameters != nil {
+			t.Errorf("Expected no schema, got %v", tool.Parameters)
+		}
+	})
+}
+
+func TestUnionArguments(t *testing.T) {
+	type Params struct {
+		Shape  testShape            `json:"shape"`
+		Shapes []testShape          `json:"shapes,omitempty"`
+		ByName map[string]testShape `json:"byName,omitempty"`
+		Label  string               `json:"label,omitempty"`
+	}
+	var got Params
+	tool := DefineTool("draw", "Draw", func(params Params, inv ToolInvocation) (string, error) {
+		got = params
+		return "drawn", nil
+	})
+	call := func(args string) ToolResult {
+		t.Helper()
+		var arguments map[string]interface{}
+		if err := json.Unmarshal([]byte(args), &arguments); err != nil {
+			t.Fatal(err)
+		}
+		result, err := tool.Handler(ToolInvocation{ToolName: "draw", Arguments: arguments})
+		if err != nil {
+			return ToolResult{ResultType: "failure", Error: err.Error()}
+		}
+		return result
+	}
+
+	result := call(`{"shape": {"type": "circle", "radius": 2}, "shapes": [{"type": "rect", "width": 2, "height": 3}], "byName": {"c": {"type": "circle", "radius": 1}}, "label": "x"}`)
+	if result.ResultType != "success" {
+		t.Fatalf("Unexpected result %+v", result)
+	}
+	if circle, ok := got.Shape.(testCircle); !ok || circle.Radius != 2 {
+		t.Errorf("Expected a circle, got %#v", got.Shape)
+	}
+	if rect, ok := got.Shapes[0].(*testRect); !ok || rect.area() != 6 {
+		t.Errorf("Expected a *testRect, got %#v", got.Shapes[0])
+	}
+	if got.ByName["c"].area() != 3 || got.Label != "x" {
+		t.Errorf("Unexpected params %#v", got)
+	}
+
+	t.Run("reports the errors of the selected variant", func(t *testing.T) {
+		result := call(`{"shape": {"type": "rect", "width": 2, "height": -1}}`)
+		if result.ResultType != "failure" || !strings.Contains(result.TextResultForLLM, "shape.height: must be at least 0") {
+			t.Errorf("Unexpected result %+v", result)
+		}
+		if strings.Contains(result.TextResultForLLM, "radius") {
+			t.Errorf("Expected only the rect's problems, got %q", result.TextResultForLLM)
+		}
+	})
+
+	t.Run("decodes top-level unions", func(t *testing.T) {
+		var action testAction
+		tool := DefineTool("act", "Act", func(params testAction, inv ToolInvocation) (string, error) {
+			action = params
+			return "", nil
+		})
+		tool.Handler(ToolInvocation{ToolName: "act", Arguments: map[string]interface{}{
+			"kind": "move",
+			"to":   map[string]interface{}{"type": "circle", "radius": 1},
+		}})
+		if move, ok := action.(testMove); !ok || move.To.(testCircle).Radius != 1 {
+			t.Errorf("Expected a move to a circle, got %#v", action)
+		}
+	})
+
+	t.Run("reports unknown discriminators", func(t *testing.T) {
+		result := call(`{"shape": {"type": "hexagon"}}`)
+		if !strings.Contains(result.TextResultForLLM, `shape.type: must be one of "circle", "rect"`) {
+			t.Errorf("Unexpected result %+v", result)
+		}
+	})
+}
+
+func TestRegisterUnionVariantPanics(t *testing.T) {
+	expectPanic := func(name string, register func()) {
+		t.Helper()
+		defer func() {
+			if recover() == nil {
+				t.Errorf("%s: expected a panic", name)
+			}
+		}()
+		register()
+	}
+	expectPanic("non-interface union", func() { RegisterUnionVariant[testCircle, testCircle]("circle") })
+	expectPanic("variant not implementing the union", func() { RegisterUnionVariant[testShape, testMove]("move") })
+	expectPanic("duplicate tag", func() { RegisterUnionVariant[testShape, testCircle]("circle") })
+}
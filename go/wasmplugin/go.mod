@@ -0,0 +1,18 @@
+module github.com/github/copilot-sdk/go/wasmplugin
+
+go 1.23.0
+
+require (
+	github.com/github/copilot-sdk/go v0.1.8
+	github.com/tetratelabs/wazero v1.10.1
+)
+
+require github.com/google/jsonschema-go v0.4.2 // indirect
+
+// The requirement is the latest SDK release, v0.1.8, the version of the SDK
+// packages in this repository (see ../../nodejs/package.json). The SDK is developed in the
+// same repository, so the replace builds against it here; consumers, which
+// ignore the replace, get the required release. Releases tag this module
+// along with the SDK and move the requirement to the tagged SDK version; see
+// Modular Builds in ../README.md.
+replace github.com/github/copilot-sdk/go => ../
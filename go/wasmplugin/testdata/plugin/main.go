@@ -0,0 +1,76 @@
+//go:build go1.24
+
+// Command plugin is a tool plugin used by the wasmplugin tests. Build it with
+//
+//	GOOS=wasip1 GOARCH=wasm go build -buildmode=c-shared -o plugin.wasm main.go
+package main
+
+import (
+	"encoding/json"
+	"unsafe"
+)
+
+// buffers keeps the memory handed to the host alive.
+var buffers [][]byte
+
+//go:wasmexport copilot_alloc
+func alloc(size uint32) uint32 {
+	buf := make([]byte, size)
+	buffers = append(buffers, buf)
+	return uint32(uintptr(unsafe.Pointer(unsafe.SliceData(buf))))
+}
+
+// output hands data to the host as a pointer and length packed into one value.
+func output(v interface{}) uint64 {
+	data, _ := json.Marshal(v)
+	buffers = append(buffers, data)
+	return uint64(uintptr(unsafe.Pointer(unsafe.SliceData(data))))<<32 | uint64(len(data))
+}
+
+//go:wasmexport copilot_tools
+func tools() uint64 {
+	return output([]map[string]interface{}{
+		{
+			"name":        "echo",
+			"description": "Echo a message",
+			"parameters": map[string]interface{}{
+				"type":       "object",
+				"properties": map[string]interface{}{"message": map[string]interface{}{"type": "string"}},
+				"required":   []string{"message"},
+			},
+		},
+		{"name": "reject", "description": "Fail"},
+		{"name": "spin", "description": "Never return"},
+		{"name": "hog", "description": "Allocate memory until stopped"},
+	})
+}
+
+type call struct {
+	Name      string                 `json:"name"`
+	Arguments map[string]interface{} `json:"arguments"`
+}
+
+//go:wasmexport copilot_call
+func handle(ptr, size uint32) uint64 {
+	var c call
+	input := unsafe.Slice((*byte)(unsafe.Pointer(uintptr(ptr))), size)
+	if err := json.Unmarshal(input, &c); err != nil {
+		return output(map[string]interface{}{"resultType": "failure", "error": err.Error()})
+	}
+	switch c.Name {
+	case "echo":
+		return output(map[string]interface{}{"textResultForLlm": c.Arguments["message"]})
+	case "reject":
+		return output(map[string]interface{}{"textResultForLlm": "no", "resultType": "failure", "error": "rejected"})
+	case "spin":
+		for {
+		}
+	case "hog":
+		for {
+			buffers = append(buffers, make([]byte, 1<<20))
+		}
+	}
+	return output(map[string]interface{}{"resultType": "failure", "error": "unknown tool " + c.Name})
+}
+
+func main() {}
@@ -0,0 +1,314 @@
+// Package wasmplugin runs tools compiled to WebAssembly, so that third parties
+// can distribute tools without the host being recompiled. Plugins run in a
+// sandbox on the wazero runtime, which needs no cgo: they have no access to the
+// file system, network, environment, or real clock, their memory is capped, and
+// each call runs in a fresh instance that is stopped when the call times out.
+//
+// # Plugin interface
+//
+// A plugin is a WASI (wasip1) reactor module that exports:
+//
+//   - copilot_alloc(size i32) i32 returns a buffer of size bytes in the
+//     plugin's memory for the host to write input to.
+//   - copilot_tools() i64 returns the plugin's tools as a JSON array of
+//     {"name", "description", "parameters"} objects, where parameters is the
+//     JSON schema of the tool's arguments.
+//   - copilot_call(ptr i32, len i32) i64 handles a tool call. Its input is a
+//     JSON {"name", "arguments", "toolCallId", "sessionId"} object, and it
+//     returns a JSON [copilot.ToolResult], such as {"textResultForLlm": "..."}.
+//     A missing resultType means success.
+//
+// Functions that return i64 pack a pointer to JSON in the plugin's memory into
+// the upper 32 bits and its length into the lower 32 bits. A Go plugin can
+// export them with //go:wasmexport and be built with
+//
+//	GOOS=wasip1 GOARCH=wasm go build -buildmode=c-shared -o tool.wasm
+//
+// Example:
+//
+//	runner := wasmplugin.NewRunner(ctx, &wasmplugin.Options{Timeout: 10 * time.Second})
+//	defer runner.Close(ctx)
+//	tools, err := runner.LoadDir(ctx, "plugins")
+//	if err != nil {
+//	    log.Fatal(err)
+//	}
+//	session, err := client.CreateSession(&copilot.SessionConfig{Tools: tools})
+package wasmplugin
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	copilot "github.com/github/copilot-sdk/go"
+	"github.com/tetratelabs/wazero"
+	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/imports/wasi_snapshot_preview1"
+)
+
+// Defaults of [Options].
+const (
+	// DefaultMemoryLimitPages is 64 MiB in 64 KiB WebAssembly pages.
+	DefaultMemoryLimitPages = 1024
+	DefaultTimeout          = 30 * time.Second
+	DefaultMaxOutputBytes   = 1 << 20
+)
+
+// Options configures a [Runner].
+type Options struct {
+	// MemoryLimitPages caps the memory of each plugin instance, in 64 KiB
+	// pages. Defaults to [DefaultMemoryLimitPages].
+	MemoryLimitPages uint32
+	// Timeout limits how long a tool call may run. Defaults to [DefaultTimeout].
+	Timeout time.Duration
+	// MaxOutputBytes caps the size of a plugin's metadata and results.
+	// Defaults to [DefaultMaxOutputBytes].
+	MaxOutputBytes int
+	// Stderr, if set, receives what plugins write to stdout and stderr, such as
+	// logs. By default it is discarded.
+	Stderr io.Writer
+}
+
+// Runner loads and runs plugins. It is safe for concurrent use.
+type Runner struct {
+	runtime wazero.Runtime
+	options Options
+}
+
+// NewRunner returns a runner configured by opts, which may be nil.
+func NewRunner(ctx context.Context, opts *Options) *Runner {
+	var options Options
+	if opts != nil {
+		options = *opts
+	}
+	if options.MemoryLimitPages == 0 {
+		options.MemoryLimitPages = DefaultMemoryLimitPages
+	}
+	if options.Timeout <= 0 {
+		options.Timeout = DefaultTimeout
+	}
+	if options.MaxOutputBytes <= 0 {
+		options.MaxOutputBytes = DefaultMaxOutputBytes
+	}
+	if options.Stderr == nil {
+		options.Stderr = io.Discard
+	}
+
+	config := wazero.NewRuntimeConfig().
+		WithMemoryLimitPages(options.MemoryLimitPages).
+		WithCloseOnContextDone(true)
+	runtime := wazero.NewRuntimeWithConfig(ctx, config)
+	wasi_snapshot_preview1.MustInstantiate(ctx, runtime)
+	return &Runner{runtime: runtime, options: options}
+}
+
+// Plugin is a loaded plugin.
+type Plugin struct {
+	// Path is the file the plugin was loaded from.
+	Path string
+
+	runner   *Runner
+	compiled wazero.CompiledModule
+	tools    []copilot.Tool
+}
+
+// Tools returns the plugin's tools, ready to be registered on a session.
+func (p *Plugin) Tools() []copilot.Tool {
+	return append([]copilot.Tool(nil), p.tools...)
+}
+
+// toolMetadata describes a tool as returned by copilot_tools.
+type toolMetadata struct {
+	Name        string                 `json:"name"`
+	Description string                 `json:"description"`
+	Parameters  map[string]interface{} `json:"parameters,omitempty"`
+}
+
+// toolCall is the input of copilot_call.
+type toolCall struct {
+	Name       string      `json:"name"`
+	Arguments  interface{} `json:"arguments"`
+	ToolCallID string      `json:"toolCallId"`
+	SessionID  string      `json:"sessionId"`
+}
+
+// Load compiles the plugin at path and reads its tools.
+func (r *Runner) Load(ctx context.Context, path string) (*Plugin, error) {
+	code, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read plugin: %w", err)
+	}
+	compiled, err := r.runtime.CompileModule(ctx, code)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compile plugin %s: %w", path, err)
+	}
+	plugin := &Plugin{Path: path, runner: r, compiled: compiled}
+
+	callCtx, cancel := context.WithTimeout(ctx, r.options.Timeout)
+	defer cancel()
+	var metadata []toolMetadata
+	if err := plugin.invoke(callCtx, "copilot_tools", nil, &metadata); err != nil {
+		compiled.Close(ctx)
+		return nil, fmt.Errorf("failed to read tools of plugin %s: %w", path, err)
+	}
+	for _, meta := range metadata {
+		tool, err := plugin.defineTool(meta)
+		if err != nil {
+			compiled.Close(ctx)
+			return nil, fmt.Errorf("plugin %s: %w", path, err)
+		}
+		plugin.tools = append(plugin.tools, tool)
+	}
+
+	return plugin, nil
+}
+
+// LoadDir loads every .wasm file in dir, in name order, and returns their
+// tools. It fails if two plugins define tools with the same name.
+func (r *Runner) LoadDir(ctx context.Context, dir string) ([]copilot.Tool, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.wasm"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list plugins: %w", err)
+	}
+	sort.Strings(paths)
+
+	var tools []copilot.Tool
+	owners := make(map[string]string)
+	for _, path := range paths {
+		plugin, err := r.Load(ctx, path)
+		if err != nil {
+			return nil, err
+		}
+		for _, tool := range plugin.tools {
+			if owner, ok := owners[tool.Name]; ok {
+				return nil, fmt.Errorf("tool %s is defined by both %s and %s", tool.Name, owner, path)
+			}
+			owners[tool.Name] = path
+			tools = append(tools, tool)
+		}
+	}
+	return tools, nil
+}
+
+// Close releases the runtime and every plugin. Tools of the runner's plugins
+// fail once it is closed.
+func (r *Runner) Close(ctx context.Context) error {
+	return r.runtime.Close(ctx)
+}
+
+// defineTool returns the tool meta describes, whose calls run in the plugin.
+func (p *Plugin) defineTool(meta toolMetadata) (copilot.Tool, error) {
+	if meta.Name == "" {
+		return copilot.Tool{}, errors.New("plugin defines a tool without a name")
+	}
+	parameters := meta.Parameters
+	if parameters == nil {
+		parameters = map[string]interface{}{"type": "object"}
+	}
+	return copilot.TryDefineToolCtx(meta.Name, meta.Description,
+		func(ctx context.Context, args json.RawMessage, inv copilot.ToolInvocation) (copilot.ToolResult, error) {
+			return p.call(ctx, meta.Name, args, inv)
+		},
+		copilot.WithParameters(parameters),
+		copilot.WithToolTimeout(p.runner.options.Timeout))
+}
+
+// call runs a tool call in a fresh instance of the plugin.
+func (p *Plugin) call(ctx context.Context, name string, args json.RawMessage, inv copilot.ToolInvocation) (copilot.ToolResult, error) {
+	ctx, cancel := context.WithTimeout(ctx, p.runner.options.Timeout)
+	defer cancel()
+
+	input, err := json.Marshal(toolCall{
+		Name:       name,
+		Arguments:  args,
+		ToolCallID: inv.ToolCallID,
+		SessionID:  inv.SessionID,
+	})
+	if err != nil {
+		return copilot.ToolResult{}, fmt.Errorf("failed to encode tool call: %w", err)
+	}
+	var result copilot.ToolResult
+	if err := p.invoke(ctx, "copilot_call", input, &result); err != nil {
+		if ctx.Err() != nil {
+			return copilot.ToolResult{}, fmt.Errorf("plugin tool %s was stopped: %w", name, context.Cause(ctx))
+		}
+		return copilot.ToolResult{}, fmt.Errorf("plugin tool %s failed: %w", name, err)
+	}
+	if result.ResultType == "" {
+		result.ResultType = "success"
+	}
+	return result, nil
+}
+
+// invoke instantiates the plugin, calls the export fn with input, if any, and
+// decodes the JSON it returns into out.
+func (p *Plugin) invoke(ctx context.Context, fn string, input []byte, out interface{}) error {
+	config := wazero.NewModuleConfig().
+		WithName("").
+		WithStartFunctions("_initialize").
+		WithStdout(p.runner.options.Stderr).
+		WithStderr(p.runner.options.Stderr)
+	module, err := p.runner.runtime.InstantiateModule(ctx, p.compiled, config)
+	if err != nil {
+		return fmt.Errorf("failed to instantiate plugin: %w", err)
+	}
+	defer module.Close(context.Background())
+
+	export := module.ExportedFunction(fn)
+	if export == nil {
+		return fmt.Errorf("plugin does not export %s", fn)
+	}
+	var params []uint64
+	if input != nil {
+		ptr, err := p.write(ctx, module, input)
+		if err != nil {
+			return err
+		}
+		params = []uint64{uint64(ptr), uint64(len(input))}
+	}
+	results, err := export.Call(ctx, params...)
+	if err != nil {
+		return err
+	}
+	if len(results) != 1 {
+		return fmt.Errorf("%s returned %d values, expected 1", fn, len(results))
+	}
+	ptr, size := uint32(results[0]>>32), uint32(results[0])
+	if int(size) > p.runner.options.MaxOutputBytes {
+		return fmt.Errorf("%s returned %d bytes, more than the limit of %d", fn, size, p.runner.options.MaxOutputBytes)
+	}
+	data, ok := module.Memory().Read(ptr, size)
+	if !ok {
+		return fmt.Errorf("%s returned out-of-range memory", fn)
+	}
+	if err := json.Unmarshal(data, out); err != nil {
+		return fmt.Errorf("failed to decode output of %s: %w", fn, err)
+	}
+	return nil
+}
+
+// write copies data into a buffer allocated by the plugin.
+func (p *Plugin) write(ctx context.Context, module api.Module, data []byte) (uint32, error) {
+	alloc := module.ExportedFunction("copilot_alloc")
+	if alloc == nil {
+		return 0, errors.New("plugin does not export copilot_alloc")
+	}
+	results, err := alloc.Call(ctx, uint64(len(data)))
+	if err != nil {
+		return 0, fmt.Errorf("failed to allocate plugin memory: %w", err)
+	}
+	if len(results) != 1 {
+		return 0, errors.New("copilot_alloc must return one value")
+	}
+	ptr := uint32(results[0])
+	if !module.Memory().Write(ptr, data) {
+		return 0, errors.New("copilot_alloc returned out-of-range memory")
+	}
+	return ptr, nil
+}
@@ -0,0 +1,116 @@
+package wasmplugin
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	copilot "github.com/github/copilot-sdk/go"
+)
+
+// buildPlugin compiles testdata/plugin into dir, skipping the test if the Go
+// toolchain cannot build wasip1 reactors.
+func buildPlugin(t *testing.T, dir string) string {
+	t.Helper()
+	path := filepath.Join(dir, "plugin.wasm")
+	cmd := exec.Command("go", "build", "-buildmode=c-shared", "-o", path, "main.go")
+	cmd.Dir = filepath.Join("testdata", "plugin")
+	cmd.Env = append(os.Environ(), "GOOS=wasip1", "GOARCH=wasm")
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Skipf("cannot build the test plugin: %v\n%s", err, out)
+	}
+	return path
+}
+
+func TestRunner(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	buildPlugin(t, dir)
+
+	runner := NewRunner(ctx, &Options{Timeout: 2 * time.Second, MemoryLimitPages: 2048})
+	defer runner.Close(ctx)
+	tools, err := runner.LoadDir(ctx, dir)
+	if err != nil {
+		t.Fatalf("Failed to load plugins: %v", err)
+	}
+	byName := make(map[string]copilot.Tool)
+	for _, tool := range tools {
+		byName[tool.Name] = tool
+	}
+	call := func(name string, args map[string]interface{}) (copilot.ToolResult, error) {
+		return byName[name].Handler(copilot.ToolInvocation{ToolName: name, ToolCallID: "call-1", Arguments: args})
+	}
+
+	t.Run("registers the plugin's tools", func(t *testing.T) {
+		echo, ok := byName["echo"]
+		if len(tools) != 4 || !ok || echo.Description != "Echo a message" || echo.Parameters["type"] != "object" {
+			t.Fatalf("Unexpected tools %+v", tools)
+		}
+		result, err := call("echo", map[string]interface{}{"message": "hello"})
+		if err != nil || result.ResultType != "success" || result.TextResultForLLM != "hello" {
+			t.Errorf("Unexpected result %+v, error %v", result, err)
+		}
+	})
+
+	t.Run("validates arguments against the plugin's schema", func(t *testing.T) {
+		result, err := call("echo", map[string]interface{}{})
+		if err != nil || result.ResultType != "failure" || !strings.Contains(result.TextResultForLLM, "message") {
+			t.Errorf("Unexpected result %+v, error %v", result, err)
+		}
+	})
+
+	t.Run("passes failure results through", func(t *testing.T) {
+		result, err := call("reject", map[string]interface{}{})
+		if err != nil || result.ResultType != "failure" || result.Error != "rejected" {
+			t.Errorf("Unexpected result %+v, error %v", result, err)
+		}
+	})
+
+	t.Run("stops calls that time out", func(t *testing.T) {
+		start := time.Now()
+		result, err := call("spin", map[string]interface{}{})
+		if err != nil || result.ResultType != "failure" || !strings.Contains(result.Error, "was stopped") {
+			t.Errorf("Expected the call to be stopped, got %+v, error %v", result, err)
+		}
+		if elapsed := time.Since(start); elapsed > 10*time.Second {
+			t.Errorf("Expected the call to stop at the timeout, took %v", elapsed)
+		}
+	})
+
+	t.Run("caps memory", func(t *testing.T) {
+		if result, err := call("hog", map[string]interface{}{}); err != nil || result.ResultType != "failure" {
+			t.Errorf("Expected the call to fail, got %+v, error %v", result, err)
+		}
+	})
+
+	t.Run("rejects duplicate tools", func(t *testing.T) {
+		data, err := os.ReadFile(filepath.Join(dir, "plugin.wasm"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, "copy.wasm"), data, 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := runner.LoadDir(ctx, dir); err == nil || !strings.Contains(err.Error(), "is defined by both") {
+			t.Errorf("Expected a duplicate tool error, got %v", err)
+		}
+	})
+}
+
+func TestLoadInvalidPlugin(t *testing.T) {
+	ctx := context.Background()
+	runner := NewRunner(ctx, nil)
+	defer runner.Close(ctx)
+
+	path := filepath.Join(t.TempDir(), "bad.wasm")
+	if err := os.WriteFile(path, []byte("not wasm"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := runner.Load(ctx, path); err == nil || !strings.Contains(err.Error(), "failed to compile plugin") {
+		t.Errorf("Expected a compile error, got %v", err)
+	}
+}